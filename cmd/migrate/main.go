@@ -0,0 +1,93 @@
+// Command migrate copies a SQLite database of the service into PostgreSQL: its short URLs
+// with their variants and recorded clicks. The schemas of both databases are migrated up
+// first. URLs the target already holds are left alone, so an interrupted run can be
+// repeated. Run it from the module root, or point -migrations at the migration files.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/sp3dr4/dove/internal/infrastructure/migration"
+	"github.com/sp3dr4/dove/internal/infrastructure/migrations"
+	postgresRepo "github.com/sp3dr4/dove/internal/infrastructure/postgres"
+	sqliteRepo "github.com/sp3dr4/dove/internal/infrastructure/sqlite"
+)
+
+func main() {
+	fromSQLite := flag.String("from-sqlite", "./data/dove.db", "SQLite database file to copy")
+	toPostgres := flag.String("to-postgres", "", "connection string of the PostgreSQL database to copy into")
+	migrationsDir := flag.String("migrations", "migrations", "directory holding the sqlite and postgres schema migrations")
+	flag.Parse()
+
+	if *toPostgres == "" {
+		fmt.Fprintln(os.Stderr, "migrate: -to-postgres is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, *fromSQLite, *toPostgres, *migrationsDir); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, sqlitePath, postgresURL, migrationsDir string) error {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	// Connecting would create a missing file as an empty database
+	if _, err := os.Stat(sqlitePath); err != nil {
+		return err
+	}
+	srcDB, err := sqlx.Connect("sqlite3", sqlitePath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SQLite: %w", err)
+	}
+	defer func() { _ = srcDB.Close() }()
+
+	dstDB, err := sqlx.Connect("postgres", postgresURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer func() { _ = dstDB.Close() }()
+
+	// Both schemas must be current for the columns copied to exist
+	if err := migrations.NewRepository(srcDB.DB, "sqlite3", filepath.Join(migrationsDir, "sqlite")).Up(ctx); err != nil {
+		return fmt.Errorf("failed to migrate SQLite schema: %w", err)
+	}
+	if err := migrations.NewRepository(dstDB.DB, "postgres", filepath.Join(migrationsDir, "postgres")).Up(ctx); err != nil {
+		return fmt.Errorf("failed to migrate PostgreSQL schema: %w", err)
+	}
+
+	src, err := sqliteRepo.NewURLRepository(srcDB, sqliteRepo.Pragmas{BusyTimeoutMs: 5000}, logger)
+	if err != nil {
+		return err
+	}
+	dst := postgresRepo.NewURLRepository(dstDB, logger, 0, false, false)
+
+	report, err := migration.MigrateSQLiteToPostgres(ctx, src, dst, srcDB, dstDB, func(table string, rows int) {
+		fmt.Printf("%s: %d rows\n", table, rows)
+	}, logger)
+	// What was copied before a failure stays copied
+	printReport(report)
+	return err
+}
+
+func printReport(report migration.DatabaseReport) {
+	fmt.Printf("urls: %d migrated, %d skipped, %d failed\nurl_variants: %d copied, %d failed\nurl_clicks: %d copied, %d failed\n",
+		report.URLs.Migrated, report.URLs.Skipped, report.URLs.Failed,
+		report.Variants.Copied, report.Variants.Failed,
+		report.Clicks.Copied, report.Clicks.Failed)
+}
@@ -0,0 +1,38 @@
+// Command openapi writes the OpenAPI 3.1 spec of the service, generated from the handler
+// annotations and the types they name. Run it from the module root, or through go generate.
+package main
+
+//go:generate go run . -dir ../.. -out docs/openapi.yaml
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sp3dr4/dove/internal/pkg/openapi"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "module root the packages are loaded from")
+	out := flag.String("out", "docs/openapi.yaml", "file to write, relative to dir")
+	flag.Parse()
+
+	if err := run(*dir, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "openapi:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, out string) error {
+	doc, err := openapi.Generate(context.Background(), dir, openapi.Packages...)
+	if err != nil {
+		return err
+	}
+	data, err := openapi.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, out), data, 0o644)
+}
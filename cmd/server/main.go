@@ -1,14 +1,22 @@
 // Package main implements a simple URL shortener service.
 //
-//	@title			Dove URL Shortener API
-//	@version		1.0
-//	@description	A fast and simple URL shortener service
-//	@host			localhost:8080
-//	@BasePath		/
-//	@schemes		http https
+//	@title						Dove URL Shortener API
+//	@version					1.0
+//	@description				A fast and simple URL shortener service
+//	@host						localhost:8080
+//	@BasePath					/
+//	@schemes					http https
+//
+//	@securityDefinitions.apikey	AdminAPIKey
+//	@in							header
+//	@name						Authorization
+//	@description				Admin API key as "Bearer <key>"
 package main
 
 import (
+	"context"
+	"flag"
+	"os"
 	"time"
 
 	"go.uber.org/fx"
@@ -17,7 +25,17 @@ import (
 	fxProviders "github.com/sp3dr4/dove/internal/fx"
 )
 
+// seedTimeout bounds a --seed-only run, large seed files take longer than a normal start
+const seedTimeout = 5 * time.Minute
+
 func main() {
+	seedOnly := flag.Bool("seed-only", false, "create the URLs of app.seed_file and exit without serving HTTP")
+	flag.Parse()
+
+	if *seedOnly {
+		os.Exit(seed())
+	}
+
 	app := fx.New(
 		fxProviders.HTTPServerModules,
 		fx.StopTimeout(30*time.Second),
@@ -25,3 +43,26 @@ func main() {
 
 	app.Run()
 }
+
+// seed starts the core modules, which seeds on start, then stops them again. fx has
+// already logged any failure by the time it is returned.
+func seed() int {
+	app := fx.New(
+		fxProviders.SeedOnlyModules,
+		fx.StartTimeout(seedTimeout),
+		fx.StopTimeout(30*time.Second),
+	)
+
+	startCtx, cancel := context.WithTimeout(context.Background(), app.StartTimeout())
+	defer cancel()
+	if err := app.Start(startCtx); err != nil {
+		return 1
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), app.StopTimeout())
+	defer cancel()
+	if err := app.Stop(stopCtx); err != nil {
+		return 1
+	}
+	return 0
+}
@@ -1,55 +1,285 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
 )
 
+// ErrInvalidConfig is returned by Load when config values fail validation
+var ErrInvalidConfig = errors.New("invalid config")
+
 type Config struct {
 	Server   ServerConfig   `mapstructure:"server"`
+	TLS      TLSConfig      `mapstructure:"tls"`
 	Database DatabaseConfig `mapstructure:"database"`
 	Cache    CacheConfig    `mapstructure:"cache"`
 	App      AppConfig      `mapstructure:"app"`
 	Logging  LoggingConfig  `mapstructure:"logging"`
 	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	Audit    AuditConfig    `mapstructure:"audit"`
+	Admin    AdminConfig    `mapstructure:"admin"`
+	Geo      GeoConfig      `mapstructure:"geo"`
+	Security SecurityConfig `mapstructure:"security"`
+
+	Analytics AnalyticsConfig `mapstructure:"analytics"`
+
+	HealthChecker HealthCheckerConfig `mapstructure:"health_checker"`
+
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
 }
 
 type ServerConfig struct {
-	Port         string `mapstructure:"port"`
-	ReadTimeout  string `mapstructure:"read_timeout"`
-	WriteTimeout string `mapstructure:"write_timeout"`
-	IdleTimeout  string `mapstructure:"idle_timeout"`
+	Port              string `mapstructure:"port" validate:"required,port"`
+	ReadTimeout       string `mapstructure:"read_timeout" validate:"omitempty,duration"`
+	WriteTimeout      string `mapstructure:"write_timeout" validate:"omitempty,duration"`
+	IdleTimeout       string `mapstructure:"idle_timeout" validate:"omitempty,duration"`
+	SSEMaxConnections int    `mapstructure:"sse_max_connections"`
+	// RouteTimeouts bounds how long the redirect, shorten and import routes may take, by route name
+	RouteTimeouts map[string]string `mapstructure:"route_timeouts" validate:"dive,keys,oneof=redirect shorten import,endkeys,duration"`
+	Compression   CompressionConfig `mapstructure:"compression"`
+	CORS          CORSConfig        `mapstructure:"cors"`
+	// DegradedCacheOK keeps /ready at 200 while the cache is unreachable but the database is up
+	DegradedCacheOK bool `mapstructure:"degraded_cache_ok"`
+	// DrainTimeout bounds how long shutdown waits for requests in flight, within the
+	// 30s stop timeout of the application
+	DrainTimeout string `mapstructure:"drain_timeout" validate:"omitempty,duration"`
+	// DefaultAPIVersion serves requests whose Accept header asks for no version; it must be
+	// one of SupportedVersions
+	DefaultAPIVersion string `mapstructure:"default_api_version" validate:"required,oneof=v1 v2"`
+	// SupportedVersions are the API versions clients may ask for with
+	// Accept: application/vnd.dove.<version>+json
+	SupportedVersions []string `mapstructure:"supported_versions" validate:"min=1,dive,oneof=v1 v2"`
+}
+
+// CompressionConfig controls gzip compression of GET responses
+type CompressionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Level   int  `mapstructure:"level" validate:"min=1,max=9"` // gzip level, 1 is fastest and 9 smallest
+	MinSize int  `mapstructure:"min_size" validate:"min=0"`    // smallest response body compressed, in bytes
+}
+
+// CORSConfig lets browser pages on other origins call the API
+type CORSConfig struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	AllowedOrigins []string `mapstructure:"allowed_origins" validate:"dive,required"` // "*" allows every origin
+	AllowedHeaders []string `mapstructure:"allowed_headers" validate:"dive,required"` // request headers preflights may ask for
+	ExposedHeaders []string `mapstructure:"exposed_headers" validate:"dive,required"` // response headers pages may read
+	MaxAgeSeconds  int      `mapstructure:"max_age_seconds" validate:"min=0"`         // how long browsers cache preflights, 0 leaves it to them
+}
+
+// TLSConfig enables HTTPS on the server port
+type TLSConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	CertFile   string `mapstructure:"cert_file"`
+	KeyFile    string `mapstructure:"key_file"`
+	MinVersion string `mapstructure:"min_version"` // TLS1.2 or TLS1.3
+	AutoCert   bool   `mapstructure:"auto_cert"`   // generate a self-signed certificate, for development only
 }
 
 type DatabaseConfig struct {
-	Type     string         `mapstructure:"type"` // memory, sqlite, postgres
+	Type     string         `mapstructure:"type" validate:"required,oneof=memory sqlite postgres"`
+	Memory   MemoryConfig   `mapstructure:"memory"`
 	SQLite   SQLiteConfig   `mapstructure:"sqlite"`
 	Postgres PostgresConfig `mapstructure:"postgres"`
+	// QueryTimeoutMs bounds each query of the lib/pq repository, 0 disables the limit.
+	// Queries past it fail with a 504.
+	QueryTimeoutMs int `mapstructure:"query_timeout_ms" validate:"min=0"`
+}
+
+type MemoryConfig struct {
+	// MaxCapacity is the most URLs held, the least recently accessed one being evicted to
+	// make room for a new one. 0 leaves the repository unbounded.
+	MaxCapacity int `mapstructure:"max_capacity" validate:"min=0"`
 }
 
 type SQLiteConfig struct {
-	Path string `mapstructure:"path"`
+	Path          string `mapstructure:"path"`
+	JournalMode   string `mapstructure:"journal_mode" validate:"omitempty,oneof=DELETE TRUNCATE PERSIST MEMORY WAL OFF"`
+	Synchronous   string `mapstructure:"synchronous" validate:"omitempty,oneof=OFF NORMAL FULL EXTRA"`
+	BusyTimeoutMs int    `mapstructure:"busy_timeout_ms" validate:"min=0"` // how long a locked database is retried before failing with SQLITE_BUSY
 }
 
 type PostgresConfig struct {
-	URL string `mapstructure:"url"`
+	URL        string `mapstructure:"url"`
+	ReplicaURL string `mapstructure:"replica_url"`                             // optional read replica for lookups
+	Driver     string `mapstructure:"driver" validate:"required,oneof=pq pgx"` // lib/pq through sqlx, or pgx with its native pool
+	// TrgmEnabled makes URL searches also match original URLs similar to the query, with the
+	// pg_trgm extension. Migration 025 creates it, when the database role is allowed to.
+	TrgmEnabled bool `mapstructure:"trgm_enabled"`
+	// DisablePrepare keeps the repository from preparing statements, for PgBouncer in
+	// transaction mode. pgx then sends every query over the simple protocol.
+	DisablePrepare bool `mapstructure:"disable_prepare"`
 }
 
 type AppConfig struct {
-	BaseURL         string `mapstructure:"base_url"`
-	ShortCodeLength int    `mapstructure:"short_code_length"`
+	BaseURL          string `mapstructure:"base_url"`
+	ShortURLScheme   string `mapstructure:"short_url_scheme" validate:"omitempty,oneof=http https"` // scheme of returned short URLs and docs, that of BaseURL when empty
+	ShortCodeLength  int    `mapstructure:"short_code_length"`
+	ShortCodeCharset string `mapstructure:"short_code_charset" validate:"required,oneof=alphanumeric safe hex custom"` // alphabet of generated short codes
+	CustomCharset    string `mapstructure:"custom_charset" validate:"required_if=ShortCodeCharset custom"`             // characters of the custom charset
+	SuggestEnabled   bool   `mapstructure:"suggest_enabled"`                                                           // expose GET /shorten/suggest, which fetches destination pages
+	FetchTitles      bool   `mapstructure:"fetch_titles"`                                                              // fetch the title of destination pages after creation
+	SigningSecret    string `mapstructure:"signing_secret"`                                                            // HMAC key for expiring signed short codes, disabled when empty
+	MaxDelaySeconds  int    `mapstructure:"max_delay_seconds" validate:"min=0,max=60"`                                 // longest countdown page before a redirect, 0 disables them
+	// FollowRedirectChains redirects straight to the final destination of short URLs that
+	// point at other short URLs of this service, under BaseURL or one of ChainAliases
+	FollowRedirectChains bool     `mapstructure:"follow_redirect_chains"`
+	MaxChainDepth        int      `mapstructure:"max_chain_depth" validate:"min=1,max=10"` // most short URLs skipped per redirect
+	ChainAliases         []string `mapstructure:"chain_aliases" validate:"dive,url"`       // other base URLs the service is reachable at
+	// TrustForwardedHeaders builds the short URLs returned on creation from the
+	// X-Forwarded-Host and X-Forwarded-Proto headers set by a reverse proxy, instead of
+	// BaseURL. Only hosts in AllowedHosts are trusted, so that clients cannot make the
+	// service hand out links to a host of their choosing.
+	TrustForwardedHeaders bool     `mapstructure:"trust_forwarded_headers"`
+	AllowedHosts          []string `mapstructure:"allowed_hosts" validate:"dive,required"` // hosts with an optional port, e.g. "dove.example.com"
+	// RobotsDisallowAll serves a robots.txt keeping crawlers off every path, docs included.
+	// RobotsCustom replaces the served robots.txt entirely when set.
+	RobotsDisallowAll bool   `mapstructure:"robots_disallow_all"`
+	RobotsCustom      string `mapstructure:"robots_custom"`
+	// SeedFile is a YAML file of short URLs created at startup when missing, see
+	// application.SeedURL. Disabled when empty.
+	SeedFile string `mapstructure:"seed_file"`
+	// CleanupIntervalMinutes is how often URLs past their expiry are deleted, 0 disables
+	// the cleanup and expired URLs are only refused
+	CleanupIntervalMinutes int `mapstructure:"cleanup_interval_minutes" validate:"min=0"`
+	// AutoDisableColdAfterDays makes the cleanup also disable URLs not clicked for that many
+	// days, 0 leaves cold URLs alone. It only runs along with the cleanup.
+	AutoDisableColdAfterDays int `mapstructure:"auto_disable_cold_after_days" validate:"min=0"`
+	// AsyncClicks counts clicks in memory and adds them to the click counters every
+	// ClickFlushIntervalMs milliseconds, instead of updating the URL on every redirect.
+	// Counters read back lag behind by up to one interval, and clicks still buffered are
+	// lost if the process dies.
+	AsyncClicks          bool `mapstructure:"async_clicks"`
+	ClickFlushIntervalMs int  `mapstructure:"click_flush_interval_ms" validate:"min=1"`
+	// CustomAliasPolicy shapes the custom aliases URLs may be created with
+	CustomAliasPolicy CustomAliasPolicyConfig `mapstructure:"custom_alias_policy"`
+	// CreateHooks adds the built-in hooks run around the creation of every URL
+	CreateHooks CreateHooksConfig `mapstructure:"create_hooks"`
+	// Custom404 serves an HTML page to browsers redirected from a short code that is not found
+	Custom404 Custom404Config `mapstructure:"custom_404"`
+}
+
+// Custom404Config sets the HTML page served when a redirect finds no short URL. The page
+// is read from FilePath, or fetched from RemoteURL when no file is set, once at startup.
+type Custom404Config struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	FilePath  string `mapstructure:"file_path"`
+	RemoteURL string `mapstructure:"remote_url" validate:"omitempty,url"`
+	// CacheDurationSeconds is how often the page is loaded again, 0 keeps the page loaded
+	// at startup
+	CacheDurationSeconds int `mapstructure:"cache_duration_seconds" validate:"min=0"`
+}
+
+// CreateHooksConfig enables the built-in create hooks, each disabled when left empty
+type CreateHooksConfig struct {
+	// BlockedDomains refuses URLs with a destination on one of these domains or their
+	// subdomains
+	BlockedDomains []string `mapstructure:"blocked_domains" validate:"dive,hostname_rfc1123"`
+	// WebhookURL is sent a POST with the JSON of every URL created. Failed deliveries are
+	// logged and not retried.
+	WebhookURL     string `mapstructure:"webhook_url" validate:"omitempty,url"`
+	WebhookTimeout string `mapstructure:"webhook_timeout" validate:"omitempty,duration"` // how long a delivery may take
+}
+
+// CustomAliasPolicyConfig shapes the custom aliases of new URLs, which are always at least
+// three letters or digits long
+type CustomAliasPolicyConfig struct {
+	MaxLength        int  `mapstructure:"max_length" validate:"min=3,max=20"` // the short_code columns hold 20 characters
+	AllowHyphens     bool `mapstructure:"allow_hyphens"`
+	AllowUnderscores bool `mapstructure:"allow_underscores"`
+	// ReservedWords are refused as aliases, ignoring case, on top of admin, api, health,
+	// metrics and swagger
+	ReservedWords []string `mapstructure:"reserved_words"`
 }
 
 type LoggingConfig struct {
-	Level string `mapstructure:"level"`
+	Level  string `mapstructure:"level"`
+	Format string `mapstructure:"format" validate:"oneof=json text"` // text is easier to read in development
+	// SampleRate is the share of request logs below Warn that are kept, warnings and errors
+	// are always logged
+	SampleRate float64 `mapstructure:"sample_rate" validate:"min=0,max=1"`
+}
+
+type AuditConfig struct {
+	LogPath string `mapstructure:"log_path"` // JSON lines audit trail, disabled when empty
+}
+
+// AdminConfig gates operator-only endpoints
+type AdminConfig struct {
+	ExportEnabled bool   `mapstructure:"export_enabled"` // expose GET /urls/export and the click exports to admins
+	APIKey        string `mapstructure:"api_key"`        // bearer token of the /admin endpoints, disabled when empty
+	ExposeCache   bool   `mapstructure:"expose_cache"`   // expose GET /admin/cache/keys, listing what is cached
+}
+
+// SecurityConfig hardens the API for server-side integrations
+type SecurityConfig struct {
+	// HMACEnabled makes POST /shorten require the HMAC-SHA256 of its body under HMACSecret,
+	// hex encoded in the X-Dove-Signature header
+	HMACEnabled bool   `mapstructure:"hmac_enabled"`
+	HMACSecret  string `mapstructure:"hmac_secret" validate:"required_if=HMACEnabled true"`
+}
+
+// GeoConfig controls how the country of a visitor is determined for geo routed URLs
+type GeoConfig struct {
+	CountryHeader string `mapstructure:"country_header"` // header set by a trusted proxy, e.g. CF-IPCountry; geo routing is disabled when empty
+}
+
+// AnalyticsConfig controls how clicks are counted
+type AnalyticsConfig struct {
+	// DeduplicationWindowSeconds is how long a visitor's repeat clicks are left out of the
+	// unique clicks of a short URL, 0 counts every click as unique. Needs the Redis cache.
+	DeduplicationWindowSeconds int `mapstructure:"deduplication_window_seconds" validate:"min=0"`
+}
+
+// HealthCheckerConfig controls the background job that probes destination URLs
+type HealthCheckerConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	IntervalMinutes int  `mapstructure:"interval_minutes"`
+	BatchSize       int  `mapstructure:"batch_size"` // URLs checked per run
+	WorkerCount     int  `mapstructure:"worker_count"`
+}
+
+// RateLimitConfig bounds how many requests each client IP may make
+type RateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Algorithm is token_bucket, which lets clients burst up to Burst requests, or
+	// sliding_window, which allows RequestsPerSecond times 60 requests over any minute
+	Algorithm         string  `mapstructure:"algorithm" validate:"required,oneof=sliding_window token_bucket"`
+	RequestsPerSecond float64 `mapstructure:"requests_per_second" validate:"gt=0"`
+	Burst             int     `mapstructure:"burst" validate:"min=1"`
+	// MaxIPs is how many client IPs are tracked, forgetting the least recently seen beyond
+	MaxIPs int `mapstructure:"max_ips" validate:"min=1"`
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For and X-Real-IP rather than
+	// from the connection. Only enable it behind a proxy that overwrites those headers.
+	TrustProxyHeaders bool `mapstructure:"trust_proxy_headers"`
 }
 
 type CacheConfig struct {
 	Enabled bool        `mapstructure:"enabled"`
+	Backend string      `mapstructure:"backend" validate:"required,oneof=redis file"`
 	Redis   RedisConfig `mapstructure:"redis"`
-	TTL     string      `mapstructure:"ttl"`
+	FileDir string      `mapstructure:"file_dir" validate:"required_if=Backend file"` // directory of the file backend, one file per entry
+	TTL     string      `mapstructure:"ttl" validate:"omitempty,duration"`
+	// StaleOnError serves expired cache entries, with a Warning header, while the database is unavailable
+	StaleOnError bool `mapstructure:"stale_on_error"`
+	// KeyPrefix starts every Redis key, so environments sharing one Redis keep apart
+	KeyPrefix string `mapstructure:"key_prefix" validate:"required"`
+	// WarmEnabled caches the WarmCount most clicked URLs on start, before the HTTP server
+	// accepts connections
+	WarmEnabled bool `mapstructure:"warm_enabled"`
+	WarmCount   int  `mapstructure:"warm_count" validate:"min=0,max=10000"`
+	// JitterPercent spreads the expiry of Redis URL entries over this percentage of the
+	// TTL either way, so URLs cached together are not all fetched again together
+	JitterPercent float64 `mapstructure:"jitter_percent" validate:"min=0,max=50"`
 }
 
 type MetricsConfig struct {
@@ -60,6 +290,19 @@ type MetricsConfig struct {
 	CollectRuntime  bool   `mapstructure:"collect_runtime"`
 	CollectDatabase bool   `mapstructure:"collect_database"`
 	CollectCache    bool   `mapstructure:"collect_cache"`
+	Backend         string `mapstructure:"backend" validate:"required,oneof=prometheus otel"`
+	OTLPEndpoint    string `mapstructure:"otlp_endpoint" validate:"required_if=Backend otel"` // host:port of an OTLP gRPC collector
+	// TrackTopNCodes is how many short codes, and namespaces, get a series of their own in
+	// the redirect and creation counters; the rest are counted as "other"
+	TrackTopNCodes int `mapstructure:"track_top_n_codes" validate:"min=0"`
+	// ConstLabels are added to every Prometheus metric, e.g. env=prod. A label can also be
+	// set through METRICS_CONST_LABELS_<NAME>, which takes precedence over the config file.
+	ConstLabels map[string]string `mapstructure:"const_labels"`
+	// HTTPBuckets bounds, in seconds, the buckets of the request duration histogram and
+	// DBBuckets those of the URL lookup and creation histograms. Unset, both are Prometheus'
+	// default buckets, from 5ms to 10s.
+	HTTPBuckets []float64 `mapstructure:"http_buckets"`
+	DBBuckets   []float64 `mapstructure:"db_buckets"`
 }
 
 type RedisConfig struct {
@@ -69,8 +312,8 @@ type RedisConfig struct {
 	PoolSize     int    `mapstructure:"pool_size"`
 	MinIdleConns int    `mapstructure:"min_idle_conns"`
 	MaxRetries   int    `mapstructure:"max_retries"`
-	ReadTimeout  string `mapstructure:"read_timeout"`
-	WriteTimeout string `mapstructure:"write_timeout"`
+	ReadTimeout  string `mapstructure:"read_timeout" validate:"omitempty,duration"`
+	WriteTimeout string `mapstructure:"write_timeout" validate:"omitempty,duration"`
 }
 
 func Load() (*Config, error) {
@@ -87,17 +330,82 @@ func Load() (*Config, error) {
 	viper.SetDefault("server.read_timeout", "15s")
 	viper.SetDefault("server.write_timeout", "15s")
 	viper.SetDefault("server.idle_timeout", "60s")
+	viper.SetDefault("server.sse_max_connections", 100)
+	viper.SetDefault("server.route_timeouts.redirect", "5s")
+	viper.SetDefault("server.route_timeouts.shorten", "15s")
+	viper.SetDefault("server.route_timeouts.import", "30s")
+	viper.SetDefault("server.compression.enabled", false)
+	viper.SetDefault("server.compression.level", 5)
+	viper.SetDefault("server.compression.min_size", 1024)
+	viper.SetDefault("server.default_api_version", "v1")
+	viper.SetDefault("server.supported_versions", []string{"v1", "v2"})
+	viper.SetDefault("server.cors.enabled", false)
+	viper.SetDefault("server.cors.allowed_origins", []string{"*"})
+	viper.SetDefault("server.cors.allowed_headers", []string{"Content-Type", "Authorization", "X-Namespace", "X-URL-Password"})
+	viper.SetDefault("server.cors.exposed_headers", []string{"Location"})
+	viper.SetDefault("server.cors.max_age_seconds", 600)
+	viper.SetDefault("server.degraded_cache_ok", true)
+	viper.SetDefault("server.drain_timeout", "25s")
+
+	viper.SetDefault("tls.enabled", false)
+	viper.SetDefault("tls.cert_file", "")
+	viper.SetDefault("tls.key_file", "")
+	viper.SetDefault("tls.min_version", "TLS1.2")
+	viper.SetDefault("tls.auto_cert", false)
 
 	viper.SetDefault("database.type", "memory")
+	viper.SetDefault("database.query_timeout_ms", 5000)
+	viper.SetDefault("database.memory.max_capacity", 0)
 	viper.SetDefault("database.sqlite.path", "./data/dove.db")
+	viper.SetDefault("database.sqlite.journal_mode", "WAL")
+	viper.SetDefault("database.sqlite.synchronous", "NORMAL")
+	viper.SetDefault("database.sqlite.busy_timeout_ms", 5000)
 	viper.SetDefault("database.postgres.url", "")
+	viper.SetDefault("database.postgres.replica_url", "")
+	viper.SetDefault("database.postgres.driver", "pq")
+	viper.SetDefault("database.postgres.trgm_enabled", false)
+	viper.SetDefault("database.postgres.disable_prepare", false)
 
 	viper.SetDefault("app.base_url", "http://localhost:8080")
+	viper.SetDefault("app.short_url_scheme", "")
 	viper.SetDefault("app.short_code_length", 6)
+	viper.SetDefault("app.short_code_charset", "alphanumeric")
+	viper.SetDefault("app.custom_charset", "")
+	viper.SetDefault("app.suggest_enabled", false)
+	viper.SetDefault("app.fetch_titles", false)
+	viper.SetDefault("app.signing_secret", "")
+	viper.SetDefault("app.max_delay_seconds", 10)
+	viper.SetDefault("app.follow_redirect_chains", false)
+	viper.SetDefault("app.max_chain_depth", 3)
+	viper.SetDefault("app.chain_aliases", []string{})
+	viper.SetDefault("app.trust_forwarded_headers", false)
+	viper.SetDefault("app.allowed_hosts", []string{})
+	viper.SetDefault("app.robots_disallow_all", false)
+	viper.SetDefault("app.robots_custom", "")
+	viper.SetDefault("app.seed_file", "")
+	viper.SetDefault("app.cleanup_interval_minutes", 0)
+	viper.SetDefault("app.auto_disable_cold_after_days", 0)
+	viper.SetDefault("app.async_clicks", false)
+	viper.SetDefault("app.click_flush_interval_ms", 1000)
+	viper.SetDefault("app.custom_alias_policy.max_length", 20)
+	viper.SetDefault("app.custom_alias_policy.allow_hyphens", false)
+	viper.SetDefault("app.custom_alias_policy.allow_underscores", false)
+	viper.SetDefault("app.custom_alias_policy.reserved_words", []string{})
+	viper.SetDefault("app.create_hooks.blocked_domains", []string{})
+	viper.SetDefault("app.create_hooks.webhook_url", "")
+	viper.SetDefault("app.create_hooks.webhook_timeout", "5s")
+	viper.SetDefault("app.custom_404.enabled", false)
+	viper.SetDefault("app.custom_404.file_path", "")
+	viper.SetDefault("app.custom_404.remote_url", "")
+	viper.SetDefault("app.custom_404.cache_duration_seconds", 0)
 
 	viper.SetDefault("logging.level", "info")
+	viper.SetDefault("logging.format", "json")
+	viper.SetDefault("logging.sample_rate", 1.0)
 
 	viper.SetDefault("cache.enabled", true)
+	viper.SetDefault("cache.backend", "redis")
+	viper.SetDefault("cache.file_dir", "./data/cache")
 	viper.SetDefault("cache.redis.url", "redis://localhost:6379")
 	viper.SetDefault("cache.redis.password", "")
 	viper.SetDefault("cache.redis.db", 0)
@@ -106,15 +414,49 @@ func Load() (*Config, error) {
 	viper.SetDefault("cache.redis.max_retries", 3)
 	viper.SetDefault("cache.redis.read_timeout", "3s")
 	viper.SetDefault("cache.redis.write_timeout", "3s")
+	viper.SetDefault("cache.stale_on_error", false)
 	viper.SetDefault("cache.ttl", "10m")
+	viper.SetDefault("cache.key_prefix", "dove")
+	viper.SetDefault("cache.warm_enabled", false)
+	viper.SetDefault("cache.warm_count", 100)
+	viper.SetDefault("cache.jitter_percent", 10)
 
 	viper.SetDefault("metrics.enabled", true)
 	viper.SetDefault("metrics.path", "/metrics")
 	viper.SetDefault("metrics.namespace", "dove")
 	viper.SetDefault("metrics.subsystem", "urlshortener")
+	viper.SetDefault("metrics.track_top_n_codes", 50)
 	viper.SetDefault("metrics.collect_runtime", true)
 	viper.SetDefault("metrics.collect_database", true)
 	viper.SetDefault("metrics.collect_cache", true)
+	viper.SetDefault("metrics.backend", "prometheus")
+	viper.SetDefault("metrics.otlp_endpoint", "")
+	viper.SetDefault("metrics.const_labels", map[string]string{})
+
+	viper.SetDefault("audit.log_path", "")
+
+	viper.SetDefault("admin.export_enabled", false)
+	viper.SetDefault("admin.api_key", "")
+	viper.SetDefault("admin.expose_cache", false)
+
+	viper.SetDefault("geo.country_header", "")
+
+	viper.SetDefault("security.hmac_enabled", false)
+	viper.SetDefault("security.hmac_secret", "")
+
+	viper.SetDefault("analytics.deduplication_window_seconds", 1800)
+
+	viper.SetDefault("health_checker.enabled", false)
+	viper.SetDefault("health_checker.interval_minutes", 60)
+	viper.SetDefault("health_checker.batch_size", 100)
+	viper.SetDefault("health_checker.worker_count", 4)
+
+	viper.SetDefault("rate_limit.enabled", false)
+	viper.SetDefault("rate_limit.algorithm", "token_bucket")
+	viper.SetDefault("rate_limit.requests_per_second", 10)
+	viper.SetDefault("rate_limit.burst", 20)
+	viper.SetDefault("rate_limit.max_ips", 10000)
+	viper.SetDefault("rate_limit.trust_proxy_headers", false)
 
 	// Read config file if it exists
 	if err := viper.ReadInConfig(); err != nil {
@@ -128,9 +470,103 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("unable to decode config: %w", err)
 	}
 
+	config.Metrics.ConstLabels = withEnvConstLabels(config.Metrics.ConstLabels, os.Environ())
+
+	if err := validate(&config); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
+// constLabelsEnvPrefix starts the environment variables setting a constant metrics label.
+// Viper only reads the environment for keys it already knows, which labels are not.
+const constLabelsEnvPrefix = "METRICS_CONST_LABELS_"
+
+// withEnvConstLabels adds to labels every label set in environ, each named after its
+// variable in lower case
+func withEnvConstLabels(labels map[string]string, environ []string) map[string]string {
+	for _, entry := range environ {
+		key, value, _ := strings.Cut(entry, "=")
+		name, ok := strings.CutPrefix(key, constLabelsEnvPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[strings.ToLower(name)] = value
+	}
+	return labels
+}
+
+// validate checks the decoded config against its validate tags, reporting every
+// violation at once by its config key, e.g. "database.type".
+func validate(config *Config) error {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("mapstructure")
+	})
+	_ = v.RegisterValidation("duration", func(fl validator.FieldLevel) bool {
+		_, err := time.ParseDuration(fl.Field().String())
+		return err == nil
+	})
+	_ = v.RegisterValidation("port", func(fl validator.FieldLevel) bool {
+		port, err := strconv.Atoi(fl.Field().String())
+		return err == nil && port >= 1 && port <= 65535
+	})
+	// required_if only rejects nil slices, while the default of allowed_hosts is empty
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		app := sl.Current().Interface().(AppConfig)
+		if app.TrustForwardedHeaders && len(app.AllowedHosts) == 0 {
+			sl.ReportError(app.AllowedHosts, "allowed_hosts", "AllowedHosts", "required_if", "TrustForwardedHeaders true")
+		}
+	}, AppConfig{})
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		page := sl.Current().Interface().(Custom404Config)
+		if page.Enabled && page.FilePath == "" && page.RemoteURL == "" {
+			sl.ReportError(page.FilePath, "file_path", "FilePath", "required_if", "Enabled true")
+		}
+	}, Custom404Config{})
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		server := sl.Current().Interface().(ServerConfig)
+		if server.DefaultAPIVersion != "" && len(server.SupportedVersions) > 0 && !slices.Contains(server.SupportedVersions, server.DefaultAPIVersion) {
+			sl.ReportError(server.DefaultAPIVersion, "default_api_version", "DefaultAPIVersion", "oneof", strings.Join(server.SupportedVersions, " "))
+		}
+	}, ServerConfig{})
+
+	err := v.Struct(config)
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	violations := make([]string, 0, len(validationErrors))
+	for _, e := range validationErrors {
+		key := strings.TrimPrefix(e.Namespace(), "Config.")
+		switch e.Tag() {
+		case "required", "required_if":
+			violations = append(violations, fmt.Sprintf("%s is required", key))
+		case "oneof":
+			violations = append(violations, fmt.Sprintf("%s must be one of: %s, got %q", key, strings.ReplaceAll(e.Param(), " ", ", "), e.Value()))
+		case "duration":
+			violations = append(violations, fmt.Sprintf("%s must be a duration such as 15s, got %q", key, e.Value()))
+		case "min":
+			violations = append(violations, fmt.Sprintf("%s must be at least %s, got %v", key, e.Param(), e.Value()))
+		case "max":
+			violations = append(violations, fmt.Sprintf("%s must be at most %s, got %v", key, e.Param(), e.Value()))
+		case "gt":
+			violations = append(violations, fmt.Sprintf("%s must be greater than %s, got %v", key, e.Param(), e.Value()))
+		case "port":
+			violations = append(violations, fmt.Sprintf("%s must be a port number between 1 and 65535, got %q", key, e.Value()))
+		default:
+			violations = append(violations, fmt.Sprintf("%s is invalid", key))
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(violations, "; "))
+}
+
 func (c *Config) GetDatabaseURL() string {
 	switch c.Database.Type {
 	case "sqlite":
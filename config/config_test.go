@@ -0,0 +1,265 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Defaults(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "memory", cfg.Database.Type)
+	assert.Equal(t, "8080", cfg.Server.Port)
+	assert.Equal(t, "prometheus", cfg.Metrics.Backend)
+	assert.Equal(t, map[string]string{"redirect": "5s", "shorten": "15s", "import": "30s"}, cfg.Server.RouteTimeouts)
+}
+
+func TestLoad_ConstLabelsFromEnv(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv("METRICS_CONST_LABELS_ENV", "prod")
+	t.Setenv("METRICS_CONST_LABELS_REGION", "eu-west-1")
+	t.Setenv("METRICS_CONST_LABELS_", "ignored")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "region": "eu-west-1"}, cfg.Metrics.ConstLabels)
+}
+
+func TestLoad_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		message string
+	}{
+		{
+			name:    "misspelled database type",
+			env:     map[string]string{"DATABASE_TYPE": "postgress"},
+			message: `database.type must be one of: memory, sqlite, postgres, got "postgress"`,
+		},
+		{
+			name:    "empty database type",
+			env:     map[string]string{"DATABASE_TYPE": " "},
+			message: "database.type must be one of",
+		},
+		{
+			name:    "non numeric port",
+			env:     map[string]string{"SERVER_PORT": "http"},
+			message: `server.port must be a port number between 1 and 65535, got "http"`,
+		},
+		{
+			name:    "port out of range",
+			env:     map[string]string{"SERVER_PORT": "70000"},
+			message: "server.port must be a port number",
+		},
+		{
+			name:    "zero port",
+			env:     map[string]string{"SERVER_PORT": "0"},
+			message: "server.port must be a port number",
+		},
+		{
+			name:    "invalid cache ttl",
+			env:     map[string]string{"CACHE_TTL": "ten minutes"},
+			message: `cache.ttl must be a duration such as 15s, got "ten minutes"`,
+		},
+		{
+			name:    "invalid server timeout",
+			env:     map[string]string{"SERVER_READ_TIMEOUT": "15"},
+			message: "server.read_timeout must be a duration",
+		},
+		{
+			name:    "invalid drain timeout",
+			env:     map[string]string{"SERVER_DRAIN_TIMEOUT": "soon"},
+			message: "server.drain_timeout must be a duration",
+		},
+		{
+			name:    "invalid route timeout",
+			env:     map[string]string{"SERVER_ROUTE_TIMEOUTS_REDIRECT": "fast"},
+			message: `server.route_timeouts[redirect] must be a duration such as 15s, got "fast"`,
+		},
+		{
+			name:    "compression level out of range",
+			env:     map[string]string{"SERVER_COMPRESSION_LEVEL": "12"},
+			message: "server.compression.level must be at most 9, got 12",
+		},
+		{
+			name:    "unknown api version",
+			env:     map[string]string{"SERVER_DEFAULT_API_VERSION": "v3"},
+			message: `server.default_api_version must be one of: v1, v2, got "v3"`,
+		},
+		{
+			name:    "default api version not supported",
+			env:     map[string]string{"SERVER_SUPPORTED_VERSIONS": "v2"},
+			message: `server.default_api_version must be one of: v2, got "v1"`,
+		},
+		{
+			name:    "unknown sqlite journal mode",
+			env:     map[string]string{"DATABASE_SQLITE_JOURNAL_MODE": "wal2"},
+			message: `database.sqlite.journal_mode must be one of: DELETE, TRUNCATE, PERSIST, MEMORY, WAL, OFF, got "wal2"`,
+		},
+		{
+			name:    "negative sqlite busy timeout",
+			env:     map[string]string{"DATABASE_SQLITE_BUSY_TIMEOUT_MS": "-1"},
+			message: "database.sqlite.busy_timeout_ms must be at least 0, got -1",
+		},
+		{
+			name:    "unknown log format",
+			env:     map[string]string{"LOGGING_FORMAT": "xml"},
+			message: "logging.format must be one of: json, text",
+		},
+		{
+			name:    "log sample rate above 1",
+			env:     map[string]string{"LOGGING_SAMPLE_RATE": "1.5"},
+			message: "logging.sample_rate must be at most 1, got 1.5",
+		},
+		{
+			name:    "negative query timeout",
+			env:     map[string]string{"DATABASE_QUERY_TIMEOUT_MS": "-1"},
+			message: "database.query_timeout_ms must be at least 0, got -1",
+		},
+		{
+			name:    "unknown postgres driver",
+			env:     map[string]string{"DATABASE_POSTGRES_DRIVER": "pgsql"},
+			message: `database.postgres.driver must be one of: pq, pgx, got "pgsql"`,
+		},
+		{
+			name:    "unknown short code charset",
+			env:     map[string]string{"APP_SHORT_CODE_CHARSET": "base64"},
+			message: `app.short_code_charset must be one of: alphanumeric, safe, hex, custom, got "base64"`,
+		},
+		{
+			name:    "unknown short URL scheme",
+			env:     map[string]string{"APP_SHORT_URL_SCHEME": "ftp"},
+			message: `app.short_url_scheme must be one of: http, https, got "ftp"`,
+		},
+		{
+			name:    "forwarded headers trusted without allowed hosts",
+			env:     map[string]string{"APP_TRUST_FORWARDED_HEADERS": "true"},
+			message: "app.allowed_hosts is required",
+		},
+		{
+			name:    "custom charset without characters",
+			env:     map[string]string{"APP_SHORT_CODE_CHARSET": "custom"},
+			message: "app.custom_charset is required",
+		},
+		{
+			name:    "request signing without a secret",
+			env:     map[string]string{"SECURITY_HMAC_ENABLED": "true"},
+			message: "security.hmac_secret is required",
+		},
+		{
+			name:    "custom 404 page without a source",
+			env:     map[string]string{"APP_CUSTOM_404_ENABLED": "true"},
+			message: "app.custom_404.file_path is required",
+		},
+		{
+			name:    "redirect delay above the cap",
+			env:     map[string]string{"APP_MAX_DELAY_SECONDS": "90"},
+			message: "app.max_delay_seconds must be at most 60, got 90",
+		},
+		{
+			name:    "negative number of tracked short codes",
+			env:     map[string]string{"METRICS_TRACK_TOP_N_CODES": "-1"},
+			message: "metrics.track_top_n_codes must be at least 0, got -1",
+		},
+		{
+			name:    "too many URLs to warm",
+			env:     map[string]string{"CACHE_WARM_COUNT": "20000"},
+			message: "cache.warm_count must be at most 10000, got 20000",
+		},
+		{
+			name:    "cache jitter above the cap",
+			env:     map[string]string{"CACHE_JITTER_PERCENT": "75"},
+			message: "cache.jitter_percent must be at most 50, got 75",
+		},
+		{
+			name:    "unknown rate limit algorithm",
+			env:     map[string]string{"RATE_LIMIT_ALGORITHM": "leaky_bucket"},
+			message: `rate_limit.algorithm must be one of: sliding_window, token_bucket, got "leaky_bucket"`,
+		},
+		{
+			name:    "rate limit without requests",
+			env:     map[string]string{"RATE_LIMIT_REQUESTS_PER_SECOND": "0"},
+			message: "rate_limit.requests_per_second must be greater than 0, got 0",
+		},
+		{
+			name:    "custom alias longer than the short code columns",
+			env:     map[string]string{"APP_CUSTOM_ALIAS_POLICY_MAX_LENGTH": "32"},
+			message: "app.custom_alias_policy.max_length must be at most 20, got 32",
+		},
+		{
+			name:    "negative cleanup interval",
+			env:     map[string]string{"APP_CLEANUP_INTERVAL_MINUTES": "-5"},
+			message: "app.cleanup_interval_minutes must be at least 0, got -5",
+		},
+		{
+			name:    "negative cold URL age",
+			env:     map[string]string{"APP_AUTO_DISABLE_COLD_AFTER_DAYS": "-1"},
+			message: "app.auto_disable_cold_after_days must be at least 0, got -1",
+		},
+		{
+			name:    "zero click flush interval",
+			env:     map[string]string{"APP_CLICK_FLUSH_INTERVAL_MS": "0"},
+			message: "app.click_flush_interval_ms must be at least 1, got 0",
+		},
+		{
+			name:    "redirect chain depth above the cap",
+			env:     map[string]string{"APP_MAX_CHAIN_DEPTH": "20"},
+			message: "app.max_chain_depth must be at most 10, got 20",
+		},
+		{
+			name:    "unknown cache backend",
+			env:     map[string]string{"CACHE_BACKEND": "memcached"},
+			message: `cache.backend must be one of: redis, file, got "memcached"`,
+		},
+		{
+			name:    "negative deduplication window",
+			env:     map[string]string{"ANALYTICS_DEDUPLICATION_WINDOW_SECONDS": "-1"},
+			message: "analytics.deduplication_window_seconds must be at least 0, got -1",
+		},
+		{
+			name:    "unknown metrics backend",
+			env:     map[string]string{"METRICS_BACKEND": "statsd"},
+			message: `metrics.backend must be one of: prometheus, otel, got "statsd"`,
+		},
+		{
+			name:    "otel backend without an endpoint",
+			env:     map[string]string{"METRICS_BACKEND": "otel"},
+			message: "metrics.otlp_endpoint is required",
+		},
+		{
+			name: "every violation is reported",
+			env: map[string]string{
+				"DATABASE_TYPE": "mongo",
+				"SERVER_PORT":   "-1",
+				"CACHE_TTL":     "soon",
+			},
+			message: "server.port must be a port number between 1 and 65535",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := Load()
+			require.ErrorIs(t, err, ErrInvalidConfig)
+			assert.Nil(t, cfg)
+			assert.Contains(t, err.Error(), tt.message)
+			if len(tt.env) > 1 {
+				assert.Contains(t, err.Error(), "database.type")
+				assert.Contains(t, err.Error(), "cache.ttl")
+			}
+		})
+	}
+}
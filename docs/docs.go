@@ -15,63 +15,140 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/health": {
+        "/admin/analytics/top-ips": {
             "get": {
-                "description": "Check if the service is running",
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "List the client IP addresses that made the most clicks across every short URL, busiest first. IPv4-mapped IPv6 addresses are counted as plain IPv4, and clicks without an address are left out. Only available when admin.api_key is set.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "admin"
+                ],
+                "summary": "List top source IPs",
+                "parameters": [
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Maximum number of addresses",
+                        "name": "n",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Health check endpoint",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Click counts per IP address, busiest first",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.IPCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid n",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
                         }
                     }
                 }
             }
         },
-        "/ready": {
+        "/admin/cache/keys": {
             "get": {
-                "description": "Check if the service is ready to serve requests (includes database connectivity)",
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "List a page of the cached keys matching a glob pattern, without the key prefix, such as url:{namespace}:{shortCode}. Pass the nextCursor of a page as cursor to get the next; 0 starts the listing and, returned, ends it. Pages can be empty before the end, and count is only a hint of their size. Redis is scanned without blocking it. Only available when admin.api_key is set and admin.expose_cache is true.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "admin"
+                ],
+                "summary": "List cache keys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "*",
+                        "description": "Glob pattern of the keys",
+                        "name": "pattern",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Cursor of the page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Keys to scan for the page",
+                        "name": "count",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Readiness check endpoint",
                 "responses": {
                     "200": {
-                        "description": "Service is ready",
+                        "description": "Page of cache keys",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "status": {
-                                    "type": "string"
-                                },
-                                "timestamp": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/internal_adapters_http.CacheKeysResponse"
                         }
                     },
-                    "503": {
-                        "description": "Service is not ready",
+                    "400": {
+                        "description": "Invalid cursor or count",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapters_http.ErrorResponse"
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
                         }
                     }
                 }
             }
         },
-        "/shorten": {
+        "/admin/cache/migrate-keys": {
             "post": {
-                "description": "Create a shortened URL from a long URL",
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Rename every Redis key starting with oldPrefix to start with newPrefix instead, ahead of changing cache.key_prefix. Keys already present under newPrefix are kept. Only available when admin.api_key is set and the Redis cache is enabled.",
                 "consumes": [
                     "application/json"
                 ],
@@ -79,188 +156,4164 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "admin"
                 ],
-                "summary": "Create a short URL",
+                "summary": "Migrate cache key prefix",
                 "parameters": [
                     {
-                        "description": "URL to shorten",
+                        "description": "Prefixes to migrate between",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.CreateURLRequest"
+                            "$ref": "#/definitions/internal_adapters_http.MigrateCacheKeysRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Successfully created short URL",
+                    "200": {
+                        "description": "Number of keys renamed",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLResponse"
+                            "$ref": "#/definitions/internal_adapters_http.MigrateCacheKeysResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request or validation error",
+                        "description": "Missing or overlapping prefixes",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapters_http.ValidationErrorResponse"
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
                         }
                     },
-                    "409": {
-                        "description": "Short code already exists",
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapters_http.ErrorResponse"
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
                         }
                     }
                 }
             }
         },
-        "/{shortCode}": {
-            "get": {
-                "description": "Redirect to the original URL using the short code. GET requests increment click count and redirect, HEAD requests only check existence without incrementing clicks.\nCheck if a short URL exists without incrementing click count or following the redirect. Returns the same redirect headers as GET but without response body.",
+        "/admin/funnels": {
+            "post": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Define an ordered sequence of existing short URLs whose visitors are followed, through the dove_sid cookie, from one step to the next. Only available when admin.api_key is set and the repository keeps funnels.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "urls",
-                    "urls"
+                    "admin"
                 ],
-                "summary": "Check short URL existence",
+                "summary": "Create a funnel",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Short code",
-                        "name": "shortCode",
-                        "in": "path",
-                        "required": true
+                        "description": "Funnel to create",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.CreateFunnelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created funnel",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.Funnel"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, validation error or unknown step",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ValidationProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
                     },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/funnels/{id}/analytics": {
+            "get": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "Short code",
-                        "name": "shortCode",
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Count, for each step of a funnel, the sessions that reached it and every step before it, and their share of the sessions that entered the funnel. Only available when admin.api_key is set and the repository keeps funnels.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get funnel analytics",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Funnel ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "301": {
-                        "description": "Short URL exists and would redirect"
+                    "200": {
+                        "description": "Sessions and completion rate per step",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.FunnelAnalyticsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid funnel ID",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
                     },
                     "404": {
-                        "description": "Short URL not found",
+                        "description": "Funnel not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapters_http.ErrorResponse"
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
                         }
                     }
                 }
-            },
-            "head": {
-                "description": "Redirect to the original URL using the short code. GET requests increment click count and redirect, HEAD requests only check existence without incrementing clicks.\nCheck if a short URL exists without incrementing click count or following the redirect. Returns the same redirect headers as GET but without response body.",
+            }
+        },
+        "/admin/migrate-store": {
+            "post": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Copy every URL of the running store into the store of type ` + "`" + `to` + "`" + `, configured under database like the running one, ahead of restarting the service on it. URLs whose short code the target already holds are skipped. Click counters are copied, recorded clicks are not. Only one migration runs at a time.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "urls",
-                    "urls"
+                    "admin"
                 ],
-                "summary": "Check short URL existence",
+                "summary": "Migrate URLs to another store",
                 "parameters": [
                     {
+                        "enum": [
+                            "memory",
+                            "sqlite",
+                            "postgres"
+                        ],
                         "type": "string",
-                        "description": "Short code",
-                        "name": "shortCode",
-                        "in": "path",
+                        "description": "Type of the running store",
+                        "name": "from",
+                        "in": "query",
                         "required": true
                     },
                     {
+                        "enum": [
+                            "sqlite",
+                            "postgres"
+                        ],
                         "type": "string",
-                        "description": "Short code",
-                        "name": "shortCode",
-                        "in": "path",
+                        "description": "Type of the target store",
+                        "name": "to",
+                        "in": "query",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "301": {
-                        "description": "Short URL exists and would redirect"
+                    "200": {
+                        "description": "Migration report",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.StoreMigrationReport"
+                        }
                     },
-                    "404": {
-                        "description": "Short URL not found",
+                    "400": {
+                        "description": "Unsupported from or to store",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
                         "schema": {
-                            "$ref": "#/definitions/internal_adapters_http.ErrorResponse"
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "409": {
+                        "description": "A store migration is already running",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "github_com_sp3dr4_dove_internal_application.CreateURLRequest": {
-            "type": "object",
-            "required": [
-                "url"
-            ],
-            "properties": {
-                "customAlias": {
-                    "type": "string",
-                    "maxLength": 20,
-                    "minLength": 3
-                },
-                "url": {
-                    "type": "string"
-                }
-            }
-        },
-        "github_com_sp3dr4_dove_internal_application.URLResponse": {
-            "type": "object",
-            "properties": {
-                "clicks": {
-                    "type": "integer"
-                },
-                "createdAt": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "originalUrl": {
-                    "type": "string"
-                },
-                "shortCode": {
-                    "type": "string"
-                },
-                "shortUrl": {
-                    "type": "string"
-                },
-                "updatedAt": {
-                    "type": "string"
-                }
-            }
         },
-        "internal_adapters_http.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
+        "/admin/migrations": {
+            "get": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "List every schema migration with whether it is applied. A dirty migration failed part way and has to be repaired by hand. Only available when admin.api_key is set and the database is SQL based.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List schema migrations",
+                "responses": {
+                    "200": {
+                        "description": "Schema migrations in version order",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.MigrationStatus"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
                     }
-                },
-                "timestamp": {
-                    "type": "string",
-                    "example": "2024-01-31T12:00:00Z"
                 }
             }
         },
-        "internal_adapters_http.ValidationErrorResponse": {
-            "type": "object",
-            "properties": {
-                "details": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
+        "/admin/migrations/down": {
+            "post": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
                     }
-                },
-                "error": {
-                    "type": "string",
-                    "example": "Validation failed"
-                }
+                ],
+                "description": "Roll back the latest schema migrations and list the migrations afterwards. Rolling back more migrations than are applied leaves an empty schema.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Roll back schema migrations",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Number of migrations to roll back",
+                        "name": "steps",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Schema migrations in version order",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.MigrationStatus"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid steps",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "409": {
+                        "description": "Schema is dirty",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/migrations/up": {
+            "post": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Apply every pending schema migration and list the migrations afterwards.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Apply schema migrations",
+                "responses": {
+                    "200": {
+                        "description": "Schema migrations in version order",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.MigrationStatus"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "409": {
+                        "description": "Schema is dirty",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/stats": {
+            "get": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Count URLs and clicks across every namespace. The \"today\" counters cover the last 24 hours and the cache hit rate covers URL lookups since the process started. The in-memory repository also reports how full it is. Only available when admin.api_key is set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Service statistics",
+                "responses": {
+                    "200": {
+                        "description": "Service statistics",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.StatsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/stats/aliases": {
+            "get": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Count the URLs of every namespace with generated short codes, per code length, and those with custom aliases, with their shortest, longest and average length. URLs created before custom aliases were recorded count as generated. Only available when admin.api_key is set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Alias length statistics",
+                "responses": {
+                    "200": {
+                        "description": "Short code lengths",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.AliasStatsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/stats/daily": {
+            "get": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Count the URLs created and the clicks made across every namespace on each UTC day of a range, days without either included. Ranges may span at most 365 days. Only available when admin.api_key is set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Daily statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day as YYYY-MM-DD, defaults to 29 days before to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day as YYYY-MM-DD (inclusive), defaults to today",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Counts per day, oldest first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.DailyCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid date range",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/urls": {
+            "get": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "List the short URLs in ID order, a page at a time: pass the id of the last URL of a page as after to get the next one. With created_by_ip only the URLs created from that address are listed. Creator addresses are shown unmasked. Only available when admin.api_key is set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List URLs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Address the URLs were created from",
+                        "name": "created_by_ip",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "List the URLs with a greater ID",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of URLs",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URLs in ID order",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLInfoResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid created_by_ip, after or limit",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/urls/cold": {
+            "get": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "List the enabled short URLs not clicked in the past days, least recently active first. URLs never clicked count from their creation. Only available when admin.api_key is set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List cold URLs",
+                "parameters": [
+                    {
+                        "maximum": 3650,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Days without a click",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of URLs",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cold URLs, least recently active first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLInfoResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid days or limit",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/urls/{shortCode}/audit-log": {
+            "get": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "List the recorded changes of a short URL, most recent first: its creation, updates, analytics resets, disabling and enabling, and deletion. Each entry holds the URL before and after the change, without its password hash. The history outlives the URL, so deleted URLs still have one. Only available when admin.api_key is set and the database keeps the history, PostgreSQL or memory.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get the audit log of a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    },
+                    {
+                        "maximum": 500,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of entries",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Changes of the URL, most recent first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.AuditEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid namespace or limit",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/urls/{shortCode}/disable": {
+            "patch": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Suspend a short URL without deleting it: its redirects answer 410 Gone until it is enabled again. Disabling a disabled URL does nothing. Only available when admin.api_key is set.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Disable a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "URL disabled"
+                    },
+                    "400": {
+                        "description": "Invalid namespace",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/urls/{shortCode}/enable": {
+            "patch": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Restore the redirects of a disabled short URL. Enabling an enabled URL does nothing. Only available when admin.api_key is set.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Enable a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "URL enabled"
+                    },
+                    "400": {
+                        "description": "Invalid namespace",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Check if the service is running",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Health check endpoint",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ready": {
+            "get": {
+                "description": "Check if the service is ready to serve requests. The database must be reachable; an unreachable cache reports \"degraded\" and, unless server.degraded_cache_ok is false, still answers 200.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness check endpoint",
+                "responses": {
+                    "200": {
+                        "description": "Service is ready, possibly with a degraded cache",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ReadinessResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Database or, when degraded_cache_ok is false, cache is not reachable",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten": {
+            "post": {
+                "description": "Create a shortened URL from a long URL. With dryRun=true the request is checked and the URL it would create returned with a 200, dryRun set, without storing it; the short code may be taken by another creation before the URL is actually created. With signedExpiry the returned shortCode is a signed token that stops resolving once it expires. Behind a proxy trusted by app.trust_forwarded_headers, shortUrl is under the X-Forwarded-Host of the request when it is one of app.allowed_hosts. Requests with Accept: application/vnd.dove.v2+json, or every request when server.default_api_version is v2, use version 2 of the API: the body may also set tags, expiresAt and redirectType, and the response is the full URL metadata of GET /shorten/{shortCode}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/vnd.dove.v2+json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Create a short URL",
+                "parameters": [
+                    {
+                        "description": "URL to shorten, plus tags, expiresAt and redirectType in version 2",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.CreateURLRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Namespace for the short code when the body names none",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "application/vnd.dove.v2+json for version 2 of the API",
+                        "name": "Accept",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Check the request without creating the URL",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hex encoded HMAC-SHA256 of the body, required when security.hmac_enabled is set",
+                        "name": "X-Dove-Signature",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL the request would create, with dryRun set",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Successfully created short URL, as application.URLInfoResponse in version 2",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or validation error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ValidationProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Signature missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "406": {
+                        "description": "Unsupported API version",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "409": {
+                        "description": "Short code already exists",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "422": {
+                        "description": "URL points to a private network or a blocked domain, alias starts with a digit or alias is a reserved word",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.SchemaProblemDetail"
+                        }
+                    },
+                    "504": {
+                        "description": "Request exceeded its route timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/suggest": {
+            "get": {
+                "description": "Fetch the destination page and propose up to three unused aliases derived from its title. Pages that cannot be fetched yield no suggestions.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Suggest custom aliases",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Destination URL",
+                        "name": "url",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Suggested aliases, possibly none",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.AliasSuggestionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing, invalid or private url",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}": {
+            "get": {
+                "description": "Return the full metadata and statistics of a short URL without following the redirect or counting a click",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Get short URL details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Short URL metadata",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLInfoResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Change only the fields present in the body, following JSON merge patch. A null expiresAt removes the expiry. Only available when admin.api_key is set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Update a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.PatchURLRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated short URL",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLInfoResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or validation error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ValidationProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key, or password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/analytics/browsers": {
+            "get": {
+                "description": "Count clicks on a short URL per browser",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Browser breakdown",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click counts per browser, busiest first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.DeviceStat"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/analytics/devices": {
+            "get": {
+                "description": "Count clicks on a short URL per device type, browser and operating system combination, as parsed from the User-Agent header",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Device breakdown",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click counts per device, busiest first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.DeviceStat"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/analytics/heatmap": {
+            "get": {
+                "description": "Count every click on a short URL by hour of the day (0-23) and day of the week (0 is Sunday) in the requested time zone",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Click heatmap",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "IANA time zone the clicks are bucketed in",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click counts per hour and weekday",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.ClickHeatmap"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown time zone",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/analytics/latency": {
+            "get": {
+                "description": "Percentiles and average, in milliseconds, of how long redirects of a short URL took from request receipt to the redirect. Clicks recorded before latencies were measured are left out.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Redirect latency",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Redirect latency distribution",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.LatencyStats"
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/analytics/os": {
+            "get": {
+                "description": "Count clicks on a short URL per operating system",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Operating system breakdown",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click counts per operating system, busiest first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.DeviceStat"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/analytics/referrers": {
+            "get": {
+                "description": "List the sites that sent the most clicks to a short URL. Referrers are reduced to host and path; clicks without a Referer header are reported as (direct).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Top referrers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Maximum number of referrers",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click counts per referrer, busiest first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.ReferrerCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/analytics/reset": {
+            "post": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Zero the click counters of a short URL and delete its recorded clicks, keeping the URL. Only available when admin.api_key is set.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset analytics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Analytics reset"
+                    },
+                    "400": {
+                        "description": "Invalid namespace",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/analytics/timeseries": {
+            "get": {
+                "description": "Count clicks on a short URL per minute, hour, day or week. Ranges may span at most 90 days.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Click time series",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "minute",
+                            "hour",
+                            "day",
+                            "week"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Bucket width",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range start as YYYY-MM-DD or RFC 3339, defaults to 30 days before to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range end as YYYY-MM-DD (inclusive) or RFC 3339, defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click counts per period",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.TimeBucket"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid granularity or date range",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/analytics/variants": {
+            "get": {
+                "description": "Count the clicks sent to each variant of an A/B tested short URL, in creation order. URLs without variants report an empty list.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Variant breakdown",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click counts per variant",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.VariantStat"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/clicks/export": {
+            "get": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Stream every recorded click of a short URL, oldest first, as a CSV or NDJSON attachment. Exports stop after 1,000,000 clicks, in which case the response ends with an X-Truncated: true trailer. Only available when admin.api_key and admin.export_enabled are set.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Export the clicks of a URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Exported clicks",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_adapters_http.ClickExportRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported format",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key, or password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/events": {
+            "get": {
+                "description": "Open a server-sent events stream that emits a JSON event every time the short URL is clicked",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Stream click events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream; each data line is a JSON click event",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many open event streams",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/health": {
+            "get": {
+                "description": "Report the last known health of the destination URL as recorded by the background checker. The destination is not probed by this request.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Destination health",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Last known destination health",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLHealthResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten/{shortCode}/preview": {
+            "get": {
+                "description": "Show where a short URL points without following the redirect or counting a click. Supports conditional requests via ETag and Last-Modified.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Preview a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from a previous response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified from a previous response",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Short URL details",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified since the supplied validators"
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/urls/batch-lookup": {
+            "post": {
+                "description": "Return the metadata of up to 50 short URLs of a namespace in one request, keyed by short code. Short codes matching no URL, or a signed or password protected one, are listed in notFound and do not fail the request.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Look up URLs in bulk",
+                "parameters": [
+                    {
+                        "description": "Short codes to look up",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.BatchLookupRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short codes",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Batch lookup result",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.BatchLookupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or validation error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ValidationProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/urls/bulk": {
+            "delete": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Delete up to 500 short URLs of a namespace in one request, together with their variants and recorded clicks. Short codes matching no URL are listed in the result and do not fail the request. Only available when admin.api_key is set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete URLs in bulk",
+                "parameters": [
+                    {
+                        "description": "Short codes to delete",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.BulkDeleteRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short codes",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Bulk delete result",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.BulkDeleteResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or validation error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ValidationProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/urls/export": {
+            "get": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Stream every short URL as a CSV or JSON attachment. Only available when admin.api_key and admin.export_enabled are set.",
+                "produces": [
+                    "text/csv",
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export all URLs",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "json"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Exported URLs",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_adapters_http.ExportRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported format",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/urls/import": {
+            "post": {
+                "security": [
+                    {
+                        "AdminAPIKey": []
+                    }
+                ],
+                "description": "Create up to 10000 short URLs in one request, either from a multipart ` + "`" + `file` + "`" + ` field holding one CreateURLRequest JSON object per line, or from a JSON array body. Entries that fail are listed in the report and do not stop the import. Only available when admin.api_key is set.",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Import URLs in bulk",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "NDJSON file, one CreateURLRequest per line",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "description": "URLs to import",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.CreateURLRequest"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import report",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed body or too many entries",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin API key",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported content type",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "504": {
+                        "description": "Request exceeded its route timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/urls/top": {
+            "get": {
+                "description": "List the short URLs with the most clicks. The ranking is cached for a few seconds and refreshed after any click. Disabled, signed and password protected URLs are left out.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Top URLs",
+                "parameters": [
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of URLs",
+                        "name": "n",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URLs, most clicked first",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid n",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/{namespace}/{shortCode}": {
+            "get": {
+                "description": "Same as GET /{shortCode}, with the namespace taken from the path instead of the X-Namespace header\nSame as HEAD /{shortCode}, with the namespace taken from the path instead of the X-Namespace header",
+                "tags": [
+                    "urls",
+                    "urls"
+                ],
+                "summary": "Check short URL existence within a namespace",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Namespace",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Namespace",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Short URL exists",
+                        "headers": {
+                            "X-Clicks": {
+                                "type": "integer",
+                                "description": "Clicks counted so far"
+                            },
+                            "X-Created-At": {
+                                "type": "string",
+                                "description": "Creation time in RFC 3339"
+                            },
+                            "X-Original-URL": {
+                                "type": "string",
+                                "description": "Destination a GET would redirect to"
+                            }
+                        }
+                    },
+                    "301": {
+                        "description": "Redirect to original URL"
+                    },
+                    "302": {
+                        "description": "Redirect to a geo or device route, a variant of an A/B tested URL or the next target of a pool"
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "410": {
+                        "description": "Short URL disabled by an operator",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "504": {
+                        "description": "Request exceeded its route timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "508": {
+                        "description": "Redirect chain loops back to a short URL already visited",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Same as GET /{shortCode}, with the namespace taken from the path instead of the X-Namespace header\nSame as HEAD /{shortCode}, with the namespace taken from the path instead of the X-Namespace header",
+                "tags": [
+                    "urls",
+                    "urls"
+                ],
+                "summary": "Check short URL existence within a namespace",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Namespace",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Namespace",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Short URL exists",
+                        "headers": {
+                            "X-Clicks": {
+                                "type": "integer",
+                                "description": "Clicks counted so far"
+                            },
+                            "X-Created-At": {
+                                "type": "string",
+                                "description": "Creation time in RFC 3339"
+                            },
+                            "X-Original-URL": {
+                                "type": "string",
+                                "description": "Destination a GET would redirect to"
+                            }
+                        }
+                    },
+                    "301": {
+                        "description": "Redirect to original URL"
+                    },
+                    "302": {
+                        "description": "Redirect to a geo or device route, a variant of an A/B tested URL or the next target of a pool"
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "410": {
+                        "description": "Short URL disabled by an operator",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "504": {
+                        "description": "Request exceeded its route timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "508": {
+                        "description": "Redirect chain loops back to a short URL already visited",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        },
+        "/{shortCode}": {
+            "get": {
+                "description": "Redirect to the original URL using the short code. GET requests increment click count and redirect, HEAD requests only check existence without incrementing clicks. While the database is unreachable and cache.stale_on_error is enabled, stale cached URLs are still served with a Warning: 110 header. Redirects of funnel steps set the dove_sid session cookie, renewed for 30 minutes on every funnel click. With app.custom_404 enabled, browsers redirected from an unknown short code get its HTML page, while clients sending Accept: application/json still get the JSON problem.\nCheck if a short URL exists without incrementing click count or following the redirect. Answers 200 without a body, describing the redirect in X-Original-URL, X-Clicks and X-Created-At headers.",
+                "tags": [
+                    "urls",
+                    "urls"
+                ],
+                "summary": "Check short URL existence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Short URL exists",
+                        "headers": {
+                            "X-Clicks": {
+                                "type": "integer",
+                                "description": "Clicks counted so far"
+                            },
+                            "X-Created-At": {
+                                "type": "string",
+                                "description": "Creation time in RFC 3339"
+                            },
+                            "X-Original-URL": {
+                                "type": "string",
+                                "description": "Destination a GET would redirect to"
+                            }
+                        }
+                    },
+                    "301": {
+                        "description": "Redirect to original URL, or the status chosen as the URL's redirectType"
+                    },
+                    "302": {
+                        "description": "Redirect to a geo or device route, a variant of an A/B tested URL or the next target of a pool"
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "410": {
+                        "description": "Short URL disabled by an operator",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "504": {
+                        "description": "Request exceeded its route timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "508": {
+                        "description": "Redirect chain loops back to a short URL already visited",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Redirect to the original URL using the short code. GET requests increment click count and redirect, HEAD requests only check existence without incrementing clicks. While the database is unreachable and cache.stale_on_error is enabled, stale cached URLs are still served with a Warning: 110 header. Redirects of funnel steps set the dove_sid session cookie, renewed for 30 minutes on every funnel click. With app.custom_404 enabled, browsers redirected from an unknown short code get its HTML page, while clients sending Accept: application/json still get the JSON problem.\nCheck if a short URL exists without incrementing click count or following the redirect. Answers 200 without a body, describing the redirect in X-Original-URL, X-Clicks and X-Created-At headers.",
+                "tags": [
+                    "urls",
+                    "urls"
+                ],
+                "summary": "Check short URL existence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "shortCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "p",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected short URLs",
+                        "name": "X-URL-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Namespace of the short code",
+                        "name": "X-Namespace",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Short URL exists",
+                        "headers": {
+                            "X-Clicks": {
+                                "type": "integer",
+                                "description": "Clicks counted so far"
+                            },
+                            "X-Created-At": {
+                                "type": "string",
+                                "description": "Creation time in RFC 3339"
+                            },
+                            "X-Original-URL": {
+                                "type": "string",
+                                "description": "Destination a GET would redirect to"
+                            }
+                        }
+                    },
+                    "301": {
+                        "description": "Redirect to original URL, or the status chosen as the URL's redirectType"
+                    },
+                    "302": {
+                        "description": "Redirect to a geo or device route, a variant of an A/B tested URL or the next target of a pool"
+                    },
+                    "401": {
+                        "description": "Password missing or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "410": {
+                        "description": "Short URL disabled by an operator",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "504": {
+                        "description": "Request exceeded its route timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    },
+                    "508": {
+                        "description": "Redirect chain loops back to a short URL already visited",
+                        "schema": {
+                            "$ref": "#/definitions/internal_adapters_http.ProblemDetail"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_sp3dr4_dove_internal_application.AliasStatsResponse": {
+            "type": "object",
+            "properties": {
+                "custom": {
+                    "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.CustomAliasStats"
+                },
+                "generated": {
+                    "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.GeneratedAliasStats"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.AliasSuggestionsResponse": {
+            "type": "object",
+            "properties": {
+                "suggestions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "golangblog",
+                        "golangblog2"
+                    ]
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.BatchLookupRequest": {
+            "type": "object",
+            "required": [
+                "shortCodes"
+            ],
+            "properties": {
+                "shortCodes": {
+                    "type": "array",
+                    "maxItems": 50,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "abc123",
+                        "promo"
+                    ]
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.BatchLookupResponse": {
+            "type": "object",
+            "properties": {
+                "notFound": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "promo"
+                    ]
+                },
+                "results": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.URLInfoResponse"
+                    }
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.BulkDeleteRequest": {
+            "type": "object",
+            "required": [
+                "shortCodes"
+            ],
+            "properties": {
+                "shortCodes": {
+                    "type": "array",
+                    "maxItems": 500,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "abc123",
+                        "promo"
+                    ]
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.BulkDeleteResult": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "notFound": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "promo"
+                    ]
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.CreateFunnelRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "steps"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Spring signup"
+                },
+                "steps": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "minItems": 2,
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.CreateFunnelStep"
+                    }
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.CreateFunnelStep": {
+            "type": "object",
+            "required": [
+                "shortCode"
+            ],
+            "properties": {
+                "namespace": {
+                    "description": "defaults to \"default\"",
+                    "type": "string"
+                },
+                "shortCode": {
+                    "type": "string",
+                    "example": "step1"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.CreateURLRequest": {
+            "type": "object",
+            "properties": {
+                "clickGoal": {
+                    "description": "ClickGoal is the number of clicks after which the URL has reached its goal, announced\nonce as a goal event",
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1000
+                },
+                "customAlias": {
+                    "description": "letters and digits, and hyphens or underscores when the alias policy allows them",
+                    "type": "string",
+                    "minLength": 3
+                },
+                "delaySeconds": {
+                    "description": "DelaySeconds shows a countdown page for this long before redirecting, up to\napp.max_delay_seconds",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 5
+                },
+                "description": {
+                    "description": "Description is a free text label for operators managing many links",
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "Spring launch campaign"
+                },
+                "deviceRoutes": {
+                    "description": "DeviceRoutes send mobile, tablet or desktop visitors to their own destination.\nGeo routes take priority.",
+                    "type": "array",
+                    "maxItems": 3,
+                    "uniqueItems": true,
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.DeviceRoute"
+                    }
+                },
+                "geoRoutes": {
+                    "description": "GeoRoutes send visitors from the listed countries to their own destination",
+                    "type": "array",
+                    "maxItems": 50,
+                    "uniqueItems": true,
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.GeoRoute"
+                    }
+                },
+                "namespace": {
+                    "description": "defaults to \"default\"",
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 4
+                },
+                "pool": {
+                    "description": "replaces url to load balance across several destinations",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.Pool"
+                        }
+                    ]
+                },
+                "signedExpiry": {
+                    "description": "SignedExpiry makes the URL reachable only through a signed token valid for this\nlong, such as \"2h\". The token is returned as shortCode.",
+                    "type": "string",
+                    "example": "2h"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.Variant"
+                    }
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.CustomAliasStats": {
+            "type": "object",
+            "properties": {
+                "avgLength": {
+                    "type": "number",
+                    "example": 8.5
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 34
+                },
+                "maxLength": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "minLength": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.DailyCount": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer",
+                    "example": 1300
+                },
+                "date": {
+                    "type": "string",
+                    "example": "2024-01-31"
+                },
+                "urlsCreated": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.DeviceRoute": {
+            "type": "object",
+            "required": [
+                "destinationUrl",
+                "deviceType"
+            ],
+            "properties": {
+                "destinationUrl": {
+                    "type": "string",
+                    "example": "https://m.example.com"
+                },
+                "deviceType": {
+                    "type": "string",
+                    "enum": [
+                        "mobile",
+                        "tablet",
+                        "desktop"
+                    ],
+                    "example": "mobile"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.FunnelAnalyticsResponse": {
+            "type": "object",
+            "properties": {
+                "funnelId": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Spring signup"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.FunnelStepAnalytics"
+                    }
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.FunnelStepAnalytics": {
+            "type": "object",
+            "properties": {
+                "completionRate": {
+                    "description": "CompletionRate is Sessions relative to the sessions of the first step, between 0 and 1,\nand 0 while no session entered the funnel",
+                    "type": "number",
+                    "example": 0.4
+                },
+                "namespace": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "order": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "sessions": {
+                    "type": "integer",
+                    "example": 40
+                },
+                "shortCode": {
+                    "type": "string",
+                    "example": "step2"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.GeneratedAliasStats": {
+            "type": "object",
+            "properties": {
+                "avgLength": {
+                    "description": "0 without any URL",
+                    "type": "number",
+                    "example": 6
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "distribution": {
+                    "description": "Distribution is the number of URLs for each short code length",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int64"
+                    }
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.GeoRoute": {
+            "type": "object",
+            "required": [
+                "countryCode",
+                "destinationUrl"
+            ],
+            "properties": {
+                "countryCode": {
+                    "type": "string",
+                    "example": "US"
+                },
+                "destinationUrl": {
+                    "type": "string",
+                    "example": "https://us.example.com"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.ImportError": {
+            "type": "object",
+            "properties": {
+                "line": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "message": {
+                    "type": "string",
+                    "example": "short code already exists"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.ImportReport": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.ImportError"
+                    }
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 98
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.MemoryStatsResponse": {
+            "type": "object",
+            "properties": {
+                "capacity": {
+                    "description": "0 when unbounded",
+                    "type": "integer"
+                },
+                "evictions": {
+                    "description": "least recently accessed URLs evicted to make room since the process started",
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.PatchURLRequest": {
+            "type": "object",
+            "required": [
+                "tags"
+            ],
+            "properties": {
+                "description": {
+                    "description": "\"\" removes the description",
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "Spring launch campaign"
+                },
+                "expiresAt": {
+                    "description": "null removes the expiry",
+                    "type": "string",
+                    "format": "date-time",
+                    "x-nullable": true
+                },
+                "originalUrl": {
+                    "type": "string",
+                    "example": "https://example.com/new"
+                },
+                "redirectType": {
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ],
+                    "example": 302
+                },
+                "tags": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "docs",
+                        "launch"
+                    ]
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.Pool": {
+            "type": "object",
+            "required": [
+                "targets"
+            ],
+            "properties": {
+                "targets": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.PoolTarget"
+                    }
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.PoolTarget": {
+            "type": "object",
+            "required": [
+                "url",
+                "weight"
+            ],
+            "properties": {
+                "url": {
+                    "type": "string",
+                    "example": "https://eu.example.com"
+                },
+                "weight": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 1,
+                    "example": 3
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.StatsResponse": {
+            "type": "object",
+            "properties": {
+                "activeUrls": {
+                    "type": "integer"
+                },
+                "cacheHitRate": {
+                    "description": "between 0 and 1, 0 before the first lookup",
+                    "type": "number",
+                    "example": 0.85
+                },
+                "clicksToday": {
+                    "type": "integer"
+                },
+                "memory": {
+                    "description": "Memory is only reported by the in-memory repository",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.MemoryStatsResponse"
+                        }
+                    ]
+                },
+                "totalClicks": {
+                    "type": "integer"
+                },
+                "totalUrls": {
+                    "type": "integer"
+                },
+                "urlsCreatedToday": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.URLHealthResponse": {
+            "type": "object",
+            "properties": {
+                "healthStatus": {
+                    "type": "string",
+                    "enum": [
+                        "unknown",
+                        "healthy",
+                        "dead"
+                    ]
+                },
+                "lastCheckedAt": {
+                    "type": "string"
+                },
+                "shortCode": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.URLInfoResponse": {
+            "type": "object",
+            "properties": {
+                "clickGoal": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "clicks": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdByIp": {
+                    "description": "CreatedByIP is the address the URL was created from, its last part masked outside\nthe admin API",
+                    "type": "string",
+                    "example": "203.0.113.xxx"
+                },
+                "delaySeconds": {
+                    "description": "countdown before redirecting",
+                    "type": "integer",
+                    "example": 5
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Spring launch campaign"
+                },
+                "deviceRoutes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.DeviceRoute"
+                    }
+                },
+                "dryRun": {
+                    "description": "DryRun is set on the answers of dry runs, the URL described not being stored",
+                    "type": "boolean"
+                },
+                "enabled": {
+                    "description": "false while the URL is disabled",
+                    "type": "boolean",
+                    "example": true
+                },
+                "expiresAt": {
+                    "description": "when the URL stops redirecting, or a signed shortCode stops resolving",
+                    "type": "string"
+                },
+                "geoRoutes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.GeoRoute"
+                    }
+                },
+                "goalReached": {
+                    "description": "whether the clicks reached clickGoal",
+                    "type": "boolean"
+                },
+                "healthStatus": {
+                    "type": "string",
+                    "enum": [
+                        "unknown",
+                        "healthy",
+                        "dead"
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastAccessedAt": {
+                    "description": "LastAccessedAt is the time of the latest click, omitted until the URL is first clicked",
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "originalUrl": {
+                    "type": "string"
+                },
+                "pool": {
+                    "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.Pool"
+                },
+                "protected": {
+                    "type": "boolean"
+                },
+                "redirectType": {
+                    "type": "integer",
+                    "example": 301
+                },
+                "shortCode": {
+                    "type": "string"
+                },
+                "shortUrl": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Title is that of the destination page, fetched in the background after creation when\napp.fetch_titles is set",
+                    "type": "string",
+                    "example": "Example Domain"
+                },
+                "uniqueClicks": {
+                    "description": "clicks without repeat visits within the deduplication window",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.Variant"
+                    }
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.URLResponse": {
+            "type": "object",
+            "properties": {
+                "clickGoal": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "clicks": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdByIp": {
+                    "description": "CreatedByIP is the address the URL was created from, its last part masked outside\nthe admin API",
+                    "type": "string",
+                    "example": "203.0.113.xxx"
+                },
+                "delaySeconds": {
+                    "description": "countdown before redirecting",
+                    "type": "integer",
+                    "example": 5
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Spring launch campaign"
+                },
+                "deviceRoutes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.DeviceRoute"
+                    }
+                },
+                "dryRun": {
+                    "description": "DryRun is set on the answers of dry runs, the URL described not being stored",
+                    "type": "boolean"
+                },
+                "expiresAt": {
+                    "description": "when the URL stops redirecting, or a signed shortCode stops resolving",
+                    "type": "string"
+                },
+                "geoRoutes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.GeoRoute"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "originalUrl": {
+                    "type": "string"
+                },
+                "pool": {
+                    "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.Pool"
+                },
+                "protected": {
+                    "type": "boolean"
+                },
+                "redirectType": {
+                    "type": "integer",
+                    "example": 301
+                },
+                "shortCode": {
+                    "type": "string"
+                },
+                "shortUrl": {
+                    "type": "string"
+                },
+                "title": {
+                    "description": "Title is that of the destination page, fetched in the background after creation when\napp.fetch_titles is set",
+                    "type": "string",
+                    "example": "Example Domain"
+                },
+                "uniqueClicks": {
+                    "description": "clicks without repeat visits within the deduplication window",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_application.Variant"
+                    }
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_application.Variant": {
+            "type": "object",
+            "required": [
+                "url",
+                "weight"
+            ],
+            "properties": {
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/landing-b"
+                },
+                "weight": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 1,
+                    "example": 30
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "actorIp": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "namespace": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "newValue": {
+                    "type": "object",
+                    "x-nullable": true
+                },
+                "oldValue": {
+                    "type": "object",
+                    "x-nullable": true
+                },
+                "operation": {
+                    "type": "string",
+                    "example": "update"
+                },
+                "requestId": {
+                    "type": "string"
+                },
+                "shortCode": {
+                    "type": "string",
+                    "example": "abc123"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.ClickHeatmap": {
+            "type": "object",
+            "properties": {
+                "dayOfWeek": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "hourOfDay": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.DeviceStat": {
+            "type": "object",
+            "properties": {
+                "browser": {
+                    "type": "string",
+                    "example": "Chrome"
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "deviceType": {
+                    "type": "string",
+                    "example": "mobile"
+                },
+                "os": {
+                    "type": "string",
+                    "example": "Android"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.Funnel": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Spring signup"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_domain.FunnelStep"
+                    }
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.FunnelStep": {
+            "type": "object",
+            "properties": {
+                "namespace": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "order": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "shortCode": {
+                    "type": "string",
+                    "example": "step1"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.IPCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "ipAddress": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.LatencyStats": {
+            "type": "object",
+            "properties": {
+                "avg": {
+                    "type": "number",
+                    "example": 1.6
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "p50": {
+                    "type": "number",
+                    "example": 1.2
+                },
+                "p95": {
+                    "type": "number",
+                    "example": 4.8
+                },
+                "p99": {
+                    "type": "number",
+                    "example": 9.5
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.MigrationStatus": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "boolean"
+                },
+                "dirty": {
+                    "description": "the migration failed part way",
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "add_redirect_delay"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 14
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.ReferrerCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "referer": {
+                    "type": "string",
+                    "example": "news.ycombinator.com/item"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.StoreMigrationReport": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "migrated": {
+                    "type": "integer",
+                    "example": 98
+                },
+                "skipped": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.TimeBucket": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "period": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_domain.VariantStat": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "originalUrl": {
+                    "type": "string",
+                    "example": "https://example.com/landing-b"
+                },
+                "variantId": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "weight": {
+                    "type": "integer",
+                    "example": 30
+                }
+            }
+        },
+        "github_com_sp3dr4_dove_internal_pkg_jsonschema.Error": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "dotted path of the value, empty for the whole document",
+                    "type": "string",
+                    "example": "url"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "url must not point to localhost or a private network"
+                }
+            }
+        },
+        "internal_adapters_http.CacheKeysResponse": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "url:default:abc123"
+                    ]
+                },
+                "nextCursor": {
+                    "description": "NextCursor continues the listing, 0 once every key was listed",
+                    "type": "integer",
+                    "example": 123
+                }
+            }
+        },
+        "internal_adapters_http.ClickExportRecord": {
+            "type": "object",
+            "properties": {
+                "browser": {
+                    "type": "string",
+                    "example": "Chrome"
+                },
+                "clickedAt": {
+                    "type": "string"
+                },
+                "country": {
+                    "type": "string",
+                    "example": "DE"
+                },
+                "deviceType": {
+                    "type": "string",
+                    "example": "desktop"
+                },
+                "ipAddress": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "os": {
+                    "type": "string",
+                    "example": "macOS"
+                },
+                "redirectDurationMs": {
+                    "type": "number",
+                    "example": 1.4
+                },
+                "referer": {
+                    "type": "string",
+                    "example": "example.com/blog"
+                },
+                "userAgent": {
+                    "type": "string",
+                    "example": "Mozilla/5.0"
+                },
+                "variantId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_adapters_http.ExportRecord": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "namespace": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "originalUrl": {
+                    "type": "string",
+                    "example": "https://example.com"
+                },
+                "redirectType": {
+                    "type": "integer",
+                    "example": 301
+                },
+                "shortCode": {
+                    "type": "string",
+                    "example": "abc123"
+                }
+            }
+        },
+        "internal_adapters_http.MigrateCacheKeysRequest": {
+            "type": "object",
+            "properties": {
+                "newPrefix": {
+                    "type": "string",
+                    "example": "dove-staging"
+                },
+                "oldPrefix": {
+                    "type": "string",
+                    "example": "dove"
+                }
+            }
+        },
+        "internal_adapters_http.MigrateCacheKeysResponse": {
+            "type": "object",
+            "properties": {
+                "renamed": {
+                    "type": "integer",
+                    "example": 1200
+                }
+            }
+        },
+        "internal_adapters_http.ProblemDetail": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "Short URL not found"
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/abc123"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "https://dove.example/errors/not-found"
+                }
+            }
+        },
+        "internal_adapters_http.ReadinessResponse": {
+            "type": "object",
+            "properties": {
+                "cache": {
+                    "type": "string",
+                    "enum": [
+                        "ok",
+                        "degraded",
+                        "disabled"
+                    ],
+                    "example": "ok"
+                },
+                "db": {
+                    "type": "string",
+                    "example": "ok"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ready"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_adapters_http.SchemaProblemDetail": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "Short URL not found"
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/abc123"
+                },
+                "jsonSchemaErrors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sp3dr4_dove_internal_pkg_jsonschema.Error"
+                    }
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "https://dove.example/errors/not-found"
+                }
+            }
+        },
+        "internal_adapters_http.ValidationFieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "INVALID_URL_FORMAT"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "url"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "url must be a valid URL"
+                }
+            }
+        },
+        "internal_adapters_http.ValidationProblemDetail": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "Short URL not found"
+                },
+                "details": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_adapters_http.ValidationFieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/abc123"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "https://dove.example/errors/not-found"
+                }
             }
         }
+    },
+    "securityDefinitions": {
+        "AdminAPIKey": {
+            "description": "Admin API key as \"Bearer \u003ckey\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
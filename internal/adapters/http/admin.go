@@ -0,0 +1,556 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/iputil"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// Defaults of the cold URLs parameters; days is at most ten years
+const (
+	defaultColdDays = 30
+	maxColdDays     = 3650
+	defaultColdURLs = 100
+)
+
+// defaultListedURLs is the page size of the admin URL listing
+const defaultListedURLs = 100
+
+// Default and maximum number of keys asked of the cache per page of the cache keys listing
+const (
+	defaultCacheKeys = 100
+	maxCacheKeys     = 1000
+)
+
+// Default and maximum number of addresses listed by the top IPs endpoint
+const (
+	defaultTopIPs = 10
+	maxTopIPs     = 1000
+)
+
+// AdminAuthMiddleware admits requests carrying apiKey as a bearer token and answers every
+// other request with a 401 problem
+func AdminAuthMiddleware(apiKey string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				respondWithProblem(w, r, http.StatusUnauthorized, ProblemTypeUnauthorized, "A valid admin API key is required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MigrateCacheKeysRequest names the prefixes of POST /admin/cache/migrate-keys
+type MigrateCacheKeysRequest struct {
+	OldPrefix string `json:"oldPrefix" example:"dove"`
+	NewPrefix string `json:"newPrefix" example:"dove-staging"`
+}
+
+// MigrateCacheKeysResponse reports how many keys moved to the new prefix
+type MigrateCacheKeysResponse struct {
+	Renamed int `json:"renamed" example:"1200"`
+}
+
+// HandleMigrateCacheKeys moves Redis keys to another prefix.
+//
+//	@Summary		Migrate cache key prefix
+//	@Description	Rename every Redis key starting with oldPrefix to start with newPrefix instead, ahead of changing cache.key_prefix. Keys already present under newPrefix are kept. Only available when admin.api_key is set and the Redis cache is enabled.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			request	body		MigrateCacheKeysRequest		true	"Prefixes to migrate between"
+//	@Success		200		{object}	MigrateCacheKeysResponse	"Number of keys renamed"
+//	@Failure		400		{object}	ProblemDetail				"Missing or overlapping prefixes"
+//	@Failure		401		{object}	ProblemDetail				"Missing or invalid admin API key"
+//	@Failure		500		{object}	ProblemDetail				"Internal server error"
+//	@Router			/admin/cache/migrate-keys [post]
+func (h *Handlers) HandleMigrateCacheKeys(w http.ResponseWriter, r *http.Request) {
+	var req MigrateCacheKeysRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid request body")
+		return
+	}
+
+	renamed, err := h.keyMigrator.MigrateKeys(r.Context(), req.OldPrefix, req.NewPrefix)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidKeyPrefix) {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, err.Error())
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to migrate cache keys", "old_prefix", req.OldPrefix, "new_prefix", req.NewPrefix, "error", err)
+		respondWithInternalError(w, r, err, "Failed to migrate cache keys")
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Migrated cache keys", "old_prefix", req.OldPrefix, "new_prefix", req.NewPrefix, "renamed", renamed)
+	respondWithJSON(w, r.Context(), http.StatusOK, MigrateCacheKeysResponse{Renamed: renamed})
+}
+
+// CacheKeysResponse is a page of the cached keys
+type CacheKeysResponse struct {
+	Keys []string `json:"keys" example:"url:default:abc123"`
+	// NextCursor continues the listing, 0 once every key was listed
+	NextCursor uint64 `json:"nextCursor" example:"123"`
+}
+
+// HandleCacheKeys lists the cached keys, for diagnostics.
+//
+//	@Summary		List cache keys
+//	@Description	List a page of the cached keys matching a glob pattern, without the key prefix, such as url:{namespace}:{shortCode}. Pass the nextCursor of a page as cursor to get the next; 0 starts the listing and, returned, ends it. Pages can be empty before the end, and count is only a hint of their size. Redis is scanned without blocking it. Only available when admin.api_key is set and admin.expose_cache is true.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			pattern	query		string				false	"Glob pattern of the keys"	default(*)
+//	@Param			cursor	query		int					false	"Cursor of the page"		minimum(0)	default(0)
+//	@Param			count	query		int					false	"Keys to scan for the page"	minimum(1)	maximum(1000)	default(100)
+//	@Success		200		{object}	CacheKeysResponse	"Page of cache keys"
+//	@Failure		400		{object}	ProblemDetail		"Invalid cursor or count"
+//	@Failure		401		{object}	ProblemDetail		"Missing or invalid admin API key"
+//	@Failure		500		{object}	ProblemDetail		"Internal server error"
+//	@Router			/admin/cache/keys [get]
+func (h *Handlers) HandleCacheKeys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var cursor uint64
+	if param := query.Get("cursor"); param != "" {
+		parsed, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "cursor must be a non-negative integer")
+			return
+		}
+		cursor = parsed
+	}
+
+	count := defaultCacheKeys
+	if param := query.Get("count"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > maxCacheKeys {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("count must be an integer between 1 and %d", maxCacheKeys))
+			return
+		}
+		count = parsed
+	}
+
+	pattern := query.Get("pattern")
+	keys, next, err := h.cache.Keys(r.Context(), pattern, cursor, count)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to list cache keys", "pattern", pattern, "error", err)
+		respondWithInternalError(w, r, err, "Failed to list cache keys")
+		return
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, CacheKeysResponse{Keys: keys, NextCursor: next})
+}
+
+// HandleColdURLs lists the URLs nobody clicked lately.
+//
+//	@Summary		List cold URLs
+//	@Description	List the enabled short URLs not clicked in the past days, least recently active first. URLs never clicked count from their creation. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			days	query		int							false	"Days without a click"		minimum(1)	maximum(3650)	default(30)
+//	@Param			limit	query		int							false	"Maximum number of URLs"	minimum(1)	maximum(1000)	default(100)
+//	@Success		200		{array}		application.URLInfoResponse	"Cold URLs, least recently active first"
+//	@Failure		400		{object}	ProblemDetail				"Invalid days or limit"
+//	@Failure		401		{object}	ProblemDetail				"Missing or invalid admin API key"
+//	@Failure		500		{object}	ProblemDetail				"Internal server error"
+//	@Router			/admin/urls/cold [get]
+func (h *Handlers) HandleColdURLs(w http.ResponseWriter, r *http.Request) {
+	days := defaultColdDays
+	if param := r.URL.Query().Get("days"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > maxColdDays {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("days must be an integer between 1 and %d", maxColdDays))
+			return
+		}
+		days = parsed
+	}
+
+	limit := defaultColdURLs
+	if param := r.URL.Query().Get("limit"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > application.MaxColdURLs {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", application.MaxColdURLs))
+			return
+		}
+		limit = parsed
+	}
+
+	urls, err := h.service.GetColdURLs(r.Context(), days, limit)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load cold URLs", "days", days, "limit", limit, "error", err)
+		respondWithInternalError(w, r, err, "Failed to load cold URLs")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, h.adminURLInfoResponses(urls))
+}
+
+// HandleListURLs lists every URL, optionally only those created from one address.
+//
+//	@Summary		List URLs
+//	@Description	List the short URLs in ID order, a page at a time: pass the id of the last URL of a page as after to get the next one. With created_by_ip only the URLs created from that address are listed. Creator addresses are shown unmasked. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			created_by_ip	query		string						false	"Address the URLs were created from"
+//	@Param			after			query		int							false	"List the URLs with a greater ID"	minimum(0)	default(0)
+//	@Param			limit			query		int							false	"Maximum number of URLs"			minimum(1)	maximum(1000)	default(100)
+//	@Success		200				{array}		application.URLInfoResponse	"URLs in ID order"
+//	@Failure		400				{object}	ProblemDetail				"Invalid created_by_ip, after or limit"
+//	@Failure		401				{object}	ProblemDetail				"Missing or invalid admin API key"
+//	@Failure		500				{object}	ProblemDetail				"Internal server error"
+//	@Router			/admin/urls [get]
+func (h *Handlers) HandleListURLs(w http.ResponseWriter, r *http.Request) {
+	creatorIP := r.URL.Query().Get("created_by_ip")
+	if creatorIP != "" && iputil.NormalizeIP(creatorIP) == "" {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "created_by_ip must be an IPv4 or IPv6 address")
+		return
+	}
+
+	var afterID int64
+	if param := r.URL.Query().Get("after"); param != "" {
+		parsed, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || parsed < 0 {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "after must be a non-negative integer")
+			return
+		}
+		afterID = parsed
+	}
+
+	limit := defaultListedURLs
+	if param := r.URL.Query().Get("limit"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > application.MaxListedURLs {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", application.MaxListedURLs))
+			return
+		}
+		limit = parsed
+	}
+
+	var urls []*domain.URL
+	var err error
+	if creatorIP != "" {
+		urls, err = h.service.ListURLsByCreatorIP(r.Context(), creatorIP, afterID, limit)
+	} else {
+		urls, err = h.service.ListURLs(r.Context(), afterID, limit)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to list URLs", "created_by_ip", creatorIP, "after", afterID, "error", err)
+		respondWithInternalError(w, r, err, "Failed to list URLs")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, h.adminURLInfoResponses(urls))
+}
+
+// adminURLInfoResponses builds the metadata responses of urls for admins, who see the
+// creator addresses unmasked
+func (h *Handlers) adminURLInfoResponses(urls []*domain.URL) []*application.URLInfoResponse {
+	responses := make([]*application.URLInfoResponse, 0, len(urls))
+	for _, url := range urls {
+		response := application.NewURLInfoResponse(url, h.baseURL)
+		response.CreatedByIP = url.CreatedByIP
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+// HandleTopIPs lists the client IP addresses with the most clicks.
+//
+//	@Summary		List top source IPs
+//	@Description	List the client IP addresses that made the most clicks across every short URL, busiest first. IPv4-mapped IPv6 addresses are counted as plain IPv4, and clicks without an address are left out. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			n	query		int				false	"Maximum number of addresses"	minimum(1)	maximum(1000)	default(10)
+//	@Success		200	{array}		domain.IPCount	"Click counts per IP address, busiest first"
+//	@Failure		400	{object}	ProblemDetail	"Invalid n"
+//	@Failure		401	{object}	ProblemDetail	"Missing or invalid admin API key"
+//	@Failure		500	{object}	ProblemDetail	"Internal server error"
+//	@Router			/admin/analytics/top-ips [get]
+func (h *Handlers) HandleTopIPs(w http.ResponseWriter, r *http.Request) {
+	n := defaultTopIPs
+	if param := r.URL.Query().Get("n"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > maxTopIPs {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("n must be an integer between 1 and %d", maxTopIPs))
+			return
+		}
+		n = parsed
+	}
+
+	ips, err := h.service.GetTopIPs(r.Context(), n)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load top IPs", "n", n, "error", err)
+		respondWithInternalError(w, r, err, "Failed to load top IPs")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, ips)
+}
+
+// HandleDisable suspends the redirects of a short URL.
+//
+//	@Summary		Disable a short URL
+//	@Description	Suspend a short URL without deleting it: its redirects answer 410 Gone until it is enabled again. Disabling a disabled URL does nothing. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Security		AdminAPIKey
+//	@Param			shortCode	path	string	true	"Short code"
+//	@Param			X-Namespace	header	string	false	"Namespace of the short code"	default(default)
+//	@Success		204			"URL disabled"
+//	@Failure		400			{object}	ProblemDetail	"Invalid namespace"
+//	@Failure		401			{object}	ProblemDetail	"Missing or invalid admin API key"
+//	@Failure		404			{object}	ProblemDetail	"Short URL not found"
+//	@Failure		500			{object}	ProblemDetail	"Internal server error"
+//	@Router			/admin/urls/{shortCode}/disable [patch]
+func (h *Handlers) HandleDisable(w http.ResponseWriter, r *http.Request) {
+	h.setEnabled(w, r, false)
+}
+
+// HandleEnable restores the redirects of a disabled short URL.
+//
+//	@Summary		Enable a short URL
+//	@Description	Restore the redirects of a disabled short URL. Enabling an enabled URL does nothing. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Security		AdminAPIKey
+//	@Param			shortCode	path	string	true	"Short code"
+//	@Param			X-Namespace	header	string	false	"Namespace of the short code"	default(default)
+//	@Success		204			"URL enabled"
+//	@Failure		400			{object}	ProblemDetail	"Invalid namespace"
+//	@Failure		401			{object}	ProblemDetail	"Missing or invalid admin API key"
+//	@Failure		404			{object}	ProblemDetail	"Short URL not found"
+//	@Failure		500			{object}	ProblemDetail	"Internal server error"
+//	@Router			/admin/urls/{shortCode}/enable [patch]
+func (h *Handlers) HandleEnable(w http.ResponseWriter, r *http.Request) {
+	h.setEnabled(w, r, true)
+}
+
+func (h *Handlers) setEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	namespace, err := namespaceFromRequest(r)
+	if err != nil {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "X-Namespace must be a lowercase slug of letters, digits and hyphens")
+		return
+	}
+
+	changed, err := h.service.SetURLEnabled(r.Context(), namespace, shortCode, enabled)
+	if err != nil {
+		if errors.Is(err, domain.ErrURLNotFound) {
+			respondWithProblem(w, r, http.StatusNotFound, ProblemTypeNotFound, "Short URL not found")
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to enable or disable URL", "namespace", namespace, "short_code", shortCode, "enabled", enabled, "error", err)
+		respondWithInternalError(w, r, err, "Failed to update URL")
+		return
+	}
+
+	if changed {
+		logging.FromContext(r.Context()).Info("Changed URL state", "namespace", namespace, "short_code", shortCode, "enabled", enabled)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleAuditLog lists the changes of a short URL.
+//
+//	@Summary		Get the audit log of a short URL
+//	@Description	List the recorded changes of a short URL, most recent first: its creation, updates, analytics resets, disabling and enabling, and deletion. Each entry holds the URL before and after the change, without its password hash. The history outlives the URL, so deleted URLs still have one. Only available when admin.api_key is set and the database keeps the history, PostgreSQL or memory.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			shortCode	path		string				true	"Short code"
+//	@Param			X-Namespace	header		string				false	"Namespace of the short code"	default(default)
+//	@Param			limit		query		int					false	"Maximum number of entries"		minimum(1)	maximum(500)	default(50)
+//	@Success		200			{array}		domain.AuditEntry	"Changes of the URL, most recent first"
+//	@Failure		400			{object}	ProblemDetail		"Invalid namespace or limit"
+//	@Failure		401			{object}	ProblemDetail		"Missing or invalid admin API key"
+//	@Failure		500			{object}	ProblemDetail		"Internal server error"
+//	@Router			/admin/urls/{shortCode}/audit-log [get]
+func (h *Handlers) HandleAuditLog(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	namespace, err := namespaceFromRequest(r)
+	if err != nil {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "X-Namespace must be a lowercase slug of letters, digits and hyphens")
+		return
+	}
+
+	limit := application.DefaultAuditLogLimit
+	if param := r.URL.Query().Get("limit"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > application.MaxAuditLogLimit {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", application.MaxAuditLogLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := h.service.GetAuditLog(r.Context(), namespace, shortCode, limit)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load audit log", "namespace", namespace, "short_code", shortCode, "error", err)
+		respondWithInternalError(w, r, err, "Failed to load audit log")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, entries)
+}
+
+// MigrationHandlers serves the schema and store migration endpoints of the admin API
+type MigrationHandlers struct {
+	migrations domain.MigrationRepository
+	stores     domain.StoreMigrator
+}
+
+// NewMigrationHandlers creates the migration handlers for migrations and stores. A nil
+// stores leaves out the store migration endpoint.
+func NewMigrationHandlers(migrations domain.MigrationRepository, stores domain.StoreMigrator) *MigrationHandlers {
+	return &MigrationHandlers{migrations: migrations, stores: stores}
+}
+
+// HandleListMigrations lists the schema migrations.
+//
+//	@Summary		List schema migrations
+//	@Description	List every schema migration with whether it is applied. A dirty migration failed part way and has to be repaired by hand. Only available when admin.api_key is set and the database is SQL based.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Success		200	{array}		domain.MigrationStatus	"Schema migrations in version order"
+//	@Failure		401	{object}	ProblemDetail			"Missing or invalid admin API key"
+//	@Failure		500	{object}	ProblemDetail			"Internal server error"
+//	@Router			/admin/migrations [get]
+func (h *MigrationHandlers) HandleListMigrations(w http.ResponseWriter, r *http.Request) {
+	h.respondWithMigrations(w, r)
+}
+
+// HandleMigrateUp applies every pending schema migration.
+//
+//	@Summary		Apply schema migrations
+//	@Description	Apply every pending schema migration and list the migrations afterwards.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Success		200	{array}		domain.MigrationStatus	"Schema migrations in version order"
+//	@Failure		401	{object}	ProblemDetail			"Missing or invalid admin API key"
+//	@Failure		409	{object}	ProblemDetail			"Schema is dirty"
+//	@Failure		500	{object}	ProblemDetail			"Internal server error"
+//	@Router			/admin/migrations/up [post]
+func (h *MigrationHandlers) HandleMigrateUp(w http.ResponseWriter, r *http.Request) {
+	if err := h.migrations.Up(r.Context()); err != nil {
+		h.respondWithMigrationError(w, r, "Failed to apply migrations", err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Applied schema migrations")
+	h.respondWithMigrations(w, r)
+}
+
+// HandleMigrateDown rolls back schema migrations.
+//
+//	@Summary		Roll back schema migrations
+//	@Description	Roll back the latest schema migrations and list the migrations afterwards. Rolling back more migrations than are applied leaves an empty schema.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			steps	query		int						false	"Number of migrations to roll back"	minimum(1)	default(1)
+//	@Success		200		{array}		domain.MigrationStatus	"Schema migrations in version order"
+//	@Failure		400		{object}	ProblemDetail			"Invalid steps"
+//	@Failure		401		{object}	ProblemDetail			"Missing or invalid admin API key"
+//	@Failure		409		{object}	ProblemDetail			"Schema is dirty"
+//	@Failure		500		{object}	ProblemDetail			"Internal server error"
+//	@Router			/admin/migrations/down [post]
+func (h *MigrationHandlers) HandleMigrateDown(w http.ResponseWriter, r *http.Request) {
+	steps := 1
+	if param := r.URL.Query().Get("steps"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "steps must be a positive integer")
+			return
+		}
+		steps = parsed
+	}
+
+	if err := h.migrations.Down(r.Context(), steps); err != nil {
+		h.respondWithMigrationError(w, r, "Failed to roll back migrations", err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Warn("Rolled back schema migrations", "steps", steps)
+	h.respondWithMigrations(w, r)
+}
+
+// HandleMigrateStore copies the URLs of the running store into another one.
+//
+//	@Summary		Migrate URLs to another store
+//	@Description	Copy every URL of the running store into the store of type `to`, configured under database like the running one, ahead of restarting the service on it. URLs whose short code the target already holds are skipped. Click counters are copied, recorded clicks are not. Only one migration runs at a time.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			from	query		string						true	"Type of the running store"	Enums(memory, sqlite, postgres)
+//	@Param			to		query		string						true	"Type of the target store"	Enums(sqlite, postgres)
+//	@Success		200		{object}	domain.StoreMigrationReport	"Migration report"
+//	@Failure		400		{object}	ProblemDetail				"Unsupported from or to store"
+//	@Failure		401		{object}	ProblemDetail				"Missing or invalid admin API key"
+//	@Failure		409		{object}	ProblemDetail				"A store migration is already running"
+//	@Failure		500		{object}	ProblemDetail				"Internal server error"
+//	@Router			/admin/migrate-store [post]
+func (h *MigrationHandlers) HandleMigrateStore(w http.ResponseWriter, r *http.Request) {
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+
+	report, err := h.stores.MigrateStore(r.Context(), from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUnsupportedStore):
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, err.Error())
+		case errors.Is(err, domain.ErrStoreMigrationRunning):
+			respondWithProblem(w, r, http.StatusConflict, ProblemTypeConflict, "A store migration is already running")
+		default:
+			logging.FromContext(r.Context()).Error("Failed to migrate store", "from", from, "to", to, "error", err)
+			respondWithInternalError(w, r, err, "Failed to migrate store")
+		}
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Migrated store", "from", from, "to", to, "migrated", report.Migrated, "skipped", report.Skipped, "failed", report.Failed)
+	respondWithJSON(w, r.Context(), http.StatusOK, report)
+}
+
+func (h *MigrationHandlers) respondWithMigrations(w http.ResponseWriter, r *http.Request) {
+	statuses, err := h.migrations.List(r.Context())
+	if err != nil {
+		h.respondWithMigrationError(w, r, "Failed to list migrations", err)
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, statuses)
+}
+
+func (h *MigrationHandlers) respondWithMigrationError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	if errors.Is(err, domain.ErrMigrationDirty) {
+		respondWithProblem(w, r, http.StatusConflict, ProblemTypeConflict, "The database schema is dirty and has to be repaired by hand")
+		return
+	}
+
+	logging.FromContext(r.Context()).Error(message, "error", err)
+	respondWithInternalError(w, r, err, message)
+}
@@ -0,0 +1,519 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// defaultTimeSeriesWindow is the range covered when the caller omits from
+const defaultTimeSeriesWindow = 30 * 24 * time.Hour
+
+// Bounds of the referrers limit parameter
+const (
+	defaultReferrersLimit = 20
+	maxReferrersLimit     = 100
+)
+
+// defaultTopURLs is the ranking size when the caller omits n
+const defaultTopURLs = 10
+
+// defaultDailyStatsDays is the number of days covered when the caller omits from
+const defaultDailyStatsDays = 30
+
+// HandleClickTimeSeries handles the click time-series analytics endpoint.
+//
+//	@Summary		Click time series
+//	@Description	Count clicks on a short URL per minute, hour, day or week. Ranges may span at most 90 days.
+//	@Tags			analytics
+//	@Produce		json
+//	@Param			shortCode		path		string				true	"Short code"
+//	@Param			granularity		query		string				false	"Bucket width"	Enums(minute, hour, day, week)	default(day)
+//	@Param			from			query		string				false	"Range start as YYYY-MM-DD or RFC 3339, defaults to 30 days before to"
+//	@Param			to				query		string				false	"Range end as YYYY-MM-DD (inclusive) or RFC 3339, defaults to now"
+//	@Param			p				query		string				false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string				false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string				false	"Namespace of the short code"	default(default)
+//	@Success		200				{array}		domain.TimeBucket	"Click counts per period"
+//	@Failure		400				{object}	ProblemDetail		"Invalid granularity or date range"
+//	@Failure		401				{object}	ProblemDetail		"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail		"Short URL not found"
+//	@Failure		500				{object}	ProblemDetail		"Internal server error"
+//	@Router			/shorten/{shortCode}/analytics/timeseries [get]
+func (h *Handlers) HandleClickTimeSeries(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+	query := r.URL.Query()
+
+	granularityParam := query.Get("granularity")
+	if granularityParam == "" {
+		granularityParam = string(domain.GranularityDay)
+	}
+	granularity, err := domain.ParseGranularity(granularityParam)
+	if err != nil {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "granularity must be one of minute, hour, day, week")
+		return
+	}
+
+	to := time.Now().UTC()
+	if param := query.Get("to"); param != "" {
+		to, err = parseRangeBound(param, true)
+		if err != nil {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid to: "+err.Error())
+			return
+		}
+	}
+
+	from := to.Add(-defaultTimeSeriesWindow)
+	if param := query.Get("from"); param != "" {
+		from, err = parseRangeBound(param, false)
+		if err != nil {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid from: "+err.Error())
+			return
+		}
+	}
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	buckets, err := h.service.GetClickTimeSeries(r.Context(), url.Namespace, url.ShortCode, granularity, from, to)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTimeRange) {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, err.Error())
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to load click time series", "short_code", shortCode, "error", err)
+		respondWithInternalError(w, r, err, "Failed to load click time series")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, buckets)
+}
+
+// HandleClickHeatmap handles the click heatmap analytics endpoint.
+//
+//	@Summary		Click heatmap
+//	@Description	Count every click on a short URL by hour of the day (0-23) and day of the week (0 is Sunday) in the requested time zone
+//	@Tags			analytics
+//	@Produce		json
+//	@Param			shortCode		path		string				true	"Short code"
+//	@Param			tz				query		string				false	"IANA time zone the clicks are bucketed in"	default(UTC)
+//	@Param			p				query		string				false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string				false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string				false	"Namespace of the short code"	default(default)
+//	@Success		200				{object}	domain.ClickHeatmap	"Click counts per hour and weekday"
+//	@Failure		400				{object}	ProblemDetail		"Unknown time zone"
+//	@Failure		401				{object}	ProblemDetail		"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail		"Short URL not found"
+//	@Failure		500				{object}	ProblemDetail		"Internal server error"
+//	@Router			/shorten/{shortCode}/analytics/heatmap [get]
+func (h *Handlers) HandleClickHeatmap(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	loc := time.UTC
+	// Local would depend on the server the request happens to reach
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		parsed, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("Unknown time zone %q", tz))
+			return
+		}
+		loc = parsed
+	}
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	heatmap, err := h.service.GetClickHeatmap(r.Context(), url.Namespace, url.ShortCode, loc)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load click heatmap", "short_code", shortCode, "error", err)
+		respondWithInternalError(w, r, err, "Failed to load click heatmap")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, heatmap)
+}
+
+// HandleTopReferrers handles the referrer analytics endpoint.
+//
+//	@Summary		Top referrers
+//	@Description	List the sites that sent the most clicks to a short URL. Referrers are reduced to host and path; clicks without a Referer header are reported as (direct).
+//	@Tags			analytics
+//	@Produce		json
+//	@Param			shortCode		path		string					true	"Short code"
+//	@Param			limit			query		int						false	"Maximum number of referrers"	minimum(1)	maximum(100)	default(20)
+//	@Param			p				query		string					false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string					false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string					false	"Namespace of the short code"	default(default)
+//	@Success		200				{array}		domain.ReferrerCount	"Click counts per referrer, busiest first"
+//	@Failure		400				{object}	ProblemDetail			"Invalid limit"
+//	@Failure		401				{object}	ProblemDetail			"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail			"Short URL not found"
+//	@Failure		500				{object}	ProblemDetail			"Internal server error"
+//	@Router			/shorten/{shortCode}/analytics/referrers [get]
+func (h *Handlers) HandleTopReferrers(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	limit := defaultReferrersLimit
+	if param := r.URL.Query().Get("limit"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > maxReferrersLimit {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", maxReferrersLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	referrers, err := h.service.GetTopReferrers(r.Context(), url.Namespace, url.ShortCode, limit)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load referrers", "short_code", shortCode, "error", err)
+		respondWithInternalError(w, r, err, "Failed to load referrers")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, referrers)
+}
+
+// HandleVariantStats handles the A/B test analytics endpoint.
+//
+//	@Summary		Variant breakdown
+//	@Description	Count the clicks sent to each variant of an A/B tested short URL, in creation order. URLs without variants report an empty list.
+//	@Tags			analytics
+//	@Produce		json
+//	@Param			shortCode		path		string				true	"Short code"
+//	@Param			p				query		string				false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string				false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string				false	"Namespace of the short code"	default(default)
+//	@Success		200				{array}		domain.VariantStat	"Click counts per variant"
+//	@Failure		401				{object}	ProblemDetail		"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail		"Short URL not found"
+//	@Failure		500				{object}	ProblemDetail		"Internal server error"
+//	@Router			/shorten/{shortCode}/analytics/variants [get]
+func (h *Handlers) HandleVariantStats(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetVariantStats(r.Context(), url.Namespace, url.ShortCode)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load variant stats", "short_code", shortCode, "error", err)
+		respondWithInternalError(w, r, err, "Failed to load variant stats")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, stats)
+}
+
+// HandleRedirectLatency handles the redirect latency analytics endpoint.
+//
+//	@Summary		Redirect latency
+//	@Description	Percentiles and average, in milliseconds, of how long redirects of a short URL took from request receipt to the redirect. Clicks recorded before latencies were measured are left out.
+//	@Tags			analytics
+//	@Produce		json
+//	@Param			shortCode		path		string				true	"Short code"
+//	@Param			p				query		string				false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string				false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string				false	"Namespace of the short code"	default(default)
+//	@Success		200				{object}	domain.LatencyStats	"Redirect latency distribution"
+//	@Failure		401				{object}	ProblemDetail		"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail		"Short URL not found"
+//	@Failure		500				{object}	ProblemDetail		"Internal server error"
+//	@Router			/shorten/{shortCode}/analytics/latency [get]
+func (h *Handlers) HandleRedirectLatency(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetRedirectLatency(r.Context(), url.Namespace, url.ShortCode)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load redirect latency", "short_code", shortCode, "error", err)
+		respondWithInternalError(w, r, err, "Failed to load redirect latency")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, stats)
+}
+
+// HandleTopURLs handles the most clicked URLs endpoint.
+//
+//	@Summary		Top URLs
+//	@Description	List the short URLs with the most clicks. The ranking is cached for a few seconds and refreshed after any click. Disabled, signed and password protected URLs are left out.
+//	@Tags			analytics
+//	@Produce		json
+//	@Param			n	query		int						false	"Number of URLs"	minimum(1)	maximum(100)	default(10)
+//	@Success		200	{array}		application.URLResponse	"URLs, most clicked first"
+//	@Failure		400	{object}	ProblemDetail			"Invalid n"
+//	@Failure		500	{object}	ProblemDetail			"Internal server error"
+//	@Router			/urls/top [get]
+func (h *Handlers) HandleTopURLs(w http.ResponseWriter, r *http.Request) {
+	n := defaultTopURLs
+	if param := r.URL.Query().Get("n"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > application.MaxTopURLs {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("n must be an integer between 1 and %d", application.MaxTopURLs))
+			return
+		}
+		n = parsed
+	}
+
+	urls, err := h.service.GetTopURLs(r.Context(), n)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load top URLs", "n", n, "error", err)
+		respondWithInternalError(w, r, err, "Failed to load top URLs")
+		return
+	}
+
+	responses := make([]*application.URLResponse, 0, len(urls))
+	for _, url := range urls {
+		responses = append(responses, application.NewURLResponse(url, h.baseURL))
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, responses)
+}
+
+// parseRangeBound accepts a calendar date or an RFC 3339 timestamp. A bare date used as
+// the end of a range covers that whole day.
+func parseRangeBound(value string, endOfRange bool) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		if endOfRange {
+			// Postgres keeps microseconds, so stop one microsecond short of midnight
+			t = t.AddDate(0, 0, 1).Add(-time.Microsecond)
+		}
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or RFC 3339, got %q", value)
+	}
+	return t.UTC(), nil
+}
+
+// HandleDeviceStats handles the device analytics endpoint.
+//
+//	@Summary		Device breakdown
+//	@Description	Count clicks on a short URL per device type, browser and operating system combination, as parsed from the User-Agent header
+//	@Tags			analytics
+//	@Produce		json
+//	@Param			shortCode		path		string				true	"Short code"
+//	@Param			p				query		string				false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string				false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string				false	"Namespace of the short code"	default(default)
+//	@Success		200				{array}		domain.DeviceStat	"Click counts per device, busiest first"
+//	@Failure		401				{object}	ProblemDetail		"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail		"Short URL not found"
+//	@Failure		500				{object}	ProblemDetail		"Internal server error"
+//	@Router			/shorten/{shortCode}/analytics/devices [get]
+func (h *Handlers) HandleDeviceStats(w http.ResponseWriter, r *http.Request) {
+	h.respondWithDeviceStats(w, r, domain.DeviceGroupingDevice)
+}
+
+// HandleBrowserStats handles the browser analytics endpoint.
+//
+//	@Summary		Browser breakdown
+//	@Description	Count clicks on a short URL per browser
+//	@Tags			analytics
+//	@Produce		json
+//	@Param			shortCode		path		string				true	"Short code"
+//	@Param			p				query		string				false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string				false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string				false	"Namespace of the short code"	default(default)
+//	@Success		200				{array}		domain.DeviceStat	"Click counts per browser, busiest first"
+//	@Failure		401				{object}	ProblemDetail		"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail		"Short URL not found"
+//	@Failure		500				{object}	ProblemDetail		"Internal server error"
+//	@Router			/shorten/{shortCode}/analytics/browsers [get]
+func (h *Handlers) HandleBrowserStats(w http.ResponseWriter, r *http.Request) {
+	h.respondWithDeviceStats(w, r, domain.DeviceGroupingBrowser)
+}
+
+// HandleOSStats handles the operating system analytics endpoint.
+//
+//	@Summary		Operating system breakdown
+//	@Description	Count clicks on a short URL per operating system
+//	@Tags			analytics
+//	@Produce		json
+//	@Param			shortCode		path		string				true	"Short code"
+//	@Param			p				query		string				false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string				false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string				false	"Namespace of the short code"	default(default)
+//	@Success		200				{array}		domain.DeviceStat	"Click counts per operating system, busiest first"
+//	@Failure		401				{object}	ProblemDetail		"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail		"Short URL not found"
+//	@Failure		500				{object}	ProblemDetail		"Internal server error"
+//	@Router			/shorten/{shortCode}/analytics/os [get]
+func (h *Handlers) HandleOSStats(w http.ResponseWriter, r *http.Request) {
+	h.respondWithDeviceStats(w, r, domain.DeviceGroupingOS)
+}
+
+func (h *Handlers) respondWithDeviceStats(w http.ResponseWriter, r *http.Request, grouping domain.DeviceGrouping) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetDeviceStats(r.Context(), url.Namespace, url.ShortCode, grouping)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load device stats", "short_code", shortCode, "grouping", grouping, "error", err)
+		respondWithInternalError(w, r, err, "Failed to load device stats")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, stats)
+}
+
+// HandleResetAnalytics clears the analytics of a short URL.
+//
+//	@Summary		Reset analytics
+//	@Description	Zero the click counters of a short URL and delete its recorded clicks, keeping the URL. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Security		AdminAPIKey
+//	@Param			shortCode	path	string	true	"Short code"
+//	@Param			X-Namespace	header	string	false	"Namespace of the short code"	default(default)
+//	@Success		204			"Analytics reset"
+//	@Failure		400			{object}	ProblemDetail	"Invalid namespace"
+//	@Failure		401			{object}	ProblemDetail	"Missing or invalid admin API key"
+//	@Failure		404			{object}	ProblemDetail	"Short URL not found"
+//	@Failure		500			{object}	ProblemDetail	"Internal server error"
+//	@Router			/shorten/{shortCode}/analytics/reset [post]
+func (h *Handlers) HandleResetAnalytics(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	namespace, err := namespaceFromRequest(r)
+	if err != nil {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "X-Namespace must be a lowercase slug of letters, digits and hyphens")
+		return
+	}
+
+	if err := h.service.ResetAnalytics(r.Context(), namespace, shortCode); err != nil {
+		if errors.Is(err, domain.ErrURLNotFound) {
+			respondWithProblem(w, r, http.StatusNotFound, ProblemTypeNotFound, "Short URL not found")
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to reset analytics", "namespace", namespace, "short_code", shortCode, "error", err)
+		respondWithInternalError(w, r, err, "Failed to reset analytics")
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Reset analytics", "namespace", namespace, "short_code", shortCode)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleStats returns service wide counters.
+//
+//	@Summary		Service statistics
+//	@Description	Count URLs and clicks across every namespace. The "today" counters cover the last 24 hours and the cache hit rate covers URL lookups since the process started. The in-memory repository also reports how full it is. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Success		200	{object}	application.StatsResponse	"Service statistics"
+//	@Failure		401	{object}	ProblemDetail				"Missing or invalid admin API key"
+//	@Failure		500	{object}	ProblemDetail				"Internal server error"
+//	@Router			/admin/stats [get]
+func (h *Handlers) HandleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.GetStats(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load service stats", "error", err)
+		respondWithInternalError(w, r, err, "Failed to load service stats")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, stats)
+}
+
+// HandleAliasStats reports the lengths of short codes.
+//
+//	@Summary		Alias length statistics
+//	@Description	Count the URLs of every namespace with generated short codes, per code length, and those with custom aliases, with their shortest, longest and average length. URLs created before custom aliases were recorded count as generated. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Success		200	{object}	application.AliasStatsResponse	"Short code lengths"
+//	@Failure		401	{object}	ProblemDetail					"Missing or invalid admin API key"
+//	@Failure		500	{object}	ProblemDetail					"Internal server error"
+//	@Router			/admin/stats/aliases [get]
+func (h *Handlers) HandleAliasStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.GetAliasStats(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to load alias stats", "error", err)
+		respondWithInternalError(w, r, err, "Failed to load alias stats")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, stats)
+}
+
+// HandleDailyStats counts URLs created and clicks per day.
+//
+//	@Summary		Daily statistics
+//	@Description	Count the URLs created and the clicks made across every namespace on each UTC day of a range, days without either included. Ranges may span at most 365 days. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			from	query		string					false	"First day as YYYY-MM-DD, defaults to 29 days before to"
+//	@Param			to		query		string					false	"Last day as YYYY-MM-DD (inclusive), defaults to today"
+//	@Success		200		{array}		application.DailyCount	"Counts per day, oldest first"
+//	@Failure		400		{object}	ProblemDetail			"Invalid date range"
+//	@Failure		401		{object}	ProblemDetail			"Missing or invalid admin API key"
+//	@Failure		500		{object}	ProblemDetail			"Internal server error"
+//	@Router			/admin/stats/daily [get]
+func (h *Handlers) HandleDailyStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	to := time.Now().UTC()
+	if param := query.Get("to"); param != "" {
+		day, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("Invalid to: expected YYYY-MM-DD, got %q", param))
+			return
+		}
+		to = day
+	}
+
+	from := to.AddDate(0, 0, 1-defaultDailyStatsDays)
+	if param := query.Get("from"); param != "" {
+		day, err := time.Parse(time.DateOnly, param)
+		if err != nil {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, fmt.Sprintf("Invalid from: expected YYYY-MM-DD, got %q", param))
+			return
+		}
+		from = day
+	}
+
+	counts, err := h.service.GetDailyStats(r.Context(), from, to)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTimeRange) {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, err.Error())
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to load daily stats", "error", err)
+		respondWithInternalError(w, r, err, "Failed to load daily stats")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, counts)
+}
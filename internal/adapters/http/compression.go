@@ -0,0 +1,34 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/klauspost/compress/gzhttp"
+
+	"github.com/sp3dr4/dove/config"
+)
+
+// CompressionMiddleware gzips the responses to GET requests of clients that accept it,
+// once a response reaches cfg.MinSize bytes. Other methods answer with small bodies and
+// are passed through. Event streams are never compressed, as that would hold events back.
+func CompressionMiddleware(cfg config.CompressionConfig) (func(http.Handler) http.Handler, error) {
+	wrap, err := gzhttp.NewWrapper(
+		gzhttp.CompressionLevel(cfg.Level),
+		gzhttp.MinSize(cfg.MinSize),
+		gzhttp.ExceptContentTypes([]string{"text/event-stream"}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		compressed := wrap(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+			compressed.ServeHTTP(w, r)
+		})
+	}, nil
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// computeETag derives a strong ETag from the fields that change when a URL is modified or clicked
+func computeETag(url *domain.URL) string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(url.UpdatedAt.UnixNano(), 10) + ":" + strconv.Itoa(url.Clicks)))
+	return `"` + hex.EncodeToString(sum[:])[:16] + `"`
+}
+
+// notModified evaluates If-None-Match and If-Modified-Since as described in RFC 7232.
+// If-None-Match takes precedence; If-Modified-Since is only consulted when it is absent.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates have second precision
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches reports whether any entry of an If-None-Match header matches etag, using weak comparison
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sp3dr4/dove/config"
+)
+
+// corsMethods are the methods a preflight may ask for, in the order they are listed
+var corsMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// corsOrigins decides which origins may read responses from another site
+type corsOrigins struct {
+	any     bool
+	allowed map[string]bool
+}
+
+func newCORSOrigins(origins []string) corsOrigins {
+	o := corsOrigins{allowed: make(map[string]bool, len(origins))}
+	for _, origin := range origins {
+		if origin == "*" {
+			o.any = true
+		}
+		o.allowed[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return o
+}
+
+// allowOrigin sets Access-Control-Allow-Origin for the origin of r, reporting whether the
+// origin is allowed. Requests without an Origin header are not cross-origin.
+func (o corsOrigins) allowOrigin(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	if o.any {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return true
+	}
+
+	w.Header().Add("Vary", "Origin")
+	if !o.allowed[strings.ToLower(origin)] {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	return true
+}
+
+// CORSMiddleware lets the origins of cfg read the responses of cross-origin requests.
+// Preflights are answered by CORSPreflightHandler.
+func CORSMiddleware(cfg config.CORSConfig) func(http.Handler) http.Handler {
+	origins := newCORSOrigins(cfg.AllowedOrigins)
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if origins.allowOrigin(w, r) && exposed != "" && r.Method != http.MethodOptions {
+				w.Header().Set("Access-Control-Expose-Headers", exposed)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CORSPreflightHandler answers the OPTIONS preflight of any route of routes with 204 and
+// the methods the route accepts, when the requested method is one of them. Paths no route
+// matches get 404 and routes without the requested method 405.
+func CORSPreflightHandler(routes chi.Routes, cfg config.CORSConfig) http.HandlerFunc {
+	origins := newCORSOrigins(cfg.AllowedOrigins)
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(cfg.MaxAgeSeconds)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range corsMethods {
+			if routes.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) == 0 {
+			respondWithProblem(w, r, http.StatusNotFound, ProblemTypeNotFound, "No route matches the path")
+			return
+		}
+		allowedMethods := strings.Join(append(allowed, http.MethodOptions), ", ")
+		w.Header().Set("Allow", allowedMethods)
+
+		requested := r.Header.Get("Access-Control-Request-Method")
+		if requested != "" && !slices.Contains(allowed, requested) {
+			respondWithProblem(w, r, http.StatusMethodNotAllowed, ProblemTypeBadRequest, requested+" is not allowed on this route")
+			return
+		}
+
+		// Without an allowed origin, or outside a preflight, only Allow is reported
+		if requested != "" && origins.allowOrigin(w, r) {
+			w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+			if headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			if cfg.MaxAgeSeconds > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// delayPage counts down before sending the visitor on. The meta refresh redirects
+// browsers without JavaScript, the script only keeps the displayed countdown current.
+var delayPage = template.Must(template.New("delay").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="refresh" content="{{.Seconds}}; url={{.Destination}}">
+    <title>Redirecting</title>
+</head>
+<body>
+    <p>You will be redirected to <a href="{{.Destination}}">{{.Destination}}</a> in <span id="countdown">{{.Seconds}}</span> seconds.</p>
+    <script>
+        (function () {
+            var remaining = {{.Seconds}};
+            var countdown = document.getElementById("countdown");
+            var timer = setInterval(function () {
+                remaining--;
+                countdown.textContent = Math.max(remaining, 0);
+                if (remaining <= 0) {
+                    clearInterval(timer);
+                    window.location.replace({{.Destination}});
+                }
+            }, 1000);
+        })();
+    </script>
+</body>
+</html>
+`))
+
+// respondWithDelayPage serves the countdown page that redirects to destination after seconds
+func respondWithDelayPage(w http.ResponseWriter, r *http.Request, destination string, seconds int) {
+	var page bytes.Buffer
+	if err := delayPage.Execute(&page, struct {
+		Destination string
+		Seconds     int
+	}{destination, seconds}); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to render delay page", "error", err)
+		respondWithProblem(w, r, http.StatusInternalServerError, ProblemTypeInternal, "Failed to render redirect page")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Each visit must come back to be counted
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(page.Bytes())
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// HandleBulkDelete deletes short URLs in bulk.
+//
+//	@Summary		Delete URLs in bulk
+//	@Description	Delete up to 500 short URLs of a namespace in one request, together with their variants and recorded clicks. Short codes matching no URL are listed in the result and do not fail the request. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			request		body		application.BulkDeleteRequest	true	"Short codes to delete"
+//	@Param			X-Namespace	header		string							false	"Namespace of the short codes"	default(default)
+//	@Success		200			{object}	application.BulkDeleteResult	"Bulk delete result"
+//	@Failure		400			{object}	ValidationProblemDetail			"Invalid request or validation error"
+//	@Failure		401			{object}	ProblemDetail					"Missing or invalid admin API key"
+//	@Router			/urls/bulk [delete]
+func (h *Handlers) HandleBulkDelete(w http.ResponseWriter, r *http.Request) {
+	namespace, err := namespaceFromRequest(r)
+	if err != nil {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "X-Namespace must be a lowercase slug of letters, digits and hyphens")
+		return
+	}
+
+	var req application.BulkDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to decode request", "error", err)
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.service.BulkDeleteURLs(r.Context(), namespace, req)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			h.handleValidationError(w, r, validationErrors)
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to delete URLs", "namespace", namespace, "error", err)
+		respondWithInternalError(w, r, err, "Failed to delete URLs")
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Deleted short URLs", "namespace", namespace, "deleted", result.Deleted, "not_found", len(result.NotFound))
+	respondWithJSON(w, r.Context(), http.StatusOK, result)
+}
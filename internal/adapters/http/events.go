@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+)
+
+// sseHeartbeatInterval keeps idle streams alive through proxies that drop silent connections
+const sseHeartbeatInterval = 30 * time.Second
+
+type shutdownKey struct{}
+
+// WithShutdown returns a copy of ctx carrying done, a channel closed when the server shuts
+// down. http.Server.Shutdown does not cancel request contexts, so event streams watch done
+// to end instead of holding the shutdown up until the drain timeout.
+func WithShutdown(ctx context.Context, done <-chan struct{}) context.Context {
+	return context.WithValue(ctx, shutdownKey{}, done)
+}
+
+// shutdownFromContext returns the channel stored by WithShutdown, nil when there is none
+func shutdownFromContext(ctx context.Context) <-chan struct{} {
+	done, _ := ctx.Value(shutdownKey{}).(<-chan struct{})
+	return done
+}
+
+// HandleClickEvents streams click notifications for a short URL as server-sent events.
+//
+//	@Summary		Stream click events
+//	@Description	Open a server-sent events stream that emits a JSON event every time the short URL is clicked
+//	@Tags			urls
+//	@Produce		text/event-stream
+//	@Param			shortCode		path		string			true	"Short code"
+//	@Param			p				query		string			false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string			false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string			false	"Namespace of the short code"	default(default)
+//	@Success		200				{string}	string			"Event stream; each data line is a JSON click event"
+//	@Failure		401				{object}	ProblemDetail	"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail	"Short URL not found"
+//	@Failure		503				{object}	ProblemDetail	"Too many open event streams"
+//	@Router			/shorten/{shortCode}/events [get]
+func (h *Handlers) HandleClickEvents(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	events, unsubscribe, err := h.service.SubscribeClicks(url.Namespace, url.ShortCode)
+	if err != nil {
+		if errors.Is(err, pubsub.ErrTooManySubscribers) {
+			respondWithProblem(w, r, http.StatusServiceUnavailable, ProblemTypeUnavailable, "Too many open event streams")
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to subscribe to click events", "error", err)
+		respondWithProblem(w, r, http.StatusInternalServerError, ProblemTypeInternal, "Failed to subscribe to click events")
+		return
+	}
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	// The server write timeout would otherwise cut long-lived streams
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprint(w, ": subscribed\n\n"); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		logging.FromContext(r.Context()).Error("Streaming is not supported by the response writer", "error", err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Click event stream opened", "short_code", shortCode)
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	// A nil channel never fires, so streams outside a server never see a shutdown
+	shutdown := shutdownFromContext(r.Context())
+
+	for {
+		select {
+		case <-r.Context().Done():
+			logging.FromContext(r.Context()).Info("Click event stream closed", "short_code", shortCode)
+			return
+		case <-shutdown:
+			logging.FromContext(r.Context()).Info("Click event stream closed by shutdown", "short_code", shortCode)
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Failed to encode click event", "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
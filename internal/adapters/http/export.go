@@ -0,0 +1,435 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// exportPageSize is the number of URLs read from the repository per query while exporting
+const exportPageSize = 500
+
+// exportColumns is the CSV header row, in the order fields are written
+var exportColumns = []string{"id", "short_code", "original_url", "clicks", "created_at", "redirect_type", "expires_at", "namespace"}
+
+// ExportRecord is a single URL in a bulk export
+type ExportRecord struct {
+	ID           int64      `json:"id" example:"1"`
+	ShortCode    string     `json:"shortCode" example:"abc123"`
+	OriginalURL  string     `json:"originalUrl" example:"https://example.com"`
+	Clicks       int        `json:"clicks" example:"42"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	RedirectType int        `json:"redirectType" example:"301"`
+	ExpiresAt    *time.Time `json:"expiresAt"`
+	Namespace    string     `json:"namespace" example:"default"`
+}
+
+func newExportRecord(url *domain.URL) ExportRecord {
+	return ExportRecord{
+		ID:           url.ID,
+		ShortCode:    url.ShortCode,
+		OriginalURL:  url.OriginalURL,
+		Clicks:       url.Clicks,
+		CreatedAt:    url.CreatedAt,
+		RedirectType: url.EffectiveRedirectType(),
+		ExpiresAt:    url.ExpiresAt,
+		Namespace:    url.Namespace,
+	}
+}
+
+// exportWriter encodes export records onto the response as they are read
+type exportWriter interface {
+	Write(record ExportRecord) error
+	// Flush pushes buffered output to the client
+	Flush() error
+	// Close finishes the document
+	Close() error
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportColumns); err != nil {
+		return nil, err
+	}
+	return &csvExportWriter{w: cw}, nil
+}
+
+func (e *csvExportWriter) Write(record ExportRecord) error {
+	expiresAt := ""
+	if record.ExpiresAt != nil {
+		expiresAt = record.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
+	return e.w.Write([]string{
+		strconv.FormatInt(record.ID, 10),
+		record.ShortCode,
+		record.OriginalURL,
+		strconv.Itoa(record.Clicks),
+		record.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.Itoa(record.RedirectType),
+		expiresAt,
+		record.Namespace,
+	})
+}
+
+func (e *csvExportWriter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExportWriter) Close() error {
+	return e.Flush()
+}
+
+// jsonExportWriter writes a JSON array with one record per line, so the output
+// is both a valid document and easy to process line by line
+type jsonExportWriter struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONExportWriter(w io.Writer) (*jsonExportWriter, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	return &jsonExportWriter{w: w}, nil
+}
+
+func (e *jsonExportWriter) Write(record ExportRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "\n"
+	}
+	e.count++
+
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExportWriter) Flush() error {
+	return nil
+}
+
+func (e *jsonExportWriter) Close() error {
+	_, err := io.WriteString(e.w, "\n]\n")
+	return err
+}
+
+// HandleExport streams every short URL as CSV or JSON.
+//
+//	@Summary		Export all URLs
+//	@Description	Stream every short URL as a CSV or JSON attachment. Only available when admin.api_key and admin.export_enabled are set.
+//	@Tags			admin
+//	@Produce		text/csv
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			format	query		string			false	"Export format"	Enums(csv, json)	default(csv)
+//	@Success		200		{array}		ExportRecord	"Exported URLs"
+//	@Failure		400		{object}	ProblemDetail	"Unsupported format"
+//	@Failure		401		{object}	ProblemDetail	"Missing or invalid admin API key"
+//	@Failure		500		{object}	ProblemDetail	"Internal server error"
+//	@Router			/urls/export [get]
+func (h *Handlers) HandleExport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv"
+	case "json":
+		contentType = "application/json"
+	default:
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "format must be csv or json")
+		return
+	}
+
+	// Read the first page before committing to a 200 so that an unavailable
+	// database still produces a proper error response
+	page, err := h.service.ListURLs(r.Context(), 0, exportPageSize)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to list URLs for export", "error", err)
+		respondWithInternalError(w, r, err, "Failed to export URLs")
+		return
+	}
+
+	filename := fmt.Sprintf("urls-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	var out exportWriter
+	if format == "csv" {
+		out, err = newCSVExportWriter(w)
+	} else {
+		out, err = newJSONExportWriter(w)
+	}
+	if err != nil {
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	exported := 0
+	for {
+		for _, url := range page {
+			if err := out.Write(newExportRecord(url)); err != nil {
+				logging.FromContext(r.Context()).Error("Failed to write export record", "error", err)
+				return
+			}
+		}
+		exported += len(page)
+
+		if err := out.Flush(); err != nil {
+			logging.FromContext(r.Context()).Error("Failed to flush export", "error", err)
+			return
+		}
+		_ = rc.Flush()
+
+		if len(page) < exportPageSize {
+			break
+		}
+
+		page, err = h.service.ListURLs(r.Context(), page[len(page)-1].ID, exportPageSize)
+		if err != nil {
+			// The status line is already sent, so the client sees a truncated file
+			logging.FromContext(r.Context()).Error("Failed to list URLs for export", "exported", exported, "error", err)
+			return
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to finish export", "error", err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("URLs exported", "format", format, "count", exported)
+}
+
+// Click exports read the clicks of a URL a page at a time and stop after
+// maxClickExportRows, reporting the cut in the X-Truncated trailer
+const (
+	clickExportPageSize = 1000
+	maxClickExportRows  = 1_000_000
+)
+
+// clickExportColumns is the CSV header row of a click export, in the order fields are written
+var clickExportColumns = []string{"clicked_at", "ip_address", "user_agent", "referer", "country", "browser", "os", "device_type", "redirect_duration_ms", "variant_id"}
+
+// ClickExportRecord is a single click in a click export
+type ClickExportRecord struct {
+	ClickedAt          time.Time `json:"clickedAt"`
+	IPAddress          string    `json:"ipAddress" example:"203.0.113.7"`
+	UserAgent          string    `json:"userAgent" example:"Mozilla/5.0"`
+	Referer            string    `json:"referer" example:"example.com/blog"`
+	Country            string    `json:"country" example:"DE"`
+	Browser            string    `json:"browser" example:"Chrome"`
+	OS                 string    `json:"os" example:"macOS"`
+	DeviceType         string    `json:"deviceType" example:"desktop"`
+	RedirectDurationMs *float64  `json:"redirectDurationMs" example:"1.4"`
+	VariantID          *int64    `json:"variantId"`
+}
+
+func newClickExportRecord(click *domain.Click) ClickExportRecord {
+	return ClickExportRecord{
+		ClickedAt:          click.ClickedAt,
+		IPAddress:          click.IPAddress,
+		UserAgent:          click.UserAgent,
+		Referer:            click.Referer,
+		Country:            click.Country,
+		Browser:            click.Browser,
+		OS:                 click.OS,
+		DeviceType:         click.DeviceType,
+		RedirectDurationMs: click.RedirectDurationMs,
+		VariantID:          click.VariantID,
+	}
+}
+
+// clickExportWriter encodes click records onto the response as they are read
+type clickExportWriter interface {
+	Write(record ClickExportRecord) error
+	// Flush pushes buffered output to the client
+	Flush() error
+}
+
+type csvClickExportWriter struct {
+	w *csv.Writer
+}
+
+func newCSVClickExportWriter(w io.Writer) (*csvClickExportWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(clickExportColumns); err != nil {
+		return nil, err
+	}
+	return &csvClickExportWriter{w: cw}, nil
+}
+
+func (e *csvClickExportWriter) Write(record ClickExportRecord) error {
+	duration, variantID := "", ""
+	if record.RedirectDurationMs != nil {
+		duration = strconv.FormatFloat(*record.RedirectDurationMs, 'f', -1, 64)
+	}
+	if record.VariantID != nil {
+		variantID = strconv.FormatInt(*record.VariantID, 10)
+	}
+
+	return e.w.Write([]string{
+		record.ClickedAt.UTC().Format(time.RFC3339Nano),
+		record.IPAddress,
+		record.UserAgent,
+		record.Referer,
+		record.Country,
+		record.Browser,
+		record.OS,
+		record.DeviceType,
+		duration,
+		variantID,
+	})
+}
+
+func (e *csvClickExportWriter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// ndjsonClickExportWriter writes one JSON object per line
+type ndjsonClickExportWriter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonClickExportWriter) Write(record ClickExportRecord) error {
+	return e.enc.Encode(record)
+}
+
+func (e *ndjsonClickExportWriter) Flush() error {
+	return nil
+}
+
+// HandleClickExport streams the recorded clicks of a short URL as CSV or NDJSON.
+//
+//	@Summary		Export the clicks of a URL
+//	@Description	Stream every recorded click of a short URL, oldest first, as a CSV or NDJSON attachment. Exports stop after 1,000,000 clicks, in which case the response ends with an X-Truncated: true trailer. Only available when admin.api_key and admin.export_enabled are set.
+//	@Tags			analytics
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Security		AdminAPIKey
+//	@Param			shortCode		path		string				true	"Short code"
+//	@Param			format			query		string				false	"Export format"	Enums(csv, ndjson)	default(csv)
+//	@Param			p				query		string				false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string				false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string				false	"Namespace of the short code"	default(default)
+//	@Success		200				{array}		ClickExportRecord	"Exported clicks"
+//	@Failure		400				{object}	ProblemDetail		"Unsupported format"
+//	@Failure		401				{object}	ProblemDetail		"Missing or invalid admin API key, or password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail		"Short URL not found"
+//	@Failure		500				{object}	ProblemDetail		"Internal server error"
+//	@Router			/shorten/{shortCode}/clicks/export [get]
+func (h *Handlers) HandleClickExport(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv"
+	case "ndjson":
+		contentType = "application/x-ndjson"
+	default:
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "format must be csv or ndjson")
+		return
+	}
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	// As with HandleExport, an unavailable database still gets a proper error response
+	page, err := h.service.ListClicks(r.Context(), url.Namespace, url.ShortCode, domain.ClickCursor{}, min(clickExportPageSize, h.clickExportLimit+1))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to list clicks for export", "short_code", shortCode, "error", err)
+		respondWithInternalError(w, r, err, "Failed to export clicks")
+		return
+	}
+
+	// Whether the export is cut short is only known at its end
+	w.Header().Set("Trailer", "X-Truncated")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-clicks.%s"`, url.ShortCode, format))
+	w.WriteHeader(http.StatusOK)
+
+	var out clickExportWriter
+	if format == "csv" {
+		out, err = newCSVClickExportWriter(w)
+		if err != nil {
+			return
+		}
+	} else {
+		out = &ndjsonClickExportWriter{enc: json.NewEncoder(w)}
+	}
+
+	rc := http.NewResponseController(w)
+	exported, truncated := 0, false
+	for {
+		for _, click := range page {
+			if exported == h.clickExportLimit {
+				truncated = true
+				break
+			}
+			if err := out.Write(newClickExportRecord(click)); err != nil {
+				logging.FromContext(r.Context()).Error("Failed to write click export record", "error", err)
+				return
+			}
+			exported++
+		}
+
+		if err := out.Flush(); err != nil {
+			logging.FromContext(r.Context()).Error("Failed to flush click export", "error", err)
+			return
+		}
+		_ = rc.Flush()
+
+		if truncated || len(page) < clickExportPageSize {
+			break
+		}
+
+		// One click past the limit tells a truncated export from one exactly at the limit
+		page, err = h.service.ListClicks(r.Context(), url.Namespace, url.ShortCode, page[len(page)-1].Cursor(), min(clickExportPageSize, h.clickExportLimit-exported+1))
+		if err != nil {
+			// The status line is already sent, so the client sees a truncated file
+			logging.FromContext(r.Context()).Error("Failed to list clicks for export", "short_code", shortCode, "exported", exported, "error", err)
+			return
+		}
+	}
+
+	if truncated {
+		w.Header().Set("X-Truncated", "true")
+	}
+	logging.FromContext(r.Context()).Info("Clicks exported", "short_code", shortCode, "format", format, "count", exported, "truncated", truncated)
+}
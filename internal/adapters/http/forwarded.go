@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ForwardedHosts builds base URLs from the X-Forwarded-Host and X-Forwarded-Proto headers of
+// a reverse proxy. Only the allowed hosts are trusted, the headers being set by clients as
+// easily as by the proxy.
+type ForwardedHosts struct {
+	allowed map[string]bool
+}
+
+// NewForwardedHosts trusts the forwarded hosts listed, with their port when they have one.
+// Hosts are compared ignoring case.
+func NewForwardedHosts(allowed []string) *ForwardedHosts {
+	hosts := &ForwardedHosts{allowed: make(map[string]bool, len(allowed))}
+	for _, host := range allowed {
+		hosts.allowed[strings.ToLower(strings.TrimSpace(host))] = true
+	}
+	return hosts
+}
+
+// BaseURL returns the base URL the client reached through the proxy, or fallback when the
+// request has no X-Forwarded-Host or its host is not allowed. The scheme of fallback is
+// kept when X-Forwarded-Proto is missing. A nil ForwardedHosts always returns fallback.
+func (f *ForwardedHosts) BaseURL(r *http.Request, fallback string) string {
+	if f == nil {
+		return fallback
+	}
+
+	// Proxies chained behind each other append to the headers, the first value being the
+	// one the client sent to the outermost proxy
+	host := strings.ToLower(strings.TrimSpace(firstValue(r.Header.Get("X-Forwarded-Host"))))
+	if host == "" || !f.allowed[host] {
+		return fallback
+	}
+
+	scheme := strings.ToLower(strings.TrimSpace(firstValue(r.Header.Get("X-Forwarded-Proto"))))
+	if scheme != "http" && scheme != "https" {
+		scheme = "http"
+		if base, err := url.Parse(fallback); err == nil && base.Scheme != "" {
+			scheme = base.Scheme
+		}
+	}
+	return scheme + "://" + host
+}
+
+func firstValue(header string) string {
+	value, _, _ := strings.Cut(header, ",")
+	return value
+}
+
+// withScheme returns baseURL with its scheme replaced by scheme. baseURL is returned as is
+// when scheme is empty or baseURL is no absolute URL.
+func withScheme(baseURL, scheme string) string {
+	if scheme == "" {
+		return baseURL
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return baseURL
+	}
+	base.Scheme = scheme
+	return base.String()
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// Funnel sessions follow a visitor across the steps of a funnel. The cookie is renewed on
+// every funnel click, so a session ends after funnelSessionMaxAge without one.
+const (
+	funnelSessionCookie = "dove_sid"
+	funnelSessionMaxAge = 30 * 60 // seconds
+	funnelSessionBytes  = 16
+)
+
+// funnelSessionID returns the session of the funnel cookie, or a new one when the request
+// carries none or one this service did not issue
+func funnelSessionID(r *http.Request) string {
+	if cookie, err := r.Cookie(funnelSessionCookie); err == nil {
+		if decoded, err := hex.DecodeString(cookie.Value); err == nil && len(decoded) == funnelSessionBytes {
+			return cookie.Value
+		}
+	}
+
+	session := make([]byte, funnelSessionBytes)
+	_, _ = rand.Read(session)
+	return hex.EncodeToString(session)
+}
+
+// recordFunnelStep counts the redirect towards the funnels url is a step of and then keeps
+// the funnel session of the visitor alive. Failures are logged and never fail the redirect.
+func (h *Handlers) recordFunnelStep(w http.ResponseWriter, r *http.Request, url *domain.URL) {
+	if !h.service.FunnelsEnabled() {
+		return
+	}
+
+	sessionID := funnelSessionID(r)
+	inFunnel, err := h.service.RecordFunnelStep(r.Context(), url.Namespace, url.ShortCode, sessionID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to record funnel step", "namespace", url.Namespace, "short_code", url.ShortCode, "error", err)
+	}
+	if !inFunnel {
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     funnelSessionCookie,
+		Value:    sessionID,
+		Path:     "/",
+		MaxAge:   funnelSessionMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// HandleCreateFunnel defines a funnel.
+//
+//	@Summary		Create a funnel
+//	@Description	Define an ordered sequence of existing short URLs whose visitors are followed, through the dove_sid cookie, from one step to the next. Only available when admin.api_key is set and the repository keeps funnels.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			request	body		application.CreateFunnelRequest	true	"Funnel to create"
+//	@Success		201		{object}	domain.Funnel					"Created funnel"
+//	@Failure		400		{object}	ValidationProblemDetail			"Invalid request, validation error or unknown step"
+//	@Failure		401		{object}	ProblemDetail					"Missing or invalid admin API key"
+//	@Failure		500		{object}	ProblemDetail					"Internal server error"
+//	@Router			/admin/funnels [post]
+func (h *Handlers) HandleCreateFunnel(w http.ResponseWriter, r *http.Request) {
+	var req application.CreateFunnelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid request body")
+		return
+	}
+
+	funnel, err := h.service.CreateFunnel(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, application.ErrInvalidFunnel) {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, err.Error())
+			return
+		}
+
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			h.handleValidationError(w, r, validationErrors)
+			return
+		}
+
+		logging.FromContext(r.Context()).Error("Failed to create funnel", "error", err)
+		respondWithInternalError(w, r, err, "Failed to create funnel")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusCreated, funnel)
+}
+
+// HandleFunnelAnalytics reports the completion of a funnel.
+//
+//	@Summary		Get funnel analytics
+//	@Description	Count, for each step of a funnel, the sessions that reached it and every step before it, and their share of the sessions that entered the funnel. Only available when admin.api_key is set and the repository keeps funnels.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			id	path		int									true	"Funnel ID"
+//	@Success		200	{object}	application.FunnelAnalyticsResponse	"Sessions and completion rate per step"
+//	@Failure		400	{object}	ProblemDetail						"Invalid funnel ID"
+//	@Failure		401	{object}	ProblemDetail						"Missing or invalid admin API key"
+//	@Failure		404	{object}	ProblemDetail						"Funnel not found"
+//	@Failure		500	{object}	ProblemDetail						"Internal server error"
+//	@Router			/admin/funnels/{id}/analytics [get]
+func (h *Handlers) HandleFunnelAnalytics(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "id must be a positive integer")
+		return
+	}
+
+	analytics, err := h.service.FunnelAnalytics(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrFunnelNotFound) {
+			respondWithProblem(w, r, http.StatusNotFound, ProblemTypeNotFound, "Funnel not found")
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to get funnel analytics", "funnel_id", id, "error", err)
+		respondWithInternalError(w, r, err, "Failed to get funnel analytics")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, analytics)
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,23 +17,80 @@ import (
 
 	"github.com/sp3dr4/dove/internal/application"
 	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/geoip"
+	"github.com/sp3dr4/dove/internal/pkg/jsonschema"
 	"github.com/sp3dr4/dove/internal/pkg/logging"
+	"github.com/sp3dr4/dove/internal/pkg/useragent"
 )
 
 type Handlers struct {
-	service *application.URLService
-	baseURL string
-	repo    domain.URLRepository
+	service         *application.URLService
+	baseURL         string
+	shortURLScheme  string
+	forwardedHosts  *ForwardedHosts
+	repo            domain.URLRepository
+	cache           domain.Cache
+	degradedCacheOK bool
+	// keyMigrator is cache when its keys can be moved to another prefix
+	keyMigrator domain.CacheKeyMigrator
+	// clickExportLimit is the number of clicks after which a click export is truncated
+	clickExportLimit int
+	// notFoundPage is served by redirects to unknown short codes, nil serves JSON errors
+	notFoundPage *NotFoundPage
 }
 
-func NewHandlers(service *application.URLService, baseURL string, repo domain.URLRepository) *Handlers {
+// HandlersDeps holds the optional dependencies and settings of the URL handlers. Every field
+// may be left zero.
+type HandlersDeps struct {
+	// ShortURLScheme, when set, replaces the scheme of the base URL and forwarded hosts
+	ShortURLScheme string
+	// ForwardedHosts are the forwarded hosts short URLs may be created under, the forwarded
+	// headers being ignored when nil
+	ForwardedHosts *ForwardedHosts
+	// Cache is only pinged by the readiness check and listed by the cache keys endpoint, and
+	// is nil when caching is disabled
+	Cache domain.Cache
+	// DegradedCacheOK keeps the service ready while Cache is down
+	DegradedCacheOK bool
+	// NotFoundPage is served to browsers redirected from unknown short codes, JSON errors
+	// being served when nil
+	NotFoundPage *NotFoundPage
+}
+
+// NewHandlers creates the URL handlers on their required dependencies and the optional
+// ones in deps. Created short URLs are under baseURL, or under the forwarded host of the
+// request when deps.ForwardedHosts allows it.
+func NewHandlers(service *application.URLService, baseURL string, repo domain.URLRepository, deps HandlersDeps) *Handlers {
+	keyMigrator, _ := deps.Cache.(domain.CacheKeyMigrator)
 	return &Handlers{
-		service: service,
-		baseURL: baseURL,
-		repo:    repo,
+		service:          service,
+		baseURL:          withScheme(baseURL, deps.ShortURLScheme),
+		shortURLScheme:   deps.ShortURLScheme,
+		forwardedHosts:   deps.ForwardedHosts,
+		repo:             repo,
+		cache:            deps.Cache,
+		degradedCacheOK:  deps.DegradedCacheOK,
+		keyMigrator:      keyMigrator,
+		clickExportLimit: maxClickExportRows,
+		notFoundPage:     deps.NotFoundPage,
 	}
 }
 
+// Readiness states of a dependency
+const (
+	readinessOK       = "ok"
+	readinessDegraded = "degraded"
+	readinessDisabled = "disabled"
+)
+
+// ReadinessResponse reports the state of each dependency checked by /ready
+type ReadinessResponse struct {
+	Status    string    `json:"status" example:"ready"`
+	DB        string    `json:"db" example:"ok"`
+	Cache     string    `json:"cache" example:"ok" enums:"ok,degraded,disabled"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // HandleHealth handles the health check endpoint.
 //
 //	@Summary		Health check endpoint
@@ -49,129 +108,671 @@ func (h *Handlers) HandleHealth(w http.ResponseWriter, _ *http.Request) {
 // HandleReady handles the readiness check endpoint.
 //
 //	@Summary		Readiness check endpoint
-//	@Description	Check if the service is ready to serve requests (includes database connectivity)
+//	@Description	Check if the service is ready to serve requests. The database must be reachable; an unreachable cache reports "degraded" and, unless server.degraded_cache_ok is false, still answers 200.
 //	@Tags			health
 //	@Produce		json
-//	@Success		200	{object}	object{status=string,timestamp=string}	"Service is ready"
-//	@Failure		503	{object}	ErrorResponse							"Service is not ready"
+//	@Success		200	{object}	ReadinessResponse	"Service is ready, possibly with a degraded cache"
+//	@Failure		503	{object}	ProblemDetail		"Database or, when degraded_cache_ok is false, cache is not reachable"
 //	@Router			/ready [get]
 func (h *Handlers) HandleReady(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
 	if err := h.repo.HealthCheck(ctx); err != nil {
-		w.WriteHeader(http.StatusServiceUnavailable)
 		logging.FromContext(r.Context()).Error("Readiness check failed", "error", err)
+		respondWithProblem(w, r, http.StatusServiceUnavailable, ProblemTypeUnavailable, "Database is not reachable")
 		return
 	}
 
-	respondWithJSON(w, r.Context(), http.StatusOK, map[string]string{
-		"status": "ready",
-	})
+	response := ReadinessResponse{
+		Status:    "ready",
+		DB:        readinessOK,
+		Cache:     readinessDisabled,
+		Timestamp: time.Now().UTC(),
+	}
+	if h.cache != nil {
+		response.Cache = readinessOK
+		if err := h.cache.Ping(ctx); err != nil {
+			if !h.degradedCacheOK {
+				logging.FromContext(r.Context()).Error("Readiness check failed", "error", err)
+				respondWithProblem(w, r, http.StatusServiceUnavailable, ProblemTypeUnavailable, "Cache is not reachable")
+				return
+			}
+			logging.FromContext(r.Context()).Warn("Cache is not reachable, serving degraded", "error", err)
+			response.Cache = readinessDegraded
+		}
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, response)
 }
 
-// HandleShorten handles the URL shortening endpoint.
+// HandleShorten handles the URL shortening endpoint, by the API version of the request.
 //
 //	@Summary		Create a short URL
-//	@Description	Create a shortened URL from a long URL
+//	@Description	Create a shortened URL from a long URL. With dryRun=true the request is checked and the URL it would create returned with a 200, dryRun set, without storing it; the short code may be taken by another creation before the URL is actually created. With signedExpiry the returned shortCode is a signed token that stops resolving once it expires. Behind a proxy trusted by app.trust_forwarded_headers, shortUrl is under the X-Forwarded-Host of the request when it is one of app.allowed_hosts. Requests with Accept: application/vnd.dove.v2+json, or every request when server.default_api_version is v2, use version 2 of the API: the body may also set tags, expiresAt and redirectType, and the response is the full URL metadata of GET /shorten/{shortCode}.
 //	@Tags			urls
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		application.CreateURLRequest	true	"URL to shorten"
-//	@Success		201		{object}	application.URLResponse			"Successfully created short URL"
-//	@Failure		400		{object}	ValidationErrorResponse			"Invalid request or validation error"
-//	@Failure		409		{object}	ErrorResponse					"Short code already exists"
+//	@Produce		application/vnd.dove.v2+json
+//	@Param			request				body		application.CreateURLRequest	true	"URL to shorten, plus tags, expiresAt and redirectType in version 2"
+//	@Param			X-Namespace			header		string							false	"Namespace for the short code when the body names none"
+//	@Param			Accept				header		string							false	"application/vnd.dove.v2+json for version 2 of the API"
+//	@Param			dryRun				query		bool							false	"Check the request without creating the URL"
+//	@Param			X-Dove-Signature	header		string							false	"Hex encoded HMAC-SHA256 of the body, required when security.hmac_enabled is set"
+//	@Success		200					{object}	application.URLResponse			"URL the request would create, with dryRun set"
+//	@Success		201					{object}	application.URLResponse			"Successfully created short URL, as application.URLInfoResponse in version 2"
+//	@Failure		400					{object}	ValidationProblemDetail			"Invalid request or validation error"
+//	@Failure		401					{object}	ProblemDetail					"Signature missing or invalid"
+//	@Failure		406					{object}	ProblemDetail					"Unsupported API version"
+//	@Failure		409					{object}	ProblemDetail					"Short code already exists"
+//	@Failure		422					{object}	SchemaProblemDetail				"URL points to a private network or a blocked domain, alias starts with a digit or alias is a reserved word"
+//	@Failure		504					{object}	ProblemDetail					"Request exceeded its route timeout"
 //	@Router			/shorten [post]
 func (h *Handlers) HandleShorten(w http.ResponseWriter, r *http.Request) {
+	if APIVersionFromContext(r.Context()) == APIVersion2 {
+		h.HandleShortenV2(w, r)
+		return
+	}
+	h.HandleShortenV1(w, r)
+}
+
+// HandleShortenV1 creates a short URL from a version 1 request
+func (h *Handlers) HandleShortenV1(w http.ResponseWriter, r *http.Request) {
 	var req application.CreateURLRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logging.FromContext(r.Context()).Error("Failed to decode request", "error", err)
-		respondWithError(w, r.Context(), http.StatusBadRequest, "Invalid request body")
+	if !h.decodeShortenRequest(w, r, &req) {
 		return
 	}
+	if req.Namespace == "" {
+		req.Namespace = r.Header.Get(namespaceHeader)
+	}
 
-	response, err := h.service.CreateShortURL(r.Context(), req, h.baseURL)
-	if err != nil {
-		if errors.Is(err, domain.ErrShortCodeExists) {
-			respondWithError(w, r.Context(), http.StatusConflict, "Short code already exists")
+	if isDryRun(r) {
+		response, err := h.service.DryRunCreate(h.createURLContext(r), req, h.shortURLBase(r))
+		if err != nil {
+			h.respondWithCreateError(w, r, err)
 			return
 		}
+		respondWithJSON(w, r.Context(), http.StatusOK, response)
+		return
+	}
 
-		var validationErrors validator.ValidationErrors
-		if errors.As(err, &validationErrors) {
-			handleValidationError(w, r.Context(), validationErrors)
+	response, err := h.service.CreateShortURL(h.createURLContext(r), req, h.shortURLBase(r))
+	if err != nil {
+		h.respondWithCreateError(w, r, err)
+		return
+	}
+
+	h.recordShortened(r, response)
+	respondWithJSON(w, r.Context(), http.StatusCreated, response)
+}
+
+// HandleShortenV2 creates a short URL from a version 2 request, which may also set the
+// tags, expiry and redirect type, and answers with the full URL metadata
+func (h *Handlers) HandleShortenV2(w http.ResponseWriter, r *http.Request) {
+	var req application.CreateURLRequestV2
+	if !h.decodeShortenRequest(w, r, &req) {
+		return
+	}
+	if req.Namespace == "" {
+		req.Namespace = r.Header.Get(namespaceHeader)
+	}
+
+	if isDryRun(r) {
+		response, err := h.service.DryRunCreateV2(h.createURLContext(r), req, h.shortURLBase(r))
+		if err != nil {
+			h.respondWithCreateError(w, r, err)
 			return
 		}
+		respondWithVersion2(w, r, http.StatusOK, response)
+		return
+	}
 
-		logging.FromContext(r.Context()).Error("Failed to create short URL", "error", err)
-		respondWithError(w, r.Context(), http.StatusInternalServerError, "Failed to create short URL")
+	response, err := h.service.CreateShortURLV2(h.createURLContext(r), req, h.shortURLBase(r))
+	if err != nil {
+		h.respondWithCreateError(w, r, err)
 		return
 	}
 
-	logging.FromContext(r.Context()).Info("Created short URL", "short_code", response.ShortCode, "original_url", response.OriginalURL)
-	respondWithJSON(w, r.Context(), http.StatusCreated, response)
+	h.recordShortened(r, &response.URLResponse)
+	respondWithVersion2(w, r, http.StatusCreated, response)
+}
+
+// respondWithVersion2 writes response as a version 2 body with the given status
+func respondWithVersion2(w http.ResponseWriter, r *http.Request, status int, response any) {
+	w.Header().Set("Content-Type", vendorMediaType(APIVersion2))
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to encode response", "error", err)
+	}
+}
+
+// isDryRun reports whether r only asks to check its creation, with ?dryRun=true
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	return dryRun
+}
+
+// shortURLBase returns the base URL of the short URLs created by r
+func (h *Handlers) shortURLBase(r *http.Request) string {
+	return withScheme(h.forwardedHosts.BaseURL(r, h.baseURL), h.shortURLScheme)
+}
+
+// decodeShortenRequest checks the body of a creation against its JSON Schema and decodes
+// it into req, answering with a problem and returning false when either fails
+func (h *Handlers) decodeShortenRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	// The schema sees the body as sent, before it is decoded into the struct
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to read request", "error", err)
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid request body")
+		return false
+	}
+	schemaErrors, err := jsonschema.CreateURLRequest.Validate(body)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to decode request", "error", err)
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid request body")
+		return false
+	}
+	if len(schemaErrors) > 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, SchemaProblemDetail{
+			ProblemDetail:    newProblem(r, http.StatusUnprocessableEntity, ProblemTypeSchema, "Request body violates the JSON Schema"),
+			JSONSchemaErrors: schemaErrors,
+		})
+		return false
+	}
+
+	if err := json.Unmarshal(body, req); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to decode request", "error", err)
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid request body")
+		return false
+	}
+	return true
+}
+
+// createURLContext returns the context of r carrying its creator for the service
+func (h *Handlers) createURLContext(r *http.Request) context.Context {
+	return application.WithCreateURLContext(r.Context(), application.CreateURLContext{CreatorIP: clientIP(r)})
+}
+
+// respondWithCreateError answers a failed creation with the problem matching err
+func (h *Handlers) respondWithCreateError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, domain.ErrShortCodeExists) {
+		respondWithProblem(w, r, http.StatusConflict, ProblemTypeConflict, "Short code already exists")
+		return
+	}
+	if errors.Is(err, domain.ErrReservedAlias) {
+		respondWithProblem(w, r, http.StatusUnprocessableEntity, ProblemTypeReservedAlias, "customAlias is a reserved word")
+		return
+	}
+	if errors.Is(err, application.ErrCreateRejected) {
+		respondWithProblem(w, r, http.StatusUnprocessableEntity, ProblemTypeRejected, err.Error())
+		return
+	}
+	if errors.Is(err, application.ErrSigningDisabled) {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "signedExpiry requires a signing secret to be configured")
+		return
+	}
+	if errors.Is(err, application.ErrDelayTooLong) {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, application.ErrExpiryInPast) {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "expiresAt must be in the future")
+		return
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		h.handleValidationError(w, r, validationErrors)
+		return
+	}
+
+	logging.FromContext(r.Context()).Error("Failed to create short URL", "error", err)
+	respondWithInternalError(w, r, err, "Failed to create short URL")
+}
+
+// recordShortened logs a created URL
+func (h *Handlers) recordShortened(r *http.Request, response *application.URLResponse) {
+	logging.FromContext(r.Context()).Info("Created short URL", "namespace", response.Namespace, "short_code", response.ShortCode, "original_url", response.OriginalURL)
+}
+
+// HandleSuggestAliases handles the alias suggestion endpoint.
+//
+//	@Summary		Suggest custom aliases
+//	@Description	Fetch the destination page and propose up to three unused aliases derived from its title. Pages that cannot be fetched yield no suggestions.
+//	@Tags			urls
+//	@Produce		json
+//	@Param			url	query		string									true	"Destination URL"
+//	@Success		200	{object}	application.AliasSuggestionsResponse	"Suggested aliases, possibly none"
+//	@Failure		400	{object}	ProblemDetail							"Missing, invalid or private url"
+//	@Failure		500	{object}	ProblemDetail							"Internal server error"
+//	@Router			/shorten/suggest [get]
+func (h *Handlers) HandleSuggestAliases(w http.ResponseWriter, r *http.Request) {
+	originalURL := r.URL.Query().Get("url")
+
+	suggestions, err := h.service.SuggestAliases(r.Context(), originalURL)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidURL) {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "url must be an absolute http or https URL")
+			return
+		}
+		if errors.Is(err, application.ErrPrivateIPURL) {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "url must not point to a private network")
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to suggest aliases", "url", originalURL, "error", err)
+		respondWithInternalError(w, r, err, "Failed to suggest aliases")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, application.AliasSuggestionsResponse{Suggestions: suggestions})
 }
 
 // HandleRedirect handles the redirect endpoint for both GET and HEAD methods.
 //
 //	@Summary		Redirect to original URL
-//	@Description	Redirect to the original URL using the short code. GET requests increment click count and redirect, HEAD requests only check existence without incrementing clicks.
+//	@Description	Redirect to the original URL using the short code. GET requests increment click count and redirect, HEAD requests only check existence without incrementing clicks. While the database is unreachable and cache.stale_on_error is enabled, stale cached URLs are still served with a Warning: 110 header. Redirects of funnel steps set the dove_sid session cookie, renewed for 30 minutes on every funnel click. With app.custom_404 enabled, browsers redirected from an unknown short code get its HTML page, while clients sending Accept: application/json still get the JSON problem.
 //	@Tags			urls
-//	@Param			shortCode	path	string	true	"Short code"
-//	@Success		301			"Redirect to original URL"
-//	@Failure		404			{object}	ErrorResponse	"Short URL not found"
+//	@Param			shortCode		path	string	true	"Short code"
+//	@Param			p				query	string	false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header	string	false	"Password for protected short URLs"
+//	@Param			X-Namespace		header	string	false	"Namespace of the short code"	default(default)
+//	@Success		301				"Redirect to original URL, or the status chosen as the URL's redirectType"
+//	@Success		302				"Redirect to a geo or device route, a variant of an A/B tested URL or the next target of a pool"
+//	@Success		200				{string}	string			"Countdown page that redirects after the URL's delaySeconds"
+//	@Failure		401				{object}	ProblemDetail	"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail	"Short URL not found"
+//	@Failure		410				{object}	ProblemDetail	"Short URL disabled by an operator"
+//	@Failure		504				{object}	ProblemDetail	"Request exceeded its route timeout"
+//	@Failure		508				{object}	ProblemDetail	"Redirect chain loops back to a short URL already visited"
 //	@Router			/{shortCode} [get]
 //
 //	@Summary		Check short URL existence
-//	@Description	Check if a short URL exists without incrementing click count or following the redirect. Returns the same redirect headers as GET but without response body.
+//	@Description	Check if a short URL exists without incrementing click count or following the redirect. Answers 200 without a body, describing the redirect in X-Original-URL, X-Clicks and X-Created-At headers.
 //	@Tags			urls
-//	@Param			shortCode	path	string	true	"Short code"
-//	@Success		301			"Short URL exists and would redirect"
-//	@Failure		404			{object}	ErrorResponse	"Short URL not found"
+//	@Param			shortCode		path	string	true	"Short code"
+//	@Param			p				query	string	false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header	string	false	"Password for protected short URLs"
+//	@Param			X-Namespace		header	string	false	"Namespace of the short code"	default(default)
+//	@Success		200				"Short URL exists"
+//	@Header			200				{string}	X-Original-URL	"Destination a GET would redirect to"
+//	@Header			200				{integer}	X-Clicks		"Clicks counted so far"
+//	@Header			200				{string}	X-Created-At	"Creation time in RFC 3339"
+//	@Failure		401				{object}	ProblemDetail	"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail	"Short URL not found"
+//	@Failure		410				{object}	ProblemDetail	"Short URL disabled by an operator"
+//	@Failure		504				{object}	ProblemDetail	"Request exceeded its route timeout"
+//	@Failure		508				{object}	ProblemDetail	"Redirect chain loops back to a short URL already visited"
 //	@Router			/{shortCode} [head]
 func (h *Handlers) HandleRedirect(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
 	shortCode := chi.URLParam(r, "shortCode")
 
-	url, err := h.service.GetURL(r.Context(), shortCode)
+	url, ok := h.lookupURLOrNotFound(w, r, shortCode, h.respondWithNotFound)
+	if !ok {
+		return
+	}
+
+	if url.IsExpired(time.Now()) {
+		h.respondWithNotFound(w, r)
+		return
+	}
+	if !url.Enabled {
+		respondWithProblem(w, r, http.StatusGone, ProblemTypeDisabled, "Short URL is disabled")
+		return
+	}
+
+	ua := useragent.Parse(r.UserAgent())
+
+	destination := url.OriginalURL
+	status := url.EffectiveRedirectType()
+	var variantID *int64
+	var targetURL string
+	if geoDestination, ok := url.GeoRoutes.Destination(geoip.CountryFromContext(r.Context())); ok {
+		destination = geoDestination
+	} else if deviceDestination, ok := url.DeviceRoutes.Destination(ua.DeviceType); ok {
+		destination = deviceDestination
+	} else if target, ok := h.service.NextPoolTarget(url); ok {
+		destination = target.URL
+		targetURL = target.URL
+		// Browsers cache permanent redirects, which would pin a visitor to one target
+		status = http.StatusFound
+	} else if variant := url.PickVariant(); variant != nil {
+		destination = variant.OriginalURL
+		variantID = &variant.ID
+		// Browsers cache permanent redirects, which would pin a visitor to one variant
+		// and hide their later visits from the split
+		status = http.StatusFound
+	}
+	if len(url.GeoRoutes) > 0 || len(url.DeviceRoutes) > 0 {
+		// Routed redirects, fallback included, must not be cached by shared caches
+		// as other visitors are sent elsewhere
+		status = http.StatusFound
+	}
+
+	destination, err := h.service.FollowRedirectChain(r.Context(), url, destination)
 	if err != nil {
-		if errors.Is(err, domain.ErrURLNotFound) {
-			respondWithError(w, r.Context(), http.StatusNotFound, "Short URL not found")
+		if errors.Is(err, domain.ErrRedirectLoop) {
+			logging.FromContext(r.Context()).Warn("Redirect loop detected", "namespace", url.Namespace, "short_code", url.ShortCode)
+			respondWithProblem(w, r, http.StatusLoopDetected, ProblemTypeLoopDetected, "The short URL redirects back to itself")
 			return
 		}
-		logging.FromContext(r.Context()).Error("Failed to get URL", "error", err)
-		respondWithError(w, r.Context(), http.StatusInternalServerError, "Failed to get URL")
+		logging.FromContext(r.Context()).Error("Failed to follow redirect chain", "error", err)
+		respondWithInternalError(w, r, err, "Failed to get URL")
 		return
 	}
 
 	// For HEAD requests, don't increment clicks (HEAD is typically used for checking existence)
 	// For GET requests, increment clicks as normal
 	if r.Method == http.MethodGet {
-		updatedURL, err := h.service.IncrementClicks(r.Context(), shortCode)
+		updatedURL, err := h.service.IncrementClicks(r.Context(), url.Namespace, url.ShortCode, application.ClickDetails{
+			Referer:    r.Referer(),
+			Browser:    ua.Browser,
+			OS:         ua.OS,
+			DeviceType: ua.DeviceType,
+			VariantID:  variantID,
+			TargetURL:  targetURL,
+			// Measured before recording the click, which is all that is left before the redirect
+			RedirectDuration: time.Since(start),
+			IP:               clientIP(r),
+			UserAgent:        r.UserAgent(),
+			Country:          geoip.CountryFromContext(r.Context()),
+		})
 		if err != nil {
 			logging.FromContext(r.Context()).Error("Failed to increment clicks", "error", err)
 			// Continue with redirect even if click increment fails
-			logging.FromContext(r.Context()).Info("Redirecting", "method", r.Method, "short_code", shortCode, "original_url", url.OriginalURL, "clicks", url.Clicks)
+			logging.FromContext(r.Context()).Info("Redirecting", "method", r.Method, "short_code", shortCode, "original_url", destination, "clicks", url.Clicks)
 		} else {
-			logging.FromContext(r.Context()).Info("Redirecting", "method", r.Method, "short_code", shortCode, "original_url", url.OriginalURL, "clicks", updatedURL.Clicks)
+			logging.FromContext(r.Context()).Info("Redirecting", "method", r.Method, "short_code", shortCode, "original_url", destination, "clicks", updatedURL.Clicks)
 		}
 	} else {
-		// HEAD request - just log without incrementing clicks
-		logging.FromContext(r.Context()).Info("Head check", "method", r.Method, "short_code", shortCode, "original_url", url.OriginalURL, "clicks", url.Clicks)
+		// HEAD request - describe the redirect without following it or counting a click
+		logging.FromContext(r.Context()).Info("Head check", "method", r.Method, "short_code", shortCode, "original_url", destination, "clicks", url.Clicks)
+		w.Header().Set("X-Original-URL", destination)
+		w.Header().Set("X-Clicks", strconv.Itoa(url.Clicks))
+		w.Header().Set("X-Created-At", url.CreatedAt.UTC().Format(time.RFC3339))
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	h.recordFunnelStep(w, r, url)
+
+	if url.DelaySeconds > 0 {
+		respondWithDelayPage(w, r, destination, url.DelaySeconds)
+		return
+	}
+	http.Redirect(w, r, destination, status)
+}
+
+// HandleNamespacedRedirect handles redirects addressed by a namespace path prefix.
+//
+//	@Summary		Redirect to original URL within a namespace
+//	@Description	Same as GET /{shortCode}, with the namespace taken from the path instead of the X-Namespace header
+//	@Tags			urls
+//	@Param			namespace		path	string	true	"Namespace"
+//	@Param			shortCode		path	string	true	"Short code"
+//	@Param			p				query	string	false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header	string	false	"Password for protected short URLs"
+//	@Success		301				"Redirect to original URL"
+//	@Success		302				"Redirect to a geo or device route, a variant of an A/B tested URL or the next target of a pool"
+//	@Success		200				{string}	string			"Countdown page that redirects after the URL's delaySeconds"
+//	@Failure		401				{object}	ProblemDetail	"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail	"Short URL not found"
+//	@Failure		410				{object}	ProblemDetail	"Short URL disabled by an operator"
+//	@Failure		504				{object}	ProblemDetail	"Request exceeded its route timeout"
+//	@Failure		508				{object}	ProblemDetail	"Redirect chain loops back to a short URL already visited"
+//	@Router			/{namespace}/{shortCode} [get]
+//
+//	@Summary		Check short URL existence within a namespace
+//	@Description	Same as HEAD /{shortCode}, with the namespace taken from the path instead of the X-Namespace header
+//	@Tags			urls
+//	@Param			namespace		path	string	true	"Namespace"
+//	@Param			shortCode		path	string	true	"Short code"
+//	@Param			p				query	string	false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header	string	false	"Password for protected short URLs"
+//	@Success		200				"Short URL exists"
+//	@Header			200				{string}	X-Original-URL	"Destination a GET would redirect to"
+//	@Header			200				{integer}	X-Clicks		"Clicks counted so far"
+//	@Header			200				{string}	X-Created-At	"Creation time in RFC 3339"
+//	@Failure		401				{object}	ProblemDetail	"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail	"Short URL not found"
+//	@Failure		410				{object}	ProblemDetail	"Short URL disabled by an operator"
+//	@Failure		504				{object}	ProblemDetail	"Request exceeded its route timeout"
+//	@Failure		508				{object}	ProblemDetail	"Redirect chain loops back to a short URL already visited"
+//	@Router			/{namespace}/{shortCode} [head]
+func (h *Handlers) HandleNamespacedRedirect(w http.ResponseWriter, r *http.Request) {
+	h.HandleRedirect(w, r)
+}
+
+// HandlePreview handles the URL preview endpoint.
+//
+//	@Summary		Preview a short URL
+//	@Description	Show where a short URL points without following the redirect or counting a click. Supports conditional requests via ETag and Last-Modified.
+//	@Tags			urls
+//	@Produce		json
+//	@Param			shortCode			path		string					true	"Short code"
+//	@Param			p					query		string					false	"Password for protected short URLs"
+//	@Param			X-URL-Password		header		string					false	"Password for protected short URLs"
+//	@Param			X-Namespace			header		string					false	"Namespace of the short code"	default(default)
+//	@Param			If-None-Match		header		string					false	"ETag from a previous response"
+//	@Param			If-Modified-Since	header		string					false	"Last-Modified from a previous response"
+//	@Success		200					{object}	application.URLResponse	"Short URL details"
+//	@Success		304					"Not modified since the supplied validators"
+//	@Failure		401					{object}	ProblemDetail	"Password missing or invalid"
+//	@Failure		404					{object}	ProblemDetail	"Short URL not found"
+//	@Router			/shorten/{shortCode}/preview [get]
+func (h *Handlers) HandlePreview(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
 	}
 
-	http.Redirect(w, r, url.OriginalURL, http.StatusMovedPermanently)
+	etag := computeETag(url)
+	w.Header().Set("ETag", etag)
+	if !url.UpdatedAt.IsZero() {
+		w.Header().Set("Last-Modified", url.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, url.UpdatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, application.NewURLResponse(url, h.baseURL))
 }
 
-// ErrorResponse represents an error response.
-type ErrorResponse struct {
-	Error     map[string]string `json:"error"`
-	Timestamp string            `json:"timestamp" example:"2024-01-31T12:00:00Z"`
+// HandleURLInfo handles the short URL metadata endpoint.
+//
+//	@Summary		Get short URL details
+//	@Description	Return the full metadata and statistics of a short URL without following the redirect or counting a click
+//	@Tags			urls
+//	@Produce		json
+//	@Param			shortCode		path		string						true	"Short code"
+//	@Param			p				query		string						false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string						false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string						false	"Namespace of the short code"	default(default)
+//	@Success		200				{object}	application.URLInfoResponse	"Short URL metadata"
+//	@Failure		401				{object}	ProblemDetail				"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail				"Short URL not found"
+//	@Router			/shorten/{shortCode} [get]
+func (h *Handlers) HandleURLInfo(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, application.NewURLInfoResponse(url, h.baseURL))
 }
 
-// ValidationErrorResponse represents a validation error response.
-type ValidationErrorResponse struct {
-	Details map[string]string `json:"details"`
-	Error   string            `json:"error" example:"Validation failed"`
+// HandlePatchURL handles partial updates of a short URL.
+//
+//	@Summary		Update a short URL
+//	@Description	Change only the fields present in the body, following JSON merge patch. A null expiresAt removes the expiry. Only available when admin.api_key is set.
+//	@Tags			urls
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			shortCode		path		string						true	"Short code"
+//	@Param			request			body		application.PatchURLRequest	true	"Fields to change"
+//	@Param			p				query		string						false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string						false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string						false	"Namespace of the short code"	default(default)
+//	@Success		200				{object}	application.URLInfoResponse	"Updated short URL"
+//	@Failure		400				{object}	ValidationProblemDetail		"Invalid request or validation error"
+//	@Failure		401				{object}	ProblemDetail				"Missing or invalid admin API key, or password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail				"Short URL not found"
+//	@Router			/shorten/{shortCode} [patch]
+func (h *Handlers) HandlePatchURL(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	var req application.PatchURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to decode request", "error", err)
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid request body")
+		return
+	}
+
+	response, err := h.service.PatchURL(r.Context(), url.Namespace, url.ShortCode, req, h.baseURL)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrors):
+			h.handleValidationError(w, r, validationErrors)
+		case errors.Is(err, application.ErrExpiryInPast):
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "expiresAt must be in the future")
+		case errors.Is(err, domain.ErrURLNotFound):
+			respondWithProblem(w, r, http.StatusNotFound, ProblemTypeNotFound, "Short URL not found")
+		default:
+			logging.FromContext(r.Context()).Error("Failed to update URL", "short_code", url.ShortCode, "error", err)
+			respondWithInternalError(w, r, err, "Failed to update URL")
+		}
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Updated short URL", "namespace", url.Namespace, "short_code", url.ShortCode)
+	respondWithJSON(w, r.Context(), http.StatusOK, response)
+}
+
+// HandleURLHealth handles the destination health endpoint.
+//
+//	@Summary		Destination health
+//	@Description	Report the last known health of the destination URL as recorded by the background checker. The destination is not probed by this request.
+//	@Tags			urls
+//	@Produce		json
+//	@Param			shortCode		path		string							true	"Short code"
+//	@Param			p				query		string							false	"Password for protected short URLs"
+//	@Param			X-URL-Password	header		string							false	"Password for protected short URLs"
+//	@Param			X-Namespace		header		string							false	"Namespace of the short code"	default(default)
+//	@Success		200				{object}	application.URLHealthResponse	"Last known destination health"
+//	@Failure		401				{object}	ProblemDetail					"Password missing or invalid"
+//	@Failure		404				{object}	ProblemDetail					"Short URL not found"
+//	@Router			/shorten/{shortCode}/health [get]
+func (h *Handlers) HandleURLHealth(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	url, ok := h.lookupURL(w, r, shortCode)
+	if !ok {
+		return
+	}
+
+	status := url.HealthStatus
+	if status == "" {
+		status = domain.HealthStatusUnknown
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, application.URLHealthResponse{
+		ShortCode:     url.ShortCode,
+		HealthStatus:  status,
+		LastCheckedAt: url.LastCheckedAt,
+	})
+}
+
+// lookupURL resolves a short code within the request namespace and enforces its password,
+// writing the error response itself. It returns false when the caller should stop handling the request.
+func (h *Handlers) lookupURL(w http.ResponseWriter, r *http.Request, shortCode string) (*domain.URL, bool) {
+	return h.lookupURLOrNotFound(w, r, shortCode, respondWithURLNotFound)
+}
+
+// lookupURLOrNotFound is lookupURL answering unknown short codes with notFound
+func (h *Handlers) lookupURLOrNotFound(w http.ResponseWriter, r *http.Request, shortCode string, notFound http.HandlerFunc) (*domain.URL, bool) {
+	namespace, err := namespaceFromRequest(r)
+	if err != nil {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "X-Namespace must be a lowercase slug of letters, digits and hyphens")
+		return nil, false
+	}
+
+	url, err := h.service.ResolveURL(r.Context(), namespace, shortCode)
+	if err != nil {
+		if errors.Is(err, domain.ErrURLNotFound) {
+			notFound(w, r)
+			return nil, false
+		}
+		logging.FromContext(r.Context()).Error("Failed to get URL", "error", err)
+		respondWithInternalError(w, r, err, "Failed to get URL")
+		return nil, false
+	}
+
+	if err := h.service.VerifyPassword(url, passwordFromRequest(r)); err != nil {
+		if errors.Is(err, domain.ErrInvalidPassword) {
+			logging.FromContext(r.Context()).Warn("Rejected access to protected URL", "short_code", shortCode)
+			respondWithProblem(w, r, http.StatusUnauthorized, ProblemTypeUnauthorized, "Password missing or invalid")
+			return nil, false
+		}
+		logging.FromContext(r.Context()).Error("Failed to verify password", "error", err)
+		respondWithInternalError(w, r, err, "Failed to get URL")
+		return nil, false
+	}
+
+	if url.Stale {
+		w.Header().Set("Warning", `110 - "Response is Stale"`)
+	}
+
+	return url, true
+}
+
+// respondWithURLNotFound answers with the problem of a short code that is not found
+func respondWithURLNotFound(w http.ResponseWriter, r *http.Request) {
+	respondWithProblem(w, r, http.StatusNotFound, ProblemTypeNotFound, "Short URL not found")
+}
+
+// namespaceHeader names the namespace of requests that do not carry it in the path
+const namespaceHeader = "X-Namespace"
+
+// namespaceFromRequest returns the namespace a request addresses: the {namespace} path
+// segment, else the X-Namespace header, else the default namespace. A path segment is
+// not validated since an invalid slug simply matches no URL.
+func namespaceFromRequest(r *http.Request) (string, error) {
+	if namespace := chi.URLParam(r, "namespace"); namespace != "" {
+		return namespace, nil
+	}
+
+	namespace := r.Header.Get(namespaceHeader)
+	if namespace == "" {
+		return domain.DefaultNamespace, nil
+	}
+	if err := domain.ValidateNamespace(namespace); err != nil {
+		return "", err
+	}
+	return namespace, nil
+}
+
+// passwordFromRequest returns the passphrase supplied for a protected URL,
+// preferring the ?p= query parameter over the X-URL-Password header
+func passwordFromRequest(r *http.Request) string {
+	if password := r.URL.Query().Get("p"); password != "" {
+		return password
+	}
+	return r.Header.Get("X-URL-Password")
 }
 
 func respondWithJSON(w http.ResponseWriter, ctx context.Context, code int, payload interface{}) {
@@ -182,63 +783,127 @@ func respondWithJSON(w http.ResponseWriter, ctx context.Context, code int, paylo
 	}
 }
 
-func respondWithError(w http.ResponseWriter, ctx context.Context, code int, message string) {
-	respondWithJSON(w, ctx, code, map[string]interface{}{
-		"error": map[string]string{
-			"message": message,
-		},
-		"timestamp": time.Now().Format(time.RFC3339),
+func (h *Handlers) handleValidationError(w http.ResponseWriter, r *http.Request, validationErrors validator.ValidationErrors) {
+	aliases := h.service.AliasPolicy()
+	details := make([]ValidationFieldError, 0, len(validationErrors))
+	for _, e := range validationErrors {
+		field := getJSONFieldName(e)
+		message, code := validationMessage(field, e, aliases)
+		details = append(details, ValidationFieldError{Field: field, Message: message, Code: code})
+	}
+
+	writeProblem(w, r, http.StatusBadRequest, ValidationProblemDetail{
+		ProblemDetail: newProblem(r, http.StatusBadRequest, ProblemTypeValidation, "Validation failed"),
+		Details:       details,
 	})
 }
 
-func handleValidationError(w http.ResponseWriter, ctx context.Context, validationErrors validator.ValidationErrors) {
-	errorMessages := make(map[string]string)
-	for _, e := range validationErrors {
-		field := getJSONFieldName(e)
-		switch e.Tag() {
-		case "required":
-			errorMessages[field] = fmt.Sprintf("%s is required", field)
-		case "url":
-			errorMessages[field] = fmt.Sprintf("%s must be a valid URL", field)
-		case "alphanum":
-			errorMessages[field] = fmt.Sprintf("%s must contain only alphanumeric characters", field)
-		case "min":
-			errorMessages[field] = fmt.Sprintf("%s must be at least %s characters long", field, e.Param())
-		case "max":
-			errorMessages[field] = fmt.Sprintf("%s must be at most %s characters long", field, e.Param())
+// validationMessage describes the rule field broke, for people and as a validation code.
+// Custom aliases are explained against aliases, the policy they were checked with.
+func validationMessage(field string, e validator.FieldError, aliases application.AliasPolicy) (string, string) {
+	// The custom alias has codes of its own, clients build alias pickers around them
+	alias := e.StructField() == "CustomAlias"
+
+	switch e.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field), ValidationCodeRequired
+	case "required_without":
+		return fmt.Sprintf("%s is required unless %s is given", field, strings.ToLower(e.Param())), ValidationCodeRequired
+	case "excluded_with":
+		return fmt.Sprintf("%s cannot be combined with %s", field, strings.ToLower(e.Param())), ValidationCodeConflictingField
+	case "url":
+		return fmt.Sprintf("%s must be a valid URL", field), ValidationCodeInvalidURLFormat
+	case "safeurl":
+		value, _ := e.Value().(string)
+		switch err := application.CheckURLSafety(value); {
+		case errors.Is(err, application.ErrUnsafeURLScheme):
+			return fmt.Sprintf("%s must not use the data, javascript, vbscript or blob scheme", field), ValidationCodeUnsafeURLScheme
+		case errors.Is(err, application.ErrPrivateIPURL):
+			return fmt.Sprintf("%s must not point to localhost or a private network", field), ValidationCodePrivateIPURL
 		default:
-			errorMessages[field] = fmt.Sprintf("%s is invalid", field)
+			return fmt.Sprintf("%s must be a valid URL", field), ValidationCodeInvalidURLFormat
+		}
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(e.Param(), " ", ", ")), ValidationCodeInvalidChoice
+	case "iso3166_1_alpha2":
+		return fmt.Sprintf("%s must be an upper case ISO 3166-1 alpha-2 country code", field), ValidationCodeInvalidCountry
+	case "unique":
+		return fmt.Sprintf("%s must not contain duplicate entries", field), ValidationCodeDuplicateEntries
+	case "duration":
+		return fmt.Sprintf("%s must be a positive duration such as 2h or 30m", field), ValidationCodeInvalidDuration
+	case "customalias":
+		value, _ := e.Value().(string)
+		switch err := aliases.Check(value); {
+		case errors.Is(err, application.ErrAliasTooLong):
+			return fmt.Sprintf("%s must be at most %d characters long", field, aliases.MaxAliasLength()), ValidationCodeAliasTooLong
+		case errors.Is(err, application.ErrAliasReserved):
+			return fmt.Sprintf("%s is reserved", field), ValidationCodeAliasReserved
+		default:
+			return fmt.Sprintf("%s must contain only %s", field, aliases.AllowedCharacters()), ValidationCodeAliasInvalidChars
+		}
+	case "min":
+		if alias {
+			return boundMessage(field, "at least", e), ValidationCodeAliasTooShort
+		}
+		return boundMessage(field, "at least", e), ValidationCodeTooSmall
+	case "max":
+		if alias {
+			return boundMessage(field, "at most", e), ValidationCodeAliasTooLong
 		}
+		return boundMessage(field, "at most", e), ValidationCodeTooLarge
+	case "namespace":
+		return fmt.Sprintf("%s must be a lowercase slug of letters, digits and hyphens and not a reserved name", field), ValidationCodeInvalidNamespace
+	default:
+		return fmt.Sprintf("%s is invalid", field), ValidationCodeInvalid
 	}
+}
 
-	respondWithJSON(w, ctx, http.StatusBadRequest, map[string]interface{}{
-		"error":   "Validation failed",
-		"details": errorMessages,
-	})
+// boundMessage describes a failed min or max rule in the unit of the field's kind
+func boundMessage(field, bound string, e validator.FieldError) string {
+	switch e.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("%s must be %s %s characters long", field, bound, e.Param())
+	case reflect.Slice:
+		return fmt.Sprintf("%s must have %s %s entries", field, bound, e.Param())
+	default:
+		return fmt.Sprintf("%s must be %s %s", field, bound, e.Param())
+	}
 }
 
-// getJSONFieldName extracts the JSON tag name from a validation error
+// getJSONFieldName extracts the JSON path of the field behind a validation error,
+// e.g. "customAlias" or "variants[0].url" for fields of nested structs
 func getJSONFieldName(e validator.FieldError) string {
 	structType := getStructTypeFromError(e)
 	if structType == nil {
 		return e.Field()
 	}
 
-	field, found := structType.FieldByName(e.StructField())
-	if !found {
-		return e.Field()
-	}
+	parts := strings.Split(e.StructNamespace(), ".")[1:]
+	path := make([]string, 0, len(parts))
+	for _, part := range parts {
+		fieldName, index, indexed := strings.Cut(part, "[")
 
-	jsonTag := field.Tag.Get("json")
-	if jsonTag == "" {
-		return e.Field()
-	}
+		field, found := structType.FieldByName(fieldName)
+		if !found {
+			return e.Field()
+		}
 
-	if commaIndex := strings.Index(jsonTag, ","); commaIndex != -1 {
-		jsonTag = jsonTag[:commaIndex]
+		name := field.Name
+		if jsonTag := field.Tag.Get("json"); jsonTag != "" {
+			name, _, _ = strings.Cut(jsonTag, ",")
+		}
+		if indexed {
+			name += "[" + index
+		}
+		path = append(path, name)
+
+		structType = field.Type
+		for structType.Kind() == reflect.Slice || structType.Kind() == reflect.Pointer {
+			structType = structType.Elem()
+		}
 	}
 
-	return jsonTag
+	return strings.Join(path, ".")
 }
 
 // getStructTypeFromError extracts the struct type from a validation error
@@ -262,6 +927,10 @@ func getTypeFromStructName(structName string) reflect.Type {
 	switch structName {
 	case "CreateURLRequest":
 		return reflect.TypeOf(application.CreateURLRequest{})
+	case "PatchURLRequest":
+		return reflect.TypeOf(application.PatchURLRequest{})
+	case "CreateURLSettings":
+		return reflect.TypeOf(application.CreateURLSettings{})
 	// Add more request types here as needed
 	// case "UpdateURLRequest":
 	//     return reflect.TypeOf(application.UpdateURLRequest{})
@@ -1,67 +1,435 @@
 package http
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/sp3dr4/dove/config"
 	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
 	"github.com/sp3dr4/dove/internal/infrastructure/cache"
 	"github.com/sp3dr4/dove/internal/infrastructure/memory"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/geoip"
+	"github.com/sp3dr4/dove/internal/pkg/jsonschema"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+	"github.com/sp3dr4/dove/internal/pkg/ratelimit"
+	"github.com/sp3dr4/dove/internal/pkg/shortcode"
 )
 
 func TestHandlers_HandleShorten_ValidationErrorCasing(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	repo := memory.NewURLRepository(logger)
+	repo := memory.NewURLRepository(logger, 0)
 	noopCache := cache.NewNoOpCache()
-	service := application.NewURLService(repo, noopCache, 10*time.Minute, logger)
-	handlers := NewHandlers(service, "http://localhost:8080", repo)
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
 
 	tests := []struct {
-		name           string
-		payload        string
-		expectedFields []string
+		name          string
+		payload       string
+		expectedCodes map[string]string
 	}{
 		{
-			name:           "invalid customAlias should return customAlias in error",
-			payload:        `{"url": "https://example.com", "customAlias": "ab"}`,
-			expectedFields: []string{"customAlias"},
+			name:          "invalid customAlias should return customAlias in error",
+			payload:       `{"url": "https://example.com", "customAlias": "ab"}`,
+			expectedCodes: map[string]string{"customAlias": ValidationCodeAliasTooShort},
+		},
+		{
+			name:          "missing url should return url in error",
+			payload:       `{"customAlias": "validalias"}`,
+			expectedCodes: map[string]string{"url": ValidationCodeRequired},
+		},
+		{
+			name:          "invalid url should return url in error",
+			payload:       `{"url": "not-a-url", "customAlias": "validalias"}`,
+			expectedCodes: map[string]string{"url": ValidationCodeInvalidURLFormat},
+		},
+		{
+			name:          "multiple validation errors should return correct field names",
+			payload:       `{"url": "not-a-url", "customAlias": "ab"}`,
+			expectedCodes: map[string]string{"url": ValidationCodeInvalidURLFormat, "customAlias": ValidationCodeAliasTooShort},
+		},
+		{
+			name:          "customAlias too long",
+			payload:       `{"url": "https://example.com", "customAlias": "abcdefghijklmnopqrstuvwxyz"}`,
+			expectedCodes: map[string]string{"customAlias": ValidationCodeAliasTooLong},
 		},
 		{
-			name:           "missing url should return url in error",
-			payload:        `{"customAlias": "validalias"}`,
-			expectedFields: []string{"url"},
+			name:          "customAlias with punctuation",
+			payload:       `{"url": "https://example.com", "customAlias": "my-alias"}`,
+			expectedCodes: map[string]string{"customAlias": ValidationCodeAliasInvalidChars},
 		},
 		{
-			name:           "invalid url should return url in error",
-			payload:        `{"url": "not-a-url", "customAlias": "validalias"}`,
-			expectedFields: []string{"url"},
+			name:          "reserved customAlias",
+			payload:       `{"url": "https://example.com", "customAlias": "admin"}`,
+			expectedCodes: map[string]string{"customAlias": ValidationCodeAliasReserved},
 		},
 		{
-			name:           "multiple validation errors should return correct field names",
-			payload:        `{"url": "not-a-url", "customAlias": "ab"}`,
-			expectedFields: []string{"url", "customAlias"},
+			name:          "reserved customAlias ignoring case",
+			payload:       `{"url": "https://example.com", "customAlias": "Swagger"}`,
+			expectedCodes: map[string]string{"customAlias": ValidationCodeAliasReserved},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			details := performValidationTest(t, handlers, tt.payload)
-			checkExpectedFields(t, details, tt.expectedFields)
-			checkNoUnexpectedFields(t, details, tt.expectedFields)
+
+			codes := make(map[string]string, len(details))
+			for field, detail := range details {
+				codes[field] = detail.Code
+			}
+			assert.Equal(t, tt.expectedCodes, codes)
+		})
+	}
+}
+
+func TestHandlers_HandleShorten_CustomAliasPolicy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	policy := &application.AliasPolicy{MaxLength: 10, AllowHyphens: true, ReservedWords: []string{"promo"}}
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{Aliases: policy}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	t.Run("accepts hyphens", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com", "customAlias": "my-alias"}`))
+		w := httptest.NewRecorder()
+		handlers.HandleShorten(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	})
+
+	tests := []struct {
+		name        string
+		customAlias string
+		expected    ValidationFieldError
+	}{
+		{
+			name:        "underscores",
+			customAlias: "my_alias",
+			expected:    ValidationFieldError{Field: "customAlias", Message: "customAlias must contain only letters, digits and hyphens", Code: ValidationCodeAliasInvalidChars},
+		},
+		{
+			name:        "beyond the configured length",
+			customAlias: "abcdefghijk",
+			expected:    ValidationFieldError{Field: "customAlias", Message: "customAlias must be at most 10 characters long", Code: ValidationCodeAliasTooLong},
+		},
+		{
+			name:        "built-in reserved word",
+			customAlias: "api",
+			expected:    ValidationFieldError{Field: "customAlias", Message: "customAlias is reserved", Code: ValidationCodeAliasReserved},
+		},
+		{
+			name:        "configured reserved word",
+			customAlias: "PROMO",
+			expected:    ValidationFieldError{Field: "customAlias", Message: "customAlias is reserved", Code: ValidationCodeAliasReserved},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := performValidationTest(t, handlers, `{"url": "https://example.com", "customAlias": "`+tt.customAlias+`"}`)
+			assert.Equal(t, map[string]ValidationFieldError{"customAlias": tt.expected}, details)
+		})
+	}
+}
+
+func TestHandlers_HandleShorten_UnsafeURLs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	unsafeScheme := ValidationFieldError{Field: "url", Message: "url must not use the data, javascript, vbscript or blob scheme", Code: ValidationCodeUnsafeURLScheme}
+	privateIP := ValidationFieldError{Field: "url", Message: "url must not point to localhost or a private network", Code: ValidationCodePrivateIPURL}
+
+	tests := []struct {
+		name     string
+		url      string
+		expected ValidationFieldError
+	}{
+		{name: "data URL", url: "data:text/html,<script>alert(1)</script>", expected: unsafeScheme},
+		{name: "javascript URL", url: "javascript:alert(document.cookie)", expected: unsafeScheme},
+		{name: "javascript URL in upper case", url: "JavaScript:alert(1)", expected: unsafeScheme},
+		{name: "vbscript URL", url: "vbscript:msgbox(1)", expected: unsafeScheme},
+		{name: "blob URL", url: "blob:https://example.com/550e8400-e29b-41d4-a716-446655440000", expected: unsafeScheme},
+		// Ranges the JSON Schema leaves to struct validation
+		{name: "cloud metadata address", url: "http://169.254.169.254/latest/meta-data", expected: privateIP},
+		{name: "unspecified address", url: "http://0.0.0.0:8080", expected: privateIP},
+		{name: "unique local IPv6 address", url: "http://[fd00::1]/", expected: privateIP},
+		{name: "IPv4-mapped private address", url: "http://[::ffff:192.168.1.1]/", expected: privateIP},
+		{name: "localhost subdomain", url: "http://app.localhost/", expected: privateIP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := json.Marshal(map[string]string{"url": tt.url})
+			require.NoError(t, err)
+
+			details := performValidationTest(t, handlers, string(payload))
+			assert.Equal(t, map[string]ValidationFieldError{"url": tt.expected}, details)
+		})
+	}
+
+	for _, url := range []string{"https://example.com", "https://8.8.8.8/dns-query", "https://docs.example.org:8443/guide?lang=en#install", "https://[2606:4700::1111]/"} {
+		t.Run(url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handlers.HandleShorten(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "`+url+`"}`)))
+			assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestHandlers_UnsafeDestinations(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Post("/shorten", handlers.HandleShorten)
+	router.Patch("/shorten/{shortCode}", handlers.HandlePatchURL)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com", CustomAlias: "patchme"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	payloads := map[string]func(target string) (string, any){
+		"geo route": func(target string) (string, any) {
+			return "/shorten", map[string]any{"url": "https://example.com", "geoRoutes": []map[string]string{{"countryCode": "US", "destinationUrl": target}}}
+		},
+		"device route": func(target string) (string, any) {
+			return "/shorten", map[string]any{"url": "https://example.com", "deviceRoutes": []map[string]string{{"deviceType": "mobile", "destinationUrl": target}}}
+		},
+		"pool target": func(target string) (string, any) {
+			return "/shorten", map[string]any{"pool": map[string]any{"targets": []map[string]any{{"url": "https://example.com", "weight": 1}, {"url": target, "weight": 1}}}}
+		},
+		"variant": func(target string) (string, any) {
+			return "/shorten", map[string]any{"url": "https://example.com", "variants": []map[string]any{{"url": target, "weight": 50}}}
+		},
+		"patched original URL": func(target string) (string, any) {
+			return "/shorten/patchme", map[string]any{"originalUrl": target}
+		},
+	}
+
+	for name, payload := range payloads {
+		for _, target := range []string{"javascript:alert(document.cookie)", "http://127.0.0.1/admin"} {
+			t.Run(name+" "+target, func(t *testing.T) {
+				path, body := payload(target)
+				encoded, err := json.Marshal(body)
+				require.NoError(t, err)
+
+				method := http.MethodPost
+				if path != "/shorten" {
+					method = http.MethodPatch
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewReader(encoded)))
+				assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			})
+		}
+	}
+
+	url, err := repo.FindByNamespaceAndCode(context.Background(), domain.DefaultNamespace, "patchme")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url.OriginalURL, "refused patches change nothing")
+}
+
+func TestHandlers_HandleShorten_JSONSchema(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected []jsonschema.Error
+	}{
+		{
+			name:     "localhost URL",
+			payload:  `{"url": "http://localhost:3000/admin"}`,
+			expected: []jsonschema.Error{{Field: "url", Message: "url must not point to localhost or a private network"}},
+		},
+		{
+			name:     "private network URL",
+			payload:  `{"url": "http://172.20.1.1/metrics"}`,
+			expected: []jsonschema.Error{{Field: "url", Message: "url must not point to localhost or a private network"}},
+		},
+		{
+			name:     "alias starting with a digit",
+			payload:  `{"url": "https://example.com", "customAlias": "1promo"}`,
+			expected: []jsonschema.Error{{Field: "customAlias", Message: "customAlias must not start with a digit"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handlers.HandleShorten(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(tt.payload)))
+
+			require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			var problem SchemaProblemDetail
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, ProblemTypeSchema, problem.Type)
+			assert.Equal(t, tt.expected, problem.JSONSchemaErrors)
+		})
+	}
+
+	t.Run("schema rules come before struct validation", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handlers.HandleShorten(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "http://10.0.0.1", "customAlias": "ab"}`)))
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("malformed bodies are still bad requests", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handlers.HandleShorten(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": `)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandlers_HandleShorten_ForwardedHeaders(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	trusting := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{ForwardedHosts: NewForwardedHosts([]string{"dove.example.com", "Links.Example.com:8443"})})
+	ignoring := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+	forcing := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{ShortURLScheme: "https", ForwardedHosts: NewForwardedHosts([]string{"dove.example.com"})})
+
+	tests := []struct {
+		name     string
+		handlers *Handlers
+		headers  map[string]string
+		expected string
+	}{
+		{name: "without headers", handlers: trusting, expected: "http://localhost:8080/"},
+		{name: "forwarded host and proto", handlers: trusting, headers: map[string]string{"X-Forwarded-Host": "dove.example.com", "X-Forwarded-Proto": "https"}, expected: "https://dove.example.com/"},
+		{name: "host with a port, ignoring case", handlers: trusting, headers: map[string]string{"X-Forwarded-Host": "links.example.com:8443", "X-Forwarded-Proto": "HTTPS"}, expected: "https://links.example.com:8443/"},
+		{name: "proto missing keeps the base URL scheme", handlers: trusting, headers: map[string]string{"X-Forwarded-Host": "dove.example.com"}, expected: "http://dove.example.com/"},
+		{name: "unknown proto keeps the base URL scheme", handlers: trusting, headers: map[string]string{"X-Forwarded-Host": "dove.example.com", "X-Forwarded-Proto": "javascript"}, expected: "http://dove.example.com/"},
+		{name: "first of chained proxies", handlers: trusting, headers: map[string]string{"X-Forwarded-Host": "dove.example.com, internal-lb:80", "X-Forwarded-Proto": "https, http"}, expected: "https://dove.example.com/"},
+		{name: "host not allowed", handlers: trusting, headers: map[string]string{"X-Forwarded-Host": "evil.example.com", "X-Forwarded-Proto": "https"}, expected: "http://localhost:8080/"},
+		{name: "allowed host on another port", handlers: trusting, headers: map[string]string{"X-Forwarded-Host": "dove.example.com:9000"}, expected: "http://localhost:8080/"},
+		{name: "headers not trusted", handlers: ignoring, headers: map[string]string{"X-Forwarded-Host": "dove.example.com", "X-Forwarded-Proto": "https"}, expected: "http://localhost:8080/"},
+		{name: "forced scheme replaces that of the base URL", handlers: forcing, expected: "https://localhost:8080/"},
+		{name: "forced scheme replaces the forwarded proto", handlers: forcing, headers: map[string]string{"X-Forwarded-Host": "dove.example.com", "X-Forwarded-Proto": "http"}, expected: "https://dove.example.com/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com"}`))
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			tt.handlers.HandleShorten(w, req)
+
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			var response application.URLResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expected+response.ShortCode, response.ShortURL)
+		})
+	}
+}
+
+func TestHandlers_HandleShorten_DryRun(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+	router := NewRouter(handlers, nil, logger, &config.Config{}, metrics.NewNoOpRegistry(), nil, nil)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com", CustomAlias: "taken"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	shorten := func(query, payload, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/shorten"+query, strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name            string
+		payload         string
+		accept          string
+		wantContentType string
+	}{
+		{name: "generated code", payload: `{"url": "https://example.com"}`, wantContentType: "application/json"},
+		{name: "custom alias", payload: `{"url": "https://example.com", "customAlias": "preview"}`, wantContentType: "application/json"},
+		{name: "version 2", payload: `{"url": "https://example.com", "tags": ["docs"]}`, accept: "application/vnd.dove.v2+json", wantContentType: "application/vnd.dove.v2+json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := shorten("?dryRun=true", tt.payload, tt.accept)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+
+			var response application.URLResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.True(t, response.DryRun)
+			assert.NotEmpty(t, response.ShortCode)
+			assert.Equal(t, "http://localhost:8080/"+response.ShortCode, response.ShortURL)
+
+			exists, err := repo.Exists(context.Background(), domain.DefaultNamespace, response.ShortCode)
+			require.NoError(t, err)
+			assert.False(t, exists, "dry runs store nothing")
 		})
 	}
+
+	t.Run("taken alias conflicts", func(t *testing.T) {
+		w := shorten("?dryRun=true", `{"url": "https://example.com", "customAlias": "taken"}`, "")
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		w := shorten("?dryRun=true", `{"url": "not a url"}`, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("false creates the URL", func(t *testing.T) {
+		w := shorten("?dryRun=false", `{"url": "https://example.com", "customAlias": "created"}`, "")
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.NotContains(t, w.Body.String(), "dryRun")
+
+		exists, err := repo.Exists(context.Background(), domain.DefaultNamespace, "created")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
 }
 
-func performValidationTest(t *testing.T, handlers *Handlers, payload string) map[string]interface{} {
+// performValidationTest posts payload to /shorten and returns the validation errors by field
+func performValidationTest(t *testing.T, handlers *Handlers, payload string) map[string]ValidationFieldError {
 	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(payload))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
@@ -69,33 +437,4199 @@ func performValidationTest(t *testing.T, handlers *Handlers, payload string) map
 	handlers.HandleShorten(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]interface{}
-	err := json.Unmarshal(w.Body.Bytes(), &response)
-	require.NoError(t, err)
+	var problem ValidationProblemDetail
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, ProblemTypeValidation, problem.Type)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
 
-	details, ok := response["details"].(map[string]interface{})
-	require.True(t, ok, "expected details field in response, got: %v", response)
+	return detailsByField(problem)
+}
 
+// detailsByField indexes the validation errors of problem by field
+func detailsByField(problem ValidationProblemDetail) map[string]ValidationFieldError {
+	details := make(map[string]ValidationFieldError, len(problem.Details))
+	for _, detail := range problem.Details {
+		details[detail.Field] = detail
+	}
 	return details
 }
 
-func checkExpectedFields(t *testing.T, details map[string]interface{}, expectedFields []string) {
-	for _, expectedField := range expectedFields {
-		assert.Contains(t, details, expectedField, "expected field %q in error details, but got fields: %v", expectedField, getKeys(details))
+func TestHandlers_HandleRedirect_PasswordProtected(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+	router.Head("/{shortCode}", handlers.HandleRedirect)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/private",
+		CustomAlias: "private",
+		Password:    "hunter22",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		header         string
+		expectedStatus int
+	}{
+		{"missing password", http.MethodGet, "/private", "", http.StatusUnauthorized},
+		{"wrong query password", http.MethodGet, "/private?p=nope", "", http.StatusUnauthorized},
+		{"wrong header password", http.MethodGet, "/private", "nope", http.StatusUnauthorized},
+		{"correct query password", http.MethodGet, "/private?p=hunter22", "", http.StatusMovedPermanently},
+		{"correct header password", http.MethodGet, "/private", "hunter22", http.StatusMovedPermanently},
+		{"head without password", http.MethodHead, "/private", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("X-URL-Password", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusMovedPermanently {
+				assert.Equal(t, "https://example.com/private", w.Header().Get("Location"))
+			} else {
+				assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestHandlers_ProblemDetails(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Post("/shorten", handlers.HandleShorten)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com",
+		CustomAlias: "taken",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		expectedStatus int
+		expectedType   string
+	}{
+		{"unknown short code", http.MethodGet, "/missing", "", http.StatusNotFound, ProblemTypeNotFound},
+		{"malformed body", http.MethodPost, "/shorten", "{", http.StatusBadRequest, ProblemTypeBadRequest},
+		{"duplicate alias", http.MethodPost, "/shorten", `{"url": "https://example.com", "customAlias": "taken"}`, http.StatusConflict, ProblemTypeConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+
+			var problem ProblemDetail
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, tt.expectedType, problem.Type)
+			assert.Equal(t, http.StatusText(tt.expectedStatus), problem.Title)
+			assert.Equal(t, tt.expectedStatus, problem.Status)
+			assert.NotEmpty(t, problem.Detail)
+			assert.Equal(t, tt.target, problem.Instance)
+		})
+	}
+}
+
+func TestHandlers_HandlePreview_ConditionalGet(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/preview", handlers.HandlePreview)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/preview",
+		CustomAlias: "preview",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	preview := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/shorten/preview/preview", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := preview(nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	lastModified := first.Header().Get("Last-Modified")
+	require.Regexp(t, `^"[0-9a-f]{16}"$`, etag)
+	require.NotEmpty(t, lastModified)
+
+	var body application.URLResponse
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &body))
+	assert.Equal(t, "https://example.com/preview", body.OriginalURL)
+	assert.Equal(t, 0, body.Clicks)
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{"matching If-None-Match", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"matching weak If-None-Match in list", map[string]string{"If-None-Match": `"deadbeefdeadbeef", W/` + etag}, http.StatusNotModified},
+		{"non-matching If-None-Match", map[string]string{"If-None-Match": `"deadbeefdeadbeef"`}, http.StatusOK},
+		{"If-Modified-Since equal to Last-Modified", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{"If-Modified-Since in the past", map[string]string{"If-Modified-Since": "Mon, 01 Jan 2001 00:00:00 GMT"}, http.StatusOK},
+		{"If-None-Match takes precedence over If-Modified-Since", map[string]string{"If-None-Match": `"deadbeefdeadbeef"`, "If-Modified-Since": lastModified}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := preview(tt.headers)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, etag, w.Header().Get("ETag"))
+			if tt.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, w.Body.Bytes())
+			} else {
+				assert.NotEmpty(t, w.Body.Bytes())
+			}
+		})
+	}
+
+	t.Run("ETag changes after a click", func(t *testing.T) {
+		time.Sleep(time.Millisecond)
+		_, err := service.IncrementClicks(context.Background(), domain.DefaultNamespace, "preview", application.ClickDetails{})
+		require.NoError(t, err)
+
+		w := preview(map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	})
+}
+
+func TestHandlers_HandlePreview_PasswordProtected(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/preview", handlers.HandlePreview)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/hidden",
+		CustomAlias: "hidden",
+		Password:    "letmein",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/hidden/preview", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/hidden/preview?p=letmein", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+}
+
+func TestHandlers_HandleClickEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/events", handlers.HandleClickEvents)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx := context.Background()
+	_, err := service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/live",
+		CustomAlias: "live",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	resp, err := http.Get(server.URL + "/shorten/live/events")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, ": subscribed\n", line)
+
+	// The subscription is live once the comment has been received
+	for i := 0; i < 2; i++ {
+		_, err := service.IncrementClicks(ctx, domain.DefaultNamespace, "live", application.ClickDetails{})
+		require.NoError(t, err)
+	}
+
+	var events []domain.ClickEvent
+	for len(events) < 2 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+		if !ok {
+			continue
+		}
+		var event domain.ClickEvent
+		require.NoError(t, json.Unmarshal([]byte(data), &event))
+		events = append(events, event)
 	}
+
+	assert.Equal(t, "live", events[0].ShortCode)
+	assert.Equal(t, 1, events[0].Clicks)
+	assert.Equal(t, 2, events[1].Clicks)
+	assert.False(t, events[1].ClickedAt.IsZero())
+
+	t.Run("unknown short code", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/shorten/missing/events")
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, problemContentType, resp.Header.Get("Content-Type"))
+	})
 }
 
-func checkNoUnexpectedFields(t *testing.T, details map[string]interface{}, expectedFields []string) {
-	for field := range details {
-		assert.Contains(t, expectedFields, field, "unexpected field %q in error details, expected only: %v", field, expectedFields)
+func TestHandlers_HandleClickEvents_ConnectionLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	broker := pubsub.NewBroker(1)
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), broker, application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/events", handlers.HandleClickEvents)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/busy",
+		CustomAlias: "busy",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	_, unsubscribe, err := broker.Subscribe(domain.DefaultNamespace, "busy")
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	req := httptest.NewRequest(http.MethodGet, "/shorten/busy/events", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+
+	var problem ProblemDetail
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, ProblemTypeUnavailable, problem.Type)
+}
+
+func TestHandlers_HandleClickTimeSeries(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/analytics/timeseries", handlers.HandleClickTimeSeries)
+
+	ctx := context.Background()
+	_, err := service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/stats",
+		CustomAlias: "stats",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := service.IncrementClicks(ctx, domain.DefaultNamespace, "stats", application.ClickDetails{})
+		require.NoError(t, err)
+	}
+
+	t.Run("buckets recorded clicks", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/shorten/stats/analytics/timeseries?granularity=week", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var buckets []domain.TimeBucket
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &buckets))
+		require.Len(t, buckets, 1)
+		assert.Equal(t, 3, buckets[0].Clicks)
+		assert.Equal(t, domain.GranularityWeek.Truncate(time.Now()), buckets[0].Period)
+	})
+
+	t.Run("empty range returns empty array", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/shorten/stats/analytics/timeseries?from=2024-01-01&to=2024-01-31", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, "[]", w.Body.String())
+	})
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+	}{
+		{"unknown granularity", "/shorten/stats/analytics/timeseries?granularity=month", http.StatusBadRequest},
+		{"malformed date", "/shorten/stats/analytics/timeseries?from=01/01/2024", http.StatusBadRequest},
+		{"range over 90 days", "/shorten/stats/analytics/timeseries?from=2024-01-01&to=2024-04-30", http.StatusBadRequest},
+		{"from after to", "/shorten/stats/analytics/timeseries?from=2024-02-01&to=2024-01-01", http.StatusBadRequest},
+		{"unknown short code", "/shorten/missing/analytics/timeseries", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+		})
 	}
 }
 
-func getKeys(m map[string]interface{}) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
+func TestHandlers_HandleURLHealth(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/health", handlers.HandleURLHealth)
+
+	ctx := context.Background()
+	_, err := service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/probe",
+		CustomAlias: "probe",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	get := func() application.URLHealthResponse {
+		req := httptest.NewRequest(http.MethodGet, "/shorten/probe/health", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp application.URLHealthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
 	}
-	return keys
+
+	resp := get()
+	assert.Equal(t, "probe", resp.ShortCode)
+	assert.Equal(t, domain.HealthStatusUnknown, resp.HealthStatus)
+	assert.Nil(t, resp.LastCheckedAt)
+
+	checkedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.UpdateHealthStatus(ctx, domain.DefaultNamespace, "probe", domain.HealthStatusDead, checkedAt))
+
+	resp = get()
+	assert.Equal(t, domain.HealthStatusDead, resp.HealthStatus)
+	require.NotNil(t, resp.LastCheckedAt)
+	assert.True(t, checkedAt.Equal(*resp.LastCheckedAt))
+
+	req := httptest.NewRequest(http.MethodGet, "/shorten/missing/health", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandlers_HandleURLInfo(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}", handlers.HandleURLInfo)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	ctx := context.Background()
+	_, err := service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/info",
+		CustomAlias: "info",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/info", nil))
+	require.Equal(t, http.StatusMovedPermanently, w.Code)
+
+	t.Run("returns metadata without counting a click", func(t *testing.T) {
+		for range 2 {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/info", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			for _, key := range []string{"shortCode", "shortUrl", "originalUrl", "clicks", "createdAt", "updatedAt", "healthStatus", "tags"} {
+				assert.Contains(t, body, key)
+			}
+
+			var resp application.URLInfoResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "info", resp.ShortCode)
+			assert.Equal(t, "https://example.com/info", resp.OriginalURL)
+			assert.Equal(t, 1, resp.Clicks)
+			assert.Equal(t, domain.HealthStatusUnknown, resp.HealthStatus)
+			assert.Empty(t, resp.Tags)
+			assert.False(t, resp.CreatedAt.IsZero())
+		}
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/missing", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	})
+}
+
+func TestHandlers_Description(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Post("/shorten", handlers.HandleShorten)
+	router.Get("/shorten/{shortCode}", handlers.HandleURLInfo)
+	router.Patch("/shorten/{shortCode}", handlers.HandlePatchURL)
+
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	info := func() application.URLInfoResponse {
+		w := send(http.MethodGet, "/shorten/labelled", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp application.URLInfoResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	w := send(http.MethodPost, "/shorten", `{"url": "https://example.com/launch", "customAlias": "labelled", "description": "Spring launch 🚀"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created application.URLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "Spring launch 🚀", created.Description)
+	assert.Equal(t, "Spring launch 🚀", info().Description)
+
+	t.Run("patch replaces and clears it", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, send(http.MethodPatch, "/shorten/labelled", `{"description": "Autumn launch"}`).Code)
+		assert.Equal(t, "Autumn launch", info().Description)
+
+		require.Equal(t, http.StatusOK, send(http.MethodPatch, "/shorten/labelled", `{"tags": ["docs"]}`).Code)
+		assert.Equal(t, "Autumn launch", info().Description)
+
+		w := send(http.MethodPatch, "/shorten/labelled", `{"description": ""}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), `"description"`)
+		assert.Empty(t, info().Description)
+	})
+
+	t.Run("at most 500 characters", func(t *testing.T) {
+		// Counted in characters, not bytes
+		w := send(http.MethodPost, "/shorten", `{"url": "https://example.com", "customAlias": "longest", "description": "`+strings.Repeat("é", 500)+`"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		for _, tt := range []struct{ method, target, body string }{
+			{http.MethodPost, "/shorten", `{"url": "https://example.com", "description": "` + strings.Repeat("a", 501) + `"}`},
+			{http.MethodPatch, "/shorten/labelled", `{"description": "` + strings.Repeat("a", 501) + `"}`},
+		} {
+			w := send(tt.method, tt.target, tt.body)
+			require.Equal(t, http.StatusBadRequest, w.Code, tt.method)
+			var problem ValidationProblemDetail
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, ValidationCodeTooLarge, detailsByField(problem)["description"].Code, tt.method)
+		}
+	})
+}
+
+func TestHandlers_HandleExport(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/urls/export", handlers.HandleExport)
+
+	// More than two pages, so the export has to follow the cursor
+	const inserted = exportPageSize*2 + 17
+	ctx := context.Background()
+	expiresAt := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < inserted; i++ {
+		url, err := domain.NewURL(fmt.Sprintf("code%d", i), fmt.Sprintf("https://example.com/%d", i))
+		require.NoError(t, err)
+		// The second URL redirects temporarily until it expires
+		if i == 1 {
+			url.RedirectType = http.StatusFound
+			url.ExpiresAt = &expiresAt
+		}
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/urls/export", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		assert.Regexp(t, `^attachment; filename="urls-\d{8}T\d{6}Z\.csv"$`, w.Header().Get("Content-Disposition"))
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, inserted+1)
+		assert.Equal(t, []string{"id", "short_code", "original_url", "clicks", "created_at", "redirect_type", "expires_at", "namespace"}, records[0])
+
+		seen := make(map[string]bool)
+		for _, record := range records[1:] {
+			seen[record[1]] = true
+			if record[1] == "code1" {
+				continue
+			}
+			assert.Equal(t, "301", record[5])
+			assert.Empty(t, record[6])
+		}
+		assert.Len(t, seen, inserted)
+		assert.Equal(t, "https://example.com/0", records[1][2])
+		assert.Equal(t, []string{"code1", "302", "2030-06-01T12:00:00Z"}, []string{records[2][1], records[2][5], records[2][6]})
+	})
+
+	t.Run("json", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/urls/export?format=json", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), ".json")
+
+		var records []ExportRecord
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &records))
+		assert.Len(t, records, inserted)
+		assert.Equal(t, "code0", records[0].ShortCode)
+		assert.Equal(t, http.StatusMovedPermanently, records[0].RedirectType)
+		assert.Nil(t, records[0].ExpiresAt)
+		assert.Equal(t, "code1", records[1].ShortCode)
+		assert.Equal(t, http.StatusFound, records[1].RedirectType)
+		require.NotNil(t, records[1].ExpiresAt)
+		assert.True(t, expiresAt.Equal(*records[1].ExpiresAt))
+
+		// One record per line between the brackets
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, inserted+2)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/urls/export?format=xml", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	})
+}
+
+func TestHandlers_HandleExport_Empty(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	req := httptest.NewRequest(http.MethodGet, "/urls/export?format=json", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleExport(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var records []ExportRecord
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &records))
+	assert.Empty(t, records)
+}
+
+func TestHandlers_HandleClickExport(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/clicks/export", handlers.HandleClickExport)
+
+	ctx := context.Background()
+	url, err := domain.NewURL("exported", "https://example.com")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	// Recorded newest first, with three clicks per second so that the cursor has ties to break
+	const inserted = 100
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := inserted - 1; i >= 0; i-- {
+		duration := float64(i) / 10
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{
+			Namespace:          domain.DefaultNamespace,
+			ShortCode:          "exported",
+			ClickedAt:          base.Add(time.Duration(i/3) * time.Second),
+			Referer:            "(direct)",
+			Browser:            "Firefox",
+			OS:                 "Linux",
+			DeviceType:         "desktop",
+			RedirectDurationMs: &duration,
+			IPAddress:          fmt.Sprintf("203.0.113.%d", i),
+			UserAgent:          "Mozilla/5.0, \"quoted\"",
+			Country:            "DE",
+		}))
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/exported/clicks/export?format=csv", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="exported-clicks.csv"`, w.Header().Get("Content-Disposition"))
+		assert.Empty(t, w.Result().Trailer.Get("X-Truncated"))
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, inserted+1)
+		assert.Equal(t, []string{"clicked_at", "ip_address", "user_agent", "referer", "country", "browser", "os", "device_type", "redirect_duration_ms", "variant_id"}, records[0])
+
+		seen := make(map[string]bool)
+		for i, record := range records[1:] {
+			seen[record[1]] = true
+			assert.Equal(t, base.Add(time.Duration(i/3)*time.Second).Format(time.RFC3339Nano), record[0], "oldest first")
+			assert.Equal(t, "Mozilla/5.0, \"quoted\"", record[2])
+			assert.Equal(t, []string{"(direct)", "DE", "Firefox", "Linux", "desktop"}, record[3:8])
+			assert.Empty(t, record[9])
+		}
+		assert.Len(t, seen, inserted, "every click appears once")
+		// Clicks of the same second come in ID order, the last recorded first
+		assert.Equal(t, "0.2", records[1][8])
+		assert.Equal(t, "9.9", records[inserted][8])
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/exported/clicks/export?format=ndjson", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="exported-clicks.ndjson"`, w.Header().Get("Content-Disposition"))
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, inserted)
+		var first ClickExportRecord
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+		assert.Equal(t, base, first.ClickedAt)
+		assert.Equal(t, "203.0.113.2", first.IPAddress)
+		assert.Nil(t, first.VariantID)
+	})
+
+	t.Run("truncates at the row limit", func(t *testing.T) {
+		limited := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+		limited.clickExportLimit = 40
+		limitedRouter := chi.NewRouter()
+		limitedRouter.Get("/shorten/{shortCode}/clicks/export", limited.HandleClickExport)
+
+		w := httptest.NewRecorder()
+		limitedRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/exported/clicks/export", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		assert.Len(t, records, 40+1)
+		assert.Equal(t, "true", w.Result().Trailer.Get("X-Truncated"))
+
+		limited.clickExportLimit = inserted
+		w = httptest.NewRecorder()
+		limitedRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/exported/clicks/export", nil))
+		assert.Empty(t, w.Result().Trailer.Get("X-Truncated"), "an export exactly at the limit is complete")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			target string
+			status int
+		}{
+			{"unknown format", "/shorten/exported/clicks/export?format=xml", http.StatusBadRequest},
+			{"unknown short code", "/shorten/missing/clicks/export", http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+				assert.Equal(t, tt.status, w.Code)
+				assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+			})
+		}
+	})
+}
+
+func TestNewRouter_ExportRequiresAdminFlag(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	for _, enabled := range []bool{false, true} {
+		cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret", ExportEnabled: enabled}}
+		router := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/urls/export", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if enabled {
+			assert.Equal(t, http.StatusOK, w.Code)
+		} else {
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		}
+	}
+}
+
+// funnelRepository adds the funnel methods to a repository so that the funnel routes are
+// registered; they are never called
+type funnelRepository struct {
+	domain.URLRepository
+	domain.FunnelRepository
+}
+
+// keyMigratorStub registers the cache key migration route
+type keyMigratorStub struct{}
+
+func (keyMigratorStub) MigrateKeys(context.Context, string, string) (int, error) { return 0, nil }
+
+func TestNewRouter_RoutesMatchOpenAPISpec(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := funnelRepository{URLRepository: memory.NewURLRepository(logger, 0)}
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{AuditLog: memory.NewAuditRepository()}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{Cache: noopCache, DegradedCacheOK: true})
+	handlers.keyMigrator = keyMigratorStub{}
+
+	// Every optional route is enabled
+	cfg := &config.Config{
+		App:   config.AppConfig{SuggestEnabled: true},
+		Admin: config.AdminConfig{APIKey: "secret", ExportEnabled: true, ExposeCache: true},
+	}
+	router := NewRouter(handlers, NewMigrationHandlers(&fakeMigrations{}, storeMigratorStub{}), logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	// Routes serving the docs themselves or robots.txt are not part of the API
+	undocumented := map[string]bool{"/swagger/*": true, "/redoc": true, "/robots.txt": true}
+
+	var routes []string
+	require.NoError(t, chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !undocumented[route] {
+			routes = append(routes, method+" "+route)
+		}
+		return nil
+	}))
+
+	spec, err := openapi3.NewLoader().LoadFromFile("../../../docs/openapi.yaml")
+	require.NoError(t, err)
+	var operations []string
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			operations = append(operations, method+" "+path)
+		}
+	}
+
+	assert.ElementsMatch(t, routes, operations, "every route is documented by an @Router annotation on its handler")
+}
+
+func TestNewRouter_ReservedRouteWords(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	reserved := shortcode.NewReservedWords()
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{Reserved: reserved}, logger)
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{}), nil, logger, &config.Config{}, metrics.NewNoOpRegistry(), nil, nil)
+	reserved.Add(RouteWords(router)...)
+
+	words := RouteWords(router)
+	assert.Subset(t, words, []string{"health", "ready", "redoc", "shorten", "swagger", "urls"})
+	assert.NotContains(t, words, "{shortCode}", "parameters are not words")
+
+	tests := []struct {
+		alias        string
+		expectedCode int
+	}{
+		{alias: "admin", expectedCode: http.StatusBadRequest},
+		{alias: "redoc", expectedCode: http.StatusUnprocessableEntity},
+		{alias: "Shorten", expectedCode: http.StatusUnprocessableEntity},
+		{alias: "validcode", expectedCode: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			body := `{"url": "https://example.com", "customAlias": "` + tt.alias + `"}`
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body)))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusUnprocessableEntity {
+				var problem ProblemDetail
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+				assert.Equal(t, ProblemTypeReservedAlias, problem.Type)
+			}
+		})
+	}
+}
+
+func TestNewRouter_BusinessMetrics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	cfg := &config.Config{Metrics: config.MetricsConfig{Enabled: true, Path: "/metrics", Namespace: "dove", TrackTopNCodes: 10}}
+	registry, err := metrics.NewPrometheusRegistry(cfg.Metrics)
+	require.NoError(t, err)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{Metrics: registry}, logger)
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{}), nil, logger, cfg, registry, nil, nil)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	require.Equal(t, http.StatusCreated, serve(http.MethodPost, "/shorten", `{"url": "https://example.com", "customAlias": "counted"}`).Code)
+	for range 3 {
+		require.Equal(t, http.StatusMovedPermanently, serve(http.MethodGet, "/counted", "").Code)
+	}
+	require.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/missing", "").Code)
+
+	body := serve(http.MethodGet, "/metrics", "").Body.String()
+	assert.Contains(t, body, `dove_urls_created_total{namespace="other"} 1`)
+	// The first redirect of a code is counted as other, later ones under the code
+	assert.Contains(t, body, `dove_urls_redirected_total{short_code="other",status_code="301"} 1`)
+	assert.Contains(t, body, `dove_urls_redirected_total{short_code="counted",status_code="301"} 2`)
+	assert.Contains(t, body, `dove_urls_redirected_total{short_code="other",status_code="404"} 1`)
+}
+
+func TestNewRouter_SuggestRequiresFlag(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<title>Golang Blog</title>`))
+	}))
+	t.Cleanup(page.Close)
+
+	for _, enabled := range []bool{false, true} {
+		cfg := &config.Config{App: config.AppConfig{SuggestEnabled: enabled}}
+		router := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/shorten/suggest?url="+page.URL, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if !enabled {
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			continue
+		}
+		// The page is on the loopback address, which the server must not be made to fetch
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		var problem ProblemDetail
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "url must not point to a private network", problem.Detail)
+		assert.NotContains(t, w.Body.String(), "golangblog")
+
+		req = httptest.NewRequest(http.MethodGet, "/shorten/suggest", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHandlers_HandleBulkDelete(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Delete("/urls/bulk", handlers.HandleBulkDelete)
+
+	for _, alias := range []string{"first", "second", "kept"} {
+		_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias}, "http://localhost:8080")
+		require.NoError(t, err)
+	}
+
+	tooMany := make([]string, 501)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("code%d", i)
+	}
+	tooManyBody, err := json.Marshal(application.BulkDeleteRequest{ShortCodes: tooMany})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expected       application.BulkDeleteResult
+	}{
+		{
+			name:           "existing and missing codes",
+			body:           `{"shortCodes": ["first", "missing", "second", "missing"]}`,
+			expectedStatus: http.StatusOK,
+			expected:       application.BulkDeleteResult{Deleted: 2, NotFound: []string{"missing"}},
+		},
+		{
+			name:           "already deleted",
+			body:           `{"shortCodes": ["first"]}`,
+			expectedStatus: http.StatusOK,
+			expected:       application.BulkDeleteResult{Deleted: 0, NotFound: []string{"first"}},
+		},
+		{name: "no codes", body: `{"shortCodes": []}`, expectedStatus: http.StatusBadRequest},
+		{name: "too many codes", body: string(tooManyBody), expectedStatus: http.StatusBadRequest},
+		{name: "malformed body", body: `{"shortCodes":`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/urls/bulk", strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			if tt.expectedStatus == http.StatusOK {
+				var result application.BulkDeleteResult
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+
+	_, err = repo.FindByNamespaceAndCode(context.Background(), domain.DefaultNamespace, "kept")
+	assert.NoError(t, err)
+}
+
+func TestHandlers_HandleBatchLookup(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Post("/urls/batch-lookup", handlers.HandleBatchLookup)
+
+	for _, req := range []application.CreateURLRequest{
+		{URL: "https://example.com/first", CustomAlias: "first"},
+		{URL: "https://example.com/second", CustomAlias: "second"},
+		{URL: "https://example.com/secret", CustomAlias: "secret", Password: "hunter2"},
+		{URL: "https://example.com/team", CustomAlias: "first", Namespace: "team"},
+	} {
+		_, err := service.CreateShortURL(context.Background(), req, "http://localhost:8080")
+		require.NoError(t, err)
+	}
+
+	tooManyBody, err := json.Marshal(application.BatchLookupRequest{ShortCodes: make([]string, 51)})
+	require.NoError(t, err)
+
+	lookup := func(body, namespace string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/urls/batch-lookup", strings.NewReader(body))
+		if namespace != "" {
+			req.Header.Set(namespaceHeader, namespace)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("found and not found codes", func(t *testing.T) {
+		w := lookup(`{"shortCodes": ["first", "missing", "second", "secret", "missing"]}`, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response application.BatchLookupResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, "https://example.com/first", response.Results["first"].OriginalURL)
+		assert.Equal(t, "http://localhost:8080/first", response.Results["first"].ShortURL)
+		assert.Equal(t, "https://example.com/second", response.Results["second"].OriginalURL)
+		assert.Equal(t, []string{"missing", "secret"}, response.NotFound, "protected URLs are not found")
+	})
+
+	t.Run("nothing found", func(t *testing.T) {
+		w := lookup(`{"shortCodes": ["missing"]}`, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"results": {}, "notFound": ["missing"]}`, w.Body.String())
+	})
+
+	t.Run("namespace header", func(t *testing.T) {
+		w := lookup(`{"shortCodes": ["first", "second"]}`, "team")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response application.BatchLookupResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Results, 1)
+		assert.Equal(t, "https://example.com/team", response.Results["first"].OriginalURL)
+		assert.Equal(t, []string{"second"}, response.NotFound)
+	})
+
+	for _, tt := range []struct {
+		name      string
+		body      string
+		namespace string
+	}{
+		{name: "no codes", body: `{"shortCodes": []}`},
+		{name: "too many codes", body: string(tooManyBody)},
+		{name: "empty code", body: `{"shortCodes": [""]}`},
+		{name: "malformed body", body: `{"shortCodes":`},
+		{name: "invalid namespace", body: `{"shortCodes": ["first"]}`, namespace: "Not A Slug"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := lookup(tt.body, tt.namespace)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestHandlers_HandleImport(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Post("/urls/import", handlers.HandleImport)
+
+	decodeReport := func(t *testing.T, w *httptest.ResponseRecorder) application.ImportReport {
+		t.Helper()
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var report application.ImportReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return report
+	}
+
+	t.Run("ndjson file", func(t *testing.T) {
+		ndjson := strings.Join([]string{
+			`{"url":"https://example.com/a","customAlias":"ndjsona"}`,
+			``,
+			`{"url":"https://example.com/b","customAlias":"ndjsona"}`,
+			`{"url":"https://example.com/c"`,
+			`{"url":"ftp//broken","customAlias":"ndjsonc"}`,
+			`{"url":"https://example.com/d","customAlias":"ndjsond"}`,
+		}, "\n")
+
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("file", "urls.ndjson")
+		require.NoError(t, err)
+		_, err = part.Write([]byte(ndjson))
+		require.NoError(t, err)
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/urls/import", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		report := decodeReport(t, w)
+		assert.Equal(t, 2, report.Succeeded)
+		assert.Equal(t, 3, report.Failed)
+		require.Len(t, report.Errors, 3)
+		// Line numbers refer to the file, blank lines included
+		assert.Equal(t, application.ImportError{Line: 3, Message: "short code already exists"}, report.Errors[0])
+		assert.Equal(t, 4, report.Errors[1].Line)
+		assert.Contains(t, report.Errors[1].Message, "invalid JSON")
+		assert.Equal(t, application.ImportError{Line: 5, Message: "url failed safeurl validation"}, report.Errors[2])
+	})
+
+	t.Run("json array", func(t *testing.T) {
+		body := `[{"url":"https://example.com/x","customAlias":"arrayx"},{"url":"https://example.com/y","customAlias":"ab"}]`
+		req := httptest.NewRequest(http.MethodPost, "/urls/import", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		report := decodeReport(t, w)
+		assert.Equal(t, 1, report.Succeeded)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, application.ImportError{Line: 2, Message: "customAlias failed min validation"}, report.Errors[0])
+
+		_, err := service.GetURL(context.Background(), domain.DefaultNamespace, "arrayx")
+		require.NoError(t, err)
+	})
+
+	t.Run("too many entries", func(t *testing.T) {
+		entries := make([]application.CreateURLRequest, application.MaxImportEntries+1)
+		body, err := json.Marshal(entries)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/urls/import", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/urls/import", strings.NewReader("url,alias"))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	t.Run("malformed json array", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/urls/import", strings.NewReader(`{"url":"https://example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandlers_HandleTopReferrers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+	router.Get("/shorten/{shortCode}/analytics/referrers", handlers.HandleTopReferrers)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/campaign",
+		CustomAlias: "campaign",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	visits := []string{
+		"https://news.example.org/item?id=1",
+		"https://News.Example.org/item?id=2#comments",
+		"https://news.example.org/item",
+		"https://social.example.net/",
+		"https://social.example.net",
+		"",
+		"not a url",
+	}
+	for _, referer := range visits {
+		req := httptest.NewRequest(http.MethodGet, "/campaign", nil)
+		if referer != "" {
+			req.Header.Set("Referer", referer)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMovedPermanently, w.Code)
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/shorten/campaign/analytics/referrers")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var referrers []domain.ReferrerCount
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &referrers))
+	assert.Equal(t, []domain.ReferrerCount{
+		{Referer: "news.example.org/item", Count: 3},
+		{Referer: domain.DirectReferer, Count: 2},
+		{Referer: "social.example.net", Count: 2},
+	}, referrers)
+
+	w = get("/shorten/campaign/analytics/referrers?limit=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &referrers))
+	assert.Len(t, referrers, 1)
+
+	for _, limit := range []string{"0", "101", "ten"} {
+		w = get("/shorten/campaign/analytics/referrers?limit=" + limit)
+		assert.Equal(t, http.StatusBadRequest, w.Code, limit)
+	}
+
+	w = get("/shorten/missing/analytics/referrers")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandlers_HandleTopIPs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+	router.Get("/admin/analytics/top-ips", handlers.HandleTopIPs)
+
+	for _, alias := range []string{"first", "second"} {
+		_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+			URL:         "https://example.com/" + alias,
+			CustomAlias: alias,
+		}, "http://localhost:8080")
+		require.NoError(t, err)
+	}
+
+	visits := []struct{ shortCode, remoteAddr string }{
+		{"first", "203.0.113.1:40000"},
+		{"first", "[::ffff:203.0.113.1]:40001"}, // the same visitor through a dual-stack listener
+		{"second", "203.0.113.1:40002"},
+		{"first", "[2001:db8::1]:40003"},
+		{"second", "[2001:DB8:0:0:0:0:0:1]:40004"},
+		{"second", "198.51.100.7:40005"},
+	}
+	for _, visit := range visits {
+		req := httptest.NewRequest(http.MethodGet, "/"+visit.shortCode, nil)
+		req.RemoteAddr = visit.remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMovedPermanently, w.Code)
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/admin/analytics/top-ips")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var ips []domain.IPCount
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ips))
+	assert.Equal(t, []domain.IPCount{
+		{IPAddress: "203.0.113.1", Count: 3},
+		{IPAddress: "2001:db8::1", Count: 2},
+		{IPAddress: "198.51.100.7", Count: 1},
+	}, ips)
+
+	w = get("/admin/analytics/top-ips?n=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ips))
+	assert.Len(t, ips, 2)
+
+	for _, n := range []string{"0", "1001", "ten"} {
+		w = get("/admin/analytics/top-ips?n=" + n)
+		assert.Equal(t, http.StatusBadRequest, w.Code, n)
+	}
+}
+
+func TestHandlers_HandleAuditLog(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{AuditLog: memory.NewAuditRepository()}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "secret"}}
+	router := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com", CustomAlias: "audited"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	send := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	require.Equal(t, http.StatusNoContent, send(http.MethodPatch, "/admin/urls/audited/disable").Code)
+
+	w := send(http.MethodGet, "/admin/urls/audited/audit-log")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var entries []domain.AuditEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "disable", entries[0].Operation)
+	assert.Equal(t, "create", entries[1].Operation)
+	assert.Contains(t, string(entries[0].OldValue), `"enabled":true`)
+	assert.Contains(t, string(entries[0].NewValue), `"enabled":false`)
+
+	w = send(http.MethodGet, "/admin/urls/audited/audit-log?limit=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	assert.Len(t, entries, 1)
+
+	w = send(http.MethodGet, "/admin/urls/unknown/audit-log")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+
+	for _, limit := range []string{"0", "501", "fifty"} {
+		w = send(http.MethodGet, "/admin/urls/audited/audit-log?limit="+limit)
+		assert.Equal(t, http.StatusBadRequest, w.Code, limit)
+	}
+
+	// Without a history the route is not registered
+	service = application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers = NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+	router = NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/admin/urls/audited/audit-log").Code)
+}
+
+func TestHandlers_DeviceAnalytics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+	router.Get("/shorten/{shortCode}/analytics/devices", handlers.HandleDeviceStats)
+	router.Get("/shorten/{shortCode}/analytics/browsers", handlers.HandleBrowserStats)
+	router.Get("/shorten/{shortCode}/analytics/os", handlers.HandleOSStats)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/app",
+		CustomAlias: "devices",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	const (
+		chromeAndroid = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Mobile Safari/537.36"
+		safariIOS     = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+		curl          = "curl/8.5.0"
+	)
+	for _, ua := range []string{chromeAndroid, chromeAndroid, safariIOS, curl} {
+		req := httptest.NewRequest(http.MethodGet, "/devices", nil)
+		req.Header.Set("User-Agent", ua)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMovedPermanently, w.Code)
+	}
+
+	get := func(t *testing.T, target string) []domain.DeviceStat {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var stats []domain.DeviceStat
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		return stats
+	}
+
+	assert.Equal(t, []domain.DeviceStat{
+		{DeviceType: "mobile", Browser: "Chrome", OS: "Android", Count: 2},
+		{DeviceType: "bot", Browser: "curl", OS: "unknown", Count: 1},
+		{DeviceType: "mobile", Browser: "Safari", OS: "iOS", Count: 1},
+	}, get(t, "/shorten/devices/analytics/devices"))
+
+	assert.Equal(t, []domain.DeviceStat{
+		{Browser: "Chrome", Count: 2},
+		{Browser: "Safari", Count: 1},
+		{Browser: "curl", Count: 1},
+	}, get(t, "/shorten/devices/analytics/browsers"))
+
+	assert.Equal(t, []domain.DeviceStat{
+		{OS: "Android", Count: 2},
+		{OS: "iOS", Count: 1},
+		{OS: "unknown", Count: 1},
+	}, get(t, "/shorten/devices/analytics/os"))
+
+	req := httptest.NewRequest(http.MethodGet, "/shorten/missing/analytics/devices", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandlers_HandleRedirectLatency(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+	router.Head("/{shortCode}", handlers.HandleRedirect)
+	router.Get("/shorten/{shortCode}/analytics/latency", handlers.HandleRedirectLatency)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/slow",
+		CustomAlias: "latency",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	for _, method := range []string{http.MethodGet, http.MethodGet, http.MethodGet, http.MethodHead} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/latency", nil))
+		require.Less(t, w.Code, http.StatusBadRequest)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/latency/analytics/latency", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats domain.LatencyStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	// HEAD checks record no click
+	assert.Equal(t, 3, stats.Count)
+	assert.Positive(t, stats.P50)
+	assert.GreaterOrEqual(t, stats.P95, stats.P50)
+	assert.GreaterOrEqual(t, stats.P99, stats.P95)
+	assert.Positive(t, stats.Avg)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/missing/analytics/latency", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandlers_HandleClickHeatmap(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/analytics/heatmap", handlers.HandleClickHeatmap)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/heatmap",
+		CustomAlias: "heatmap",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+	// Friday 23:30 UTC is Saturday morning in Tokyo
+	require.NoError(t, repo.RecordClick(context.Background(), &domain.Click{
+		Namespace: domain.DefaultNamespace,
+		ShortCode: "heatmap",
+		ClickedAt: time.Date(2024, 5, 10, 23, 30, 0, 0, time.UTC),
+	}))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantHour   int
+		wantDay    time.Weekday
+	}{
+		{"defaults to UTC", "", http.StatusOK, 23, time.Friday},
+		{"named time zone", "?tz=Asia/Tokyo", http.StatusOK, 8, time.Saturday},
+		{"unknown time zone", "?tz=Mars/Olympus_Mons", http.StatusBadRequest, 0, 0},
+		{"server local time", "?tz=Local", http.StatusBadRequest, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/heatmap/analytics/heatmap"+tt.query, nil))
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var heatmap domain.ClickHeatmap
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &heatmap))
+			assert.Equal(t, 1, heatmap.HourOfDay[tt.wantHour])
+			assert.Equal(t, 1, heatmap.DayOfWeek[tt.wantDay])
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/missing/analytics/heatmap", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandlers_HandleRedirect_Head(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+	router.Head("/{shortCode}", handlers.HandleRedirect)
+
+	created, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/head",
+		CustomAlias: "head",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/head", nil))
+	require.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com/head", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/head", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Equal(t, "https://example.com/head", w.Header().Get("X-Original-URL"))
+	assert.Equal(t, "1", w.Header().Get("X-Clicks"))
+	assert.Equal(t, created.CreatedAt.UTC().Format(time.RFC3339), w.Header().Get("X-Created-At"))
+	assert.Equal(t, "0", w.Header().Get("Content-Length"))
+	assert.Zero(t, w.Body.Len())
+
+	t.Run("HEAD counts no click", func(t *testing.T) {
+		url, err := repo.FindByNamespaceAndCode(context.Background(), domain.DefaultNamespace, "head")
+		require.NoError(t, err)
+		assert.Equal(t, 1, url.Clicks)
+	})
+
+	t.Run("missing short code", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/missing", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestHandlers_HandleRedirect_CustomNotFoundPage(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+
+	page := `<!DOCTYPE html><html><body><h1>Nothing here</h1></body></html>`
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(w, page)
+	}))
+	defer remote.Close()
+
+	pagePath := t.TempDir() + "/404.html"
+	require.NoError(t, os.WriteFile(pagePath, []byte(page), 0o600))
+
+	sources := []struct {
+		name     string
+		filePath string
+		url      string
+	}{
+		{name: "from file", filePath: pagePath},
+		{name: "from URL", url: remote.URL},
+	}
+	for _, source := range sources {
+		t.Run(source.name, func(t *testing.T) {
+			notFoundPage := NewNotFoundPage(source.filePath, source.url, logger)
+			require.NoError(t, notFoundPage.Load(context.Background()))
+			handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{NotFoundPage: notFoundPage})
+			router := chi.NewRouter()
+			router.Get("/{shortCode}", handlers.HandleRedirect)
+
+			tests := []struct {
+				name        string
+				accept      string
+				contentType string
+				body        string
+			}{
+				{name: "browser", accept: "text/html,application/xhtml+xml,*/*;q=0.8", contentType: "text/html; charset=utf-8", body: page},
+				{name: "no Accept header", contentType: "text/html; charset=utf-8", body: page},
+				{name: "JSON client", accept: "application/json", contentType: problemContentType, body: `"status":404`},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+					if tt.accept != "" {
+						req.Header.Set("Accept", tt.accept)
+					}
+					w := httptest.NewRecorder()
+					router.ServeHTTP(w, req)
+
+					assert.Equal(t, http.StatusNotFound, w.Code)
+					assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+					assert.Contains(t, w.Body.String(), tt.body)
+				})
+			}
+		})
+	}
+
+	t.Run("other endpoints keep JSON errors", func(t *testing.T) {
+		notFoundPage := NewNotFoundPage(pagePath, "", logger)
+		require.NoError(t, notFoundPage.Load(context.Background()))
+		handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{NotFoundPage: notFoundPage})
+		router := chi.NewRouter()
+		router.Get("/shorten/{shortCode}", handlers.HandleURLInfo)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/missing", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	})
+
+	t.Run("page not loaded", func(t *testing.T) {
+		notFoundPage := NewNotFoundPage(t.TempDir()+"/missing.html", "", logger)
+		require.Error(t, notFoundPage.Load(context.Background()))
+		handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{NotFoundPage: notFoundPage})
+		router := chi.NewRouter()
+		router.Get("/{shortCode}", handlers.HandleRedirect)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	})
+}
+
+func TestHandlers_HandleRedirect_Chains(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	chains := &application.RedirectChains{BaseURLs: []string{"https://dove.example"}, MaxDepth: 3}
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{Chains: chains}, logger)
+	handlers := NewHandlers(service, "https://dove.example", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	for alias, url := range map[string]string{
+		"first":  "https://dove.example/second",
+		"second": "https://example.com/final",
+		"ping":   "https://dove.example/pong",
+		"pong":   "https://dove.example/ping",
+	} {
+		_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: url, CustomAlias: alias}, "https://dove.example")
+		require.NoError(t, err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/first", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com/final", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	assert.Equal(t, http.StatusLoopDetected, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), ProblemTypeLoopDetected)
+}
+
+func TestHandlers_HandleTopURLs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/urls/top", handlers.HandleTopURLs)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	for _, alias := range []string{"urla", "urlb", "urlc"} {
+		_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+			URL:         "https://example.com/" + alias,
+			CustomAlias: alias,
+		}, "http://localhost:8080")
+		require.NoError(t, err)
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, shortCode := range []string{"urlb", "urla", "urla", "urla"} {
+		require.Equal(t, http.StatusMovedPermanently, get("/"+shortCode).Code)
+	}
+
+	w := get("/urls/top?n=2")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var top []application.URLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &top))
+	require.Len(t, top, 2)
+	assert.Equal(t, "urla", top[0].ShortCode)
+	assert.Equal(t, 3, top[0].Clicks)
+	assert.Equal(t, "http://localhost:8080/urla", top[0].ShortURL)
+	assert.Equal(t, "urlb", top[1].ShortCode)
+	assert.Equal(t, 1, top[1].Clicks)
+
+	w = get("/urls/top")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &top))
+	assert.Len(t, top, 3)
+
+	for _, n := range []string{"0", "101", "ten"} {
+		w = get("/urls/top?n=" + n)
+		assert.Equal(t, http.StatusBadRequest, w.Code, n)
+	}
+
+	t.Run("protected and disabled URLs are left out", func(t *testing.T) {
+		_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+			URL:         "https://example.com/private-destination",
+			CustomAlias: "secret",
+			Password:    "hunter2",
+		}, "http://localhost:8080")
+		require.NoError(t, err)
+		_, err = service.CreateShortURL(context.Background(), application.CreateURLRequest{
+			URL:         "https://example.com/suspended-destination",
+			CustomAlias: "suspended",
+		}, "http://localhost:8080")
+		require.NoError(t, err)
+		for _, shortCode := range []string{"secret", "suspended"} {
+			for range 5 {
+				_, err := repo.IncrementClicks(context.Background(), domain.DefaultNamespace, shortCode, false)
+				require.NoError(t, err)
+			}
+		}
+		_, err = service.SetURLEnabled(context.Background(), domain.DefaultNamespace, "suspended", false)
+		require.NoError(t, err)
+
+		w := get("/urls/top?n=2")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "private-destination")
+		assert.NotContains(t, w.Body.String(), "suspended-destination")
+
+		var top []application.URLResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &top))
+		require.Len(t, top, 2, "URLs ranking first but not listed do not shorten the page")
+		assert.Equal(t, "urla", top[0].ShortCode)
+		assert.Equal(t, "urlb", top[1].ShortCode)
+	})
+}
+
+func TestHandlers_Namespaces(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Post("/shorten", handlers.HandleShorten)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+	router.Get("/{namespace}/{shortCode}", handlers.HandleNamespacedRedirect)
+
+	shorten := func(body, namespaceHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if namespaceHeader != "" {
+			req.Header.Set("X-Namespace", namespaceHeader)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := shorten(`{"url": "https://example.com/default", "customAlias": "shared"}`, "")
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = shorten(`{"url": "https://example.com/acme", "customAlias": "shared", "namespace": "acme"}`, "")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created application.URLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "acme", created.Namespace)
+	assert.Equal(t, "http://localhost:8080/acme/shared", created.ShortURL)
+
+	w = shorten(`{"url": "https://example.com/globex", "customAlias": "shared"}`, "globex")
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = shorten(`{"url": "https://example.com/admin", "customAlias": "shared", "namespace": "admin"}`, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	tests := []struct {
+		name             string
+		target           string
+		header           string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{"default namespace", "/shared", "", http.StatusMovedPermanently, "https://example.com/default"},
+		{"path prefix", "/acme/shared", "", http.StatusMovedPermanently, "https://example.com/acme"},
+		{"header", "/shared", "globex", http.StatusMovedPermanently, "https://example.com/globex"},
+		{"path prefix wins over header", "/acme/shared", "globex", http.StatusMovedPermanently, "https://example.com/acme"},
+		{"unknown namespace", "/initech/shared", "", http.StatusNotFound, ""},
+		{"invalid header", "/shared", "Not A Slug", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("X-Namespace", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestHandlers_Variants(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Post("/shorten", handlers.HandleShorten)
+	router.Get("/shorten/{shortCode}/analytics/variants", handlers.HandleVariantStats)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/shorten", `{
+		"url": "https://example.com/landing",
+		"customAlias": "split",
+		"variants": [
+			{"url": "https://example.com/landing-a", "weight": 70},
+			{"url": "https://example.com/landing-b", "weight": 30}
+		]
+	}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created application.URLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, []application.Variant{
+		{URL: "https://example.com/landing-a", Weight: 70},
+		{URL: "https://example.com/landing-b", Weight: 30},
+	}, created.Variants)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/single",
+		CustomAlias: "single",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	t.Run("redirects to a variant and counts it", func(t *testing.T) {
+		locations := make(map[string]int)
+		for range 50 {
+			w := do(http.MethodGet, "/split", "")
+			require.Equal(t, http.StatusFound, w.Code)
+			locations[w.Header().Get("Location")]++
+		}
+
+		w := do(http.MethodGet, "/shorten/split/analytics/variants", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var stats []domain.VariantStat
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		require.Len(t, stats, 2)
+		for _, stat := range stats {
+			assert.Equal(t, locations[stat.OriginalURL], stat.Clicks, stat.OriginalURL)
+		}
+		assert.Equal(t, 70, stats[0].Weight)
+		assert.Equal(t, 50, stats[0].Clicks+stats[1].Clicks)
+	})
+
+	t.Run("single destination is unchanged", func(t *testing.T) {
+		w := do(http.MethodGet, "/single", "")
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "https://example.com/single", w.Header().Get("Location"))
+
+		w = do(http.MethodGet, "/shorten/single/analytics/variants", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+
+	t.Run("invalid variants", func(t *testing.T) {
+		details := performValidationTest(t, handlers, `{
+			"url": "https://example.com/landing",
+			"variants": [{"url": "not-a-url", "weight": 10}, {"url": "https://example.com/b", "weight": 0}]
+		}`)
+		assert.Equal(t, ValidationFieldError{Field: "variants[0].url", Message: "variants[0].url must be a valid URL", Code: ValidationCodeInvalidURLFormat}, details["variants[0].url"])
+		assert.Equal(t, ValidationFieldError{Field: "variants[1].weight", Message: "variants[1].weight is required", Code: ValidationCodeRequired}, details["variants[1].weight"])
+	})
+}
+
+func TestHandlers_PoolRoundRobin(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Post("/shorten", handlers.HandleShorten)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{
+		"customAlias": "pool",
+		"pool": {"targets": [
+			{"url": "https://eu.example.com", "weight": 3},
+			{"url": "https://us.example.com", "weight": 1}
+		]}
+	}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created application.URLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "https://eu.example.com", created.OriginalURL)
+	require.NotNil(t, created.Pool)
+	assert.Len(t, created.Pool.Targets, 2)
+
+	const requests = 100
+	var mu sync.Mutex
+	locations := make(map[string]int)
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pool", nil))
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, http.StatusFound, w.Code)
+			locations[w.Header().Get("Location")]++
+		}()
+	}
+	wg.Wait()
+
+	// Every run of four redirects hits each target in proportion to its weight
+	assert.Equal(t, map[string]int{"https://eu.example.com": 75, "https://us.example.com": 25}, locations)
+
+	url, err := service.GetURL(context.Background(), domain.DefaultNamespace, "pool")
+	require.NoError(t, err)
+	assert.Equal(t, requests, url.Clicks)
+
+	t.Run("url and pool are mutually exclusive", func(t *testing.T) {
+		details := performValidationTest(t, handlers, `{
+			"url": "https://example.com",
+			"pool": {"targets": [{"url": "https://eu.example.com", "weight": 1}]}
+		}`)
+		assert.Equal(t, ValidationFieldError{Field: "url", Message: "url cannot be combined with pool", Code: ValidationCodeConflictingField}, details["url"])
+
+		details = performValidationTest(t, handlers, `{"customAlias": "neither"}`)
+		assert.Equal(t, ValidationFieldError{Field: "url", Message: "url is required unless pool is given", Code: ValidationCodeRequired}, details["url"])
+
+		details = performValidationTest(t, handlers, `{"pool": {"targets": []}}`)
+		assert.Equal(t, ValidationCodeTooSmall, details["pool.targets"].Code)
+	})
+}
+
+func TestHandlers_SignedURLs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	secret := application.SigningSecret("0123456789abcdef0123456789abcdef")
+
+	newRouter := func(secret application.SigningSecret) *chi.Mux {
+		repo := memory.NewURLRepository(logger, 0)
+		service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{SigningSecret: secret}, logger)
+		handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+		router := chi.NewRouter()
+		router.Post("/shorten", handlers.HandleShorten)
+		router.Get("/{shortCode}", handlers.HandleRedirect)
+		return router
+	}
+	router := newRouter(secret)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com/private", "customAlias": "private", "signedExpiry": "2h"}`)))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created application.URLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, shortcode.IsSigned(created.ShortCode))
+	assert.Equal(t, "http://localhost:8080/"+created.ShortCode, created.ShortURL)
+	require.NotNil(t, created.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), *created.ExpiresAt, time.Minute)
+
+	redirect := func(code string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+		return w
+	}
+
+	t.Run("token redirects", func(t *testing.T) {
+		w := redirect(created.ShortCode)
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "https://example.com/private", w.Header().Get("Location"))
+	})
+
+	t.Run("plain code is hidden", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, redirect("private").Code)
+	})
+
+	t.Run("tampered token is rejected", func(t *testing.T) {
+		tampered := created.ShortCode[:len(created.ShortCode)-2] + "AA"
+		if tampered == created.ShortCode {
+			tampered = created.ShortCode[:len(created.ShortCode)-2] + "BB"
+		}
+		assert.Equal(t, http.StatusNotFound, redirect(tampered).Code)
+	})
+
+	t.Run("expired token is rejected", func(t *testing.T) {
+		expired := shortcode.GenerateSigned(secret, "private", time.Now().Add(-time.Minute))
+		assert.Equal(t, http.StatusNotFound, redirect(expired).Code)
+	})
+
+	t.Run("token signed with another secret is rejected", func(t *testing.T) {
+		forged := shortcode.GenerateSigned([]byte("another-secret-another-secret-xx"), "private", time.Now().Add(time.Hour))
+		assert.Equal(t, http.StatusNotFound, redirect(forged).Code)
+	})
+
+	t.Run("invalid duration", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com", "signedExpiry": "soon"}`)))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var problem ValidationProblemDetail
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, ValidationFieldError{Field: "signedExpiry", Message: "signedExpiry must be a positive duration such as 2h or 30m", Code: ValidationCodeInvalidDuration}, detailsByField(problem)["signedExpiry"])
+	})
+
+	t.Run("signing disabled", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newRouter(nil).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com", "signedExpiry": "2h"}`)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// remoteAddrLocator stands in for a GeoIP database, keyed by client address
+type remoteAddrLocator map[string]string
+
+func (l remoteAddrLocator) Country(r *http.Request) string {
+	return l[r.RemoteAddr]
+}
+
+func TestHandlers_GeoRoutes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Use(geoip.Middleware(remoteAddrLocator{
+		"192.0.2.1:1234":    "US",
+		"198.51.100.1:1234": "DE",
+		"203.0.113.1:1234":  "BR",
+	}))
+	router.Post("/shorten", handlers.HandleShorten)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{
+		"url": "https://example.com/docs",
+		"customAlias": "docs",
+		"geoRoutes": [
+			{"countryCode": "US", "destinationUrl": "https://us.example.com/docs"},
+			{"countryCode": "DE", "destinationUrl": "https://eu.example.com/docs"}
+		]
+	}`)))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created application.URLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Len(t, created.GeoRoutes, 2)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		location   string
+	}{
+		{name: "US visitor", remoteAddr: "192.0.2.1:1234", location: "https://us.example.com/docs"},
+		{name: "DE visitor", remoteAddr: "198.51.100.1:1234", location: "https://eu.example.com/docs"},
+		{name: "country without a route", remoteAddr: "203.0.113.1:1234", location: "https://example.com/docs"},
+		{name: "unknown country", remoteAddr: "127.0.0.1:1234", location: "https://example.com/docs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+			req.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tt.location, w.Header().Get("Location"))
+		})
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{
+			"url": "https://example.com",
+			"geoRoutes": [
+				{"countryCode": "usa", "destinationUrl": "https://us.example.com"},
+				{"countryCode": "DE", "destinationUrl": "not a url"}
+			]
+		}`)))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var problem ValidationProblemDetail
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		details := detailsByField(problem)
+		assert.Equal(t, ValidationFieldError{Field: "geoRoutes[0].countryCode", Message: "geoRoutes[0].countryCode must be an upper case ISO 3166-1 alpha-2 country code", Code: ValidationCodeInvalidCountry}, details["geoRoutes[0].countryCode"])
+		assert.Equal(t, ValidationFieldError{Field: "geoRoutes[1].destinationUrl", Message: "geoRoutes[1].destinationUrl must be a valid URL", Code: ValidationCodeInvalidURLFormat}, details["geoRoutes[1].destinationUrl"])
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{
+			"url": "https://example.com",
+			"geoRoutes": [
+				{"countryCode": "US", "destinationUrl": "https://us.example.com"},
+				{"countryCode": "US", "destinationUrl": "https://us2.example.com"}
+			]
+		}`)))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, ValidationFieldError{Field: "geoRoutes", Message: "geoRoutes must not contain duplicate entries", Code: ValidationCodeDuplicateEntries}, detailsByField(problem)["geoRoutes"])
+	})
+}
+
+func TestHandlers_DeviceRoutes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Use(geoip.Middleware(remoteAddrLocator{"198.51.100.7:1234": "US"}))
+	router.Post("/shorten", handlers.HandleShorten)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{
+		"url": "https://example.com/app",
+		"customAlias": "app",
+		"deviceRoutes": [
+			{"deviceType": "mobile", "destinationUrl": "https://m.example.com/app"},
+			{"deviceType": "tablet", "destinationUrl": "https://tablet.example.com/app"}
+		],
+		"geoRoutes": [
+			{"countryCode": "US", "destinationUrl": "https://us.example.com/app"}
+		]
+	}`)))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created application.URLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Len(t, created.DeviceRoutes, 2)
+
+	const (
+		iPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+		iPad    = "Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+		windows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	)
+
+	tests := []struct {
+		name       string
+		userAgent  string
+		remoteAddr string
+		location   string
+	}{
+		{name: "mobile", userAgent: iPhone, location: "https://m.example.com/app"},
+		{name: "tablet", userAgent: iPad, location: "https://tablet.example.com/app"},
+		{name: "desktop without a route", userAgent: windows, location: "https://example.com/app"},
+		{name: "missing user agent", userAgent: "", location: "https://example.com/app"},
+		{name: "geo route wins", userAgent: iPhone, remoteAddr: "198.51.100.7:1234", location: "https://us.example.com/app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/app", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tt.location, w.Header().Get("Location"))
+		})
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{
+			"url": "https://example.com",
+			"deviceRoutes": [{"deviceType": "watch", "destinationUrl": "https://watch.example.com"}]
+		}`)))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var problem ValidationProblemDetail
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, ValidationFieldError{Field: "deviceRoutes[0].deviceType", Message: "deviceRoutes[0].deviceType must be one of: mobile, tablet, desktop", Code: ValidationCodeInvalidChoice}, detailsByField(problem)["deviceRoutes[0].deviceType"])
+	})
+}
+
+func TestHandlers_RedirectDelay(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{MaxDelay: 10}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Post("/shorten", handlers.HandleShorten)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/shorten", `{"url": "https://example.com/landing?a=1&b=2", "customAlias": "delayed", "delaySeconds": 5}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created application.URLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, 5, created.DelaySeconds)
+
+	t.Run("serves a countdown page and counts the click", func(t *testing.T) {
+		w := do(http.MethodGet, "/delayed", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Header().Get("Location"))
+
+		body := w.Body.String()
+		assert.Contains(t, body, `<meta http-equiv="refresh" content="5; url=https://example.com/landing?a=1&amp;b=2">`)
+		assert.Contains(t, body, `window.location.replace("https://example.com/landing?a=1\u0026b=2")`)
+
+		url, err := repo.FindByShortCode(context.Background(), "delayed")
+		require.NoError(t, err)
+		assert.Equal(t, 1, url.Clicks)
+	})
+
+	t.Run("delay above the configured maximum", func(t *testing.T) {
+		w := do(http.MethodPost, "/shorten", `{"url": "https://example.com", "delaySeconds": 11}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "delaySeconds must be at most 10")
+	})
+
+	t.Run("no delay redirects immediately", func(t *testing.T) {
+		w := do(http.MethodPost, "/shorten", `{"url": "https://example.com/now", "customAlias": "immediate"}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		w = do(http.MethodGet, "/immediate", "")
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "https://example.com/now", w.Header().Get("Location"))
+	})
+}
+
+func TestHandlers_HandlePatchURL(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Patch("/shorten/{shortCode}", handlers.HandlePatchURL)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	ctx := context.Background()
+	_, err := service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/original",
+		CustomAlias: "patchme",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	patch := func(body string) (*httptest.ResponseRecorder, application.URLInfoResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/shorten/patchme", strings.NewReader(body)))
+
+		var resp application.URLInfoResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w, resp
+	}
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	t.Run("tags only", func(t *testing.T) {
+		w, resp := patch(`{"tags": ["docs", "launch"]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"docs", "launch"}, resp.Tags)
+		assert.Equal(t, "https://example.com/original", resp.OriginalURL)
+		assert.Equal(t, http.StatusMovedPermanently, resp.RedirectType)
+		assert.Nil(t, resp.ExpiresAt)
+	})
+
+	t.Run("redirect type and expiry keep the tags", func(t *testing.T) {
+		w, resp := patch(`{"redirectType": 307, "expiresAt": "` + expiresAt.Format(time.RFC3339) + `"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusTemporaryRedirect, resp.RedirectType)
+		require.NotNil(t, resp.ExpiresAt)
+		assert.True(t, expiresAt.Equal(*resp.ExpiresAt))
+		assert.Equal(t, []string{"docs", "launch"}, resp.Tags)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/patchme", nil))
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	})
+
+	t.Run("destination only", func(t *testing.T) {
+		w, resp := patch(`{"originalUrl": "https://example.com/moved"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://example.com/moved", resp.OriginalURL)
+		assert.Equal(t, http.StatusTemporaryRedirect, resp.RedirectType)
+		assert.NotNil(t, resp.ExpiresAt)
+
+		found, err := repo.FindByShortCode(ctx, "patchme")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/moved", found.OriginalURL)
+		assert.Equal(t, domain.Tags{"docs", "launch"}, found.Tags)
+	})
+
+	t.Run("null clears the expiry", func(t *testing.T) {
+		w, resp := patch(`{"expiresAt": null}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, resp.ExpiresAt)
+		assert.Equal(t, "https://example.com/moved", resp.OriginalURL)
+	})
+
+	t.Run("empty document changes nothing", func(t *testing.T) {
+		w, resp := patch(`{}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://example.com/moved", resp.OriginalURL)
+		assert.Equal(t, http.StatusTemporaryRedirect, resp.RedirectType)
+		assert.Equal(t, []string{"docs", "launch"}, resp.Tags)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		w, _ := patch(`{"originalUrl": "", "redirectType": 200}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var problem ValidationProblemDetail
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		details := detailsByField(problem)
+		assert.Equal(t, ValidationFieldError{Field: "originalUrl", Message: "originalUrl must be a valid URL", Code: ValidationCodeInvalidURLFormat}, details["originalUrl"])
+		assert.Equal(t, ValidationFieldError{Field: "redirectType", Message: "redirectType must be one of: 301, 302, 307, 308", Code: ValidationCodeInvalidChoice}, details["redirectType"])
+
+		w, _ = patch(`{"expiresAt": "2001-01-01T00:00:00Z"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("expired URLs stop redirecting", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		url, err := repo.FindByShortCode(ctx, "patchme")
+		require.NoError(t, err)
+		url.ExpiresAt = &past
+		_, err = repo.Update(ctx, url)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/patchme", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// The expiry can still be lifted
+		w, _ = patch(`{"expiresAt": null}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/patchme", nil))
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/shorten/missing", strings.NewReader(`{}`)))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+// outageRepository fails lookups while clicks and everything else still work
+type outageRepository struct {
+	domain.URLRepository
+}
+
+func (r *outageRepository) FindByNamespaceAndCode(context.Context, string, string) (*domain.URL, error) {
+	return nil, errors.New("database is down")
+}
+
+// staleOnlyCache serves every URL it was given from GetStale only
+type staleOnlyCache struct {
+	*cache.NoOpCache
+	urls map[string]*domain.URL
+}
+
+func (c *staleOnlyCache) GetStale(_ context.Context, _, shortCode string) (*domain.URL, error) {
+	url, ok := c.urls[shortCode]
+	if !ok {
+		return nil, nil
+	}
+	copied := *url
+	return &copied, nil
+}
+
+func TestHandlers_HandleRedirect_StaleOnDatabaseError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := &outageRepository{URLRepository: memory.NewURLRepository(logger, 0)}
+	staleURL, err := domain.NewURL("cached", "https://example.com/cached")
+	require.NoError(t, err)
+	staleCache := &staleOnlyCache{NoOpCache: cache.NewNoOpCache(), urls: map[string]*domain.URL{"cached": staleURL}}
+	service := application.NewURLService(repo, staleCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cached", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com/cached", w.Header().Get("Location"))
+	assert.Equal(t, `110 - "Response is Stale"`, w.Header().Get("Warning"))
+
+	// Without a stale entry the outage surfaces
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/uncached", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("Warning"))
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	sleepy := func(delay time.Duration) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+			w.Header().Set("X-Handled", "yes")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("done"))
+		}
+	}
+
+	tests := []struct {
+		name           string
+		delay          time.Duration
+		expectedStatus int
+	}{
+		{"completes in time", 0, http.StatusCreated},
+		{"exceeds the deadline", 200 * time.Millisecond, http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := TimeoutMiddleware(20 * time.Millisecond)(sleepy(tt.delay))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusCreated {
+				assert.Equal(t, "yes", w.Header().Get("X-Handled"))
+				assert.Equal(t, "done", w.Body.String())
+				return
+			}
+
+			assert.Empty(t, w.Header().Get("X-Handled"))
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			var problem ProblemDetail
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, ProblemTypeTimeout, problem.Type)
+		})
+	}
+
+	t.Run("panics reach the recoverer", func(t *testing.T) {
+		handler := TimeoutMiddleware(time.Second)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic("boom")
+		}))
+
+		assert.PanicsWithValue(t, "boom", func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+}
+
+func TestHMACVerificationMiddleware(t *testing.T) {
+	secret := []byte("s3cret")
+	body := `{"url": "https://example.com/signed"}`
+	sign := func(key []byte, payload string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(payload))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var received string
+	handler := HMACVerificationMiddleware(secret, SignatureHeader)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		received = string(read)
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	tests := []struct {
+		name           string
+		signature      string
+		expectedStatus int
+	}{
+		{"valid signature", sign(secret, body), http.StatusCreated},
+		{"signature of another body", sign(secret, body+" "), http.StatusUnauthorized},
+		{"signature under another secret", sign([]byte("other"), body), http.StatusUnauthorized},
+		{"signature not hex", "not-a-signature", http.StatusUnauthorized},
+		{"missing signature", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = ""
+			req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body))
+			if tt.signature != "" {
+				req.Header.Set(SignatureHeader, tt.signature)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusUnauthorized {
+				// The handler reads the body the middleware already read
+				assert.Equal(t, body, received)
+				return
+			}
+			assert.Empty(t, received)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			var problem ProblemDetail
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, ProblemTypeUnauthorized, problem.Type)
+		})
+	}
+
+	t.Run("body too large", func(t *testing.T) {
+		received = ""
+		large := strings.Repeat("a", maxSignedBodyBytes+1)
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(large))
+		req.Header.Set(SignatureHeader, sign(secret, large))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Empty(t, received)
+		assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	})
+}
+
+func TestNewRouter_HMACVerification(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	cfg := &config.Config{Security: config.SecurityConfig{HMACEnabled: true, HMACSecret: "s3cret"}}
+	router := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	body := `{"url": "https://example.com/signed", "customAlias": "signed"}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body))
+	req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Redirects are not signed
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/signed", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+}
+
+// slowRepository delays lookups until the delay passes or the request gives up
+type slowRepository struct {
+	domain.URLRepository
+	delay time.Duration
+}
+
+func (r *slowRepository) FindByNamespaceAndCode(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
+	select {
+	case <-time.After(r.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return r.URLRepository.FindByNamespaceAndCode(ctx, namespace, shortCode)
+}
+
+func TestNewRouter_RouteTimeouts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := &slowRepository{URLRepository: memory.NewURLRepository(logger, 0), delay: 100 * time.Millisecond}
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com/slow", CustomAlias: "slow"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	cfg := &config.Config{Server: config.ServerConfig{RouteTimeouts: map[string]string{"redirect": "20ms"}}}
+	router := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+
+	// Routes without a configured timeout wait for the slow lookup
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/slow/preview", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHandlers_HandleStats(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	for _, alias := range []string{"first", "second"} {
+		_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias}, "http://localhost:8080")
+		require.NoError(t, err)
+	}
+
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+	router := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/first", nil))
+	require.Equal(t, http.StatusMovedPermanently, w.Code)
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{"valid key", "Bearer s3cret", http.StatusOK},
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong key", "Bearer wrong", http.StatusUnauthorized},
+		{"wrong scheme", "Basic s3cret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+				assert.Equal(t, `Bearer realm="admin"`, w.Header().Get("WWW-Authenticate"))
+				return
+			}
+
+			var stats application.StatsResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+			assert.Equal(t, application.StatsResponse{
+				TotalURLs:        2,
+				TotalClicks:      1,
+				ActiveURLs:       2,
+				URLsCreatedToday: 2,
+				ClicksToday:      1,
+				Memory:           &application.MemoryStatsResponse{Size: 2},
+			}, stats)
+		})
+	}
+
+	t.Run("disabled without an API key", func(t *testing.T) {
+		router := NewRouter(handlers, nil, logger, &config.Config{}, metrics.NewNoOpRegistry(), nil, nil)
+		req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+		req.Header.Set("Authorization", "Bearer ")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.NotEqual(t, http.StatusOK, w.Code)
+	})
+}
+
+// fakeMigrations tracks the applied version of a schema of three migrations
+type fakeMigrations struct {
+	version int
+	dirty   bool
+	steps   int
+}
+
+func (f *fakeMigrations) List(context.Context) ([]domain.MigrationStatus, error) {
+	statuses := []domain.MigrationStatus{}
+	for version, name := range []string{"create_urls", "add_clicks", "add_tags"} {
+		statuses = append(statuses, domain.MigrationStatus{
+			Version: uint(version + 1),
+			Name:    name,
+			Applied: version < f.version,
+			Dirty:   f.dirty && version+1 == f.version,
+		})
+	}
+	return statuses, nil
+}
+
+func (f *fakeMigrations) Up(context.Context) error {
+	if f.dirty {
+		return domain.ErrMigrationDirty
+	}
+	f.version = 3
+	return nil
+}
+
+func (f *fakeMigrations) Down(_ context.Context, steps int) error {
+	if f.dirty {
+		return domain.ErrMigrationDirty
+	}
+	f.steps = steps
+	f.version = max(f.version-steps, 0)
+	return nil
+}
+
+func TestMigrationHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+
+	serve := func(router chi.Router, method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	applied := func(t *testing.T, w *httptest.ResponseRecorder) int {
+		t.Helper()
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var statuses []domain.MigrationStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+		require.Len(t, statuses, 3)
+		count := 0
+		for _, status := range statuses {
+			if status.Applied {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Run("list, down and up", func(t *testing.T) {
+		migrations := &fakeMigrations{version: 3}
+		router := NewRouter(handlers, NewMigrationHandlers(migrations, nil), logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+		assert.Equal(t, 3, applied(t, serve(router, http.MethodGet, "/admin/migrations")))
+		assert.Equal(t, 2, applied(t, serve(router, http.MethodPost, "/admin/migrations/down")))
+		assert.Equal(t, 1, migrations.steps, "steps defaults to 1")
+		assert.Equal(t, 0, applied(t, serve(router, http.MethodPost, "/admin/migrations/down?steps=5")))
+		assert.Equal(t, 3, applied(t, serve(router, http.MethodPost, "/admin/migrations/up")))
+	})
+
+	t.Run("invalid steps", func(t *testing.T) {
+		router := NewRouter(handlers, NewMigrationHandlers(&fakeMigrations{version: 3}, nil), logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+		for _, steps := range []string{"0", "-1", "abc"} {
+			w := serve(router, http.MethodPost, "/admin/migrations/down?steps="+steps)
+			assert.Equal(t, http.StatusBadRequest, w.Code, steps)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("dirty schema", func(t *testing.T) {
+		router := NewRouter(handlers, NewMigrationHandlers(&fakeMigrations{version: 2, dirty: true}, nil), logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+		w := serve(router, http.MethodGet, "/admin/migrations")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"dirty":true`)
+
+		for _, target := range []string{"/admin/migrations/up", "/admin/migrations/down"} {
+			w := serve(router, http.MethodPost, target)
+			assert.Equal(t, http.StatusConflict, w.Code, target)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("requires the admin API key", func(t *testing.T) {
+		router := NewRouter(handlers, NewMigrationHandlers(&fakeMigrations{}, nil), logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+		for _, target := range []string{"/admin/migrations", "/admin/migrations/up", "/admin/migrations/down"} {
+			method := http.MethodPost
+			if target == "/admin/migrations" {
+				method = http.MethodGet
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+			assert.Equal(t, http.StatusUnauthorized, w.Code, target)
+		}
+	})
+
+	t.Run("not routed without a schema", func(t *testing.T) {
+		router := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+		w := serve(router, http.MethodPost, "/admin/migrations/up")
+		assert.NotEqual(t, http.StatusOK, w.Code)
+
+		router = NewRouter(handlers, NewMigrationHandlers(nil, storeMigratorStub{}), logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+		w = serve(router, http.MethodPost, "/admin/migrations/up")
+		assert.NotEqual(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("migrate store", func(t *testing.T) {
+		router := NewRouter(handlers, NewMigrationHandlers(nil, storeMigratorStub{}), logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+		tests := []struct {
+			name         string
+			query        string
+			expectedCode int
+		}{
+			{name: "migrated", query: "?from=memory&to=postgres", expectedCode: http.StatusOK},
+			{name: "unsupported store", query: "?from=memory&to=memory", expectedCode: http.StatusBadRequest},
+			{name: "already running", query: "?from=memory&to=sqlite", expectedCode: http.StatusConflict},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := serve(router, http.MethodPost, "/admin/migrate-store"+tt.query)
+				require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+				if tt.expectedCode == http.StatusOK {
+					var report domain.StoreMigrationReport
+					require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+					assert.Equal(t, domain.StoreMigrationReport{Migrated: 98, Skipped: 1, Failed: 1}, report)
+				} else {
+					assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+				}
+			})
+		}
+	})
+}
+
+// storeMigratorStub migrates memory to postgres, pretends a migration to sqlite is running
+// and refuses every other store
+type storeMigratorStub struct{}
+
+func (storeMigratorStub) MigrateStore(_ context.Context, from, to string) (*domain.StoreMigrationReport, error) {
+	switch {
+	case from == "memory" && to == "postgres":
+		return &domain.StoreMigrationReport{Migrated: 98, Skipped: 1, Failed: 1}, nil
+	case from == "memory" && to == "sqlite":
+		return nil, domain.ErrStoreMigrationRunning
+	default:
+		return nil, fmt.Errorf("%w: cannot migrate %s to %s", domain.ErrUnsupportedStore, from, to)
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	compression, err := CompressionMiddleware(config.CompressionConfig{Enabled: true, Level: 5, MinSize: 1024})
+	require.NoError(t, err)
+
+	large := strings.Repeat(`{"shortCode":"abc123","originalUrl":"https://example.com"},`, 100)
+	handler := compression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.URL.Query().Get("type")
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		w.Header().Set("Content-Type", contentType)
+		if r.URL.Query().Has("small") {
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		_, _ = w.Write([]byte(large))
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		acceptEncoding string
+		compressed     bool
+	}{
+		{"large GET", http.MethodGet, "/", "gzip, deflate, br", true},
+		{"small GET", http.MethodGet, "/?small", "gzip", false},
+		{"client without gzip", http.MethodGet, "/", "", false},
+		{"POST", http.MethodPost, "/", "gzip", false},
+		{"event stream", http.MethodGet, "/?type=text/event-stream", "gzip", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if !tt.compressed {
+				assert.Empty(t, w.Header().Get("Content-Encoding"))
+				assert.NotContains(t, w.Body.String(), "\x1f\x8b")
+				return
+			}
+
+			assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+			assert.Less(t, w.Body.Len(), len(large))
+			reader, err := gzip.NewReader(w.Body)
+			require.NoError(t, err)
+			body, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, large, string(body))
+		})
+	}
+}
+
+func TestNewRouter_Compression(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	for i := range 10 {
+		_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: fmt.Sprintf("https://example.com/page/%d", i)}, "http://localhost:8080")
+		require.NoError(t, err)
+	}
+
+	cfg := &config.Config{Server: config.ServerConfig{Compression: config.CompressionConfig{Enabled: true, Level: 5, MinSize: 1024}}}
+	router := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/urls/top", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	var top []application.URLResponse
+	require.NoError(t, json.NewDecoder(reader).Decode(&top))
+	assert.Len(t, top, 10)
+}
+
+// unhealthyRepository fails the readiness check
+type unhealthyRepository struct {
+	domain.URLRepository
+}
+
+func (unhealthyRepository) HealthCheck(context.Context) error {
+	return errors.New("connection refused")
+}
+
+// unreachableCache fails every ping
+type unreachableCache struct {
+	*cache.NoOpCache
+}
+
+func (unreachableCache) Ping(context.Context) error {
+	return errors.New("dial tcp: connection refused")
+}
+
+func TestHandlers_HandleReady(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+
+	tests := []struct {
+		name            string
+		repo            domain.URLRepository
+		cache           domain.Cache
+		degradedCacheOK bool
+		wantStatus      int
+		wantCache       string
+	}{
+		{"fully healthy", repo, cache.NewNoOpCache(), true, http.StatusOK, "ok"},
+		{"cache disabled", repo, nil, true, http.StatusOK, "disabled"},
+		{"degraded cache", repo, unreachableCache{cache.NewNoOpCache()}, true, http.StatusOK, "degraded"},
+		{"degraded cache not accepted", repo, unreachableCache{cache.NewNoOpCache()}, false, http.StatusServiceUnavailable, ""},
+		{"database down", unhealthyRepository{repo}, cache.NewNoOpCache(), true, http.StatusServiceUnavailable, ""},
+		{"fully down", unhealthyRepository{repo}, unreachableCache{cache.NewNoOpCache()}, true, http.StatusServiceUnavailable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers := NewHandlers(service, "http://localhost:8080", tt.repo, HandlersDeps{Cache: tt.cache, DegradedCacheOK: tt.degradedCacheOK})
+
+			w := httptest.NewRecorder()
+			handlers.HandleReady(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+
+			if tt.wantStatus != http.StatusOK {
+				var problem ProblemDetail
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+				assert.Equal(t, ProblemTypeUnavailable, problem.Type)
+				return
+			}
+
+			var response ReadinessResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, "ready", response.Status)
+			assert.Equal(t, "ok", response.DB)
+			assert.Equal(t, tt.wantCache, response.Cache)
+			assert.WithinDuration(t, time.Now(), response.Timestamp, time.Minute)
+		})
+	}
+}
+
+func TestNewRouter_DocsSpecURL(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	tests := []struct {
+		name string
+		app  config.AppConfig
+		want string
+	}{
+		{"scheme of the base URL", config.AppConfig{BaseURL: "http://dove.example.com/"}, "http://dove.example.com/swagger/doc.json"},
+		{"forced scheme", config.AppConfig{BaseURL: "http://dove.example.com", ShortURLScheme: "https"}, "https://dove.example.com/swagger/doc.json"},
+		{"no base URL", config.AppConfig{ShortURLScheme: "https"}, "/swagger/doc.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter(handlers, nil, logger, &config.Config{App: tt.app}, metrics.NewNoOpRegistry(), nil, nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/redoc", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), "spec-url='"+tt.want+"'")
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+			// The page escapes the slashes of the URL within its script
+			assert.Contains(t, w.Body.String(), `url: "`+strings.ReplaceAll(tt.want, "/", `\/`)+`"`)
+		})
+	}
+}
+
+func TestNewRouter_Robots(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	created, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		app  config.AppConfig
+		want string
+	}{
+		{"default keeps docs crawlable", config.AppConfig{}, "User-agent: *\nAllow: /swagger/\nAllow: /redoc$\nDisallow: /\n"},
+		{"disallow all", config.AppConfig{RobotsDisallowAll: true}, "User-agent: *\nDisallow: /\n"},
+		{"custom body wins", config.AppConfig{RobotsDisallowAll: true, RobotsCustom: "User-agent: Googlebot\nDisallow: /\n"}, "User-agent: Googlebot\nDisallow: /\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter(handlers, nil, logger, &config.Config{App: tt.app}, metrics.NewNoOpRegistry(), nil, nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+
+	t.Run("redirects are not indexed", func(t *testing.T) {
+		router := NewRouter(handlers, nil, logger, &config.Config{}, metrics.NewNoOpRegistry(), nil, nil)
+
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(method, "/"+created.ShortCode, nil))
+			require.Less(t, w.Code, 400, method)
+			assert.Equal(t, "noindex, nofollow", w.Header().Get("X-Robots-Tag"), method)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/urls/top", nil))
+		assert.Empty(t, w.Header().Get("X-Robots-Tag"))
+	})
+}
+
+// fakeKeyMigrator is a cache that records key prefix migrations
+type fakeKeyMigrator struct {
+	*cache.NoOpCache
+	oldPrefix, newPrefix string
+}
+
+func (f *fakeKeyMigrator) MigrateKeys(_ context.Context, oldPrefix, newPrefix string) (int, error) {
+	if oldPrefix == newPrefix {
+		return 0, fmt.Errorf("%w: same prefix", domain.ErrInvalidKeyPrefix)
+	}
+	f.oldPrefix, f.newPrefix = oldPrefix, newPrefix
+	return 3, nil
+}
+
+func TestHandlers_HandleMigrateCacheKeys(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+
+	serve := func(router chi.Router, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/cache/migrate-keys", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	migrator := &fakeKeyMigrator{NoOpCache: cache.NewNoOpCache()}
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{Cache: migrator, DegradedCacheOK: true}), nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	t.Run("migrates", func(t *testing.T) {
+		w := serve(router, `{"oldPrefix":"dove","newPrefix":"prod"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response MigrateCacheKeysResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 3, response.Renamed)
+		assert.Equal(t, "dove", migrator.oldPrefix)
+		assert.Equal(t, "prod", migrator.newPrefix)
+	})
+
+	t.Run("invalid prefixes", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(router, `{"oldPrefix":"dove","newPrefix":"dove"}`).Code)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(router, `{`).Code)
+	})
+
+	t.Run("not routed without a prefixed cache", func(t *testing.T) {
+		router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{Cache: cache.NewNoOpCache(), DegradedCacheOK: true}), nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+		assert.Equal(t, http.StatusNotFound, serve(router, `{"oldPrefix":"dove","newPrefix":"prod"}`).Code)
+	})
+}
+
+func TestHandlers_HandleCacheKeys(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	fileCache, err := cache.NewFileCache(t.TempDir(), logger)
+	require.NoError(t, err)
+	service := application.NewURLService(repo, fileCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret", ExposeCache: true}}
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{Cache: fileCache, DegradedCacheOK: true}), nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	for _, shortCode := range []string{"qakeys1", "qakeys2", "qakeys3"} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		require.NoError(t, fileCache.Set(context.Background(), url, time.Minute))
+	}
+
+	serve := func(router chi.Router, query, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/cache/keys"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	page := func(t *testing.T, query string) CacheKeysResponse {
+		t.Helper()
+		w := serve(router, query, "s3cret")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response CacheKeysResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("pages through the matching keys", func(t *testing.T) {
+		first := page(t, "?pattern=url:*&count=2")
+		assert.Equal(t, CacheKeysResponse{Keys: []string{"url:default:qakeys1", "url:default:qakeys2"}, NextCursor: 2}, first)
+
+		last := page(t, "?pattern=url:*&count=2&cursor=2")
+		assert.Equal(t, CacheKeysResponse{Keys: []string{"url:default:qakeys3"}}, last)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		assert.Equal(t, CacheKeysResponse{Keys: []string{}}, page(t, "?pattern=top_urls:*"))
+	})
+
+	for _, query := range []string{"?cursor=-1", "?cursor=x", "?count=0", "?count=1001"} {
+		t.Run("invalid "+query, func(t *testing.T) {
+			assert.Equal(t, http.StatusBadRequest, serve(router, query, "s3cret").Code)
+		})
+	}
+
+	t.Run("requires the admin API key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(router, "", "wrong").Code)
+	})
+
+	t.Run("not routed unless exposed", func(t *testing.T) {
+		cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+		router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{Cache: fileCache, DegradedCacheOK: true}), nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+		assert.Equal(t, http.StatusNotFound, serve(router, "", "s3cret").Code)
+	})
+}
+
+func TestHandlers_HandleResetAnalytics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+	router := NewRouter(handlers, nil, logger, &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}, metrics.NewNoOpRegistry(), nil, nil)
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com", CustomAlias: "qareset"}, "http://localhost:8080")
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/qareset", nil))
+	require.Equal(t, http.StatusMovedPermanently, w.Code)
+
+	reset := func(shortCode, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/shorten/"+shortCode+"/analytics/reset", nil)
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, reset("qareset", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, reset("qareset", "wrong").Code)
+	assert.Equal(t, http.StatusNotFound, reset("missing", "s3cret").Code)
+
+	w = reset("qareset", "s3cret")
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.Empty(t, w.Body.String())
+
+	url, err := service.GetURL(context.Background(), domain.DefaultNamespace, "qareset")
+	require.NoError(t, err)
+	assert.Zero(t, url.Clicks)
+}
+
+func TestHandlers_DisableEnable(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}, Metrics: config.MetricsConfig{Enabled: true, Path: "/metrics", Namespace: "dove"}}
+	registry, err := metrics.NewPrometheusRegistry(cfg.Metrics)
+	require.NoError(t, err)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{Metrics: registry}, logger)
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{}), nil, logger, cfg, registry, nil, nil)
+
+	for _, req := range []application.CreateURLRequest{
+		{URL: "https://example.com", CustomAlias: "paused"},
+		{URL: "https://example.com/team", CustomAlias: "paused", Namespace: "team"},
+	} {
+		_, err := service.CreateShortURL(context.Background(), req, "http://localhost:8080")
+		require.NoError(t, err)
+	}
+
+	serve := func(method, target, namespace, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if namespace != "" {
+			req.Header.Set("X-Namespace", namespace)
+		}
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPatch, "/admin/urls/paused/disable", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPatch, "/admin/urls/missing/disable", "", "s3cret").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPatch, "/admin/urls/paused/disable", "Not A Slug", "s3cret").Code)
+
+	w := serve(http.MethodPatch, "/admin/urls/paused/disable", "", "s3cret")
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.Empty(t, w.Body.String())
+
+	t.Run("disabled URLs are gone", func(t *testing.T) {
+		w := serve(http.MethodGet, "/paused", "", "")
+		require.Equal(t, http.StatusGone, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		var problem ProblemDetail
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, ProblemTypeDisabled, problem.Type)
+		assert.Equal(t, "Short URL is disabled", problem.Detail)
+
+		assert.Equal(t, http.StatusGone, serve(http.MethodHead, "/paused", "", "").Code)
+
+		url, err := service.GetURL(context.Background(), domain.DefaultNamespace, "paused")
+		require.NoError(t, err)
+		assert.Zero(t, url.Clicks, "refused redirects are not clicks")
+
+		w = serve(http.MethodGet, "/shorten/paused", "", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var info application.URLInfoResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+		assert.False(t, info.Enabled)
+	})
+
+	t.Run("other namespaces keep redirecting", func(t *testing.T) {
+		assert.Equal(t, http.StatusMovedPermanently, serve(http.MethodGet, "/team/paused", "", "").Code)
+	})
+
+	t.Run("disabling a disabled URL is not counted", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, serve(http.MethodPatch, "/admin/urls/paused/disable", "", "s3cret").Code)
+		require.Equal(t, http.StatusNoContent, serve(http.MethodPatch, "/admin/urls/paused/disable", "team", "s3cret").Code)
+
+		body := serve(http.MethodGet, "/metrics", "", "").Body.String()
+		assert.Contains(t, body, "dove_urls_disabled_total 2")
+	})
+
+	t.Run("enabling restores the redirect", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, serve(http.MethodPatch, "/admin/urls/paused/enable", "", "s3cret").Code)
+
+		w := serve(http.MethodGet, "/paused", "", "")
+		require.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+		assert.Equal(t, http.StatusGone, serve(http.MethodGet, "/team/paused", "", "").Code)
+	})
+}
+
+func TestHandlers_ActiveURLsGauge(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}, Metrics: config.MetricsConfig{Enabled: true, Path: "/metrics", Namespace: "dove"}}
+	registry, err := metrics.NewPrometheusRegistry(cfg.Metrics)
+	require.NoError(t, err)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{Metrics: registry}, logger)
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{}), nil, logger, cfg, registry, nil, nil)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	active := func() string {
+		for _, line := range strings.Split(serve(http.MethodGet, "/metrics", "").Body.String(), "\n") {
+			if strings.HasPrefix(line, "dove_urls_active ") {
+				return strings.TrimPrefix(line, "dove_urls_active ")
+			}
+		}
+		return ""
+	}
+
+	registry.SetActiveURLs(3)
+	require.Equal(t, http.StatusCreated, serve(http.MethodPost, "/shorten", `{"url": "https://example.com", "customAlias": "gauged"}`).Code)
+	require.Equal(t, http.StatusCreated, serve(http.MethodPost, "/shorten", `{"url": "https://example.com/other"}`).Code)
+	assert.Equal(t, "5", active(), "created URLs are added")
+
+	require.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/shorten", `{"url": "not a url"}`).Code)
+	assert.Equal(t, "5", active(), "failed creations are not")
+
+	require.Equal(t, http.StatusNoContent, serve(http.MethodPatch, "/admin/urls/gauged/disable", "").Code)
+	require.Equal(t, http.StatusNoContent, serve(http.MethodPatch, "/admin/urls/gauged/disable", "").Code)
+	assert.Equal(t, "4", active(), "disabled URLs are removed once")
+
+	require.Equal(t, http.StatusNoContent, serve(http.MethodPatch, "/admin/urls/gauged/enable", "").Code)
+	assert.Equal(t, "5", active())
+}
+
+// timedOutRepository fails lookups and referrer queries as if they ran past the query timeout
+type timedOutRepository struct {
+	domain.URLRepository
+}
+
+func (timedOutRepository) FindByNamespaceAndCode(context.Context, string, string) (*domain.URL, error) {
+	return nil, fmt.Errorf("%w: find URL by short code", domain.ErrQueryTimeout)
+}
+
+func (timedOutRepository) TopReferrers(context.Context, string, string, int) ([]domain.ReferrerCount, error) {
+	return nil, fmt.Errorf("%w: top referrers", domain.ErrQueryTimeout)
+}
+
+func TestHandlers_QueryTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := timedOutRepository{memory.NewURLRepository(logger, 0)}
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{}), nil, logger, &config.Config{}, metrics.NewNoOpRegistry(), nil, nil)
+
+	for _, target := range []string{"/shorten/slow", "/shorten/slow/analytics/referrers"} {
+		t.Run(target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			require.Equal(t, http.StatusGatewayTimeout, w.Code, w.Body.String())
+
+			var problem ProblemDetail
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, ProblemTypeTimeout, problem.Type)
+			assert.Equal(t, "Database query timed out", problem.Detail)
+		})
+	}
+}
+
+func TestLoggingMiddleware_Sampling(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		sampleRate    float64
+		wantCompleted bool
+	}{
+		{"keeps every request log at rate 1", 1, true},
+		{"drops request logs at rate 0", 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			handler := LoggingMiddleware(logger, tt.sampleRate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logging.FromContext(r.Context()).Warn("Destination is slow")
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc123", nil))
+
+			assert.Equal(t, tt.wantCompleted, strings.Contains(logs.String(), "Request completed"))
+			assert.Contains(t, logs.String(), "Destination is slow", "warnings are never sampled")
+		})
+	}
+}
+
+func TestHandlers_Funnels(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{}), nil, logger, &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}, metrics.NewNoOpRegistry(), nil, nil)
+
+	for _, alias := range []string{"step1", "step2", "step3", "outside"} {
+		_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias}, "http://localhost:8080")
+		require.NoError(t, err)
+	}
+
+	admin := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	// click follows shortCode with the funnel cookie of the visitor, if any, and returns
+	// the cookie set by the response
+	click := func(t *testing.T, shortCode string, session *http.Cookie) *http.Cookie {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/"+shortCode, nil)
+		if session != nil {
+			req.AddCookie(session)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMovedPermanently, w.Code)
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == funnelSessionCookie {
+				return cookie
+			}
+		}
+		return nil
+	}
+
+	w := admin(http.MethodPost, "/admin/funnels", `{"name":"Signup","steps":[{"shortCode":"step1"},{"shortCode":"step2"},{"shortCode":"step3"}]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var funnel domain.Funnel
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &funnel))
+	assert.Equal(t, "Signup", funnel.Name)
+	assert.Equal(t, []domain.FunnelStep{
+		{Order: 1, Namespace: domain.DefaultNamespace, ShortCode: "step1"},
+		{Order: 2, Namespace: domain.DefaultNamespace, ShortCode: "step2"},
+		{Order: 3, Namespace: domain.DefaultNamespace, ShortCode: "step3"},
+	}, funnel.Steps)
+
+	t.Run("rejects invalid funnels", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{name: "invalid body", body: `{`},
+			{name: "single step", body: `{"name":"Short","steps":[{"shortCode":"step1"}]}`},
+			{name: "missing name", body: `{"steps":[{"shortCode":"step1"},{"shortCode":"step2"}]}`},
+			{name: "unknown step", body: `{"name":"Broken","steps":[{"shortCode":"step1"},{"shortCode":"missing"}]}`},
+			{name: "repeated step", body: `{"name":"Loop","steps":[{"shortCode":"step1"},{"shortCode":"step2"},{"shortCode":"step1"}]}`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := admin(http.MethodPost, "/admin/funnels", tt.body)
+				assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+				assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			})
+		}
+	})
+
+	t.Run("follows sessions through the funnel", func(t *testing.T) {
+		assert.Nil(t, click(t, "outside", nil), "URLs outside funnels set no cookie")
+
+		// A visitor completing the funnel keeps one session
+		session := click(t, "step1", nil)
+		require.NotNil(t, session)
+		assert.Equal(t, funnelSessionMaxAge, session.MaxAge)
+		assert.True(t, session.HttpOnly)
+		renewed := click(t, "step2", session)
+		require.NotNil(t, renewed)
+		assert.Equal(t, session.Value, renewed.Value)
+		click(t, "step3", renewed)
+
+		// A visitor leaving after the first step
+		click(t, "step1", nil)
+		// A visitor landing on the last step skipped the funnel
+		click(t, "step3", &http.Cookie{Name: funnelSessionCookie, Value: "forged"})
+
+		w := admin(http.MethodGet, fmt.Sprintf("/admin/funnels/%d/analytics", funnel.ID), "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var analytics application.FunnelAnalyticsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &analytics))
+		assert.Equal(t, funnel.ID, analytics.FunnelID)
+		assert.Equal(t, []application.FunnelStepAnalytics{
+			{Order: 1, Namespace: domain.DefaultNamespace, ShortCode: "step1", Sessions: 2, CompletionRate: 1},
+			{Order: 2, Namespace: domain.DefaultNamespace, ShortCode: "step2", Sessions: 1, CompletionRate: 0.5},
+			{Order: 3, Namespace: domain.DefaultNamespace, ShortCode: "step3", Sessions: 1, CompletionRate: 0.5},
+		}, analytics.Steps)
+	})
+
+	t.Run("analytics of unknown funnels", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, admin(http.MethodGet, "/admin/funnels/999/analytics", "").Code)
+		assert.Equal(t, http.StatusBadRequest, admin(http.MethodGet, "/admin/funnels/abc/analytics", "").Code)
+	})
+
+	t.Run("requires the admin API key", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/admin/funnels/%d/analytics", funnel.ID), nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestHandlers_ColdURLs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{}), nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	for shortCode, age := range map[string]int{"ancient": 400, "dusty": 60, "recent": 3} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		url.CreatedAt = time.Now().AddDate(0, 0, -age)
+		_, err = repo.Create(context.Background(), url)
+		require.NoError(t, err)
+	}
+	_, err := repo.IncrementClicks(context.Background(), domain.DefaultNamespace, "dusty", false)
+	require.NoError(t, err)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		want   []string
+	}{
+		{name: "defaults to 30 days", target: "/admin/urls/cold", want: []string{"ancient"}},
+		{name: "longer window", target: "/admin/urls/cold?days=500", want: []string{}},
+		{name: "shorter window", target: "/admin/urls/cold?days=2", want: []string{"ancient", "recent"}},
+		{name: "limited", target: "/admin/urls/cold?days=2&limit=1", want: []string{"ancient"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.target)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var urls []application.URLInfoResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &urls))
+			shortCodes := []string{}
+			for _, url := range urls {
+				shortCodes = append(shortCodes, url.ShortCode)
+				assert.Nil(t, url.LastAccessedAt, "cold URLs listed here were never clicked")
+			}
+			assert.Equal(t, tt.want, shortCodes)
+		})
+	}
+
+	for _, target := range []string{
+		"/admin/urls/cold?days=0",
+		"/admin/urls/cold?days=3651",
+		"/admin/urls/cold?days=soon",
+		"/admin/urls/cold?limit=0",
+		"/admin/urls/cold?limit=1001",
+	} {
+		t.Run("rejects "+target, func(t *testing.T) {
+			assert.Equal(t, http.StatusBadRequest, serve(target).Code)
+		})
+	}
+
+	t.Run("requires the admin API key", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/urls/cold", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestHandlers_CreatedByIP(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{}), nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	shorten := func(alias, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com/`+alias+`", "customAlias": "`+alias+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name         string
+		alias        string
+		remoteAddr   string
+		forwardedFor string
+		wantStored   string
+		wantMasked   string
+	}{
+		{name: "remote address", alias: "direct", remoteAddr: "192.168.1.1:51234", wantStored: "192.168.1.1", wantMasked: "192.168.1.xxx"},
+		{name: "forwarded for", alias: "proxied", remoteAddr: "10.0.0.1:443", forwardedFor: "203.0.113.7", wantStored: "203.0.113.7", wantMasked: "203.0.113.xxx"},
+		{name: "ipv4-mapped ipv6", alias: "mapped", remoteAddr: "[::ffff:192.168.1.1]:51234", wantStored: "192.168.1.1", wantMasked: "192.168.1.xxx"},
+		{name: "ipv6", alias: "sixer", remoteAddr: "[2001:db8::42]:51234", wantStored: "2001:db8::42", wantMasked: "2001:db8::xxx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := shorten(tt.alias, tt.remoteAddr, tt.forwardedFor)
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+			var response application.URLResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.wantMasked, response.CreatedByIP)
+
+			stored, err := repo.FindByShortCode(context.Background(), tt.alias)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStored, stored.CreatedByIP)
+
+			// The public metadata endpoint masks the address as well
+			info := httptest.NewRecorder()
+			router.ServeHTTP(info, httptest.NewRequest(http.MethodGet, "/shorten/"+tt.alias, nil))
+			require.Equal(t, http.StatusOK, info.Code)
+			var infoResponse application.URLInfoResponse
+			require.NoError(t, json.Unmarshal(info.Body.Bytes(), &infoResponse))
+			assert.Equal(t, tt.wantMasked, infoResponse.CreatedByIP)
+		})
+	}
+
+	list := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("admins filter by creator and see it unmasked", func(t *testing.T) {
+		w := list("/admin/urls?created_by_ip=192.168.1.1")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var urls []application.URLInfoResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &urls))
+		require.Len(t, urls, 2)
+		assert.Equal(t, "direct", urls[0].ShortCode)
+		assert.Equal(t, "mapped", urls[1].ShortCode)
+		for _, url := range urls {
+			assert.Equal(t, "192.168.1.1", url.CreatedByIP)
+		}
+	})
+
+	t.Run("admins page through every URL", func(t *testing.T) {
+		w := list("/admin/urls?limit=3")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var first []application.URLInfoResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+		require.Len(t, first, 3)
+
+		w = list(fmt.Sprintf("/admin/urls?after=%d", first[2].ID))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var rest []application.URLInfoResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rest))
+		require.Len(t, rest, 1)
+		assert.Equal(t, "sixer", rest[0].ShortCode)
+		assert.Equal(t, "2001:db8::42", rest[0].CreatedByIP)
+	})
+
+	for _, target := range []string{
+		"/admin/urls?created_by_ip=nobody",
+		"/admin/urls?after=-1",
+		"/admin/urls?limit=0",
+		"/admin/urls?limit=1001",
+	} {
+		t.Run("rejects "+target, func(t *testing.T) {
+			assert.Equal(t, http.StatusBadRequest, list(target).Code)
+		})
+	}
+
+	t.Run("requires the admin API key", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/urls", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestHandlers_AliasStats(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{}), nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	serve := func() application.AliasStatsResponse {
+		req := httptest.NewRequest(http.MethodGet, "/admin/stats/aliases", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var stats application.AliasStatsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		return stats
+	}
+
+	t.Run("without URLs", func(t *testing.T) {
+		assert.Equal(t, application.AliasStatsResponse{Generated: application.GeneratedAliasStats{Distribution: map[int]int64{}}}, serve())
+	})
+
+	t.Run("generated codes apart from custom aliases", func(t *testing.T) {
+		for _, alias := range []string{"", "", "", "abc", "promo", "spring2025"} {
+			_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com", CustomAlias: alias}, "http://localhost:8080")
+			require.NoError(t, err)
+		}
+
+		stats := serve()
+		assert.Equal(t, int64(3), stats.Generated.Count)
+		assert.Equal(t, map[int]int64{6: 3}, stats.Generated.Distribution)
+		assert.InDelta(t, 6, stats.Generated.AvgLength, 0.001)
+		assert.Equal(t, application.CustomAliasStats{Count: 3, AvgLength: 6, MinLength: 3, MaxLength: 10}, stats.Custom)
+	})
+
+	t.Run("requires the admin API key", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/stats/aliases", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestHandlers_DailyStats(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+	router := NewRouter(NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{}), nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+	ctx := context.Background()
+
+	for shortCode, createdAt := range map[string]time.Time{
+		"first":  time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		"second": time.Date(2024, 1, 1, 23, 59, 0, 0, time.UTC),
+		"third":  time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		"later":  time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		url.CreatedAt = createdAt
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	for _, clickedAt := range []time.Time{
+		time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 13, 0, 0, 0, time.UTC),
+	} {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "first", ClickedAt: clickedAt, Referer: domain.DirectReferer}))
+	}
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("counts every day of the range", func(t *testing.T) {
+		w := serve("/admin/stats/daily?from=2024-01-01&to=2024-01-04")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var counts []application.DailyCount
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &counts))
+		assert.Equal(t, []application.DailyCount{
+			{Date: "2024-01-01", URLsCreated: 2, Clicks: 1},
+			{Date: "2024-01-02", URLsCreated: 0, Clicks: 2},
+			{Date: "2024-01-03", URLsCreated: 1, Clicks: 0},
+			{Date: "2024-01-04", URLsCreated: 0, Clicks: 0},
+		}, counts)
+	})
+
+	t.Run("a year at most", func(t *testing.T) {
+		w := serve("/admin/stats/daily?from=2024-01-01&to=2024-12-30")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var counts []application.DailyCount
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &counts))
+		assert.Len(t, counts, application.MaxDailyStatsDays)
+
+		assert.Equal(t, http.StatusBadRequest, serve("/admin/stats/daily?from=2024-01-01&to=2024-12-31").Code)
+	})
+
+	for _, target := range []string{
+		"/admin/stats/daily?from=2024-01-05&to=2024-01-01",
+		"/admin/stats/daily?from=yesterday",
+		"/admin/stats/daily?to=2024-01-01T00:00:00Z",
+	} {
+		t.Run("rejects "+target, func(t *testing.T) {
+			w := serve(target)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+		})
+	}
+
+	t.Run("requires the admin API key", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/stats/daily", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestNewRouter_AdminOnlyRoutes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com/guarded", CustomAlias: "guarded"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret", ExportEnabled: true}}
+	router := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"patch", http.MethodPatch, "/shorten/guarded", `{"originalUrl": "https://attacker.example.com"}`},
+		{"bulk delete", http.MethodDelete, "/urls/bulk", `{"shortCodes": ["guarded"]}`},
+		{"import", http.MethodPost, "/urls/import", `[{"url": "https://example.com/imported"}]`},
+		{"URL export", http.MethodGet, "/urls/export", ""},
+		{"click export", http.MethodGet, "/shorten/guarded/clicks/export", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, `Bearer realm="admin"`, w.Header().Get("WWW-Authenticate"))
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer wrong")
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+
+	// Nothing was changed by the rejected requests
+	url, err := repo.FindByNamespaceAndCode(context.Background(), domain.DefaultNamespace, "guarded")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/guarded", url.OriginalURL)
+}
+
+func TestNewRouter_CORS(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	cors := config.CORSConfig{
+		Enabled:        true,
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		ExposedHeaders: []string{"Location"},
+		MaxAgeSeconds:  600,
+	}
+	cfg := &config.Config{Server: config.ServerConfig{CORS: cors}, Admin: config.AdminConfig{APIKey: "s3cret"}}
+	router := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil)
+
+	preflight := func(path, method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		method      string
+		wantAllowed string
+	}{
+		{"shorten", "/shorten", http.MethodPost, "POST, OPTIONS"},
+		{"redirect", "/abc123", http.MethodGet, "GET"},
+		{"urls", "/urls", http.MethodGet, "GET"},
+		{"bulk delete", "/urls/bulk", http.MethodDelete, "DELETE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := preflight(tt.path, tt.method, "https://app.example.com")
+			require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+			assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), tt.wantAllowed)
+			assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+			assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+			assert.Equal(t, "Origin", w.Header().Get("Vary"))
+		})
+	}
+
+	t.Run("method the route lacks", func(t *testing.T) {
+		w := preflight("/shorten", http.MethodPut, "https://app.example.com")
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Contains(t, w.Header().Get("Allow"), http.MethodPost)
+		assert.NotContains(t, w.Header().Get("Allow"), http.MethodPut)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("origin not allowed", func(t *testing.T) {
+		w := preflight("/shorten", http.MethodPost, "https://evil.example.com")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("cross-origin request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Location", w.Header().Get("Access-Control-Expose-Headers"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		router := NewRouter(handlers, nil, logger, &config.Config{}, metrics.NewNoOpRegistry(), nil, nil)
+		req := httptest.NewRequest(http.MethodOptions, "/shorten", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestNewRouter_APIVersions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+
+	expiresAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	body := func(alias string) string {
+		return fmt.Sprintf(`{"url": "https://example.com/%s", "customAlias": "%s", "tags": ["docs", "launch"], "expiresAt": %q, "redirectType": 302}`, alias, alias, expiresAt.Format(time.RFC3339))
+	}
+	shorten := func(router http.Handler, alias, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body(alias)))
+		req.Header.Set("Content-Type", "application/json")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	v1Fields := []string{"id", "shortUrl", "namespace", "shortCode", "originalUrl", "clicks", "uniqueClicks", "createdAt", "updatedAt", "protected", "redirectType"}
+	v2Only := []string{"tags", "healthStatus", "enabled", "goalReached"}
+
+	tests := []struct {
+		name            string
+		server          config.ServerConfig
+		accept          string
+		wantVersion     string
+		wantContentType string
+	}{
+		{name: "v1 by default", accept: "", wantVersion: APIVersion1, wantContentType: "application/json"},
+		{name: "plain json", accept: "application/json", wantVersion: APIVersion1, wantContentType: "application/json"},
+		{name: "v1 asked for", accept: "application/vnd.dove.v1+json", wantVersion: APIVersion1, wantContentType: "application/json"},
+		{name: "v2 asked for", accept: "application/vnd.dove.v2+json", wantVersion: APIVersion2, wantContentType: "application/vnd.dove.v2+json"},
+		{name: "v2 among other types", accept: "text/html;q=0.9, application/vnd.dove.v2+json; q=1", wantVersion: APIVersion2, wantContentType: "application/vnd.dove.v2+json"},
+		{name: "v2 by default", server: config.ServerConfig{DefaultAPIVersion: APIVersion2}, accept: "", wantVersion: APIVersion2, wantContentType: "application/vnd.dove.v2+json"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter(handlers, nil, logger, &config.Config{Server: tt.server}, metrics.NewNoOpRegistry(), nil, nil)
+			alias := fmt.Sprintf("version%d", i)
+
+			w := shorten(router, alias, tt.accept)
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Header().Values("Vary"), "Accept")
+
+			var fields map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fields))
+			for _, field := range v1Fields {
+				assert.Contains(t, fields, field)
+			}
+
+			stored, err := repo.FindByShortCode(context.Background(), alias)
+			require.NoError(t, err)
+
+			if tt.wantVersion == APIVersion1 {
+				for _, field := range v2Only {
+					assert.NotContains(t, fields, field, "version 1 responses keep their shape")
+				}
+				assert.Equal(t, float64(http.StatusMovedPermanently), fields["redirectType"])
+				assert.NotContains(t, fields, "expiresAt", "version 1 ignores the settings of version 2")
+				assert.Empty(t, stored.Tags)
+				assert.Nil(t, stored.ExpiresAt)
+				return
+			}
+
+			var response application.URLInfoResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, []string{"docs", "launch"}, response.Tags)
+			assert.Equal(t, http.StatusFound, response.RedirectType)
+			require.NotNil(t, response.ExpiresAt)
+			assert.True(t, expiresAt.Equal(*response.ExpiresAt))
+			assert.Equal(t, domain.HealthStatusUnknown, response.HealthStatus)
+			assert.True(t, response.Enabled)
+			assert.Equal(t, domain.Tags{"docs", "launch"}, stored.Tags)
+			assert.Equal(t, http.StatusFound, stored.RedirectType)
+		})
+	}
+
+	t.Run("unsupported versions", func(t *testing.T) {
+		router := NewRouter(handlers, nil, logger, &config.Config{Server: config.ServerConfig{SupportedVersions: []string{APIVersion1}}}, metrics.NewNoOpRegistry(), nil, nil)
+
+		for _, accept := range []string{"application/vnd.dove.v2+json", "application/vnd.dove.v3+json"} {
+			w := shorten(router, "unsupported", accept)
+			assert.Equal(t, http.StatusNotAcceptable, w.Code, accept)
+
+			var problem ProblemDetail
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, ProblemTypeNotAcceptable, problem.Type)
+		}
+		assert.Equal(t, http.StatusCreated, shorten(router, "supported", "application/vnd.dove.v1+json").Code)
+	})
+
+	t.Run("v2 validates its settings", func(t *testing.T) {
+		router := NewRouter(handlers, nil, logger, &config.Config{}, metrics.NewNoOpRegistry(), nil, nil)
+		send := func(payload string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(payload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/vnd.dove.v2+json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := send(`{"url": "https://example.com", "redirectType": 303}`)
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		var problem ValidationProblemDetail
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		require.Len(t, problem.Details, 1)
+		assert.Equal(t, "redirectType", problem.Details[0].Field)
+
+		w = send(`{"url": "https://example.com", "expiresAt": "2020-01-01T00:00:00Z"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+}
+
+func TestNewRouter_RateLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := NewHandlers(service, "http://localhost:8080", repo, HandlersDeps{})
+	router := NewRouter(handlers, nil, logger, &config.Config{}, metrics.NewNoOpRegistry(), nil, ratelimit.NewTokenBucketLimiter(1, 2, 100))
+
+	get := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, get("/robots.txt", "192.0.2.1:1234").Code)
+	assert.Equal(t, http.StatusOK, get("/robots.txt", "192.0.2.1:1235").Code)
+
+	w := get("/robots.txt", "192.0.2.1:1236")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var problem ProblemDetail
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, ProblemTypeRateLimited, problem.Type)
+
+	assert.Equal(t, http.StatusOK, get("/robots.txt", "192.0.2.2:1234").Code, "other clients are not limited")
+	assert.Equal(t, http.StatusOK, get("/health", "192.0.2.1:1237").Code, "health checks are never limited")
+
+	t.Run("proxy headers", func(t *testing.T) {
+		getVia := func(router chi.Router, forwardedFor string) int {
+			req := httptest.NewRequest(http.MethodGet, "/robots.txt", nil)
+			req.RemoteAddr = "192.0.2.3:1234"
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		untrusted := NewRouter(handlers, nil, logger, &config.Config{}, metrics.NewNoOpRegistry(), nil, ratelimit.NewTokenBucketLimiter(1, 2, 100))
+		assert.Equal(t, http.StatusOK, getVia(untrusted, "203.0.113.1"))
+		assert.Equal(t, http.StatusOK, getVia(untrusted, "203.0.113.2"))
+		assert.Equal(t, http.StatusTooManyRequests, getVia(untrusted, "203.0.113.3"), "a new X-Forwarded-For does not reset the bucket")
+
+		cfg := &config.Config{RateLimit: config.RateLimitConfig{TrustProxyHeaders: true}}
+		trusted := NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, ratelimit.NewTokenBucketLimiter(1, 2, 100))
+		assert.Equal(t, http.StatusOK, getVia(trusted, "203.0.113.1"))
+		assert.Equal(t, http.StatusOK, getVia(trusted, "203.0.113.1"))
+		assert.Equal(t, http.StatusTooManyRequests, getVia(trusted, "203.0.113.1"))
+		assert.Equal(t, http.StatusOK, getVia(trusted, "203.0.113.2"), "trusted proxy headers tell clients apart")
+	})
 }
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+const (
+	// maxImportBodyBytes bounds the request body accepted by the import endpoint
+	maxImportBodyBytes = 32 << 20
+	// maxImportLineBytes bounds a single NDJSON line
+	maxImportLineBytes = 64 << 10
+)
+
+// HandleImport creates short URLs in bulk from NDJSON or a JSON array.
+//
+//	@Summary		Import URLs in bulk
+//	@Description	Create up to 10000 short URLs in one request, either from a multipart `file` field holding one CreateURLRequest JSON object per line, or from a JSON array body. Entries that fail are listed in the report and do not stop the import. Only available when admin.api_key is set.
+//	@Tags			admin
+//	@Accept			multipart/form-data
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminAPIKey
+//	@Param			file	formData	file							false	"NDJSON file, one CreateURLRequest per line"
+//	@Param			request	body		[]application.CreateURLRequest	false	"URLs to import"
+//	@Success		200		{object}	application.ImportReport		"Import report"
+//	@Failure		400		{object}	ProblemDetail					"Malformed body or too many entries"
+//	@Failure		401		{object}	ProblemDetail					"Missing or invalid admin API key"
+//	@Failure		415		{object}	ProblemDetail					"Unsupported content type"
+//	@Failure		504		{object}	ProblemDetail					"Request exceeded its route timeout"
+//	@Router			/urls/import [post]
+func (h *Handlers) HandleImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	var entries []application.ImportEntry
+	switch mediaType {
+	case "multipart/form-data":
+		entries, err = readNDJSONImport(r)
+	case "application/json":
+		entries, err = readJSONImport(r.Body)
+	default:
+		respondWithProblem(w, r, http.StatusUnsupportedMediaType, ProblemTypeBadRequest, "Content-Type must be multipart/form-data or application/json")
+		return
+	}
+	if err != nil {
+		if errors.Is(err, application.ErrImportTooLarge) {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, err.Error())
+			return
+		}
+		logging.FromContext(r.Context()).Warn("Failed to read import", "error", err)
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid import: "+err.Error())
+		return
+	}
+
+	report, err := h.service.ImportURLs(r.Context(), entries, h.baseURL)
+	if err != nil {
+		if errors.Is(err, application.ErrImportTooLarge) {
+			respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, err.Error())
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to import URLs", "error", err)
+		respondWithInternalError(w, r, err, "Failed to import URLs")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, report)
+}
+
+// readNDJSONImport reads the multipart file field, one entry per non-blank line
+func readNDJSONImport(r *http.Request) ([]application.ImportEntry, error) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("missing file field: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 4096), maxImportLineBytes)
+
+	var entries []application.ImportEntry
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(entries) == application.MaxImportEntries {
+			return nil, application.ErrImportTooLarge
+		}
+		entries = append(entries, application.ImportEntry{Line: line, Data: bytes.Clone(data)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// readJSONImport reads a JSON array body; entries are numbered by their position in the array
+func readJSONImport(body io.Reader) ([]application.ImportEntry, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	if len(raw) > application.MaxImportEntries {
+		return nil, application.ErrImportTooLarge
+	}
+
+	entries := make([]application.ImportEntry, len(raw))
+	for i, data := range raw {
+		entries[i] = application.ImportEntry{Line: i + 1, Data: data}
+	}
+
+	return entries, nil
+}
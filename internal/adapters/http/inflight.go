@@ -0,0 +1,57 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// InFlightTracker counts the requests being served so that shutdown can wait for them,
+// including hijacked connections and handlers that outlive http.Server.Shutdown
+type InFlightTracker struct {
+	wg     sync.WaitGroup
+	active atomic.Int64
+}
+
+// NewInFlightTracker creates a tracker with no requests in flight
+func NewInFlightTracker() *InFlightTracker {
+	return &InFlightTracker{}
+}
+
+// Middleware tracks every request passing through it until its handler returns
+func (t *InFlightTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.wg.Add(1)
+		t.active.Add(1)
+		defer func() {
+			t.active.Add(-1)
+			t.wg.Done()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// InFlight reports how many requests are being served
+func (t *InFlightTracker) InFlight() int64 {
+	return t.active.Load()
+}
+
+// Wait blocks until every tracked request has finished or ctx is done. New requests must
+// no longer be accepted, as after http.Server.Shutdown.
+func (t *InFlightTracker) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d requests still in flight: %w", t.InFlight(), ctx.Err())
+	}
+}
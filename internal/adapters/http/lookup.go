@@ -0,0 +1,53 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// HandleBatchLookup handles lookups of several short URLs at once.
+//
+//	@Summary		Look up URLs in bulk
+//	@Description	Return the metadata of up to 50 short URLs of a namespace in one request, keyed by short code. Short codes matching no URL, or a signed or password protected one, are listed in notFound and do not fail the request.
+//	@Tags			urls
+//	@Accept			json
+//	@Produce		json
+//	@Param			request		body		application.BatchLookupRequest	true	"Short codes to look up"
+//	@Param			X-Namespace	header		string							false	"Namespace of the short codes"	default(default)
+//	@Success		200			{object}	application.BatchLookupResponse	"Batch lookup result"
+//	@Failure		400			{object}	ValidationProblemDetail			"Invalid request or validation error"
+//	@Router			/urls/batch-lookup [post]
+func (h *Handlers) HandleBatchLookup(w http.ResponseWriter, r *http.Request) {
+	namespace, err := namespaceFromRequest(r)
+	if err != nil {
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "X-Namespace must be a lowercase slug of letters, digits and hyphens")
+		return
+	}
+
+	var req application.BatchLookupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to decode request", "error", err)
+		respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid request body")
+		return
+	}
+
+	response, err := h.service.LookupURLs(r.Context(), namespace, req, h.baseURL)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			h.handleValidationError(w, r, validationErrors)
+			return
+		}
+		logging.FromContext(r.Context()).Error("Failed to look up URLs", "namespace", namespace, "error", err)
+		respondWithInternalError(w, r, err, "Failed to look up URLs")
+		return
+	}
+
+	respondWithJSON(w, r.Context(), http.StatusOK, response)
+}
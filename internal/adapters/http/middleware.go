@@ -1,7 +1,15 @@
 package http
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"time"
 
@@ -11,8 +19,12 @@ import (
 )
 
 // LoggingMiddleware creates HTTP middleware that injects request-scoped logger
-// This adapter bridges chi-specific middleware with our generic logging package
-func LoggingMiddleware(baseLogger *slog.Logger) func(http.Handler) http.Handler {
+// This adapter bridges chi-specific middleware with our generic logging package.
+// Below 1, sampleRate is the share of request logs under Warn that are kept.
+func LoggingMiddleware(baseLogger *slog.Logger, sampleRate float64) func(http.Handler) http.Handler {
+	if sampleRate < 1 {
+		baseLogger = slog.New(logging.NewSamplingHandler(baseLogger.Handler(), sampleRate))
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -21,6 +33,7 @@ func LoggingMiddleware(baseLogger *slog.Logger) func(http.Handler) http.Handler
 			if reqID := middleware.GetReqID(ctx); reqID != "" {
 				ctx = logging.WithRequestID(ctx, reqID)
 			}
+			ctx = logging.WithClientIP(ctx, clientIP(r))
 
 			traceID := r.Header.Get("X-Trace-Id")
 			if traceID == "" {
@@ -53,6 +66,85 @@ func LoggingMiddleware(baseLogger *slog.Logger) func(http.Handler) http.Handler
 	}
 }
 
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of signed request bodies
+	SignatureHeader = "X-Dove-Signature"
+	// maxSignedBodyBytes bounds the body buffered to check its signature
+	maxSignedBodyBytes = 1 << 20
+)
+
+// HMACVerificationMiddleware admits requests whose headerName header holds the hex encoded
+// HMAC-SHA256 of their body under secret, and answers every other request with a 401
+// problem. The body, at most maxSignedBodyBytes, is read whole and handed on unchanged.
+func HMACVerificationMiddleware(secret []byte, headerName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signature, err := hex.DecodeString(r.Header.Get(headerName))
+			if err != nil || len(signature) == 0 {
+				respondWithProblem(w, r, http.StatusUnauthorized, ProblemTypeUnauthorized, "A valid "+headerName+" signature is required")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBodyBytes))
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				respondWithProblem(w, r, http.StatusRequestEntityTooLarge, ProblemTypeBadRequest, "Request body too large")
+				return
+			}
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Failed to read request", "error", err)
+				respondWithProblem(w, r, http.StatusBadRequest, ProblemTypeBadRequest, "Invalid request body")
+				return
+			}
+			_ = r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(body)
+			if !hmac.Equal(signature, mac.Sum(nil)) {
+				logging.FromContext(r.Context()).Warn("Rejected request with an invalid signature", "path", r.URL.Path)
+				respondWithProblem(w, r, http.StatusUnauthorized, ProblemTypeUnauthorized, "A valid "+headerName+" signature is required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the caller address without the port.
+// middleware.RealIP runs earlier in the chain, so RemoteAddr already honours proxy headers.
+func clientIP(r *http.Request) string {
+	return hostOf(r.RemoteAddr)
+}
+
+type peerAddrKey struct{}
+
+// peerAddrMiddleware records the address of the TCP peer before middleware.RealIP
+// replaces RemoteAddr with what the client claims in its proxy headers
+func peerAddrMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), peerAddrKey{}, r.RemoteAddr)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// peerIP returns the TCP peer address without the port, ignoring proxy headers. It falls
+// back to RemoteAddr when peerAddrMiddleware did not run.
+func peerIP(r *http.Request) string {
+	if addr, ok := r.Context().Value(peerAddrKey{}).(string); ok {
+		return hostOf(addr)
+	}
+	return clientIP(r)
+}
+
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -63,3 +155,8 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
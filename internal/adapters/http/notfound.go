@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// notFoundPageTimeout bounds the fetch of a remote not found page
+	notFoundPageTimeout = 10 * time.Second
+	// maxNotFoundPageSize is the largest not found page loaded, in bytes
+	maxNotFoundPageSize = 1 << 20
+)
+
+// NotFoundPage holds the HTML page served by redirects to short codes that are not found.
+// The page is read from a file, or fetched from a remote URL, and kept in memory.
+type NotFoundPage struct {
+	filePath  string
+	remoteURL string
+	client    *http.Client
+	logger    *slog.Logger
+	html      atomic.Pointer[[]byte]
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewNotFoundPage creates a not found page read from filePath, or fetched from remoteURL
+// when filePath is empty. It serves nothing until Load succeeds.
+func NewNotFoundPage(filePath, remoteURL string, logger *slog.Logger) *NotFoundPage {
+	return &NotFoundPage{
+		filePath:  filePath,
+		remoteURL: remoteURL,
+		client:    &http.Client{Timeout: notFoundPageTimeout},
+		logger:    logger,
+	}
+}
+
+// Load reads the page again, keeping the previous one when it fails
+func (p *NotFoundPage) Load(ctx context.Context) error {
+	var page []byte
+	var err error
+	if p.filePath != "" {
+		page, err = p.readFile()
+	} else {
+		page, err = p.fetch(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	p.html.Store(&page)
+	return nil
+}
+
+func (p *NotFoundPage) readFile() ([]byte, error) {
+	file, err := os.Open(p.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open not found page: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	page, err := io.ReadAll(io.LimitReader(file, maxNotFoundPageSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read not found page: %w", err)
+	}
+	return page, nil
+}
+
+func (p *NotFoundPage) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.remoteURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create not found page request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch not found page: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch not found page: status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return nil, fmt.Errorf("not found page is not HTML: %q", resp.Header.Get("Content-Type"))
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxNotFoundPageSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read not found page: %w", err)
+	}
+	return page, nil
+}
+
+// HTML returns the page last loaded, nil before the first load
+func (p *NotFoundPage) HTML() []byte {
+	if page := p.html.Load(); page != nil {
+		return *page
+	}
+	return nil
+}
+
+// StartReloading loads the page again every interval in the background. Failed loads are
+// logged and the previous page is kept.
+func (p *NotFoundPage) StartReloading(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := p.Load(ctx); err != nil && ctx.Err() == nil {
+				p.logger.Error("Failed to reload not found page", "error", err)
+			}
+		}
+	}()
+}
+
+// Stop ends the reloading and waits for a load in progress or ctx to expire
+func (p *NotFoundPage) Stop(ctx context.Context) error {
+	if p.cancel == nil {
+		return nil
+	}
+	p.cancel()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// respondWithNotFound answers a redirect to an unknown short code with the not found page,
+// or with the JSON problem when there is no page or the client asks for JSON
+func (h *Handlers) respondWithNotFound(w http.ResponseWriter, r *http.Request) {
+	var page []byte
+	if h.notFoundPage != nil {
+		page = h.notFoundPage.HTML()
+	}
+	if page == nil || acceptsJSON(r) {
+		respondWithURLNotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(http.StatusNotFound)
+	_, _ = w.Write(page)
+}
+
+// acceptsJSON reports whether the Accept header of r names a JSON media type
+func acceptsJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return true
+		}
+	}
+	return false
+}
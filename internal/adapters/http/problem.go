@@ -0,0 +1,120 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/jsonschema"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// problemContentType is the media type defined by RFC 7807 for problem details
+const problemContentType = "application/problem+json"
+
+// problemTypeBase is the URI namespace for dove error types
+const problemTypeBase = "https://dove.example/errors/"
+
+// Problem types returned by the API
+const (
+	ProblemTypeBadRequest    = problemTypeBase + "bad-request"
+	ProblemTypeValidation    = problemTypeBase + "validation"
+	ProblemTypeSchema        = problemTypeBase + "schema-violation"
+	ProblemTypeUnauthorized  = problemTypeBase + "unauthorized"
+	ProblemTypeNotFound      = problemTypeBase + "not-found"
+	ProblemTypeConflict      = problemTypeBase + "conflict"
+	ProblemTypeUnavailable   = problemTypeBase + "service-unavailable"
+	ProblemTypeTimeout       = problemTypeBase + "timeout"
+	ProblemTypeLoopDetected  = problemTypeBase + "loop-detected"
+	ProblemTypeDisabled      = problemTypeBase + "disabled"
+	ProblemTypeReservedAlias = problemTypeBase + "reserved-alias"
+	ProblemTypeRejected      = problemTypeBase + "rejected"
+	ProblemTypeNotAcceptable = problemTypeBase + "not-acceptable"
+	ProblemTypeRateLimited   = problemTypeBase + "rate-limited"
+	ProblemTypeInternal      = problemTypeBase + "internal"
+)
+
+// ProblemDetail represents an RFC 7807 problem details response.
+type ProblemDetail struct {
+	Type     string `json:"type" example:"https://dove.example/errors/not-found"`
+	Title    string `json:"title" example:"Not Found"`
+	Status   int    `json:"status" example:"404"`
+	Detail   string `json:"detail,omitempty" example:"Short URL not found"`
+	Instance string `json:"instance,omitempty" example:"/abc123"`
+}
+
+// Validation error codes, stable identifiers of the rule a field broke
+const (
+	ValidationCodeRequired          = "REQUIRED"
+	ValidationCodeInvalidURLFormat  = "INVALID_URL_FORMAT"
+	ValidationCodeUnsafeURLScheme   = "UNSAFE_URL_SCHEME"
+	ValidationCodePrivateIPURL      = "PRIVATE_IP_URL"
+	ValidationCodeAliasTooShort     = "ALIAS_TOO_SHORT"
+	ValidationCodeAliasTooLong      = "ALIAS_TOO_LONG"
+	ValidationCodeAliasInvalidChars = "ALIAS_INVALID_CHARS"
+	ValidationCodeAliasReserved     = "ALIAS_RESERVED"
+	ValidationCodeConflictingField  = "CONFLICTING_FIELD"
+	ValidationCodeInvalidChoice     = "INVALID_CHOICE"
+	ValidationCodeInvalidCountry    = "INVALID_COUNTRY_CODE"
+	ValidationCodeDuplicateEntries  = "DUPLICATE_ENTRIES"
+	ValidationCodeInvalidDuration   = "INVALID_DURATION"
+	ValidationCodeInvalidChars      = "INVALID_CHARS"
+	ValidationCodeInvalidNamespace  = "INVALID_NAMESPACE"
+	ValidationCodeTooSmall          = "TOO_SMALL"
+	ValidationCodeTooLarge          = "TOO_LARGE"
+	ValidationCodeInvalid           = "INVALID"
+)
+
+// ValidationFieldError describes one field that failed validation
+type ValidationFieldError struct {
+	Field   string `json:"field" example:"url"`
+	Message string `json:"message" example:"url must be a valid URL"`
+	Code    string `json:"code" example:"INVALID_URL_FORMAT"`
+}
+
+// ValidationProblemDetail is a problem details response carrying per-field validation errors.
+type ValidationProblemDetail struct {
+	ProblemDetail
+	Details []ValidationFieldError `json:"details"`
+}
+
+// SchemaProblemDetail is a problem details response carrying the JSON Schema rules a
+// request body broke.
+type SchemaProblemDetail struct {
+	ProblemDetail
+	JSONSchemaErrors []jsonschema.Error `json:"jsonSchemaErrors"`
+}
+
+// newProblem builds a problem for the current request, using the status text as title
+func newProblem(r *http.Request, status int, problemType, detail string) ProblemDetail {
+	return ProblemDetail{
+		Type:     problemType,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+// respondWithInternalError reports an unexpected failure, as a 504 when a database query
+// ran out of time
+func respondWithInternalError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	if errors.Is(err, domain.ErrQueryTimeout) {
+		respondWithProblem(w, r, http.StatusGatewayTimeout, ProblemTypeTimeout, "Database query timed out")
+		return
+	}
+	respondWithProblem(w, r, http.StatusInternalServerError, ProblemTypeInternal, detail)
+}
+
+func respondWithProblem(w http.ResponseWriter, r *http.Request, status int, problemType, detail string) {
+	writeProblem(w, r, status, newProblem(r, status, problemType, detail))
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, problem interface{}) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to encode problem response", "error", err)
+	}
+}
@@ -1,48 +1,246 @@
 package http
 
 import (
+	"html"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	httpswagger "github.com/swaggo/http-swagger"
 
 	"github.com/sp3dr4/dove/config"
+	"github.com/sp3dr4/dove/internal/pkg/geoip"
 	"github.com/sp3dr4/dove/internal/pkg/metrics"
+	"github.com/sp3dr4/dove/internal/pkg/ratelimit"
 )
 
-func NewRouter(handlers *Handlers, logger *slog.Logger, cfg *config.Config, metricsRegistry metrics.Registry) chi.Router {
+// NewRouter wires every route. inFlight, when set, tracks each request so that shutdown
+// can drain them, and limiter, when set, answers clients over their rate limit with 429.
+func NewRouter(handlers *Handlers, migrationHandlers *MigrationHandlers, logger *slog.Logger, cfg *config.Config, metricsRegistry metrics.Registry, inFlight *InFlightTracker, limiter ratelimit.Limiter) chi.Router {
 	r := chi.NewRouter()
 
+	// First, so that shutdown waits for the whole middleware chain
+	if inFlight != nil {
+		r.Use(inFlight.Middleware)
+	}
 	r.Use(middleware.RequestID)
+	r.Use(peerAddrMiddleware)
 	r.Use(middleware.RealIP)
-	r.Use(LoggingMiddleware(logger))
+	r.Use(LoggingMiddleware(logger, cfg.Logging.SampleRate))
 	r.Use(metrics.PrometheusMiddleware(metricsRegistry))
+	if limiter != nil {
+		r.Use(ratelimit.Middleware(limiter, rateLimitKey(cfg), http.HandlerFunc(handleRateLimited)))
+	}
 	r.Use(middleware.Recoverer)
+	if cfg.Server.Compression.Enabled {
+		// Validation at load guarantees the settings are accepted
+		if compression, err := CompressionMiddleware(cfg.Server.Compression); err != nil {
+			logger.Error("Response compression disabled", "error", err)
+		} else {
+			r.Use(compression)
+		}
+	}
+	if cfg.Geo.CountryHeader != "" {
+		r.Use(geoip.Middleware(geoip.HeaderLocator(cfg.Geo.CountryHeader)))
+	}
+	if cfg.Server.CORS.Enabled {
+		r.Use(CORSMiddleware(cfg.Server.CORS))
+	}
+	r.Use(VersionMiddleware(cfg.Server.DefaultAPIVersion, cfg.Server.SupportedVersions))
 
 	r.Get("/health", handlers.HandleHealth)
 	r.Get("/ready", handlers.HandleReady)
 
-	if cfg.Metrics.Enabled {
-		r.Handle(cfg.Metrics.Path, metricsRegistry.GetHandler())
+	// Push based backends have no scrape endpoint
+	if handler := metricsRegistry.GetHandler(); cfg.Metrics.Enabled && handler != nil {
+		r.Handle(cfg.Metrics.Path, handler)
 	}
 
+	specURL := strings.TrimSuffix(withScheme(cfg.App.BaseURL, cfg.App.ShortURLScheme), "/") + "/swagger/doc.json"
 	r.Get("/swagger/*", httpswagger.Handler(
-		httpswagger.URL("http://localhost:8080/swagger/doc.json"),
+		httpswagger.URL(specURL),
 	))
-	r.Get("/redoc", handleRedoc)
+	r.Get("/redoc", handleRedoc(specURL))
+	r.Get("/robots.txt", handleRobots(robotsBody(cfg.App)))
+
+	shorten := withTimeout(r, cfg, "shorten")
+	if cfg.Security.HMACEnabled {
+		shorten = shorten.With(HMACVerificationMiddleware([]byte(cfg.Security.HMACSecret), SignatureHeader))
+	}
+	shorten.Post("/shorten", handlers.HandleShorten)
+	if cfg.App.SuggestEnabled {
+		r.Get("/shorten/suggest", handlers.HandleSuggestAliases)
+	}
+	r.Get("/shorten/{shortCode}", handlers.HandleURLInfo)
+	r.Get("/shorten/{shortCode}/preview", handlers.HandlePreview)
+	r.Get("/shorten/{shortCode}/events", handlers.HandleClickEvents)
+	r.Get("/shorten/{shortCode}/analytics/timeseries", handlers.HandleClickTimeSeries)
+	r.Get("/shorten/{shortCode}/analytics/heatmap", handlers.HandleClickHeatmap)
+	r.Get("/shorten/{shortCode}/analytics/referrers", handlers.HandleTopReferrers)
+	r.Get("/shorten/{shortCode}/analytics/devices", handlers.HandleDeviceStats)
+	r.Get("/shorten/{shortCode}/analytics/browsers", handlers.HandleBrowserStats)
+	r.Get("/shorten/{shortCode}/analytics/os", handlers.HandleOSStats)
+	r.Get("/shorten/{shortCode}/analytics/variants", handlers.HandleVariantStats)
+	r.Get("/shorten/{shortCode}/analytics/latency", handlers.HandleRedirectLatency)
+	r.Get("/shorten/{shortCode}/health", handlers.HandleURLHealth)
 
-	r.Post("/shorten", handlers.HandleShorten)
+	r.Get("/urls/top", handlers.HandleTopURLs)
+	r.Post("/urls/batch-lookup", handlers.HandleBatchLookup)
+	if cfg.Admin.APIKey != "" {
+		r.Group(func(admin chi.Router) {
+			admin.Use(AdminAuthMiddleware(cfg.Admin.APIKey))
+			admin.Patch("/shorten/{shortCode}", handlers.HandlePatchURL)
+			admin.Delete("/urls/bulk", handlers.HandleBulkDelete)
+			withTimeout(admin, cfg, "import").Post("/urls/import", handlers.HandleImport)
+			// Exports hold every destination, and the IP address and user agent of every visitor
+			if cfg.Admin.ExportEnabled {
+				admin.Get("/urls/export", handlers.HandleExport)
+				admin.Get("/shorten/{shortCode}/clicks/export", handlers.HandleClickExport)
+			}
+			admin.Get("/admin/stats", handlers.HandleStats)
+			admin.Get("/admin/stats/aliases", handlers.HandleAliasStats)
+			admin.Get("/admin/stats/daily", handlers.HandleDailyStats)
+			admin.Post("/shorten/{shortCode}/analytics/reset", handlers.HandleResetAnalytics)
+			admin.Get("/admin/urls", handlers.HandleListURLs)
+			admin.Get("/admin/urls/cold", handlers.HandleColdURLs)
+			admin.Get("/admin/analytics/top-ips", handlers.HandleTopIPs)
+			admin.Patch("/admin/urls/{shortCode}/disable", handlers.HandleDisable)
+			admin.Patch("/admin/urls/{shortCode}/enable", handlers.HandleEnable)
+			if handlers.service.AuditLogEnabled() {
+				admin.Get("/admin/urls/{shortCode}/audit-log", handlers.HandleAuditLog)
+			}
+			if handlers.service.FunnelsEnabled() {
+				admin.Post("/admin/funnels", handlers.HandleCreateFunnel)
+				admin.Get("/admin/funnels/{id}/analytics", handlers.HandleFunnelAnalytics)
+			}
+			// Only the Redis cache has prefixed keys
+			if handlers.keyMigrator != nil {
+				admin.Post("/admin/cache/migrate-keys", handlers.HandleMigrateCacheKeys)
+			}
+			if cfg.Admin.ExposeCache && handlers.cache != nil {
+				admin.Get("/admin/cache/keys", handlers.HandleCacheKeys)
+			}
+			// The in-memory repository has no schema
+			if migrationHandlers != nil && migrationHandlers.migrations != nil {
+				admin.Get("/admin/migrations", migrationHandlers.HandleListMigrations)
+				admin.Post("/admin/migrations/up", migrationHandlers.HandleMigrateUp)
+				admin.Post("/admin/migrations/down", migrationHandlers.HandleMigrateDown)
+			}
+			if migrationHandlers != nil && migrationHandlers.stores != nil {
+				admin.Post("/admin/migrate-store", migrationHandlers.HandleMigrateStore)
+			}
+		})
+	}
+
+	redirects := withTimeout(r, cfg, "redirect").With(noIndexMiddleware, metrics.RedirectMiddleware(metricsRegistry))
+	redirects.Get("/{shortCode}", handlers.HandleRedirect)
+	redirects.Head("/{shortCode}", handlers.HandleRedirect)
+	redirects.Get("/{namespace}/{shortCode}", handlers.HandleNamespacedRedirect)
+	redirects.Head("/{namespace}/{shortCode}", handlers.HandleNamespacedRedirect)
 
-	r.Get("/{shortCode}", handlers.HandleRedirect)
-	r.Head("/{shortCode}", handlers.HandleRedirect)
+	// Preflights for every route, answered from the routes registered above
+	if cfg.Server.CORS.Enabled {
+		r.Options("/*", CORSPreflightHandler(r, cfg.Server.CORS))
+	}
 
 	return r
 }
 
-func handleRedoc(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+// RouteWords returns the first static path segment of every route of router, the words a
+// short URL must not be claimed under since it would shadow the route
+func RouteWords(router chi.Routes) []string {
+	seen := make(map[string]bool)
+	var words []string
+	_ = chi.Walk(router, func(_, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if segment == "" || strings.ContainsAny(segment, "{*") || seen[segment] {
+			return nil
+		}
+		seen[segment] = true
+		words = append(words, segment)
+		return nil
+	})
+	return words
+}
+
+// withTimeout returns r with the timeout configured for route applied, or r itself when the
+// route has none. Validation at load guarantees configured values parse.
+func withTimeout(r chi.Router, cfg *config.Config, route string) chi.Router {
+	timeout, err := time.ParseDuration(cfg.Server.RouteTimeouts[route])
+	if err != nil || timeout <= 0 {
+		return r
+	}
+	return r.With(TimeoutMiddleware(timeout))
+}
+
+// rateLimitKey tells clients apart by IP. Health, readiness and metrics requests come from
+// probes and scrapers polling on purpose, so they are never limited. Unless proxy headers
+// are trusted, the IP is the TCP peer's, so that clients cannot pick a fresh key per request.
+func rateLimitKey(cfg *config.Config) func(*http.Request) string {
+	return func(r *http.Request) string {
+		switch r.URL.Path {
+		case "/health", "/ready":
+			return ""
+		case cfg.Metrics.Path:
+			if cfg.Metrics.Enabled {
+				return ""
+			}
+		}
+		if cfg.RateLimit.TrustProxyHeaders {
+			return clientIP(r)
+		}
+		return peerIP(r)
+	}
+}
+
+func handleRateLimited(w http.ResponseWriter, r *http.Request) {
+	respondWithProblem(w, r, http.StatusTooManyRequests, ProblemTypeRateLimited, "Too many requests, slow down")
+}
+
+// robotsDocsOnly is the default robots.txt: the API docs may be crawled, short URLs may not
+const robotsDocsOnly = `User-agent: *
+Allow: /swagger/
+Allow: /redoc$
+Disallow: /
+`
+
+// robotsDisallowAll keeps crawlers off every path
+const robotsDisallowAll = `User-agent: *
+Disallow: /
+`
+
+// robotsBody returns the robots.txt configured in cfg
+func robotsBody(cfg config.AppConfig) string {
+	switch {
+	case cfg.RobotsCustom != "":
+		return cfg.RobotsCustom
+	case cfg.RobotsDisallowAll:
+		return robotsDisallowAll
+	default:
+		return robotsDocsOnly
+	}
+}
+
+func handleRobots(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+// noIndexMiddleware asks crawlers that ignore robots.txt not to index or follow redirects
+func noIndexMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleRedoc serves the Redoc page rendering the OpenAPI document at specURL
+func handleRedoc(specURL string) http.HandlerFunc {
 	redocHTML := `<!DOCTYPE html>
 <html>
 <head>
@@ -58,9 +256,12 @@ func handleRedoc(w http.ResponseWriter, _ *http.Request) {
     </style>
 </head>
 <body>
-    <redoc spec-url='/swagger/doc.json'></redoc>
+    <redoc spec-url='` + html.EscapeString(specURL) + `'></redoc>
     <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
 </body>
 </html>`
-	_, _ = w.Write([]byte(redocHTML))
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(redocHTML))
+	}
 }
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// TimeoutMiddleware gives each request a context deadline of d. Handlers are expected to
+// give up once ctx is done; a response finished after the deadline is discarded and the
+// client gets a 504 problem instead. The response is buffered until the handler returns,
+// which rules the middleware out for streaming endpoints.
+func TimeoutMiddleware(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			next.ServeHTTP(tw, r)
+
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				tw.flushTo(w)
+				return
+			}
+
+			logging.FromContext(ctx).Warn("Request timed out", "method", r.Method, "path", r.URL.Path, "timeout", d)
+			respondWithProblem(w, r, http.StatusGatewayTimeout, ProblemTypeTimeout, fmt.Sprintf("Request did not complete within %s", d))
+		})
+	}
+}
+
+// timeoutWriter holds a handler's response until it is known to have finished in time
+type timeoutWriter struct {
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	if tw.wroteHeader {
+		return
+	}
+	tw.status = status
+	tw.wroteHeader = true
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.status = http.StatusOK
+		tw.wroteHeader = true
+	}
+	return tw.body.Write(p)
+}
+
+func (tw *timeoutWriter) flushTo(w http.ResponseWriter) {
+	for key, values := range tw.header {
+		w.Header()[key] = values
+	}
+	if tw.wroteHeader {
+		w.WriteHeader(tw.status)
+	}
+	_, _ = w.Write(tw.body.Bytes())
+}
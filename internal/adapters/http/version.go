@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// API versions clients can ask for
+const (
+	APIVersion1 = "v1"
+	APIVersion2 = "v2"
+)
+
+// vendorMediaTypePrefix starts the media types naming an API version, such as
+// application/vnd.dove.v2+json
+const vendorMediaTypePrefix = "application/vnd.dove."
+
+type apiVersionKey struct{}
+
+// WithAPIVersion returns a copy of ctx carrying the API version of the request
+func WithAPIVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, apiVersionKey{}, version)
+}
+
+// APIVersionFromContext returns the version stored by VersionMiddleware, version 1 when
+// there is none
+func APIVersionFromContext(ctx context.Context) string {
+	if version, ok := ctx.Value(apiVersionKey{}).(string); ok {
+		return version
+	}
+	return APIVersion1
+}
+
+// VersionMiddleware stores in the request context the API version named by the Accept
+// header, or defaultVersion when it names none. Requests asking for a version outside
+// supported get a 406 problem. An empty defaultVersion means version 1, and an empty
+// supported every known version.
+func VersionMiddleware(defaultVersion string, supported []string) func(http.Handler) http.Handler {
+	if defaultVersion == "" {
+		defaultVersion = APIVersion1
+	}
+	if len(supported) == 0 {
+		supported = []string{APIVersion1, APIVersion2}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Responses differ by the version asked for
+			w.Header().Add("Vary", "Accept")
+
+			version, named := requestedAPIVersion(r.Header.Get("Accept"))
+			if !named {
+				version = defaultVersion
+			} else if !slices.Contains(supported, version) {
+				respondWithProblem(w, r, http.StatusNotAcceptable, ProblemTypeNotAcceptable,
+					"API version "+version+" is not supported, use one of: "+strings.Join(supported, ", "))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithAPIVersion(r.Context(), version)))
+		})
+	}
+}
+
+// requestedAPIVersion returns the version of the first vendor media type of accept, and
+// whether there was one
+func requestedAPIVersion(accept string) (string, bool) {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(mediaType, vendorMediaTypePrefix); ok {
+			version, _, _ := strings.Cut(rest, "+")
+			return version, true
+		}
+	}
+	return "", false
+}
+
+// vendorMediaType is the Content-Type of responses shaped by version
+func vendorMediaType(version string) string {
+	return vendorMediaTypePrefix + version + "+json"
+}
@@ -0,0 +1,94 @@
+package application
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/shortcode"
+)
+
+// DefaultMaxAliasLength is the longest custom alias when the policy sets no limit, and the
+// most the short_code columns hold
+const DefaultMaxAliasLength = 20
+
+// Custom alias policy violations, as reported by AliasPolicy.Check
+var (
+	ErrAliasTooLong      = errors.New("alias too long")
+	ErrAliasInvalidChars = errors.New("alias has invalid characters")
+	ErrAliasReserved     = errors.New("alias is reserved")
+)
+
+// AliasPolicy shapes the custom aliases URLs may be created with, on top of the minimum of
+// three characters. The zero value allows letters and digits up to DefaultMaxAliasLength.
+type AliasPolicy struct {
+	MaxLength        int // DefaultMaxAliasLength when 0
+	AllowHyphens     bool
+	AllowUnderscores bool
+	// ReservedWords are refused, ignoring case, along with the aliases reserved by the service
+	ReservedWords []string
+}
+
+// MaxAliasLength is the longest alias the policy allows
+func (p AliasPolicy) MaxAliasLength() int {
+	if p.MaxLength <= 0 {
+		return DefaultMaxAliasLength
+	}
+	return p.MaxLength
+}
+
+// AllowedCharacters describes the characters the policy allows, for error messages
+func (p AliasPolicy) AllowedCharacters() string {
+	switch {
+	case p.AllowHyphens && p.AllowUnderscores:
+		return "letters, digits, hyphens and underscores"
+	case p.AllowHyphens:
+		return "letters, digits and hyphens"
+	case p.AllowUnderscores:
+		return "letters, digits and underscores"
+	default:
+		return "letters and digits"
+	}
+}
+
+// Check returns the first rule of the policy alias breaks, or nil
+func (p AliasPolicy) Check(alias string) error {
+	for _, r := range alias {
+		if !p.allows(r) {
+			return ErrAliasInvalidChars
+		}
+	}
+	if utf8.RuneCountInString(alias) > p.MaxAliasLength() {
+		return ErrAliasTooLong
+	}
+
+	// Aliases must never read as signed tokens, which underscores would otherwise permit
+	if domain.IsReservedAlias(alias) || shortcode.IsSigned(alias) {
+		return ErrAliasReserved
+	}
+	for _, word := range p.ReservedWords {
+		if strings.EqualFold(alias, word) {
+			return ErrAliasReserved
+		}
+	}
+	return nil
+}
+
+func (p AliasPolicy) allows(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r == '-':
+		return p.AllowHyphens
+	case r == '_':
+		return p.AllowUnderscores
+	default:
+		return false
+	}
+}
+
+// AliasPolicy returns the policy custom aliases are checked against
+func (s *URLService) AliasPolicy() AliasPolicy {
+	return s.aliases
+}
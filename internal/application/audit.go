@@ -0,0 +1,60 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// Default and maximum number of entries returned by GetAuditLog
+const (
+	DefaultAuditLogLimit = 50
+	MaxAuditLogLimit     = 500
+)
+
+// AuditLogEnabled reports whether the change history of URLs is kept
+func (s *URLService) AuditLogEnabled() bool {
+	return s.auditLog != nil
+}
+
+// GetAuditLog returns at most limit changes of the URL under namespace and shortCode, most
+// recent first. The history outlives the URL, so a deleted URL still has one.
+func (s *URLService) GetAuditLog(ctx context.Context, namespace, shortCode string, limit int) ([]domain.AuditEntry, error) {
+	return s.auditLog.ListByShortCode(ctx, namespace, shortCode, limit)
+}
+
+// newAuditEntry describes operation turning before into after, either of which is nil
+// when the URL did not exist. The actor and request come from ctx.
+func newAuditEntry(ctx context.Context, operation string, before, after *domain.URL) domain.AuditEntry {
+	url := after
+	if url == nil {
+		url = before
+	}
+	return domain.AuditEntry{
+		Timestamp: time.Now().UTC(),
+		Operation: operation,
+		Namespace: url.Namespace,
+		ShortCode: url.ShortCode,
+		ActorIP:   logging.ClientIPFromContext(ctx),
+		RequestID: logging.RequestIDFromContext(ctx),
+		OldValue:  auditValue(before),
+		NewValue:  auditValue(after),
+	}
+}
+
+// auditValue is url as kept in the history, without its password hash
+func auditValue(url *domain.URL) json.RawMessage {
+	if url == nil {
+		return nil
+	}
+	redacted := *url
+	redacted.PasswordHash = ""
+	value, err := json.Marshal(redacted)
+	if err != nil {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,100 @@
+package application
+
+import (
+	"context"
+	"errors"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// RedirectChains configures the following of destinations that are short URLs of this
+// service, saving clients a round trip per hop
+type RedirectChains struct {
+	BaseURLs []string // base URLs the service is reachable at
+	MaxDepth int      // most hops followed for a redirect
+}
+
+// shortCode extracts the namespace and short code of destination when it is a short URL
+// under one of the base URLs
+func (c *RedirectChains) shortCode(destination string) (namespace, shortCode string, ok bool) {
+	target, err := neturl.Parse(destination)
+	if err != nil || target.Host == "" {
+		return "", "", false
+	}
+
+	for _, baseURL := range c.BaseURLs {
+		base, err := neturl.Parse(baseURL)
+		if err != nil || !strings.EqualFold(base.Host, target.Host) {
+			continue
+		}
+		path, ok := strings.CutPrefix(target.Path, strings.TrimSuffix(base.Path, "/")+"/")
+		if !ok {
+			continue
+		}
+
+		segments := strings.Split(path, "/")
+		switch {
+		case len(segments) == 1 && segments[0] != "":
+			return domain.DefaultNamespace, segments[0], true
+		case len(segments) == 2 && segments[0] != "" && segments[1] != "":
+			return segments[0], segments[1], true
+		}
+	}
+	return "", "", false
+}
+
+// FollowRedirectChain resolves destination, the redirect target chosen for url, through
+// the short URLs of this service it points at and returns the first destination outside
+// of them. Hops count no clicks. Following stops early at hops that are missing, out of
+// hops, or would redirect differently per request, such as password protected, delayed
+// or routed URLs, leaving the client to follow the rest. A short code met twice fails with
+// domain.ErrRedirectLoop.
+func (s *URLService) FollowRedirectChain(ctx context.Context, url *domain.URL, destination string) (string, error) {
+	if s.chains == nil {
+		return destination, nil
+	}
+
+	seen := map[string]bool{url.Namespace + "/" + url.ShortCode: true}
+	for hop := 0; hop < s.chains.MaxDepth; hop++ {
+		namespace, shortCode, ok := s.chains.shortCode(destination)
+		if !ok {
+			return destination, nil
+		}
+
+		next, err := s.ResolveURL(ctx, namespace, shortCode)
+		if err != nil {
+			if !errors.Is(err, domain.ErrURLNotFound) {
+				s.logger.Warn("Failed to follow redirect chain", "namespace", namespace, "short_code", shortCode, "error", err)
+			}
+			return destination, nil
+		}
+
+		key := next.Namespace + "/" + next.ShortCode
+		if seen[key] {
+			return "", domain.ErrRedirectLoop
+		}
+		seen[key] = true
+
+		if !isChainHop(next) {
+			return destination, nil
+		}
+		destination = next.OriginalURL
+	}
+	return destination, nil
+}
+
+// isChainHop reports whether url always redirects to its OriginalURL, so the redirect can
+// be skipped
+func isChainHop(url *domain.URL) bool {
+	return url.Enabled &&
+		!url.IsPasswordProtected() &&
+		!url.IsExpired(time.Now()) &&
+		url.DelaySeconds == 0 &&
+		!url.PoolEnabled &&
+		len(url.Variants) == 0 &&
+		len(url.GeoRoutes) == 0 &&
+		len(url.DeviceRoutes) == 0
+}
@@ -0,0 +1,89 @@
+package application
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+)
+
+// BulkDeleteRequest lists the short codes of one namespace to delete, at most 500
+type BulkDeleteRequest struct {
+	ShortCodes []string `json:"shortCodes" validate:"required,min=1,max=500,dive,required" example:"abc123,promo"`
+}
+
+// BulkDeleteResult reports a bulk delete; short codes matching no URL are listed, not failed
+type BulkDeleteResult struct {
+	Deleted  int      `json:"deleted" example:"1"`
+	NotFound []string `json:"notFound" example:"promo"`
+}
+
+// BulkDeleteURLs deletes the URLs under namespace with any of the requested short codes in a
+// single repository call, then drops each of them from the cache
+func (s *URLService) BulkDeleteURLs(ctx context.Context, namespace string, req BulkDeleteRequest) (*BulkDeleteResult, error) {
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.repo.DeleteMany(ctx, namespace, req.ShortCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(deleted))
+	for _, url := range deleted {
+		found[url.ShortCode] = true
+		if err := s.cache.Delete(ctx, namespace, url.ShortCode); err != nil {
+			s.logger.Warn("Failed to invalidate cache after deleting URL", "namespace", namespace, "short_code", url.ShortCode, "error", err)
+		}
+		if s.dedup != nil {
+			if err := s.dedup.Forget(ctx, namespace, url.ShortCode); err != nil {
+				s.logger.Warn("Failed to forget click deduplication of deleted URL", "namespace", namespace, "short_code", url.ShortCode, "error", err)
+			}
+		}
+		s.audit(ctx, audit.OperationDelete, url, nil)
+	}
+	if len(deleted) > 0 {
+		if err := s.cache.InvalidateTopURLs(ctx); err != nil {
+			s.logger.Warn("Failed to invalidate top URLs cache", "error", err)
+		}
+	}
+
+	result := &BulkDeleteResult{Deleted: len(deleted), NotFound: []string{}}
+	for _, shortCode := range req.ShortCodes {
+		if !found[shortCode] {
+			result.NotFound = append(result.NotFound, shortCode)
+			// A code requested twice is reported once
+			found[shortCode] = true
+		}
+	}
+
+	s.logger.Info("Bulk delete finished", "namespace", namespace, "deleted", result.Deleted, "not_found", len(result.NotFound))
+	return result, nil
+}
+
+// DeleteExpiredURL deletes url, found expired by the cleanup, drops it from the cache and
+// records the deletion in the audit log. A URL deleted meanwhile fails with
+// domain.ErrURLNotFound, its cache entry still dropped.
+func (s *URLService) DeleteExpiredURL(ctx context.Context, url *domain.URL) error {
+	deleteErr := s.repo.Delete(ctx, url.Namespace, url.ShortCode)
+	if deleteErr != nil && !errors.Is(deleteErr, domain.ErrURLNotFound) {
+		return deleteErr
+	}
+
+	if err := s.cache.Delete(ctx, url.Namespace, url.ShortCode); err != nil {
+		s.logger.Warn("Failed to invalidate cache after deleting expired URL", "namespace", url.Namespace, "short_code", url.ShortCode, "error", err)
+	}
+	if deleteErr != nil {
+		return deleteErr
+	}
+
+	if s.dedup != nil {
+		if err := s.dedup.Forget(ctx, url.Namespace, url.ShortCode); err != nil {
+			s.logger.Warn("Failed to forget click deduplication of deleted URL", "namespace", url.Namespace, "short_code", url.ShortCode, "error", err)
+		}
+	}
+	s.audit(ctx, audit.OperationDelete, url, nil)
+	return nil
+}
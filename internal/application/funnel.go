@@ -0,0 +1,165 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// ErrFunnelsUnsupported is returned by the funnel methods when the repository keeps no funnels
+var ErrFunnelsUnsupported = errors.New("funnels are not supported by the repository")
+
+// ErrInvalidFunnel is returned when the steps of a new funnel do not make a usable funnel
+var ErrInvalidFunnel = errors.New("invalid funnel")
+
+// CreateFunnelRequest defines a funnel; steps are numbered from 1 in the order given
+type CreateFunnelRequest struct {
+	Name  string             `json:"name" validate:"required,max=100" example:"Spring signup"`
+	Steps []CreateFunnelStep `json:"steps" validate:"required,min=2,max=10,dive"`
+}
+
+// CreateFunnelStep names the short URL of a funnel step
+type CreateFunnelStep struct {
+	ShortCode string `json:"shortCode" validate:"required" example:"step1"`
+	Namespace string `json:"namespace,omitempty" validate:"omitempty,namespace"` // defaults to "default"
+}
+
+// FunnelAnalyticsResponse reports how far the sessions entering a funnel got
+type FunnelAnalyticsResponse struct {
+	FunnelID int64                 `json:"funnelId" example:"1"`
+	Name     string                `json:"name" example:"Spring signup"`
+	Steps    []FunnelStepAnalytics `json:"steps"`
+}
+
+// FunnelStepAnalytics counts the sessions that reached a step after every earlier one
+type FunnelStepAnalytics struct {
+	Order     int    `json:"order" example:"2"`
+	Namespace string `json:"namespace" example:"default"`
+	ShortCode string `json:"shortCode" example:"step2"`
+	Sessions  int    `json:"sessions" example:"40"`
+	// CompletionRate is Sessions relative to the sessions of the first step, between 0 and 1,
+	// and 0 while no session entered the funnel
+	CompletionRate float64 `json:"completionRate" example:"0.4"`
+}
+
+// FunnelsEnabled reports whether the repository keeps funnels
+func (s *URLService) FunnelsEnabled() bool {
+	return s.funnels != nil
+}
+
+// CreateFunnel validates and stores a funnel. Every step must be an existing short URL,
+// each at most once.
+func (s *URLService) CreateFunnel(ctx context.Context, req CreateFunnelRequest) (*domain.Funnel, error) {
+	if s.funnels == nil {
+		return nil, ErrFunnelsUnsupported
+	}
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	funnel := &domain.Funnel{Name: req.Name, CreatedAt: time.Now().UTC()}
+	seen := make(map[string]int, len(req.Steps))
+	for i, step := range req.Steps {
+		order := i + 1
+		namespace := step.Namespace
+		if namespace == "" {
+			namespace = domain.DefaultNamespace
+		}
+
+		key := namespace + "/" + step.ShortCode
+		if earlier, ok := seen[key]; ok {
+			return nil, fmt.Errorf("%w: step %d repeats step %d", ErrInvalidFunnel, order, earlier)
+		}
+		seen[key] = order
+
+		exists, err := s.repo.Exists(ctx, namespace, step.ShortCode)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: step %d, %s, is not a short URL", ErrInvalidFunnel, order, key)
+		}
+
+		funnel.Steps = append(funnel.Steps, domain.FunnelStep{Order: order, Namespace: namespace, ShortCode: step.ShortCode})
+	}
+
+	created, err := s.funnels.CreateFunnel(ctx, funnel)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Funnel created", "id", created.ID, "name", created.Name, "steps", len(created.Steps))
+	return created, nil
+}
+
+// RecordFunnelStep records sessionID reaching every funnel step the short URL is, and
+// reports whether it is one. It does nothing when funnels are not supported.
+func (s *URLService) RecordFunnelStep(ctx context.Context, namespace, shortCode, sessionID string) (bool, error) {
+	if s.funnels == nil {
+		return false, nil
+	}
+
+	refs, err := s.funnels.FindFunnelSteps(ctx, namespace, shortCode)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	for _, ref := range refs {
+		click := &domain.FunnelClick{FunnelID: ref.FunnelID, StepOrder: ref.StepOrder, SessionID: sessionID, ClickedAt: now}
+		if err := s.funnels.RecordFunnelClick(ctx, click); err != nil {
+			return true, err
+		}
+	}
+
+	return len(refs) > 0, nil
+}
+
+// FunnelAnalytics counts, for each step, the sessions that reached it and every step before
+// it, in whatever order they were clicked
+func (s *URLService) FunnelAnalytics(ctx context.Context, id int64) (*FunnelAnalyticsResponse, error) {
+	if s.funnels == nil {
+		return nil, ErrFunnelsUnsupported
+	}
+
+	funnel, err := s.funnels.FindFunnel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sessionSteps, err := s.funnels.FunnelSessionSteps(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	reached := make(map[string]map[int]bool)
+	for _, step := range sessionSteps {
+		if reached[step.SessionID] == nil {
+			reached[step.SessionID] = make(map[int]bool)
+		}
+		reached[step.SessionID][step.StepOrder] = true
+	}
+
+	counts := make([]int, len(funnel.Steps))
+	for _, orders := range reached {
+		for i, step := range funnel.Steps {
+			if !orders[step.Order] {
+				break
+			}
+			counts[i]++
+		}
+	}
+
+	response := &FunnelAnalyticsResponse{FunnelID: funnel.ID, Name: funnel.Name, Steps: make([]FunnelStepAnalytics, 0, len(funnel.Steps))}
+	for i, step := range funnel.Steps {
+		analytics := FunnelStepAnalytics{Order: step.Order, Namespace: step.Namespace, ShortCode: step.ShortCode, Sessions: counts[i]}
+		if counts[0] > 0 {
+			analytics.CompletionRate = float64(counts[i]) / float64(counts[0])
+		}
+		response.Steps = append(response.Steps, analytics)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,164 @@
+package application
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// healthCheckTimeout bounds each probe of a destination URL
+const healthCheckTimeout = 5 * time.Second
+
+// HealthChecker periodically probes destination URLs with HEAD requests and records
+// whether they still resolve. Each run checks one batch of the least recently checked URLs.
+type HealthChecker struct {
+	repo        domain.URLRepository
+	cache       domain.Cache
+	client      *http.Client
+	interval    time.Duration
+	batchSize   int
+	workerCount int
+	logger      *slog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewHealthChecker(repo domain.URLRepository, cache domain.Cache, client *http.Client, interval time.Duration, batchSize, workerCount int, logger *slog.Logger) *HealthChecker {
+	if workerCount < 1 {
+		workerCount = 1
+	}
+	return &HealthChecker{
+		repo:        repo,
+		cache:       cache,
+		client:      client,
+		interval:    interval,
+		batchSize:   batchSize,
+		workerCount: workerCount,
+		logger:      logger,
+	}
+}
+
+// Start launches the background loop. The first batch is checked immediately.
+func (c *HealthChecker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := c.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				c.logger.Error("URL health check run failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels in-flight checks and waits for the loop to exit or ctx to expire
+func (c *HealthChecker) Stop(ctx context.Context) error {
+	if c.cancel == nil {
+		return nil
+	}
+	c.cancel()
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunOnce checks a single batch of URLs using the configured number of workers
+func (c *HealthChecker) RunOnce(ctx context.Context) error {
+	urls, err := c.repo.ListForHealthCheck(ctx, c.batchSize)
+	if err != nil {
+		return err
+	}
+
+	jobs := make(chan *domain.URL)
+	var wg sync.WaitGroup
+	for i := 0; i < c.workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range jobs {
+				c.checkAndRecord(ctx, url)
+			}
+		}()
+	}
+
+	for _, url := range urls {
+		select {
+		case jobs <- url:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	c.logger.Debug("URL health check batch completed", "checked", len(urls))
+	return ctx.Err()
+}
+
+func (c *HealthChecker) checkAndRecord(ctx context.Context, url *domain.URL) {
+	status := c.probe(ctx, url.OriginalURL)
+	if ctx.Err() != nil {
+		// Shutting down; an interrupted probe says nothing about the destination
+		return
+	}
+
+	if err := c.repo.UpdateHealthStatus(ctx, url.Namespace, url.ShortCode, status, time.Now().UTC()); err != nil {
+		c.logger.Warn("Failed to record URL health", "short_code", url.ShortCode, "error", err)
+		return
+	}
+
+	// Drop the cached copy so lookups see the new status
+	if err := c.cache.Delete(ctx, url.Namespace, url.ShortCode); err != nil {
+		c.logger.Warn("Failed to invalidate cache after health check", "short_code", url.ShortCode, "error", err)
+	}
+
+	if status == domain.HealthStatusDead {
+		c.logger.Info("Destination URL is dead", "short_code", url.ShortCode, "original_url", url.OriginalURL)
+	}
+}
+
+// probe reports a destination as healthy when a HEAD request answers with a non-error status
+func (c *HealthChecker) probe(ctx context.Context, target string) string {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return domain.HealthStatusDead
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return domain.HealthStatusDead
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return domain.HealthStatusDead
+	}
+	return domain.HealthStatusHealthy
+}
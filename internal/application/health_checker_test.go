@@ -0,0 +1,144 @@
+package application
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/infrastructure/cache"
+	"github.com/sp3dr4/dove/internal/infrastructure/memory"
+)
+
+func newHealthTarget(t *testing.T, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		assert.Equal(t, http.MethodHead, r.Method)
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func createURL(t *testing.T, repo domain.URLRepository, shortCode, originalURL string) {
+	t.Helper()
+
+	url, err := domain.NewURL(shortCode, originalURL)
+	require.NoError(t, err)
+	_, err = repo.Create(context.Background(), url)
+	require.NoError(t, err)
+}
+
+func TestHealthChecker_RunOnce(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	var hits atomic.Int32
+	target := newHealthTarget(t, &hits)
+
+	// A listener that was closed immediately gives a guaranteed connection failure
+	closed := httptest.NewServer(http.NotFoundHandler())
+	unreachable := closed.URL
+	closed.Close()
+
+	createURL(t, repo, "ok", target.URL+"/ok")
+	createURL(t, repo, "moved", target.URL+"/moved")
+	createURL(t, repo, "gone", target.URL+"/gone")
+	createURL(t, repo, "broken", target.URL+"/broken")
+	createURL(t, repo, "offline", unreachable)
+
+	checker := NewHealthChecker(repo, cache.NewNoOpCache(), target.Client(), time.Hour, 10, 3, logger)
+	before := time.Now().UTC()
+
+	require.NoError(t, checker.RunOnce(context.Background()))
+
+	expected := map[string]string{
+		"ok":      domain.HealthStatusHealthy,
+		"moved":   domain.HealthStatusHealthy,
+		"gone":    domain.HealthStatusDead,
+		"broken":  domain.HealthStatusDead,
+		"offline": domain.HealthStatusDead,
+	}
+	for shortCode, status := range expected {
+		url, err := repo.FindByShortCode(context.Background(), shortCode)
+		require.NoError(t, err)
+		assert.Equal(t, status, url.HealthStatus, shortCode)
+		require.NotNil(t, url.LastCheckedAt, shortCode)
+		assert.False(t, url.LastCheckedAt.Before(before), shortCode)
+	}
+}
+
+func TestHealthChecker_ChecksStalestURLsFirst(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	var hits atomic.Int32
+	target := newHealthTarget(t, &hits)
+
+	createURL(t, repo, "first", target.URL+"/ok")
+	createURL(t, repo, "second", target.URL+"/ok")
+	createURL(t, repo, "third", target.URL+"/ok")
+
+	checker := NewHealthChecker(repo, cache.NewNoOpCache(), target.Client(), time.Hour, 2, 1, logger)
+	ctx := context.Background()
+
+	require.NoError(t, checker.RunOnce(ctx))
+	assert.Equal(t, int32(2), hits.Load())
+
+	third, err := repo.FindByShortCode(ctx, "third")
+	require.NoError(t, err)
+	assert.Nil(t, third.LastCheckedAt, "third URL should wait for the next batch")
+	assert.Equal(t, domain.HealthStatusUnknown, third.HealthStatus)
+
+	// The never-checked URL now sorts ahead of the two that were just checked
+	require.NoError(t, checker.RunOnce(ctx))
+	third, err = repo.FindByShortCode(ctx, "third")
+	require.NoError(t, err)
+	require.NotNil(t, third.LastCheckedAt)
+	assert.Equal(t, domain.HealthStatusHealthy, third.HealthStatus)
+}
+
+func TestHealthChecker_StartStop(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	var hits atomic.Int32
+	target := newHealthTarget(t, &hits)
+
+	createURL(t, repo, "gone", target.URL+"/gone")
+
+	checker := NewHealthChecker(repo, cache.NewNoOpCache(), target.Client(), time.Hour, 10, 2, logger)
+	checker.Start()
+
+	// The first batch runs as soon as the checker starts
+	assert.Eventually(t, func() bool {
+		url, err := repo.FindByShortCode(context.Background(), "gone")
+		return err == nil && url.HealthStatus == domain.HealthStatusDead
+	}, 2*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, checker.Stop(ctx))
+}
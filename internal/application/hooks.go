@@ -0,0 +1,180 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+)
+
+// CreateHook runs around the creation of a URL. Pre-create hooks see the URL about to be
+// stored and may change it, post-create hooks see it as stored.
+type CreateHook func(ctx context.Context, url *domain.URL) error
+
+// ErrCreateRejected is wrapped by the errors of pre-create hooks refusing a URL, as opposed
+// to hooks failing to decide
+var ErrCreateRejected = errors.New("url rejected")
+
+// AddPreCreateHook adds h to the hooks run, in the order added, after a new URL is
+// validated and before it is stored. The first hook to fail aborts the creation with its
+// error.
+func (s *URLService) AddPreCreateHook(h CreateHook) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.preCreateHooks = append(s.preCreateHooks, h)
+}
+
+// AddPostCreateHook adds h to the hooks run, in the order added, once a new URL is stored.
+// Their errors are logged and the creation still succeeds.
+func (s *URLService) AddPostCreateHook(h CreateHook) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.postCreateHooks = append(s.postCreateHooks, h)
+}
+
+// runPreCreateHooks returns the error of the first pre-create hook refusing url
+func (s *URLService) runPreCreateHooks(ctx context.Context, url *domain.URL) error {
+	s.hooksMu.RLock()
+	hooks := s.preCreateHooks
+	s.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx, url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPostCreateHooks runs every post-create hook on url, logging their failures
+func (s *URLService) runPostCreateHooks(ctx context.Context, url *domain.URL) {
+	s.hooksMu.RLock()
+	hooks := s.postCreateHooks
+	s.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx, url); err != nil {
+			s.logger.Error("Post-create hook failed", "namespace", url.Namespace, "short_code", url.ShortCode, "error", err)
+		}
+	}
+}
+
+// BlacklistHook refuses URLs with a destination on one of domains or their subdomains,
+// ignoring case. Every destination counts: variants, pool targets and routes as well as
+// the original URL.
+func BlacklistHook(domains []string) CreateHook {
+	blocked := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		blocked[strings.TrimSuffix(strings.ToLower(d), ".")] = true
+	}
+
+	return func(_ context.Context, url *domain.URL) error {
+		for _, destination := range destinationsOf(url) {
+			parsed, err := neturl.Parse(destination)
+			if err != nil {
+				continue
+			}
+			host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+			for host != "" {
+				if blocked[host] {
+					return fmt.Errorf("%w: %s is on a blocked domain", ErrCreateRejected, parsed.Hostname())
+				}
+				_, parent, found := strings.Cut(host, ".")
+				if !found {
+					break
+				}
+				host = parent
+			}
+		}
+		return nil
+	}
+}
+
+// destinationsOf lists every URL a short URL may redirect to
+func destinationsOf(url *domain.URL) []string {
+	destinations := []string{url.OriginalURL}
+	for _, variant := range url.Variants {
+		destinations = append(destinations, variant.OriginalURL)
+	}
+	if url.Pool != nil {
+		for _, target := range url.Pool.Targets {
+			destinations = append(destinations, target.URL)
+		}
+	}
+	for _, route := range url.GeoRoutes {
+		destinations = append(destinations, route.DestinationURL)
+	}
+	for _, route := range url.DeviceRoutes {
+		destinations = append(destinations, route.DestinationURL)
+	}
+	return destinations
+}
+
+// CreateWebhookEvent is the body POSTed by WebhookDispatchHook
+type CreateWebhookEvent struct {
+	Event       string    `json:"event" example:"url.created"`
+	Namespace   string    `json:"namespace" example:"default"`
+	ShortCode   string    `json:"shortCode" example:"abc123"`
+	OriginalURL string    `json:"originalUrl" example:"https://example.com"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// WebhookDispatchHook POSTs a CreateWebhookEvent to endpoint for every URL it is run on.
+// The delivery is made once; a response other than 2xx is an error.
+func WebhookDispatchHook(client *http.Client, endpoint string) CreateHook {
+	return func(ctx context.Context, url *domain.URL) error {
+		body, err := json.Marshal(CreateWebhookEvent{
+			Event:       "url.created",
+			Namespace:   url.Namespace,
+			ShortCode:   url.ShortCode,
+			OriginalURL: url.OriginalURL,
+			CreatedAt:   url.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to deliver webhook: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook answered %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// AuditRepositoryHook records a create entry in auditLog for every URL it is run on
+func AuditRepositoryHook(auditLog domain.AuditRepository) CreateHook {
+	return func(ctx context.Context, url *domain.URL) error {
+		return auditLog.Record(ctx, newAuditEntry(ctx, audit.OperationCreate, nil, url))
+	}
+}
+
+// AuditLogHook writes a create entry to auditLogger for every URL it is run on. The
+// service always runs it first among its post-create hooks.
+func AuditLogHook(auditLogger *audit.AuditLogger) CreateHook {
+	return func(ctx context.Context, url *domain.URL) error {
+		return auditLogger.Log(ctx, audit.Entry{
+			Operation:   audit.OperationCreate,
+			Namespace:   url.Namespace,
+			ShortCode:   url.ShortCode,
+			OriginalURL: url.OriginalURL,
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// MaxImportEntries caps the number of URLs accepted by a single import
+const MaxImportEntries = 10000
+
+// ErrImportTooLarge is returned when an import holds more than MaxImportEntries entries
+var ErrImportTooLarge = fmt.Errorf("import exceeds %d entries", MaxImportEntries)
+
+// ImportEntry is one raw CreateURLRequest from an import, tagged with its position in the source
+type ImportEntry struct {
+	Line int
+	Data []byte
+}
+
+// ImportError explains why a single entry was not imported
+type ImportError struct {
+	Line    int    `json:"line" example:"3"`
+	Message string `json:"message" example:"short code already exists"`
+}
+
+// ImportReport summarises a bulk import; failed entries do not abort the rest
+type ImportReport struct {
+	Succeeded int           `json:"succeeded" example:"98"`
+	Failed    int           `json:"failed" example:"2"`
+	Errors    []ImportError `json:"errors"`
+}
+
+// ImportURLs creates a short URL for each entry in order. Entries that fail to decode,
+// validate or store are recorded in the report and the import carries on.
+func (s *URLService) ImportURLs(ctx context.Context, entries []ImportEntry, baseURL string) (*ImportReport, error) {
+	if len(entries) > MaxImportEntries {
+		return nil, ErrImportTooLarge
+	}
+
+	report := &ImportReport{Errors: []ImportError{}}
+	created := make([]*domain.URL, 0, len(entries))
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		url, err := s.importEntry(ctx, entry, baseURL)
+		if err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, ImportError{Line: entry.Line, Message: s.importErrorMessage(entry.Line, err)})
+			continue
+		}
+		created = append(created, url)
+		report.Succeeded++
+	}
+
+	if err := s.cache.SetMulti(ctx, created, s.cacheTTL); err != nil {
+		s.logger.Warn("Failed to cache imported URLs", "count", len(created), "error", err)
+	}
+
+	s.logger.Info("Bulk import finished", "succeeded", report.Succeeded, "failed", report.Failed)
+	return report, nil
+}
+
+func (s *URLService) importEntry(ctx context.Context, entry ImportEntry, baseURL string) (*domain.URL, error) {
+	var req CreateURLRequest
+	if err := json.Unmarshal(entry.Data, &req); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidImportJSON, err)
+	}
+
+	url, _, err := s.createShortURL(ctx, req, CreateURLSettings{}, baseURL)
+	return url, err
+}
+
+// errInvalidImportJSON marks entries that are not a CreateURLRequest object
+var errInvalidImportJSON = errors.New("invalid JSON")
+
+// importErrorMessage explains to the client why the entry on line was not imported. As
+// for a single creation, errors the client cannot act on are logged rather than reported.
+func (s *URLService) importErrorMessage(line int, err error) string {
+	if message, ok := createErrorMessage(err); ok {
+		return message
+	}
+	s.logger.Error("Failed to import URL", "line", line, "error", err)
+	return "failed to create URL"
+}
+
+// createErrorMessage describes the creation errors caused by the request itself, reporting
+// false for any other error
+func createErrorMessage(err error) (string, bool) {
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrors):
+		messages := make([]string, 0, len(validationErrors))
+		for _, e := range validationErrors {
+			messages = append(messages, fmt.Sprintf("%s failed %s validation", requestFieldName(e), e.Tag()))
+		}
+		return strings.Join(messages, "; "), true
+	case errors.Is(err, domain.ErrShortCodeExists):
+		return "short code already exists", true
+	case errors.Is(err, domain.ErrReservedAlias):
+		return "customAlias is a reserved word", true
+	case errors.Is(err, ErrSigningDisabled):
+		return "signedExpiry requires a signing secret to be configured", true
+	case errors.Is(err, errInvalidImportJSON), errors.Is(err, ErrCreateRejected),
+		errors.Is(err, ErrDelayTooLong), errors.Is(err, ErrExpiryInPast):
+		return err.Error(), true
+	default:
+		return "", false
+	}
+}
+
+// requestFieldName reports a CreateURLRequest field by its JSON name, as it appears in the import file
+func requestFieldName(e validator.FieldError) string {
+	field, ok := reflect.TypeOf(CreateURLRequest{}).FieldByName(e.StructField())
+	if !ok {
+		return e.Field()
+	}
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" {
+		return name
+	}
+	return e.Field()
+}
@@ -0,0 +1,91 @@
+package application
+
+import (
+	"context"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// BatchLookupRequest lists the short codes of one namespace to look up, at most 50
+type BatchLookupRequest struct {
+	ShortCodes []string `json:"shortCodes" validate:"required,min=1,max=50,dive,required" example:"abc123,promo"`
+}
+
+// BatchLookupResponse holds the metadata of every short code found, keyed by short code.
+// Short codes matching no URL are listed, not failed.
+type BatchLookupResponse struct {
+	Results  map[string]*URLInfoResponse `json:"results"`
+	NotFound []string                    `json:"notFound" example:"promo"`
+}
+
+// LookupURLs returns the metadata of the URLs under namespace with any of the requested
+// short codes. As with a single lookup, signed URLs are not found by their plain short code,
+// and password protected URLs, whose passwords cannot be given here, are not found either.
+func (s *URLService) LookupURLs(ctx context.Context, namespace string, req BatchLookupRequest, baseURL string) (*BatchLookupResponse, error) {
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	urls, err := s.GetURLBatch(ctx, namespace, req.ShortCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &BatchLookupResponse{Results: map[string]*URLInfoResponse{}, NotFound: []string{}}
+	listed := make(map[string]bool, len(req.ShortCodes))
+	for _, shortCode := range req.ShortCodes {
+		// A code requested twice is reported once
+		if listed[shortCode] {
+			continue
+		}
+		listed[shortCode] = true
+
+		url := urls[shortCode]
+		if url == nil || hidesDestination(url) {
+			response.NotFound = append(response.NotFound, shortCode)
+			continue
+		}
+		response.Results[shortCode] = NewURLInfoResponse(url, baseURL)
+	}
+
+	return response, nil
+}
+
+// GetURLBatch resolves shortCodes within namespace, keyed by short code. The cache is read
+// in one round trip, and the misses are read from the repository in one query and cached.
+// Short codes matching no URL are left out of the map.
+func (s *URLService) GetURLBatch(ctx context.Context, namespace string, shortCodes []string) (map[string]*domain.URL, error) {
+	urls, err := s.cache.GetMulti(ctx, namespace, shortCodes)
+	if err != nil {
+		s.logger.Warn("Cache error during batch get", "namespace", namespace, "count", len(shortCodes), "error", err)
+		urls = map[string]*domain.URL{}
+	}
+
+	var missing []string
+	seen := make(map[string]bool, len(shortCodes))
+	for _, shortCode := range shortCodes {
+		if urls[shortCode] == nil && !seen[shortCode] {
+			missing = append(missing, shortCode)
+		}
+		seen[shortCode] = true
+	}
+	s.logger.Debug("Batch cache lookup", "namespace", namespace, "hits", len(urls), "misses", len(missing))
+	if len(missing) == 0 {
+		return urls, nil
+	}
+
+	fetched, err := s.repo.FindByShortCodes(ctx, namespace, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(fetched) > 0 {
+		if err := s.cache.SetMulti(ctx, fetched, s.cacheTTL); err != nil {
+			s.logger.Warn("Failed to cache URLs", "namespace", namespace, "count", len(fetched), "error", err)
+		}
+	}
+
+	for _, url := range fetched {
+		urls[url.ShortCode] = url
+	}
+	return urls, nil
+}
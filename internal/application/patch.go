@@ -0,0 +1,94 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+)
+
+// ErrExpiryInPast is returned when a URL would be given an expiry that has already passed
+var ErrExpiryInPast = errors.New("expiry must be in the future")
+
+// NullableTime is a JSON merge patch field. A field absent from the document leaves the
+// value alone, a null clears it and any other value replaces it.
+type NullableTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (n *NullableTime) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if bytes.Equal(data, []byte("null")) {
+		n.Value = nil
+		return nil
+	}
+
+	var value time.Time
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	n.Value = &value
+	return nil
+}
+
+// PatchURLRequest lists the fields to change on a short URL, following JSON merge patch:
+// fields left out of the document keep their current value
+type PatchURLRequest struct {
+	OriginalURL  *string      `json:"originalUrl,omitempty" validate:"omitnil,safeurl" example:"https://example.com/new"`
+	RedirectType *int         `json:"redirectType,omitempty" validate:"omitnil,oneof=301 302 307 308" example:"302"`
+	ExpiresAt    NullableTime `json:"expiresAt" swaggertype:"string" format:"date-time" extensions:"x-nullable"` // null removes the expiry
+	Tags         *[]string    `json:"tags,omitempty" validate:"omitnil,max=20,dive,required,max=32" example:"docs,launch"`
+	Description  *string      `json:"description,omitempty" validate:"omitnil,max=500" example:"Spring launch campaign"` // "" removes the description
+}
+
+// PatchURL applies the non nil fields of req to the URL stored under namespace and shortCode
+func (s *URLService) PatchURL(ctx context.Context, namespace, shortCode string, req PatchURLRequest, baseURL string) (*URLInfoResponse, error) {
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	url, err := s.repo.FindByNamespaceAndCode(ctx, namespace, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	before := *url
+
+	if req.OriginalURL != nil {
+		url.OriginalURL = *req.OriginalURL
+	}
+	if req.RedirectType != nil {
+		url.RedirectType = *req.RedirectType
+	}
+	if req.ExpiresAt.Set {
+		if req.ExpiresAt.Value != nil && !req.ExpiresAt.Value.After(time.Now()) {
+			return nil, ErrExpiryInPast
+		}
+		url.ExpiresAt = req.ExpiresAt.Value
+	}
+	if req.Tags != nil {
+		url.Tags = *req.Tags
+	}
+	if req.Description != nil {
+		url.Description = *req.Description
+	}
+
+	updated, err := s.repo.Update(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.Delete(ctx, namespace, shortCode); err != nil {
+		s.logger.Warn("Failed to invalidate cache after update", "namespace", namespace, "short_code", shortCode, "error", err)
+	}
+	if err := s.cache.InvalidateTopURLs(ctx); err != nil {
+		s.logger.Warn("Failed to invalidate top URLs cache", "short_code", shortCode, "error", err)
+	}
+
+	s.audit(ctx, audit.OperationUpdate, &before, updated)
+
+	return NewURLInfoResponse(updated, baseURL), nil
+}
@@ -0,0 +1,49 @@
+package application
+
+import (
+	"errors"
+	"net/netip"
+	neturl "net/url"
+	"strings"
+)
+
+// Destination safety violations, as reported by CheckURLSafety
+var (
+	ErrUnsafeURLScheme = errors.New("URL scheme can run content in the browser")
+	ErrPrivateIPURL    = errors.New("URL points to a private network")
+)
+
+// unsafeSchemes carry their content in the URL itself, so their short URLs would serve
+// whatever page or script the creator wrote
+var unsafeSchemes = map[string]bool{"data": true, "javascript": true, "vbscript": true, "blob": true}
+
+// CheckURLSafety returns why rawURL is no destination to redirect visitors to, or nil.
+// Hosts are only refused when they are an IP address of a private, loopback, link-local
+// or unspecified range, or localhost; host names are not resolved.
+func CheckURLSafety(rawURL string) error {
+	// Browsers ignore the case of schemes and the whitespace around them
+	scheme, _, found := strings.Cut(strings.TrimSpace(rawURL), ":")
+	if found && unsafeSchemes[strings.ToLower(scheme)] {
+		return ErrUnsafeURLScheme
+	}
+
+	parsed, err := neturl.Parse(rawURL)
+	if err != nil {
+		// Left to the URL format rule
+		return nil
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateIPURL
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() {
+		return ErrPrivateIPURL
+	}
+	return nil
+}
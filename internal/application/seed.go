@@ -0,0 +1,112 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// SeedURL is one short URL of a seed file, created under a fixed short code so that the
+// same file gives the same URLs in every environment
+type SeedURL struct {
+	ShortCode   string     `yaml:"shortCode"`
+	OriginalURL string     `yaml:"originalUrl"`
+	Namespace   string     `yaml:"namespace"` // defaults to "default"
+	Tags        []string   `yaml:"tags"`
+	ExpiresAt   *time.Time `yaml:"expiresAt"`
+}
+
+// seedFile is the layout of app.seed_file
+type seedFile struct {
+	URLs []SeedURL `yaml:"urls"`
+}
+
+// SeedReport summarises a seeding run; existing short codes are skipped, not failed
+type SeedReport struct {
+	Seeded  int
+	Skipped int
+	Failed  int
+}
+
+// LoadSeedFile reads the URLs listed in the YAML seed file at path
+func LoadSeedFile(path string) ([]SeedURL, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file: %w", err)
+	}
+
+	var file seedFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse seed file: %w", err)
+	}
+	return file.URLs, nil
+}
+
+// SeedURLs creates each URL that does not exist yet. Short codes already taken are left
+// untouched, so seeding the same file again is a no-op.
+func (s *URLService) SeedURLs(ctx context.Context, entries []SeedURL, baseURL string) (*SeedReport, error) {
+	report := &SeedReport{}
+	for i, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		err := s.seedURL(ctx, entry, baseURL)
+		switch {
+		case err == nil:
+			report.Seeded++
+		case errors.Is(err, domain.ErrShortCodeExists):
+			report.Skipped++
+		default:
+			report.Failed++
+			message, ok := createErrorMessage(err)
+			if !ok {
+				message = err.Error()
+			}
+			s.logger.Warn("Failed to seed URL", "entry", i+1, "short_code", entry.ShortCode, "error", message)
+		}
+	}
+
+	s.logger.Info("Seeding finished", "seeded", report.Seeded, "skipped", report.Skipped, "failed", report.Failed)
+	return report, nil
+}
+
+func (s *URLService) seedURL(ctx context.Context, entry SeedURL, baseURL string) error {
+	if entry.ShortCode == "" {
+		return errors.New("shortCode is required")
+	}
+
+	// Tags and expiry are applied once the URL exists, checked up front so that a bad
+	// entry is not left half seeded
+	patch := PatchURLRequest{ExpiresAt: NullableTime{Set: entry.ExpiresAt != nil, Value: entry.ExpiresAt}}
+	if entry.Tags != nil {
+		patch.Tags = &entry.Tags
+	}
+	if err := s.validate.Struct(patch); err != nil {
+		return err
+	}
+	if entry.ExpiresAt != nil && !entry.ExpiresAt.After(time.Now()) {
+		return ErrExpiryInPast
+	}
+
+	created, err := s.CreateShortURL(ctx, CreateURLRequest{
+		URL:         entry.OriginalURL,
+		CustomAlias: entry.ShortCode,
+		Namespace:   entry.Namespace,
+	}, baseURL)
+	if err != nil {
+		return err
+	}
+
+	if patch.Tags == nil && !patch.ExpiresAt.Set {
+		return nil
+	}
+	_, err = s.PatchURL(ctx, created.Namespace, created.ShortCode, patch, baseURL)
+	return err
+}
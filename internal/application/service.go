@@ -2,135 +2,1393 @@ package application
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/iputil"
+	"github.com/sp3dr4/dove/internal/pkg/lock"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+	"github.com/sp3dr4/dove/internal/pkg/shortcode"
+	"github.com/sp3dr4/dove/internal/pkg/singleflight"
+	"github.com/sp3dr4/dove/internal/pkg/urlfetch"
+	"github.com/sp3dr4/dove/internal/pkg/useragent"
 )
 
+// MaxTopURLs caps the size of the most clicked URLs ranking
+const MaxTopURLs = 100
+
+// MaxColdURLs caps the number of cold URLs listed at once
+const MaxColdURLs = 1000
+
+// MaxListedURLs caps the number of URLs of one page of the admin listing
+const MaxListedURLs = 1000
+
+// Alias suggestions
+const (
+	maxAliasSuggestions = 3
+	minAliasLength      = 3  // matches the customAlias validation rule
+	maxSuggestionTries  = 10 // numeric suffixes tried before giving up
+)
+
+// shortCodeLength is the length of generated short codes
+const shortCodeLength = 6
+
+// maxGenerateAttempts is how many generated short codes are tried before giving up on a
+// creation, each one being taken by another URL
+const maxGenerateAttempts = 3
+
+// aliasLockTTL bounds how long a custom alias stays locked by a creation that never
+// released it, such as one from a crashed instance
+const aliasLockTTL = 5 * time.Second
+
+// topURLsCacheTTL keeps the ranking short-lived, as every click may reorder it
+const topURLsCacheTTL = 30 * time.Second
+
+// statsWindow is how far back the "today" counters of the service stats reach
+const statsWindow = 24 * time.Hour
+
+// MaxDailyStatsDays is the most days the daily stats cover at once
+const MaxDailyStatsDays = 365
+
 type URLService struct {
-	repo     domain.URLRepository
-	cache    domain.Cache
-	cacheTTL time.Duration
-	validate *validator.Validate
-	logger   *slog.Logger
+	repo domain.URLRepository
+	// funnels is repo when it keeps funnels
+	funnels       domain.FunnelRepository
+	cache         domain.Cache
+	cacheTTL      time.Duration
+	auditLogger   *audit.AuditLogger
+	auditLog      domain.AuditRepository
+	broker        *pubsub.Broker
+	signingSecret SigningSecret
+	maxDelay      MaxRedirectDelay
+	codes         *shortcode.ShortCodeGenerator
+	dedup         domain.ClickDeduplicator
+	chains        *RedirectChains
+	aliases       AliasPolicy
+	locker        lock.Locker
+	clicks        domain.ClickCounter // buffers click counters when set, instead of updating every URL clicked
+	reserved      *shortcode.ReservedWords
+	validate      *validator.Validate
+	fetchClient   *http.Client // only reaches public addresses, see urlfetch.NewClient
+	fetchTitles   FetchTitles
+	metrics       metrics.Registry
+	logger        *slog.Logger
+
+	// poolCounters holds the round-robin position of each pooled URL, keyed by URL ID
+	poolCounters sync.Map
+	// lookups coalesces concurrent cache misses for the same short code into one repository read
+	lookups singleflight.Group[*domain.URL]
+	// preCreateHooks and postCreateHooks run around the creation of every URL, see CreateHook
+	hooksMu         sync.RWMutex
+	preCreateHooks  []CreateHook
+	postCreateHooks []CreateHook
+	// cacheHits and cacheMisses count GetURL lookups since the service started
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
 }
 
-func NewURLService(repo domain.URLRepository, cache domain.Cache, cacheTTL time.Duration, logger *slog.Logger) *URLService {
-	return &URLService{
-		repo:     repo,
-		cache:    cache,
-		cacheTTL: cacheTTL,
-		validate: validator.New(),
-		logger:   logger,
+// SigningSecret is the HMAC key of signed short codes. Signed URLs are disabled when it is empty.
+type SigningSecret []byte
+
+// ErrSigningDisabled is returned when a signed URL is requested but no signing secret is configured
+var ErrSigningDisabled = errors.New("signed short URLs are not enabled")
+
+// MaxRedirectDelay is the longest redirect delay, in seconds, a URL may be created with.
+// Delayed redirects are disabled when it is zero.
+type MaxRedirectDelay int
+
+// ErrDelayTooLong is returned when the requested redirect delay exceeds MaxRedirectDelay
+var ErrDelayTooLong = errors.New("redirect delay too long")
+
+// FetchTitles fetches the title of the destination page of every URL created, in the
+// background
+type FetchTitles bool
+
+// URLServiceDeps holds the optional dependencies of the URL service. Every field may be
+// left zero.
+type URLServiceDeps struct {
+	// SigningSecret enables signed short URLs
+	SigningSecret SigningSecret
+	// MaxDelay enables delayed redirects of up to this many seconds
+	MaxDelay MaxRedirectDelay
+	// Codes generates short codes, from the alphanumeric charset when nil
+	Codes *shortcode.ShortCodeGenerator
+	// Dedup tells unique clicks apart, every click counting as unique when nil
+	Dedup domain.ClickDeduplicator
+	// Chains follows redirect chains at creation, leaving them to the client when nil
+	Chains *RedirectChains
+	// Aliases is the custom alias policy, only alphanumeric aliases being allowed when nil
+	Aliases *AliasPolicy
+	// Locker serializes concurrent claims of an alias, left to the repository when nil
+	Locker lock.Locker
+	// Clicks buffers clicks, every click being stored as it happens when nil
+	Clicks domain.ClickCounter
+	// Reserved lists the words no alias may take, leaving aliases to the alias policy when nil
+	Reserved *shortcode.ReservedWords
+	// AuditLog keeps the change history of URLs, none besides the audit logger when nil
+	AuditLog domain.AuditRepository
+	// Metrics records service metrics, none when nil
+	Metrics metrics.Registry
+	// FetchTitles fetches the page titles of the destinations of new URLs
+	FetchTitles FetchTitles
+}
+
+// NewURLService creates the URL service on its required dependencies and the optional
+// ones in deps
+func NewURLService(repo domain.URLRepository, cache domain.Cache, cacheTTL time.Duration, auditLogger *audit.AuditLogger, broker *pubsub.Broker, deps URLServiceDeps, logger *slog.Logger) *URLService {
+	aliases, codes, metricsRegistry := deps.Aliases, deps.Codes, deps.Metrics
+	var aliasPolicy AliasPolicy
+	if aliases != nil {
+		aliasPolicy = *aliases
+	}
+
+	validate := validator.New()
+	_ = validate.RegisterValidation("namespace", func(fl validator.FieldLevel) bool {
+		return domain.ValidateNamespace(fl.Field().String()) == nil
+	})
+	_ = validate.RegisterValidation("customalias", func(fl validator.FieldLevel) bool {
+		return aliasPolicy.Check(fl.Field().String()) == nil
+	})
+	// safeurl is the url rule refusing destinations CheckURLSafety rejects
+	_ = validate.RegisterValidation("safeurl", func(fl validator.FieldLevel) bool {
+		return validate.Var(fl.Field().String(), "url") == nil && CheckURLSafety(fl.Field().String()) == nil
+	})
+	_ = validate.RegisterValidation("duration", func(fl validator.FieldLevel) bool {
+		d, err := time.ParseDuration(fl.Field().String())
+		return err == nil && d > 0
+	})
+	if codes == nil {
+		// The alphanumeric charset is always valid
+		codes, _ = shortcode.NewShortCodeGenerator(shortcode.CharsetAlphanumeric, "")
+	}
+	funnels, _ := repo.(domain.FunnelRepository)
+	if metricsRegistry == nil {
+		metricsRegistry = metrics.NewNoOpRegistry()
 	}
+
+	service := &URLService{
+		repo:          repo,
+		funnels:       funnels,
+		cache:         cache,
+		cacheTTL:      cacheTTL,
+		auditLogger:   auditLogger,
+		auditLog:      deps.AuditLog,
+		broker:        broker,
+		signingSecret: deps.SigningSecret,
+		maxDelay:      deps.MaxDelay,
+		codes:         codes,
+		dedup:         deps.Dedup,
+		chains:        deps.Chains,
+		aliases:       aliasPolicy,
+		locker:        deps.Locker,
+		clicks:        deps.Clicks,
+		reserved:      deps.Reserved,
+		validate:      validate,
+		fetchClient:   urlfetch.NewClient(),
+		fetchTitles:   deps.FetchTitles,
+		metrics:       metricsRegistry,
+		logger:        logger,
+	}
+	service.AddPostCreateHook(AuditLogHook(auditLogger))
+	if deps.AuditLog != nil {
+		service.AddPostCreateHook(AuditRepositoryHook(deps.AuditLog))
+	}
+	return service
 }
 
 type CreateURLRequest struct {
-	URL         string `json:"url" validate:"required,url"`
-	CustomAlias string `json:"customAlias,omitempty" validate:"omitempty,alphanum,min=3,max=20"`
+	URL         string    `json:"url,omitempty" validate:"required_without=Pool,excluded_with=Pool,omitempty,safeurl"`
+	CustomAlias string    `json:"customAlias,omitempty" validate:"omitempty,min=3,customalias"` // letters and digits, and hyphens or underscores when the alias policy allows them
+	Password    string    `json:"password,omitempty" validate:"omitempty,min=4,max=72"`
+	Namespace   string    `json:"namespace,omitempty" validate:"omitempty,namespace"` // defaults to "default"
+	Variants    []Variant `json:"variants,omitempty" validate:"omitempty,excluded_with=Pool,max=10,dive"`
+	Pool        *Pool     `json:"pool,omitempty"` // replaces url to load balance across several destinations
+	// DelaySeconds shows a countdown page for this long before redirecting, up to
+	// app.max_delay_seconds
+	DelaySeconds int `json:"delaySeconds,omitempty" validate:"min=0" example:"5"`
+	// SignedExpiry makes the URL reachable only through a signed token valid for this
+	// long, such as "2h". The token is returned as shortCode.
+	SignedExpiry string `json:"signedExpiry,omitempty" validate:"omitempty,duration" example:"2h"`
+	// GeoRoutes send visitors from the listed countries to their own destination
+	GeoRoutes []GeoRoute `json:"geoRoutes,omitempty" validate:"omitempty,max=50,unique=CountryCode,dive"`
+	// DeviceRoutes send mobile, tablet or desktop visitors to their own destination.
+	// Geo routes take priority.
+	DeviceRoutes []DeviceRoute `json:"deviceRoutes,omitempty" validate:"omitempty,max=3,unique=DeviceType,dive"`
+	// Description is a free text label for operators managing many links
+	Description string `json:"description,omitempty" validate:"omitempty,max=500" example:"Spring launch campaign"`
+	// ClickGoal is the number of clicks after which the URL has reached its goal, announced
+	// once as a goal event
+	ClickGoal *int `json:"clickGoal,omitempty" validate:"omitempty,min=1" example:"1000"`
+}
+
+// CreateURLSettings are the settings version 2 of the API accepts at creation, which
+// version 1 only sets through a patch
+type CreateURLSettings struct {
+	Tags []string `json:"tags,omitempty" validate:"omitempty,max=20,dive,required,max=32" example:"docs,launch"`
+	// ExpiresAt is the moment the URL stops redirecting, in the future
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	RedirectType int        `json:"redirectType,omitempty" validate:"omitempty,oneof=301 302 307 308" example:"302"` // defaults to 301
+}
+
+// CreateURLRequestV2 is the body of POST /shorten in version 2 of the API
+type CreateURLRequestV2 struct {
+	CreateURLRequest
+	CreateURLSettings
+}
+
+// CreateURLContext describes who creates URLs, for the service to record alongside them
+type CreateURLContext struct {
+	// CreatorIP is the address of the client, empty when unknown
+	CreatorIP string
+}
+
+type createURLContextKey struct{}
+
+// WithCreateURLContext returns a copy of ctx carrying c
+func WithCreateURLContext(ctx context.Context, c CreateURLContext) context.Context {
+	return context.WithValue(ctx, createURLContextKey{}, c)
+}
+
+// CreateURLContextFrom returns the CreateURLContext of ctx, or the zero value when it has none
+func CreateURLContextFrom(ctx context.Context) CreateURLContext {
+	c, _ := ctx.Value(createURLContextKey{}).(CreateURLContext)
+	return c
+}
+
+// GeoRoute sends visitors from one country to a region specific destination
+type GeoRoute struct {
+	CountryCode    string `json:"countryCode" validate:"required,iso3166_1_alpha2" example:"US"`
+	DestinationURL string `json:"destinationUrl" validate:"required,safeurl" example:"https://us.example.com"`
+}
+
+// DeviceRoute sends visitors on one kind of device to a dedicated destination
+type DeviceRoute struct {
+	DeviceType     string `json:"deviceType" validate:"required,oneof=mobile tablet desktop" enums:"mobile,tablet,desktop" example:"mobile"`
+	DestinationURL string `json:"destinationUrl" validate:"required,safeurl" example:"https://m.example.com"`
+}
+
+// Pool lists the destinations of a load balanced short URL. Redirects cycle through
+// the targets in order, each receiving a share of the traffic proportional to its weight.
+type Pool struct {
+	Targets []PoolTarget `json:"targets" validate:"required,min=1,max=20,dive"`
+}
+
+// PoolTarget is one destination of a Pool
+type PoolTarget struct {
+	URL    string `json:"url" validate:"required,safeurl" example:"https://eu.example.com"`
+	Weight int    `json:"weight" validate:"required,min=1,max=1000" example:"3"`
+}
+
+// Variant is an alternative destination of an A/B tested short URL. Each redirect picks
+// a variant at random, in proportion to its weight.
+type Variant struct {
+	URL    string `json:"url" validate:"required,safeurl" example:"https://example.com/landing-b"`
+	Weight int    `json:"weight" validate:"required,min=1,max=1000" example:"30"`
 }
 
 type URLResponse struct {
-	ID          int64     `json:"id"`
-	ShortURL    string    `json:"shortUrl"`
-	ShortCode   string    `json:"shortCode"`
-	OriginalURL string    `json:"originalUrl"`
-	Clicks      int       `json:"clicks"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID           int64         `json:"id"`
+	ShortURL     string        `json:"shortUrl"`
+	Namespace    string        `json:"namespace"`
+	ShortCode    string        `json:"shortCode"`
+	OriginalURL  string        `json:"originalUrl"`
+	Clicks       int           `json:"clicks"`
+	UniqueClicks int           `json:"uniqueClicks"` // clicks without repeat visits within the deduplication window
+	CreatedAt    time.Time     `json:"createdAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+	Protected    bool          `json:"protected"`
+	Variants     []Variant     `json:"variants,omitempty"`
+	Pool         *Pool         `json:"pool,omitempty"`
+	RedirectType int           `json:"redirectType" example:"301"`
+	DelaySeconds int           `json:"delaySeconds,omitempty" example:"5"` // countdown before redirecting
+	ExpiresAt    *time.Time    `json:"expiresAt,omitempty"`                // when the URL stops redirecting, or a signed shortCode stops resolving
+	GeoRoutes    []GeoRoute    `json:"geoRoutes,omitempty"`
+	DeviceRoutes []DeviceRoute `json:"deviceRoutes,omitempty"`
+	Description  string        `json:"description,omitempty" example:"Spring launch campaign"`
+	ClickGoal    *int          `json:"clickGoal,omitempty" example:"1000"`
+	// CreatedByIP is the address the URL was created from, its last part masked outside
+	// the admin API
+	CreatedByIP string `json:"createdByIp,omitempty" example:"203.0.113.xxx"`
+	// DryRun is set on the answers of dry runs, the URL described not being stored
+	DryRun bool `json:"dryRun,omitempty"`
+	// Title is that of the destination page, fetched in the background after creation when
+	// app.fetch_titles is set
+	Title string `json:"title,omitempty" example:"Example Domain"`
+}
+
+// AliasSuggestionsResponse lists free custom aliases derived from a destination page title
+type AliasSuggestionsResponse struct {
+	Suggestions []string `json:"suggestions" example:"golangblog,golangblog2"`
+}
+
+// URLHealthResponse reports the last known health of a short URL's destination
+type URLHealthResponse struct {
+	ShortCode     string     `json:"shortCode"`
+	HealthStatus  string     `json:"healthStatus" enums:"unknown,healthy,dead"`
+	LastCheckedAt *time.Time `json:"lastCheckedAt,omitempty"`
+}
+
+// StatsResponse holds service wide counters. The "today" counters cover the last 24 hours
+// and the cache hit rate covers URL lookups since the process started.
+type StatsResponse struct {
+	TotalURLs        int64   `json:"totalUrls"`
+	TotalClicks      int64   `json:"totalClicks"`
+	ActiveURLs       int64   `json:"activeUrls"`
+	URLsCreatedToday int64   `json:"urlsCreatedToday"`
+	ClicksToday      int64   `json:"clicksToday"`
+	CacheHitRate     float64 `json:"cacheHitRate" example:"0.85"` // between 0 and 1, 0 before the first lookup
+	// Memory is only reported by the in-memory repository
+	Memory *MemoryStatsResponse `json:"memory,omitempty"`
+}
+
+// MemoryStatsResponse describes how full the in-memory repository is
+type MemoryStatsResponse struct {
+	Capacity  int   `json:"capacity"` // 0 when unbounded
+	Size      int   `json:"size"`
+	Evictions int64 `json:"evictions"` // least recently accessed URLs evicted to make room since the process started
+}
+
+// DailyCount is the number of URLs created and clicks made across every namespace on a
+// UTC day
+type DailyCount struct {
+	Date        string `json:"date" example:"2024-01-31"`
+	URLsCreated int64  `json:"urlsCreated" example:"42"`
+	Clicks      int64  `json:"clicks" example:"1300"`
+}
+
+// AliasStatsResponse describes the lengths of the short codes of every URL, generated codes
+// apart from custom aliases
+type AliasStatsResponse struct {
+	Generated GeneratedAliasStats `json:"generated"`
+	Custom    CustomAliasStats    `json:"custom"`
+}
+
+// GeneratedAliasStats describes the lengths of generated short codes
+type GeneratedAliasStats struct {
+	Count     int64   `json:"count" example:"1200"`
+	AvgLength float64 `json:"avgLength" example:"6"` // 0 without any URL
+	// Distribution is the number of URLs for each short code length
+	Distribution map[int]int64 `json:"distribution"`
+}
+
+// CustomAliasStats describes the lengths of custom aliases. The lengths are 0 without any
+// custom alias.
+type CustomAliasStats struct {
+	Count     int64   `json:"count" example:"34"`
+	AvgLength float64 `json:"avgLength" example:"8.5"`
+	MinLength int     `json:"minLength" example:"3"`
+	MaxLength int     `json:"maxLength" example:"20"`
+}
+
+// URLInfoResponse is the full metadata of a short URL, as returned without redirecting
+type URLInfoResponse struct {
+	URLResponse
+	Tags         []string `json:"tags"`
+	HealthStatus string   `json:"healthStatus" enums:"unknown,healthy,dead"`
+	Enabled      bool     `json:"enabled" example:"true"` // false while the URL is disabled
+	GoalReached  bool     `json:"goalReached"`            // whether the clicks reached clickGoal
+	// LastAccessedAt is the time of the latest click, omitted until the URL is first clicked
+	LastAccessedAt *time.Time `json:"lastAccessedAt,omitempty"`
 }
 
 func (s *URLService) CreateShortURL(ctx context.Context, req CreateURLRequest, baseURL string) (*URLResponse, error) {
+	createdURL, response, err := s.createShortURL(ctx, req, CreateURLSettings{}, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheNewURL(ctx, createdURL)
+	s.fetchTitle(ctx, createdURL)
+	return response, nil
+}
+
+// CreateShortURLV2 creates a URL from a version 2 request, answering with its metadata
+func (s *URLService) CreateShortURLV2(ctx context.Context, req CreateURLRequestV2, baseURL string) (*URLInfoResponse, error) {
+	createdURL, response, err := s.createShortURL(ctx, req.CreateURLRequest, req.CreateURLSettings, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheNewURL(ctx, createdURL)
+	s.fetchTitle(ctx, createdURL)
+	info := NewURLInfoResponse(createdURL, baseURL)
+	// Keeps the signed token in place of the plain short code
+	info.URLResponse = *response
+	return info, nil
+}
+
+func (s *URLService) cacheNewURL(ctx context.Context, url *domain.URL) {
+	if err := s.cache.Set(ctx, url, s.cacheTTL); err != nil {
+		s.logger.Warn("Failed to cache new URL", "short_code", url.ShortCode, "error", err)
+	}
+}
+
+// createShortURL validates and stores a new URL without caching it, leaving bulk callers
+// free to cache their URLs together
+func (s *URLService) createShortURL(ctx context.Context, req CreateURLRequest, settings CreateURLSettings, baseURL string) (_ *domain.URL, _ *URLResponse, err error) {
+	start := time.Now()
+	defer func() {
+		s.metrics.RecordCreateDuration(time.Since(start).Seconds(), err == nil)
+	}()
+
+	url, err := s.newURL(ctx, req, settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	if url.IsCustomAlias {
+		unlock, err := s.lockAlias(ctx, url.Namespace, url.ShortCode)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer unlock()
+	}
+
+	createdURL, err := s.storeURL(ctx, url, !url.IsCustomAlias)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.runPostCreateHooks(ctx, createdURL)
+	s.metrics.RecordURLCreated(createdURL.Namespace)
+	s.metrics.IncActiveURLs()
+
+	return createdURL, s.newCreatedResponse(createdURL, req, baseURL), nil
+}
+
+// DryRunCreate checks a creation as CreateShortURL would and answers with the URL it would
+// create, without storing or caching it. The short code is free when checked, but may be
+// taken by the time the URL is actually created.
+func (s *URLService) DryRunCreate(ctx context.Context, req CreateURLRequest, baseURL string) (*URLResponse, error) {
+	url, err := s.dryRunCreate(ctx, req, CreateURLSettings{})
+	if err != nil {
+		return nil, err
+	}
+	response := s.newCreatedResponse(url, req, baseURL)
+	response.DryRun = true
+	return response, nil
+}
+
+// DryRunCreateV2 is DryRunCreate for a version 2 request
+func (s *URLService) DryRunCreateV2(ctx context.Context, req CreateURLRequestV2, baseURL string) (*URLInfoResponse, error) {
+	url, err := s.dryRunCreate(ctx, req.CreateURLRequest, req.CreateURLSettings)
+	if err != nil {
+		return nil, err
+	}
+	info := NewURLInfoResponse(url, baseURL)
+	info.URLResponse = *s.newCreatedResponse(url, req.CreateURLRequest, baseURL)
+	info.DryRun = true
+	return info, nil
+}
+
+// dryRunCreate builds the URL a creation would store, under a short code found free
+func (s *URLService) dryRunCreate(ctx context.Context, req CreateURLRequest, settings CreateURLSettings) (*domain.URL, error) {
+	url, err := s.newURL(ctx, req, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		exists, err := s.repo.Exists(ctx, url.Namespace, url.ShortCode)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return url, nil
+		}
+		if url.IsCustomAlias || attempt == maxGenerateAttempts {
+			return nil, domain.ErrShortCodeExists
+		}
+		if url.ShortCode, err = s.codes.Generate(shortCodeLength); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newURL validates a creation request and builds the URL it creates, under a generated
+// short code unless the request has a custom alias
+func (s *URLService) newURL(ctx context.Context, req CreateURLRequest, settings CreateURLSettings) (*domain.URL, error) {
 	if err := s.validate.Struct(req); err != nil {
 		return nil, err
 	}
+	if err := s.validate.Struct(settings); err != nil {
+		return nil, err
+	}
+	if settings.ExpiresAt != nil && !settings.ExpiresAt.After(time.Now()) {
+		return nil, ErrExpiryInPast
+	}
+	if req.SignedExpiry != "" && len(s.signingSecret) == 0 {
+		return nil, ErrSigningDisabled
+	}
+	if req.DelaySeconds > int(s.maxDelay) {
+		return nil, fmt.Errorf("%w: delaySeconds must be at most %d", ErrDelayTooLong, s.maxDelay)
+	}
+
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = domain.DefaultNamespace
+	}
 
 	shortCode := req.CustomAlias
 	if shortCode == "" {
-		shortCode = generateShortCode()
+		generated, err := s.codes.Generate(shortCodeLength)
+		if err != nil {
+			return nil, err
+		}
+		shortCode = generated
+	} else {
+		if s.reserved.Contains(shortCode) {
+			return nil, domain.ErrReservedAlias
+		}
+	}
+
+	// A pooled URL keeps its first target as the nominal destination
+	originalURL := req.URL
+	if req.Pool != nil {
+		originalURL = req.Pool.Targets[0].URL
 	}
 
-	exists, err := s.repo.Exists(ctx, shortCode)
+	url, err := domain.NewURL(shortCode, originalURL)
 	if err != nil {
 		return nil, err
 	}
-	if exists {
+	url.Namespace = namespace
+	if req.Pool != nil {
+		pool := &domain.URLPool{}
+		for _, target := range req.Pool.Targets {
+			pool.Targets = append(pool.Targets, domain.PoolTarget{URL: target.URL, Weight: target.Weight})
+		}
+		url.PoolEnabled = true
+		url.Pool = pool
+	}
+	url.Signed = req.SignedExpiry != ""
+	url.IsCustomAlias = req.CustomAlias != ""
+	url.DelaySeconds = req.DelaySeconds
+	url.Description = req.Description
+	url.ClickGoal = req.ClickGoal
+	url.CreatedByIP = iputil.NormalizeIP(CreateURLContextFrom(ctx).CreatorIP)
+	url.Tags = settings.Tags
+	url.ExpiresAt = settings.ExpiresAt
+	url.RedirectType = settings.RedirectType
+	for _, variant := range req.Variants {
+		url.Variants = append(url.Variants, domain.URLVariant{OriginalURL: variant.URL, Weight: variant.Weight})
+	}
+	for _, route := range req.GeoRoutes {
+		url.GeoRoutes = append(url.GeoRoutes, domain.GeoRoute{CountryCode: route.CountryCode, DestinationURL: route.DestinationURL})
+	}
+	for _, route := range req.DeviceRoutes {
+		url.DeviceRoutes = append(url.DeviceRoutes, domain.DeviceRoute{DeviceType: route.DeviceType, DestinationURL: route.DestinationURL})
+	}
+
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		url.PasswordHash = string(hash)
+	}
+
+	if err := s.runPreCreateHooks(ctx, url); err != nil {
+		return nil, err
+	}
+	return url, nil
+
+}
+
+// newCreatedResponse builds the response to the creation of url by req, with the signed
+// token in place of the short code when req asked for one
+func (s *URLService) newCreatedResponse(url *domain.URL, req CreateURLRequest, baseURL string) *URLResponse {
+	response := NewURLResponse(url, baseURL)
+	if url.Signed {
+		// Validated when the URL was built
+		ttl, _ := time.ParseDuration(req.SignedExpiry)
+		expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+		response.ShortCode = shortcode.GenerateSigned(s.signingSecret, url.ShortCode, expiresAt)
+		response.ShortURL = buildShortURL(baseURL, url.Namespace, response.ShortCode)
+		response.ExpiresAt = &expiresAt
+	}
+	return response
+}
+
+// lockAlias keeps other instances from claiming the alias until unlock is called. An alias
+// already being claimed counts as taken. Without a working locker, the unique constraint of
+// the repository still settles concurrent claims.
+func (s *URLService) lockAlias(ctx context.Context, namespace, alias string) (func(), error) {
+	if s.locker == nil {
+		return func() {}, nil
+	}
+
+	unlock, ok, err := s.locker.TryLock(ctx, "alias:"+namespace+":"+alias, aliasLockTTL)
+	if err != nil {
+		s.logger.Warn("Failed to lock custom alias, creating it unlocked", "namespace", namespace, "short_code", alias, "error", err)
+		return func() {}, nil
+	}
+	if !ok {
 		return nil, domain.ErrShortCodeExists
 	}
+	return unlock, nil
+}
+
+// storeURL creates url under its short code. When generated, a short code found taken is
+// replaced by a new one, up to maxGenerateAttempts codes in all.
+func (s *URLService) storeURL(ctx context.Context, url *domain.URL, generated bool) (*domain.URL, error) {
+	for attempt := 1; ; attempt++ {
+		exists, err := s.repo.Exists(ctx, url.Namespace, url.ShortCode)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			createdURL, err := s.repo.Create(ctx, url)
+			// Another creation may have taken the short code since the check
+			if !errors.Is(err, domain.ErrShortCodeExists) {
+				return createdURL, err
+			}
+		}
+
+		if !generated || attempt == maxGenerateAttempts {
+			return nil, domain.ErrShortCodeExists
+		}
+		s.logger.Debug("Generated short code taken, retrying", "namespace", url.Namespace, "short_code", url.ShortCode, "attempt", attempt)
+
+		if url.ShortCode, err = s.codes.Generate(shortCodeLength); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// NewURLResponse builds the public representation of a URL, with the creator address
+// masked. Short URLs outside the default namespace carry the namespace as a path prefix.
+func NewURLResponse(url *domain.URL, baseURL string) *URLResponse {
+	var variants []Variant
+	for _, variant := range url.Variants {
+		variants = append(variants, Variant{URL: variant.OriginalURL, Weight: variant.Weight})
+	}
+
+	var pool *Pool
+	if url.PoolEnabled && url.Pool != nil {
+		pool = &Pool{}
+		for _, target := range url.Pool.Targets {
+			pool.Targets = append(pool.Targets, PoolTarget{URL: target.URL, Weight: target.Weight})
+		}
+	}
+
+	var geoRoutes []GeoRoute
+	for _, route := range url.GeoRoutes {
+		geoRoutes = append(geoRoutes, GeoRoute{CountryCode: route.CountryCode, DestinationURL: route.DestinationURL})
+	}
+
+	var deviceRoutes []DeviceRoute
+	for _, route := range url.DeviceRoutes {
+		deviceRoutes = append(deviceRoutes, DeviceRoute{DeviceType: route.DeviceType, DestinationURL: route.DestinationURL})
+	}
+
+	return &URLResponse{
+		ID:           url.ID,
+		ShortURL:     buildShortURL(baseURL, url.Namespace, url.ShortCode),
+		Namespace:    url.Namespace,
+		ShortCode:    url.ShortCode,
+		OriginalURL:  url.OriginalURL,
+		Clicks:       url.Clicks,
+		UniqueClicks: url.UniqueClicks,
+		CreatedAt:    url.CreatedAt,
+		UpdatedAt:    url.UpdatedAt,
+		Protected:    url.IsPasswordProtected(),
+		RedirectType: url.EffectiveRedirectType(),
+		DelaySeconds: url.DelaySeconds,
+		ExpiresAt:    url.ExpiresAt,
+		Variants:     variants,
+		Pool:         pool,
+		GeoRoutes:    geoRoutes,
+		DeviceRoutes: deviceRoutes,
+		Description:  url.Description,
+		ClickGoal:    url.ClickGoal,
+		CreatedByIP:  iputil.MaskIP(url.CreatedByIP),
+		Title:        url.Title,
+	}
+}
+
+// NewURLInfoResponse builds the metadata response of a short URL
+func NewURLInfoResponse(url *domain.URL, baseURL string) *URLInfoResponse {
+	status := url.HealthStatus
+	if status == "" {
+		status = domain.HealthStatusUnknown
+	}
+
+	tags := append([]string{}, url.Tags...)
+
+	return &URLInfoResponse{
+		URLResponse:    *NewURLResponse(url, baseURL),
+		Tags:           tags,
+		HealthStatus:   status,
+		Enabled:        url.Enabled,
+		GoalReached:    url.GoalReached,
+		LastAccessedAt: url.LastAccessedAt,
+	}
+}
+
+func buildShortURL(baseURL, namespace, shortCode string) string {
+	if namespace != "" && namespace != domain.DefaultNamespace {
+		return baseURL + "/" + namespace + "/" + shortCode
+	}
+	return baseURL + "/" + shortCode
+}
+
+// ResolveURL resolves a short code as presented by a client. Signed tokens are checked
+// against their signature and expiry before any lookup; URLs created as signed are not
+// reachable by their plain code.
+func (s *URLService) ResolveURL(ctx context.Context, namespace, code string) (*domain.URL, error) {
+	if !shortcode.IsSigned(code) {
+		url, err := s.GetURL(ctx, namespace, code)
+		if err != nil {
+			return nil, err
+		}
+		if url.Signed {
+			return nil, domain.ErrURLNotFound
+		}
+		return url, nil
+	}
+
+	if len(s.signingSecret) == 0 {
+		return nil, domain.ErrURLNotFound
+	}
+	shortCode, ok := shortcode.VerifySigned(s.signingSecret, code)
+	if !ok {
+		return nil, domain.ErrURLNotFound
+	}
+	return s.GetURL(ctx, namespace, shortCode)
+}
+
+// GetURL resolves shortCode within namespace
+func (s *URLService) GetURL(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
+	start := time.Now()
+	url, err := s.getURL(ctx, namespace, shortCode)
+	s.metrics.RecordGetDuration(time.Since(start).Seconds(), err == nil)
+	return url, err
+}
+
+func (s *URLService) getURL(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
+	// The cache only calls fetch on a miss. The read runs detached from ctx since its result
+	// is shared with every caller that joins it, which must not fail because the first
+	// caller went away.
+	var fetched, shared bool
+	lookupCtx := context.WithoutCancel(ctx)
+	url, err := s.cache.GetOrSet(ctx, namespace, shortCode, s.cacheTTL, func() (*domain.URL, error) {
+		fetched = true
+		url, joined, err := s.lookups.Do(ctx, namespace+"/"+shortCode, func() (*domain.URL, error) {
+			return s.repo.FindByNamespaceAndCode(lookupCtx, namespace, shortCode)
+		})
+		shared = joined
+		return url, err
+	})
+
+	if fetched {
+		s.cacheMisses.Add(1)
+	} else if err == nil {
+		s.logger.Debug("Cache hit", "namespace", namespace, "short_code", shortCode)
+		s.cacheHits.Add(1)
+	}
 
-	url, err := domain.NewURL(shortCode, req.URL)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, domain.ErrURLNotFound) || ctx.Err() != nil {
+			return nil, err
+		}
+		return s.getStaleURL(ctx, namespace, shortCode, err)
 	}
 
-	createdURL, err := s.repo.Create(ctx, url)
+	if shared {
+		copied := *url
+		url = &copied
+	}
+	return url, nil
+}
+
+// getStaleURL falls back to the stale cache when the repository failed with repoErr, which
+// is returned unchanged when no stale entry exists
+func (s *URLService) getStaleURL(ctx context.Context, namespace, shortCode string, repoErr error) (*domain.URL, error) {
+	url, err := s.cache.GetStale(ctx, namespace, shortCode)
 	if err != nil {
+		s.logger.Warn("Cache error during stale get", "namespace", namespace, "short_code", shortCode, "error", err)
+	}
+	if url == nil {
+		return nil, repoErr
+	}
+
+	s.logger.Warn("Serving stale cached URL, repository unavailable", "namespace", namespace, "short_code", shortCode, "error", repoErr)
+	url.Stale = true
+	return url, nil
+}
+
+// SuggestAliases proposes up to three unused aliases in the default namespace, derived
+// from the <title> of the page at originalURL. Numeric suffixes are added when the plain
+// slug is taken, and candidates the alias policy refuses are skipped. A page that cannot
+// be fetched or has no usable title yields no suggestions rather than an error. URLs
+// CheckURLSafety refuses are not fetched, and fail with its error.
+func (s *URLService) SuggestAliases(ctx context.Context, originalURL string) ([]string, error) {
+	parsed, err := neturl.Parse(originalURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, domain.ErrInvalidURL
+	}
+	// The page is fetched by the server, so it must not be one only the server can reach
+	if err := CheckURLSafety(originalURL); err != nil {
 		return nil, err
 	}
 
-	if err := s.cache.Set(ctx, createdURL, s.cacheTTL); err != nil {
-		s.logger.Warn("Failed to cache new URL", "short_code", createdURL.ShortCode, "error", err)
+	suggestions := []string{}
+
+	title, err := urlfetch.FetchTitle(ctx, s.fetchClient, originalURL)
+	if err != nil {
+		s.logger.Info("Could not fetch page title for alias suggestions", "url", originalURL, "error", err)
+		return suggestions, nil
 	}
 
-	return &URLResponse{
-		ID:          createdURL.ID,
-		ShortURL:    baseURL + "/" + shortCode,
-		ShortCode:   shortCode,
-		OriginalURL: createdURL.OriginalURL,
-		Clicks:      createdURL.Clicks,
-		CreatedAt:   createdURL.CreatedAt,
-		UpdatedAt:   createdURL.UpdatedAt,
-	}, nil
+	slug := urlfetch.Slugify(title)
+	if len(slug) < minAliasLength {
+		return suggestions, nil
+	}
+
+	for i := 1; i <= maxSuggestionTries && len(suggestions) < maxAliasSuggestions; i++ {
+		candidate := slug
+		if i > 1 {
+			candidate += strconv.Itoa(i)
+		}
+
+		exists, err := s.repo.Exists(ctx, domain.DefaultNamespace, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if !exists && s.aliases.Check(candidate) == nil {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+
+	return suggestions, nil
+}
+
+// ListURLs returns a page of URLs in ID order, starting after afterID
+func (s *URLService) ListURLs(ctx context.Context, afterID int64, limit int) ([]*domain.URL, error) {
+	return s.repo.List(ctx, afterID, limit)
 }
 
-func (s *URLService) GetURL(ctx context.Context, shortCode string) (*domain.URL, error) {
-	cachedURL, err := s.cache.Get(ctx, shortCode)
+// ListURLsByCreatorIP returns a page of the URLs created from ip, in ID order, starting
+// after afterID
+func (s *URLService) ListURLsByCreatorIP(ctx context.Context, ip string, afterID int64, limit int) ([]*domain.URL, error) {
+	return s.repo.ListByCreatorIP(ctx, iputil.NormalizeIP(ip), afterID, limit)
+}
+
+// GetColdURLs returns up to limit enabled URLs neither clicked nor created in the past
+// days, least recently active first. limit is capped at MaxColdURLs.
+func (s *URLService) GetColdURLs(ctx context.Context, days, limit int) ([]*domain.URL, error) {
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	return s.repo.FindNotAccessedSince(ctx, since, min(limit, MaxColdURLs))
+}
+
+// WarmCache caches the limit most clicked public URLs in a single round trip, so the first
+// redirects after a restart do not all reach the repository. It returns how many URLs
+// were cached.
+func (s *URLService) WarmCache(ctx context.Context, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	urls, err := s.repo.TopByClicks(ctx, limit)
 	if err != nil {
-		s.logger.Warn("Cache error during get", "short_code", shortCode, "error", err)
+		return 0, fmt.Errorf("failed to list the most clicked URLs: %w", err)
+	}
+	if len(urls) == 0 {
+		return 0, nil
+	}
+	if err := s.cache.SetMulti(ctx, urls, s.cacheTTL); err != nil {
+		return 0, fmt.Errorf("failed to cache the most clicked URLs: %w", err)
+	}
+	return len(urls), nil
+}
+
+// GetTopURLs returns the n most clicked URLs, most clicked first. n is capped at MaxTopURLs.
+// Disabled, signed and password protected URLs are left out.
+func (s *URLService) GetTopURLs(ctx context.Context, n int) ([]*domain.URL, error) {
+	return s.getTopURLs(ctx, min(n, MaxTopURLs))
+}
+
+// hidesDestination reports whether url must not be shown to clients that did not open it:
+// signed URLs are only reachable through their token, and protected ones with their password
+func hidesDestination(url *domain.URL) bool {
+	return url.Signed || url.IsPasswordProtected()
+}
+
+func (s *URLService) getTopURLs(ctx context.Context, n int) ([]*domain.URL, error) {
+	cachedURLs, err := s.cache.GetTopURLs(ctx, n)
+	if err != nil {
+		s.logger.Warn("Cache error during top URLs get", "n", n, "error", err)
 	}
 
 	// Cache hit
-	if cachedURL != nil {
-		s.logger.Debug("Cache hit", "short_code", shortCode)
-		return cachedURL, nil
+	if cachedURLs != nil {
+		s.logger.Debug("Cache hit", "top_urls", n)
+		return cachedURLs, nil
 	}
 
 	// Cache miss
-	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	urls, err := s.repo.TopByClicks(ctx, n)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.cache.Set(ctx, url, s.cacheTTL); err != nil {
-		s.logger.Warn("Failed to cache URL", "short_code", shortCode, "error", err)
+	if err := s.cache.SetTopURLs(ctx, n, urls, topURLsCacheTTL); err != nil {
+		s.logger.Warn("Failed to cache top URLs", "n", n, "error", err)
 	}
 
-	return url, nil
+	return urls, nil
 }
 
-func (s *URLService) IncrementClicks(ctx context.Context, shortCode string) (*domain.URL, error) {
-	url, err := s.repo.IncrementClicks(ctx, shortCode)
+// GetStats returns service wide counters
+func (s *URLService) GetStats(ctx context.Context) (*StatsResponse, error) {
+	now := time.Now()
+	stats, err := s.repo.Stats(ctx, now, now.Add(-statsWindow))
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.cache.Set(ctx, url, s.cacheTTL); err != nil {
-		s.logger.Warn("Failed to update cache after incrementing clicks", "short_code", shortCode, "error", err)
+	var hitRate float64
+	hits, misses := s.cacheHits.Load(), s.cacheMisses.Load()
+	if lookups := hits + misses; lookups > 0 {
+		hitRate = float64(hits) / float64(lookups)
+	}
+
+	response := &StatsResponse{
+		TotalURLs:        stats.TotalURLs,
+		TotalClicks:      stats.TotalClicks,
+		ActiveURLs:       stats.ActiveURLs,
+		URLsCreatedToday: stats.RecentURLs,
+		ClicksToday:      stats.RecentClicks,
+		CacheHitRate:     hitRate,
+	}
+	if reporter, ok := s.repo.(domain.MemoryStatsReporter); ok {
+		memory := reporter.MemoryStats()
+		response.Memory = &MemoryStatsResponse{Capacity: memory.Capacity, Size: memory.Size, Evictions: memory.Evictions}
+	}
+	return response, nil
+}
+
+// GetDailyStats counts the URLs created and clicks made on each UTC day from the day of
+// from to that of to, both included, with a zero count for the days without any
+func (s *URLService) GetDailyStats(ctx context.Context, from, to time.Time) ([]DailyCount, error) {
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from must not be after to", domain.ErrInvalidTimeRange)
+	}
+	days := int(to.Sub(from).Hours()/24) + 1
+	if days > MaxDailyStatsDays {
+		return nil, fmt.Errorf("%w: range must not exceed %d days", domain.ErrInvalidTimeRange, MaxDailyStatsDays)
+	}
+
+	activity, err := s.repo.DailyActivity(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]DailyCount, 0, days)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		count := DailyCount{Date: day.Format(time.DateOnly)}
+		if len(activity) > 0 && activity[0].Day.Equal(day) {
+			count.URLsCreated, count.Clicks = activity[0].URLsCreated, activity[0].Clicks
+			activity = activity[1:]
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+// GetAliasStats summarises the lengths of generated short codes and custom aliases
+func (s *URLService) GetAliasStats(ctx context.Context) (*AliasStatsResponse, error) {
+	counts, err := s.repo.AliasLengths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &AliasStatsResponse{Generated: GeneratedAliasStats{Distribution: map[int]int64{}}}
+	var generatedLength, customLength int64
+	for _, count := range counts {
+		if !count.Custom {
+			stats.Generated.Count += count.URLs
+			stats.Generated.Distribution[count.Length] += count.URLs
+			generatedLength += int64(count.Length) * count.URLs
+			continue
+		}
+
+		if stats.Custom.Count == 0 || count.Length < stats.Custom.MinLength {
+			stats.Custom.MinLength = count.Length
+		}
+		stats.Custom.MaxLength = max(stats.Custom.MaxLength, count.Length)
+		stats.Custom.Count += count.URLs
+		customLength += int64(count.Length) * count.URLs
 	}
 
+	if stats.Generated.Count > 0 {
+		stats.Generated.AvgLength = float64(generatedLength) / float64(stats.Generated.Count)
+	}
+	if stats.Custom.Count > 0 {
+		stats.Custom.AvgLength = float64(customLength) / float64(stats.Custom.Count)
+	}
+	return stats, nil
+}
+
+// NextPoolTarget returns the destination of the next redirect of a pooled URL, advancing
+// its round-robin position. Positions live in memory, so each instance of the service
+// balances its own share of the traffic. It returns false when the URL has no pool.
+func (s *URLService) NextPoolTarget(url *domain.URL) (domain.PoolTarget, bool) {
+	if !url.PoolEnabled || url.Pool == nil {
+		return domain.PoolTarget{}, false
+	}
+
+	counter, _ := s.poolCounters.LoadOrStore(url.ID, new(atomic.Uint64))
+	n := counter.(*atomic.Uint64).Add(1) - 1
+	return url.Pool.Target(n)
+}
+
+// ClickDetails describes the request behind a click, as seen by the redirect handler.
+// Empty fields are recorded as unknown.
+type ClickDetails struct {
+	Referer    string // raw Referer header, empty for direct traffic
+	Browser    string
+	OS         string
+	DeviceType string
+	VariantID  *int64 // variant the visitor was sent to, nil for single-destination URLs
+	TargetURL  string // pool target the visitor was sent to, empty for URLs without a pool
+	// RedirectDuration is how long the redirect took up to recording the click, zero when unmeasured
+	RedirectDuration time.Duration
+	// IP and UserAgent identify the visitor for deduplication, a click without IP is always
+	// unique. IP is normalised before use, so an IPv4-mapped address counts as plain IPv4.
+	IP        string
+	UserAgent string
+	Country   string // country the request came from, empty when unknown
+}
+
+// IncrementClicks counts a visit of shortCode within namespace and records it for analytics
+func (s *URLService) IncrementClicks(ctx context.Context, namespace, shortCode string, details ClickDetails) (*domain.URL, error) {
+	details.IP = iputil.NormalizeIP(details.IP)
+	unique := s.isUniqueClick(ctx, namespace, shortCode, details)
+
+	var url *domain.URL
+	if s.clicks != nil {
+		buffered, err := s.bufferClick(ctx, namespace, shortCode, unique)
+		if err != nil {
+			return nil, err
+		}
+		url = buffered
+	} else {
+		updated, err := s.repo.IncrementClicks(ctx, namespace, shortCode, unique)
+		if err != nil {
+			return nil, err
+		}
+		url = updated
+
+		if err := s.cache.Set(ctx, url, s.cacheTTL); err != nil {
+			s.logger.Warn("Failed to update cache after incrementing clicks", "short_code", shortCode, "error", err)
+		}
+		if err := s.cache.InvalidateTopURLs(ctx); err != nil {
+			s.logger.Warn("Failed to invalidate top URLs cache", "short_code", shortCode, "error", err)
+		}
+	}
+
+	clickedAt := time.Now().UTC()
+	click := &domain.Click{
+		Namespace:  url.Namespace,
+		ShortCode:  url.ShortCode,
+		ClickedAt:  clickedAt,
+		Referer:    domain.NormalizeReferer(details.Referer),
+		Browser:    valueOr(details.Browser, useragent.Unknown),
+		OS:         valueOr(details.OS, useragent.Unknown),
+		DeviceType: valueOr(details.DeviceType, useragent.DeviceUnknown),
+		VariantID:  details.VariantID,
+		TargetURL:  details.TargetURL,
+		IPAddress:  details.IP,
+		UserAgent:  details.UserAgent,
+		Country:    details.Country,
+	}
+	if details.RedirectDuration > 0 {
+		ms := float64(details.RedirectDuration) / float64(time.Millisecond)
+		click.RedirectDurationMs = &ms
+	}
+	if err := s.repo.RecordClick(ctx, click); err != nil {
+		s.logger.Warn("Failed to record click for analytics", "short_code", shortCode, "error", err)
+	}
+
+	s.broker.Publish(domain.ClickEvent{
+		Namespace: url.Namespace,
+		ShortCode: url.ShortCode,
+		Clicks:    url.Clicks,
+		ClickedAt: clickedAt,
+	})
+	s.checkClickGoal(ctx, url, clickedAt)
+
 	return url, nil
 }
 
-func generateShortCode() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const length = 6
+// checkClickGoal marks the goal of url reached once its clicks get there and publishes a
+// goal event. Only one caller can mark the goal in the repository, so the event goes out
+// once however many clicks, on however many instances, reach the goal together. With the
+// click buffer the clicks of url lag behind until they are flushed, and so does the goal.
+func (s *URLService) checkClickGoal(ctx context.Context, url *domain.URL, clickedAt time.Time) {
+	if url.ClickGoal == nil || url.GoalReached || url.Clicks < *url.ClickGoal {
+		return
+	}
+
+	reached, err := s.repo.MarkGoalReached(ctx, url.Namespace, url.ShortCode)
+	if err != nil {
+		s.logger.Warn("Failed to mark click goal reached", "short_code", url.ShortCode, "error", err)
+		return
+	}
+	url.GoalReached = true
+	if !reached {
+		return
+	}
+
+	// The cached copy was stored before the goal was marked
+	if err := s.cache.Delete(ctx, url.Namespace, url.ShortCode); err != nil {
+		s.logger.Warn("Failed to invalidate cache after reaching click goal", "short_code", url.ShortCode, "error", err)
+	}
+
+	s.broker.PublishGoalReached(domain.GoalReachedEvent{
+		Namespace: url.Namespace,
+		ShortCode: url.ShortCode,
+		ClickGoal: *url.ClickGoal,
+		Clicks:    url.Clicks,
+		ReachedAt: clickedAt,
+	})
+	s.logger.Info("Click goal reached", "namespace", url.Namespace, "short_code", url.ShortCode, "click_goal", *url.ClickGoal)
+}
+
+// bufferClick counts a click in the click buffer, which stores it and refreshes the cache
+// later. The URL is returned as last stored with this click added, leaving out the other
+// clicks still buffered.
+func (s *URLService) bufferClick(ctx context.Context, namespace, shortCode string, unique bool) (*domain.URL, error) {
+	stored, err := s.GetURL(ctx, namespace, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	s.clicks.Add(namespace, shortCode, unique)
+
+	url := *stored
+	url.Clicks++
+	if unique {
+		url.UniqueClicks++
+	}
+	now := time.Now().UTC()
+	url.LastAccessedAt = &now
+	return &url, nil
+}
+
+// isUniqueClick reports whether the visitor behind details has not clicked shortCode within
+// the deduplication window. Clicks count as unique when the deduplicator is unavailable.
+func (s *URLService) isUniqueClick(ctx context.Context, namespace, shortCode string, details ClickDetails) bool {
+	if s.dedup == nil || details.IP == "" {
+		return true
+	}
+
+	first, err := s.dedup.FirstVisit(ctx, namespace, shortCode, visitorHash(details.IP, details.UserAgent))
+	if err != nil {
+		s.logger.Warn("Failed to deduplicate click", "short_code", shortCode, "error", err)
+		return true
+	}
+	return first
+}
+
+// visitorHash identifies a visitor by IP and user agent without keeping either in clear
+func visitorHash(ip, userAgent string) string {
+	sum := sha256.Sum256([]byte(ip + "\x00" + userAgent))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ResetAnalytics zeroes the click counters of the URL under namespace and shortCode and
+// deletes its recorded clicks, keeping the URL itself. Its visitors count as new again.
+func (s *URLService) ResetAnalytics(ctx context.Context, namespace, shortCode string) error {
+	url, err := s.repo.FindByNamespaceAndCode(ctx, namespace, shortCode)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.ResetClicks(ctx, namespace, shortCode); err != nil {
+		return err
+	}
+
+	if err := s.cache.Delete(ctx, namespace, shortCode); err != nil {
+		s.logger.Warn("Failed to invalidate cache after analytics reset", "namespace", namespace, "short_code", shortCode, "error", err)
+	}
+	if err := s.cache.InvalidateTopURLs(ctx); err != nil {
+		s.logger.Warn("Failed to invalidate top URLs cache", "short_code", shortCode, "error", err)
+	}
+	if s.dedup != nil {
+		if err := s.dedup.Forget(ctx, namespace, shortCode); err != nil {
+			s.logger.Warn("Failed to reset click deduplication", "namespace", namespace, "short_code", shortCode, "error", err)
+		}
+	}
+
+	reset := *url
+	reset.Clicks, reset.UniqueClicks = 0, 0
+	s.audit(ctx, audit.OperationReset, url, &reset)
+	return nil
+}
+
+// SetURLEnabled suspends or restores the redirects of the URL under namespace and
+// shortCode, reporting whether that changed anything
+func (s *URLService) SetURLEnabled(ctx context.Context, namespace, shortCode string, enabled bool) (bool, error) {
+	url, err := s.repo.FindByNamespaceAndCode(ctx, namespace, shortCode)
+	if err != nil {
+		return false, err
+	}
+	if url.Enabled == enabled {
+		return false, nil
+	}
+
+	if err := s.repo.SetEnabled(ctx, namespace, shortCode, enabled); err != nil {
+		return false, err
+	}
+	if enabled {
+		s.metrics.IncActiveURLs()
+	} else {
+		s.metrics.IncURLsDisabled()
+		s.metrics.DecActiveURLs()
+	}
+
+	if err := s.cache.Delete(ctx, namespace, shortCode); err != nil {
+		s.logger.Warn("Failed to invalidate cache after enabling or disabling URL", "namespace", namespace, "short_code", shortCode, "error", err)
+	}
+	// Disabled URLs are left out of the top URLs
+	if err := s.cache.InvalidateTopURLs(ctx); err != nil {
+		s.logger.Warn("Failed to invalidate top URLs cache", "error", err)
+	}
+
+	operation := audit.OperationEnable
+	if !enabled {
+		operation = audit.OperationDisable
+	}
+	changed := *url
+	changed.Enabled = enabled
+	s.audit(ctx, operation, url, &changed)
+	return true, nil
+}
+
+// GetClickTimeSeries returns the clicks on shortCode within namespace between from and to,
+// inclusive, grouped into buckets of the given granularity
+func (s *URLService) GetClickTimeSeries(ctx context.Context, namespace, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.TimeBucket, error) {
+	if _, err := domain.ParseGranularity(string(granularity)); err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from must not be after to", domain.ErrInvalidTimeRange)
+	}
+	if to.Sub(from) > domain.MaxTimeSeriesRange {
+		return nil, fmt.Errorf("%w: range must not exceed %d days", domain.ErrInvalidTimeRange, int(domain.MaxTimeSeriesRange.Hours()/24))
+	}
+
+	return s.repo.ClickTimeSeries(ctx, namespace, shortCode, granularity, from, to)
+}
+
+// GetClickHeatmap counts the clicks on shortCode by hour of the day and day of the week in loc
+func (s *URLService) GetClickHeatmap(ctx context.Context, namespace, shortCode string, loc *time.Location) (*domain.ClickHeatmap, error) {
+	return s.repo.ClickHeatmap(ctx, namespace, shortCode, loc)
+}
+
+// ListClicks returns up to limit recorded clicks of shortCode after the given cursor, oldest first
+func (s *URLService) ListClicks(ctx context.Context, namespace, shortCode string, after domain.ClickCursor, limit int) ([]*domain.Click, error) {
+	return s.repo.ListClicks(ctx, namespace, shortCode, after, limit)
+}
+
+// GetTopIPs returns the client IP addresses that made the most clicks across every URL,
+// busiest first
+func (s *URLService) GetTopIPs(ctx context.Context, limit int) ([]domain.IPCount, error) {
+	return s.repo.TopIPs(ctx, limit)
+}
+
+// GetTopReferrers returns the referrers that sent the most clicks to shortCode, busiest first
+func (s *URLService) GetTopReferrers(ctx context.Context, namespace, shortCode string, limit int) ([]domain.ReferrerCount, error) {
+	return s.repo.TopReferrers(ctx, namespace, shortCode, limit)
+}
+
+// GetDeviceStats breaks down the clicks on shortCode by device, browser or operating system
+func (s *URLService) GetDeviceStats(ctx context.Context, namespace, shortCode string, grouping domain.DeviceGrouping) ([]domain.DeviceStat, error) {
+	return s.repo.DeviceStats(ctx, namespace, shortCode, grouping)
+}
+
+// GetRedirectLatency summarises how long redirects of shortCode took
+func (s *URLService) GetRedirectLatency(ctx context.Context, namespace, shortCode string) (*domain.LatencyStats, error) {
+	return s.repo.RedirectLatency(ctx, namespace, shortCode)
+}
+
+// GetVariantStats counts the clicks sent to each variant of shortCode, in creation order
+func (s *URLService) GetVariantStats(ctx context.Context, namespace, shortCode string) ([]domain.VariantStat, error) {
+	return s.repo.VariantStats(ctx, namespace, shortCode)
+}
+
+// SubscribeClicks streams click events for shortCode until the returned function is called
+func (s *URLService) SubscribeClicks(namespace, shortCode string) (<-chan domain.ClickEvent, func(), error) {
+	return s.broker.Subscribe(namespace, shortCode)
+}
+
+// audit records a successful mutation turning before into after, either of which is nil
+// when the URL did not exist. Failures are logged rather than returned because the change
+// has already been committed.
+func (s *URLService) audit(ctx context.Context, operation string, before, after *domain.URL) {
+	url := after
+	if url == nil {
+		url = before
+	}
+	err := s.auditLogger.Log(ctx, audit.Entry{
+		Operation:   operation,
+		Namespace:   url.Namespace,
+		ShortCode:   url.ShortCode,
+		OriginalURL: url.OriginalURL,
+	})
+	if err != nil {
+		s.logger.Error("Failed to write audit entry", "operation", operation, "short_code", url.ShortCode, "error", err)
+	}
+
+	if s.auditLog == nil {
+		return
+	}
+	if err := s.auditLog.Record(ctx, newAuditEntry(ctx, operation, before, after)); err != nil {
+		s.logger.Error("Failed to record audit entry", "operation", operation, "short_code", url.ShortCode, "error", err)
+	}
+}
+
+// VerifyPassword checks the supplied passphrase against a protected URL.
+// Public URLs always pass. The comparison is delegated to bcrypt, which runs in constant time.
+func (s *URLService) VerifyPassword(url *domain.URL, password string) error {
+	if !url.IsPasswordProtected() {
+		return nil
+	}
+	if password == "" {
+		return domain.ErrInvalidPassword
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return domain.ErrInvalidPassword
+		}
+		return fmt.Errorf("failed to verify password: %w", err)
+	}
+
+	return nil
+}
 
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[time.Now().UnixNano()%int64(len(charset))]
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
 	}
-	return string(b)
+	return value
 }
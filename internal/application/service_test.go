@@ -1,26 +1,43 @@
 package application
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/sp3dr4/dove/internal/domain"
 	"github.com/sp3dr4/dove/internal/infrastructure/cache"
 	"github.com/sp3dr4/dove/internal/infrastructure/memory"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
 )
 
 // TestURLService_ShortCodeGeneration tests the short code generation algorithm
 func TestURLService_ShortCodeGeneration(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	repo := memory.NewURLRepository(logger)
+	repo := memory.NewURLRepository(logger, 0)
 	noopCache := cache.NewNoOpCache()
-	service := NewURLService(repo, noopCache, 10*time.Minute, logger)
+	service := NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
 	ctx := context.Background()
 
 	// Test that generated short codes are unique and have correct length
@@ -51,9 +68,9 @@ func TestURLService_ShortCodeGeneration(t *testing.T) {
 // TestURLService_CustomAliasValidation tests custom alias validation logic
 func TestURLService_CustomAliasValidation(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	repo := memory.NewURLRepository(logger)
+	repo := memory.NewURLRepository(logger, 0)
 	noopCache := cache.NewNoOpCache()
-	service := NewURLService(repo, noopCache, 10*time.Minute, logger)
+	service := NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
 	ctx := context.Background()
 
 	tests := []struct {
@@ -128,3 +145,1452 @@ func TestURLService_CustomAliasValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestURLService_CustomAliasPolicy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ctx := context.Background()
+	hyphens := &AliasPolicy{MaxLength: 8, AllowHyphens: true, ReservedWords: []string{"promo"}}
+	underscores := &AliasPolicy{AllowUnderscores: true}
+
+	tests := []struct {
+		name        string
+		policy      *AliasPolicy
+		customAlias string
+		wantErr     error
+	}{
+		{name: "hyphens disallowed by default", customAlias: "my-alias", wantErr: ErrAliasInvalidChars},
+		{name: "hyphens allowed", policy: hyphens, customAlias: "my-alias"},
+		{name: "underscores disallowed", policy: hyphens, customAlias: "my_alias", wantErr: ErrAliasInvalidChars},
+		{name: "underscores allowed", policy: underscores, customAlias: "my_alias"},
+		{name: "configured maximum length", policy: hyphens, customAlias: strings.Repeat("a", 8)},
+		{name: "beyond configured maximum length", policy: hyphens, customAlias: strings.Repeat("a", 9), wantErr: ErrAliasTooLong},
+		{name: "default maximum length", policy: underscores, customAlias: strings.Repeat("a", DefaultMaxAliasLength+1), wantErr: ErrAliasTooLong},
+		{name: "reserved admin", policy: hyphens, customAlias: "admin", wantErr: ErrAliasReserved},
+		{name: "reserved api", policy: hyphens, customAlias: "API", wantErr: ErrAliasReserved},
+		{name: "configured reserved word ignoring case", policy: hyphens, customAlias: "Promo", wantErr: ErrAliasReserved},
+		{name: "signed token prefix", policy: underscores, customAlias: "s_campaign", wantErr: ErrAliasReserved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewURLService(memory.NewURLRepository(logger, 0), cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{Aliases: tt.policy}, logger)
+
+			assert.ErrorIs(t, service.AliasPolicy().Check(tt.customAlias), tt.wantErr)
+
+			_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: tt.customAlias}, "http://localhost:8080")
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			var validationErrors validator.ValidationErrors
+			require.ErrorAs(t, err, &validationErrors)
+			require.Len(t, validationErrors, 1)
+			assert.Equal(t, "customalias", validationErrors[0].Tag())
+		})
+	}
+}
+
+// TestURLService_CreateShortURL_WritesAuditEntry tests that successful creations are audited
+func TestURLService_CreateShortURL_WritesAuditEntry(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	var auditBuf bytes.Buffer
+	service := NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(&auditBuf), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+	ctx := logging.WithRequestID(context.Background(), "req-42")
+	ctx = logging.WithClientIP(ctx, "192.0.2.10")
+
+	_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "audited"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	// A rejected request must not produce an entry
+	_, err = service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "audited"}, "http://localhost:8080")
+	require.Error(t, err)
+
+	lines := strings.Split(strings.TrimSpace(auditBuf.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var entry audit.Entry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, audit.OperationCreate, entry.Operation)
+	assert.Equal(t, "audited", entry.ShortCode)
+	assert.Equal(t, "https://example.com", entry.OriginalURL)
+	assert.Equal(t, "192.0.2.10", entry.ActorIP)
+	assert.Equal(t, "req-42", entry.RequestID)
+	assert.False(t, entry.Timestamp.IsZero())
+}
+
+func TestURLService_AuditLog(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := NewURLService(memory.NewURLRepository(logger, 0), cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{AuditLog: memory.NewAuditRepository()}, logger)
+	require.True(t, service.AuditLogEnabled())
+
+	ctx := logging.WithRequestID(context.Background(), "req-7")
+	ctx = logging.WithClientIP(ctx, "192.0.2.10")
+
+	_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com/old", CustomAlias: "history", Password: "s3cret"}, "http://localhost:8080")
+	require.NoError(t, err)
+	newURL := "https://example.com/new"
+	_, err = service.PatchURL(ctx, domain.DefaultNamespace, "history", PatchURLRequest{OriginalURL: &newURL}, "http://localhost:8080")
+	require.NoError(t, err)
+	_, err = service.BulkDeleteURLs(ctx, domain.DefaultNamespace, BulkDeleteRequest{ShortCodes: []string{"history"}})
+	require.NoError(t, err)
+
+	entries, err := service.GetAuditLog(ctx, domain.DefaultNamespace, "history", DefaultAuditLogLimit)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	originalURL := func(value json.RawMessage) string {
+		var url domain.URL
+		require.NoError(t, json.Unmarshal(value, &url))
+		assert.Empty(t, url.PasswordHash, "password hashes stay out of the history")
+		return url.OriginalURL
+	}
+	assert.Equal(t, []string{audit.OperationDelete, audit.OperationUpdate, audit.OperationCreate},
+		[]string{entries[0].Operation, entries[1].Operation, entries[2].Operation}, "most recent first")
+
+	assert.Equal(t, "https://example.com/new", originalURL(entries[0].OldValue))
+	assert.Nil(t, entries[0].NewValue)
+	assert.Equal(t, "https://example.com/old", originalURL(entries[1].OldValue))
+	assert.Equal(t, "https://example.com/new", originalURL(entries[1].NewValue))
+	assert.Nil(t, entries[2].OldValue)
+	assert.Equal(t, "https://example.com/old", originalURL(entries[2].NewValue))
+
+	for _, entry := range entries {
+		assert.Equal(t, "192.0.2.10", entry.ActorIP)
+		assert.Equal(t, "req-7", entry.RequestID)
+		assert.Equal(t, "history", entry.ShortCode)
+	}
+
+	limited, err := service.GetAuditLog(ctx, domain.DefaultNamespace, "history", 1)
+	require.NoError(t, err)
+	require.Len(t, limited, 1)
+	assert.Equal(t, audit.OperationDelete, limited[0].Operation)
+}
+
+func TestURLService_CreateHooks(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	var auditBuf bytes.Buffer
+	service := NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(&auditBuf), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+	ctx := context.Background()
+
+	var calls []string
+	record := func(name string, err error) CreateHook {
+		return func(_ context.Context, url *domain.URL) error {
+			calls = append(calls, name+":"+url.ShortCode)
+			return err
+		}
+	}
+	rejectPromo := func(_ context.Context, url *domain.URL) error {
+		calls = append(calls, "reject:"+url.ShortCode)
+		if url.ShortCode == "promo" {
+			return fmt.Errorf("%w: no promos", ErrCreateRejected)
+		}
+		return nil
+	}
+	service.AddPreCreateHook(record("pre1", nil))
+	service.AddPreCreateHook(rejectPromo)
+	service.AddPostCreateHook(func(_ context.Context, url *domain.URL) error {
+		assert.NotZero(t, url.ID, "post-create hooks see the stored URL")
+		calls = append(calls, "post1:"+url.ShortCode)
+		return errors.New("webhook down")
+	})
+	service.AddPostCreateHook(record("post2", nil))
+
+	t.Run("run in order", func(t *testing.T) {
+		calls = nil
+		_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "hooked"}, "http://localhost:8080")
+		require.NoError(t, err, "post-create hook errors do not fail the creation")
+		assert.Equal(t, []string{"pre1:hooked", "reject:hooked", "post1:hooked", "post2:hooked"}, calls)
+	})
+
+	t.Run("pre-create hook error aborts the creation", func(t *testing.T) {
+		calls = nil
+		auditBuf.Reset()
+		_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "promo"}, "http://localhost:8080")
+		require.ErrorIs(t, err, ErrCreateRejected)
+		assert.Equal(t, []string{"pre1:promo", "reject:promo"}, calls)
+
+		exists, err := repo.Exists(ctx, domain.DefaultNamespace, "promo")
+		require.NoError(t, err)
+		assert.False(t, exists)
+		assert.Empty(t, auditBuf.String(), "a rejected URL is not audited")
+	})
+
+	t.Run("invalid requests never reach the hooks", func(t *testing.T) {
+		calls = nil
+		_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "not a url"}, "http://localhost:8080")
+		require.Error(t, err)
+		assert.Empty(t, calls)
+	})
+}
+
+func TestBlacklistHook(t *testing.T) {
+	hook := BlacklistHook([]string{"Evil.example", "spam.test."})
+
+	tests := []struct {
+		name    string
+		url     *domain.URL
+		blocked bool
+	}{
+		{name: "allowed", url: &domain.URL{OriginalURL: "https://example.com/evil.example"}},
+		{name: "blocked domain", url: &domain.URL{OriginalURL: "https://evil.example/login"}, blocked: true},
+		{name: "subdomain ignoring case", url: &domain.URL{OriginalURL: "https://WWW.Evil.Example:8443/"}, blocked: true},
+		{name: "lookalike", url: &domain.URL{OriginalURL: "https://notevil.example"}},
+		{name: "trailing dot", url: &domain.URL{OriginalURL: "https://spam.test./"}, blocked: true},
+		{
+			name:    "variant",
+			url:     &domain.URL{OriginalURL: "https://example.com", Variants: []domain.URLVariant{{OriginalURL: "https://spam.test/b", Weight: 50}}},
+			blocked: true,
+		},
+		{
+			name:    "device route",
+			url:     &domain.URL{OriginalURL: "https://example.com", DeviceRoutes: []domain.DeviceRoute{{DeviceType: "mobile", DestinationURL: "https://m.evil.example"}}},
+			blocked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := hook(context.Background(), tt.url)
+			if tt.blocked {
+				assert.ErrorIs(t, err, ErrCreateRejected)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWebhookDispatchHook(t *testing.T) {
+	var received CreateWebhookEvent
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	hook := WebhookDispatchHook(server.Client(), server.URL)
+	url := &domain.URL{Namespace: "team", ShortCode: "hooked", OriginalURL: "https://example.com", CreatedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+
+	require.NoError(t, hook(context.Background(), url))
+	assert.Equal(t, CreateWebhookEvent{Event: "url.created", Namespace: "team", ShortCode: "hooked", OriginalURL: "https://example.com", CreatedAt: url.CreatedAt}, received)
+
+	status = http.StatusBadGateway
+	assert.Error(t, hook(context.Background(), url))
+}
+
+func TestURLService_SuggestAliases(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	noopCache := cache.NewNoOpCache()
+	service := NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/blog" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Golang Blog</title></head></html>`))
+	}))
+	t.Cleanup(server.Close)
+	// Pages on the loopback address are refused, so a public host is sent to the server
+	service.fetchClient = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+	blogURL := "http://blog.example.com"
+
+	suggestions, err := service.SuggestAliases(ctx, blogURL+"/blog")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"golangblog", "golangblog2", "golangblog3"}, suggestions)
+
+	// Aliases already in use are skipped
+	for _, alias := range []string{"golangblog", "golangblog3"} {
+		_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: alias}, "http://localhost:8080")
+		require.NoError(t, err)
+	}
+	suggestions, err = service.SuggestAliases(ctx, blogURL+"/blog")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"golangblog2", "golangblog4", "golangblog5"}, suggestions)
+
+	// Fetch failures are not errors
+	suggestions, err = service.SuggestAliases(ctx, blogURL+"/missing")
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+
+	for _, invalid := range []string{"", "not a url", "ftp://example.com/file", "/relative"} {
+		_, err = service.SuggestAliases(ctx, invalid)
+		assert.ErrorIs(t, err, domain.ErrInvalidURL, invalid)
+	}
+
+	// The server itself, and the networks only it can reach, are never fetched
+	for _, private := range []string{server.URL + "/blog", "http://localhost/blog", "http://169.254.169.254/latest/meta-data/", "http://10.0.0.1/"} {
+		_, err = service.SuggestAliases(ctx, private)
+		assert.ErrorIs(t, err, ErrPrivateIPURL, private)
+	}
+}
+
+// titleCache records the titles of the URLs cached
+type titleCache struct {
+	*cache.NoOpCache
+	mu     sync.Mutex
+	titles map[string]string
+}
+
+func (c *titleCache) Set(_ context.Context, url *domain.URL, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.titles[url.ShortCode] = url.Title
+	return nil
+}
+
+func (c *titleCache) title(shortCode string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.titles[shortCode]
+}
+
+func TestURLService_FetchTitles(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Plain title</title><meta property="og:title" content="Shared title"></head></html>`))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Plain title</title></head></html>`))
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	// Destinations on the loopback address are refused, so public hosts are sent to the server
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+
+	newService := func(fetchTitles FetchTitles) (*URLService, *memory.URLRepository, *titleCache) {
+		repo := memory.NewURLRepository(logger, 0)
+		urlCache := &titleCache{NoOpCache: cache.NewNoOpCache(), titles: make(map[string]string)}
+		service := NewURLService(repo, urlCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{FetchTitles: fetchTitles}, logger)
+		service.fetchClient = client
+		return service, repo, urlCache
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/article", expected: "Shared title"},
+		{path: "/plain", expected: "Plain title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			service, repo, urlCache := newService(true)
+
+			created, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "http://titles.example.com" + tt.path}, "http://localhost:8080")
+			require.NoError(t, err)
+
+			assert.Eventually(t, func() bool {
+				stored, err := repo.FindByNamespaceAndCode(ctx, created.Namespace, created.ShortCode)
+				return err == nil && stored.Title == tt.expected
+			}, time.Second, 10*time.Millisecond)
+			assert.Eventually(t, func() bool { return urlCache.title(created.ShortCode) == tt.expected }, time.Second, 10*time.Millisecond)
+
+			url, err := service.GetURL(ctx, created.Namespace, created.ShortCode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, NewURLResponse(url, "http://localhost:8080").Title)
+		})
+	}
+
+	t.Run("failed fetch leaves the title empty", func(t *testing.T) {
+		service, repo, _ := newService(true)
+
+		created, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "http://titles.example.com/missing"}, "http://localhost:8080")
+		require.NoError(t, err)
+		assert.Empty(t, created.Title)
+
+		assert.Never(t, func() bool {
+			stored, err := repo.FindByNamespaceAndCode(ctx, created.Namespace, created.ShortCode)
+			return err != nil || stored.Title != ""
+		}, 100*time.Millisecond, 10*time.Millisecond)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		service, repo, _ := newService(false)
+
+		created, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "http://titles.example.com/plain"}, "http://localhost:8080")
+		require.NoError(t, err)
+
+		assert.Never(t, func() bool {
+			stored, err := repo.FindByNamespaceAndCode(ctx, created.Namespace, created.ShortCode)
+			return err != nil || stored.Title != ""
+		}, 100*time.Millisecond, 10*time.Millisecond)
+	})
+}
+
+// countingRepository counts lookups and holds each one for delay, so concurrent callers
+// pile up behind the first
+type countingRepository struct {
+	domain.URLRepository
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (r *countingRepository) FindByNamespaceAndCode(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
+	r.calls.Add(1)
+	time.Sleep(r.delay)
+	return r.URLRepository.FindByNamespaceAndCode(ctx, namespace, shortCode)
+}
+
+func newCoalescingService(t testing.TB) (*URLService, *countingRepository) {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	memoryRepo := memory.NewURLRepository(logger, 0)
+	url, err := domain.NewURL("hot", "https://example.com/hot")
+	require.NoError(t, err)
+	_, err = memoryRepo.Create(context.Background(), url)
+	require.NoError(t, err)
+
+	repo := &countingRepository{URLRepository: memoryRepo, delay: 50 * time.Millisecond}
+	// The no-op cache makes every lookup a miss
+	return NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger), repo
+}
+
+// getConcurrently resolves the hot URL from n goroutines released at once
+func getConcurrently(t testing.TB, service *URLService, n int) []*domain.URL {
+	t.Helper()
+
+	results := make([]*domain.URL, n)
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = service.GetURL(context.Background(), domain.DefaultNamespace, "hot")
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	return results
+}
+
+func TestURLService_GetURL_CoalescesConcurrentMisses(t *testing.T) {
+	service, repo := newCoalescingService(t)
+
+	results := getConcurrently(t, service, 100)
+	assert.Equal(t, int32(1), repo.calls.Load())
+
+	// Callers share the lookup but not the URL value
+	for _, url := range results {
+		assert.Equal(t, "https://example.com/hot", url.OriginalURL)
+	}
+	results[0].OriginalURL = "https://example.com/changed"
+	assert.Equal(t, "https://example.com/hot", results[1].OriginalURL)
+
+	// Once the lookup completes, the next miss reads again
+	_, err := service.GetURL(context.Background(), domain.DefaultNamespace, "hot")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), repo.calls.Load())
+
+	t.Run("lookup errors are returned", func(t *testing.T) {
+		_, err := service.GetURL(context.Background(), domain.DefaultNamespace, "missing")
+		assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	})
+
+	t.Run("canceled caller does not fail the shared lookup", func(t *testing.T) {
+		calls := repo.calls.Load()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.GetURL(ctx, domain.DefaultNamespace, "hot")
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}()
+
+		url, err := service.GetURL(context.Background(), domain.DefaultNamespace, "hot")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/hot", url.OriginalURL)
+		wg.Wait()
+		assert.Equal(t, calls+1, repo.calls.Load())
+	})
+}
+
+func BenchmarkURLService_GetURL_Coalesced(b *testing.B) {
+	service, repo := newCoalescingService(b)
+	repo.delay = 5 * time.Millisecond
+
+	for b.Loop() {
+		getConcurrently(b, service, 100)
+	}
+
+	b.ReportMetric(float64(repo.calls.Load())/float64(b.N), "db_calls/op")
+	if calls := int(repo.calls.Load()); calls != b.N {
+		b.Fatalf("expected one repository call per 100 concurrent lookups, got %d calls for %d ops", calls, b.N)
+	}
+}
+
+// unavailableRepository fails every lookup as a database outage would
+type unavailableRepository struct {
+	domain.URLRepository
+}
+
+var errDatabaseDown = errors.New("dial tcp: connection refused")
+
+func (r *unavailableRepository) FindByNamespaceAndCode(context.Context, string, string) (*domain.URL, error) {
+	return nil, errDatabaseDown
+}
+
+func (r *unavailableRepository) FindByShortCodes(context.Context, string, []string) ([]*domain.URL, error) {
+	return nil, errDatabaseDown
+}
+
+func (r *unavailableRepository) TopByClicks(context.Context, int) ([]*domain.URL, error) {
+	return nil, errDatabaseDown
+}
+
+// staleCache misses every regular lookup and holds stale entries only
+type staleCache struct {
+	*cache.NoOpCache
+	stale map[string]*domain.URL
+}
+
+func (c *staleCache) GetStale(_ context.Context, namespace, shortCode string) (*domain.URL, error) {
+	url, ok := c.stale[namespace+"/"+shortCode]
+	if !ok {
+		return nil, nil
+	}
+	copied := *url
+	return &copied, nil
+}
+
+func TestURLService_GetURL_ServesStaleOnRepositoryError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	staleURL, err := domain.NewURL("cached", "https://example.com/cached")
+	require.NoError(t, err)
+
+	stale := &staleCache{NoOpCache: cache.NewNoOpCache(), stale: map[string]*domain.URL{domain.DefaultNamespace + "/cached": staleURL}}
+	service := NewURLService(&unavailableRepository{}, stale, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+	ctx := context.Background()
+
+	url, err := service.GetURL(ctx, domain.DefaultNamespace, "cached")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/cached", url.OriginalURL)
+	assert.True(t, url.Stale)
+
+	_, err = service.GetURL(ctx, domain.DefaultNamespace, "uncached")
+	assert.ErrorIs(t, err, errDatabaseDown)
+
+	t.Run("not found is not an outage", func(t *testing.T) {
+		repo := memory.NewURLRepository(logger, 0)
+		service := NewURLService(repo, stale, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+		_, err := service.GetURL(ctx, domain.DefaultNamespace, "cached")
+		assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	})
+}
+
+// hitCache hits for the short codes it holds and misses for every other one
+type hitCache struct {
+	*cache.NoOpCache
+	urls map[string]*domain.URL
+}
+
+func (c *hitCache) Get(_ context.Context, _, shortCode string) (*domain.URL, error) {
+	return c.urls[shortCode], nil
+}
+
+func (c *hitCache) GetOrSet(_ context.Context, _, shortCode string, _ time.Duration, fetch func() (*domain.URL, error)) (*domain.URL, error) {
+	if url, ok := c.urls[shortCode]; ok {
+		return url, nil
+	}
+	return fetch()
+}
+
+func TestURLService_GetStats(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	ctx := context.Background()
+
+	hot, err := domain.NewURL("hot", "https://example.com/hot")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, hot)
+	require.NoError(t, err)
+	cold, err := domain.NewURL("cold", "https://example.com/cold")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, cold)
+	require.NoError(t, err)
+
+	hits := &hitCache{NoOpCache: cache.NewNoOpCache(), urls: map[string]*domain.URL{"hot": hot}}
+	service := NewURLService(repo, hits, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+	stats, err := service.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.CacheHitRate, "no lookups yet")
+
+	for range 3 {
+		_, err := service.GetURL(ctx, domain.DefaultNamespace, "hot")
+		require.NoError(t, err)
+	}
+	_, err = service.GetURL(ctx, domain.DefaultNamespace, "cold")
+	require.NoError(t, err)
+
+	stats, err = service.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &StatsResponse{
+		TotalURLs:        2,
+		TotalClicks:      0,
+		ActiveURLs:       2,
+		URLsCreatedToday: 2,
+		ClicksToday:      0,
+		CacheHitRate:     0.75,
+		Memory:           &MemoryStatsResponse{Size: 2},
+	}, stats)
+}
+
+// batchCache counts single and batched cache writes, and hits batched reads for the short
+// codes it holds
+type batchCache struct {
+	*cache.NoOpCache
+	urls      map[string]*domain.URL
+	sets      int
+	setMultis [][]*domain.URL
+}
+
+func (c *batchCache) GetMulti(_ context.Context, _ string, shortCodes []string) (map[string]*domain.URL, error) {
+	urls := map[string]*domain.URL{}
+	for _, shortCode := range shortCodes {
+		if url, ok := c.urls[shortCode]; ok {
+			urls[shortCode] = url
+		}
+	}
+	return urls, nil
+}
+
+func (c *batchCache) Set(context.Context, *domain.URL, time.Duration) error {
+	c.sets++
+	return nil
+}
+
+func (c *batchCache) SetMulti(_ context.Context, urls []*domain.URL, _ time.Duration) error {
+	c.setMultis = append(c.setMultis, urls)
+	return nil
+}
+
+// countingRegistry counts the service metrics recorded to it
+type countingRegistry struct {
+	metrics.NoOpRegistry
+	created         map[string]int
+	active          int
+	disabled        int
+	createDurations map[bool]int
+	getDurations    map[bool]int
+}
+
+func newCountingRegistry() *countingRegistry {
+	return &countingRegistry{created: map[string]int{}, createDurations: map[bool]int{}, getDurations: map[bool]int{}}
+}
+
+func (r *countingRegistry) RecordURLCreated(namespace string) { r.created[namespace]++ }
+func (r *countingRegistry) IncActiveURLs()                    { r.active++ }
+func (r *countingRegistry) DecActiveURLs()                    { r.active-- }
+func (r *countingRegistry) IncURLsDisabled()                  { r.disabled++ }
+func (r *countingRegistry) RecordCreateDuration(duration float64, success bool) {
+	r.createDurations[success]++
+}
+func (r *countingRegistry) RecordGetDuration(duration float64, success bool) {
+	r.getDurations[success]++
+}
+
+func TestURLService_Metrics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	registry := newCountingRegistry()
+	service := NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{Metrics: registry}, logger)
+	ctx := context.Background()
+
+	_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "counted"}, "http://localhost:8080")
+	require.NoError(t, err)
+	_, err = service.CreateShortURLV2(ctx, CreateURLRequestV2{CreateURLRequest: CreateURLRequest{URL: "https://example.com", Namespace: "team"}}, "http://localhost:8080")
+	require.NoError(t, err)
+	_, err = service.CreateShortURL(ctx, CreateURLRequest{URL: "not a url"}, "http://localhost:8080")
+	require.Error(t, err)
+	_, err = service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "counted"}, "http://localhost:8080")
+	require.ErrorIs(t, err, domain.ErrShortCodeExists)
+
+	report, err := service.ImportURLs(ctx, []ImportEntry{
+		{Line: 1, Data: []byte(`{"url": "https://example.com/imported"}`)},
+		{Line: 2, Data: []byte(`{"url": "not a url"}`)},
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Succeeded)
+
+	assert.Equal(t, map[string]int{domain.DefaultNamespace: 2, "team": 1}, registry.created)
+	assert.Equal(t, 3, registry.active)
+	assert.Equal(t, map[bool]int{true: 3, false: 3}, registry.createDurations)
+
+	_, err = service.GetURL(ctx, domain.DefaultNamespace, "counted")
+	require.NoError(t, err)
+	_, err = service.GetURL(ctx, domain.DefaultNamespace, "missing")
+	require.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.Equal(t, map[bool]int{true: 1, false: 1}, registry.getDurations)
+
+	for range 2 {
+		_, err = service.SetURLEnabled(ctx, domain.DefaultNamespace, "counted", false)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, registry.disabled, "disabling a disabled URL is not counted")
+	assert.Equal(t, 2, registry.active)
+
+	_, err = service.SetURLEnabled(ctx, domain.DefaultNamespace, "counted", true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, registry.active)
+}
+
+func TestURLService_ImportURLs_CachesInOneBatch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	batches := &batchCache{NoOpCache: cache.NewNoOpCache()}
+	service := NewURLService(memory.NewURLRepository(logger, 0), batches, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+	entries := []ImportEntry{
+		{Line: 1, Data: []byte(`{"url": "https://example.com/1", "customAlias": "first"}`)},
+		{Line: 2, Data: []byte(`{"url": "not-a-url"}`)},
+		{Line: 3, Data: []byte(`{"url": "https://example.com/3", "customAlias": "third"}`)},
+	}
+	report, err := service.ImportURLs(context.Background(), entries, "http://localhost:8080")
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Succeeded)
+
+	assert.Zero(t, batches.sets)
+	require.Len(t, batches.setMultis, 1)
+	require.Len(t, batches.setMultis[0], 2)
+	assert.Equal(t, "first", batches.setMultis[0][0].ShortCode)
+	assert.Equal(t, "third", batches.setMultis[0][1].ShortCode)
+}
+
+// createFailingRepository fails every creation as a database outage would
+type createFailingRepository struct {
+	domain.URLRepository
+}
+
+func (r *createFailingRepository) Create(context.Context, *domain.URL) (*domain.URL, error) {
+	return nil, errDatabaseDown
+}
+
+func TestURLService_ImportURLs_HidesStoreErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := &createFailingRepository{URLRepository: memory.NewURLRepository(logger, 0)}
+	service := NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+	entries := []ImportEntry{
+		{Line: 1, Data: []byte(`{"url": "https://example.com/1"}`)},
+		{Line: 2, Data: []byte(`{"url": "https://example.com/2", "delaySeconds": 5}`)},
+	}
+	report, err := service.ImportURLs(context.Background(), entries, "http://localhost:8080")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, ImportError{Line: 1, Message: "failed to create URL"}, report.Errors[0])
+	assert.NotContains(t, report.Errors[0].Message, errDatabaseDown.Error())
+	assert.Contains(t, report.Errors[1].Message, "redirect delay too long")
+}
+
+func TestURLService_WarmCache(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	repo := memory.NewURLRepository(logger, 0)
+	for i, shortCode := range []string{"quiet", "busy", "busiest"} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		url.Clicks = i * 10
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	batches := &batchCache{NoOpCache: cache.NewNoOpCache()}
+	service := NewURLService(repo, batches, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+	warmed, err := service.WarmCache(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, warmed)
+	assert.Zero(t, batches.sets)
+	require.Len(t, batches.setMultis, 1)
+	require.Len(t, batches.setMultis[0], 2)
+	assert.Equal(t, "busiest", batches.setMultis[0][0].ShortCode)
+	assert.Equal(t, "busy", batches.setMultis[0][1].ShortCode)
+
+	warmed, err = service.WarmCache(ctx, 0)
+	require.NoError(t, err)
+	assert.Zero(t, warmed)
+	assert.Len(t, batches.setMultis, 1, "nothing to warm")
+
+	t.Run("database down", func(t *testing.T) {
+		service := NewURLService(&unavailableRepository{}, batches, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+		_, err := service.WarmCache(ctx, 10)
+		assert.ErrorIs(t, err, errDatabaseDown)
+	})
+}
+
+func TestURLService_GetURLBatch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	repo := memory.NewURLRepository(logger, 0)
+	for _, shortCode := range []string{"stored", "cached"} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	cached := &domain.URL{Namespace: domain.DefaultNamespace, ShortCode: "cached", OriginalURL: "https://example.com/from-cache"}
+	batches := &batchCache{NoOpCache: cache.NewNoOpCache(), urls: map[string]*domain.URL{"cached": cached}}
+	service := NewURLService(repo, batches, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+	urls, err := service.GetURLBatch(ctx, domain.DefaultNamespace, []string{"cached", "stored", "missing", "stored"})
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	assert.Same(t, cached, urls["cached"], "cache hits are not read again")
+	assert.Equal(t, "https://example.com/stored", urls["stored"].OriginalURL)
+
+	require.Len(t, batches.setMultis, 1, "misses are cached in one batch")
+	require.Len(t, batches.setMultis[0], 1)
+	assert.Equal(t, "stored", batches.setMultis[0][0].ShortCode)
+
+	t.Run("only cache hits", func(t *testing.T) {
+		service := NewURLService(&unavailableRepository{}, batches, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+		urls, err := service.GetURLBatch(ctx, domain.DefaultNamespace, []string{"cached"})
+		require.NoError(t, err)
+		assert.Len(t, urls, 1)
+	})
+
+	t.Run("database down", func(t *testing.T) {
+		service := NewURLService(&unavailableRepository{}, batches, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+		_, err := service.GetURLBatch(ctx, domain.DefaultNamespace, []string{"cached", "stored"})
+		assert.ErrorIs(t, err, errDatabaseDown)
+	})
+}
+
+// memoryDeduplicator remembers every visitor forever, or fails when err is set
+type memoryDeduplicator struct {
+	seen map[string]bool
+	err  error
+}
+
+func (d *memoryDeduplicator) FirstVisit(_ context.Context, namespace, shortCode, visitor string) (bool, error) {
+	if d.err != nil {
+		return false, d.err
+	}
+	key := namespace + "/" + shortCode + "/" + visitor
+	first := !d.seen[key]
+	d.seen[key] = true
+	return first, nil
+}
+
+func (d *memoryDeduplicator) Forget(_ context.Context, namespace, shortCode string) error {
+	prefix := namespace + "/" + shortCode + "/"
+	for key := range d.seen {
+		if strings.HasPrefix(key, prefix) {
+			delete(d.seen, key)
+		}
+	}
+	return nil
+}
+
+func TestURLService_ResetAnalytics(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dedup := &memoryDeduplicator{seen: make(map[string]bool)}
+	service := NewURLService(memory.NewURLRepository(logger, 0), cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{Dedup: dedup}, logger)
+
+	_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "qareset"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	visitor := ClickDetails{IP: "203.0.113.1", UserAgent: "curl/8.5.0"}
+	for range 3 {
+		_, err := service.IncrementClicks(ctx, domain.DefaultNamespace, "qareset", visitor)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, service.ResetAnalytics(ctx, domain.DefaultNamespace, "qareset"))
+
+	url, err := service.GetURL(ctx, domain.DefaultNamespace, "qareset")
+	require.NoError(t, err)
+	assert.Zero(t, url.Clicks)
+	assert.Zero(t, url.UniqueClicks)
+
+	t.Run("visitors count as unique again", func(t *testing.T) {
+		url, err := service.IncrementClicks(ctx, domain.DefaultNamespace, "qareset", visitor)
+		require.NoError(t, err)
+		assert.Equal(t, 1, url.UniqueClicks)
+	})
+
+	t.Run("unknown short code", func(t *testing.T) {
+		assert.ErrorIs(t, service.ResetAnalytics(ctx, domain.DefaultNamespace, "missing"), domain.ErrURLNotFound)
+	})
+}
+
+func TestURLService_IncrementClicks_UniqueClicks(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dedup := &memoryDeduplicator{seen: make(map[string]bool)}
+	service := NewURLService(memory.NewURLRepository(logger, 0), cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{Dedup: dedup}, logger)
+
+	_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "unique"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	visitors := []ClickDetails{
+		{IP: "203.0.113.1", UserAgent: "curl/8.5.0"},
+		{IP: "203.0.113.1", UserAgent: "curl/8.5.0"},
+		{IP: "203.0.113.1", UserAgent: "Mozilla/5.0"}, // same IP, another browser
+		{IP: "203.0.113.2", UserAgent: "curl/8.5.0"},
+		{}, // unknown visitors are never deduplicated
+		{},
+	}
+	var url *domain.URL
+	for _, details := range visitors {
+		url, err = service.IncrementClicks(ctx, domain.DefaultNamespace, "unique", details)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 6, url.Clicks)
+	assert.Equal(t, 5, url.UniqueClicks)
+
+	t.Run("clicks count as unique when deduplication fails", func(t *testing.T) {
+		dedup.err = errors.New("redis down")
+		url, err := service.IncrementClicks(ctx, domain.DefaultNamespace, "unique", visitors[0])
+		require.NoError(t, err)
+		assert.Equal(t, 7, url.Clicks)
+		assert.Equal(t, 6, url.UniqueClicks)
+	})
+
+	t.Run("visitors are hashed", func(t *testing.T) {
+		for key := range dedup.seen {
+			assert.NotContains(t, key, "203.0.113")
+		}
+	})
+}
+
+func TestURLService_IncrementClicks_ClickGoal(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	broker := pubsub.NewBroker(0)
+	service := NewURLService(memory.NewURLRepository(logger, 0), cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), broker, URLServiceDeps{}, logger)
+	goals, unsubscribe := broker.SubscribeGoals()
+	defer unsubscribe()
+
+	goal := 3
+	_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "campaign", ClickGoal: &goal}, "http://localhost:8080")
+	require.NoError(t, err)
+	_, err = service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "nogoal"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	for click := 1; click <= 5; click++ {
+		url, err := service.IncrementClicks(ctx, domain.DefaultNamespace, "campaign", ClickDetails{})
+		require.NoError(t, err)
+		_, err = service.IncrementClicks(ctx, domain.DefaultNamespace, "nogoal", ClickDetails{})
+		require.NoError(t, err)
+
+		if click < goal {
+			assert.False(t, url.GoalReached, "click %d", click)
+			assert.Empty(t, goals, "click %d", click)
+			continue
+		}
+		assert.True(t, url.GoalReached, "click %d", click)
+		if click == goal {
+			require.Len(t, goals, 1)
+			event := <-goals
+			assert.Equal(t, "campaign", event.ShortCode)
+			assert.Equal(t, 3, event.ClickGoal)
+			assert.Equal(t, 3, event.Clicks)
+		}
+		assert.Empty(t, goals, "the goal is only announced once")
+	}
+
+	stored, err := service.GetURL(ctx, domain.DefaultNamespace, "campaign")
+	require.NoError(t, err)
+	assert.True(t, stored.GoalReached)
+
+	t.Run("concurrent clicks announce the goal once", func(t *testing.T) {
+		goal := 5
+		_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "rush", ClickGoal: &goal}, "http://localhost:8080")
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := service.IncrementClicks(ctx, domain.DefaultNamespace, "rush", ClickDetails{})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		require.Len(t, goals, 1)
+		assert.Equal(t, "rush", (<-goals).ShortCode)
+	})
+
+	t.Run("goals must be positive", func(t *testing.T) {
+		zero := 0
+		_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", ClickGoal: &zero}, "http://localhost:8080")
+		var validationErrors validator.ValidationErrors
+		require.ErrorAs(t, err, &validationErrors)
+		assert.Equal(t, "ClickGoal", validationErrors[0].Field())
+	})
+}
+
+// memoryClickCounter keeps buffered clicks without ever storing them
+type memoryClickCounter struct {
+	mu     sync.Mutex
+	clicks map[string]int
+	unique map[string]int
+}
+
+func (c *memoryClickCounter) Add(namespace, shortCode string, unique bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clicks[namespace+"/"+shortCode]++
+	if unique {
+		c.unique[namespace+"/"+shortCode]++
+	}
+}
+
+func TestURLService_IncrementClicks_Buffered(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	dedup := &memoryDeduplicator{seen: make(map[string]bool)}
+	counter := &memoryClickCounter{clicks: make(map[string]int), unique: make(map[string]int)}
+	broker := pubsub.NewBroker(0)
+	service := NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), broker, URLServiceDeps{Dedup: dedup, Clicks: counter}, logger)
+
+	_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "buffered"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	visitor := ClickDetails{IP: "203.0.113.1", UserAgent: "curl/8.5.0", Referer: "https://news.example/"}
+	url, err := service.IncrementClicks(ctx, domain.DefaultNamespace, "buffered", visitor)
+	require.NoError(t, err)
+	assert.Equal(t, 1, url.Clicks, "the click is added to the returned URL")
+	assert.Equal(t, 1, url.UniqueClicks)
+	assert.NotNil(t, url.LastAccessedAt)
+
+	url, err = service.IncrementClicks(ctx, domain.DefaultNamespace, "buffered", visitor)
+	require.NoError(t, err)
+	assert.Equal(t, 1, url.Clicks, "buffered clicks are left out until stored")
+	assert.Zero(t, url.UniqueClicks, "a repeat visit is not unique")
+
+	assert.Equal(t, 2, counter.clicks["default/buffered"])
+	assert.Equal(t, 1, counter.unique["default/buffered"])
+
+	stored, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "buffered")
+	require.NoError(t, err)
+	assert.Zero(t, stored.Clicks, "the counters are left to the buffer")
+
+	referrers, err := service.GetTopReferrers(ctx, domain.DefaultNamespace, "buffered", 10)
+	require.NoError(t, err)
+	require.Len(t, referrers, 1)
+	assert.Equal(t, 2, referrers[0].Count, "clicks are still recorded for analytics")
+
+	t.Run("unknown short code", func(t *testing.T) {
+		_, err := service.IncrementClicks(ctx, domain.DefaultNamespace, "missing", visitor)
+		assert.ErrorIs(t, err, domain.ErrURLNotFound)
+		assert.Zero(t, counter.clicks["default/missing"])
+	})
+}
+
+func TestCheckURLSafety(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{url: "https://example.com/page", want: nil},
+		{url: "http://example.com:8080/?q=1", want: nil},
+		{url: "https://93.184.216.34/", want: nil},
+		{url: "https://[2606:4700::1111]/", want: nil},
+		{url: "ftp://files.example.com/archive.zip", want: nil},
+		{url: "data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==", want: ErrUnsafeURLScheme},
+		{url: "javascript:alert(1)", want: ErrUnsafeURLScheme},
+		{url: " JAVASCRIPT:alert(1)", want: ErrUnsafeURLScheme},
+		{url: "vbscript:msgbox(1)", want: ErrUnsafeURLScheme},
+		{url: "blob:https://example.com/550e8400-e29b-41d4-a716-446655440000", want: ErrUnsafeURLScheme},
+		{url: "http://localhost:3000", want: ErrPrivateIPURL},
+		{url: "http://LOCALHOST./", want: ErrPrivateIPURL},
+		{url: "http://127.0.0.1/", want: ErrPrivateIPURL},
+		{url: "http://10.1.2.3/", want: ErrPrivateIPURL},
+		{url: "http://172.16.0.1/", want: ErrPrivateIPURL},
+		{url: "http://192.168.0.10/", want: ErrPrivateIPURL},
+		{url: "http://169.254.169.254/", want: ErrPrivateIPURL},
+		{url: "http://0.0.0.0/", want: ErrPrivateIPURL},
+		{url: "http://[::1]:8080/", want: ErrPrivateIPURL},
+		{url: "http://[fc00::1]/", want: ErrPrivateIPURL},
+		{url: "http://[fe80::1]/", want: ErrPrivateIPURL},
+		{url: "http://[::ffff:10.0.0.1]/", want: ErrPrivateIPURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.ErrorIs(t, CheckURLSafety(tt.url), tt.want)
+		})
+	}
+}
+
+func TestURLService_FollowRedirectChain(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	chains := &RedirectChains{BaseURLs: []string{"https://dove.example", "https://short.example/go"}, MaxDepth: 3}
+	service := NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{Chains: chains}, logger)
+	ctx := context.Background()
+
+	for _, req := range []CreateURLRequest{
+		{URL: "https://example.com/final", CustomAlias: "three"},
+		{URL: "https://short.example/go/three", CustomAlias: "two"},
+		{URL: "https://dove.example/two", CustomAlias: "one"},
+		{URL: "https://dove.example/pong", CustomAlias: "ping"},
+		{URL: "https://dove.example/ping", CustomAlias: "pong"},
+		{URL: "https://dove.example/three", CustomAlias: "locked", Password: "s3cret"},
+		{URL: "https://dove.example/locked", CustomAlias: "guarded"},
+		{URL: "https://dove.example/team/inner", CustomAlias: "outer"},
+		{URL: "https://example.com/team", CustomAlias: "inner", Namespace: "team"},
+		{URL: "https://dove.example/missing", CustomAlias: "dangling"},
+		{URL: "https://example.com/paused", CustomAlias: "paused"},
+		{URL: "https://dove.example/paused", CustomAlias: "topaused"},
+	} {
+		_, err := service.CreateShortURL(ctx, req, "https://dove.example")
+		require.NoError(t, err, req.CustomAlias)
+	}
+	_, err := service.SetURLEnabled(ctx, domain.DefaultNamespace, "paused", false)
+	require.NoError(t, err)
+
+	follow := func(service *URLService, shortCode string) (string, error) {
+		url, err := service.GetURL(ctx, domain.DefaultNamespace, shortCode)
+		require.NoError(t, err)
+		return service.FollowRedirectChain(ctx, url, url.OriginalURL)
+	}
+
+	tests := []struct {
+		name      string
+		shortCode string
+		expected  string
+	}{
+		{"three hop chain", "one", "https://example.com/final"},
+		{"alias base URL", "two", "https://example.com/final"},
+		{"no chain", "three", "https://example.com/final"},
+		{"namespaced hop", "outer", "https://example.com/team"},
+		{"stops before a password protected hop", "guarded", "https://dove.example/locked"},
+		{"stops before a missing hop", "dangling", "https://dove.example/missing"},
+		{"stops before a disabled hop", "topaused", "https://dove.example/paused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destination, err := follow(service, tt.shortCode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, destination)
+		})
+	}
+
+	t.Run("cycle", func(t *testing.T) {
+		_, err := follow(service, "ping")
+		assert.ErrorIs(t, err, domain.ErrRedirectLoop)
+	})
+
+	t.Run("depth limit", func(t *testing.T) {
+		shallow := NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{Chains: &RedirectChains{BaseURLs: chains.BaseURLs, MaxDepth: 1}}, logger)
+		destination, err := follow(shallow, "one")
+		require.NoError(t, err)
+		assert.Equal(t, "https://short.example/go/three", destination)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+		destination, err := follow(disabled, "one")
+		require.NoError(t, err)
+		assert.Equal(t, "https://dove.example/two", destination)
+
+		_, err = follow(disabled, "ping")
+		assert.NoError(t, err)
+	})
+
+	t.Run("hops count no clicks", func(t *testing.T) {
+		url, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "two")
+		require.NoError(t, err)
+		assert.Zero(t, url.Clicks)
+	})
+}
+
+func TestURLService_SeedURLs(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+	_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com/kept", CustomAlias: "taken"}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	entries := []SeedURL{
+		{ShortCode: "fresh", OriginalURL: "https://example.com/fresh", Namespace: "team", Tags: []string{"go"}, ExpiresAt: &future},
+		{ShortCode: "taken", OriginalURL: "https://example.com/replaced"},
+		{ShortCode: "expired", OriginalURL: "https://example.com/expired", ExpiresAt: &past},
+		{OriginalURL: "https://example.com/no-code"},
+		{ShortCode: "badurl", OriginalURL: "not-a-url"},
+	}
+
+	report, err := service.SeedURLs(ctx, entries, "http://localhost:8080")
+	require.NoError(t, err)
+	assert.Equal(t, SeedReport{Seeded: 1, Skipped: 1, Failed: 3}, *report)
+
+	fresh, err := repo.FindByNamespaceAndCode(ctx, "team", "fresh")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Tags{"go"}, fresh.Tags)
+	require.NotNil(t, fresh.ExpiresAt)
+	assert.WithinDuration(t, future, *fresh.ExpiresAt, time.Second)
+
+	taken, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "taken")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/kept", taken.OriginalURL)
+
+	_, err = repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "expired")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	t.Run("seeding again skips everything seeded", func(t *testing.T) {
+		report, err := service.SeedURLs(ctx, entries[:2], "http://localhost:8080")
+		require.NoError(t, err)
+		assert.Equal(t, SeedReport{Skipped: 2}, *report)
+	})
+}
+
+func TestLoadSeedFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "seed.yaml")
+	require.NoError(t, os.WriteFile(valid, []byte(`urls:
+  - shortCode: docs
+    originalUrl: https://example.com/docs
+    namespace: team
+    tags: [docs]
+    expiresAt: 2030-01-02T03:04:05Z
+`), 0600))
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("urls: {shortCode: [\n"), 0600))
+
+	urls, err := LoadSeedFile(valid)
+	require.NoError(t, err)
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, []SeedURL{{ShortCode: "docs", OriginalURL: "https://example.com/docs", Namespace: "team", Tags: []string{"docs"}, ExpiresAt: &expiresAt}}, urls)
+
+	_, err = LoadSeedFile(invalid)
+	assert.Error(t, err)
+	_, err = LoadSeedFile(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+// collidingRepository reports the first collisions short codes it is asked about as taken
+type collidingRepository struct {
+	domain.URLRepository
+	mu         sync.Mutex
+	collisions int
+	checked    []string
+}
+
+func (r *collidingRepository) Exists(ctx context.Context, namespace, shortCode string) (bool, error) {
+	r.mu.Lock()
+	r.checked = append(r.checked, shortCode)
+	taken := len(r.checked) <= r.collisions
+	r.mu.Unlock()
+	if taken {
+		return true, nil
+	}
+	return r.URLRepository.Exists(ctx, namespace, shortCode)
+}
+
+func TestURLService_CreateShortURL_RetriesTakenGeneratedCodes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		req         CreateURLRequest
+		collisions  int
+		wantErr     error
+		wantChecked int
+	}{
+		{name: "free code", req: CreateURLRequest{URL: "https://example.com"}, collisions: 0, wantChecked: 1},
+		{name: "third code is free", req: CreateURLRequest{URL: "https://example.com"}, collisions: 2, wantChecked: 3},
+		{name: "every attempt taken", req: CreateURLRequest{URL: "https://example.com"}, collisions: 3, wantErr: domain.ErrShortCodeExists, wantChecked: 3},
+		{name: "custom alias is not retried", req: CreateURLRequest{URL: "https://example.com", CustomAlias: "promo"}, collisions: 1, wantErr: domain.ErrShortCodeExists, wantChecked: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &collidingRepository{URLRepository: memory.NewURLRepository(logger, 0), collisions: tt.collisions}
+			service := NewURLService(repo, cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+			response, err := service.CreateShortURL(ctx, tt.req, "http://localhost:8080")
+			assert.Len(t, repo.checked, tt.wantChecked)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, repo.checked[len(repo.checked)-1], response.ShortCode, "the URL gets the first free code")
+		})
+	}
+}
+
+func TestURLService_DryRunCreate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		req         CreateURLRequest
+		collisions  int
+		wantErr     error
+		wantChecked int
+	}{
+		{name: "generated code", req: CreateURLRequest{URL: "https://example.com"}, wantChecked: 1},
+		{name: "taken generated code is replaced", req: CreateURLRequest{URL: "https://example.com"}, collisions: 1, wantChecked: 2},
+		{name: "free custom alias", req: CreateURLRequest{URL: "https://example.com", CustomAlias: "promo"}, wantChecked: 1},
+		{name: "taken custom alias", req: CreateURLRequest{URL: "https://example.com", CustomAlias: "promo"}, collisions: 1, wantErr: domain.ErrShortCodeExists, wantChecked: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &collidingRepository{URLRepository: memory.NewURLRepository(logger, 0), collisions: tt.collisions}
+			urlCache := &batchCache{NoOpCache: cache.NewNoOpCache()}
+			service := NewURLService(repo, urlCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{}, logger)
+
+			response, err := service.DryRunCreate(ctx, tt.req, "http://localhost:8080")
+			assert.Len(t, repo.checked, tt.wantChecked)
+			assert.Zero(t, urlCache.sets, "dry runs cache nothing")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.True(t, response.DryRun)
+			assert.Equal(t, repo.checked[len(repo.checked)-1], response.ShortCode)
+			assert.Equal(t, "http://localhost:8080/"+response.ShortCode, response.ShortURL)
+			_, err = repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, response.ShortCode)
+			assert.ErrorIs(t, err, domain.ErrURLNotFound, "dry runs store nothing")
+		})
+	}
+}
+
+// memoryLocker grants each key to one holder at a time
+type memoryLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+	keys []string
+	err  error
+}
+
+func (l *memoryLocker) TryLock(_ context.Context, key string, _ time.Duration) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keys = append(l.keys, key)
+	if l.err != nil {
+		return nil, false, l.err
+	}
+	if l.held[key] {
+		return nil, false, nil
+	}
+	l.held[key] = true
+
+	unlock := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, key)
+	}
+	return unlock, true, nil
+}
+
+func TestURLService_CreateShortURL_LocksCustomAliases(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	newService := func(locker *memoryLocker) *URLService {
+		return NewURLService(memory.NewURLRepository(logger, 0), cache.NewNoOpCache(), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), URLServiceDeps{Locker: locker}, logger)
+	}
+
+	t.Run("concurrent claims of an alias create it once", func(t *testing.T) {
+		locker := &memoryLocker{held: make(map[string]bool)}
+		service := newService(locker)
+
+		var created, taken atomic.Int32
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "promo"}, "http://localhost:8080")
+				switch {
+				case err == nil:
+					created.Add(1)
+				case errors.Is(err, domain.ErrShortCodeExists):
+					taken.Add(1)
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), created.Load())
+		assert.Equal(t, int32(19), taken.Load())
+		assert.Empty(t, locker.held, "every lock is released")
+	})
+
+	t.Run("a held alias is taken", func(t *testing.T) {
+		locker := &memoryLocker{held: map[string]bool{"alias:team:promo": true}}
+		service := newService(locker)
+
+		_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "promo", Namespace: "team"}, "http://localhost:8080")
+		assert.ErrorIs(t, err, domain.ErrShortCodeExists)
+
+		_, err = service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "promo"}, "http://localhost:8080")
+		assert.NoError(t, err, "the lock is per namespace")
+	})
+
+	t.Run("generated codes are not locked", func(t *testing.T) {
+		locker := &memoryLocker{held: make(map[string]bool)}
+		service := newService(locker)
+
+		_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com"}, "http://localhost:8080")
+		require.NoError(t, err)
+		assert.Empty(t, locker.keys)
+	})
+
+	t.Run("a failing locker does not fail the creation", func(t *testing.T) {
+		locker := &memoryLocker{held: make(map[string]bool), err: errors.New("redis down")}
+		service := newService(locker)
+
+		_, err := service.CreateShortURL(ctx, CreateURLRequest{URL: "https://example.com", CustomAlias: "promo"}, "http://localhost:8080")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alias:default:promo"}, locker.keys)
+	})
+}
@@ -0,0 +1,45 @@
+package application
+
+import (
+	"context"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/urlfetch"
+)
+
+// fetchTitle stores the title of the destination page of url once fetched, in the
+// background and only with fetchTitles. The creation has answered by then, so failures
+// are logged and leave the title empty.
+func (s *URLService) fetchTitle(ctx context.Context, url *domain.URL) {
+	if !s.fetchTitles {
+		return
+	}
+
+	// The fetch outlives the request creating the URL
+	ctx = context.WithoutCancel(ctx)
+	namespace, shortCode, originalURL := url.Namespace, url.ShortCode, url.OriginalURL
+	go func() {
+		title, err := urlfetch.FetchTitle(ctx, s.fetchClient, originalURL)
+		if err != nil {
+			s.logger.Info("Could not fetch page title", "namespace", namespace, "short_code", shortCode, "url", originalURL, "error", err)
+			return
+		}
+
+		// Read again, keeping the edits made during the fetch
+		current, err := s.repo.FindByNamespaceAndCode(ctx, namespace, shortCode)
+		if err != nil {
+			s.logger.Warn("Failed to load URL to store its title", "namespace", namespace, "short_code", shortCode, "error", err)
+			return
+		}
+		current.Title = title
+		updated, err := s.repo.Update(ctx, current)
+		if err != nil {
+			s.logger.Warn("Failed to store page title", "namespace", namespace, "short_code", shortCode, "error", err)
+			return
+		}
+
+		if err := s.cache.Set(ctx, updated, s.cacheTTL); err != nil {
+			s.logger.Warn("Failed to cache URL with its title", "namespace", namespace, "short_code", shortCode, "error", err)
+		}
+	}()
+}
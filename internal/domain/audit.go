@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry is one recorded change of a short URL. OldValue and NewValue hold the URL as
+// JSON before and after the change, null when it did not exist.
+type AuditEntry struct {
+	Timestamp time.Time       `db:"recorded_at" json:"timestamp"`
+	Operation string          `db:"operation" json:"operation" example:"update"`
+	Namespace string          `db:"namespace" json:"namespace" example:"default"`
+	ShortCode string          `db:"short_code" json:"shortCode" example:"abc123"`
+	ActorIP   string          `db:"actor_ip" json:"actorIp" example:"203.0.113.7"`
+	RequestID string          `db:"request_id" json:"requestId"`
+	OldValue  json.RawMessage `db:"old_value" json:"oldValue" swaggertype:"object" extensions:"x-nullable"`
+	NewValue  json.RawMessage `db:"new_value" json:"newValue" swaggertype:"object" extensions:"x-nullable"`
+}
+
+// AuditRepository keeps the change history of short URLs
+type AuditRepository interface {
+	Record(ctx context.Context, entry AuditEntry) error
+	// ListByShortCode returns at most limit entries of the URL, most recent first
+	ListByShortCode(ctx context.Context, namespace, shortCode string, limit int) ([]AuditEntry, error)
+}
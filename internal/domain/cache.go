@@ -2,20 +2,63 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrInvalidKeyPrefix is returned when cache keys cannot be moved between two prefixes
+var ErrInvalidKeyPrefix = errors.New("invalid cache key prefix")
+
 // Cache defines the interface for caching operations
 type Cache interface {
-	// Get retrieves a URL from cache by its short code
-	Get(ctx context.Context, shortCode string) (*URL, error)
+	// Get retrieves a URL from cache by its namespace and short code
+	Get(ctx context.Context, namespace, shortCode string) (*URL, error)
+
+	// GetMulti retrieves the URLs of namespace with any of shortCodes in a single round trip,
+	// keyed by short code. Misses are left out of the map.
+	GetMulti(ctx context.Context, namespace string, shortCodes []string) (map[string]*URL, error)
+
+	// GetOrSet retrieves a URL from cache, or on a miss stores the URL returned by fetch with
+	// the specified TTL. Concurrent misses for the same URL call fetch as few times as the
+	// cache allows, and errors of fetch are returned unchanged.
+	GetOrSet(ctx context.Context, namespace, shortCode string, ttl time.Duration, fetch func() (*URL, error)) (*URL, error)
 
 	// Set stores a URL in cache with the specified TTL
 	Set(ctx context.Context, url *URL, ttl time.Duration) error
 
+	// SetMulti stores several URLs with the specified TTL in a single round trip
+	SetMulti(ctx context.Context, urls []*URL, ttl time.Duration) error
+
+	// GetStale retrieves a URL even after its TTL has passed, for use when the repository
+	// is unavailable. It returns nil on a miss or when stale entries are not kept.
+	GetStale(ctx context.Context, namespace, shortCode string) (*URL, error)
+
 	// Delete removes a URL from cache
-	Delete(ctx context.Context, shortCode string) error
+	Delete(ctx context.Context, namespace, shortCode string) error
+
+	// DeleteMulti removes several URLs of one namespace in a single round trip
+	DeleteMulti(ctx context.Context, namespace string, shortCodes []string) error
+
+	// GetTopURLs retrieves the cached ranking of the n most clicked URLs, nil on a miss
+	GetTopURLs(ctx context.Context, n int) ([]*URL, error)
+
+	// SetTopURLs stores the ranking of the n most clicked URLs with the specified TTL
+	SetTopURLs(ctx context.Context, n int, urls []*URL, ttl time.Duration) error
+
+	// InvalidateTopURLs removes every cached ranking
+	InvalidateTopURLs(ctx context.Context) error
 
 	// Ping checks if the cache is available
 	Ping(ctx context.Context) error
+
+	// Keys lists about count of the keys matching the glob pattern, without the key prefix,
+	// starting at cursor, and returns the cursor the listing continues from, 0 once done.
+	// Keys written or removed meanwhile may be missed or listed twice.
+	Keys(ctx context.Context, pattern string, cursor uint64, count int) ([]string, uint64, error)
+}
+
+// CacheKeyMigrator is implemented by caches whose keys start with a configurable prefix
+type CacheKeyMigrator interface {
+	// MigrateKeys moves every key under oldPrefix to newPrefix and returns how many moved
+	MigrateKeys(ctx context.Context, oldPrefix, newPrefix string) (int, error)
 }
@@ -0,0 +1,238 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidGranularity = errors.New("invalid granularity")
+	ErrInvalidTimeRange   = errors.New("invalid time range")
+)
+
+// MaxTimeSeriesRange is the widest window a click time series may cover
+const MaxTimeSeriesRange = 90 * 24 * time.Hour
+
+// ClickEvent describes a single recorded click on a short URL
+type ClickEvent struct {
+	Namespace string    `json:"namespace"`
+	ShortCode string    `json:"shortCode"`
+	Clicks    int       `json:"clicks"`
+	ClickedAt time.Time `json:"clickedAt"`
+}
+
+// GoalReachedEvent announces that a short URL reached its click goal, once per URL
+type GoalReachedEvent struct {
+	Namespace string    `json:"namespace"`
+	ShortCode string    `json:"shortCode"`
+	ClickGoal int       `json:"clickGoal"`
+	Clicks    int       `json:"clicks"`
+	ReachedAt time.Time `json:"reachedAt"`
+}
+
+// Click is a persisted visit of a short URL, kept for analytics
+type Click struct {
+	ID         int64     `db:"id"`
+	Namespace  string    `db:"namespace"`
+	ShortCode  string    `db:"short_code"`
+	ClickedAt  time.Time `db:"clicked_at"`
+	Referer    string    `db:"referer"`
+	Browser    string    `db:"ua_browser"`
+	OS         string    `db:"ua_os"`
+	DeviceType string    `db:"ua_device_type"`
+	VariantID  *int64    `db:"variant_id"` // nil unless the URL has variants
+	TargetURL  string    `db:"target_url"` // pool target redirected to, empty unless the URL has a pool
+	// RedirectDurationMs is how long the redirect handler ran before recording the click, nil when unknown
+	RedirectDurationMs *float64 `db:"redirect_duration_ms"`
+	IPAddress          string   `db:"ip_address"` // client IP of the visitor, empty when unknown
+	UserAgent          string   `db:"user_agent"`
+	Country            string   `db:"country"` // ISO 3166-1 alpha-2 code, empty when unknown
+}
+
+// ClickCursor is the position of a click in the clicks of a URL, ordered by time and then
+// ID. The zero cursor comes before every click.
+type ClickCursor struct {
+	ClickedAt time.Time
+	ID        int64
+}
+
+// Cursor returns the position of the click, where a listing continues after it
+func (c *Click) Cursor() ClickCursor {
+	return ClickCursor{ClickedAt: c.ClickedAt, ID: c.ID}
+}
+
+// LatencyStats is the distribution of redirect durations of a short URL, in milliseconds.
+// Clicks without a recorded duration are left out.
+type LatencyStats struct {
+	P50   float64 `db:"p50" json:"p50" example:"1.2"`
+	P95   float64 `db:"p95" json:"p95" example:"4.8"`
+	P99   float64 `db:"p99" json:"p99" example:"9.5"`
+	Avg   float64 `db:"avg" json:"avg" example:"1.6"`
+	Count int     `db:"count" json:"count" example:"100"`
+}
+
+// NewLatencyStats summarises durations, which must be sorted in ascending order.
+// Percentiles interpolate between neighbouring durations, as PostgreSQL's percentile_cont does.
+func NewLatencyStats(durations []float64) LatencyStats {
+	stats := LatencyStats{Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+
+	var sum float64
+	for _, d := range durations {
+		sum += d
+	}
+	stats.Avg = sum / float64(len(durations))
+	stats.P50 = percentile(durations, 0.5)
+	stats.P95 = percentile(durations, 0.95)
+	stats.P99 = percentile(durations, 0.99)
+	return stats
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	rank := p * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// ClickDelta is the number of clicks counted against a URL since its counters were last stored
+type ClickDelta struct {
+	Namespace    string
+	ShortCode    string
+	Clicks       int64
+	UniqueClicks int64
+}
+
+// ClickCounter counts clicks in memory and stores them in batches, so that redirects do
+// not wait for the click counters to be written
+type ClickCounter interface {
+	// Add counts a click, and a unique click as well when unique is set, without blocking
+	Add(namespace, shortCode string, unique bool)
+}
+
+// ClickDeduplicator recognises visitors that clicked a short URL recently, so that reloads
+// are not counted as unique clicks
+type ClickDeduplicator interface {
+	// FirstVisit records visitor against the short URL and reports whether it was not
+	// already seen within the deduplication window
+	FirstVisit(ctx context.Context, namespace, shortCode, visitor string) (bool, error)
+
+	// Forget drops every visitor recorded against the short URL
+	Forget(ctx context.Context, namespace, shortCode string) error
+}
+
+// DirectReferer labels clicks that arrived without a usable Referer header
+const DirectReferer = "(direct)"
+
+// NormalizeReferer reduces a Referer header to host and path so that visits from the
+// same page group together regardless of query string or fragment
+func NormalizeReferer(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return DirectReferer
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return DirectReferer
+	}
+
+	path := u.Path
+	if path == "/" {
+		path = ""
+	}
+	return strings.ToLower(u.Host) + path
+}
+
+// ReferrerCount is the number of clicks that arrived from one referrer
+type ReferrerCount struct {
+	Referer string `db:"referer" json:"referer" example:"news.ycombinator.com/item"`
+	Count   int    `db:"count" json:"count" example:"42"`
+}
+
+// IPCount is the number of clicks that came from one client IP address
+type IPCount struct {
+	IPAddress string `db:"ip_address" json:"ipAddress" example:"203.0.113.7"`
+	Count     int    `db:"count" json:"count" example:"42"`
+}
+
+// Granularity is the bucket width of a click time series
+type Granularity string
+
+const (
+	GranularityMinute Granularity = "minute"
+	GranularityHour   Granularity = "hour"
+	GranularityDay    Granularity = "day"
+	GranularityWeek   Granularity = "week"
+)
+
+// ParseGranularity validates a granularity name
+func ParseGranularity(s string) (Granularity, error) {
+	switch g := Granularity(s); g {
+	case GranularityMinute, GranularityHour, GranularityDay, GranularityWeek:
+		return g, nil
+	default:
+		return "", ErrInvalidGranularity
+	}
+}
+
+// Truncate returns the start of the UTC bucket containing t. Weeks start on Monday,
+// matching PostgreSQL's date_trunc.
+func (g Granularity) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	switch g {
+	case GranularityMinute:
+		return t.Truncate(time.Minute)
+	case GranularityHour:
+		return t.Truncate(time.Hour)
+	case GranularityWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// TimeBucket is the number of clicks within one period of a time series
+type TimeBucket struct {
+	Period time.Time `db:"period" json:"period"`
+	Clicks int       `db:"clicks" json:"clicks"`
+}
+
+// ClickHeatmap counts clicks by local hour of the day and day of the week, Sunday first
+type ClickHeatmap struct {
+	HourOfDay [24]int `json:"hourOfDay"`
+	DayOfWeek [7]int  `json:"dayOfWeek"`
+}
+
+// Add counts clicks made at t, in the location of t
+func (h *ClickHeatmap) Add(t time.Time, clicks int) {
+	h.HourOfDay[t.Hour()] += clicks
+	h.DayOfWeek[t.Weekday()] += clicks
+}
+
+// DeviceGrouping selects the dimensions of a device breakdown
+type DeviceGrouping string
+
+const (
+	// DeviceGroupingDevice groups by device type, browser and operating system together
+	DeviceGroupingDevice  DeviceGrouping = "device"
+	DeviceGroupingBrowser DeviceGrouping = "browser"
+	DeviceGroupingOS      DeviceGrouping = "os"
+)
+
+// DeviceStat is the number of clicks from one device, browser and OS combination.
+// Dimensions not covered by the requested grouping are left empty.
+type DeviceStat struct {
+	DeviceType string `db:"ua_device_type" json:"deviceType,omitempty" example:"mobile"`
+	Browser    string `db:"ua_browser" json:"browser,omitempty" example:"Chrome"`
+	OS         string `db:"ua_os" json:"os,omitempty" example:"Android"`
+	Count      int    `db:"count" json:"count" example:"42"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// DeviceRoute sends visitors on one kind of device to a dedicated destination
+type DeviceRoute struct {
+	DeviceType     string `json:"deviceType"` // mobile, tablet or desktop
+	DestinationURL string `json:"destinationUrl"`
+}
+
+// DeviceRoutes is the device routing table of a short URL, stored as a single JSON document
+type DeviceRoutes []DeviceRoute
+
+// Destination returns the route destination for deviceType, if the URL has one
+func (d DeviceRoutes) Destination(deviceType string) (string, bool) {
+	for _, route := range d {
+		if route.DeviceType == deviceType {
+			return route.DestinationURL, true
+		}
+	}
+	return "", false
+}
+
+// Value stores the routes as JSON text, which both JSONB and TEXT columns accept. URLs
+// without routes store NULL.
+func (d DeviceRoutes) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]DeviceRoute(d))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads routes stored by Value
+func (d *DeviceRoutes) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, (*[]DeviceRoute)(d))
+	case string:
+		return json.Unmarshal([]byte(data), (*[]DeviceRoute)(d))
+	default:
+		return fmt.Errorf("cannot scan %T into DeviceRoutes", src)
+	}
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrFunnelNotFound is returned when no funnel has the requested ID
+var ErrFunnelNotFound = errors.New("funnel not found")
+
+// Funnel is an ordered sequence of short URLs, such as the pages of a campaign. Visitors
+// are followed through it by session, see FunnelClick.
+type Funnel struct {
+	ID        int64        `db:"id" json:"id" example:"1"`
+	Name      string       `db:"name" json:"name" example:"Spring signup"`
+	Steps     []FunnelStep `db:"-" json:"steps"`
+	CreatedAt time.Time    `db:"created_at" json:"createdAt"`
+}
+
+// FunnelStep is one URL of a funnel. Orders start at 1.
+type FunnelStep struct {
+	Order     int    `db:"step_order" json:"order" example:"1"`
+	Namespace string `db:"namespace" json:"namespace" example:"default"`
+	ShortCode string `db:"short_code" json:"shortCode" example:"step1"`
+}
+
+// FunnelStepRef locates a URL within one of the funnels it is a step of
+type FunnelStepRef struct {
+	FunnelID  int64 `db:"funnel_id"`
+	StepOrder int   `db:"step_order"`
+}
+
+// FunnelClick records a visitor session reaching a funnel step
+type FunnelClick struct {
+	FunnelID  int64
+	StepOrder int
+	SessionID string
+	ClickedAt time.Time
+}
+
+// FunnelSessionStep is a step reached by a session, however many times it was clicked
+type FunnelSessionStep struct {
+	SessionID string `db:"session_id"`
+	StepOrder int    `db:"step_order"`
+}
+
+// FunnelRepository stores funnels and the sessions moving through them. URL repositories
+// keeping funnels implement it next to URLRepository.
+type FunnelRepository interface {
+	// CreateFunnel stores the funnel with its steps and returns it with its ID set
+	CreateFunnel(ctx context.Context, funnel *Funnel) (*Funnel, error)
+	FindFunnel(ctx context.Context, id int64) (*Funnel, error)
+	// FindFunnelSteps returns the steps the URL is in, across every funnel
+	FindFunnelSteps(ctx context.Context, namespace, shortCode string) ([]FunnelStepRef, error)
+	RecordFunnelClick(ctx context.Context, click *FunnelClick) error
+	// FunnelSessionSteps returns every step reached by every session of the funnel, each once
+	FunnelSessionSteps(ctx context.Context, funnelID int64) ([]FunnelSessionStep, error)
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GeoRoute sends visitors from one country to a region specific destination
+type GeoRoute struct {
+	CountryCode    string `json:"countryCode"` // ISO 3166-1 alpha-2, e.g. "US"
+	DestinationURL string `json:"destinationUrl"`
+}
+
+// GeoRoutes is the country routing table of a short URL, stored as a single JSON document
+type GeoRoutes []GeoRoute
+
+// Destination returns the route destination for countryCode, if the URL has one
+func (g GeoRoutes) Destination(countryCode string) (string, bool) {
+	if countryCode == "" {
+		return "", false
+	}
+	for _, route := range g {
+		if strings.EqualFold(route.CountryCode, countryCode) {
+			return route.DestinationURL, true
+		}
+	}
+	return "", false
+}
+
+// Value stores the routes as JSON text, which both JSONB and TEXT columns accept. URLs
+// without routes store NULL.
+func (g GeoRoutes) Value() (driver.Value, error) {
+	if len(g) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]GeoRoute(g))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads routes stored by Value
+func (g *GeoRoutes) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		*g = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, (*[]GeoRoute)(g))
+	case string:
+		return json.Unmarshal([]byte(data), (*[]GeoRoute)(g))
+	default:
+		return fmt.Errorf("cannot scan %T into GeoRoutes", src)
+	}
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMigrationDirty is returned when a previous migration failed part way. The schema has
+// to be repaired by hand before migrations can run again.
+var ErrMigrationDirty = errors.New("database schema is dirty")
+
+// MigrationStatus describes one schema migration. The migration tool only records the
+// current version, so the time a migration was applied is not known.
+type MigrationStatus struct {
+	Version uint   `json:"version" example:"14"`
+	Name    string `json:"name" example:"add_redirect_delay"`
+	Applied bool   `json:"applied"`
+	Dirty   bool   `json:"dirty"` // the migration failed part way
+}
+
+// MigrationRepository reports and applies the schema migrations of the database
+type MigrationRepository interface {
+	// List returns every known migration in version order
+	List(ctx context.Context) ([]MigrationStatus, error)
+	// Up applies every pending migration
+	Up(ctx context.Context) error
+	// Down rolls back at most steps migrations, stopping at an empty schema
+	Down(ctx context.Context, steps int) error
+}
+
+// Store migration errors
+var (
+	ErrStoreMigrationRunning = errors.New("a store migration is already running")
+	ErrUnsupportedStore      = errors.New("unsupported store")
+)
+
+// StoreMigrationReport counts the URLs of a store migration by outcome. URLs whose short
+// code the target already holds are skipped.
+type StoreMigrationReport struct {
+	Migrated int `json:"migrated" example:"98"`
+	Skipped  int `json:"skipped" example:"1"`
+	Failed   int `json:"failed" example:"1"`
+}
+
+// StoreMigrator copies every URL of the running repository into a store of another type,
+// named like database.type
+type StoreMigrator interface {
+	MigrateStore(ctx context.Context, from, to string) (*StoreMigrationReport, error)
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+var ErrInvalidNamespace = errors.New("invalid namespace")
+
+// DefaultNamespace holds the short codes of requests that do not name a namespace
+const DefaultNamespace = "default"
+
+// namespacePattern keeps slugs safe to use as a URL path segment
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// reservedNamespaces are the first path segments already routed by the API,
+// which would shadow /{namespace}/{shortCode} redirects
+var reservedNamespaces = map[string]bool{
+	"admin":   true,
+	"health":  true,
+	"metrics": true,
+	"ready":   true,
+	"redoc":   true,
+	"shorten": true,
+	"swagger": true,
+	"urls":    true,
+}
+
+// Namespace is an isolated short code space, typically owned by one tenant
+type Namespace struct {
+	ID        int64     `db:"id" json:"id"`
+	Slug      string    `db:"slug" json:"slug"`
+	OwnerID   string    `db:"owner_id" json:"ownerId,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// ValidateNamespace checks that slug can be used as a namespace
+func ValidateNamespace(slug string) error {
+	if !namespacePattern.MatchString(slug) || reservedNamespaces[slug] {
+		return ErrInvalidNamespace
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// PoolTarget is one destination of a load balanced short URL
+type PoolTarget struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// URLPool spreads the traffic of a short URL over several destinations in weighted
+// round-robin order. It is stored as a single JSON document.
+type URLPool struct {
+	Targets []PoolTarget `json:"targets"`
+}
+
+// Target returns the destination for the nth request. Over every run of requests as long
+// as the total weight, each target is returned exactly as many times as its weight.
+func (p URLPool) Target(n uint64) (PoolTarget, bool) {
+	totalWeight := 0
+	for _, target := range p.Targets {
+		totalWeight += target.Weight
+	}
+	if totalWeight <= 0 {
+		return PoolTarget{}, false
+	}
+
+	position := int(n % uint64(totalWeight)) //nolint:gosec // totalWeight is positive
+	for _, target := range p.Targets {
+		if position < target.Weight {
+			return target, true
+		}
+		position -= target.Weight
+	}
+	return PoolTarget{}, false
+}
+
+// Value stores the pool as JSON text, which both JSONB and TEXT columns accept
+func (p URLPool) Value() (driver.Value, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads a pool stored by Value
+func (p *URLPool) Scan(src any) error {
+	switch data := src.(type) {
+	case []byte:
+		return json.Unmarshal(data, p)
+	case string:
+		return json.Unmarshal([]byte(data), p)
+	default:
+		return fmt.Errorf("cannot scan %T into URLPool", src)
+	}
+}
@@ -1,12 +1,90 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
+// ErrQueryTimeout is returned when a repository query runs past its time limit
+var ErrQueryTimeout = errors.New("database query timed out")
+
+// URLRepository persists URLs and their clicks. Short codes are unique within
+// a namespace, so lookups take both. Single URL lookups also load the URL's variants.
 type URLRepository interface {
 	Create(ctx context.Context, url *URL) (*URL, error)
 	FindByShortCode(ctx context.Context, shortCode string) (*URL, error)
-	IncrementClicks(ctx context.Context, shortCode string) (*URL, error)
-	Exists(ctx context.Context, shortCode string) (bool, error)
+	FindByNamespaceAndCode(ctx context.Context, namespace, shortCode string) (*URL, error)
+	// FindByShortCodes looks up the URLs of namespace with any of shortCodes, variants
+	// included, in a single query. Short codes matching no URL are skipped.
+	FindByShortCodes(ctx context.Context, namespace string, shortCodes []string) ([]*URL, error)
+	// IncrementClicks counts a click, and a unique click as well when unique is set
+	IncrementClicks(ctx context.Context, namespace, shortCode string, unique bool) (*URL, error)
+	// AddClicks adds buffered clicks to the counters of their URLs in a single transaction.
+	// URLs deleted since their clicks were counted are skipped.
+	AddClicks(ctx context.Context, deltas []ClickDelta) error
+	// Update stores the mutable fields of the URL identified by its namespace and short code
+	Update(ctx context.Context, url *URL) (*URL, error)
+	Exists(ctx context.Context, namespace, shortCode string) (bool, error)
+	List(ctx context.Context, afterID int64, limit int) ([]*URL, error)
+	// ListByCreatorIP lists like List the URLs created from ip, a normalized address
+	ListByCreatorIP(ctx context.Context, ip string, afterID int64, limit int) ([]*URL, error)
+	// Search returns up to limit URLs whose short code, original URL or description contains
+	// query, ignoring case, in ID order. Repositories with fuzzy search also return URLs
+	// whose original URL is merely similar to query.
+	Search(ctx context.Context, query string, limit int) ([]*URL, error)
+	ListForHealthCheck(ctx context.Context, limit int) ([]*URL, error)
+	// FindExpired returns up to limit URLs whose expiry is before the given time, oldest ID first
+	FindExpired(ctx context.Context, before time.Time, limit int) ([]*URL, error)
+	// FindNotAccessedSince returns up to limit enabled URLs neither clicked nor created since
+	// the given time, least recently active first
+	FindNotAccessedSince(ctx context.Context, since time.Time, limit int) ([]*URL, error)
+	// TopByClicks returns the n most clicked URLs that may be listed publicly: enabled, and
+	// neither signed nor password protected
+	TopByClicks(ctx context.Context, n int) ([]*URL, error)
+	UpdateHealthStatus(ctx context.Context, namespace, shortCode, status string, checkedAt time.Time) error
+	// SetEnabled suspends or restores the redirects of a URL
+	SetEnabled(ctx context.Context, namespace, shortCode string, enabled bool) error
+	// MarkGoalReached records that a URL reached its click goal. reached is false when the
+	// goal was already marked, or the URL does not exist, so only one caller ever sees true.
+	MarkGoalReached(ctx context.Context, namespace, shortCode string) (reached bool, err error)
+	RecordClick(ctx context.Context, click *Click) error
+	// ListClicks returns up to limit recorded clicks of a URL after the given cursor, oldest
+	// first with ties broken by ID
+	ListClicks(ctx context.Context, namespace, shortCode string, after ClickCursor, limit int) ([]*Click, error)
+	// ResetClicks zeroes the click counters of a URL and deletes its recorded clicks, together
+	ResetClicks(ctx context.Context, namespace, shortCode string) error
+	// Delete removes a URL together with its variants and recorded clicks
+	Delete(ctx context.Context, namespace, shortCode string) error
+	// DeleteMany removes the URLs of namespace with any of shortCodes, together with their
+	// variants and recorded clicks, and returns the URLs it removed. Short codes matching
+	// no URL are skipped.
+	DeleteMany(ctx context.Context, namespace string, shortCodes []string) ([]*URL, error)
+	ClickTimeSeries(ctx context.Context, namespace, shortCode string, granularity Granularity, from, to time.Time) ([]TimeBucket, error)
+	// ClickHeatmap buckets every click by its hour and weekday in loc
+	ClickHeatmap(ctx context.Context, namespace, shortCode string, loc *time.Location) (*ClickHeatmap, error)
+	TopReferrers(ctx context.Context, namespace, shortCode string, limit int) ([]ReferrerCount, error)
+	// TopIPs returns the limit client IP addresses with the most clicks across every URL,
+	// busiest first. Clicks without an address are left out.
+	TopIPs(ctx context.Context, limit int) ([]IPCount, error)
+	DeviceStats(ctx context.Context, namespace, shortCode string, grouping DeviceGrouping) ([]DeviceStat, error)
+	VariantStats(ctx context.Context, namespace, shortCode string) ([]VariantStat, error)
+	RedirectLatency(ctx context.Context, namespace, shortCode string) (*LatencyStats, error)
+	// Stats counts URLs and clicks across every namespace. URLs expiring after now are
+	// active, and URLs created or clicks made after since are recent.
+	Stats(ctx context.Context, now, since time.Time) (*ServiceStats, error)
+	// CountActive counts the enabled URLs across every namespace that expire after now or
+	// never do
+	CountActive(ctx context.Context, now time.Time) (int64, error)
+	// CountByCreatedDate counts the URLs across every namespace created on the UTC day of date
+	CountByCreatedDate(ctx context.Context, date time.Time) (int, error)
+	// DailyActivity counts the URLs created and the clicks made across every namespace on
+	// each UTC day from the day of from to that of to, both included. Days without either
+	// are left out.
+	DailyActivity(ctx context.Context, from, to time.Time) ([]DailyActivity, error)
+	// AliasLengths counts URLs across every namespace by short code length, custom aliases
+	// apart from generated codes, ordered by kind and then length
+	AliasLengths(ctx context.Context) ([]AliasLengthCount, error)
 	Close() error
 	HealthCheck(ctx context.Context) error
 }
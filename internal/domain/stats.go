@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// ServiceStats are service wide counters over every namespace. The recent counters
+// cover URLs created and clicks made after the cutoff passed to the repository.
+type ServiceStats struct {
+	TotalURLs    int64 `db:"total_urls"`
+	TotalClicks  int64 `db:"total_clicks"`
+	ActiveURLs   int64 `db:"active_urls"` // URLs without an expiry or not expired yet
+	RecentURLs   int64 `db:"recent_urls"`
+	RecentClicks int64 `db:"recent_clicks"`
+}
+
+// MemoryStats describe how full a bounded in-memory repository is
+type MemoryStats struct {
+	Capacity  int   // most URLs held, 0 when unbounded
+	Size      int   // URLs held
+	Evictions int64 // URLs evicted to make room since the process started
+}
+
+// MemoryStatsReporter is implemented by URL repositories holding their URLs in memory
+type MemoryStatsReporter interface {
+	MemoryStats() MemoryStats
+}
+
+// AliasLengthCount is the number of URLs whose short codes have a length, counted apart
+// for custom aliases and generated codes
+type AliasLengthCount struct {
+	Custom bool  `db:"custom"`
+	Length int   `db:"length"`
+	URLs   int64 `db:"urls"`
+}
+
+// DailyActivity is the number of URLs created and clicks made on a UTC day
+type DailyActivity struct {
+	Day         time.Time `db:"day"` // midnight UTC
+	URLsCreated int64     `db:"urls_created"`
+	Clicks      int64     `db:"clicks"`
+}
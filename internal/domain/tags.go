@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Tags are free form labels of a short URL, stored as a single JSON document
+type Tags []string
+
+// Value stores the tags as JSON text, which both JSONB and TEXT columns accept. URLs
+// without tags store NULL.
+func (t Tags) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]string(t))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads tags stored by Value
+func (t *Tags) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, (*[]string)(t))
+	case string:
+		return json.Unmarshal([]byte(data), (*[]string)(t))
+	default:
+		return fmt.Errorf("cannot scan %T into Tags", src)
+	}
+}
@@ -2,6 +2,8 @@ package domain
 
 import (
 	"errors"
+	"net/http"
+	"strings"
 	"time"
 )
 
@@ -10,15 +12,86 @@ var (
 	ErrShortCodeExists  = errors.New("short code already exists")
 	ErrInvalidURL       = errors.New("invalid url")
 	ErrInvalidShortCode = errors.New("invalid short code")
+	ErrInvalidPassword  = errors.New("invalid password")
+	ErrRedirectLoop     = errors.New("redirect loop detected")
+	ErrReservedAlias    = errors.New("alias is reserved")
+)
+
+// reservedAliases are refused as custom aliases, since short URLs under them would read
+// as links to the service itself
+var reservedAliases = map[string]bool{
+	"admin":   true,
+	"api":     true,
+	"health":  true,
+	"metrics": true,
+	"swagger": true,
+}
+
+// IsReservedAlias reports whether alias is reserved, ignoring case
+func IsReservedAlias(alias string) bool {
+	return reservedAliases[strings.ToLower(alias)]
+}
+
+// Health statuses of a URL's destination, as determined by the background health checker
+const (
+	HealthStatusUnknown = "unknown"
+	HealthStatusHealthy = "healthy"
+	HealthStatusDead    = "dead"
 )
 
 type URL struct {
-	ID          int64     `db:"id" json:"id"`
-	ShortCode   string    `db:"short_code" json:"shortCode"`
-	OriginalURL string    `db:"original_url" json:"originalUrl"`
-	Clicks      int       `db:"clicks" json:"clicks"`
-	CreatedAt   time.Time `db:"created_at" json:"createdAt"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updatedAt"`
+	ID            int64      `db:"id" json:"id"`
+	Namespace     string     `db:"namespace" json:"namespace"`
+	ShortCode     string     `db:"short_code" json:"shortCode"`
+	OriginalURL   string     `db:"original_url" json:"originalUrl"`
+	Clicks        int        `db:"clicks" json:"clicks"`
+	UniqueClicks  int        `db:"unique_clicks" json:"uniqueClicks"` // clicks without repeat visits within the deduplication window
+	CreatedAt     time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updatedAt"`
+	PasswordHash  string     `db:"password_hash" json:"passwordHash,omitempty"`
+	HealthStatus  string     `db:"health_status" json:"healthStatus"`
+	LastCheckedAt *time.Time `db:"last_checked_at" json:"lastCheckedAt,omitempty"`
+	// LastAccessedAt is the time of the latest click, nil while the URL was never clicked
+	LastAccessedAt *time.Time `db:"last_accessed_at" json:"lastAccessedAt,omitempty"`
+	// RedirectType is the HTTP status of plain redirects, 0 meaning 301 Moved Permanently
+	RedirectType int `db:"redirect_type" json:"redirectType,omitempty"`
+	// DelaySeconds, when positive, shows a countdown page for that long instead of redirecting at once
+	DelaySeconds int `db:"delay_seconds" json:"delaySeconds,omitempty"`
+	// ExpiresAt, when set, is the moment the URL stops redirecting
+	ExpiresAt *time.Time `db:"expires_at" json:"expiresAt,omitempty"`
+	Tags      Tags       `db:"tags" json:"tags,omitempty"`
+	// Description is a free text label for operators, stored as NULL when empty
+	Description string `db:"description" json:"description,omitempty"`
+	// Signed URLs are only reachable through an unexpired signed token, never by their plain code
+	Signed bool `db:"signed" json:"signed,omitempty"`
+	// IsCustomAlias is set when the creator chose the short code rather than having it generated
+	IsCustomAlias bool `db:"is_custom_alias" json:"isCustomAlias,omitempty"`
+	// ClickGoal, when set, is the number of clicks the URL aims for; GoalReached is set by
+	// the click reaching it
+	ClickGoal   *int `db:"click_goal" json:"clickGoal,omitempty"`
+	GoalReached bool `db:"goal_reached" json:"goalReached"`
+	// Enabled is false while an operator suspends the URL, which then answers with 410 Gone
+	Enabled bool `db:"enabled" json:"enabled"`
+	// CreatedByIP is the address of the client that created the URL, empty when unknown
+	CreatedByIP string `db:"created_by_ip" json:"createdByIp,omitempty"`
+	// Title is the title of the destination page, fetched after creation when enabled and
+	// empty until then or when the page has none
+	Title string `db:"title" json:"title,omitempty"`
+
+	// PoolEnabled URLs redirect to the targets of Pool in turn; OriginalURL holds the first one
+	PoolEnabled bool     `db:"pool_enabled" json:"poolEnabled,omitempty"`
+	Pool        *URLPool `db:"pool" json:"pool,omitempty"`
+
+	// Variants, when present, replace OriginalURL as the redirect destination
+	Variants []URLVariant `db:"-" json:"variants,omitempty"`
+
+	// GeoRoutes override every other destination for visitors from the listed countries
+	GeoRoutes GeoRoutes `db:"geo_routes" json:"geoRoutes,omitempty"`
+	// DeviceRoutes do the same by device type, for visitors not matched by a geo route
+	DeviceRoutes DeviceRoutes `db:"device_routes" json:"deviceRoutes,omitempty"`
+
+	// Stale marks a copy served from the stale cache because the repository failed
+	Stale bool `db:"-" json:"-"`
 }
 
 func NewURL(shortCode, originalURL string) (*URL, error) {
@@ -31,14 +104,43 @@ func NewURL(shortCode, originalURL string) (*URL, error) {
 
 	now := time.Now()
 	return &URL{
-		ShortCode:   shortCode,
-		OriginalURL: originalURL,
-		Clicks:      0,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		Namespace:    DefaultNamespace,
+		ShortCode:    shortCode,
+		OriginalURL:  originalURL,
+		Clicks:       0,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		HealthStatus: HealthStatusUnknown,
+		Enabled:      true,
 	}, nil
 }
 
 func (u *URL) IncrementClicks() {
 	u.Clicks++
 }
+
+// EffectiveRedirectType is the HTTP status used for plain redirects
+func (u *URL) EffectiveRedirectType() int {
+	if u.RedirectType == 0 {
+		return http.StatusMovedPermanently
+	}
+	return u.RedirectType
+}
+
+// IsExpired reports whether the URL has an expiry that has passed at now
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
+
+// LastActivity is when the URL was last clicked, or created when it never was
+func (u *URL) LastActivity() time.Time {
+	if u.LastAccessedAt != nil {
+		return *u.LastAccessedAt
+	}
+	return u.CreatedAt
+}
+
+// IsPasswordProtected reports whether a passphrase is required to access the URL
+func (u *URL) IsPasswordProtected() bool {
+	return u.PasswordHash != ""
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"math/rand"
+)
+
+// URLVariant is one of several destinations a short URL splits its traffic between
+type URLVariant struct {
+	ID          int64  `db:"id" json:"id"`
+	URLID       int64  `db:"url_id" json:"-"`
+	OriginalURL string `db:"original_url" json:"originalUrl"`
+	Weight      int    `db:"weight" json:"weight"`
+}
+
+// VariantStat is the number of clicks that were sent to one variant
+type VariantStat struct {
+	VariantID   int64  `db:"variant_id" json:"variantId" example:"1"`
+	OriginalURL string `db:"original_url" json:"originalUrl" example:"https://example.com/landing-b"`
+	Weight      int    `db:"weight" json:"weight" example:"30"`
+	Clicks      int    `db:"clicks" json:"clicks" example:"42"`
+}
+
+// HasVariants reports whether the URL splits its traffic between several destinations
+func (u *URL) HasVariants() bool {
+	return len(u.Variants) > 0
+}
+
+// PickVariant selects a variant at random, each one chosen in proportion to its weight.
+// It returns nil when the URL has no variants.
+func (u *URL) PickVariant() *URLVariant {
+	totalWeight := 0
+	for _, variant := range u.Variants {
+		totalWeight += variant.Weight
+	}
+	if totalWeight <= 0 {
+		return nil
+	}
+
+	n := rand.Intn(totalWeight) //nolint:gosec // traffic splitting does not need a secure source
+	for i := range u.Variants {
+		if n < u.Variants[i].Weight {
+			return &u.Variants[i]
+		}
+		n -= u.Variants[i].Weight
+	}
+	return nil
+}
@@ -12,3 +12,9 @@ var HTTPServerModules = fx.Options(
 	httpFX.HTTPModule,
 	httpFX.HTTPLifecycleModule,
 )
+
+// SeedOnlyModules creates the URLs of the seed file without serving HTTP
+var SeedOnlyModules = fx.Options(
+	CoreModules,
+	fx.Invoke(RequireSeedFile),
+)
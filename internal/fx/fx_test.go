@@ -2,14 +2,22 @@ package fx
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxtest"
 
@@ -18,6 +26,11 @@ import (
 	"github.com/sp3dr4/dove/internal/domain"
 	httpFX "github.com/sp3dr4/dove/internal/fx/http"
 	cacheImpl "github.com/sp3dr4/dove/internal/infrastructure/cache"
+	"github.com/sp3dr4/dove/internal/infrastructure/memory"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+	"github.com/sp3dr4/dove/internal/pkg/shortcode"
 )
 
 func TestFXIntegration(t *testing.T) {
@@ -36,8 +49,9 @@ func TestFXIntegration(t *testing.T) {
 					Type: "memory",
 				},
 				App: config.AppConfig{
-					BaseURL:         "http://localhost:8080",
-					ShortCodeLength: 6,
+					BaseURL:          "http://localhost:8080",
+					ShortCodeLength:  6,
+					ShortCodeCharset: "alphanumeric",
 				},
 				Cache: config.CacheConfig{
 					Enabled: false, // Disable cache for tests
@@ -48,6 +62,7 @@ func TestFXIntegration(t *testing.T) {
 		// Use the same providers as the main app
 		InfrastructureModule,
 		ApplicationModule,
+		MetricsModule,
 		httpFX.HTTPModule,
 
 		// Test that we can get the service
@@ -73,6 +88,167 @@ func TestFXIntegration(t *testing.T) {
 	app.RequireStop()
 }
 
+func TestRegisterSeedHooks(t *testing.T) {
+	seedFile := filepath.Join(t.TempDir(), "seed.yaml")
+	require.NoError(t, os.WriteFile(seedFile, []byte(`urls:
+  - shortCode: docs
+    originalUrl: https://example.com/docs
+    tags: [docs, launch]
+    expiresAt: 2999-01-01T00:00:00Z
+  - shortCode: blog
+    originalUrl: https://example.com/blog
+  - shortCode: broken
+    originalUrl: not-a-url
+`), 0600))
+
+	var router chi.Router
+	var repo domain.URLRepository
+	app := fxtest.New(t,
+		fx.Provide(func() (*config.Config, error) {
+			return &config.Config{
+				Server:   config.ServerConfig{Port: "8080"},
+				Database: config.DatabaseConfig{Type: "memory"},
+				App:      config.AppConfig{BaseURL: "http://localhost:8080", ShortCodeLength: 6, ShortCodeCharset: "alphanumeric", SeedFile: seedFile},
+			}, nil
+		}),
+		InfrastructureModule,
+		ApplicationModule,
+		MetricsModule,
+		httpFX.HTTPModule,
+		fx.Invoke(RegisterSeedHooks),
+		fx.Populate(&router, &repo),
+	)
+	app.RequireStart()
+	t.Cleanup(app.RequireStop)
+
+	for shortCode, destination := range map[string]string{"docs": "https://example.com/docs", "blog": "https://example.com/blog"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+shortCode, nil))
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code, shortCode)
+		assert.Equal(t, destination, rec.Header().Get("Location"), shortCode)
+	}
+
+	docs, err := repo.FindByNamespaceAndCode(context.Background(), domain.DefaultNamespace, "docs")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Tags{"docs", "launch"}, docs.Tags)
+	require.NotNil(t, docs.ExpiresAt)
+	assert.Equal(t, 2999, docs.ExpiresAt.Year())
+
+	_, err = repo.FindByNamespaceAndCode(context.Background(), domain.DefaultNamespace, "broken")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	t.Run("unreadable seed file stops the start", func(t *testing.T) {
+		app := fx.New(
+			fx.Provide(func() *config.Config {
+				return &config.Config{Database: config.DatabaseConfig{Type: "memory"}, App: config.AppConfig{ShortCodeLength: 6, ShortCodeCharset: "alphanumeric", SeedFile: filepath.Join(t.TempDir(), "missing.yaml")}}
+			}),
+			InfrastructureModule,
+			ApplicationModule,
+			MetricsModule,
+			fx.Invoke(RegisterSeedHooks),
+			fx.NopLogger,
+		)
+		require.NoError(t, app.Err())
+		assert.Error(t, app.Start(context.Background()))
+	})
+
+	t.Run("seed-only needs a seed file", func(t *testing.T) {
+		assert.Error(t, RequireSeedFile(&config.Config{}))
+		assert.NoError(t, RequireSeedFile(&config.Config{App: config.AppConfig{SeedFile: seedFile}}))
+	})
+}
+
+// unreachableRepository fails to list the most clicked URLs, as during a database outage
+type unreachableRepository struct {
+	mockRepository
+}
+
+func (r *unreachableRepository) TopByClicks(context.Context, int) ([]*domain.URL, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestRegisterCacheWarmerHooks(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := application.NewURLService(&unreachableRepository{}, cacheImpl.NewNoOpCache(), time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+
+	for name, cache := range map[string]config.CacheConfig{
+		"enabled":  {Enabled: true, WarmEnabled: true, WarmCount: 10},
+		"disabled": {Enabled: true, WarmCount: 10},
+		"no cache": {WarmEnabled: true, WarmCount: 10},
+	} {
+		t.Run(name, func(t *testing.T) {
+			lc := fxtest.NewLifecycle(t)
+			RegisterCacheWarmerHooks(lc, CacheWarmerParams{Service: service, Config: &config.Config{Cache: cache}, Logger: logger})
+
+			// A failed warm-up does not stop the start
+			lc.RequireStart()
+			lc.RequireStop()
+		})
+	}
+}
+
+func TestRegisterGoalMetricsHooks(t *testing.T) {
+	var router chi.Router
+	app := fxtest.New(t,
+		fx.Provide(func() (*config.Config, error) {
+			return &config.Config{
+				Server:   config.ServerConfig{Port: "8080"},
+				Database: config.DatabaseConfig{Type: "memory"},
+				App:      config.AppConfig{BaseURL: "http://localhost:8080", ShortCodeLength: 6, ShortCodeCharset: "alphanumeric"},
+				Metrics:  config.MetricsConfig{Enabled: true, Path: "/metrics", Namespace: "dove", Backend: "prometheus"},
+			}, nil
+		}),
+		InfrastructureModule,
+		ApplicationModule,
+		MetricsModule,
+		httpFX.HTTPModule,
+		fx.Invoke(RegisterGoalMetricsHooks),
+		fx.Populate(&router),
+	)
+	app.RequireStart()
+	t.Cleanup(app.RequireStop)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+	require.Equal(t, http.StatusCreated, serve(http.MethodPost, "/shorten", `{"url": "https://example.com", "customAlias": "campaign", "clickGoal": 2}`).Code)
+	for range 3 {
+		require.Equal(t, http.StatusMovedPermanently, serve(http.MethodGet, "/campaign", "").Code)
+	}
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(serve(http.MethodGet, "/metrics", "").Body.String(), "dove_url_goals_reached_total 1")
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, serve(http.MethodGet, "/shorten/campaign", "").Body.String(), `"goalReached":true`)
+}
+
+func TestProvideHealthChecker_RefusesPrivateDestinations(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var hits atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+	}))
+	t.Cleanup(internal.Close)
+
+	repo := memory.NewURLRepository(logger, 0)
+	url, err := domain.NewURL("internal", internal.URL)
+	require.NoError(t, err)
+	_, err = repo.Create(context.Background(), url)
+	require.NoError(t, err)
+
+	cfg := &config.Config{HealthChecker: config.HealthCheckerConfig{BatchSize: 10, WorkerCount: 1}}
+	checker := ProvideHealthChecker(cfg, repo, cacheImpl.NewNoOpCache(), logger)
+	require.NoError(t, checker.RunOnce(context.Background()))
+
+	// The health of internal services is not probed, so it cannot be read from the URL
+	assert.Zero(t, hits.Load())
+	checked, err := repo.FindByNamespaceAndCode(context.Background(), domain.DefaultNamespace, "internal")
+	require.NoError(t, err)
+	assert.Equal(t, domain.HealthStatusDead, checked.HealthStatus)
+}
+
 func TestFXModules(t *testing.T) {
 	// Test that individual modules can be loaded
 	tests := []struct {
@@ -112,7 +288,7 @@ func TestFXModules(t *testing.T) {
 				options = append(options, fx.Provide(func(repo domain.URLRepository) *application.URLService {
 					logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 					noopCache := cacheImpl.NewNoOpCache()
-					return application.NewURLService(repo, noopCache, 10*time.Minute, logger)
+					return application.NewURLService(repo, noopCache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
 				}))
 			}
 
@@ -128,9 +304,18 @@ func TestFXModules(t *testing.T) {
 
 func TestConfigModule(t *testing.T) {
 	// Test ConfigModule separately since it provides config
-	app := fxtest.New(t, ConfigModule)
+	var cfg *config.Config
+	app := fxtest.New(t, ConfigModule, fx.Populate(&cfg))
 	app.RequireStart()
 	app.RequireStop()
+	assert.Equal(t, "memory", cfg.Database.Type)
+
+	t.Run("rejects invalid config", func(t *testing.T) {
+		t.Setenv("DATABASE_TYPE", "postgress")
+
+		app := fx.New(ConfigModule, fx.Invoke(func(*config.Config) {}), fx.NopLogger)
+		require.ErrorIs(t, app.Err(), config.ErrInvalidConfig)
+	})
 }
 func TestProviderFunctions(t *testing.T) {
 	t.Run("ProvideLogger", func(t *testing.T) {
@@ -169,10 +354,80 @@ func TestProviderFunctions(t *testing.T) {
 		// Create a chi router for testing
 		router := chi.NewRouter()
 
-		server := httpFX.ProvideHTTPServer(cfg, router)
+		server, err := httpFX.ProvideHTTPServer(cfg, router, nil)
+		require.NoError(t, err)
 		assert.NotNil(t, server)
 		assert.Equal(t, ":8080", server.Addr())
 	})
+
+	t.Run("ProvideSigningSecret", func(t *testing.T) {
+		secret, err := ProvideSigningSecret(&config.Config{})
+		require.NoError(t, err)
+		assert.Empty(t, secret)
+
+		_, err = ProvideSigningSecret(&config.Config{App: config.AppConfig{SigningSecret: "too-short"}})
+		assert.Error(t, err)
+
+		secret, err = ProvideSigningSecret(&config.Config{App: config.AppConfig{SigningSecret: "0123456789abcdef0123456789abcdef"}})
+		require.NoError(t, err)
+		assert.Len(t, secret, 32)
+	})
+
+	t.Run("ProvideShortCodeGenerator", func(t *testing.T) {
+		generator, err := ProvideShortCodeGenerator(&config.Config{App: config.AppConfig{ShortCodeCharset: "hex"}})
+		require.NoError(t, err)
+		assert.NotNil(t, generator)
+
+		// Custom alphabets are checked at startup
+		_, err = ProvideShortCodeGenerator(&config.Config{App: config.AppConfig{ShortCodeCharset: "custom", CustomCharset: "abc"}})
+		assert.ErrorIs(t, err, shortcode.ErrInvalidCharset)
+	})
+
+	t.Run("ProvideCreateHooks", func(t *testing.T) {
+		hooks, err := ProvideCreateHooks(&config.Config{})
+		require.NoError(t, err)
+		assert.Empty(t, hooks.Pre)
+		assert.Empty(t, hooks.Post)
+
+		hooks, err = ProvideCreateHooks(&config.Config{App: config.AppConfig{CreateHooks: config.CreateHooksConfig{
+			BlockedDomains: []string{"evil.example"},
+			WebhookURL:     "https://hooks.example.com/dove",
+			WebhookTimeout: "2s",
+		}}})
+		require.NoError(t, err)
+		assert.Len(t, hooks.Pre, 1)
+		assert.Len(t, hooks.Post, 1)
+	})
+
+	t.Run("ProvideMetricsRegistry", func(t *testing.T) {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		cfg := &config.Config{Metrics: config.MetricsConfig{Enabled: true, Namespace: "dove", Backend: "prometheus"}}
+
+		meterProvider, err := ProvideMeterProvider(cfg)
+		require.NoError(t, err)
+		registry, err := ProvideMetricsRegistry(cfg, meterProvider, logger)
+		require.NoError(t, err)
+		assert.IsType(t, &metrics.PrometheusRegistry{}, registry)
+
+		// The otel registry only needs a meter provider, the OTLP exporter is tested by the integration suite
+		cfg.Metrics.Backend = "otel"
+		registry, err = ProvideMetricsRegistry(cfg, noop.NewMeterProvider(), logger)
+		require.NoError(t, err)
+		assert.IsType(t, &metrics.OTelRegistry{}, registry)
+		assert.Nil(t, registry.GetHandler())
+
+		_, err = ProvideMeterProvider(&config.Config{Metrics: config.MetricsConfig{Enabled: true, Backend: "otel"}})
+		assert.Error(t, err)
+
+		for _, metricsConfig := range []config.MetricsConfig{
+			{Enabled: true, Backend: "prometheus", HTTPBuckets: []float64{}},
+			{Enabled: true, Backend: "prometheus", DBBuckets: []float64{1, 0.5}},
+			{Enabled: true, Backend: "otel", HTTPBuckets: []float64{0.1, 0.1}},
+		} {
+			_, err = ProvideMetricsRegistry(&config.Config{Metrics: metricsConfig}, noop.NewMeterProvider(), logger)
+			assert.Error(t, err, "buckets %v %v", metricsConfig.HTTPBuckets, metricsConfig.DBBuckets)
+		}
+	})
 }
 
 // mockRepository is a simple mock repository for testing
@@ -186,14 +441,138 @@ func (m *mockRepository) FindByShortCode(ctx context.Context, shortCode string)
 	return &domain.URL{ShortCode: shortCode, OriginalURL: "https://example.com"}, nil
 }
 
-func (m *mockRepository) IncrementClicks(ctx context.Context, shortCode string) (*domain.URL, error) {
-	return &domain.URL{ShortCode: shortCode, OriginalURL: "https://example.com", Clicks: 1}, nil
+func (m *mockRepository) FindByNamespaceAndCode(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
+	return &domain.URL{Namespace: namespace, ShortCode: shortCode, OriginalURL: "https://example.com"}, nil
+}
+
+func (m *mockRepository) FindByShortCodes(ctx context.Context, namespace string, shortCodes []string) ([]*domain.URL, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) IncrementClicks(ctx context.Context, namespace, shortCode string, unique bool) (*domain.URL, error) {
+	return &domain.URL{Namespace: namespace, ShortCode: shortCode, OriginalURL: "https://example.com", Clicks: 1}, nil
+}
+
+func (m *mockRepository) Update(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	return url, nil
+}
+
+func (m *mockRepository) Exists(ctx context.Context, namespace, shortCode string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockRepository) List(ctx context.Context, afterID int64, limit int) ([]*domain.URL, error) {
+	return []*domain.URL{}, nil
+}
+
+func (m *mockRepository) ListByCreatorIP(ctx context.Context, ip string, afterID int64, limit int) ([]*domain.URL, error) {
+	return []*domain.URL{}, nil
+}
+
+func (m *mockRepository) Search(ctx context.Context, query string, limit int) ([]*domain.URL, error) {
+	return []*domain.URL{}, nil
+}
+
+func (m *mockRepository) ListForHealthCheck(ctx context.Context, limit int) ([]*domain.URL, error) {
+	return []*domain.URL{}, nil
+}
+
+func (m *mockRepository) FindExpired(ctx context.Context, before time.Time, limit int) ([]*domain.URL, error) {
+	return []*domain.URL{}, nil
+}
+
+func (m *mockRepository) TopByClicks(ctx context.Context, n int) ([]*domain.URL, error) {
+	return []*domain.URL{}, nil
+}
+
+func (m *mockRepository) UpdateHealthStatus(ctx context.Context, namespace, shortCode, status string, checkedAt time.Time) error {
+	return nil
+}
+
+func (m *mockRepository) AddClicks(ctx context.Context, deltas []domain.ClickDelta) error {
+	return nil
+}
+
+func (m *mockRepository) FindNotAccessedSince(ctx context.Context, since time.Time, limit int) ([]*domain.URL, error) {
+	return []*domain.URL{}, nil
+}
+
+func (m *mockRepository) SetEnabled(ctx context.Context, namespace, shortCode string, enabled bool) error {
+	return nil
 }
 
-func (m *mockRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
+func (m *mockRepository) MarkGoalReached(ctx context.Context, namespace, shortCode string) (bool, error) {
 	return false, nil
 }
 
+func (m *mockRepository) RecordClick(ctx context.Context, click *domain.Click) error {
+	return nil
+}
+
+func (m *mockRepository) ListClicks(ctx context.Context, namespace, shortCode string, after domain.ClickCursor, limit int) ([]*domain.Click, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) ClickTimeSeries(ctx context.Context, namespace, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.TimeBucket, error) {
+	return []domain.TimeBucket{}, nil
+}
+
+func (m *mockRepository) ResetClicks(ctx context.Context, namespace, shortCode string) error {
+	return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, namespace, shortCode string) error {
+	return nil
+}
+
+func (m *mockRepository) DeleteMany(ctx context.Context, namespace string, shortCodes []string) ([]*domain.URL, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) ClickHeatmap(ctx context.Context, namespace, shortCode string, loc *time.Location) (*domain.ClickHeatmap, error) {
+	return &domain.ClickHeatmap{}, nil
+}
+
+func (m *mockRepository) TopReferrers(ctx context.Context, namespace, shortCode string, limit int) ([]domain.ReferrerCount, error) {
+	return []domain.ReferrerCount{}, nil
+}
+
+func (m *mockRepository) TopIPs(ctx context.Context, limit int) ([]domain.IPCount, error) {
+	return []domain.IPCount{}, nil
+}
+
+func (m *mockRepository) DeviceStats(ctx context.Context, namespace, shortCode string, grouping domain.DeviceGrouping) ([]domain.DeviceStat, error) {
+	return []domain.DeviceStat{}, nil
+}
+
+func (m *mockRepository) VariantStats(ctx context.Context, namespace, shortCode string) ([]domain.VariantStat, error) {
+	return []domain.VariantStat{}, nil
+}
+
+func (m *mockRepository) RedirectLatency(ctx context.Context, namespace, shortCode string) (*domain.LatencyStats, error) {
+	return &domain.LatencyStats{}, nil
+}
+
+func (m *mockRepository) CountActive(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockRepository) CountByCreatedDate(ctx context.Context, date time.Time) (int, error) {
+	return 0, nil
+}
+
+func (m *mockRepository) DailyActivity(ctx context.Context, from, to time.Time) ([]domain.DailyActivity, error) {
+	return []domain.DailyActivity{}, nil
+}
+
+func (m *mockRepository) AliasLengths(ctx context.Context) ([]domain.AliasLengthCount, error) {
+	return []domain.AliasLengthCount{}, nil
+}
+
+func (m *mockRepository) Stats(ctx context.Context, now, since time.Time) (*domain.ServiceStats, error) {
+	return &domain.ServiceStats{}, nil
+}
+
 func (m *mockRepository) Close() error {
 	return nil
 }
@@ -3,10 +3,12 @@ package http
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	"go.uber.org/fx"
 
 	"github.com/sp3dr4/dove/config"
+	httpAdapter "github.com/sp3dr4/dove/internal/adapters/http"
 	"github.com/sp3dr4/dove/internal/server"
 )
 
@@ -26,6 +28,7 @@ func RegisterHTTPServerHooks(lc fx.Lifecycle, params ServerParams) {
 			params.Logger.Info("Starting HTTP server",
 				"addr", params.Server.Addr(),
 				"database", params.Config.Database.Type,
+				"tls", params.Config.TLS.Enabled,
 				"base_url", params.Config.App.BaseURL,
 			)
 			return params.Server.Start(ctx)
@@ -41,3 +44,37 @@ func RegisterHTTPServerHooks(lc fx.Lifecycle, params ServerParams) {
 		},
 	})
 }
+
+// NotFoundPageParams holds the parameters needed for custom not found page lifecycle management
+type NotFoundPageParams struct {
+	fx.In
+
+	Page   *httpAdapter.NotFoundPage
+	Config *config.Config
+	Logger *slog.Logger
+}
+
+// RegisterNotFoundPageHooks loads the custom not found page on start, failing the start
+// when it cannot be loaded, and reloads it every app.custom_404.cache_duration_seconds
+func RegisterNotFoundPageHooks(lc fx.Lifecycle, params NotFoundPageParams) {
+	if params.Page == nil {
+		return
+	}
+	interval := time.Duration(params.Config.App.Custom404.CacheDurationSeconds) * time.Second
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := params.Page.Load(ctx); err != nil {
+				return err
+			}
+			params.Logger.Info("Custom not found page loaded", "reload_interval", interval)
+			if interval > 0 {
+				params.Page.StartReloading(interval)
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return params.Page.Stop(ctx)
+		},
+	})
+}
@@ -9,21 +9,31 @@ import (
 	"github.com/sp3dr4/dove/config"
 	httpAdapter "github.com/sp3dr4/dove/internal/adapters/http"
 	"github.com/sp3dr4/dove/internal/pkg/metrics"
+	"github.com/sp3dr4/dove/internal/pkg/ratelimit"
+	"github.com/sp3dr4/dove/internal/pkg/shortcode"
 )
 
-// ProvideRouter creates a chi router with all dependencies
-func ProvideRouter(handlers *httpAdapter.Handlers, logger *slog.Logger, cfg *config.Config, metricsRegistry metrics.Registry) chi.Router {
-	return httpAdapter.NewRouter(handlers, logger, cfg, metricsRegistry)
+// ProvideRouter creates a chi router with all dependencies and reserves its paths, so that
+// no short URL shadows a route
+func ProvideRouter(handlers *httpAdapter.Handlers, migrationHandlers *httpAdapter.MigrationHandlers, logger *slog.Logger, cfg *config.Config, metricsRegistry metrics.Registry, inFlight *httpAdapter.InFlightTracker, limiter ratelimit.Limiter, reserved *shortcode.ReservedWords) chi.Router {
+	router := httpAdapter.NewRouter(handlers, migrationHandlers, logger, cfg, metricsRegistry, inFlight, limiter)
+	reserved.Add(httpAdapter.RouteWords(router)...)
+	return router
 }
 
 // HTTPModule provides HTTP-related dependencies
 var HTTPModule = fx.Module("http",
+	fx.Provide(ProvideNotFoundPage),
 	fx.Provide(ProvideHandlers),
+	fx.Provide(ProvideMigrationHandlers),
+	fx.Provide(httpAdapter.NewInFlightTracker),
+	fx.Provide(ProvideRateLimiter),
 	fx.Provide(ProvideRouter),
 	fx.Provide(ProvideHTTPServer),
 )
 
 // HTTPLifecycleModule provides HTTP server lifecycle management
 var HTTPLifecycleModule = fx.Module("http-lifecycle",
+	fx.Invoke(RegisterNotFoundPageHooks),
 	fx.Invoke(RegisterHTTPServerHooks),
 )
@@ -2,34 +2,67 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"go.uber.org/fx"
+	"golang.org/x/time/rate"
 
 	"github.com/sp3dr4/dove/config"
 	httpAdapter "github.com/sp3dr4/dove/internal/adapters/http"
 	"github.com/sp3dr4/dove/internal/application"
 	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/ratelimit"
+	"github.com/sp3dr4/dove/internal/pkg/selfsigned"
 	"github.com/sp3dr4/dove/internal/server"
 )
 
 // HTTPServer implements the generic Server interface for HTTP
 type HTTPServer struct {
-	server *http.Server
+	server       *http.Server
+	tls          bool
+	certFile     string
+	keyFile      string
+	inFlight     *httpAdapter.InFlightTracker
+	drainTimeout time.Duration
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server, serving HTTPS when TLS is enabled
 func (s *HTTPServer) Start(ctx context.Context) error {
 	go func() {
+		if s.tls {
+			_ = s.server.ListenAndServeTLS(s.certFile, s.keyFile)
+			return
+		}
 		_ = s.server.ListenAndServe()
 	}()
 	return nil
 }
 
-// Stop stops the HTTP server gracefully
+// Stop stops accepting connections, then waits for the requests in flight to finish,
+// for at most the drain timeout when one is set
 func (s *HTTPServer) Stop(ctx context.Context) error {
-	return s.server.Shutdown(ctx)
+	if s.drainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.drainTimeout)
+		defer cancel()
+	}
+
+	err := s.server.Shutdown(ctx)
+	if s.inFlight == nil {
+		return err
+	}
+	// Shutdown does not wait for hijacked connections, and gives up at the deadline
+	if waitErr := s.inFlight.Wait(ctx); err == nil {
+		err = waitErr
+	}
+	return err
 }
 
 // Addr returns the server address
@@ -37,14 +70,22 @@ func (s *HTTPServer) Addr() string {
 	return s.server.Addr
 }
 
-// ProvideHTTPServer creates an HTTP server that implements the Server interface
-func ProvideHTTPServer(cfg *config.Config, router chi.Router) server.Server {
+// ProvideHTTPServer creates an HTTP server that implements the Server interface. Stop
+// drains the requests tracked by inFlight, which may be nil, after ending the event streams.
+func ProvideHTTPServer(cfg *config.Config, router chi.Router, inFlight *httpAdapter.InFlightTracker) (server.Server, error) {
 	srv := &http.Server{
 		Addr:              ":" + cfg.Server.Port,
 		Handler:           router,
 		ReadHeaderTimeout: 30 * time.Second,
 	}
 
+	// Shutdown leaves request contexts alive, so long-lived event streams learn of it here
+	shutdown := make(chan struct{})
+	srv.BaseContext = func(net.Listener) context.Context {
+		return httpAdapter.WithShutdown(context.Background(), shutdown)
+	}
+	srv.RegisterOnShutdown(sync.OnceFunc(func() { close(shutdown) }))
+
 	if timeout, err := time.ParseDuration(cfg.Server.ReadTimeout); err == nil {
 		srv.ReadTimeout = timeout
 	}
@@ -55,10 +96,118 @@ func ProvideHTTPServer(cfg *config.Config, router chi.Router) server.Server {
 		srv.IdleTimeout = timeout
 	}
 
-	return &HTTPServer{server: srv}
+	httpServer := &HTTPServer{server: srv, inFlight: inFlight}
+	if timeout, err := time.ParseDuration(cfg.Server.DrainTimeout); err == nil {
+		httpServer.drainTimeout = timeout
+	}
+	if cfg.TLS.Enabled {
+		if err := configureTLS(httpServer, cfg.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	return httpServer, nil
+}
+
+// configureTLS prepares s to serve HTTPS. Certificate problems are reported here rather
+// than from the serving goroutine, so a misconfigured server fails at startup.
+func configureTLS(s *HTTPServer, cfg config.TLSConfig) error {
+	minVersion, err := parseTLSVersion(cfg.MinVersion)
+	if err != nil {
+		return err
+	}
+	s.server.TLSConfig = &tls.Config{MinVersion: minVersion}
+	s.tls = true
+
+	if cfg.AutoCert {
+		certPEM, keyPEM, err := selfsigned.Generate(selfsigned.DefaultHosts...)
+		if err != nil {
+			return fmt.Errorf("failed to generate self-signed certificate: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("failed to load self-signed certificate: %w", err)
+		}
+		// With the certificate already in TLSConfig, ListenAndServeTLS takes no files
+		s.server.TLSConfig.Certificates = []tls.Certificate{cert}
+		return nil
+	}
+
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return fmt.Errorf("tls.cert_file and tls.key_file are required when TLS is enabled without auto_cert")
+	}
+	if _, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile); err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	s.certFile = cfg.CertFile
+	s.keyFile = cfg.KeyFile
+	return nil
+}
+
+// parseTLSVersion maps the configured minimum version to its crypto/tls constant
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "TLS1.2":
+		return tls.VersionTLS12, nil
+	case "TLS1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS min_version %q, expected TLS1.2 or TLS1.3", version)
+	}
 }
 
 // ProvideHandlers creates HTTP handlers with proper dependencies
-func ProvideHandlers(service *application.URLService, cfg *config.Config, repo domain.URLRepository) *httpAdapter.Handlers {
-	return httpAdapter.NewHandlers(service, cfg.App.BaseURL, repo)
+func ProvideHandlers(service *application.URLService, cfg *config.Config, repo domain.URLRepository, cache domain.Cache, notFoundPage *httpAdapter.NotFoundPage) *httpAdapter.Handlers {
+	if !cfg.Cache.Enabled {
+		cache = nil
+	}
+	deps := httpAdapter.HandlersDeps{
+		ShortURLScheme:  cfg.App.ShortURLScheme,
+		Cache:           cache,
+		DegradedCacheOK: cfg.Server.DegradedCacheOK,
+		NotFoundPage:    notFoundPage,
+	}
+	if cfg.App.TrustForwardedHeaders {
+		deps.ForwardedHosts = httpAdapter.NewForwardedHosts(cfg.App.AllowedHosts)
+	}
+	return httpAdapter.NewHandlers(service, cfg.App.BaseURL, repo, deps)
+}
+
+// ProvideNotFoundPage creates the custom not found page, nil when it is disabled
+func ProvideNotFoundPage(cfg *config.Config, logger *slog.Logger) *httpAdapter.NotFoundPage {
+	if !cfg.App.Custom404.Enabled {
+		return nil
+	}
+	return httpAdapter.NewNotFoundPage(cfg.App.Custom404.FilePath, cfg.App.Custom404.RemoteURL, logger)
+}
+
+// MigrationHandlersParams holds the dependencies of the migration endpoints
+type MigrationHandlersParams struct {
+	fx.In
+
+	Migrations domain.MigrationRepository `optional:"true"`
+	Stores     domain.StoreMigrator       `optional:"true"`
+}
+
+// ProvideMigrationHandlers creates the migration handlers, nil when the repository has no
+// schema and stores cannot be migrated
+func ProvideMigrationHandlers(params MigrationHandlersParams) *httpAdapter.MigrationHandlers {
+	if params.Migrations == nil && params.Stores == nil {
+		return nil
+	}
+	return httpAdapter.NewMigrationHandlers(params.Migrations, params.Stores)
+}
+
+// ProvideRateLimiter creates the per IP rate limiter of the configured algorithm, nil when
+// rate limiting is disabled
+func ProvideRateLimiter(cfg *config.Config) ratelimit.Limiter {
+	limits := cfg.RateLimit
+	if !limits.Enabled {
+		return nil
+	}
+	if limits.Algorithm == "sliding_window" {
+		perMinute := max(int(limits.RequestsPerSecond*60), 1)
+		return ratelimit.NewSlidingWindowLimiter(perMinute, time.Minute, limits.MaxIPs)
+	}
+	return ratelimit.NewTokenBucketLimiter(rate.Limit(limits.RequestsPerSecond), limits.Burst, limits.MaxIPs)
 }
@@ -0,0 +1,275 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/config"
+	httpAdapter "github.com/sp3dr4/dove/internal/adapters/http"
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/infrastructure/cache"
+	"github.com/sp3dr4/dove/internal/infrastructure/memory"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+	"github.com/sp3dr4/dove/internal/pkg/selfsigned"
+	"github.com/sp3dr4/dove/internal/server"
+)
+
+// freePort asks the kernel for an unused TCP port
+func freePort(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	return fmt.Sprint(ln.Addr().(*net.TCPAddr).Port)
+}
+
+// startServer starts an HTTPS server for cfg answering "ok" on /ping
+func startServer(t *testing.T, cfg *config.Config) {
+	t.Helper()
+
+	router := chi.NewRouter()
+	router.Get("/ping", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	srv, err := ProvideHTTPServer(cfg, router, nil)
+	require.NoError(t, err)
+	require.NoError(t, srv.Start(context.Background()))
+	t.Cleanup(func() { _ = srv.Stop(context.Background()) })
+}
+
+// httpsGet retries until the server goroutine is accepting connections
+func httpsGet(t *testing.T, client *http.Client, url string) (*http.Response, error) {
+	t.Helper()
+
+	var resp *http.Response
+	var err error
+	for range 50 {
+		resp, err = client.Get(url)
+		var opErr *net.OpError
+		if err == nil || !errors.As(err, &opErr) || opErr.Op != "dial" {
+			return resp, err
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return resp, err
+}
+
+func TestHTTPServer_ServesTLSWithCertFiles(t *testing.T) {
+	certPEM, keyPEM, err := selfsigned.Generate(selfsigned.DefaultHosts...)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+
+	port := freePort(t)
+	startServer(t, &config.Config{
+		Server: config.ServerConfig{Port: port},
+		TLS: config.TLSConfig{
+			Enabled:    true,
+			CertFile:   certFile,
+			KeyFile:    keyFile,
+			MinVersion: "TLS1.2",
+		},
+	})
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(certPEM))
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}},
+	}
+
+	resp, err := httpsGet(t, client, "https://localhost:"+port+"/ping")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", string(body))
+	require.NotNil(t, resp.TLS)
+	assert.GreaterOrEqual(t, resp.TLS.Version, uint16(tls.VersionTLS12))
+}
+
+func TestHTTPServer_AutoCertEnforcesMinVersion(t *testing.T) {
+	port := freePort(t)
+	startServer(t, &config.Config{
+		Server: config.ServerConfig{Port: port},
+		TLS:    config.TLSConfig{Enabled: true, AutoCert: true, MinVersion: "TLS1.3"},
+	})
+
+	// The generated certificate is not known to the client, so only the handshake is under test
+	insecure := func(maxVersion uint16) *http.Client {
+		return &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true, //nolint:gosec // self-signed test certificate
+				MaxVersion:         maxVersion,
+			}},
+		}
+	}
+
+	resp, err := httpsGet(t, insecure(tls.VersionTLS13), "https://127.0.0.1:"+port+"/ping")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, uint16(tls.VersionTLS13), resp.TLS.Version)
+
+	_, err = httpsGet(t, insecure(tls.VersionTLS12), "https://127.0.0.1:"+port+"/ping")
+	assert.Error(t, err)
+}
+
+func TestProvideHTTPServer_InvalidTLSConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		tls  config.TLSConfig
+	}{
+		{name: "unknown min version", tls: config.TLSConfig{Enabled: true, AutoCert: true, MinVersion: "TLS1.0"}},
+		{name: "missing cert files", tls: config.TLSConfig{Enabled: true}},
+		{name: "unreadable cert files", tls: config.TLSConfig{Enabled: true, CertFile: "missing.pem", KeyFile: "missing.key"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ProvideHTTPServer(&config.Config{Server: config.ServerConfig{Port: "8443"}, TLS: tt.tls}, chi.NewRouter(), nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHTTPServer_StopDrainsInFlightRequests(t *testing.T) {
+	// slowServer starts a plain HTTP server whose /slow handler runs until release is closed
+	slowServer := func(t *testing.T, drainTimeout string, release <-chan struct{}) (server.Server, *httpAdapter.InFlightTracker, string) {
+		t.Helper()
+
+		inFlight := httpAdapter.NewInFlightTracker()
+		router := chi.NewRouter()
+		router.Use(inFlight.Middleware)
+		router.Get("/slow", func(w http.ResponseWriter, _ *http.Request) {
+			<-release
+			_, _ = w.Write([]byte("done"))
+		})
+
+		port := freePort(t)
+		srv, err := ProvideHTTPServer(&config.Config{Server: config.ServerConfig{Port: port, DrainTimeout: drainTimeout}}, router, inFlight)
+		require.NoError(t, err)
+		require.NoError(t, srv.Start(context.Background()))
+		return srv, inFlight, "http://127.0.0.1:" + port + "/slow"
+	}
+
+	t.Run("waits for the slow handler", func(t *testing.T) {
+		release := make(chan struct{})
+		srv, inFlight, url := slowServer(t, "10s", release)
+
+		var finished atomic.Bool
+		go func() {
+			time.Sleep(2 * time.Second)
+			finished.Store(true)
+			close(release)
+		}()
+
+		responses := make(chan *http.Response, 1)
+		go func() {
+			resp, err := httpsGet(t, &http.Client{Timeout: 5 * time.Second}, url)
+			assert.NoError(t, err)
+			responses <- resp
+		}()
+		require.Eventually(t, func() bool { return inFlight.InFlight() == 1 }, time.Second, 10*time.Millisecond)
+
+		require.NoError(t, srv.Stop(context.Background()))
+		assert.True(t, finished.Load(), "Stop returned before the handler completed")
+		assert.Zero(t, inFlight.InFlight())
+
+		resp := <-responses
+		require.NotNil(t, resp)
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "done", string(body))
+	})
+
+	t.Run("gives up after the drain timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		srv, inFlight, url := slowServer(t, "100ms", release)
+
+		go func() {
+			resp, err := httpsGet(t, &http.Client{Timeout: 5 * time.Second}, url)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+		require.Eventually(t, func() bool { return inFlight.InFlight() == 1 }, time.Second, 10*time.Millisecond)
+
+		start := time.Now()
+		err := srv.Stop(context.Background())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+}
+
+func TestHTTPServer_StopEndsEventStreams(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	service := application.NewURLService(repo, cache.NewNoOpCache(), time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+	handlers := httpAdapter.NewHandlers(service, "http://localhost:8080", repo, httpAdapter.HandlersDeps{})
+
+	_, err := service.CreateShortURL(context.Background(), application.CreateURLRequest{
+		URL:         "https://example.com/live",
+		CustomAlias: "live",
+	}, "http://localhost:8080")
+	require.NoError(t, err)
+
+	inFlight := httpAdapter.NewInFlightTracker()
+	router := chi.NewRouter()
+	router.Use(inFlight.Middleware)
+	router.Get("/shorten/{shortCode}/events", handlers.HandleClickEvents)
+
+	port := freePort(t)
+	srv, err := ProvideHTTPServer(&config.Config{Server: config.ServerConfig{Port: port, DrainTimeout: "10s"}}, router, inFlight)
+	require.NoError(t, err)
+	require.NoError(t, srv.Start(context.Background()))
+
+	resp, err := httpsGet(t, &http.Client{}, "http://127.0.0.1:"+port+"/shorten/live/events")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ": subscribed\n", line)
+
+	start := time.Now()
+	require.NoError(t, srv.Stop(context.Background()))
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Zero(t, inFlight.InFlight())
+
+	// The stream ends instead of waiting for the next event
+	_, err = io.ReadAll(reader)
+	assert.NoError(t, err)
+}
@@ -4,7 +4,6 @@ import (
 	"go.uber.org/fx"
 
 	"github.com/sp3dr4/dove/config"
-	"github.com/sp3dr4/dove/internal/application"
 )
 
 // ConfigModule provides configuration-related dependencies
@@ -19,15 +18,35 @@ var InfrastructureModule = fx.Module("infrastructure",
 	fx.Provide(ProvideRedisClient),
 	fx.Provide(ProvideCache),
 	fx.Provide(ProvideCacheTTL),
+	fx.Provide(ProvideSigningSecret),
+	fx.Provide(ProvideMaxRedirectDelay),
+	fx.Provide(ProvideFetchTitles),
+	fx.Provide(ProvideRedirectChains),
+	fx.Provide(ProvideAliasPolicy),
+	fx.Provide(ProvideReservedWords),
+	fx.Provide(ProvideCreateHooks),
+	fx.Provide(ProvideShortCodeGenerator),
+	fx.Provide(ProvideClickDeduplicator),
+	fx.Provide(ProvideLocker),
+	fx.Provide(ProvideClickBuffer),
+	fx.Provide(ProvideClickCounter),
+	fx.Provide(ProvideMigrationRepository),
+	fx.Provide(ProvideStoreMigrator),
+	fx.Provide(ProvideAuditRepository),
+	fx.Provide(ProvideAuditLogger),
+	fx.Provide(ProvideBroker),
 )
 
 // ApplicationModule provides application service dependencies
 var ApplicationModule = fx.Module("application",
-	fx.Provide(application.NewURLService),
+	fx.Provide(ProvideURLService),
+	fx.Provide(ProvideHealthChecker),
+	fx.Provide(ProvideCleanupScheduler),
 )
 
 // MetricsModule provides metrics-related dependencies
 var MetricsModule = fx.Module("metrics",
+	fx.Provide(ProvideMeterProvider),
 	fx.Provide(ProvideMetricsRegistry),
 )
 
@@ -35,6 +54,17 @@ var MetricsModule = fx.Module("metrics",
 var CoreLifecycleModule = fx.Module("core-lifecycle",
 	fx.Invoke(RegisterRepositoryHooks),
 	fx.Invoke(RegisterCacheHooks),
+	fx.Invoke(RegisterClickBufferHooks),
+	fx.Invoke(RegisterAuditHooks),
+	fx.Invoke(RegisterHealthCheckerHooks),
+	fx.Invoke(RegisterCleanupSchedulerHooks),
+	fx.Invoke(RegisterMetricsHooks),
+	fx.Invoke(RegisterPoolMetrics),
+	fx.Invoke(RegisterCreateHooks),
+	fx.Invoke(RegisterSeedHooks),
+	fx.Invoke(RegisterCacheWarmerHooks),
+	fx.Invoke(RegisterActiveURLsHooks),
+	fx.Invoke(RegisterGoalMetricsHooks),
 )
 
 // CoreModules combines the core modules shared by all entrypoints
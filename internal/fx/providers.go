@@ -3,38 +3,55 @@ package fx
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	"github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
 	"go.uber.org/fx"
 
 	"github.com/sp3dr4/dove/config"
+	"github.com/sp3dr4/dove/internal/application"
 	"github.com/sp3dr4/dove/internal/domain"
 	cacheImpl "github.com/sp3dr4/dove/internal/infrastructure/cache"
+	"github.com/sp3dr4/dove/internal/infrastructure/clickbuffer"
 	memoryRepo "github.com/sp3dr4/dove/internal/infrastructure/memory"
+	"github.com/sp3dr4/dove/internal/infrastructure/migration"
+	"github.com/sp3dr4/dove/internal/infrastructure/migrations"
+	pgxRepo "github.com/sp3dr4/dove/internal/infrastructure/pgx"
 	postgresRepo "github.com/sp3dr4/dove/internal/infrastructure/postgres"
 	redisCache "github.com/sp3dr4/dove/internal/infrastructure/redis"
 	sqliteRepo "github.com/sp3dr4/dove/internal/infrastructure/sqlite"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/lock"
 	"github.com/sp3dr4/dove/internal/pkg/metrics"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+	"github.com/sp3dr4/dove/internal/pkg/scheduler"
+	"github.com/sp3dr4/dove/internal/pkg/shortcode"
+	"github.com/sp3dr4/dove/internal/pkg/urlfetch"
 )
 
 // ProvideLogger creates and configures the application logger
 func ProvideLogger(cfg *config.Config) *slog.Logger {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: parseLogLevel(cfg.Logging.Level),
-	}))
+	options := &slog.HandlerOptions{Level: parseLogLevel(cfg.Logging.Level)}
+	var handler slog.Handler = slog.NewJSONHandler(os.Stdout, options)
+	if cfg.Logging.Format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, options)
+	}
+
+	logger := slog.New(handler)
 	slog.SetDefault(logger)
 	return logger
 }
@@ -58,8 +75,8 @@ func parseLogLevel(level string) slog.Level {
 func ProvideRepository(cfg *config.Config, logger *slog.Logger) (domain.URLRepository, error) {
 	switch cfg.Database.Type {
 	case "memory":
-		logger.Info("Using in-memory repository")
-		return memoryRepo.NewURLRepository(logger), nil
+		logger.Info("Using in-memory repository", "max_capacity", cfg.Database.Memory.MaxCapacity)
+		return memoryRepo.NewURLRepository(logger, cfg.Database.Memory.MaxCapacity), nil
 
 	case "sqlite":
 		dbURL := cfg.GetDatabaseURL()
@@ -75,13 +92,21 @@ func ProvideRepository(cfg *config.Config, logger *slog.Logger) (domain.URLRepos
 			return nil, fmt.Errorf("failed to connect to SQLite: %w", err)
 		}
 
-		if err := runMigrations(db, "sqlite3", "sqlite"); err != nil {
+		if err := runMigrations(db.DB, "sqlite3", "sqlite"); err != nil {
 			return nil, fmt.Errorf("failed to run migrations: %w", err)
 		}
 
-		return sqliteRepo.NewURLRepository(db, logger), nil
+		return sqliteRepo.NewURLRepository(db, sqliteRepo.Pragmas{
+			JournalMode:   cfg.Database.SQLite.JournalMode,
+			Synchronous:   cfg.Database.SQLite.Synchronous,
+			BusyTimeoutMs: cfg.Database.SQLite.BusyTimeoutMs,
+		}, logger)
 
 	case "postgres":
+		if cfg.Database.Postgres.Driver == "pgx" {
+			return providePgxRepository(cfg, logger)
+		}
+
 		dbURL := cfg.GetDatabaseURL()
 		logger.Info("Using PostgreSQL repository", "url", dbURL)
 
@@ -90,63 +115,131 @@ func ProvideRepository(cfg *config.Config, logger *slog.Logger) (domain.URLRepos
 			return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 		}
 
-		if err := runMigrations(db, "postgres", "postgres"); err != nil {
+		if err := runMigrations(db.DB, "postgres", "postgres"); err != nil {
 			return nil, fmt.Errorf("failed to run migrations: %w", err)
 		}
 
-		return postgresRepo.NewURLRepository(db, logger), nil
+		queryTimeout := time.Duration(cfg.Database.QueryTimeoutMs) * time.Millisecond
+		if cfg.Database.Postgres.ReplicaURL == "" {
+			return postgresRepo.NewURLRepository(db, logger, queryTimeout, cfg.Database.Postgres.TrgmEnabled, cfg.Database.Postgres.DisablePrepare), nil
+		}
+
+		logger.Info("Using PostgreSQL read replica", "url", cfg.Database.Postgres.ReplicaURL)
+		replica, err := sqlx.Connect("postgres", cfg.Database.Postgres.ReplicaURL)
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to connect to PostgreSQL replica: %w", err)
+		}
+
+		return postgresRepo.NewURLRepositoryWithReplica(db, replica, logger, queryTimeout, cfg.Database.Postgres.TrgmEnabled, cfg.Database.Postgres.DisablePrepare), nil
 
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", cfg.Database.Type)
 	}
 }
 
-// runMigrations runs database migrations
-func runMigrations(db interface{}, driverName, migrationDir string) error {
-	var driver database.Driver
-	var err error
+// providePgxRepository creates the PostgreSQL repository on pgx connection pools
+func providePgxRepository(cfg *config.Config, logger *slog.Logger) (domain.URLRepository, error) {
+	dbURL := cfg.GetDatabaseURL()
+	simpleProtocol := cfg.Database.Postgres.DisablePrepare
+	logger.Info("Using PostgreSQL repository", "url", dbURL, "driver", "pgx", "simple_protocol", simpleProtocol)
 
-	sqlDB, ok := db.(*sqlx.DB)
-	if ok {
-		db = sqlDB.DB
+	ctx := context.Background()
+	pool, err := pgxRepo.NewPool(ctx, dbURL, simpleProtocol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
-
-	sqliteDB, ok := db.(*sql.DB)
-	if !ok {
-		return fmt.Errorf("expected *sql.DB, got %T", db)
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
 
-	switch driverName {
-	case "sqlite3":
-		driver, err = sqlite3.WithInstance(sqliteDB, &sqlite3.Config{})
-	case "postgres":
-		driver, err = postgres.WithInstance(sqliteDB, &postgres.Config{})
-	default:
-		return fmt.Errorf("unsupported driver: %s", driverName)
+	var replica *pgxpool.Pool
+	if cfg.Database.Postgres.ReplicaURL != "" {
+		logger.Info("Using PostgreSQL read replica", "url", cfg.Database.Postgres.ReplicaURL)
+		replica, err = pgxRepo.NewPool(ctx, cfg.Database.Postgres.ReplicaURL, simpleProtocol)
+		if err == nil {
+			err = replica.Ping(ctx)
+		}
+		if err != nil {
+			pool.Close()
+			if replica != nil {
+				replica.Close()
+			}
+			return nil, fmt.Errorf("failed to connect to PostgreSQL replica: %w", err)
+		}
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to create migration driver: %w", err)
+	repo := pgxRepo.NewURLRepositoryWithReplica(pool, replica, logger, cfg.Database.Postgres.TrgmEnabled)
+	if err := runMigrations(repo.DB(), "postgres", "postgres"); err != nil {
+		_ = repo.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	migrationPath := fmt.Sprintf("file://migrations/%s", migrationDir)
-	m, err := migrate.NewWithDatabaseInstance(
-		migrationPath,
-		driverName,
-		driver,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
-	}
+	return repo, nil
+}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("failed to run migrations: %w", err)
+// runMigrations applies every pending migration in migrations/<migrationDir>
+func runMigrations(db *sql.DB, driverName, migrationDir string) error {
+	if err := migrationRepository(db, driverName, migrationDir).Up(context.Background()); err != nil {
+		return err
 	}
 
 	slog.Info("Migrations completed successfully")
 	return nil
 }
 
+func migrationRepository(db *sql.DB, driverName, migrationDir string) *migrations.Repository {
+	return migrations.NewRepository(db, driverName, filepath.Join("migrations", migrationDir))
+}
+
+// ProvideMigrationRepository exposes the schema migrations of the SQL repositories, nil for
+// the in-memory one
+func ProvideMigrationRepository(cfg *config.Config, repo domain.URLRepository) domain.MigrationRepository {
+	withDB, ok := repo.(interface{ DB() *sql.DB })
+	if !ok {
+		return nil
+	}
+
+	switch cfg.Database.Type {
+	case "sqlite":
+		return migrationRepository(withDB.DB(), "sqlite3", "sqlite")
+	case "postgres":
+		return migrationRepository(withDB.DB(), "postgres", "postgres")
+	default:
+		return nil
+	}
+}
+
+// ProvideAuditRepository provides the change history of URLs: the url_audit_log table of
+// PostgreSQL, or memory next to the in-memory repository. SQLite keeps none.
+func ProvideAuditRepository(cfg *config.Config, repo domain.URLRepository) domain.AuditRepository {
+	switch cfg.Database.Type {
+	case "memory":
+		return memoryRepo.NewAuditRepository()
+	case "postgres":
+		withDB, ok := repo.(interface{ DB() *sql.DB })
+		if !ok {
+			return nil
+		}
+		// The driver name only picks the $N placeholders, which pgx shares
+		return postgresRepo.NewAuditRepository(sqlx.NewDb(withDB.DB(), "postgres"))
+	default:
+		return nil
+	}
+}
+
+// ProvideStoreMigrator provides the migrator of the running repository into the stores
+// configured under database, each opened like the running one would be with its type
+func ProvideStoreMigrator(cfg *config.Config, repo domain.URLRepository, logger *slog.Logger) domain.StoreMigrator {
+	open := func(storeType string) (domain.URLRepository, error) {
+		target := *cfg
+		target.Database.Type = storeType
+		return ProvideRepository(&target, logger)
+	}
+	return migration.NewStoreMigrator(repo, cfg.Database.Type, open, logger)
+}
+
 // RepositoryParams holds the parameters needed for repository lifecycle management
 type RepositoryParams struct {
 	fx.In
@@ -171,7 +264,7 @@ func RegisterRepositoryHooks(lc fx.Lifecycle, params RepositoryParams) {
 
 // ProvideRedisClient creates a Redis client
 func ProvideRedisClient(cfg *config.Config, logger *slog.Logger) (*redis.Client, error) {
-	if !cfg.Cache.Enabled {
+	if !cfg.Cache.Enabled || cfg.Cache.Backend == "file" {
 		return nil, nil
 	}
 
@@ -220,15 +313,29 @@ func ProvideRedisClient(cfg *config.Config, logger *slog.Logger) (*redis.Client,
 	return client, nil
 }
 
+// staleCacheRetention is how long a URL stays available to stale-on-error lookups after it
+// was last cached, bounding both Redis memory and how outdated a stale redirect can be
+const staleCacheRetention = 24 * time.Hour
+
 // ProvideCache creates the appropriate cache implementation
-func ProvideCache(cfg *config.Config, client *redis.Client, logger *slog.Logger) domain.Cache {
+func ProvideCache(cfg *config.Config, client *redis.Client, logger *slog.Logger) (domain.Cache, error) {
+	if cfg.Cache.Enabled && cfg.Cache.Backend == "file" {
+		logger.Info("Using file cache", "dir", cfg.Cache.FileDir, "ttl", cfg.Cache.TTL)
+		return cacheImpl.NewFileCache(cfg.Cache.FileDir, logger)
+	}
+
 	if !cfg.Cache.Enabled || client == nil {
 		logger.Info("Caching disabled")
-		return cacheImpl.NewNoOpCache()
+		return cacheImpl.NewNoOpCache(), nil
 	}
 
-	logger.Info("Using Redis cache", "ttl", cfg.Cache.TTL)
-	return redisCache.NewRedisCache(client, logger)
+	var staleTTL time.Duration
+	if cfg.Cache.StaleOnError {
+		staleTTL = staleCacheRetention
+	}
+
+	logger.Info("Using Redis cache", "ttl", cfg.Cache.TTL, "stale_on_error", cfg.Cache.StaleOnError, "jitter_percent", cfg.Cache.JitterPercent, "key_prefix", cfg.Cache.KeyPrefix)
+	return redisCache.NewRedisCache(client, cfg.Cache.KeyPrefix, staleTTL, cfg.Cache.JitterPercent, logger), nil
 }
 
 // ProvideCacheTTL provides the cache TTL duration
@@ -245,6 +352,172 @@ func ProvideCacheTTL(cfg *config.Config) (time.Duration, error) {
 	return ttl, nil
 }
 
+// minSigningSecretLength is the shortest accepted HMAC key, 256 bits
+const minSigningSecretLength = 32
+
+// ProvideSigningSecret provides the key for signed short codes, empty when they are disabled
+func ProvideSigningSecret(cfg *config.Config) (application.SigningSecret, error) {
+	if cfg.App.SigningSecret == "" {
+		return nil, nil
+	}
+	if len(cfg.App.SigningSecret) < minSigningSecretLength {
+		return nil, fmt.Errorf("signing secret must be at least %d bytes", minSigningSecretLength)
+	}
+	return application.SigningSecret(cfg.App.SigningSecret), nil
+}
+
+// ProvideRedirectChains provides the redirect chain settings, nil when chains are left to clients
+func ProvideRedirectChains(cfg *config.Config) *application.RedirectChains {
+	if !cfg.App.FollowRedirectChains {
+		return nil
+	}
+	return &application.RedirectChains{
+		BaseURLs: append([]string{cfg.App.BaseURL}, cfg.App.ChainAliases...),
+		MaxDepth: cfg.App.MaxChainDepth,
+	}
+}
+
+// ProvideAliasPolicy provides the rules custom aliases are checked against
+func ProvideAliasPolicy(cfg *config.Config) *application.AliasPolicy {
+	policy := cfg.App.CustomAliasPolicy
+	return &application.AliasPolicy{
+		MaxLength:        policy.MaxLength,
+		AllowHyphens:     policy.AllowHyphens,
+		AllowUnderscores: policy.AllowUnderscores,
+		ReservedWords:    policy.ReservedWords,
+	}
+}
+
+// ProvideReservedWords provides the words refused as short codes. It starts with the
+// configured reserved words, the router adds its own paths once the routes are built.
+func ProvideReservedWords(cfg *config.Config) *shortcode.ReservedWords {
+	return shortcode.NewReservedWords(cfg.App.CustomAliasPolicy.ReservedWords...)
+}
+
+// CreateHooks are the built-in create hooks enabled by the configuration
+type CreateHooks struct {
+	Pre  []application.CreateHook
+	Post []application.CreateHook
+}
+
+// ProvideCreateHooks provides the create hooks enabled under app.create_hooks. The audit
+// hook is not among them, as the URL service always adds it itself.
+func ProvideCreateHooks(cfg *config.Config) (CreateHooks, error) {
+	var hooks CreateHooks
+	hooksCfg := cfg.App.CreateHooks
+
+	if len(hooksCfg.BlockedDomains) > 0 {
+		hooks.Pre = append(hooks.Pre, application.BlacklistHook(hooksCfg.BlockedDomains))
+	}
+	if hooksCfg.WebhookURL != "" {
+		var timeout time.Duration
+		if hooksCfg.WebhookTimeout != "" {
+			var err error
+			if timeout, err = time.ParseDuration(hooksCfg.WebhookTimeout); err != nil {
+				return CreateHooks{}, fmt.Errorf("invalid webhook timeout: %w", err)
+			}
+		}
+		hooks.Post = append(hooks.Post, application.WebhookDispatchHook(&http.Client{Timeout: timeout}, hooksCfg.WebhookURL))
+	}
+	return hooks, nil
+}
+
+// RegisterCreateHooks adds the configured create hooks to the URL service
+func RegisterCreateHooks(service *application.URLService, hooks CreateHooks) {
+	for _, hook := range hooks.Pre {
+		service.AddPreCreateHook(hook)
+	}
+	for _, hook := range hooks.Post {
+		service.AddPostCreateHook(hook)
+	}
+}
+
+// ProvideMaxRedirectDelay provides the longest redirect delay URLs may be created with
+func ProvideMaxRedirectDelay(cfg *config.Config) application.MaxRedirectDelay {
+	return application.MaxRedirectDelay(cfg.App.MaxDelaySeconds)
+}
+
+// ProvideFetchTitles provides whether the titles of destination pages are fetched
+func ProvideFetchTitles(cfg *config.Config) application.FetchTitles {
+	return application.FetchTitles(cfg.App.FetchTitles)
+}
+
+// ProvideShortCodeGenerator provides the generator of short codes for URLs without a custom alias
+func ProvideShortCodeGenerator(cfg *config.Config) (*shortcode.ShortCodeGenerator, error) {
+	return shortcode.NewShortCodeGenerator(cfg.App.ShortCodeCharset, cfg.App.CustomCharset)
+}
+
+// ProvideClickDeduplicator provides the deduplicator of unique clicks, nil when deduplication
+// is disabled or Redis is unavailable, in which case every click is unique
+func ProvideClickDeduplicator(cfg *config.Config, client *redis.Client, logger *slog.Logger) domain.ClickDeduplicator {
+	window := time.Duration(cfg.Analytics.DeduplicationWindowSeconds) * time.Second
+	if window <= 0 || client == nil {
+		logger.Info("Click deduplication disabled")
+		return nil
+	}
+
+	logger.Info("Deduplicating clicks", "window", window)
+	return redisCache.NewClickDeduplicator(client, cfg.Cache.KeyPrefix, window)
+}
+
+// ProvideLocker provides the locks shared by every instance, nil without Redis
+func ProvideLocker(cfg *config.Config, client *redis.Client) lock.Locker {
+	if client == nil {
+		return nil
+	}
+	return lock.NewRedisLock(client, cfg.Cache.KeyPrefix)
+}
+
+// ProvideClickBuffer provides the in-memory buffer of click counters, nil when clicks are
+// stored as they happen
+func ProvideClickBuffer(cfg *config.Config, repo domain.URLRepository, cache domain.Cache, logger *slog.Logger) *clickbuffer.Buffer {
+	if !cfg.App.AsyncClicks {
+		return nil
+	}
+	return clickbuffer.NewBuffer(repo, cache, time.Duration(cfg.App.ClickFlushIntervalMs)*time.Millisecond, logger)
+}
+
+// ProvideClickCounter provides the click buffer to the service, nil when there is none
+func ProvideClickCounter(buffer *clickbuffer.Buffer) domain.ClickCounter {
+	if buffer == nil {
+		return nil
+	}
+	return buffer
+}
+
+// ClickBufferParams holds the parameters needed for click buffer lifecycle management
+type ClickBufferParams struct {
+	fx.In
+
+	Buffer *clickbuffer.Buffer
+	Config *config.Config
+	Logger *slog.Logger
+}
+
+// RegisterClickBufferHooks starts flushing buffered clicks with the application and flushes
+// the last of them on stop, before the repository closes
+func RegisterClickBufferHooks(lc fx.Lifecycle, params ClickBufferParams) {
+	if params.Buffer == nil {
+		return
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			params.Logger.Info("Buffering clicks", "flush_interval_ms", params.Config.App.ClickFlushIntervalMs)
+			params.Buffer.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if err := params.Buffer.Stop(ctx); err != nil {
+				params.Logger.Error("Failed to flush buffered clicks on stop", "error", err)
+				return err
+			}
+			params.Logger.Info("Click buffer stopped")
+			return nil
+		},
+	})
+}
+
 // CacheParams holds the parameters needed for cache lifecycle management
 type CacheParams struct {
 	fx.In
@@ -269,13 +542,309 @@ func RegisterCacheHooks(lc fx.Lifecycle, params CacheParams) {
 	}
 }
 
+// ProvideAuditLogger creates the audit logger for mutating operations
+func ProvideAuditLogger(cfg *config.Config, logger *slog.Logger) (*audit.AuditLogger, error) {
+	if cfg.Audit.LogPath == "" {
+		logger.Info("Audit logging disabled")
+		return audit.NewAuditLogger(io.Discard), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Audit.LogPath), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	auditLogger, err := audit.NewFileAuditLogger(cfg.Audit.LogPath)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Audit logging enabled", "path", cfg.Audit.LogPath)
+	return auditLogger, nil
+}
+
+// AuditParams holds the parameters needed for audit logger lifecycle management
+type AuditParams struct {
+	fx.In
+
+	AuditLogger *audit.AuditLogger
+	Logger      *slog.Logger
+}
+
+// RegisterAuditHooks registers audit logger lifecycle hooks with FX
+func RegisterAuditHooks(lc fx.Lifecycle, params AuditParams) {
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			if err := params.AuditLogger.Close(); err != nil {
+				params.Logger.Error("Failed to close audit log", "error", err)
+				return err
+			}
+			return nil
+		},
+	})
+}
+
+// ProvideBroker creates the in-process click event broker
+func ProvideBroker(cfg *config.Config) *pubsub.Broker {
+	return pubsub.NewBroker(cfg.Server.SSEMaxConnections)
+}
+
+// URLServiceParams holds the dependencies of the URL service
+type URLServiceParams struct {
+	fx.In
+
+	Repo            domain.URLRepository
+	Cache           domain.Cache
+	CacheTTL        time.Duration
+	AuditLogger     *audit.AuditLogger
+	Broker          *pubsub.Broker
+	SigningSecret   application.SigningSecret
+	MaxDelay        application.MaxRedirectDelay
+	Codes           *shortcode.ShortCodeGenerator
+	Dedup           domain.ClickDeduplicator
+	Chains          *application.RedirectChains
+	Aliases         *application.AliasPolicy
+	Locker          lock.Locker
+	Clicks          domain.ClickCounter
+	Reserved        *shortcode.ReservedWords
+	AuditLog        domain.AuditRepository
+	MetricsRegistry metrics.Registry
+	FetchTitles     application.FetchTitles
+	Logger          *slog.Logger
+}
+
+// ProvideURLService creates the URL service
+func ProvideURLService(params URLServiceParams) *application.URLService {
+	return application.NewURLService(params.Repo, params.Cache, params.CacheTTL, params.AuditLogger, params.Broker, application.URLServiceDeps{
+		SigningSecret: params.SigningSecret,
+		MaxDelay:      params.MaxDelay,
+		Codes:         params.Codes,
+		Dedup:         params.Dedup,
+		Chains:        params.Chains,
+		Aliases:       params.Aliases,
+		Locker:        params.Locker,
+		Clicks:        params.Clicks,
+		Reserved:      params.Reserved,
+		AuditLog:      params.AuditLog,
+		Metrics:       params.MetricsRegistry,
+		FetchTitles:   params.FetchTitles,
+	}, params.Logger)
+}
+
+// ProvideHealthChecker creates the background destination URL health checker
+func ProvideHealthChecker(cfg *config.Config, repo domain.URLRepository, cache domain.Cache, logger *slog.Logger) *application.HealthChecker {
+	interval := time.Duration(cfg.HealthChecker.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	// Destinations are chosen by users, and their health is public
+	return application.NewHealthChecker(
+		repo,
+		cache,
+		urlfetch.NewClient(),
+		interval,
+		cfg.HealthChecker.BatchSize,
+		cfg.HealthChecker.WorkerCount,
+		logger,
+	)
+}
+
+// HealthCheckerParams holds the parameters needed for health checker lifecycle management
+type HealthCheckerParams struct {
+	fx.In
+
+	Checker *application.HealthChecker
+	Config  *config.Config
+	Logger  *slog.Logger
+}
+
+// RegisterHealthCheckerHooks starts the health checker with the application when it is enabled
+func RegisterHealthCheckerHooks(lc fx.Lifecycle, params HealthCheckerParams) {
+	if !params.Config.HealthChecker.Enabled {
+		params.Logger.Info("URL health checker disabled")
+		return
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			params.Logger.Info("Starting URL health checker",
+				"interval_minutes", params.Config.HealthChecker.IntervalMinutes,
+				"batch_size", params.Config.HealthChecker.BatchSize,
+				"worker_count", params.Config.HealthChecker.WorkerCount,
+			)
+			params.Checker.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if err := params.Checker.Stop(ctx); err != nil {
+				params.Logger.Error("Failed to stop URL health checker", "error", err)
+				return err
+			}
+			params.Logger.Info("URL health checker stopped")
+			return nil
+		},
+	})
+}
+
+// ProvideCleanupScheduler creates the background job deleting expired URLs and disabling
+// cold ones
+func ProvideCleanupScheduler(cfg *config.Config, repo domain.URLRepository, service *application.URLService, metricsRegistry metrics.Registry, logger *slog.Logger) *scheduler.CleanupScheduler {
+	interval := time.Duration(cfg.App.CleanupIntervalMinutes) * time.Minute
+	coldAfter := time.Duration(cfg.App.AutoDisableColdAfterDays) * 24 * time.Hour
+	return scheduler.NewCleanupScheduler(repo, service, metricsRegistry, interval, coldAfter, logger)
+}
+
+// CleanupSchedulerParams holds the parameters needed for cleanup scheduler lifecycle management
+type CleanupSchedulerParams struct {
+	fx.In
+
+	Scheduler *scheduler.CleanupScheduler
+	Config    *config.Config
+	Logger    *slog.Logger
+}
+
+// RegisterCleanupSchedulerHooks starts the expired URL cleanup with the application when an
+// interval is configured
+func RegisterCleanupSchedulerHooks(lc fx.Lifecycle, params CleanupSchedulerParams) {
+	if params.Config.App.CleanupIntervalMinutes <= 0 {
+		params.Logger.Info("Expired URL cleanup disabled")
+		return
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			params.Logger.Info("Starting expired URL cleanup", "interval_minutes", params.Config.App.CleanupIntervalMinutes, "auto_disable_cold_after_days", params.Config.App.AutoDisableColdAfterDays)
+			params.Scheduler.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if err := params.Scheduler.Stop(ctx); err != nil {
+				params.Logger.Error("Failed to stop expired URL cleanup", "error", err)
+				return err
+			}
+			params.Logger.Info("Expired URL cleanup stopped")
+			return nil
+		},
+	})
+}
+
+// SeedParams holds the parameters needed to seed URLs at startup
+type SeedParams struct {
+	fx.In
+
+	Service *application.URLService
+	Config  *config.Config
+	Logger  *slog.Logger
+}
+
+// RegisterSeedHooks creates the URLs of app.seed_file on start. A seed file that cannot
+// be read stops the application, a URL that cannot be created does not.
+func RegisterSeedHooks(lc fx.Lifecycle, params SeedParams) {
+	path := params.Config.App.SeedFile
+	if path == "" {
+		return
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			entries, err := application.LoadSeedFile(path)
+			if err != nil {
+				params.Logger.Error("Failed to load seed file", "path", path, "error", err)
+				return err
+			}
+
+			params.Logger.Info("Seeding URLs", "path", path, "count", len(entries))
+			_, err = params.Service.SeedURLs(ctx, entries, params.Config.App.BaseURL)
+			return err
+		},
+	})
+}
+
+// CacheWarmerParams holds the parameters needed to warm the cache at startup
+type CacheWarmerParams struct {
+	fx.In
+
+	Service *application.URLService
+	Config  *config.Config
+	Logger  *slog.Logger
+}
+
+// RegisterCacheWarmerHooks caches the most clicked URLs on start, once the seed URLs are
+// created and before the HTTP server accepts connections. A failed warm-up is logged and
+// leaves the cache to fill with traffic rather than stopping the application.
+func RegisterCacheWarmerHooks(lc fx.Lifecycle, params CacheWarmerParams) {
+	if !params.Config.Cache.Enabled || !params.Config.Cache.WarmEnabled {
+		return
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			warmed, err := params.Service.WarmCache(ctx, params.Config.Cache.WarmCount)
+			if err != nil {
+				params.Logger.Warn("Failed to warm the cache", "error", err)
+				return nil
+			}
+			params.Logger.Info("Cache warmed", "urls", warmed)
+			return nil
+		},
+	})
+}
+
+// RequireSeedFile fails the seed-only entrypoint when there is nothing to seed
+func RequireSeedFile(cfg *config.Config) error {
+	if cfg.App.SeedFile == "" {
+		return errors.New("app.seed_file must be set to seed URLs")
+	}
+	return nil
+}
+
+// otlpExportInterval is how often the otel metrics backend pushes to the collector
+const otlpExportInterval = 15 * time.Second
+
+// ProvideMeterProvider creates the OTLP meter provider behind the otel metrics backend,
+// every other configuration gets a no-op provider
+func ProvideMeterProvider(cfg *config.Config) (metric.MeterProvider, error) {
+	if !cfg.Metrics.Enabled || cfg.Metrics.Backend != "otel" {
+		return noop.NewMeterProvider(), nil
+	}
+
+	provider, err := metrics.NewOTLPMeterProvider(cfg.Metrics.OTLPEndpoint, cfg.Metrics.Namespace, otlpExportInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP meter provider: %w", err)
+	}
+
+	return provider, nil
+}
+
 // ProvideMetricsRegistry creates the appropriate metrics registry based on configuration
-func ProvideMetricsRegistry(cfg *config.Config, logger *slog.Logger) (metrics.Registry, error) {
+func ProvideMetricsRegistry(cfg *config.Config, meterProvider metric.MeterProvider, logger *slog.Logger) (metrics.Registry, error) {
 	if !cfg.Metrics.Enabled {
 		logger.Info("Metrics collection disabled")
 		return metrics.NewNoOpRegistry(), nil
 	}
 
+	if err := metrics.ValidateBuckets(cfg.Metrics.HTTPBuckets); err != nil {
+		return nil, fmt.Errorf("invalid metrics.http_buckets: %w", err)
+	}
+	if err := metrics.ValidateBuckets(cfg.Metrics.DBBuckets); err != nil {
+		return nil, fmt.Errorf("invalid metrics.db_buckets: %w", err)
+	}
+
+	if cfg.Metrics.Backend == "otel" {
+		logger.Info("Enabling OpenTelemetry metrics",
+			"endpoint", cfg.Metrics.OTLPEndpoint,
+			"namespace", cfg.Metrics.Namespace,
+			"subsystem", cfg.Metrics.Subsystem,
+		)
+
+		registry, err := metrics.NewOTelRegistry(cfg.Metrics, meterProvider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OpenTelemetry registry: %w", err)
+		}
+
+		return registry, nil
+	}
+
 	logger.Info("Enabling Prometheus metrics",
 		"path", cfg.Metrics.Path,
 		"namespace", cfg.Metrics.Namespace,
@@ -289,3 +858,144 @@ func ProvideMetricsRegistry(cfg *config.Config, logger *slog.Logger) (metrics.Re
 
 	return registry, nil
 }
+
+// MetricsParams holds the parameters needed for metrics lifecycle management
+type MetricsParams struct {
+	fx.In
+
+	MeterProvider metric.MeterProvider
+	Logger        *slog.Logger
+}
+
+// RegisterMetricsHooks flushes and closes the OTLP exporter when the application stops
+func RegisterMetricsHooks(lc fx.Lifecycle, params MetricsParams) {
+	provider, ok := params.MeterProvider.(*metrics.OTLPMeterProvider)
+	if !ok {
+		return
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			if err := provider.Shutdown(ctx); err != nil {
+				params.Logger.Error("Failed to shut down OTLP metrics exporter", "error", err)
+				return err
+			}
+			return nil
+		},
+	})
+}
+
+// PoolMetricsParams holds the parameters needed to export connection pool statistics
+type PoolMetricsParams struct {
+	fx.In
+
+	Config      *config.Config
+	Registry    metrics.Registry
+	Repository  domain.URLRepository
+	RedisClient *redis.Client `optional:"true"`
+	Logger      *slog.Logger
+}
+
+// RegisterPoolMetrics exposes the database and Redis connection pools on the Prometheus
+// registry. Pools are read on scrape, so the OpenTelemetry backend does not report them.
+func RegisterPoolMetrics(params PoolMetricsParams) error {
+	registry, ok := params.Registry.(*metrics.PrometheusRegistry)
+	if !ok {
+		return nil
+	}
+
+	if params.Config.Metrics.CollectDatabase {
+		var err error
+		collected := true
+		switch pooled := params.Repository.(type) {
+		case interface{ Pool() *pgxpool.Pool }:
+			// pgx's own pool, the repository's *sql.DB only wraps it
+			err = registry.CollectPgxPoolStats(pooled.Pool(), params.Config.Database.Type)
+		case interface{ DB() *sql.DB }:
+			err = registry.CollectDBStats(pooled.DB(), params.Config.Database.Type)
+		default:
+			collected = false
+		}
+		if err != nil {
+			return fmt.Errorf("failed to register database pool metrics: %w", err)
+		}
+		if collected {
+			params.Logger.Info("Collecting database pool metrics", "database", params.Config.Database.Type)
+		}
+	}
+
+	if params.Config.Metrics.CollectCache && params.RedisClient != nil {
+		if err := registry.CollectRedisPoolStats(params.RedisClient); err != nil {
+			return fmt.Errorf("failed to register Redis pool metrics: %w", err)
+		}
+		params.Logger.Info("Collecting Redis pool metrics")
+	}
+
+	return nil
+}
+
+// ActiveURLsParams holds the parameters needed to initialise the active URLs gauge
+type ActiveURLsParams struct {
+	fx.In
+
+	Config     *config.Config
+	Registry   metrics.Registry
+	Repository domain.URLRepository
+	Logger     *slog.Logger
+}
+
+// RegisterActiveURLsHooks sets the active URLs gauge from the repository on start, once
+// the seed URLs are created. A failed count is logged and leaves the gauge at zero rather
+// than stopping the application.
+func RegisterActiveURLsHooks(lc fx.Lifecycle, params ActiveURLsParams) {
+	if !params.Config.Metrics.Enabled {
+		return
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			count, err := params.Repository.CountActive(ctx, time.Now().UTC())
+			if err != nil {
+				params.Logger.Warn("Failed to count active URLs", "error", err)
+				return nil
+			}
+			params.Registry.SetActiveURLs(count)
+			return nil
+		},
+	})
+}
+
+// GoalMetricsParams holds the parameters needed to count reached click goals
+type GoalMetricsParams struct {
+	fx.In
+
+	Config   *config.Config
+	Registry metrics.Registry
+	Broker   *pubsub.Broker
+}
+
+// RegisterGoalMetricsHooks counts the goal events published by the service while the
+// application runs
+func RegisterGoalMetricsHooks(lc fx.Lifecycle, params GoalMetricsParams) {
+	if !params.Config.Metrics.Enabled {
+		return
+	}
+
+	var unsubscribe func()
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			var events <-chan domain.GoalReachedEvent
+			events, unsubscribe = params.Broker.SubscribeGoals()
+			go func() {
+				for range events {
+					params.Registry.IncGoalsReached()
+				}
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			unsubscribe()
+			return nil
+		},
+	})
+}
@@ -0,0 +1,328 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// topURLsDir is the subdirectory holding the cached rankings, kept apart from namespaces
+// so that all rankings can be dropped together
+const topURLsDir = "_top_urls"
+
+// fileEntry is the content of a cache file
+type fileEntry struct {
+	ExpiresAt time.Time       `json:"expiresAt"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// FileCache stores every entry in its own JSON file under dir, one subdirectory per
+// namespace, for deployments without Redis. Expired files are removed when read.
+// Stale entries are not kept, GetStale always misses.
+type FileCache struct {
+	dir    string
+	logger *slog.Logger
+}
+
+// NewFileCache creates a cache in dir, creating the directory if needed
+func NewFileCache(dir string, logger *slog.Logger) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	return &FileCache{
+		dir:    dir,
+		logger: logger,
+	}, nil
+}
+
+func (c *FileCache) Get(_ context.Context, namespace, shortCode string) (*domain.URL, error) {
+	path, ok := c.urlPath(namespace, shortCode)
+	if !ok {
+		return nil, nil
+	}
+
+	// Entries cached before URLs could be disabled have no enabled field
+	url := domain.URL{Enabled: true}
+	found, err := c.read(path, &url)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &url, nil
+}
+
+func (c *FileCache) GetMulti(ctx context.Context, namespace string, shortCodes []string) (map[string]*domain.URL, error) {
+	urls := make(map[string]*domain.URL, len(shortCodes))
+	for _, shortCode := range shortCodes {
+		url, err := c.Get(ctx, namespace, shortCode)
+		if err != nil {
+			return nil, err
+		}
+		if url != nil {
+			urls[shortCode] = url
+		}
+	}
+	return urls, nil
+}
+
+// GetOrSet leaves coalescing concurrent misses to the caller, as only one process uses dir
+func (c *FileCache) GetOrSet(ctx context.Context, namespace, shortCode string, ttl time.Duration, fetch func() (*domain.URL, error)) (*domain.URL, error) {
+	url, err := c.Get(ctx, namespace, shortCode)
+	if err != nil {
+		c.logger.Warn("Failed to read cache, fetching URL", "namespace", namespace, "short_code", shortCode, "error", err)
+	}
+	if url != nil {
+		return url, nil
+	}
+
+	url, err = fetch()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.setURL(url, ttl); err != nil {
+		c.logger.Warn("Failed to cache URL", "namespace", namespace, "short_code", shortCode, "error", err)
+	}
+	return url, nil
+}
+
+func (c *FileCache) Set(_ context.Context, url *domain.URL, ttl time.Duration) error {
+	return c.setURL(url, ttl)
+}
+
+func (c *FileCache) SetMulti(_ context.Context, urls []*domain.URL, ttl time.Duration) error {
+	for _, url := range urls {
+		if err := c.setURL(url, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *FileCache) GetStale(_ context.Context, _, _ string) (*domain.URL, error) {
+	// Expired files are removed, there is nothing stale to serve
+	return nil, nil
+}
+
+func (c *FileCache) Delete(_ context.Context, namespace, shortCode string) error {
+	path, ok := c.urlPath(namespace, shortCode)
+	if !ok {
+		return nil
+	}
+	return c.remove(path)
+}
+
+func (c *FileCache) DeleteMulti(_ context.Context, namespace string, shortCodes []string) error {
+	for _, shortCode := range shortCodes {
+		path, ok := c.urlPath(namespace, shortCode)
+		if !ok {
+			continue
+		}
+		if err := c.remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *FileCache) GetTopURLs(_ context.Context, n int) ([]*domain.URL, error) {
+	var urls []*domain.URL
+	found, err := c.read(c.topURLsPath(n), &urls)
+	if err != nil || !found {
+		return nil, err
+	}
+	return urls, nil
+}
+
+func (c *FileCache) SetTopURLs(_ context.Context, n int, urls []*domain.URL, ttl time.Duration) error {
+	return c.write(c.topURLsPath(n), urls, ttl)
+}
+
+func (c *FileCache) InvalidateTopURLs(_ context.Context) error {
+	if err := os.RemoveAll(filepath.Join(c.dir, topURLsDir)); err != nil {
+		c.logger.Error("Failed to delete top URLs from cache", "error", err)
+		return fmt.Errorf("cache delete failed: %w", err)
+	}
+	return nil
+}
+
+// Ping checks that the cache directory is still there
+func (c *FileCache) Ping(_ context.Context) error {
+	info, err := os.Stat(c.dir)
+	if err != nil {
+		return fmt.Errorf("cache directory unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("cache directory unavailable: %s is not a directory", c.dir)
+	}
+	return nil
+}
+
+// Keys names URL entries url:<namespace>:<shortCode> and rankings top_urls:<n>, as the Redis
+// cache does. The cursor is the number of matching keys already listed, in name order.
+func (c *FileCache) Keys(_ context.Context, pattern string, cursor uint64, count int) ([]string, uint64, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	var keys []string
+	err := filepath.WalkDir(c.dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(c.dir, file)
+		if err != nil {
+			return err
+		}
+		dir, name, nested := strings.Cut(filepath.ToSlash(rel), "/")
+		name, isEntry := strings.CutSuffix(name, ".json")
+		if !nested || !isEntry || strings.Contains(name, "/") {
+			// Temporary files, and anything not written by the cache
+			return nil
+		}
+
+		key := "url:" + dir + ":" + name
+		if dir == topURLsDir {
+			key = "top_urls:" + name
+		}
+		// Like a Redis glob, a malformed pattern matches nothing
+		if matched, _ := path.Match(pattern, key); matched {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("Failed to list cache keys", "error", err)
+		return nil, 0, fmt.Errorf("cache scan failed: %w", err)
+	}
+
+	sort.Strings(keys)
+	if cursor >= uint64(len(keys)) {
+		return []string{}, 0, nil
+	}
+	keys = keys[cursor:]
+	if count <= 0 || count >= len(keys) {
+		return keys, 0, nil
+	}
+	return keys[:count], cursor + uint64(count), nil
+}
+
+func (c *FileCache) setURL(url *domain.URL, ttl time.Duration) error {
+	path, ok := c.urlPath(url.Namespace, url.ShortCode)
+	if !ok {
+		return fmt.Errorf("cache set failed: invalid cache key %q/%q", url.Namespace, url.ShortCode)
+	}
+	return c.write(path, url, ttl)
+}
+
+// read decodes the entry at path into v, reporting false on a miss. An expired entry is
+// removed and counts as a miss.
+func (c *FileCache) read(path string, v any) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		c.logger.Error("Failed to get from cache", "path", path, "error", err)
+		return false, fmt.Errorf("cache get failed: %w", err)
+	}
+
+	var entry fileEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		c.logger.Error("Failed to unmarshal cached value", "path", path, "error", err)
+		return false, fmt.Errorf("failed to unmarshal cached value: %w", err)
+	}
+
+	if !entry.ExpiresAt.IsZero() && !time.Now().Before(entry.ExpiresAt) {
+		return false, c.remove(path)
+	}
+
+	if err := json.Unmarshal(entry.Value, v); err != nil {
+		c.logger.Error("Failed to unmarshal cached value", "path", path, "error", err)
+		return false, fmt.Errorf("failed to unmarshal cached value: %w", err)
+	}
+	return true, nil
+}
+
+// write stores v at path for ttl, zero meaning no expiry. The entry is written to a
+// temporary file first and renamed into place, so readers never see a partial file.
+func (c *FileCache) write(path string, v any, ttl time.Duration) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		c.logger.Error("Failed to marshal value for cache", "path", path, "error", err)
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	entry := fileEntry{Value: value}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl).UTC()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	if err := c.writeAtomic(path, data); err != nil {
+		c.logger.Error("Failed to set cache", "path", path, "error", err)
+		return fmt.Errorf("cache set failed: %w", err)
+	}
+	return nil
+}
+
+func (c *FileCache) writeAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (c *FileCache) remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		c.logger.Error("Failed to delete from cache", "path", path, "error", err)
+		return fmt.Errorf("cache delete failed: %w", err)
+	}
+	return nil
+}
+
+// urlPath returns the file of a URL, false when namespace or shortCode would leave the
+// cache directory
+func (c *FileCache) urlPath(namespace, shortCode string) (string, bool) {
+	if !isPathSegment(namespace) || !isPathSegment(shortCode) || namespace == topURLsDir {
+		return "", false
+	}
+	return filepath.Join(c.dir, namespace, shortCode+".json"), true
+}
+
+func (c *FileCache) topURLsPath(n int) string {
+	return filepath.Join(c.dir, topURLsDir, strconv.Itoa(n)+".json")
+}
+
+func isPathSegment(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, `/\`)
+}
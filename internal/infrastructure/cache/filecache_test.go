@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+func newTestFileCache(t *testing.T) (*FileCache, string) {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "cache")
+	cache, err := NewFileCache(dir, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	return cache, dir
+}
+
+func TestFileCache_SetGet(t *testing.T) {
+	ctx := context.Background()
+	cache, dir := newTestFileCache(t)
+
+	url := &domain.URL{ID: 7, ShortCode: "abc123", OriginalURL: "https://example.com", Namespace: "default", Clicks: 3}
+	require.NoError(t, cache.Set(ctx, url, time.Minute))
+	assert.FileExists(t, filepath.Join(dir, "default", "abc123.json"))
+
+	got, err := cache.Get(ctx, "default", "abc123")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, url.ID, got.ID)
+	assert.Equal(t, url.OriginalURL, got.OriginalURL)
+	assert.Equal(t, url.Clicks, got.Clicks)
+
+	t.Run("namespaces are kept apart", func(t *testing.T) {
+		got, err := cache.Get(ctx, "other", "abc123")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("no temporary files are left behind", func(t *testing.T) {
+		entries, err := os.ReadDir(filepath.Join(dir, "default"))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "abc123.json", entries[0].Name())
+	})
+
+	t.Run("stale entries are not kept", func(t *testing.T) {
+		got, err := cache.GetStale(ctx, "default", "abc123")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("entries cached before URLs could be disabled read as enabled", func(t *testing.T) {
+		legacy := `{"expiresAt":"0001-01-01T00:00:00Z","value":{"namespace":"default","shortCode":"legacy","originalUrl":"https://example.com"}}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "default", "legacy.json"), []byte(legacy), 0600))
+
+		got, err := cache.Get(ctx, "default", "legacy")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.True(t, got.Enabled)
+	})
+}
+
+func TestFileCache_GetOrSet(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestFileCache(t)
+
+	calls := 0
+	fetch := func() (*domain.URL, error) {
+		calls++
+		return &domain.URL{ID: 7, ShortCode: "abc123", OriginalURL: "https://example.com", Namespace: "default"}, nil
+	}
+
+	for range 3 {
+		got, err := cache.GetOrSet(ctx, "default", "abc123", time.Minute, fetch)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", got.OriginalURL)
+	}
+	assert.Equal(t, 1, calls, "only the first lookup misses")
+
+	t.Run("fetch errors are returned and nothing is stored", func(t *testing.T) {
+		_, err := cache.GetOrSet(ctx, "default", "missing", time.Minute, func() (*domain.URL, error) {
+			return nil, domain.ErrURLNotFound
+		})
+		assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+		got, err := cache.Get(ctx, "default", "missing")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestFileCache_Expiry(t *testing.T) {
+	ctx := context.Background()
+	cache, dir := newTestFileCache(t)
+
+	url := &domain.URL{ShortCode: "gone", OriginalURL: "https://example.com", Namespace: "default"}
+	require.NoError(t, cache.Set(ctx, url, time.Nanosecond))
+	time.Sleep(time.Millisecond)
+
+	got, err := cache.Get(ctx, "default", "gone")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	assert.NoFileExists(t, filepath.Join(dir, "default", "gone.json"))
+}
+
+func TestFileCache_Delete(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestFileCache(t)
+
+	urls := []*domain.URL{
+		{ShortCode: "one", OriginalURL: "https://example.com/1", Namespace: "default"},
+		{ShortCode: "two", OriginalURL: "https://example.com/2", Namespace: "default"},
+		{ShortCode: "three", OriginalURL: "https://example.com/3", Namespace: "default"},
+	}
+	require.NoError(t, cache.SetMulti(ctx, urls, time.Minute))
+
+	require.NoError(t, cache.Delete(ctx, "default", "one"))
+	require.NoError(t, cache.DeleteMulti(ctx, "default", []string{"two", "missing"}))
+
+	for shortCode, cached := range map[string]bool{"one": false, "two": false, "three": true} {
+		got, err := cache.Get(ctx, "default", shortCode)
+		require.NoError(t, err)
+		assert.Equal(t, cached, got != nil, shortCode)
+	}
+
+	assert.NoError(t, cache.Delete(ctx, "default", "missing"))
+}
+
+func TestFileCache_TopURLs(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestFileCache(t)
+
+	got, err := cache.GetTopURLs(ctx, 5)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	top := []*domain.URL{{ShortCode: "hot", Clicks: 10}, {ShortCode: "warm", Clicks: 5}}
+	require.NoError(t, cache.SetTopURLs(ctx, 5, top, time.Minute))
+	require.NoError(t, cache.SetTopURLs(ctx, 1, top[:1], time.Minute))
+
+	got, err = cache.GetTopURLs(ctx, 5)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "hot", got[0].ShortCode)
+
+	require.NoError(t, cache.InvalidateTopURLs(ctx))
+	for _, n := range []int{1, 5} {
+		got, err := cache.GetTopURLs(ctx, n)
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	}
+}
+
+func TestFileCache_Keys(t *testing.T) {
+	ctx := context.Background()
+	cache, dir := newTestFileCache(t)
+
+	require.NoError(t, cache.Set(ctx, &domain.URL{Namespace: "default", ShortCode: "abc"}, time.Minute))
+	require.NoError(t, cache.Set(ctx, &domain.URL{Namespace: "team", ShortCode: "xyz"}, time.Minute))
+	require.NoError(t, cache.SetTopURLs(ctx, 10, nil, time.Minute))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "default", ".tmp-123"), []byte("{}"), 0o600))
+
+	keys, next, err := cache.Keys(ctx, "", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"top_urls:10", "url:default:abc", "url:team:xyz"}, keys)
+	assert.Zero(t, next)
+
+	keys, next, err = cache.Keys(ctx, "url:*", 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"url:default:abc"}, keys)
+	assert.Equal(t, uint64(1), next)
+
+	keys, next, err = cache.Keys(ctx, "url:*", next, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"url:team:xyz"}, keys)
+	assert.Zero(t, next)
+
+	keys, _, err = cache.Keys(ctx, "[", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestFileCache_RejectsPathsOutsideDir(t *testing.T) {
+	ctx := context.Background()
+	cache, dir := newTestFileCache(t)
+
+	tests := []struct {
+		name      string
+		namespace string
+		shortCode string
+	}{
+		{"parent directory", "default", ".."},
+		{"separator in short code", "default", "../escape"},
+		{"separator in namespace", "../outside", "abc"},
+		{"empty short code", "default", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := &domain.URL{ShortCode: tt.shortCode, Namespace: tt.namespace}
+			assert.Error(t, cache.Set(ctx, url, time.Minute))
+
+			got, err := cache.Get(ctx, tt.namespace, tt.shortCode)
+			require.NoError(t, err)
+			assert.Nil(t, got)
+		})
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(dir))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestFileCache_Ping(t *testing.T) {
+	ctx := context.Background()
+	cache, dir := newTestFileCache(t)
+
+	assert.NoError(t, cache.Ping(ctx))
+
+	require.NoError(t, os.RemoveAll(dir))
+	assert.Error(t, cache.Ping(ctx))
+}
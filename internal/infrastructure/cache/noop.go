@@ -15,17 +15,57 @@ func NewNoOpCache() *NoOpCache {
 	return &NoOpCache{}
 }
 
-func (c *NoOpCache) Get(_ context.Context, _ string) (*domain.URL, error) {
+func (c *NoOpCache) Get(_ context.Context, _, _ string) (*domain.URL, error) {
 	// Always return cache miss
 	return nil, nil
 }
 
+func (c *NoOpCache) GetMulti(_ context.Context, _ string, _ []string) (map[string]*domain.URL, error) {
+	// Always return cache misses
+	return map[string]*domain.URL{}, nil
+}
+
+func (c *NoOpCache) GetOrSet(_ context.Context, _, _ string, _ time.Duration, fetch func() (*domain.URL, error)) (*domain.URL, error) {
+	// Every lookup misses
+	return fetch()
+}
+
 func (c *NoOpCache) Set(_ context.Context, _ *domain.URL, _ time.Duration) error {
 	// Do nothing
 	return nil
 }
 
-func (c *NoOpCache) Delete(_ context.Context, _ string) error {
+func (c *NoOpCache) SetMulti(_ context.Context, _ []*domain.URL, _ time.Duration) error {
+	// Do nothing
+	return nil
+}
+
+func (c *NoOpCache) GetStale(_ context.Context, _, _ string) (*domain.URL, error) {
+	// Nothing is ever stored
+	return nil, nil
+}
+
+func (c *NoOpCache) Delete(_ context.Context, _, _ string) error {
+	// Do nothing
+	return nil
+}
+
+func (c *NoOpCache) DeleteMulti(_ context.Context, _ string, _ []string) error {
+	// Do nothing
+	return nil
+}
+
+func (c *NoOpCache) GetTopURLs(_ context.Context, _ int) ([]*domain.URL, error) {
+	// Always return cache miss
+	return nil, nil
+}
+
+func (c *NoOpCache) SetTopURLs(_ context.Context, _ int, _ []*domain.URL, _ time.Duration) error {
+	// Do nothing
+	return nil
+}
+
+func (c *NoOpCache) InvalidateTopURLs(_ context.Context) error {
 	// Do nothing
 	return nil
 }
@@ -34,3 +74,8 @@ func (c *NoOpCache) Ping(_ context.Context) error {
 	// Always available
 	return nil
 }
+
+func (c *NoOpCache) Keys(_ context.Context, _ string, _ uint64, _ int) ([]string, uint64, error) {
+	// Nothing is ever stored
+	return []string{}, 0, nil
+}
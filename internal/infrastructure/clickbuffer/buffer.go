@@ -0,0 +1,198 @@
+// Package clickbuffer counts clicks in memory and adds them to the stored click counters
+// in batches, so that redirects do not each wait for a database write.
+package clickbuffer
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// urlKey identifies the counters of a URL; short codes are only unique within a namespace
+type urlKey struct {
+	namespace string
+	shortCode string
+}
+
+// counters are the clicks of one URL not stored yet. Once removed from the buffer they
+// take no more clicks, which go to a fresh entry instead.
+type counters struct {
+	mu      sync.Mutex
+	clicks  int64
+	unique  int64
+	removed bool
+}
+
+// Buffer implements domain.ClickCounter. A flush takes the clicks of each URL and, once
+// they are stored, removes the entries no click reached meanwhile; a click counted during
+// a flush is left for the next one instead of being lost.
+type Buffer struct {
+	repo     domain.URLRepository
+	cache    domain.Cache
+	interval time.Duration
+	logger   *slog.Logger
+
+	counts sync.Map // urlKey to *counters
+	// flushMu serialises flushes, as the loop and Stop may both flush
+	flushMu sync.Mutex
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewBuffer creates a buffer flushing to repo every interval once started. The cache
+// entries of flushed URLs are dropped so that their counters are read afresh.
+func NewBuffer(repo domain.URLRepository, cache domain.Cache, interval time.Duration, logger *slog.Logger) *Buffer {
+	return &Buffer{
+		repo:     repo,
+		cache:    cache,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (b *Buffer) Add(namespace, shortCode string, unique bool) {
+	var uniqueClicks int64
+	if unique {
+		uniqueClicks = 1
+	}
+	b.add(urlKey{namespace: namespace, shortCode: shortCode}, 1, uniqueClicks)
+}
+
+// add counts clicks on the URL of key, retrying when a flush removes its entry meanwhile
+func (b *Buffer) add(key urlKey, clicks, unique int64) {
+	for {
+		value, ok := b.counts.Load(key)
+		if !ok {
+			value, _ = b.counts.LoadOrStore(key, &counters{})
+		}
+
+		c := value.(*counters)
+		c.mu.Lock()
+		if !c.removed {
+			c.clicks += clicks
+			c.unique += unique
+			c.mu.Unlock()
+			return
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Start launches the background flush loop
+func (b *Buffer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+
+	go func() {
+		defer close(b.done)
+
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if _, err := b.Flush(ctx); err != nil && ctx.Err() == nil {
+				b.logger.Error("Failed to flush buffered clicks", "error", err)
+			}
+		}
+	}()
+}
+
+// Stop ends the flush loop and then flushes the clicks still buffered, giving up when ctx
+// expires
+func (b *Buffer) Stop(ctx context.Context) error {
+	if b.cancel != nil {
+		b.cancel()
+		select {
+		case <-b.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	_, err := b.Flush(ctx)
+	return err
+}
+
+// Flush stores every buffered click in one repository call and returns how many clicks
+// it stored. When the repository fails, the clicks are put back for the next flush.
+func (b *Buffer) Flush(ctx context.Context) (int64, error) {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	var deltas []domain.ClickDelta
+	b.counts.Range(func(key, value any) bool {
+		k, c := key.(urlKey), value.(*counters)
+		c.mu.Lock()
+		delta := domain.ClickDelta{Namespace: k.namespace, ShortCode: k.shortCode, Clicks: c.clicks, UniqueClicks: c.unique}
+		c.clicks, c.unique = 0, 0
+		c.mu.Unlock()
+		if delta.Clicks > 0 {
+			deltas = append(deltas, delta)
+		} else {
+			b.removeIdle(k, c)
+		}
+		return true
+	})
+	if len(deltas) == 0 {
+		return 0, nil
+	}
+
+	// Instances flushing the same URLs update them in the same order, which keeps their
+	// transactions from deadlocking
+	slices.SortFunc(deltas, func(a, b domain.ClickDelta) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.ShortCode, b.ShortCode))
+	})
+
+	if err := b.repo.AddClicks(ctx, deltas); err != nil {
+		b.restore(deltas)
+		return 0, err
+	}
+
+	var total int64
+	for _, delta := range deltas {
+		total += delta.Clicks
+		key := urlKey{namespace: delta.Namespace, shortCode: delta.ShortCode}
+		if value, ok := b.counts.Load(key); ok {
+			b.removeIdle(key, value.(*counters))
+		}
+		if err := b.cache.Delete(ctx, delta.Namespace, delta.ShortCode); err != nil {
+			b.logger.Warn("Failed to invalidate cache after flushing clicks", "namespace", delta.Namespace, "short_code", delta.ShortCode, "error", err)
+		}
+	}
+	if err := b.cache.InvalidateTopURLs(ctx); err != nil {
+		b.logger.Warn("Failed to invalidate top URLs cache after flushing clicks", "error", err)
+	}
+
+	b.logger.Debug("Flushed buffered clicks", "urls", len(deltas), "clicks", total)
+	return total, nil
+}
+
+// restore adds back the clicks of a failed flush
+func (b *Buffer) restore(deltas []domain.ClickDelta) {
+	for _, delta := range deltas {
+		b.add(urlKey{namespace: delta.Namespace, shortCode: delta.ShortCode}, delta.Clicks, delta.UniqueClicks)
+	}
+}
+
+// removeIdle removes the entry c of key unless it counted clicks since they were last taken
+func (b *Buffer) removeIdle(key urlKey, c *counters) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clicks == 0 {
+		c.removed = true
+		b.counts.CompareAndDelete(key, c)
+	}
+}
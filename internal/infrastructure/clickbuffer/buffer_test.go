@@ -0,0 +1,286 @@
+package clickbuffer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/infrastructure/cache"
+	"github.com/sp3dr4/dove/internal/infrastructure/memory"
+	sqliteRepo "github.com/sp3dr4/dove/internal/infrastructure/sqlite"
+)
+
+// recordingCache remembers which short codes were invalidated
+type recordingCache struct {
+	*cache.NoOpCache
+	mu      sync.Mutex
+	deleted []string
+}
+
+func (c *recordingCache) Delete(_ context.Context, _, shortCode string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleted = append(c.deleted, shortCode)
+	return nil
+}
+
+// failingRepository fails every flush while failing is set
+type failingRepository struct {
+	domain.URLRepository
+	failing bool
+	deltas  [][]domain.ClickDelta
+}
+
+func (r *failingRepository) AddClicks(ctx context.Context, deltas []domain.ClickDelta) error {
+	r.deltas = append(r.deltas, deltas)
+	if r.failing {
+		return errors.New("database unavailable")
+	}
+	return r.URLRepository.AddClicks(ctx, deltas)
+}
+
+func newURL(t testing.TB, repo domain.URLRepository, namespace, shortCode string) {
+	t.Helper()
+
+	url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+	require.NoError(t, err)
+	url.Namespace = namespace
+	_, err = repo.Create(context.Background(), url)
+	require.NoError(t, err)
+}
+
+func clicksOf(t testing.TB, repo domain.URLRepository, namespace, shortCode string) (int, int) {
+	t.Helper()
+
+	url, err := repo.FindByNamespaceAndCode(context.Background(), namespace, shortCode)
+	require.NoError(t, err)
+	return url.Clicks, url.UniqueClicks
+}
+
+func TestBuffer_Flush(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	t.Run("stores every concurrent click once", func(t *testing.T) {
+		repo := memory.NewURLRepository(logger, 0)
+		newURL(t, repo, domain.DefaultNamespace, "hot")
+		newURL(t, repo, "team", "hot")
+		urlCache := &recordingCache{NoOpCache: cache.NewNoOpCache()}
+		buffer := NewBuffer(repo, urlCache, time.Minute, logger)
+
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					buffer.Add(domain.DefaultNamespace, "hot", i%5 == 0)
+				}
+				buffer.Add("team", "hot", true)
+			}()
+			if i%10 == 0 {
+				// Flushes racing the clicks must not lose any
+				_, err := buffer.Flush(ctx)
+				require.NoError(t, err)
+			}
+		}
+		wg.Wait()
+		_, err := buffer.Flush(ctx)
+		require.NoError(t, err)
+
+		clicks, unique := clicksOf(t, repo, domain.DefaultNamespace, "hot")
+		assert.Equal(t, 5000, clicks)
+		assert.Equal(t, 1000, unique)
+		clicks, unique = clicksOf(t, repo, "team", "hot")
+		assert.Equal(t, 50, clicks)
+		assert.Equal(t, 50, unique)
+		assert.Contains(t, urlCache.deleted, "hot")
+
+		flushed, err := buffer.Flush(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, flushed, "nothing is left after a flush")
+	})
+
+	t.Run("drops the entries of flushed URLs", func(t *testing.T) {
+		repo := memory.NewURLRepository(logger, 0)
+		newURL(t, repo, domain.DefaultNamespace, "promo")
+		buffer := NewBuffer(repo, cache.NewNoOpCache(), time.Minute, logger)
+
+		buffer.Add(domain.DefaultNamespace, "promo", true)
+		buffer.Add(domain.DefaultNamespace, "gone", false)
+		_, err := buffer.Flush(ctx)
+		require.NoError(t, err)
+
+		entries := 0
+		buffer.counts.Range(func(_, _ any) bool {
+			entries++
+			return true
+		})
+		assert.Zero(t, entries)
+
+		// A later click gets a fresh entry
+		buffer.Add(domain.DefaultNamespace, "promo", false)
+		flushed, err := buffer.Flush(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), flushed)
+		clicks, unique := clicksOf(t, repo, domain.DefaultNamespace, "promo")
+		assert.Equal(t, 2, clicks)
+		assert.Equal(t, 1, unique)
+	})
+
+	t.Run("keeps the clicks of a failed flush", func(t *testing.T) {
+		memoryRepo := memory.NewURLRepository(logger, 0)
+		newURL(t, memoryRepo, domain.DefaultNamespace, "promo")
+		repo := &failingRepository{URLRepository: memoryRepo, failing: true}
+		buffer := NewBuffer(repo, cache.NewNoOpCache(), time.Minute, logger)
+
+		buffer.Add(domain.DefaultNamespace, "promo", true)
+		buffer.Add(domain.DefaultNamespace, "promo", false)
+		_, err := buffer.Flush(ctx)
+		require.Error(t, err)
+
+		buffer.Add(domain.DefaultNamespace, "promo", false)
+		repo.failing = false
+		flushed, err := buffer.Flush(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), flushed)
+
+		clicks, unique := clicksOf(t, memoryRepo, domain.DefaultNamespace, "promo")
+		assert.Equal(t, 3, clicks)
+		assert.Equal(t, 1, unique)
+	})
+
+	t.Run("flushes URLs in a stable order in one call", func(t *testing.T) {
+		memoryRepo := memory.NewURLRepository(logger, 0)
+		repo := &failingRepository{URLRepository: memoryRepo}
+		buffer := NewBuffer(repo, cache.NewNoOpCache(), time.Minute, logger)
+
+		for _, key := range []urlKey{{"team", "b"}, {domain.DefaultNamespace, "z"}, {"team", "a"}, {domain.DefaultNamespace, "a"}} {
+			buffer.Add(key.namespace, key.shortCode, false)
+		}
+		_, err := buffer.Flush(ctx)
+		require.NoError(t, err)
+
+		require.Len(t, repo.deltas, 1)
+		var order []string
+		for _, delta := range repo.deltas[0] {
+			order = append(order, delta.Namespace+"/"+delta.ShortCode)
+		}
+		assert.Equal(t, []string{"default/a", "default/z", "team/a", "team/b"}, order)
+	})
+
+	t.Run("skips deleted URLs", func(t *testing.T) {
+		repo := memory.NewURLRepository(logger, 0)
+		newURL(t, repo, domain.DefaultNamespace, "kept")
+		buffer := NewBuffer(repo, cache.NewNoOpCache(), time.Minute, logger)
+
+		buffer.Add(domain.DefaultNamespace, "kept", false)
+		buffer.Add(domain.DefaultNamespace, "gone", false)
+		_, err := buffer.Flush(ctx)
+		require.NoError(t, err)
+
+		clicks, _ := clicksOf(t, repo, domain.DefaultNamespace, "kept")
+		assert.Equal(t, 1, clicks)
+	})
+}
+
+func TestBuffer_StartStop(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	newURL(t, repo, domain.DefaultNamespace, "ticked")
+	buffer := NewBuffer(repo, cache.NewNoOpCache(), 10*time.Millisecond, logger)
+	buffer.Start()
+
+	buffer.Add(domain.DefaultNamespace, "ticked", false)
+	assert.Eventually(t, func() bool {
+		clicks, _ := clicksOf(t, repo, domain.DefaultNamespace, "ticked")
+		return clicks == 1
+	}, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	buffer.Add(domain.DefaultNamespace, "ticked", false)
+	require.NoError(t, buffer.Stop(ctx))
+
+	clicks, _ := clicksOf(t, repo, domain.DefaultNamespace, "ticked")
+	assert.Equal(t, 2, clicks, "stopping flushes the clicks still buffered")
+}
+
+// newBenchmarkRepository opens a migrated SQLite database, as click counters are only
+// costly to store in a real database
+func newBenchmarkRepository(b *testing.B) domain.URLRepository {
+	b.Helper()
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(b.TempDir(), "dove.db"))
+	require.NoError(b, err)
+	driver, err := sqlite3.WithInstance(db.DB, &sqlite3.Config{})
+	require.NoError(b, err)
+	migrationsPath, err := filepath.Abs("../../../migrations/sqlite")
+	require.NoError(b, err)
+	m, err := migrate.NewWithDatabaseInstance(fmt.Sprintf("file://%s", migrationsPath), "sqlite3", driver)
+	require.NoError(b, err)
+	require.NoError(b, m.Up())
+
+	repo, err := sqliteRepo.NewURLRepository(db, sqliteRepo.Pragmas{JournalMode: "WAL", Synchronous: "NORMAL", BusyTimeoutMs: 5000}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(b, err)
+	b.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+// BenchmarkClicks compares updating the counters on every click with buffering them. The
+// buffered run includes a flush every 1000 clicks and a final one.
+func BenchmarkClicks(b *testing.B) {
+	ctx := context.Background()
+	shortCodes := []string{"bench0", "bench1", "bench2", "bench3"}
+
+	b.Run("sync", func(b *testing.B) {
+		repo := newBenchmarkRepository(b)
+		for _, shortCode := range shortCodes {
+			newURL(b, repo, domain.DefaultNamespace, shortCode)
+		}
+
+		i := 0
+		for b.Loop() {
+			if _, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, shortCodes[i%len(shortCodes)], false); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+
+	b.Run("async", func(b *testing.B) {
+		repo := newBenchmarkRepository(b)
+		for _, shortCode := range shortCodes {
+			newURL(b, repo, domain.DefaultNamespace, shortCode)
+		}
+		buffer := NewBuffer(repo, cache.NewNoOpCache(), time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		i := 0
+		for b.Loop() {
+			buffer.Add(domain.DefaultNamespace, shortCodes[i%len(shortCodes)], false)
+			i++
+			if i%1000 == 0 {
+				if _, err := buffer.Flush(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		if _, err := buffer.Flush(ctx); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
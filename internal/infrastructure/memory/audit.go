@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// AuditRepository keeps the change history of short URLs in memory, for the in-memory
+// URL repository
+type AuditRepository struct {
+	mu      sync.RWMutex
+	entries []domain.AuditEntry // in recording order
+}
+
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+func (r *AuditRepository) Record(ctx context.Context, entry domain.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.OldValue = slices.Clone(entry.OldValue)
+	entry.NewValue = slices.Clone(entry.NewValue)
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *AuditRepository) ListByShortCode(ctx context.Context, namespace, shortCode string, limit int) ([]domain.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Entries are recorded as they happen, so the last recorded is the most recent
+	entries := []domain.AuditEntry{}
+	for i := len(r.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		entry := r.entries[i]
+		if entry.Namespace == namespace && entry.ShortCode == shortCode {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+func (r *URLRepository) CreateFunnel(ctx context.Context, funnel *domain.Funnel) (*domain.Funnel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastFunnelID++
+	created := &domain.Funnel{
+		ID:        r.lastFunnelID,
+		Name:      funnel.Name,
+		Steps:     append([]domain.FunnelStep(nil), funnel.Steps...),
+		CreatedAt: funnel.CreatedAt,
+	}
+	r.funnels[created.ID] = created
+
+	copied := *created
+	return &copied, nil
+}
+
+func (r *URLRepository) FindFunnel(ctx context.Context, id int64) (*domain.Funnel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	funnel, ok := r.funnels[id]
+	if !ok {
+		return nil, domain.ErrFunnelNotFound
+	}
+
+	copied := *funnel
+	copied.Steps = append([]domain.FunnelStep(nil), funnel.Steps...)
+	return &copied, nil
+}
+
+func (r *URLRepository) FindFunnelSteps(ctx context.Context, namespace, shortCode string) ([]domain.FunnelStepRef, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var refs []domain.FunnelStepRef
+	for id, funnel := range r.funnels {
+		for _, step := range funnel.Steps {
+			if step.Namespace == namespace && step.ShortCode == shortCode {
+				refs = append(refs, domain.FunnelStepRef{FunnelID: id, StepOrder: step.Order})
+			}
+		}
+	}
+
+	// Match the ORDER BY of the SQL repositories
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].FunnelID != refs[j].FunnelID {
+			return refs[i].FunnelID < refs[j].FunnelID
+		}
+		return refs[i].StepOrder < refs[j].StepOrder
+	})
+	return refs, nil
+}
+
+func (r *URLRepository) RecordFunnelClick(ctx context.Context, click *domain.FunnelClick) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.funnelClicks = append(r.funnelClicks, *click)
+	return nil
+}
+
+func (r *URLRepository) FunnelSessionSteps(ctx context.Context, funnelID int64) ([]domain.FunnelSessionStep, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[domain.FunnelSessionStep]bool)
+	var steps []domain.FunnelSessionStep
+	for _, click := range r.funnelClicks {
+		step := domain.FunnelSessionStep{SessionID: click.SessionID, StepOrder: click.StepOrder}
+		if click.FunnelID != funnelID || seen[step] {
+			continue
+		}
+		seen[step] = true
+		steps = append(steps, step)
+	}
+
+	sort.Slice(steps, func(i, j int) bool {
+		if steps[i].SessionID != steps[j].SessionID {
+			return steps[i].SessionID < steps[j].SessionID
+		}
+		return steps[i].StepOrder < steps[j].StepOrder
+	})
+	return steps, nil
+}
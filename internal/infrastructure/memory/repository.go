@@ -1,84 +1,1020 @@
 package memory
 
 import (
+	"container/list"
 	"context"
+	"fmt"
 	"log/slog"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sp3dr4/dove/internal/domain"
 )
 
+// urlKey identifies a URL, short codes being unique per namespace only
+type urlKey struct {
+	namespace string
+	shortCode string
+}
+
 type URLRepository struct {
-	urls   map[string]*domain.URL
-	mu     sync.RWMutex
-	logger *slog.Logger
+	urls          map[urlKey]*domain.URL
+	clicks        map[urlKey][]domain.Click
+	lastID        atomic.Int64 // IDs are never reused, like a database sequence
+	nextVariantID int64
+	lastClickID   int64
+	funnels       map[int64]*domain.Funnel
+	funnelClicks  []domain.FunnelClick
+	lastFunnelID  int64
+	mu            sync.RWMutex
+	logger        *slog.Logger
+
+	// capacity bounds the number of stored URLs, 0 leaves it unbounded. recent orders the
+	// URLs from the most to the least recently accessed, the last one being evicted to make
+	// room for a new URL. It has its own lock as lookups only hold a read lock on mu.
+	capacity  int
+	evictions int64
+	lruMu     sync.Mutex
+	recent    *list.List // of urlKey
+	elements  map[urlKey]*list.Element
 }
 
-func NewURLRepository(logger *slog.Logger) *URLRepository {
+// NewURLRepository creates a repository holding up to capacity URLs, evicting the least
+// recently accessed one when full. A capacity of 0 leaves it unbounded.
+func NewURLRepository(logger *slog.Logger, capacity int) *URLRepository {
 	return &URLRepository{
-		urls:   make(map[string]*domain.URL),
-		logger: logger,
+		urls:     make(map[urlKey]*domain.URL),
+		clicks:   make(map[urlKey][]domain.Click),
+		funnels:  make(map[int64]*domain.Funnel),
+		logger:   logger,
+		capacity: capacity,
+		recent:   list.New(),
+		elements: make(map[urlKey]*list.Element),
+	}
+}
+
+// MemoryStats reports how full the repository is
+func (r *URLRepository) MemoryStats() domain.MemoryStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return domain.MemoryStats{Capacity: r.capacity, Size: len(r.urls), Evictions: r.evictions}
+}
+
+// touch marks the URL as the most recently accessed
+func (r *URLRepository) touch(key urlKey) {
+	if r.capacity == 0 {
+		return
+	}
+
+	r.lruMu.Lock()
+	defer r.lruMu.Unlock()
+
+	if element, ok := r.elements[key]; ok {
+		r.recent.MoveToFront(element)
+		return
+	}
+	r.elements[key] = r.recent.PushFront(key)
+}
+
+// forget stops tracking the accesses of a removed URL
+func (r *URLRepository) forget(key urlKey) {
+	r.lruMu.Lock()
+	defer r.lruMu.Unlock()
+
+	if element, ok := r.elements[key]; ok {
+		r.recent.Remove(element)
+		delete(r.elements, key)
+	}
+}
+
+// evictLeastRecent removes the least recently accessed URL with its clicks. The caller
+// holds the write lock.
+func (r *URLRepository) evictLeastRecent() {
+	r.lruMu.Lock()
+	element := r.recent.Back()
+	if element == nil {
+		r.lruMu.Unlock()
+		return
 	}
+	key := r.recent.Remove(element).(urlKey)
+	delete(r.elements, key)
+	r.lruMu.Unlock()
+
+	delete(r.urls, key)
+	delete(r.clicks, key)
+	r.evictions++
+	r.logger.Warn("Evicted the least recently accessed URL, the memory repository is full", "namespace", key.namespace, "short_code", key.shortCode, "capacity", r.capacity)
 }
 
 func (r *URLRepository) Create(ctx context.Context, url *domain.URL) (*domain.URL, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.urls[url.ShortCode]; exists {
+	key := urlKey{namespace: url.Namespace, shortCode: url.ShortCode}
+	if _, exists := r.urls[key]; exists {
 		return nil, domain.ErrShortCodeExists
 	}
+	if r.capacity > 0 && len(r.urls) >= r.capacity {
+		r.evictLeastRecent()
+	}
 
 	// Create a copy with a generated ID (simulate database behavior)
 	createdURL := &domain.URL{
-		ID:          int64(len(r.urls) + 1), // Simple ID generation
-		ShortCode:   url.ShortCode,
-		OriginalURL: url.OriginalURL,
-		Clicks:      url.Clicks,
-		CreatedAt:   url.CreatedAt,
-		UpdatedAt:   url.UpdatedAt,
+		ID:            r.lastID.Add(1),
+		Namespace:     url.Namespace,
+		ShortCode:     url.ShortCode,
+		OriginalURL:   url.OriginalURL,
+		Clicks:        url.Clicks,
+		CreatedAt:     url.CreatedAt,
+		UpdatedAt:     url.UpdatedAt,
+		PasswordHash:  url.PasswordHash,
+		HealthStatus:  url.HealthStatus,
+		LastCheckedAt: url.LastCheckedAt,
+		Signed:        url.Signed,
+		PoolEnabled:   url.PoolEnabled,
+		Pool:          url.Pool,
+		GeoRoutes:     append(domain.GeoRoutes(nil), url.GeoRoutes...),
+		DeviceRoutes:  append(domain.DeviceRoutes(nil), url.DeviceRoutes...),
+		RedirectType:  url.RedirectType,
+		DelaySeconds:  url.DelaySeconds,
+		ExpiresAt:     url.ExpiresAt,
+		Tags:          append(domain.Tags(nil), url.Tags...),
+		Description:   url.Description,
+		Enabled:       url.Enabled,
+		IsCustomAlias: url.IsCustomAlias,
+		ClickGoal:     url.ClickGoal,
+		CreatedByIP:   url.CreatedByIP,
+		Title:         url.Title,
 	}
+	for _, variant := range url.Variants {
+		r.nextVariantID++
+		createdURL.Variants = append(createdURL.Variants, domain.URLVariant{
+			ID:          r.nextVariantID,
+			URLID:       createdURL.ID,
+			OriginalURL: variant.OriginalURL,
+			Weight:      variant.Weight,
+		})
+	}
+
+	r.urls[key] = createdURL
+	r.touch(key)
 
-	r.urls[url.ShortCode] = createdURL
-	return createdURL, nil
+	copied := *createdURL
+	return &copied, nil
 }
 
 func (r *URLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return r.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+}
+
+func (r *URLRepository) FindByNamespaceAndCode(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	url, exists := r.urls[shortCode]
+	key := urlKey{namespace: namespace, shortCode: shortCode}
+	url, exists := r.urls[key]
 	if !exists {
 		return nil, domain.ErrURLNotFound
 	}
+	r.touch(key)
+
+	// Callers get a copy so later updates do not race with their reads
+	copied := *url
+	return &copied, nil
+}
+
+func (r *URLRepository) FindByShortCodes(ctx context.Context, namespace string, shortCodes []string) ([]*domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := make([]*domain.URL, 0, len(shortCodes))
+	seen := make(map[string]bool, len(shortCodes))
+	for _, shortCode := range shortCodes {
+		key := urlKey{namespace: namespace, shortCode: shortCode}
+		url, exists := r.urls[key]
+		if !exists || seen[shortCode] {
+			continue
+		}
+		seen[shortCode] = true
+		r.touch(key)
+
+		copied := *url
+		urls = append(urls, &copied)
+	}
 
-	return url, nil
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].ID < urls[j].ID
+	})
+	return urls, nil
 }
 
-func (r *URLRepository) IncrementClicks(ctx context.Context, shortCode string) (*domain.URL, error) {
+func (r *URLRepository) IncrementClicks(ctx context.Context, namespace, shortCode string, unique bool) (*domain.URL, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	url, exists := r.urls[shortCode]
+	key := urlKey{namespace: namespace, shortCode: shortCode}
+	url, exists := r.urls[key]
 	if !exists {
 		return nil, domain.ErrURLNotFound
 	}
+	r.touch(key)
 
 	url.Clicks++
-	url.UpdatedAt = time.Now()
+	if unique {
+		url.UniqueClicks++
+	}
+	now := time.Now()
+	url.UpdatedAt = now
+	url.LastAccessedAt = &now
+
+	copied := *url
+	return &copied, nil
+}
+
+func (r *URLRepository) AddClicks(ctx context.Context, deltas []domain.ClickDelta) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, delta := range deltas {
+		key := urlKey{namespace: delta.Namespace, shortCode: delta.ShortCode}
+		url, exists := r.urls[key]
+		if !exists {
+			continue
+		}
+		r.touch(key)
+		url.Clicks += int(delta.Clicks)
+		url.UniqueClicks += int(delta.UniqueClicks)
+		url.UpdatedAt = now
+		url.LastAccessedAt = &now
+	}
+	return nil
+}
+
+// FindByOriginalURL returns the oldest URL of namespace that redirects to originalURL
+func (r *URLRepository) FindByOriginalURL(ctx context.Context, namespace, originalURL string) (*domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found *domain.URL
+	for key, url := range r.urls {
+		if key.namespace == namespace && url.OriginalURL == originalURL && (found == nil || url.ID < found.ID) {
+			found = url
+		}
+	}
+	if found == nil {
+		return nil, domain.ErrURLNotFound
+	}
+
+	copied := *found
+	return &copied, nil
+}
+
+// Update replaces the stored fields of the URL with the same namespace and short code,
+// keeping its ID, creation time, click count, health and variants
+func (r *URLRepository) Update(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := urlKey{namespace: url.Namespace, shortCode: url.ShortCode}
+	stored, exists := r.urls[key]
+	if !exists {
+		return nil, domain.ErrURLNotFound
+	}
+
+	updated := *url
+	updated.ID = stored.ID
+	updated.CreatedAt = stored.CreatedAt
+	updated.Clicks = stored.Clicks
+	updated.HealthStatus = stored.HealthStatus
+	updated.LastCheckedAt = stored.LastCheckedAt
+	updated.Enabled = stored.Enabled
+	updated.LastAccessedAt = stored.LastAccessedAt
+	updated.IsCustomAlias = stored.IsCustomAlias
+	updated.ClickGoal = stored.ClickGoal
+	updated.GoalReached = stored.GoalReached
+	updated.CreatedByIP = stored.CreatedByIP
+	updated.Variants = stored.Variants
+	updated.Tags = append(domain.Tags(nil), url.Tags...)
+	updated.GeoRoutes = append(domain.GeoRoutes(nil), url.GeoRoutes...)
+	updated.DeviceRoutes = append(domain.DeviceRoutes(nil), url.DeviceRoutes...)
+	updated.UpdatedAt = time.Now()
+	r.urls[key] = &updated
+
+	copied := updated
+	return &copied, nil
+}
+
+// Delete removes a URL together with its recorded clicks
+func (r *URLRepository) Delete(ctx context.Context, namespace, shortCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := urlKey{namespace: namespace, shortCode: shortCode}
+	if _, exists := r.urls[key]; !exists {
+		return domain.ErrURLNotFound
+	}
+
+	delete(r.urls, key)
+	delete(r.clicks, key)
+	r.forget(key)
+	return nil
+}
+
+// DeleteMany removes the URLs of namespace with any of shortCodes, in ID order
+func (r *URLRepository) DeleteMany(ctx context.Context, namespace string, shortCodes []string) ([]*domain.URL, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	urls := make([]*domain.URL, 0, len(shortCodes))
+	for _, shortCode := range shortCodes {
+		key := urlKey{namespace: namespace, shortCode: shortCode}
+		url, exists := r.urls[key]
+		if !exists {
+			continue
+		}
+		urls = append(urls, url)
+		delete(r.urls, key)
+		delete(r.clicks, key)
+		r.forget(key)
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].ID < urls[j].ID
+	})
+	return urls, nil
+}
 
-	return url, nil
+// Search returns up to limit URLs whose short code, original URL or description contains
+// query, ignoring case, in ID order
+func (r *URLRepository) Search(ctx context.Context, query string, limit int) ([]*domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	query = strings.ToLower(query)
+	urls := make([]*domain.URL, 0)
+	for _, url := range r.urls {
+		if strings.Contains(strings.ToLower(url.ShortCode), query) || strings.Contains(strings.ToLower(url.OriginalURL), query) ||
+			strings.Contains(strings.ToLower(url.Description), query) {
+			copied := *url
+			urls = append(urls, &copied)
+		}
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].ID < urls[j].ID
+	})
+
+	if len(urls) > limit {
+		urls = urls[:limit]
+	}
+	return urls, nil
 }
 
-func (r *URLRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
+func (r *URLRepository) Exists(ctx context.Context, namespace, shortCode string) (bool, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	_, exists := r.urls[shortCode]
+	_, exists := r.urls[urlKey{namespace: namespace, shortCode: shortCode}]
 	return exists, nil
 }
 
+func (r *URLRepository) List(ctx context.Context, afterID int64, limit int) ([]*domain.URL, error) {
+	return r.list(afterID, limit, func(*domain.URL) bool { return true })
+}
+
+func (r *URLRepository) ListByCreatorIP(ctx context.Context, ip string, afterID int64, limit int) ([]*domain.URL, error) {
+	return r.list(afterID, limit, func(url *domain.URL) bool { return url.CreatedByIP == ip })
+}
+
+// list returns up to limit URLs matching keep after afterID, in ID order
+func (r *URLRepository) list(afterID int64, limit int, keep func(*domain.URL) bool) ([]*domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := make([]*domain.URL, 0)
+	for _, url := range r.urls {
+		if url.ID > afterID && keep(url) {
+			copied := *url
+			urls = append(urls, &copied)
+		}
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].ID < urls[j].ID
+	})
+
+	if len(urls) > limit {
+		urls = urls[:limit]
+	}
+	return urls, nil
+}
+
+func (r *URLRepository) FindExpired(ctx context.Context, before time.Time, limit int) ([]*domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := []*domain.URL{}
+	for _, url := range r.urls {
+		if url.ExpiresAt != nil && url.ExpiresAt.Before(before) {
+			copied := *url
+			urls = append(urls, &copied)
+		}
+	}
+
+	sort.Slice(urls, func(i, j int) bool { return urls[i].ID < urls[j].ID })
+	if len(urls) > limit {
+		urls = urls[:limit]
+	}
+	return urls, nil
+}
+
+func (r *URLRepository) FindNotAccessedSince(ctx context.Context, since time.Time, limit int) ([]*domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := []*domain.URL{}
+	for _, url := range r.urls {
+		if url.Enabled && url.LastActivity().Before(since) {
+			copied := *url
+			urls = append(urls, &copied)
+		}
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		if !urls[i].LastActivity().Equal(urls[j].LastActivity()) {
+			return urls[i].LastActivity().Before(urls[j].LastActivity())
+		}
+		return urls[i].ID < urls[j].ID
+	})
+	if len(urls) > limit {
+		urls = urls[:limit]
+	}
+	return urls, nil
+}
+
+func (r *URLRepository) ListForHealthCheck(ctx context.Context, limit int) ([]*domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := make([]*domain.URL, 0, len(r.urls))
+	for _, url := range r.urls {
+		copied := *url
+		urls = append(urls, &copied)
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		a, b := urls[i].LastCheckedAt, urls[j].LastCheckedAt
+		switch {
+		case a == nil && b == nil:
+			return urls[i].ID < urls[j].ID
+		case a == nil:
+			return true
+		case b == nil:
+			return false
+		case a.Equal(*b):
+			return urls[i].ID < urls[j].ID
+		default:
+			return a.Before(*b)
+		}
+	})
+
+	if len(urls) > limit {
+		urls = urls[:limit]
+	}
+	return urls, nil
+}
+
+func (r *URLRepository) TopByClicks(ctx context.Context, n int) ([]*domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := make([]*domain.URL, 0, len(r.urls))
+	for _, url := range r.urls {
+		if !url.Enabled || url.Signed || url.IsPasswordProtected() {
+			continue
+		}
+		copied := *url
+		urls = append(urls, &copied)
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		if urls[i].Clicks != urls[j].Clicks {
+			return urls[i].Clicks > urls[j].Clicks
+		}
+		return urls[i].ID < urls[j].ID
+	})
+
+	if len(urls) > n {
+		urls = urls[:n]
+	}
+	return urls, nil
+}
+
+func (r *URLRepository) UpdateHealthStatus(ctx context.Context, namespace, shortCode, status string, checkedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, exists := r.urls[urlKey{namespace: namespace, shortCode: shortCode}]
+	if !exists {
+		return domain.ErrURLNotFound
+	}
+
+	url.HealthStatus = status
+	url.LastCheckedAt = &checkedAt
+	return nil
+}
+
+func (r *URLRepository) SetEnabled(ctx context.Context, namespace, shortCode string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, exists := r.urls[urlKey{namespace: namespace, shortCode: shortCode}]
+	if !exists {
+		return domain.ErrURLNotFound
+	}
+
+	url.Enabled = enabled
+	url.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *URLRepository) MarkGoalReached(ctx context.Context, namespace, shortCode string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, exists := r.urls[urlKey{namespace: namespace, shortCode: shortCode}]
+	if !exists || url.GoalReached {
+		return false, nil
+	}
+
+	url.GoalReached = true
+	return true, nil
+}
+
+func (r *URLRepository) RecordClick(ctx context.Context, click *domain.Click) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := urlKey{namespace: click.Namespace, shortCode: click.ShortCode}
+	if _, exists := r.urls[key]; !exists {
+		return domain.ErrURLNotFound
+	}
+
+	r.lastClickID++
+	click.ID = r.lastClickID
+	r.clicks[key] = append(r.clicks[key], *click)
+	return nil
+}
+
+func (r *URLRepository) ListClicks(ctx context.Context, namespace, shortCode string, after domain.ClickCursor, limit int) ([]*domain.Click, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Clicks are kept in the order they were recorded, which need not be the order of their times
+	var clicks []*domain.Click
+	for _, click := range r.clicks[urlKey{namespace: namespace, shortCode: shortCode}] {
+		if click.ClickedAt.After(after.ClickedAt) || (click.ClickedAt.Equal(after.ClickedAt) && click.ID > after.ID) {
+			clicks = append(clicks, &click)
+		}
+	}
+	sort.Slice(clicks, func(i, j int) bool {
+		if !clicks[i].ClickedAt.Equal(clicks[j].ClickedAt) {
+			return clicks[i].ClickedAt.Before(clicks[j].ClickedAt)
+		}
+		return clicks[i].ID < clicks[j].ID
+	})
+
+	if len(clicks) > limit {
+		clicks = clicks[:limit]
+	}
+	return clicks, nil
+}
+
+func (r *URLRepository) ResetClicks(ctx context.Context, namespace, shortCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := urlKey{namespace: namespace, shortCode: shortCode}
+	url, exists := r.urls[key]
+	if !exists {
+		return domain.ErrURLNotFound
+	}
+
+	url.Clicks = 0
+	url.UniqueClicks = 0
+	url.UpdatedAt = time.Now()
+	delete(r.clicks, key)
+	return nil
+}
+
+func (r *URLRepository) ClickTimeSeries(ctx context.Context, namespace, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.TimeBucket, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[time.Time]int)
+	for _, click := range r.clicks[urlKey{namespace: namespace, shortCode: shortCode}] {
+		if click.ClickedAt.Before(from) || click.ClickedAt.After(to) {
+			continue
+		}
+		counts[granularity.Truncate(click.ClickedAt)]++
+	}
+
+	buckets := make([]domain.TimeBucket, 0, len(counts))
+	for period, clicks := range counts {
+		buckets = append(buckets, domain.TimeBucket{Period: period, Clicks: clicks})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Period.Before(buckets[j].Period)
+	})
+
+	return buckets, nil
+}
+
+func (r *URLRepository) ClickHeatmap(ctx context.Context, namespace, shortCode string, loc *time.Location) (*domain.ClickHeatmap, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	heatmap := &domain.ClickHeatmap{}
+	for _, click := range r.clicks[urlKey{namespace: namespace, shortCode: shortCode}] {
+		heatmap.Add(click.ClickedAt.In(loc), 1)
+	}
+	return heatmap, nil
+}
+
+func (r *URLRepository) TopReferrers(ctx context.Context, namespace, shortCode string, limit int) ([]domain.ReferrerCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, click := range r.clicks[urlKey{namespace: namespace, shortCode: shortCode}] {
+		counts[click.Referer]++
+	}
+
+	referrers := make([]domain.ReferrerCount, 0, len(counts))
+	for referer, count := range counts {
+		referrers = append(referrers, domain.ReferrerCount{Referer: referer, Count: count})
+	}
+	sort.Slice(referrers, func(i, j int) bool {
+		if referrers[i].Count != referrers[j].Count {
+			return referrers[i].Count > referrers[j].Count
+		}
+		return referrers[i].Referer < referrers[j].Referer
+	})
+
+	if len(referrers) > limit {
+		referrers = referrers[:limit]
+	}
+	return referrers, nil
+}
+
+func (r *URLRepository) TopIPs(ctx context.Context, limit int) ([]domain.IPCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, clicks := range r.clicks {
+		for _, click := range clicks {
+			if click.IPAddress != "" {
+				counts[click.IPAddress]++
+			}
+		}
+	}
+
+	ips := make([]domain.IPCount, 0, len(counts))
+	for ip, count := range counts {
+		ips = append(ips, domain.IPCount{IPAddress: ip, Count: count})
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		if ips[i].Count != ips[j].Count {
+			return ips[i].Count > ips[j].Count
+		}
+		return ips[i].IPAddress < ips[j].IPAddress
+	})
+
+	if len(ips) > limit {
+		ips = ips[:limit]
+	}
+	return ips, nil
+}
+
+func (r *URLRepository) DeviceStats(ctx context.Context, namespace, shortCode string, grouping domain.DeviceGrouping) ([]domain.DeviceStat, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[domain.DeviceStat]int)
+	for _, click := range r.clicks[urlKey{namespace: namespace, shortCode: shortCode}] {
+		var key domain.DeviceStat
+		switch grouping {
+		case domain.DeviceGroupingDevice:
+			key = domain.DeviceStat{DeviceType: click.DeviceType, Browser: click.Browser, OS: click.OS}
+		case domain.DeviceGroupingBrowser:
+			key = domain.DeviceStat{Browser: click.Browser}
+		case domain.DeviceGroupingOS:
+			key = domain.DeviceStat{OS: click.OS}
+		default:
+			return nil, fmt.Errorf("unsupported device grouping %q", grouping)
+		}
+		counts[key]++
+	}
+
+	stats := make([]domain.DeviceStat, 0, len(counts))
+	for key, count := range counts {
+		key.Count = count
+		stats = append(stats, key)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.DeviceType != b.DeviceType {
+			return a.DeviceType < b.DeviceType
+		}
+		if a.Browser != b.Browser {
+			return a.Browser < b.Browser
+		}
+		return a.OS < b.OS
+	})
+
+	return stats, nil
+}
+
+func (r *URLRepository) VariantStats(ctx context.Context, namespace, shortCode string) ([]domain.VariantStat, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key := urlKey{namespace: namespace, shortCode: shortCode}
+	url, exists := r.urls[key]
+	if !exists {
+		return []domain.VariantStat{}, nil
+	}
+
+	counts := make(map[int64]int)
+	for _, click := range r.clicks[key] {
+		if click.VariantID != nil {
+			counts[*click.VariantID]++
+		}
+	}
+
+	stats := make([]domain.VariantStat, 0, len(url.Variants))
+	for _, variant := range url.Variants {
+		stats = append(stats, domain.VariantStat{
+			VariantID:   variant.ID,
+			OriginalURL: variant.OriginalURL,
+			Weight:      variant.Weight,
+			Clicks:      counts[variant.ID],
+		})
+	}
+
+	return stats, nil
+}
+
+func (r *URLRepository) RedirectLatency(ctx context.Context, namespace, shortCode string) (*domain.LatencyStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var durations []float64
+	for _, click := range r.clicks[urlKey{namespace: namespace, shortCode: shortCode}] {
+		if click.RedirectDurationMs != nil {
+			durations = append(durations, *click.RedirectDurationMs)
+		}
+	}
+	sort.Float64s(durations)
+
+	stats := domain.NewLatencyStats(durations)
+	return &stats, nil
+}
+
+func (r *URLRepository) Stats(ctx context.Context, now, since time.Time) (*domain.ServiceStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := &domain.ServiceStats{TotalURLs: int64(len(r.urls))}
+	for _, url := range r.urls {
+		stats.TotalClicks += int64(url.Clicks)
+		if url.ExpiresAt == nil || url.ExpiresAt.After(now) {
+			stats.ActiveURLs++
+		}
+		if url.CreatedAt.After(since) {
+			stats.RecentURLs++
+		}
+	}
+	for _, clicks := range r.clicks {
+		for _, click := range clicks {
+			if click.ClickedAt.After(since) {
+				stats.RecentClicks++
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+func (r *URLRepository) CountActive(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, url := range r.urls {
+		if url.Enabled && (url.ExpiresAt == nil || url.ExpiresAt.After(now)) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *URLRepository) CountByCreatedDate(ctx context.Context, date time.Time) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	day := utcDay(date)
+	var count int
+	for _, url := range r.urls {
+		if utcDay(url.CreatedAt).Equal(day) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *URLRepository) DailyActivity(ctx context.Context, from, to time.Time) ([]domain.DailyActivity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	from, to = utcDay(from), utcDay(to)
+	byDay := make(map[time.Time]*domain.DailyActivity)
+	activity := func(t time.Time) *domain.DailyActivity {
+		day := utcDay(t)
+		if day.Before(from) || day.After(to) {
+			return nil
+		}
+		if byDay[day] == nil {
+			byDay[day] = &domain.DailyActivity{Day: day}
+		}
+		return byDay[day]
+	}
+
+	for _, url := range r.urls {
+		if day := activity(url.CreatedAt); day != nil {
+			day.URLsCreated++
+		}
+	}
+	for _, clicks := range r.clicks {
+		for _, click := range clicks {
+			if day := activity(click.ClickedAt); day != nil {
+				day.Clicks++
+			}
+		}
+	}
+
+	days := make([]domain.DailyActivity, 0, len(byDay))
+	for _, day := range byDay {
+		days = append(days, *day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day.Before(days[j].Day) })
+
+	return days, nil
+}
+
+// utcDay returns midnight UTC of the day of t
+func utcDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func (r *URLRepository) AliasLengths(ctx context.Context) ([]domain.AliasLengthCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	index := make(map[domain.AliasLengthCount]int)
+	counts := []domain.AliasLengthCount{}
+	for _, url := range r.urls {
+		key := domain.AliasLengthCount{Custom: url.IsCustomAlias, Length: len(url.ShortCode)}
+		i, seen := index[key]
+		if !seen {
+			i = len(counts)
+			index[key] = i
+			counts = append(counts, key)
+		}
+		counts[i].URLs++
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Custom != counts[j].Custom {
+			return !counts[i].Custom
+		}
+		return counts[i].Length < counts[j].Length
+	})
+	return counts, nil
+}
+
+// Snapshot returns a deep copy of every stored URL in ID order, for tests and debugging
+func (r *URLRepository) Snapshot() []domain.URL {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := make([]domain.URL, 0, len(r.urls))
+	for _, url := range r.urls {
+		urls = append(urls, cloneURL(url))
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].ID < urls[j].ID
+	})
+	return urls
+}
+
+// Load stores copies of urls as they are, the inverse of Snapshot. URLs and variants
+// without an ID get the next one and later creates never reuse a loaded ID. Nothing is
+// loaded when a URL takes the short code or ID of another, or when they would not fit
+// in the capacity.
+func (r *URLRepository) Load(urls []domain.URL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.capacity > 0 && len(r.urls)+len(urls) > r.capacity {
+		return fmt.Errorf("cannot load %d URLs, %d of %d slots are taken", len(urls), len(r.urls), r.capacity)
+	}
+
+	ids := make(map[int64]bool, len(r.urls)+len(urls))
+	for _, url := range r.urls {
+		ids[url.ID] = true
+	}
+	keys := make(map[urlKey]bool, len(urls))
+	for _, url := range urls {
+		key := urlKey{namespace: url.Namespace, shortCode: url.ShortCode}
+		if _, exists := r.urls[key]; exists || keys[key] {
+			return fmt.Errorf("%w: %s/%s", domain.ErrShortCodeExists, url.Namespace, url.ShortCode)
+		}
+		if url.ID != 0 && ids[url.ID] {
+			return fmt.Errorf("URL ID %d is taken", url.ID)
+		}
+		keys[key] = true
+		ids[url.ID] = true
+	}
+
+	// Assigned IDs follow the highest loaded one
+	lastID := r.lastID.Load()
+	for _, url := range urls {
+		lastID = max(lastID, url.ID)
+		for _, variant := range url.Variants {
+			r.nextVariantID = max(r.nextVariantID, variant.ID)
+		}
+	}
+
+	for _, url := range urls {
+		loaded := cloneURL(&url)
+		if loaded.ID == 0 {
+			lastID++
+			loaded.ID = lastID
+		}
+		for i := range loaded.Variants {
+			loaded.Variants[i].URLID = loaded.ID
+			if loaded.Variants[i].ID == 0 {
+				r.nextVariantID++
+				loaded.Variants[i].ID = r.nextVariantID
+			}
+		}
+
+		key := urlKey{namespace: loaded.Namespace, shortCode: loaded.ShortCode}
+		r.urls[key] = &loaded
+		r.touch(key)
+	}
+	r.lastID.Store(lastID)
+	return nil
+}
+
+// cloneURL copies url together with the values its pointers and slices refer to
+func cloneURL(url *domain.URL) domain.URL {
+	cloned := *url
+	cloned.LastCheckedAt = clonePtr(url.LastCheckedAt)
+	cloned.LastAccessedAt = clonePtr(url.LastAccessedAt)
+	cloned.ExpiresAt = clonePtr(url.ExpiresAt)
+	cloned.ClickGoal = clonePtr(url.ClickGoal)
+	cloned.Tags = slices.Clone(url.Tags)
+	cloned.Variants = slices.Clone(url.Variants)
+	cloned.GeoRoutes = slices.Clone(url.GeoRoutes)
+	cloned.DeviceRoutes = slices.Clone(url.DeviceRoutes)
+	if url.Pool != nil {
+		cloned.Pool = &domain.URLPool{Targets: slices.Clone(url.Pool.Targets)}
+	}
+	return cloned
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
 func (r *URLRepository) Close() error {
 	return nil
 }
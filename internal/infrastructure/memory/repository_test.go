@@ -0,0 +1,613 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+func newTestRepository(t *testing.T) *URLRepository {
+	t.Helper()
+	return NewURLRepository(slog.New(slog.NewTextHandler(os.Stdout, nil)), 0)
+}
+
+func createURL(t *testing.T, repo *URLRepository, namespace, shortCode, originalURL string) *domain.URL {
+	t.Helper()
+
+	url, err := domain.NewURL(shortCode, originalURL)
+	require.NoError(t, err)
+	url.Namespace = namespace
+
+	created, err := repo.Create(context.Background(), url)
+	require.NoError(t, err)
+	return created
+}
+
+func TestURLRepository_SequentialIDs(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	first := createURL(t, repo, domain.DefaultNamespace, "first", "https://example.com/1")
+	second := createURL(t, repo, domain.DefaultNamespace, "second", "https://example.com/2")
+	assert.Equal(t, int64(1), first.ID)
+	assert.Equal(t, int64(2), second.ID)
+
+	// IDs are not reused once a URL is deleted
+	require.NoError(t, repo.Delete(ctx, domain.DefaultNamespace, "first"))
+	third := createURL(t, repo, domain.DefaultNamespace, "third", "https://example.com/3")
+	assert.Equal(t, int64(3), third.ID)
+
+	t.Run("unique under concurrent creates and deletes", func(t *testing.T) {
+		var wg sync.WaitGroup
+		ids := make(chan int64, 100)
+		for i := range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				url, _ := domain.NewURL(fmt.Sprintf("c%d", i), "https://example.com")
+				created, err := repo.Create(ctx, url)
+				if !assert.NoError(t, err) {
+					return
+				}
+				ids <- created.ID
+				if i%2 == 0 {
+					assert.NoError(t, repo.Delete(ctx, domain.DefaultNamespace, created.ShortCode))
+				}
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		seen := make(map[int64]bool)
+		for id := range ids {
+			assert.False(t, seen[id], "duplicate ID %d", id)
+			seen[id] = true
+		}
+		assert.Len(t, seen, 100)
+	})
+}
+
+func TestURLRepository_CreateAndFind(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	createURL(t, repo, domain.DefaultNamespace, "shared", "https://example.com/default")
+	createURL(t, repo, "acme", "shared", "https://example.com/acme")
+
+	url, err := domain.NewURL("shared", "https://example.com/again")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, url)
+	assert.ErrorIs(t, err, domain.ErrShortCodeExists)
+
+	found, err := repo.FindByShortCode(ctx, "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/default", found.OriginalURL)
+
+	found, err = repo.FindByNamespaceAndCode(ctx, "acme", "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/acme", found.OriginalURL)
+
+	_, err = repo.FindByNamespaceAndCode(ctx, "globex", "shared")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	exists, err := repo.Exists(ctx, "acme", "shared")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Returned URLs are copies
+	found.OriginalURL = "https://example.com/changed"
+	found, err = repo.FindByNamespaceAndCode(ctx, "acme", "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/acme", found.OriginalURL)
+}
+
+func TestURLRepository_FindByOriginalURL(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	oldest := createURL(t, repo, domain.DefaultNamespace, "oldest", "https://example.com/same")
+	createURL(t, repo, domain.DefaultNamespace, "newer", "https://example.com/same")
+	createURL(t, repo, "acme", "other", "https://example.com/acme")
+
+	found, err := repo.FindByOriginalURL(ctx, domain.DefaultNamespace, "https://example.com/same")
+	require.NoError(t, err)
+	assert.Equal(t, oldest.ID, found.ID)
+
+	_, err = repo.FindByOriginalURL(ctx, domain.DefaultNamespace, "https://example.com/acme")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestURLRepository_Update(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	created := createURL(t, repo, domain.DefaultNamespace, "edit", "https://example.com/before")
+	_, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, "edit", true)
+	require.NoError(t, err)
+
+	changes := *created
+	changes.ID = 99
+	changes.Clicks = 0
+	changes.OriginalURL = "https://example.com/after"
+	changes.DeviceRoutes = domain.DeviceRoutes{{DeviceType: "mobile", DestinationURL: "https://m.example.com"}}
+
+	updated, err := repo.Update(ctx, &changes)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, 1, updated.Clicks)
+	assert.Equal(t, "https://example.com/after", updated.OriginalURL)
+	assert.False(t, updated.UpdatedAt.Before(created.UpdatedAt))
+
+	found, err := repo.FindByShortCode(ctx, "edit")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/after", found.OriginalURL)
+	assert.Equal(t, changes.DeviceRoutes, found.DeviceRoutes)
+
+	missing, err := domain.NewURL("missing", "https://example.com")
+	require.NoError(t, err)
+	_, err = repo.Update(ctx, missing)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestURLRepository_Delete(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	createURL(t, repo, domain.DefaultNamespace, "gone", "https://example.com/gone")
+	require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "gone", ClickedAt: time.Now(), Referer: domain.DirectReferer}))
+
+	require.NoError(t, repo.Delete(ctx, domain.DefaultNamespace, "gone"))
+	assert.ErrorIs(t, repo.Delete(ctx, domain.DefaultNamespace, "gone"), domain.ErrURLNotFound)
+
+	_, err := repo.FindByShortCode(ctx, "gone")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// A URL created again under the same code starts without the old clicks
+	createURL(t, repo, domain.DefaultNamespace, "gone", "https://example.com/back")
+	referrers, err := repo.TopReferrers(ctx, domain.DefaultNamespace, "gone", 10)
+	require.NoError(t, err)
+	assert.Empty(t, referrers)
+}
+
+func TestURLRepository_Eviction(t *testing.T) {
+	repo := NewURLRepository(slog.New(slog.NewTextHandler(os.Stdout, nil)), 3)
+	ctx := context.Background()
+
+	for _, shortCode := range []string{"first", "second", "third"} {
+		createURL(t, repo, domain.DefaultNamespace, shortCode, "https://example.com/"+shortCode)
+	}
+	assert.Equal(t, domain.MemoryStats{Capacity: 3, Size: 3}, repo.MemoryStats())
+
+	// Looking up the oldest URL keeps it, the least recently accessed being second
+	_, err := repo.FindByShortCode(ctx, "first")
+	require.NoError(t, err)
+	require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "second", ClickedAt: time.Now(), Referer: domain.DirectReferer}))
+	createURL(t, repo, domain.DefaultNamespace, "fourth", "https://example.com/fourth")
+
+	_, err = repo.FindByShortCode(ctx, "second")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	for _, shortCode := range []string{"first", "third", "fourth"} {
+		_, err := repo.FindByShortCode(ctx, shortCode)
+		assert.NoError(t, err, shortCode)
+	}
+	assert.Equal(t, domain.MemoryStats{Capacity: 3, Size: 3, Evictions: 1}, repo.MemoryStats())
+
+	// The clicks of an evicted URL go with it. Creating it again evicts first, the least
+	// recently looked up.
+	createURL(t, repo, domain.DefaultNamespace, "second", "https://example.com/back")
+	referrers, err := repo.TopReferrers(ctx, domain.DefaultNamespace, "second", 10)
+	require.NoError(t, err)
+	assert.Empty(t, referrers)
+	_, err = repo.FindByShortCode(ctx, "first")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.Equal(t, domain.MemoryStats{Capacity: 3, Size: 3, Evictions: 2}, repo.MemoryStats())
+
+	t.Run("deleted URLs free their room", func(t *testing.T) {
+		require.NoError(t, repo.Delete(ctx, domain.DefaultNamespace, "fourth"))
+		createURL(t, repo, "team", "fourth", "https://example.com/team")
+		assert.Equal(t, domain.MemoryStats{Capacity: 3, Size: 3, Evictions: 2}, repo.MemoryStats())
+	})
+
+	t.Run("unbounded without a capacity", func(t *testing.T) {
+		repo := newTestRepository(t)
+		for i := range 100 {
+			createURL(t, repo, domain.DefaultNamespace, fmt.Sprintf("c%d", i), "https://example.com")
+		}
+		assert.Equal(t, domain.MemoryStats{Size: 100}, repo.MemoryStats())
+	})
+}
+
+func TestURLRepository_List(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for i := range 5 {
+		createURL(t, repo, domain.DefaultNamespace, fmt.Sprintf("list%d", i), "https://example.com")
+	}
+
+	page, err := repo.List(ctx, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, int64(1), page[0].ID)
+	assert.Equal(t, int64(2), page[1].ID)
+
+	page, err = repo.List(ctx, page[1].ID, 10)
+	require.NoError(t, err)
+	require.Len(t, page, 3)
+	assert.Equal(t, int64(3), page[0].ID)
+}
+
+func TestURLRepository_SnapshotAndLoad(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	seedExpiry, goal := expiresAt, 10
+	seed := []domain.URL{
+		{ID: 7, Namespace: domain.DefaultNamespace, ShortCode: "seventh", OriginalURL: "https://example.com/7", Clicks: 3, Enabled: true, Tags: domain.Tags{"docs"}, ExpiresAt: &seedExpiry, ClickGoal: &goal},
+		{ID: 2, Namespace: "team", ShortCode: "second", OriginalURL: "https://example.com/2", Enabled: true, Variants: []domain.URLVariant{{OriginalURL: "https://example.com/b", Weight: 1}}},
+		{Namespace: domain.DefaultNamespace, ShortCode: "unnumbered", OriginalURL: "https://example.com/new", Enabled: true},
+	}
+	require.NoError(t, repo.Load(seed))
+
+	snapshot := repo.Snapshot()
+	require.Len(t, snapshot, 3)
+	assert.Equal(t, []int64{2, 7, 8}, []int64{snapshot[0].ID, snapshot[1].ID, snapshot[2].ID}, "URLs without an ID follow the highest loaded one")
+	assert.Equal(t, seed[0].Tags, snapshot[1].Tags)
+	assert.Equal(t, 3, snapshot[1].Clicks)
+	require.Len(t, snapshot[0].Variants, 1)
+	assert.Equal(t, int64(2), snapshot[0].Variants[0].URLID)
+	assert.NotZero(t, snapshot[0].Variants[0].ID)
+
+	// Neither the seed nor the snapshot share memory with the store
+	seed[0].Tags[0] = "changed"
+	*seed[0].ExpiresAt = time.Time{}
+	snapshot[1].Tags[0] = "changed"
+	*snapshot[1].ClickGoal = 0
+	found, err := repo.FindByShortCode(ctx, "seventh")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Tags{"docs"}, found.Tags)
+	assert.True(t, expiresAt.Equal(*found.ExpiresAt))
+	assert.Equal(t, 10, *found.ClickGoal)
+
+	created := createURL(t, repo, domain.DefaultNamespace, "created", "https://example.com/created")
+	assert.Equal(t, int64(9), created.ID, "loaded IDs are not reused")
+
+	t.Run("restores a snapshot", func(t *testing.T) {
+		restored := newTestRepository(t)
+		require.NoError(t, restored.Load(repo.Snapshot()))
+		assert.Equal(t, repo.Snapshot(), restored.Snapshot())
+	})
+
+	t.Run("loads nothing on conflicts", func(t *testing.T) {
+		err := repo.Load([]domain.URL{{ShortCode: "fresh", OriginalURL: "https://example.com"}, {Namespace: "team", ShortCode: "second", OriginalURL: "https://example.com"}})
+		assert.ErrorIs(t, err, domain.ErrShortCodeExists)
+		assert.Error(t, repo.Load([]domain.URL{{ID: 7, ShortCode: "fresh", OriginalURL: "https://example.com"}}))
+		assert.ErrorIs(t, repo.Load([]domain.URL{{ShortCode: "twice"}, {ShortCode: "twice"}}), domain.ErrShortCodeExists)
+		assert.Len(t, repo.Snapshot(), 4)
+	})
+
+	t.Run("within the capacity", func(t *testing.T) {
+		bounded := NewURLRepository(slog.New(slog.NewTextHandler(os.Stdout, nil)), 2)
+		assert.Error(t, bounded.Load(repo.Snapshot()))
+		assert.Empty(t, bounded.Snapshot())
+		require.NoError(t, bounded.Load(repo.Snapshot()[:2]))
+		assert.Equal(t, domain.MemoryStats{Capacity: 2, Size: 2}, bounded.MemoryStats())
+	})
+}
+
+func TestURLRepository_SnapshotConcurrent(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	createURL(t, repo, domain.DefaultNamespace, "hot", "https://example.com/hot")
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			createURL(t, repo, domain.DefaultNamespace, fmt.Sprintf("c%d", i), "https://example.com")
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, "hot", true)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			for _, url := range repo.Snapshot() {
+				// Reading what the snapshot points to must not race with increments
+				_ = url.LastAccessedAt != nil && url.LastAccessedAt.IsZero()
+			}
+		}()
+	}
+	wg.Wait()
+
+	snapshot := repo.Snapshot()
+	assert.Len(t, snapshot, 51)
+	assert.Equal(t, 50, snapshot[0].Clicks)
+}
+
+func TestURLRepository_Search(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	createURL(t, repo, domain.DefaultNamespace, "golang", "https://go.dev")
+	createURL(t, repo, domain.DefaultNamespace, "docs", "https://example.com/GoLang/docs")
+	rust := createURL(t, repo, domain.DefaultNamespace, "rust", "https://rust-lang.org")
+	rust.Description = "Systems language homepage"
+	_, err := repo.Update(ctx, rust)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		query    string
+		limit    int
+		expected []string
+	}{
+		{name: "short code", query: "rust", limit: 10, expected: []string{"rust"}},
+		{name: "original URL ignoring case", query: "golang", limit: 10, expected: []string{"golang", "docs"}},
+		{name: "limit", query: "golang", limit: 1, expected: []string{"golang"}},
+		{name: "description ignoring case", query: "SYSTEMS", limit: 10, expected: []string{"rust"}},
+		{name: "no match", query: "python", limit: 10, expected: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := repo.Search(ctx, tt.query, tt.limit)
+			require.NoError(t, err)
+
+			codes := make([]string, 0, len(urls))
+			for _, url := range urls {
+				codes = append(codes, url.ShortCode)
+			}
+			assert.Equal(t, tt.expected, codes)
+		})
+	}
+}
+
+func TestURLRepository_FindExpired(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i, expiresIn := range []time.Duration{-time.Hour, time.Hour, -time.Minute, -time.Second} {
+		url := createURL(t, repo, domain.DefaultNamespace, fmt.Sprintf("exp%d", i), "https://example.com")
+		expiresAt := now.Add(expiresIn)
+		url.ExpiresAt = &expiresAt
+		_, err := repo.Update(ctx, url)
+		require.NoError(t, err)
+	}
+	createURL(t, repo, domain.DefaultNamespace, "permanent", "https://example.com")
+
+	expired, err := repo.FindExpired(ctx, now, 2)
+	require.NoError(t, err)
+	require.Len(t, expired, 2)
+	// Oldest first, so repeated batches make progress
+	assert.Equal(t, "exp0", expired[0].ShortCode)
+	assert.Equal(t, "exp2", expired[1].ShortCode)
+
+	expired, err = repo.FindExpired(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Len(t, expired, 3)
+}
+
+func TestURLRepository_TopByClicks(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for i, clicks := range []int{1, 3, 3, 0} {
+		code := fmt.Sprintf("top%d", i)
+		createURL(t, repo, domain.DefaultNamespace, code, "https://example.com")
+		for range clicks {
+			_, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, code, true)
+			require.NoError(t, err)
+		}
+	}
+
+	// URLs that may not be listed are left out however often clicked
+	for i, hide := range []func(*domain.URL){
+		func(url *domain.URL) { url.Signed = true },
+		func(url *domain.URL) { url.PasswordHash = "hash" },
+		func(url *domain.URL) { url.Enabled = false },
+	} {
+		url, err := domain.NewURL(fmt.Sprintf("hidden%d", i), "https://example.com/hidden")
+		require.NoError(t, err)
+		hide(url)
+		url.Clicks = 10
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	top, err := repo.TopByClicks(ctx, 3)
+	require.NoError(t, err)
+	require.Len(t, top, 3)
+	// Ties are broken by ID
+	assert.Equal(t, "top1", top[0].ShortCode)
+	assert.Equal(t, "top2", top[1].ShortCode)
+	assert.Equal(t, "top0", top[2].ShortCode)
+}
+
+func TestURLRepository_Stats(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	createURL(t, repo, domain.DefaultNamespace, "fresh", "https://example.com/fresh")
+
+	old, err := domain.NewURL("old", "https://example.com/old")
+	require.NoError(t, err)
+	old.CreatedAt = now.Add(-48 * time.Hour)
+	expired := now.Add(-time.Hour)
+	old.ExpiresAt = &expired
+	_, err = repo.Create(ctx, old)
+	require.NoError(t, err)
+
+	createURL(t, repo, "team", "fresh", "https://example.com/team")
+
+	for range 2 {
+		_, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, "fresh", true)
+		require.NoError(t, err)
+	}
+	for _, clickedAt := range []time.Time{now.Add(-time.Minute), now.Add(-25 * time.Hour)} {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: "team", ShortCode: "fresh", ClickedAt: clickedAt}))
+	}
+
+	stats, err := repo.Stats(ctx, now, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, &domain.ServiceStats{
+		TotalURLs:    3,
+		TotalClicks:  2,
+		ActiveURLs:   2,
+		RecentURLs:   2,
+		RecentClicks: 1,
+	}, stats)
+}
+
+func TestURLRepository_RedirectLatency(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	createURL(t, repo, domain.DefaultNamespace, "latency", "https://example.com/latency")
+	for _, ms := range []float64{40, 10, 30, 20} {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "latency", ClickedAt: time.Now(), RedirectDurationMs: &ms}))
+	}
+	require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "latency", ClickedAt: time.Now()}))
+
+	stats, err := repo.RedirectLatency(ctx, domain.DefaultNamespace, "latency")
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Count)
+	assert.InDelta(t, 25, stats.P50, 1e-9)
+	assert.InDelta(t, 38.5, stats.P95, 1e-9)
+	assert.InDelta(t, 39.7, stats.P99, 1e-9)
+	assert.InDelta(t, 25, stats.Avg, 1e-9)
+
+	t.Run("single duration", func(t *testing.T) {
+		createURL(t, repo, domain.DefaultNamespace, "once", "https://example.com/once")
+		ms := 7.5
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "once", ClickedAt: time.Now(), RedirectDurationMs: &ms}))
+
+		stats, err := repo.RedirectLatency(ctx, domain.DefaultNamespace, "once")
+		require.NoError(t, err)
+		assert.Equal(t, &domain.LatencyStats{P50: 7.5, P95: 7.5, P99: 7.5, Avg: 7.5, Count: 1}, stats)
+	})
+
+	t.Run("no durations", func(t *testing.T) {
+		stats, err := repo.RedirectLatency(ctx, domain.DefaultNamespace, "unknown")
+		require.NoError(t, err)
+		assert.Equal(t, &domain.LatencyStats{}, stats)
+	})
+}
+
+func TestURLRepository_ClickHeatmap(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	createURL(t, repo, domain.DefaultNamespace, "heatmap", "https://example.com/heatmap")
+
+	// Wednesday 2024-01-03 twice, Sunday 2024-01-07 and Monday 2024-07-01, in daylight saving time in New York
+	for _, clickedAt := range []time.Time{
+		time.Date(2024, 1, 3, 10, 15, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 10, 45, 0, 0, time.UTC),
+		time.Date(2024, 1, 7, 2, 30, 0, 0, time.UTC),
+		time.Date(2024, 7, 1, 3, 0, 0, 0, time.UTC),
+	} {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "heatmap", ClickedAt: clickedAt}))
+	}
+
+	tests := []struct {
+		tz    string
+		hours map[int]int
+		days  map[time.Weekday]int
+	}{
+		{"UTC", map[int]int{10: 2, 2: 1, 3: 1}, map[time.Weekday]int{time.Wednesday: 2, time.Sunday: 1, time.Monday: 1}},
+		{"America/New_York", map[int]int{5: 2, 21: 1, 23: 1}, map[time.Weekday]int{time.Wednesday: 2, time.Saturday: 1, time.Sunday: 1}},
+		{"Asia/Kolkata", map[int]int{15: 1, 16: 1, 8: 2}, map[time.Weekday]int{time.Wednesday: 2, time.Sunday: 1, time.Monday: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tz, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.tz)
+			require.NoError(t, err)
+
+			var expected domain.ClickHeatmap
+			for hour, clicks := range tt.hours {
+				expected.HourOfDay[hour] = clicks
+			}
+			for day, clicks := range tt.days {
+				expected.DayOfWeek[day] = clicks
+			}
+
+			heatmap, err := repo.ClickHeatmap(ctx, domain.DefaultNamespace, "heatmap", loc)
+			require.NoError(t, err)
+			assert.Equal(t, &expected, heatmap)
+		})
+	}
+
+	t.Run("no clicks", func(t *testing.T) {
+		heatmap, err := repo.ClickHeatmap(ctx, domain.DefaultNamespace, "unknown", time.UTC)
+		require.NoError(t, err)
+		assert.Equal(t, &domain.ClickHeatmap{}, heatmap)
+	})
+}
+
+func TestURLRepository_ResetClicks(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	createURL(t, repo, domain.DefaultNamespace, "reset", "https://example.com/reset")
+	createURL(t, repo, domain.DefaultNamespace, "kept", "https://example.com/kept")
+	for _, shortCode := range []string{"reset", "kept"} {
+		_, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, shortCode, true)
+		require.NoError(t, err)
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: shortCode, ClickedAt: time.Now(), Referer: "example.org"}))
+	}
+
+	require.NoError(t, repo.ResetClicks(ctx, domain.DefaultNamespace, "reset"))
+
+	for shortCode, clicks := range map[string]int{"reset": 0, "kept": 1} {
+		url, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+		require.NoError(t, err)
+		assert.Equal(t, clicks, url.Clicks, shortCode)
+		assert.Equal(t, clicks, url.UniqueClicks, shortCode)
+
+		referrers, err := repo.TopReferrers(ctx, domain.DefaultNamespace, shortCode, 10)
+		require.NoError(t, err)
+		assert.Len(t, referrers, clicks, shortCode)
+	}
+
+	assert.ErrorIs(t, repo.ResetClicks(ctx, domain.DefaultNamespace, "missing"), domain.ErrURLNotFound)
+}
+
+func TestURLRepository_HealthStatus(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	createURL(t, repo, domain.DefaultNamespace, "checked", "https://example.com/checked")
+	createURL(t, repo, domain.DefaultNamespace, "unchecked", "https://example.com/unchecked")
+
+	checkedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.UpdateHealthStatus(ctx, domain.DefaultNamespace, "checked", domain.HealthStatusDead, checkedAt))
+	assert.ErrorIs(t, repo.UpdateHealthStatus(ctx, domain.DefaultNamespace, "missing", domain.HealthStatusDead, checkedAt), domain.ErrURLNotFound)
+
+	found, err := repo.FindByShortCode(ctx, "checked")
+	require.NoError(t, err)
+	assert.Equal(t, domain.HealthStatusDead, found.HealthStatus)
+	require.NotNil(t, found.LastCheckedAt)
+	assert.True(t, checkedAt.Equal(*found.LastCheckedAt))
+
+	// Never checked URLs come first
+	batch, err := repo.ListForHealthCheck(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "unchecked", batch[0].ShortCode)
+	assert.Equal(t, "checked", batch[1].ShortCode)
+}
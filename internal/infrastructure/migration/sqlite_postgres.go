@@ -0,0 +1,217 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// progressInterval is how many rows of a table are copied between progress reports
+const progressInterval = 1000
+
+// Progress is told how many rows of table have been copied so far, every progressInterval
+// rows and once the table is done
+type Progress func(table string, rows int)
+
+// RowsReport counts the rows of a table copied into the target, and those it refused
+type RowsReport struct {
+	Copied int
+	Failed int
+}
+
+// DatabaseReport is the outcome of MigrateSQLiteToPostgres
+type DatabaseReport struct {
+	URLs     domain.StoreMigrationReport
+	Variants RowsReport
+	Clicks   RowsReport
+}
+
+// MigrateSQLiteToPostgres copies the URLs of src, the repository of the SQLite database
+// srcDB, into dst, the repository of the PostgreSQL database dstDB, with MigrateStore.
+// The variants and recorded clicks of the URLs it creates are then copied table by table;
+// those of URLs dst already held are left alone, so an interrupted migration can be run
+// again. Rows violating a constraint of the target are counted, logged and skipped.
+func MigrateSQLiteToPostgres(ctx context.Context, src, dst domain.URLRepository, srcDB, dstDB *sqlx.DB, progress Progress, logger *slog.Logger) (DatabaseReport, error) {
+	if progress == nil {
+		progress = func(string, int) {}
+	}
+
+	var report DatabaseReport
+	recorder := &recordingRepository{
+		URLRepository: dst,
+		ids:           make(map[int64]int64),
+		keys:          make(map[urlKey]bool),
+		progress:      progress,
+	}
+
+	urls, err := MigrateStore(ctx, src, recorder, logger)
+	report.URLs = urls
+	if err != nil {
+		return report, err
+	}
+	progress("urls", recorder.rows)
+
+	variantIDs, variants, err := copyVariants(ctx, srcDB, dstDB, recorder.ids, progress, logger)
+	report.Variants = variants
+	if err != nil {
+		return report, err
+	}
+
+	clicks, err := copyClicks(ctx, srcDB, dstDB, recorder.keys, variantIDs, progress, logger)
+	report.Clicks = clicks
+	return report, err
+}
+
+type urlKey struct {
+	namespace string
+	shortCode string
+}
+
+// recordingRepository notes the URLs MigrateStore creates in the wrapped repository
+type recordingRepository struct {
+	domain.URLRepository
+	ids      map[int64]int64 // source ID of the URLs created to their ID in the target
+	keys     map[urlKey]bool
+	rows     int
+	progress Progress
+}
+
+func (r *recordingRepository) Create(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	sourceID := url.ID
+	created, err := r.URLRepository.Create(ctx, url)
+
+	r.rows++
+	if r.rows%progressInterval == 0 {
+		r.progress("urls", r.rows)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.ids[sourceID] = created.ID
+	r.keys[urlKey{created.Namespace, created.ShortCode}] = true
+	return created, nil
+}
+
+// copyVariants copies the variants of the URLs in urlIDs, returning the IDs they got in
+// the target by their source ID
+func copyVariants(ctx context.Context, srcDB, dstDB *sqlx.DB, urlIDs map[int64]int64, progress Progress, logger *slog.Logger) (map[int64]int64, RowsReport, error) {
+	var report RowsReport
+	variantIDs := make(map[int64]int64)
+
+	rows, err := srcDB.QueryxContext(ctx, `SELECT id, url_id, original_url, weight FROM url_variants ORDER BY id ASC`)
+	if err != nil {
+		return nil, report, fmt.Errorf("failed to read source variants: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var variant domain.URLVariant
+		if err := rows.StructScan(&variant); err != nil {
+			return nil, report, fmt.Errorf("failed to read source variant: %w", err)
+		}
+		urlID, ok := urlIDs[variant.URLID]
+		if !ok {
+			continue
+		}
+
+		var id int64
+		err := dstDB.QueryRowxContext(ctx, `INSERT INTO url_variants (url_id, original_url, weight) VALUES ($1, $2, $3) RETURNING id`,
+			urlID, variant.OriginalURL, variant.Weight).Scan(&id)
+		if err != nil {
+			if !isConstraintViolation(err) {
+				return nil, report, fmt.Errorf("failed to copy variant %d: %w", variant.ID, err)
+			}
+			report.Failed++
+			logger.Warn("Skipped variant violating a constraint", "id", variant.ID, "url_id", variant.URLID, "error", err)
+			continue
+		}
+
+		variantIDs[variant.ID] = id
+		report.Copied++
+		if report.Copied%progressInterval == 0 {
+			progress("url_variants", report.Copied)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, report, fmt.Errorf("failed to read source variants: %w", err)
+	}
+
+	progress("url_variants", report.Copied)
+	return variantIDs, report, nil
+}
+
+// copyClicks copies the recorded clicks of the URLs in urls, pointing them at the variants
+// with the IDs of the target. Clicks of variants since removed keep no variant.
+func copyClicks(ctx context.Context, srcDB, dstDB *sqlx.DB, urls map[urlKey]bool, variantIDs map[int64]int64, progress Progress, logger *slog.Logger) (RowsReport, error) {
+	var report RowsReport
+
+	rows, err := srcDB.QueryxContext(ctx, `
+		SELECT id, namespace, short_code, clicked_at, referer, ua_browser, ua_os, ua_device_type, variant_id, target_url, redirect_duration_ms, ip_address, user_agent, country
+		FROM url_clicks ORDER BY id ASC`)
+	if err != nil {
+		return report, fmt.Errorf("failed to read source clicks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	query := `
+		INSERT INTO url_clicks (namespace, short_code, clicked_at, referer, ua_browser, ua_os, ua_device_type, variant_id, target_url, redirect_duration_ms, ip_address, user_agent, country)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, '')::inet, $12, $13)`
+
+	for rows.Next() {
+		var click domain.Click
+		if err := rows.StructScan(&click); err != nil {
+			return report, fmt.Errorf("failed to read source click: %w", err)
+		}
+		if !urls[urlKey{click.Namespace, click.ShortCode}] {
+			continue
+		}
+
+		var variantID *int64
+		if click.VariantID != nil {
+			if id, ok := variantIDs[*click.VariantID]; ok {
+				variantID = &id
+			}
+		}
+
+		_, err := dstDB.ExecContext(ctx, query, click.Namespace, click.ShortCode, click.ClickedAt, click.Referer, click.Browser, click.OS, click.DeviceType,
+			variantID, click.TargetURL, click.RedirectDurationMs, click.IPAddress, click.UserAgent, click.Country)
+		if err != nil {
+			if !isConstraintViolation(err) {
+				return report, fmt.Errorf("failed to copy click %d: %w", click.ID, err)
+			}
+			report.Failed++
+			logger.Warn("Skipped click violating a constraint", "id", click.ID, "namespace", click.Namespace, "short_code", click.ShortCode, "error", err)
+			continue
+		}
+
+		report.Copied++
+		if report.Copied%progressInterval == 0 {
+			progress("url_clicks", report.Copied)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("failed to read source clicks: %w", err)
+	}
+
+	progress("url_clicks", report.Copied)
+	return report, nil
+}
+
+// isConstraintViolation reports whether PostgreSQL refused a row for breaking a constraint,
+// including a value of the wrong format such as an IP address it cannot parse
+func isConstraintViolation(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	// Class 23 is integrity constraint violation, 22 is data exception
+	class := pqErr.Code.Class()
+	return class == "23" || class == "22"
+}
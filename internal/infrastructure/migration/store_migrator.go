@@ -0,0 +1,113 @@
+// Package migration copies short URLs from one repository into another, such as when
+// moving off the in-memory store. Schema migrations are in package migrations.
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// pageSize is how many URLs are read from the source at a time
+const pageSize = 100
+
+// MigrateStore creates every URL of src in dst, in ID order. URLs whose short code dst
+// already holds are skipped and URLs dst fails to create are counted and logged; neither
+// stops the migration. Click counters carry over, recorded clicks do not.
+func MigrateStore(ctx context.Context, src, dst domain.URLRepository, logger *slog.Logger) (domain.StoreMigrationReport, error) {
+	var report domain.StoreMigrationReport
+
+	var afterID int64
+	for {
+		urls, err := src.List(ctx, afterID, pageSize)
+		if err != nil {
+			return report, fmt.Errorf("failed to list source URLs: %w", err)
+		}
+
+		for _, url := range urls {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+
+			copied := *url
+			_, err := dst.Create(ctx, &copied)
+			switch {
+			case err == nil:
+				report.Migrated++
+			case errors.Is(err, domain.ErrShortCodeExists):
+				report.Skipped++
+			default:
+				report.Failed++
+				logger.Warn("Failed to migrate URL", "namespace", url.Namespace, "short_code", url.ShortCode, "error", err)
+			}
+		}
+
+		if len(urls) < pageSize {
+			return report, nil
+		}
+		afterID = urls[len(urls)-1].ID
+	}
+}
+
+// Opener opens a repository of the given database type, which the caller closes
+type Opener func(storeType string) (domain.URLRepository, error)
+
+// StoreMigrator migrates the running repository into stores it opens, one migration at a
+// time
+type StoreMigrator struct {
+	mu          sync.Mutex
+	current     domain.URLRepository
+	currentType string
+	open        Opener
+	logger      *slog.Logger
+}
+
+// NewStoreMigrator creates a migrator of current, the repository of type currentType,
+// opening target stores with open
+func NewStoreMigrator(current domain.URLRepository, currentType string, open Opener, logger *slog.Logger) *StoreMigrator {
+	return &StoreMigrator{current: current, currentType: currentType, open: open, logger: logger}
+}
+
+// migrationTargets are the store types URLs can be migrated to; an in-memory target would
+// be lost with the process
+var migrationTargets = map[string]bool{"sqlite": true, "postgres": true}
+
+// MigrateStore copies the URLs of the running repository, which must be of type from, into
+// a newly opened store of type to. It fails with domain.ErrStoreMigrationRunning while
+// another migration runs. The service keeps serving from the running repository.
+func (m *StoreMigrator) MigrateStore(ctx context.Context, from, to string) (*domain.StoreMigrationReport, error) {
+	if from != m.currentType {
+		return nil, fmt.Errorf("%w: the running store is %s, not %s", domain.ErrUnsupportedStore, m.currentType, from)
+	}
+	if !migrationTargets[to] || to == from {
+		return nil, fmt.Errorf("%w: cannot migrate %s to %s", domain.ErrUnsupportedStore, from, to)
+	}
+
+	if !m.mu.TryLock() {
+		return nil, domain.ErrStoreMigrationRunning
+	}
+	defer m.mu.Unlock()
+
+	dst, err := m.open(to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s store: %w", to, err)
+	}
+	defer func() {
+		if err := dst.Close(); err != nil {
+			m.logger.Warn("Failed to close migration target", "store", to, "error", err)
+		}
+	}()
+
+	m.logger.Info("Store migration started", "from", from, "to", to)
+	report, err := MigrateStore(ctx, m.current, dst, m.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Store migration finished", "from", from, "to", to, "migrated", report.Migrated, "skipped", report.Skipped, "failed", report.Failed)
+	return &report, nil
+}
@@ -0,0 +1,139 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/infrastructure/memory"
+)
+
+// flakyRepository fails to create the URLs under one short code
+type flakyRepository struct {
+	domain.URLRepository
+	failing string
+}
+
+func (r *flakyRepository) Create(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	if url.ShortCode == r.failing {
+		return nil, errors.New("disk full")
+	}
+	return r.URLRepository.Create(ctx, url)
+}
+
+// blockingRepository holds every Create until release is closed
+type blockingRepository struct {
+	domain.URLRepository
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRepository) Create(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	select {
+	case r.started <- struct{}{}:
+	default:
+	}
+	<-r.release
+	return r.URLRepository.Create(ctx, url)
+}
+
+func newSource(t *testing.T, count int) *memory.URLRepository {
+	t.Helper()
+
+	urls := make([]domain.URL, count)
+	for i := range count {
+		url, err := domain.NewURL(fmt.Sprintf("code%d", i), fmt.Sprintf("https://example.com/%d", i))
+		require.NoError(t, err)
+		url.Clicks = i
+		urls[i] = *url
+	}
+	src := memory.NewURLRepository(slog.New(slog.NewTextHandler(io.Discard, nil)), 0)
+	require.NoError(t, src.Load(urls))
+	return src
+}
+
+func TestMigrateStore(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	src := newSource(t, 250)
+
+	dst := memory.NewURLRepository(logger, 0)
+	taken, err := domain.NewURL("code7", "https://example.com/taken")
+	require.NoError(t, err)
+	_, err = dst.Create(ctx, taken)
+	require.NoError(t, err)
+
+	report, err := MigrateStore(ctx, src, &flakyRepository{URLRepository: dst, failing: "code42"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StoreMigrationReport{Migrated: 248, Skipped: 1, Failed: 1}, report)
+
+	migrated := dst.Snapshot()
+	require.Len(t, migrated, 249)
+	assert.Equal(t, "code7", migrated[0].ShortCode)
+	assert.Equal(t, "https://example.com/taken", migrated[0].OriginalURL, "URLs already in the target are kept")
+	for i, url := range migrated[1:] {
+		want := i
+		if i >= 7 {
+			want++ // code7 was skipped
+		}
+		if want >= 42 {
+			want++ // code42 failed
+		}
+		assert.Equal(t, fmt.Sprintf("code%d", want), url.ShortCode)
+		assert.Equal(t, fmt.Sprintf("https://example.com/%d", want), url.OriginalURL)
+		assert.Equal(t, want, url.Clicks, "click counters carry over")
+	}
+}
+
+func TestStoreMigrator_MigrateStore(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	src := newSource(t, 3)
+
+	t.Run("unsupported stores", func(t *testing.T) {
+		migrator := NewStoreMigrator(src, "memory", func(string) (domain.URLRepository, error) {
+			t.Fatal("no store is opened")
+			return nil, nil
+		}, logger)
+
+		for _, stores := range [][2]string{{"sqlite", "postgres"}, {"memory", "memory"}, {"memory", "redis"}} {
+			_, err := migrator.MigrateStore(ctx, stores[0], stores[1])
+			assert.ErrorIs(t, err, domain.ErrUnsupportedStore, stores)
+		}
+	})
+
+	t.Run("one migration at a time", func(t *testing.T) {
+		dst := &blockingRepository{
+			URLRepository: memory.NewURLRepository(logger, 0),
+			started:       make(chan struct{}, 1),
+			release:       make(chan struct{}),
+		}
+		var opened []string
+		migrator := NewStoreMigrator(src, "memory", func(storeType string) (domain.URLRepository, error) {
+			opened = append(opened, storeType)
+			return dst, nil
+		}, logger)
+
+		done := make(chan *domain.StoreMigrationReport)
+		go func() {
+			report, err := migrator.MigrateStore(ctx, "memory", "postgres")
+			assert.NoError(t, err)
+			done <- report
+		}()
+		<-dst.started
+
+		_, err := migrator.MigrateStore(ctx, "memory", "postgres")
+		assert.ErrorIs(t, err, domain.ErrStoreMigrationRunning)
+
+		close(dst.release)
+		assert.Equal(t, &domain.StoreMigrationReport{Migrated: 3}, <-done)
+		assert.Equal(t, []string{"postgres"}, opened)
+	})
+}
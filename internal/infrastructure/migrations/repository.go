@@ -0,0 +1,171 @@
+// Package migrations runs the SQL migrations of the postgres and sqlite repositories with
+// golang-migrate, which tracks the current version in the schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/file"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// Repository implements domain.MigrationRepository for the migration files in dir
+type Repository struct {
+	db         *sql.DB
+	driverName string // postgres or sqlite3
+	dir        string
+	// mu keeps the migrations of this process from overlapping, SQLite has no database lock
+	mu sync.Mutex
+}
+
+// NewRepository creates a repository migrating db, opened with driverName, with the files in dir
+func NewRepository(db *sql.DB, driverName, dir string) *Repository {
+	return &Repository{
+		db:         db,
+		driverName: driverName,
+		dir:        dir,
+	}
+}
+
+// List merges the migration files with the version recorded in the database
+func (r *Repository) List(_ context.Context) ([]domain.MigrationStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, src, closeFn, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	current, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	applied := err == nil
+
+	statuses := []domain.MigrationStatus{}
+	version, err := src.First()
+	for err == nil {
+		body, name, readErr := src.ReadUp(version)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read migration %d: %w", version, readErr)
+		}
+		_ = body.Close()
+
+		statuses = append(statuses, domain.MigrationStatus{
+			Version: version,
+			Name:    name,
+			Applied: applied && version <= current,
+			Dirty:   dirty && version == current,
+		})
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	return statuses, nil
+}
+
+func (r *Repository) Up(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, _, closeFn, err := r.open()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	return r.check(m.Up())
+}
+
+func (r *Repository) Down(_ context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, _, closeFn, err := r.open()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	err = m.Steps(-steps)
+	var short migrate.ErrShortLimit
+	if errors.As(err, &short) {
+		// Fewer migrations than steps were applied, all of them were rolled back
+		return nil
+	}
+	return r.check(err)
+}
+
+// check maps the outcome of a migration run onto the repository's errors
+func (r *Repository) check(err error) error {
+	var dirty migrate.ErrDirty
+	switch {
+	case err == nil, errors.Is(err, migrate.ErrNoChange):
+		return nil
+	case errors.As(err, &dirty):
+		return fmt.Errorf("%w at version %d", domain.ErrMigrationDirty, dirty.Version)
+	default:
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+}
+
+// open prepares a migration run. The returned function releases what the run holds
+// without closing db, which the application keeps using.
+func (r *Repository) open() (*migrate.Migrate, source.Driver, func(), error) {
+	src, err := (&file.File{}).Open("file://" + r.dir)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open migrations: %w", err)
+	}
+
+	var driver database.Driver
+	closeDriver := false
+	switch r.driverName {
+	case "postgres":
+		// Holds a dedicated connection, closing the driver returns it to the pool
+		driver, err = postgres.WithInstance(r.db, &postgres.Config{})
+		closeDriver = true
+	case "sqlite3":
+		// Closing this driver would close db itself
+		driver, err = sqlite3.WithInstance(r.db, &sqlite3.Config{})
+	default:
+		err = fmt.Errorf("unsupported driver: %s", r.driverName)
+	}
+	if err != nil {
+		_ = src.Close()
+		return nil, nil, nil, fmt.Errorf("failed to create migration driver: %w", err)
+	}
+
+	closeFn := func() {
+		_ = src.Close()
+		if closeDriver {
+			_ = driver.Close()
+		}
+	}
+
+	m, err := migrate.NewWithInstance("file", src, r.driverName, driver)
+	if err != nil {
+		closeFn()
+		return nil, nil, nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+
+	return m, src, closeFn, nil
+}
@@ -0,0 +1,108 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+const sqliteMigrations = "../../../migrations/sqlite"
+
+func newTestRepository(t *testing.T) (*Repository, *sql.DB) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "dove.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return NewRepository(db, "sqlite3", sqliteMigrations), db
+}
+
+func appliedVersions(statuses []domain.MigrationStatus) []uint {
+	versions := []uint{}
+	for _, status := range statuses {
+		if status.Applied {
+			versions = append(versions, status.Version)
+		}
+	}
+	return versions
+}
+
+func TestRepository_UpDown(t *testing.T) {
+	ctx := context.Background()
+	repo, db := newTestRepository(t)
+
+	statuses, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+	assert.Empty(t, appliedVersions(statuses), "nothing is applied to a new database")
+	assert.Equal(t, uint(1), statuses[0].Version)
+	assert.NotEmpty(t, statuses[0].Name)
+	latest := statuses[len(statuses)-1].Version
+
+	require.NoError(t, repo.Up(ctx))
+	statuses, err = repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, appliedVersions(statuses), len(statuses))
+
+	t.Run("up without pending migrations is a no-op", func(t *testing.T) {
+		assert.NoError(t, repo.Up(ctx))
+	})
+
+	t.Run("down rolls back the latest migrations", func(t *testing.T) {
+		require.NoError(t, repo.Down(ctx, 2))
+		statuses, err := repo.List(ctx)
+		require.NoError(t, err)
+		applied := appliedVersions(statuses)
+		require.NotEmpty(t, applied)
+		assert.Equal(t, latest-2, applied[len(applied)-1])
+	})
+
+	t.Run("up reapplies them", func(t *testing.T) {
+		require.NoError(t, repo.Up(ctx))
+		statuses, err := repo.List(ctx)
+		require.NoError(t, err)
+		assert.Len(t, appliedVersions(statuses), len(statuses))
+	})
+
+	t.Run("down past the first migration empties the schema", func(t *testing.T) {
+		require.NoError(t, repo.Down(ctx, len(statuses)+5))
+		statuses, err := repo.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, appliedVersions(statuses))
+	})
+
+	t.Run("invalid steps are rejected", func(t *testing.T) {
+		assert.Error(t, repo.Down(ctx, 0))
+	})
+
+	t.Run("the database stays open", func(t *testing.T) {
+		assert.NoError(t, db.Ping())
+	})
+}
+
+func TestRepository_Dirty(t *testing.T) {
+	ctx := context.Background()
+	repo, db := newTestRepository(t)
+
+	require.NoError(t, repo.Up(ctx))
+	_, err := db.Exec("UPDATE schema_migrations SET dirty = 1")
+	require.NoError(t, err)
+
+	statuses, err := repo.List(ctx)
+	require.NoError(t, err)
+	last := statuses[len(statuses)-1]
+	assert.True(t, last.Dirty)
+	assert.True(t, last.Applied)
+	assert.False(t, statuses[0].Dirty)
+
+	assert.ErrorIs(t, repo.Up(ctx), domain.ErrMigrationDirty)
+	assert.ErrorIs(t, repo.Down(ctx, 1), domain.ErrMigrationDirty)
+}
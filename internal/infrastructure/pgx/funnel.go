@@ -0,0 +1,91 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// CreateFunnel inserts the funnel and its steps in one transaction
+func (r *URLRepository) CreateFunnel(ctx context.Context, funnel *domain.Funnel) (*domain.Funnel, error) {
+	tx, err := r.writePool.Begin(ctx)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "begin create funnel")
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	result := domain.Funnel{Name: funnel.Name}
+	query := `INSERT INTO funnels (name, created_at) VALUES ($1, $2) RETURNING id, created_at`
+	if err := tx.QueryRow(ctx, query, funnel.Name, funnel.CreatedAt).Scan(&result.ID, &result.CreatedAt); err != nil {
+		return nil, r.handlePostgreSQLError(err, "create funnel")
+	}
+
+	stepQuery := `INSERT INTO funnel_steps (funnel_id, step_order, namespace, short_code) VALUES ($1, $2, $3, $4)`
+	for _, step := range funnel.Steps {
+		if _, err := tx.Exec(ctx, stepQuery, result.ID, step.Order, step.Namespace, step.ShortCode); err != nil {
+			return nil, r.handlePostgreSQLError(err, "create funnel step")
+		}
+		result.Steps = append(result.Steps, step)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, r.handlePostgreSQLError(err, "commit create funnel")
+	}
+
+	r.logger.Debug("Funnel created", "id", result.ID, "steps", len(result.Steps))
+	return &result, nil
+}
+
+func (r *URLRepository) FindFunnel(ctx context.Context, id int64) (*domain.Funnel, error) {
+	funnel, err := queryOne[domain.Funnel](ctx, r.readPool, `SELECT id, name, created_at FROM funnels WHERE id = $1`, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrFunnelNotFound
+	}
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "find funnel")
+	}
+
+	query := `SELECT step_order, namespace, short_code FROM funnel_steps WHERE funnel_id = $1 ORDER BY step_order`
+	funnel.Steps, err = queryAll[domain.FunnelStep](ctx, r.readPool, query, id)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "find funnel steps")
+	}
+
+	return funnel, nil
+}
+
+// FindFunnelSteps runs on every redirect, so it is served by readPool like the URL lookup itself
+func (r *URLRepository) FindFunnelSteps(ctx context.Context, namespace, shortCode string) ([]domain.FunnelStepRef, error) {
+	query := `SELECT funnel_id, step_order FROM funnel_steps WHERE namespace = $1 AND short_code = $2 ORDER BY funnel_id, step_order`
+
+	refs, err := queryAll[domain.FunnelStepRef](ctx, r.readPool, query, namespace, shortCode)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "find funnel steps of URL")
+	}
+
+	return refs, nil
+}
+
+func (r *URLRepository) RecordFunnelClick(ctx context.Context, click *domain.FunnelClick) error {
+	query := `INSERT INTO funnel_clicks (funnel_id, step_order, session_id, clicked_at) VALUES ($1, $2, $3, $4)`
+	if _, err := r.writePool.Exec(ctx, query, click.FunnelID, click.StepOrder, click.SessionID, click.ClickedAt); err != nil {
+		return r.handlePostgreSQLError(err, "record funnel click")
+	}
+
+	return nil
+}
+
+// FunnelSessionSteps is analytics, which tolerate replica lag, so it is served by readPool
+func (r *URLRepository) FunnelSessionSteps(ctx context.Context, funnelID int64) ([]domain.FunnelSessionStep, error) {
+	query := `SELECT DISTINCT session_id, step_order FROM funnel_clicks WHERE funnel_id = $1 ORDER BY session_id, step_order`
+
+	steps, err := queryAll[domain.FunnelSessionStep](ctx, r.readPool, query, funnelID)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "find funnel session steps")
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,848 @@
+// Package pgx implements the URL repository on PostgreSQL with the pgx driver and its
+// native connection pool. Queries match the lib/pq repository in the postgres package.
+package pgx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// URLRepository routes queries to separate pools: lookups go to readPool,
+// which may be a streaming replica, while every mutation goes to writePool.
+type URLRepository struct {
+	writePool *pgxpool.Pool
+	readPool  *pgxpool.Pool
+	// db wraps writePool for database/sql users such as the schema migrations
+	db     *sql.DB
+	logger *slog.Logger
+	// trigramSearch also matches original URLs similar to a search query, with the pg_trgm
+	// similarity operator
+	trigramSearch bool
+}
+
+// urlColumns lists the columns mapped onto domain.URL, in table order. A NULL description
+// or creator address reads as empty.
+const urlColumns = `id, short_code, original_url, clicks, created_at, updated_at, password_hash, health_status, last_checked_at, namespace, pool_enabled, pool, signed, geo_routes, device_routes, redirect_type, expires_at, tags, delay_seconds, unique_clicks, COALESCE(description, '') AS description, enabled, last_accessed_at, is_custom_alias, click_goal, goal_reached, COALESCE(host(created_by_ip), '') AS created_by_ip, COALESCE(title, '') AS title`
+
+// clickColumns lists the url_clicks columns mapped onto domain.Click. ip_address is an
+// inet, NULL when unknown, and is read back as plain text.
+const clickColumns = `id, namespace, short_code, clicked_at, referer, ua_browser, ua_os, ua_device_type, variant_id, target_url, redirect_duration_ms, COALESCE(host(ip_address), '') AS ip_address, user_agent, country`
+
+// NewPool creates a connection pool on connString. simpleProtocol sends every query as
+// plain text, without preparing and caching statements, for poolers such as PgBouncer in
+// transaction mode that hand each transaction a different server connection.
+func NewPool(ctx context.Context, connString string, simpleProtocol bool) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	if simpleProtocol {
+		config.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	}
+	return pgxpool.NewWithConfig(ctx, config)
+}
+
+func NewURLRepository(pool *pgxpool.Pool, logger *slog.Logger, trigramSearch bool) *URLRepository {
+	return NewURLRepositoryWithReplica(pool, nil, logger, trigramSearch)
+}
+
+// NewURLRepositoryWithReplica creates a repository that reads from readPool and writes to
+// writePool. When readPool is nil both paths use writePool. trigramSearch makes searches
+// fuzzy, and needs the pg_trgm extension.
+func NewURLRepositoryWithReplica(writePool, readPool *pgxpool.Pool, logger *slog.Logger, trigramSearch bool) *URLRepository {
+	if readPool == nil {
+		readPool = writePool
+	}
+	return &URLRepository{
+		writePool:     writePool,
+		readPool:      readPool,
+		db:            stdlib.OpenDBFromPool(writePool),
+		logger:        logger,
+		trigramSearch: trigramSearch,
+	}
+}
+
+func (r *URLRepository) Create(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	tx, err := r.writePool.Begin(ctx)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "begin create URL")
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Namespaces are registered on first use
+	namespaceQuery := `INSERT INTO namespaces (slug) VALUES ($1) ON CONFLICT (slug) DO NOTHING`
+	if _, err := tx.Exec(ctx, namespaceQuery, url.Namespace); err != nil {
+		return nil, r.handlePostgreSQLError(err, "register namespace")
+	}
+
+	query := `
+		INSERT INTO urls (namespace, short_code, original_url, clicks, created_at, password_hash, health_status, pool_enabled, pool, signed, geo_routes, device_routes, redirect_type, expires_at, tags, delay_seconds, description, enabled, is_custom_alias, click_goal, created_by_ip, title)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NULLIF($17, ''), $18, $19, $20, $21, NULLIF($22, ''))
+		RETURNING ` + urlColumns
+
+	result, err := queryOne[domain.URL](ctx, tx, query, url.Namespace, url.ShortCode, url.OriginalURL, url.Clicks, url.CreatedAt, url.PasswordHash, url.HealthStatus, url.PoolEnabled, url.Pool, url.Signed, url.GeoRoutes, url.DeviceRoutes, url.RedirectType, url.ExpiresAt, url.Tags, url.DelaySeconds, url.Description, url.Enabled, url.IsCustomAlias, url.ClickGoal, nullableIP(url.CreatedByIP), url.Title)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "create URL")
+	}
+
+	variantQuery := `
+		INSERT INTO url_variants (url_id, original_url, weight)
+		VALUES ($1, $2, $3)
+		RETURNING id, url_id, original_url, weight`
+
+	for _, variant := range url.Variants {
+		created, err := queryOne[domain.URLVariant](ctx, tx, variantQuery, result.ID, variant.OriginalURL, variant.Weight)
+		if err != nil {
+			return nil, r.handlePostgreSQLError(err, "create URL variant")
+		}
+		result.Variants = append(result.Variants, *created)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, r.handlePostgreSQLError(err, "commit create URL")
+	}
+
+	r.logger.Debug("URL created successfully", "namespace", result.Namespace, "short_code", result.ShortCode, "id", result.ID, "variants", len(result.Variants))
+	return result, nil
+}
+
+// FindByShortCode looks shortCode up in the default namespace
+func (r *URLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return r.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+}
+
+func (r *URLRepository) FindByNamespaceAndCode(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE namespace = $1 AND short_code = $2`
+
+	url, err := queryOne[domain.URL](ctx, r.readPool, query, namespace, shortCode)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "find URL by short code")
+	}
+
+	if err := r.loadVariants(ctx, r.readPool, url); err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+// loadVariants attaches the variants of url, if any, in creation order
+func (r *URLRepository) loadVariants(ctx context.Context, pool *pgxpool.Pool, url *domain.URL) error {
+	query := `SELECT id, url_id, original_url, weight FROM url_variants WHERE url_id = $1 ORDER BY id ASC`
+
+	variants, err := queryAll[domain.URLVariant](ctx, pool, query, url.ID)
+	if err != nil {
+		return r.handlePostgreSQLError(err, "load URL variants")
+	}
+
+	// Match sqlx, which leaves the slice nil without variants
+	if len(variants) > 0 {
+		url.Variants = variants
+	}
+	return nil
+}
+
+// FindByShortCodes reads the URLs and then all of their variants, two queries however many
+// short codes are asked for
+func (r *URLRepository) FindByShortCodes(ctx context.Context, namespace string, shortCodes []string) ([]*domain.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE namespace = $1 AND short_code = ANY($2) ORDER BY id ASC`
+
+	urls, err := queryAllAddr[domain.URL](ctx, r.readPool, query, namespace, shortCodes)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "find URLs by short codes")
+	}
+	if len(urls) == 0 {
+		return urls, nil
+	}
+
+	byID := make(map[int64]*domain.URL, len(urls))
+	ids := make([]int64, 0, len(urls))
+	for _, url := range urls {
+		byID[url.ID] = url
+		ids = append(ids, url.ID)
+	}
+
+	variantQuery := `SELECT id, url_id, original_url, weight FROM url_variants WHERE url_id = ANY($1) ORDER BY id ASC`
+
+	variants, err := queryAll[domain.URLVariant](ctx, r.readPool, variantQuery, ids)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "load URL variants")
+	}
+	for _, variant := range variants {
+		byID[variant.URLID].Variants = append(byID[variant.URLID].Variants, variant)
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) IncrementClicks(ctx context.Context, namespace, shortCode string, unique bool) (*domain.URL, error) {
+	query := `
+		UPDATE urls
+		SET clicks = clicks + 1, unique_clicks = unique_clicks + CASE WHEN $1 THEN 1 ELSE 0 END, last_accessed_at = CURRENT_TIMESTAMP
+		WHERE namespace = $2 AND short_code = $3
+		RETURNING ` + urlColumns
+
+	url, err := queryOne[domain.URL](ctx, r.writePool, query, unique, namespace, shortCode)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "increment clicks")
+	}
+
+	// Read from the primary like the update itself, so the result is never behind it
+	if err := r.loadVariants(ctx, r.writePool, url); err != nil {
+		return nil, err
+	}
+
+	r.logger.Debug("Clicks incremented", "namespace", namespace, "short_code", shortCode, "new_count", url.Clicks)
+	return url, nil
+}
+
+// AddClicks sends every update in one batch, within the transaction
+func (r *URLRepository) AddClicks(ctx context.Context, deltas []domain.ClickDelta) error {
+	tx, err := r.writePool.Begin(ctx)
+	if err != nil {
+		return r.handlePostgreSQLError(err, "begin add clicks")
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+		UPDATE urls
+		SET clicks = clicks + $1, unique_clicks = unique_clicks + $2, last_accessed_at = CURRENT_TIMESTAMP
+		WHERE namespace = $3 AND short_code = $4
+	`
+	batch := &pgx.Batch{}
+	for _, delta := range deltas {
+		batch.Queue(query, delta.Clicks, delta.UniqueClicks, delta.Namespace, delta.ShortCode)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return r.handlePostgreSQLError(err, "add clicks")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return r.handlePostgreSQLError(err, "commit add clicks")
+	}
+	return nil
+}
+
+// Update stores the editable fields of url. Clicks, health and variants are left alone,
+// as they have their own writers.
+func (r *URLRepository) Update(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	query := `
+		UPDATE urls
+		SET original_url = $1, password_hash = $2, signed = $3, pool_enabled = $4, pool = $5, geo_routes = $6,
+			device_routes = $7, redirect_type = $8, expires_at = $9, tags = $10, description = NULLIF($11, ''),
+			title = NULLIF($12, '')
+		WHERE namespace = $13 AND short_code = $14
+		RETURNING ` + urlColumns
+
+	updated, err := queryOne[domain.URL](ctx, r.writePool, query, url.OriginalURL, url.PasswordHash, url.Signed, url.PoolEnabled, url.Pool, url.GeoRoutes,
+		url.DeviceRoutes, url.RedirectType, url.ExpiresAt, url.Tags, url.Description, url.Title, url.Namespace, url.ShortCode)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "update URL")
+	}
+
+	if err := r.loadVariants(ctx, r.writePool, updated); err != nil {
+		return nil, err
+	}
+
+	r.logger.Debug("URL updated", "namespace", updated.Namespace, "short_code", updated.ShortCode)
+	return updated, nil
+}
+
+func (r *URLRepository) Exists(ctx context.Context, namespace, shortCode string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM urls WHERE namespace = $1 AND short_code = $2)`
+
+	if err := r.readPool.QueryRow(ctx, query, namespace, shortCode).Scan(&exists); err != nil {
+		return false, r.handlePostgreSQLError(err, "check URL existence")
+	}
+
+	return exists, nil
+}
+
+// List returns up to limit URLs with an ID greater than afterID, in ID order.
+// Passing the last ID of a page as afterID fetches the next one.
+func (r *URLRepository) List(ctx context.Context, afterID int64, limit int) ([]*domain.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE id > $1 ORDER BY id ASC LIMIT $2`
+
+	urls, err := queryAllAddr[domain.URL](ctx, r.readPool, query, afterID, limit)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "list URLs")
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) ListByCreatorIP(ctx context.Context, ip string, afterID int64, limit int) ([]*domain.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE created_by_ip = $1 AND id > $2 ORDER BY id ASC LIMIT $3`
+
+	urls, err := queryAllAddr[domain.URL](ctx, r.readPool, query, ip, afterID, limit)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "list URLs by creator")
+	}
+
+	return urls, nil
+}
+
+// Search matches the query as a substring, and with trigramSearch set by similarity as
+// well. The trigram indexes of migration 025 serve both kinds of match.
+func (r *URLRepository) Search(ctx context.Context, query string, limit int) ([]*domain.URL, error) {
+	match := `original_url ILIKE $1 OR short_code ILIKE $1 OR description ILIKE $1`
+	args := []any{containsPattern(query), limit}
+	if r.trigramSearch {
+		match = `original_url % $3 OR ` + match
+		args = append(args, query)
+	}
+	sqlQuery := `SELECT ` + urlColumns + ` FROM urls WHERE ` + match + ` ORDER BY id ASC LIMIT $2`
+
+	urls, err := queryAllAddr[domain.URL](ctx, r.readPool, sqlQuery, args...)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "search URLs")
+	}
+
+	return urls, nil
+}
+
+// containsPattern is a LIKE pattern matching the values containing s, its wildcards
+// matched literally
+// nullableIP passes an empty address as NULL. The parameter takes the inet type of its
+// column, which the empty string is not valid for.
+func nullableIP(ip string) any {
+	if ip == "" {
+		return nil
+	}
+	return ip
+}
+
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ListForHealthCheck returns up to limit URLs, never-checked ones first, then the most stale
+func (r *URLRepository) ListForHealthCheck(ctx context.Context, limit int) ([]*domain.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls ORDER BY last_checked_at ASC NULLS FIRST, id ASC LIMIT $1`
+
+	urls, err := queryAllAddr[domain.URL](ctx, r.readPool, query, limit)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "list URLs for health check")
+	}
+
+	return urls, nil
+}
+
+// FindExpired reads from writePool, so URLs deleted by a previous batch are never returned again
+func (r *URLRepository) FindExpired(ctx context.Context, before time.Time, limit int) ([]*domain.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE expires_at IS NOT NULL AND expires_at < $1 ORDER BY id ASC LIMIT $2`
+
+	urls, err := queryAllAddr[domain.URL](ctx, r.writePool, query, before, limit)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "find expired URLs")
+	}
+
+	return urls, nil
+}
+
+// FindNotAccessedSince reads from writePool, so URLs disabled by a previous batch are never
+// returned again
+func (r *URLRepository) FindNotAccessedSince(ctx context.Context, since time.Time, limit int) ([]*domain.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls
+		WHERE enabled AND COALESCE(last_accessed_at, created_at) < $1
+		ORDER BY COALESCE(last_accessed_at, created_at) ASC, id ASC LIMIT $2`
+
+	urls, err := queryAllAddr[domain.URL](ctx, r.writePool, query, since, limit)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "find cold URLs")
+	}
+
+	return urls, nil
+}
+
+// TopByClicks returns the n most clicked public URLs, ties broken by creation order
+func (r *URLRepository) TopByClicks(ctx context.Context, n int) ([]*domain.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls
+		WHERE enabled AND NOT signed AND password_hash = ''
+		ORDER BY clicks DESC, id ASC LIMIT $1`
+
+	urls, err := queryAllAddr[domain.URL](ctx, r.readPool, query, n)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "list top URLs")
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) UpdateHealthStatus(ctx context.Context, namespace, shortCode, status string, checkedAt time.Time) error {
+	query := `UPDATE urls SET health_status = $1, last_checked_at = $2 WHERE namespace = $3 AND short_code = $4`
+
+	tag, err := r.writePool.Exec(ctx, query, status, checkedAt, namespace, shortCode)
+	if err != nil {
+		return r.handlePostgreSQLError(err, "update health status")
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (r *URLRepository) SetEnabled(ctx context.Context, namespace, shortCode string, enabled bool) error {
+	query := `UPDATE urls SET enabled = $1 WHERE namespace = $2 AND short_code = $3`
+
+	tag, err := r.writePool.Exec(ctx, query, enabled, namespace, shortCode)
+	if err != nil {
+		return r.handlePostgreSQLError(err, "set enabled")
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (r *URLRepository) MarkGoalReached(ctx context.Context, namespace, shortCode string) (bool, error) {
+	query := `UPDATE urls SET goal_reached = TRUE WHERE namespace = $1 AND short_code = $2 AND NOT goal_reached`
+
+	tag, err := r.writePool.Exec(ctx, query, namespace, shortCode)
+	if err != nil {
+		return false, r.handlePostgreSQLError(err, "mark goal reached")
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *URLRepository) RecordClick(ctx context.Context, click *domain.Click) error {
+	query := `
+		INSERT INTO url_clicks (namespace, short_code, clicked_at, referer, ua_browser, ua_os, ua_device_type, variant_id, target_url, redirect_duration_ms, ip_address, user_agent, country)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, '')::inet, $12, $13)
+	`
+
+	if _, err := r.writePool.Exec(ctx, query, click.Namespace, click.ShortCode, click.ClickedAt, click.Referer, click.Browser, click.OS, click.DeviceType, click.VariantID, click.TargetURL, click.RedirectDurationMs, click.IPAddress, click.UserAgent, click.Country); err != nil {
+		return r.handlePostgreSQLError(err, "record click")
+	}
+
+	return nil
+}
+
+func (r *URLRepository) ListClicks(ctx context.Context, namespace, shortCode string, after domain.ClickCursor, limit int) ([]*domain.Click, error) {
+	query := `SELECT ` + clickColumns + ` FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2 AND (clicked_at, id) > ($3, $4)
+		ORDER BY clicked_at ASC, id ASC LIMIT $5`
+
+	clicks, err := queryAllAddr[domain.Click](ctx, r.readPool, query, namespace, shortCode, after.ClickedAt, after.ID, limit)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "list clicks")
+	}
+
+	return clicks, nil
+}
+
+func (r *URLRepository) ResetClicks(ctx context.Context, namespace, shortCode string) error {
+	tx, err := r.writePool.Begin(ctx)
+	if err != nil {
+		return r.handlePostgreSQLError(err, "begin reset clicks")
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `UPDATE urls SET clicks = 0, unique_clicks = 0, updated_at = NOW() WHERE namespace = $1 AND short_code = $2`
+	tag, err := tx.Exec(ctx, query, namespace, shortCode)
+	if err != nil {
+		return r.handlePostgreSQLError(err, "reset clicks")
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM url_clicks WHERE namespace = $1 AND short_code = $2`, namespace, shortCode); err != nil {
+		return r.handlePostgreSQLError(err, "delete clicks")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return r.handlePostgreSQLError(err, "commit reset clicks")
+	}
+
+	r.logger.Debug("Clicks reset", "namespace", namespace, "short_code", shortCode)
+	return nil
+}
+
+// Delete removes a URL; its variants and clicks go with it through ON DELETE CASCADE
+func (r *URLRepository) Delete(ctx context.Context, namespace, shortCode string) error {
+	tag, err := r.writePool.Exec(ctx, `DELETE FROM urls WHERE namespace = $1 AND short_code = $2`, namespace, shortCode)
+	if err != nil {
+		return r.handlePostgreSQLError(err, "delete URL")
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	r.logger.Debug("URL deleted", "namespace", namespace, "short_code", shortCode)
+	return nil
+}
+
+// DeleteMany removes the URLs of namespace with any of shortCodes in a single statement. The
+// variants and recorded clicks of each go with it by cascade.
+func (r *URLRepository) DeleteMany(ctx context.Context, namespace string, shortCodes []string) ([]*domain.URL, error) {
+	query := `DELETE FROM urls WHERE namespace = $1 AND short_code = ANY($2) RETURNING ` + urlColumns
+
+	urls, err := queryAllAddr[domain.URL](ctx, r.writePool, query, namespace, shortCodes)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "delete URLs")
+	}
+
+	r.logger.Debug("URLs deleted", "namespace", namespace, "count", len(urls))
+	return urls, nil
+}
+
+// ClickTimeSeries aggregates recorded clicks into UTC buckets. Analytics tolerate replica lag,
+// so this is served by readPool.
+func (r *URLRepository) ClickTimeSeries(ctx context.Context, namespace, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.TimeBucket, error) {
+	query := `
+		SELECT date_trunc($1, clicked_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS period, COUNT(*) AS clicks
+		FROM url_clicks
+		WHERE namespace = $2 AND short_code = $3 AND clicked_at BETWEEN $4 AND $5
+		GROUP BY period
+		ORDER BY period
+	`
+
+	buckets, err := queryAll[domain.TimeBucket](ctx, r.readPool, query, string(granularity), namespace, shortCode, from, to)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "click time series")
+	}
+
+	return buckets, nil
+}
+
+// heatmapRow counts the clicks of one local hour and weekday
+type heatmapRow struct {
+	Hour   int `db:"hour"`
+	Dow    int `db:"dow"`
+	Clicks int `db:"clicks"`
+}
+
+// ClickHeatmap converts clicked_at to loc in the database, which shares the IANA zone names
+// of time.LoadLocation. Analytics tolerate replica lag, so this is served by readPool.
+func (r *URLRepository) ClickHeatmap(ctx context.Context, namespace, shortCode string, loc *time.Location) (*domain.ClickHeatmap, error) {
+	query := `
+		SELECT EXTRACT(hour FROM clicked_at AT TIME ZONE $1)::int AS hour,
+			EXTRACT(dow FROM clicked_at AT TIME ZONE $1)::int AS dow,
+			COUNT(*) AS clicks
+		FROM url_clicks
+		WHERE namespace = $2 AND short_code = $3
+		GROUP BY 1, 2
+	`
+
+	rows, err := queryAll[heatmapRow](ctx, r.readPool, query, loc.String(), namespace, shortCode)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "click heatmap")
+	}
+
+	heatmap := &domain.ClickHeatmap{}
+	for _, row := range rows {
+		heatmap.HourOfDay[row.Hour] += row.Clicks
+		heatmap.DayOfWeek[row.Dow] += row.Clicks
+	}
+	return heatmap, nil
+}
+
+func (r *URLRepository) TopReferrers(ctx context.Context, namespace, shortCode string, limit int) ([]domain.ReferrerCount, error) {
+	query := `
+		SELECT referer, COUNT(*) AS count
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2
+		GROUP BY referer
+		ORDER BY count DESC, referer ASC
+		LIMIT $3
+	`
+
+	referrers, err := queryAll[domain.ReferrerCount](ctx, r.readPool, query, namespace, shortCode, limit)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "top referrers")
+	}
+
+	return referrers, nil
+}
+
+func (r *URLRepository) TopIPs(ctx context.Context, limit int) ([]domain.IPCount, error) {
+	query := `
+		SELECT host(ip_address) AS ip_address, COUNT(*) AS count
+		FROM url_clicks
+		WHERE ip_address IS NOT NULL
+		GROUP BY ip_address
+		ORDER BY count DESC, ip_address ASC
+		LIMIT $1
+	`
+
+	ips, err := queryAll[domain.IPCount](ctx, r.readPool, query, limit)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "top IPs")
+	}
+
+	return ips, nil
+}
+
+// deviceGroupColumns maps each device grouping to the url_clicks columns it aggregates by
+var deviceGroupColumns = map[domain.DeviceGrouping]string{
+	domain.DeviceGroupingDevice:  "ua_device_type, ua_browser, ua_os",
+	domain.DeviceGroupingBrowser: "ua_browser",
+	domain.DeviceGroupingOS:      "ua_os",
+}
+
+func (r *URLRepository) DeviceStats(ctx context.Context, namespace, shortCode string, grouping domain.DeviceGrouping) ([]domain.DeviceStat, error) {
+	columns, ok := deviceGroupColumns[grouping]
+	if !ok {
+		return nil, fmt.Errorf("unsupported device grouping %q", grouping)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %[1]s, COUNT(*) AS count
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2
+		GROUP BY %[1]s
+		ORDER BY count DESC, %[1]s
+	`, columns)
+
+	stats, err := queryAll[domain.DeviceStat](ctx, r.readPool, query, namespace, shortCode)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "device stats")
+	}
+
+	return stats, nil
+}
+
+// VariantStats counts the clicks sent to each variant of a URL, including variants never picked
+func (r *URLRepository) VariantStats(ctx context.Context, namespace, shortCode string) ([]domain.VariantStat, error) {
+	query := `
+		SELECT v.id AS variant_id, v.original_url, v.weight, COUNT(c.id) AS clicks
+		FROM url_variants v
+		JOIN urls u ON u.id = v.url_id
+		LEFT JOIN url_clicks c ON c.variant_id = v.id
+		WHERE u.namespace = $1 AND u.short_code = $2
+		GROUP BY v.id, v.original_url, v.weight
+		ORDER BY v.id ASC
+	`
+
+	stats, err := queryAll[domain.VariantStat](ctx, r.readPool, query, namespace, shortCode)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "variant stats")
+	}
+
+	return stats, nil
+}
+
+// RedirectLatency computes the percentiles of recorded redirect durations in the database,
+// served by readPool
+func (r *URLRepository) RedirectLatency(ctx context.Context, namespace, shortCode string) (*domain.LatencyStats, error) {
+	query := `
+		SELECT
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY redirect_duration_ms), 0) AS p50,
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY redirect_duration_ms), 0) AS p95,
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY redirect_duration_ms), 0) AS p99,
+			COALESCE(AVG(redirect_duration_ms), 0) AS avg,
+			COUNT(redirect_duration_ms) AS count
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2 AND redirect_duration_ms IS NOT NULL
+	`
+
+	stats, err := queryOne[domain.LatencyStats](ctx, r.readPool, query, namespace, shortCode)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "redirect latency")
+	}
+
+	return stats, nil
+}
+
+func (r *URLRepository) Stats(ctx context.Context, now, since time.Time) (*domain.ServiceStats, error) {
+	query := `
+		SELECT
+			COUNT(*) AS total_urls,
+			COALESCE(SUM(clicks), 0) AS total_clicks,
+			COUNT(*) FILTER (WHERE expires_at IS NULL OR expires_at > $1) AS active_urls,
+			COUNT(*) FILTER (WHERE created_at > $2) AS recent_urls,
+			(SELECT COUNT(*) FROM url_clicks WHERE clicked_at > $2) AS recent_clicks
+		FROM urls
+	`
+
+	stats, err := queryOne[domain.ServiceStats](ctx, r.readPool, query, now, since)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "service stats")
+	}
+
+	return stats, nil
+}
+
+func (r *URLRepository) CountActive(ctx context.Context, now time.Time) (int64, error) {
+	query := `SELECT COUNT(*) FROM urls WHERE enabled AND (expires_at IS NULL OR expires_at > $1)`
+
+	var count int64
+	if err := r.readPool.QueryRow(ctx, query, now).Scan(&count); err != nil {
+		return 0, r.handlePostgreSQLError(err, "count active")
+	}
+
+	return count, nil
+}
+
+// CountByCreatedDate truncates created_at to its UTC day, as indexed by migration 029
+func (r *URLRepository) CountByCreatedDate(ctx context.Context, date time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM urls WHERE (created_at AT TIME ZONE 'UTC')::date = $1::date`
+
+	var count int
+	if err := r.readPool.QueryRow(ctx, query, date.UTC().Format(time.DateOnly)).Scan(&count); err != nil {
+		return 0, r.handlePostgreSQLError(err, "count by created date")
+	}
+
+	return count, nil
+}
+
+func (r *URLRepository) DailyActivity(ctx context.Context, from, to time.Time) ([]domain.DailyActivity, error) {
+	query := `
+		SELECT day::timestamp AT TIME ZONE 'UTC' AS day, SUM(urls_created) AS urls_created, SUM(clicks) AS clicks
+		FROM (
+			SELECT (created_at AT TIME ZONE 'UTC')::date AS day, 1 AS urls_created, 0 AS clicks
+			FROM urls
+			WHERE (created_at AT TIME ZONE 'UTC')::date BETWEEN $1::date AND $2::date
+			UNION ALL
+			SELECT (clicked_at AT TIME ZONE 'UTC')::date, 0, 1
+			FROM url_clicks
+			WHERE (clicked_at AT TIME ZONE 'UTC')::date BETWEEN $1::date AND $2::date
+		) activity
+		GROUP BY day
+		ORDER BY day
+	`
+
+	days, err := queryAll[domain.DailyActivity](ctx, r.readPool, query, from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "daily activity")
+	}
+
+	return days, nil
+}
+
+func (r *URLRepository) AliasLengths(ctx context.Context) ([]domain.AliasLengthCount, error) {
+	query := `
+		SELECT is_custom_alias AS custom, LENGTH(short_code) AS length, COUNT(*) AS urls
+		FROM urls
+		GROUP BY is_custom_alias, LENGTH(short_code)
+		ORDER BY is_custom_alias, length
+	`
+
+	counts, err := queryAll[domain.AliasLengthCount](ctx, r.readPool, query)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(err, "alias lengths")
+	}
+
+	return counts, nil
+}
+
+// querier is what the query helpers need, satisfied by pools and transactions
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// queryOne scans the single row returned by query into a T, by db tag
+func queryOne[T any](ctx context.Context, q querier, query string, args ...any) (*T, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByNameLax[T])
+}
+
+// queryAll scans every row returned by query into a T, by db tag
+func queryAll[T any](ctx context.Context, q querier, query string, args ...any) ([]T, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByNameLax[T])
+}
+
+// queryAllAddr is queryAll for callers that keep pointers to the rows
+func queryAllAddr[T any](ctx context.Context, q querier, query string, args ...any) ([]*T, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[T])
+}
+
+// handlePostgreSQLError converts PostgreSQL-specific errors to domain errors
+func (r *URLRepository) handlePostgreSQLError(err error, operation string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		r.logger.Error("PostgreSQL error",
+			"operation", operation,
+			"code", pgErr.Code,
+			"message", pgErr.Message,
+			"detail", pgErr.Detail,
+		)
+
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			if pgErr.ConstraintName == "urls_namespace_short_code_key" {
+				return domain.ErrShortCodeExists
+			}
+			return fmt.Errorf("unique constraint violation: %s", pgErr.Detail)
+		case "23502": // not_null_violation
+			return fmt.Errorf("required field missing: %s", pgErr.ColumnName)
+		case "23514": // check_violation
+			return fmt.Errorf("check constraint violation: %s", pgErr.Detail)
+		case "08000", "08003", "08006": // connection errors
+			return fmt.Errorf("database connection error: %s", pgErr.Message)
+		default:
+			return fmt.Errorf("database error [%s]: %s", pgErr.Code, pgErr.Message)
+		}
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrURLNotFound
+	}
+
+	return err
+}
+
+// DB returns a database/sql handle on the primary pool, for the schema migrations
+func (r *URLRepository) DB() *sql.DB {
+	return r.db
+}
+
+// Pool returns the primary connection pool, the one writes go through
+func (r *URLRepository) Pool() *pgxpool.Pool {
+	return r.writePool
+}
+
+func (r *URLRepository) Close() error {
+	// Closing the wrapper leaves writePool open
+	err := r.db.Close()
+	if r.readPool != r.writePool {
+		r.readPool.Close()
+	}
+	r.writePool.Close()
+	return err
+}
+
+func (r *URLRepository) HealthCheck(ctx context.Context) error {
+	if r.writePool == nil || r.readPool == nil {
+		return errors.New("database connection is nil")
+	}
+	if err := r.writePool.Ping(ctx); err != nil {
+		return fmt.Errorf("primary unreachable: %w", err)
+	}
+	if r.readPool != r.writePool {
+		if err := r.readPool.Ping(ctx); err != nil {
+			return fmt.Errorf("replica unreachable: %w", err)
+		}
+	}
+	return nil
+}
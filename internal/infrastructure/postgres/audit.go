@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// AuditRepository keeps the change history of short URLs in the url_audit_log table. It
+// only uses database/sql, so it serves the pgx repository too.
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+// NewAuditRepository creates an audit repository on db
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Record(ctx context.Context, entry domain.AuditEntry) error {
+	query := `
+		INSERT INTO url_audit_log (recorded_at, operation, namespace, short_code, actor_ip, request_id, old_value, new_value)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query, entry.Timestamp, entry.Operation, entry.Namespace, entry.ShortCode,
+		entry.ActorIP, entry.RequestID, jsonbParam(entry.OldValue), jsonbParam(entry.NewValue))
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditRepository) ListByShortCode(ctx context.Context, namespace, shortCode string, limit int) ([]domain.AuditEntry, error) {
+	query := `
+		SELECT recorded_at, operation, namespace, short_code, actor_ip, request_id, old_value, new_value
+		FROM url_audit_log
+		WHERE namespace = $1 AND short_code = $2
+		ORDER BY recorded_at DESC, id DESC
+		LIMIT $3
+	`
+	rows, err := r.db.QueryContext(ctx, query, namespace, shortCode, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	entries := []domain.AuditEntry{}
+	for rows.Next() {
+		var entry domain.AuditEntry
+		// database/sql only scans NULL into a plain []byte, not into json.RawMessage
+		var oldValue, newValue []byte
+		err := rows.Scan(&entry.Timestamp, &entry.Operation, &entry.Namespace, &entry.ShortCode, &entry.ActorIP, &entry.RequestID, &oldValue, &newValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit entry: %w", err)
+		}
+		entry.OldValue, entry.NewValue = oldValue, newValue
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+// jsonbParam passes value as text, which jsonb columns accept from every driver, and a
+// missing value as NULL
+func jsonbParam(value json.RawMessage) any {
+	if value == nil {
+		return nil
+	}
+	return string(value)
+}
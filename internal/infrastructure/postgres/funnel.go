@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// CreateFunnel inserts the funnel and its steps in one transaction
+func (r *URLRepository) CreateFunnel(ctx context.Context, funnel *domain.Funnel) (*domain.Funnel, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.writeDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "begin create funnel")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result := domain.Funnel{Name: funnel.Name}
+	query := `INSERT INTO funnels (name, created_at) VALUES ($1, $2) RETURNING id, created_at`
+	if err := tx.QueryRowxContext(ctx, query, funnel.Name, funnel.CreatedAt).Scan(&result.ID, &result.CreatedAt); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "create funnel")
+	}
+
+	stepQuery := `INSERT INTO funnel_steps (funnel_id, step_order, namespace, short_code) VALUES ($1, $2, $3, $4)`
+	for _, step := range funnel.Steps {
+		if _, err := tx.ExecContext(ctx, stepQuery, result.ID, step.Order, step.Namespace, step.ShortCode); err != nil {
+			return nil, r.handlePostgreSQLError(ctx, err, "create funnel step")
+		}
+		result.Steps = append(result.Steps, step)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "commit create funnel")
+	}
+
+	r.logger.Debug("Funnel created", "id", result.ID, "steps", len(result.Steps))
+	return &result, nil
+}
+
+func (r *URLRepository) FindFunnel(ctx context.Context, id int64) (*domain.Funnel, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var funnel domain.Funnel
+	err := r.readDB.GetContext(ctx, &funnel, `SELECT id, name, created_at FROM funnels WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrFunnelNotFound
+	}
+	if err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "find funnel")
+	}
+
+	query := `SELECT step_order, namespace, short_code FROM funnel_steps WHERE funnel_id = $1 ORDER BY step_order`
+	if err := r.readDB.SelectContext(ctx, &funnel.Steps, query, id); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "find funnel steps")
+	}
+
+	return &funnel, nil
+}
+
+// FindFunnelSteps runs on every redirect, so it is served by readDB like the URL lookup itself
+func (r *URLRepository) FindFunnelSteps(ctx context.Context, namespace, shortCode string) ([]domain.FunnelStepRef, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var refs []domain.FunnelStepRef
+	query := `SELECT funnel_id, step_order FROM funnel_steps WHERE namespace = $1 AND short_code = $2 ORDER BY funnel_id, step_order`
+	if err := r.readDB.SelectContext(ctx, &refs, query, namespace, shortCode); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "find funnel steps of URL")
+	}
+
+	return refs, nil
+}
+
+func (r *URLRepository) RecordFunnelClick(ctx context.Context, click *domain.FunnelClick) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO funnel_clicks (funnel_id, step_order, session_id, clicked_at) VALUES ($1, $2, $3, $4)`
+	if _, err := r.writeDB.ExecContext(ctx, query, click.FunnelID, click.StepOrder, click.SessionID, click.ClickedAt); err != nil {
+		return r.handlePostgreSQLError(ctx, err, "record funnel click")
+	}
+
+	return nil
+}
+
+// FunnelSessionSteps is analytics, which tolerate replica lag, so it is served by readDB
+func (r *URLRepository) FunnelSessionSteps(ctx context.Context, funnelID int64) ([]domain.FunnelSessionStep, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	var steps []domain.FunnelSessionStep
+	query := `SELECT DISTINCT session_id, step_order FROM funnel_clicks WHERE funnel_id = $1 ORDER BY session_id, step_order`
+	if err := r.readDB.SelectContext(ctx, &steps, query, funnelID); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "find funnel session steps")
+	}
+
+	return steps, nil
+}
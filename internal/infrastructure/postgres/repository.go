@@ -6,88 +6,905 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 
 	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/telemetry"
 )
 
+// URLRepository routes queries to separate connections: lookups go to readDB,
+// which may be a streaming replica, while every mutation goes to writeDB.
 type URLRepository struct {
-	db     *sqlx.DB
-	logger *slog.Logger
+	writeDB      *sqlx.DB
+	readDB       *sqlx.DB
+	logger       *slog.Logger
+	queryTimeout time.Duration
+	// trigramSearch also matches original URLs similar to a search query, with the pg_trgm
+	// similarity operator
+	trigramSearch bool
+	// disablePrepare never prepares statements explicitly, which PgBouncer in transaction
+	// mode cannot keep across transactions
+	disablePrepare bool
 }
 
-func NewURLRepository(db *sqlx.DB, logger *slog.Logger) *URLRepository {
-	return &URLRepository{db: db, logger: logger}
+// urlColumns lists the columns mapped onto domain.URL, in table order. A NULL description
+// or creator address reads as empty.
+const urlColumns = `id, short_code, original_url, clicks, created_at, updated_at, password_hash, health_status, last_checked_at, namespace, pool_enabled, pool, signed, geo_routes, device_routes, redirect_type, expires_at, tags, delay_seconds, unique_clicks, COALESCE(description, '') AS description, enabled, last_accessed_at, is_custom_alias, click_goal, goal_reached, COALESCE(host(created_by_ip), '') AS created_by_ip, COALESCE(title, '') AS title`
+
+// clickColumns lists the url_clicks columns mapped onto domain.Click. ip_address is an
+// inet, NULL when unknown, and is read back as plain text.
+const clickColumns = `id, namespace, short_code, clicked_at, referer, ua_browser, ua_os, ua_device_type, variant_id, target_url, redirect_duration_ms, COALESCE(host(ip_address), '') AS ip_address, user_agent, country`
+
+// NewURLRepository creates a repository on db. Every method gives up after queryTimeout
+// with domain.ErrQueryTimeout, 0 leaving queries bounded by the caller's context only.
+// trigramSearch makes searches fuzzy, and needs the pg_trgm extension. disablePrepare runs
+// batches query by query instead of through a prepared statement.
+func NewURLRepository(db *sqlx.DB, logger *slog.Logger, queryTimeout time.Duration, trigramSearch, disablePrepare bool) *URLRepository {
+	return NewURLRepositoryWithReplica(db, nil, logger, queryTimeout, trigramSearch, disablePrepare)
+}
+
+// NewURLRepositoryWithReplica creates a repository that reads from readDB and writes to writeDB.
+// When readDB is nil both paths use writeDB.
+func NewURLRepositoryWithReplica(writeDB, readDB *sqlx.DB, logger *slog.Logger, queryTimeout time.Duration, trigramSearch, disablePrepare bool) *URLRepository {
+	if readDB == nil {
+		readDB = writeDB
+	}
+	return &URLRepository{writeDB: writeDB, readDB: readDB, logger: logger, queryTimeout: queryTimeout, trigramSearch: trigramSearch, disablePrepare: disablePrepare}
+}
+
+// withQueryTimeout bounds ctx by the query timeout of the repository, when one is set
+func (r *URLRepository) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.queryTimeout)
 }
 
 func (r *URLRepository) Create(ctx context.Context, url *domain.URL) (*domain.URL, error) {
 	query := `
-		INSERT INTO urls (short_code, original_url, clicks, created_at)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, short_code, original_url, clicks, created_at, updated_at
-	`
+		INSERT INTO urls (namespace, short_code, original_url, clicks, created_at, password_hash, health_status, pool_enabled, pool, signed, geo_routes, device_routes, redirect_type, expires_at, tags, delay_seconds, description, enabled, is_custom_alias, click_goal, created_by_ip, title)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NULLIF($17, ''), $18, $19, $20, $21, NULLIF($22, ''))
+		RETURNING ` + urlColumns
+
+	ctx, span := telemetry.StartDBSpan(ctx, "Create", query)
+	defer span.End()
+
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.writeDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "begin create URL")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Namespaces are registered on first use
+	namespaceQuery := `INSERT INTO namespaces (slug) VALUES ($1) ON CONFLICT (slug) DO NOTHING`
+	if _, err := tx.ExecContext(ctx, namespaceQuery, url.Namespace); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "register namespace")
+	}
 
 	var result domain.URL
-	err := r.db.QueryRowContext(ctx, query, url.ShortCode, url.OriginalURL, url.Clicks, url.CreatedAt).
-		Scan(&result.ID, &result.ShortCode, &result.OriginalURL, &result.Clicks, &result.CreatedAt, &result.UpdatedAt)
+	err = tx.QueryRowxContext(ctx, query, url.Namespace, url.ShortCode, url.OriginalURL, url.Clicks, url.CreatedAt, url.PasswordHash, url.HealthStatus, url.PoolEnabled, url.Pool, url.Signed, url.GeoRoutes, url.DeviceRoutes, url.RedirectType, url.ExpiresAt, url.Tags, url.DelaySeconds, url.Description, url.Enabled, url.IsCustomAlias, url.ClickGoal, nullableIP(url.CreatedByIP), url.Title).
+		StructScan(&result)
 	if err != nil {
-		return nil, r.handlePostgreSQLError(err, "create URL")
+		return nil, r.handlePostgreSQLError(ctx, err, "create URL")
+	}
+
+	variantQuery := `
+		INSERT INTO url_variants (url_id, original_url, weight)
+		VALUES ($1, $2, $3)
+		RETURNING id, url_id, original_url, weight`
+
+	for _, variant := range url.Variants {
+		var created domain.URLVariant
+		if err := tx.QueryRowxContext(ctx, variantQuery, result.ID, variant.OriginalURL, variant.Weight).StructScan(&created); err != nil {
+			return nil, r.handlePostgreSQLError(ctx, err, "create URL variant")
+		}
+		result.Variants = append(result.Variants, created)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "commit create URL")
 	}
 
-	r.logger.Debug("URL created successfully", "short_code", result.ShortCode, "id", result.ID)
+	r.logger.Debug("URL created successfully", "namespace", result.Namespace, "short_code", result.ShortCode, "id", result.ID, "variants", len(result.Variants))
 	return &result, nil
 }
 
+// FindByShortCode looks shortCode up in the default namespace
 func (r *URLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return r.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+}
+
+func (r *URLRepository) FindByNamespaceAndCode(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE namespace = $1 AND short_code = $2`
+
+	// FindByShortCode goes through here too, so both share its span
+	ctx, span := telemetry.StartDBSpan(ctx, "FindByShortCode", query)
+	defer span.End()
+
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
 	var url domain.URL
-	query := `SELECT id, short_code, original_url, clicks, created_at FROM urls WHERE short_code = $1`
 
-	err := r.db.GetContext(ctx, &url, query, shortCode)
+	err := r.readDB.GetContext(ctx, &url, query, namespace, shortCode)
 	if err != nil {
-		return nil, r.handlePostgreSQLError(err, "find URL by short code")
+		return nil, r.handlePostgreSQLError(ctx, err, "find URL by short code")
+	}
+
+	if err := r.loadVariants(ctx, r.readDB, &url); err != nil {
+		return nil, err
 	}
 
 	return &url, nil
 }
 
-func (r *URLRepository) IncrementClicks(ctx context.Context, shortCode string) (*domain.URL, error) {
+// loadVariants attaches the variants of url, if any, in creation order
+func (r *URLRepository) loadVariants(ctx context.Context, db *sqlx.DB, url *domain.URL) error {
+	query := `SELECT id, url_id, original_url, weight FROM url_variants WHERE url_id = $1 ORDER BY id ASC`
+
+	var variants []domain.URLVariant
+	if err := db.SelectContext(ctx, &variants, query, url.ID); err != nil {
+		return r.handlePostgreSQLError(ctx, err, "load URL variants")
+	}
+
+	url.Variants = variants
+	return nil
+}
+
+// FindByShortCodes reads the URLs and then all of their variants, two queries however many
+// short codes are asked for
+func (r *URLRepository) FindByShortCodes(ctx context.Context, namespace string, shortCodes []string) ([]*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE namespace = $1 AND short_code = ANY($2) ORDER BY id ASC`
+
+	urls := []*domain.URL{}
+	if err := r.readDB.SelectContext(ctx, &urls, query, namespace, pq.Array(shortCodes)); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "find URLs by short codes")
+	}
+	if len(urls) == 0 {
+		return urls, nil
+	}
+
+	byID := make(map[int64]*domain.URL, len(urls))
+	ids := make([]int64, 0, len(urls))
+	for _, url := range urls {
+		byID[url.ID] = url
+		ids = append(ids, url.ID)
+	}
+
+	variantQuery := `SELECT id, url_id, original_url, weight FROM url_variants WHERE url_id = ANY($1) ORDER BY id ASC`
+
+	var variants []domain.URLVariant
+	if err := r.readDB.SelectContext(ctx, &variants, variantQuery, pq.Array(ids)); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "load URL variants")
+	}
+	for _, variant := range variants {
+		byID[variant.URLID].Variants = append(byID[variant.URLID].Variants, variant)
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) IncrementClicks(ctx context.Context, namespace, shortCode string, unique bool) (*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE urls 
-		SET clicks = clicks + 1 
-		WHERE short_code = $1
-		RETURNING id, short_code, original_url, clicks, created_at, updated_at
-	`
+		SET clicks = clicks + 1, unique_clicks = unique_clicks + CASE WHEN $1 THEN 1 ELSE 0 END, last_accessed_at = CURRENT_TIMESTAMP
+		WHERE namespace = $2 AND short_code = $3
+		RETURNING ` + urlColumns
 
 	var url domain.URL
-	err := r.db.QueryRowContext(ctx, query, shortCode).
-		Scan(&url.ID, &url.ShortCode, &url.OriginalURL, &url.Clicks, &url.CreatedAt, &url.UpdatedAt)
+	err := r.writeDB.QueryRowxContext(ctx, query, unique, namespace, shortCode).StructScan(&url)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrURLNotFound
 		}
-		return nil, r.handlePostgreSQLError(err, "increment clicks")
+		return nil, r.handlePostgreSQLError(ctx, err, "increment clicks")
 	}
 
-	r.logger.Debug("Clicks incremented", "short_code", shortCode, "new_count", url.Clicks)
+	// Read from the primary like the update itself, so the result is never behind it
+	if err := r.loadVariants(ctx, r.writeDB, &url); err != nil {
+		return nil, err
+	}
+
+	r.logger.Debug("Clicks incremented", "namespace", namespace, "short_code", shortCode, "new_count", url.Clicks)
 	return &url, nil
 }
 
-func (r *URLRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
+func (r *URLRepository) AddClicks(ctx context.Context, deltas []domain.ClickDelta) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.writeDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return r.handlePostgreSQLError(ctx, err, "begin add clicks")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		UPDATE urls
+		SET clicks = clicks + $1, unique_clicks = unique_clicks + $2, last_accessed_at = CURRENT_TIMESTAMP
+		WHERE namespace = $3 AND short_code = $4
+	`
+	exec := func(args ...any) (sql.Result, error) { return tx.ExecContext(ctx, query, args...) }
+	if !r.disablePrepare {
+		stmt, err := tx.PreparexContext(ctx, query)
+		if err != nil {
+			return r.handlePostgreSQLError(ctx, err, "prepare add clicks")
+		}
+		defer func() { _ = stmt.Close() }()
+		exec = func(args ...any) (sql.Result, error) { return stmt.ExecContext(ctx, args...) }
+	}
+
+	for _, delta := range deltas {
+		if _, err := exec(delta.Clicks, delta.UniqueClicks, delta.Namespace, delta.ShortCode); err != nil {
+			return r.handlePostgreSQLError(ctx, err, "add clicks")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.handlePostgreSQLError(ctx, err, "commit add clicks")
+	}
+	return nil
+}
+
+// Update stores the editable fields of url. Clicks, health and variants are left alone,
+// as they have their own writers.
+func (r *URLRepository) Update(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE urls
+		SET original_url = $1, password_hash = $2, signed = $3, pool_enabled = $4, pool = $5, geo_routes = $6,
+			device_routes = $7, redirect_type = $8, expires_at = $9, tags = $10, description = NULLIF($11, ''),
+			title = NULLIF($12, '')
+		WHERE namespace = $13 AND short_code = $14
+		RETURNING ` + urlColumns
+
+	var updated domain.URL
+	err := r.writeDB.QueryRowxContext(ctx, query, url.OriginalURL, url.PasswordHash, url.Signed, url.PoolEnabled, url.Pool, url.GeoRoutes,
+		url.DeviceRoutes, url.RedirectType, url.ExpiresAt, url.Tags, url.Description, url.Title, url.Namespace, url.ShortCode).StructScan(&updated)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrURLNotFound
+		}
+		return nil, r.handlePostgreSQLError(ctx, err, "update URL")
+	}
+
+	if err := r.loadVariants(ctx, r.writeDB, &updated); err != nil {
+		return nil, err
+	}
+
+	r.logger.Debug("URL updated", "namespace", updated.Namespace, "short_code", updated.ShortCode)
+	return &updated, nil
+}
+
+func (r *URLRepository) Exists(ctx context.Context, namespace, shortCode string) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM urls WHERE short_code = $1)`
+	query := `SELECT EXISTS(SELECT 1 FROM urls WHERE namespace = $1 AND short_code = $2)`
 
-	err := r.db.GetContext(ctx, &exists, query, shortCode)
+	err := r.readDB.GetContext(ctx, &exists, query, namespace, shortCode)
 	if err != nil {
-		return false, r.handlePostgreSQLError(err, "check URL existence")
+		return false, r.handlePostgreSQLError(ctx, err, "check URL existence")
 	}
 
 	return exists, nil
 }
 
-// handlePostgreSQLError converts PostgreSQL-specific errors to domain errors
-func (r *URLRepository) handlePostgreSQLError(err error, operation string) error {
+// List returns up to limit URLs with an ID greater than afterID, in ID order.
+// Passing the last ID of a page as afterID fetches the next one.
+func (r *URLRepository) List(ctx context.Context, afterID int64, limit int) ([]*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE id > $1 ORDER BY id ASC LIMIT $2`
+
+	urls := []*domain.URL{}
+	if err := r.readDB.SelectContext(ctx, &urls, query, afterID, limit); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "list URLs")
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) ListByCreatorIP(ctx context.Context, ip string, afterID int64, limit int) ([]*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE created_by_ip = $1 AND id > $2 ORDER BY id ASC LIMIT $3`
+
+	urls := []*domain.URL{}
+	if err := r.readDB.SelectContext(ctx, &urls, query, ip, afterID, limit); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "list URLs by creator")
+	}
+
+	return urls, nil
+}
+
+// Search matches the query as a substring, and with trigramSearch set by similarity as
+// well. The trigram indexes of migration 025 serve both kinds of match.
+func (r *URLRepository) Search(ctx context.Context, query string, limit int) ([]*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	match := `original_url ILIKE $1 OR short_code ILIKE $1 OR description ILIKE $1`
+	args := []any{containsPattern(query), limit}
+	if r.trigramSearch {
+		match = `original_url % $3 OR ` + match
+		args = append(args, query)
+	}
+	sqlQuery := `SELECT ` + urlColumns + ` FROM urls WHERE ` + match + ` ORDER BY id ASC LIMIT $2`
+
+	urls := []*domain.URL{}
+	if err := r.readDB.SelectContext(ctx, &urls, sqlQuery, args...); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "search URLs")
+	}
+
+	return urls, nil
+}
+
+// containsPattern is a LIKE pattern matching the values containing s, its wildcards
+// matched literally
+// nullableIP passes an empty address as NULL. The parameter takes the inet type of its
+// column, which the empty string is not valid for.
+func nullableIP(ip string) any {
+	if ip == "" {
+		return nil
+	}
+	return ip
+}
+
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ListForHealthCheck returns up to limit URLs, never-checked ones first, then the most stale
+func (r *URLRepository) ListForHealthCheck(ctx context.Context, limit int) ([]*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + urlColumns + ` FROM urls ORDER BY last_checked_at ASC NULLS FIRST, id ASC LIMIT $1`
+
+	var urls []*domain.URL
+	if err := r.readDB.SelectContext(ctx, &urls, query, limit); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "list URLs for health check")
+	}
+
+	return urls, nil
+}
+
+// FindExpired reads from writeDB, so URLs deleted by a previous batch are never returned again
+func (r *URLRepository) FindExpired(ctx context.Context, before time.Time, limit int) ([]*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE expires_at IS NOT NULL AND expires_at < $1 ORDER BY id ASC LIMIT $2`
+
+	urls := []*domain.URL{}
+	if err := r.writeDB.SelectContext(ctx, &urls, query, before, limit); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "find expired URLs")
+	}
+
+	return urls, nil
+}
+
+// FindNotAccessedSince reads from writeDB, so URLs disabled by a previous batch are never
+// returned again
+func (r *URLRepository) FindNotAccessedSince(ctx context.Context, since time.Time, limit int) ([]*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + urlColumns + ` FROM urls
+		WHERE enabled AND COALESCE(last_accessed_at, created_at) < $1
+		ORDER BY COALESCE(last_accessed_at, created_at) ASC, id ASC LIMIT $2`
+
+	urls := []*domain.URL{}
+	if err := r.writeDB.SelectContext(ctx, &urls, query, since, limit); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "find cold URLs")
+	}
+
+	return urls, nil
+}
+
+// TopByClicks returns the n most clicked public URLs, ties broken by creation order
+func (r *URLRepository) TopByClicks(ctx context.Context, n int) ([]*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + urlColumns + ` FROM urls
+		WHERE enabled AND NOT signed AND password_hash = ''
+		ORDER BY clicks DESC, id ASC LIMIT $1`
+
+	urls := []*domain.URL{}
+	if err := r.readDB.SelectContext(ctx, &urls, query, n); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "list top URLs")
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) UpdateHealthStatus(ctx context.Context, namespace, shortCode, status string, checkedAt time.Time) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE urls SET health_status = $1, last_checked_at = $2 WHERE namespace = $3 AND short_code = $4`
+
+	result, err := r.writeDB.ExecContext(ctx, query, status, checkedAt, namespace, shortCode)
+	if err != nil {
+		return r.handlePostgreSQLError(ctx, err, "update health status")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (r *URLRepository) SetEnabled(ctx context.Context, namespace, shortCode string, enabled bool) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE urls SET enabled = $1 WHERE namespace = $2 AND short_code = $3`
+
+	result, err := r.writeDB.ExecContext(ctx, query, enabled, namespace, shortCode)
+	if err != nil {
+		return r.handlePostgreSQLError(ctx, err, "set enabled")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (r *URLRepository) MarkGoalReached(ctx context.Context, namespace, shortCode string) (bool, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE urls SET goal_reached = TRUE WHERE namespace = $1 AND short_code = $2 AND NOT goal_reached`
+
+	result, err := r.writeDB.ExecContext(ctx, query, namespace, shortCode)
+	if err != nil {
+		return false, r.handlePostgreSQLError(ctx, err, "mark goal reached")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *URLRepository) RecordClick(ctx context.Context, click *domain.Click) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO url_clicks (namespace, short_code, clicked_at, referer, ua_browser, ua_os, ua_device_type, variant_id, target_url, redirect_duration_ms, ip_address, user_agent, country)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, '')::inet, $12, $13)
+	`
+
+	if _, err := r.writeDB.ExecContext(ctx, query, click.Namespace, click.ShortCode, click.ClickedAt, click.Referer, click.Browser, click.OS, click.DeviceType, click.VariantID, click.TargetURL, click.RedirectDurationMs, click.IPAddress, click.UserAgent, click.Country); err != nil {
+		return r.handlePostgreSQLError(ctx, err, "record click")
+	}
+
+	return nil
+}
+
+func (r *URLRepository) ListClicks(ctx context.Context, namespace, shortCode string, after domain.ClickCursor, limit int) ([]*domain.Click, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + clickColumns + ` FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2 AND (clicked_at, id) > ($3, $4)
+		ORDER BY clicked_at ASC, id ASC LIMIT $5`
+
+	clicks := []*domain.Click{}
+	if err := r.readDB.SelectContext(ctx, &clicks, query, namespace, shortCode, after.ClickedAt, after.ID, limit); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "list clicks")
+	}
+
+	return clicks, nil
+}
+
+func (r *URLRepository) ResetClicks(ctx context.Context, namespace, shortCode string) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.writeDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return r.handlePostgreSQLError(ctx, err, "begin reset clicks")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `UPDATE urls SET clicks = 0, unique_clicks = 0, updated_at = NOW() WHERE namespace = $1 AND short_code = $2`
+	result, err := tx.ExecContext(ctx, query, namespace, shortCode)
+	if err != nil {
+		return r.handlePostgreSQLError(ctx, err, "reset clicks")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM url_clicks WHERE namespace = $1 AND short_code = $2`, namespace, shortCode); err != nil {
+		return r.handlePostgreSQLError(ctx, err, "delete clicks")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.handlePostgreSQLError(ctx, err, "commit reset clicks")
+	}
+
+	r.logger.Debug("Clicks reset", "namespace", namespace, "short_code", shortCode)
+	return nil
+}
+
+// Delete removes a URL; its variants and clicks go with it through ON DELETE CASCADE
+func (r *URLRepository) Delete(ctx context.Context, namespace, shortCode string) error {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := r.writeDB.ExecContext(ctx, `DELETE FROM urls WHERE namespace = $1 AND short_code = $2`, namespace, shortCode)
+	if err != nil {
+		return r.handlePostgreSQLError(ctx, err, "delete URL")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	r.logger.Debug("URL deleted", "namespace", namespace, "short_code", shortCode)
+	return nil
+}
+
+// DeleteMany removes the URLs of namespace with any of shortCodes in a single statement. The
+// variants and recorded clicks of each go with it by cascade.
+func (r *URLRepository) DeleteMany(ctx context.Context, namespace string, shortCodes []string) ([]*domain.URL, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM urls WHERE namespace = $1 AND short_code = ANY($2) RETURNING ` + urlColumns
+
+	urls := []*domain.URL{}
+	if err := r.writeDB.SelectContext(ctx, &urls, query, namespace, pq.Array(shortCodes)); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "delete URLs")
+	}
+
+	r.logger.Debug("URLs deleted", "namespace", namespace, "count", len(urls))
+	return urls, nil
+}
+
+// ClickTimeSeries aggregates recorded clicks into UTC buckets. Analytics tolerate replica lag,
+// so this is served by readDB.
+func (r *URLRepository) ClickTimeSeries(ctx context.Context, namespace, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.TimeBucket, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT date_trunc($1, clicked_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS period, COUNT(*) AS clicks
+		FROM url_clicks
+		WHERE namespace = $2 AND short_code = $3 AND clicked_at BETWEEN $4 AND $5
+		GROUP BY period
+		ORDER BY period
+	`
+
+	buckets := []domain.TimeBucket{}
+	err := r.readDB.SelectContext(ctx, &buckets, query, string(granularity), namespace, shortCode, from, to)
+	if err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "click time series")
+	}
+
+	return buckets, nil
+}
+
+// heatmapRow counts the clicks of one local hour and weekday
+type heatmapRow struct {
+	Hour   int `db:"hour"`
+	Dow    int `db:"dow"`
+	Clicks int `db:"clicks"`
+}
+
+// ClickHeatmap converts clicked_at to loc in the database, which shares the IANA zone names
+// of time.LoadLocation. Analytics tolerate replica lag, so this is served by readDB.
+func (r *URLRepository) ClickHeatmap(ctx context.Context, namespace, shortCode string, loc *time.Location) (*domain.ClickHeatmap, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT EXTRACT(hour FROM clicked_at AT TIME ZONE $1)::int AS hour,
+			EXTRACT(dow FROM clicked_at AT TIME ZONE $1)::int AS dow,
+			COUNT(*) AS clicks
+		FROM url_clicks
+		WHERE namespace = $2 AND short_code = $3
+		GROUP BY 1, 2
+	`
+
+	var rows []heatmapRow
+	if err := r.readDB.SelectContext(ctx, &rows, query, loc.String(), namespace, shortCode); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "click heatmap")
+	}
+
+	heatmap := &domain.ClickHeatmap{}
+	for _, row := range rows {
+		heatmap.HourOfDay[row.Hour] += row.Clicks
+		heatmap.DayOfWeek[row.Dow] += row.Clicks
+	}
+	return heatmap, nil
+}
+
+func (r *URLRepository) TopReferrers(ctx context.Context, namespace, shortCode string, limit int) ([]domain.ReferrerCount, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT referer, COUNT(*) AS count
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2
+		GROUP BY referer
+		ORDER BY count DESC, referer ASC
+		LIMIT $3
+	`
+
+	referrers := []domain.ReferrerCount{}
+	if err := r.readDB.SelectContext(ctx, &referrers, query, namespace, shortCode, limit); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "top referrers")
+	}
+
+	return referrers, nil
+}
+
+func (r *URLRepository) TopIPs(ctx context.Context, limit int) ([]domain.IPCount, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT host(ip_address) AS ip_address, COUNT(*) AS count
+		FROM url_clicks
+		WHERE ip_address IS NOT NULL
+		GROUP BY ip_address
+		ORDER BY count DESC, ip_address ASC
+		LIMIT $1
+	`
+
+	ips := []domain.IPCount{}
+	if err := r.readDB.SelectContext(ctx, &ips, query, limit); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "top IPs")
+	}
+
+	return ips, nil
+}
+
+// deviceGroupColumns maps each device grouping to the url_clicks columns it aggregates by
+var deviceGroupColumns = map[domain.DeviceGrouping]string{
+	domain.DeviceGroupingDevice:  "ua_device_type, ua_browser, ua_os",
+	domain.DeviceGroupingBrowser: "ua_browser",
+	domain.DeviceGroupingOS:      "ua_os",
+}
+
+func (r *URLRepository) DeviceStats(ctx context.Context, namespace, shortCode string, grouping domain.DeviceGrouping) ([]domain.DeviceStat, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	columns, ok := deviceGroupColumns[grouping]
+	if !ok {
+		return nil, fmt.Errorf("unsupported device grouping %q", grouping)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %[1]s, COUNT(*) AS count
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2
+		GROUP BY %[1]s
+		ORDER BY count DESC, %[1]s
+	`, columns)
+
+	stats := []domain.DeviceStat{}
+	if err := r.readDB.SelectContext(ctx, &stats, query, namespace, shortCode); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "device stats")
+	}
+
+	return stats, nil
+}
+
+// VariantStats counts the clicks sent to each variant of a URL, including variants never picked
+func (r *URLRepository) VariantStats(ctx context.Context, namespace, shortCode string) ([]domain.VariantStat, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT v.id AS variant_id, v.original_url, v.weight, COUNT(c.id) AS clicks
+		FROM url_variants v
+		JOIN urls u ON u.id = v.url_id
+		LEFT JOIN url_clicks c ON c.variant_id = v.id
+		WHERE u.namespace = $1 AND u.short_code = $2
+		GROUP BY v.id, v.original_url, v.weight
+		ORDER BY v.id ASC
+	`
+
+	stats := []domain.VariantStat{}
+	if err := r.readDB.SelectContext(ctx, &stats, query, namespace, shortCode); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "variant stats")
+	}
+
+	return stats, nil
+}
+
+// RedirectLatency computes the percentiles of recorded redirect durations in the database,
+// served by readDB
+func (r *URLRepository) RedirectLatency(ctx context.Context, namespace, shortCode string) (*domain.LatencyStats, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY redirect_duration_ms), 0) AS p50,
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY redirect_duration_ms), 0) AS p95,
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY redirect_duration_ms), 0) AS p99,
+			COALESCE(AVG(redirect_duration_ms), 0) AS avg,
+			COUNT(redirect_duration_ms) AS count
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2 AND redirect_duration_ms IS NOT NULL
+	`
+
+	var stats domain.LatencyStats
+	if err := r.readDB.GetContext(ctx, &stats, query, namespace, shortCode); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "redirect latency")
+	}
+
+	return &stats, nil
+}
+
+func (r *URLRepository) Stats(ctx context.Context, now, since time.Time) (*domain.ServiceStats, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT
+			COUNT(*) AS total_urls,
+			COALESCE(SUM(clicks), 0) AS total_clicks,
+			COUNT(*) FILTER (WHERE expires_at IS NULL OR expires_at > $1) AS active_urls,
+			COUNT(*) FILTER (WHERE created_at > $2) AS recent_urls,
+			(SELECT COUNT(*) FROM url_clicks WHERE clicked_at > $2) AS recent_clicks
+		FROM urls
+	`
+
+	var stats domain.ServiceStats
+	if err := r.readDB.GetContext(ctx, &stats, query, now, since); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "service stats")
+	}
+
+	return &stats, nil
+}
+
+func (r *URLRepository) CountActive(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM urls WHERE enabled AND (expires_at IS NULL OR expires_at > $1)`
+
+	var count int64
+	if err := r.readDB.GetContext(ctx, &count, query, now); err != nil {
+		return 0, r.handlePostgreSQLError(ctx, err, "count active")
+	}
+
+	return count, nil
+}
+
+// CountByCreatedDate truncates created_at to its UTC day, as indexed by migration 029
+func (r *URLRepository) CountByCreatedDate(ctx context.Context, date time.Time) (int, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM urls WHERE (created_at AT TIME ZONE 'UTC')::date = $1::date`
+
+	var count int
+	if err := r.readDB.GetContext(ctx, &count, query, date.UTC().Format(time.DateOnly)); err != nil {
+		return 0, r.handlePostgreSQLError(ctx, err, "count by created date")
+	}
+
+	return count, nil
+}
+
+func (r *URLRepository) DailyActivity(ctx context.Context, from, to time.Time) ([]domain.DailyActivity, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT day::timestamp AT TIME ZONE 'UTC' AS day, SUM(urls_created) AS urls_created, SUM(clicks) AS clicks
+		FROM (
+			SELECT (created_at AT TIME ZONE 'UTC')::date AS day, 1 AS urls_created, 0 AS clicks
+			FROM urls
+			WHERE (created_at AT TIME ZONE 'UTC')::date BETWEEN $1::date AND $2::date
+			UNION ALL
+			SELECT (clicked_at AT TIME ZONE 'UTC')::date, 0, 1
+			FROM url_clicks
+			WHERE (clicked_at AT TIME ZONE 'UTC')::date BETWEEN $1::date AND $2::date
+		) activity
+		GROUP BY day
+		ORDER BY day
+	`
+
+	days := []domain.DailyActivity{}
+	err := r.readDB.SelectContext(ctx, &days, query, from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "daily activity")
+	}
+
+	return days, nil
+}
+
+func (r *URLRepository) AliasLengths(ctx context.Context) ([]domain.AliasLengthCount, error) {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT is_custom_alias AS custom, LENGTH(short_code) AS length, COUNT(*) AS urls
+		FROM urls
+		GROUP BY is_custom_alias, LENGTH(short_code)
+		ORDER BY is_custom_alias, length
+	`
+
+	counts := []domain.AliasLengthCount{}
+	if err := r.readDB.SelectContext(ctx, &counts, query); err != nil {
+		return nil, r.handlePostgreSQLError(ctx, err, "alias lengths")
+	}
+
+	return counts, nil
+}
+
+// handlePostgreSQLError converts PostgreSQL-specific errors to domain errors. ctx is the
+// context the query ran under, telling a timeout apart from a caller that went away.
+func (r *URLRepository) handlePostgreSQLError(ctx context.Context, err error, operation string) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		r.logger.Error("PostgreSQL query timed out", "operation", operation, "timeout", r.queryTimeout)
+		return fmt.Errorf("%w: %s", domain.ErrQueryTimeout, operation)
+	}
+
+	// lib/pq cancels the query with query_canceled once ctx is done, whether its deadline
+	// passed or the caller went away, such as a client disconnecting
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "57014" && ctx.Err() != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			r.logger.Error("PostgreSQL query timed out", "operation", operation, "timeout", r.queryTimeout)
+			return fmt.Errorf("%w: %s", domain.ErrQueryTimeout, operation)
+		}
+		return ctx.Err()
+	}
+
 	if pqErr, ok := err.(*pq.Error); ok {
 		r.logger.Error("PostgreSQL error",
 			"operation", operation,
@@ -98,7 +915,7 @@ func (r *URLRepository) handlePostgreSQLError(err error, operation string) error
 
 		switch pqErr.Code {
 		case "23505": // unique_violation
-			if pqErr.Constraint == "urls_short_code_key" {
+			if pqErr.Constraint == "urls_namespace_short_code_key" {
 				return domain.ErrShortCodeExists
 			}
 			return fmt.Errorf("unique constraint violation: %s", pqErr.Detail)
@@ -121,16 +938,40 @@ func (r *URLRepository) handlePostgreSQLError(err error, operation string) error
 	return err
 }
 
+// DB returns the primary connection pool, the one writes go through
+func (r *URLRepository) DB() *sql.DB {
+	return r.writeDB.DB
+}
+
 func (r *URLRepository) Close() error {
-	if r.db != nil {
-		return r.db.Close()
+	var errs []error
+	if r.readDB != nil && r.readDB != r.writeDB {
+		if err := r.readDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close replica: %w", err))
+		}
 	}
-	return nil
+	if r.writeDB != nil {
+		if err := r.writeDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close primary: %w", err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 func (r *URLRepository) HealthCheck(ctx context.Context) error {
-	if r.db == nil {
+	ctx, cancel := r.withQueryTimeout(ctx)
+	defer cancel()
+
+	if r.writeDB == nil || r.readDB == nil {
 		return errors.New("database connection is nil")
 	}
-	return r.db.PingContext(ctx)
+	if err := r.writeDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("primary unreachable: %w", err)
+	}
+	if r.readDB != r.writeDB {
+		if err := r.readDB.PingContext(ctx); err != nil {
+			return fmt.Errorf("replica unreachable: %w", err)
+		}
+	}
+	return nil
 }
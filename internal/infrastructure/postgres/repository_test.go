@@ -0,0 +1,413 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// The queries in this repository only use $N placeholders and RETURNING, both of which
+// SQLite understands, so two SQLite files are enough to observe read/write routing.
+const testSchema = `
+	CREATE TABLE namespaces (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		slug TEXT UNIQUE NOT NULL,
+		owner_id TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE urls (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		namespace TEXT NOT NULL DEFAULT 'default',
+		short_code TEXT NOT NULL,
+		original_url TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		clicks INTEGER DEFAULT 0,
+		password_hash TEXT NOT NULL DEFAULT '',
+		health_status TEXT NOT NULL DEFAULT 'unknown',
+		last_checked_at DATETIME,
+		pool_enabled BOOLEAN NOT NULL DEFAULT 0,
+		pool TEXT,
+		signed BOOLEAN NOT NULL DEFAULT 0,
+		geo_routes TEXT,
+		device_routes TEXT,
+		redirect_type INTEGER NOT NULL DEFAULT 0,
+		expires_at DATETIME,
+		tags TEXT,
+		delay_seconds INTEGER NOT NULL DEFAULT 0,
+		unique_clicks INTEGER NOT NULL DEFAULT 0,
+		description TEXT,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		last_accessed_at DATETIME,
+		is_custom_alias BOOLEAN NOT NULL DEFAULT 0,
+		click_goal INTEGER,
+		goal_reached BOOLEAN NOT NULL DEFAULT 0,
+		created_by_ip TEXT,
+		title TEXT,
+		UNIQUE (namespace, short_code)
+	);
+	CREATE TABLE url_variants (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url_id INTEGER NOT NULL,
+		original_url TEXT NOT NULL,
+		weight INTEGER NOT NULL
+	)
+`
+
+// The test schema stores inet columns as text, so PostgreSQL's host() has nothing to strip
+func init() {
+	sql.Register("sqlite3_postgres", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("host", func(addr any) any { return addr }, true)
+		},
+	})
+}
+
+func openTestDB(t *testing.T, name string) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlx.Connect("sqlite3_postgres", filepath.Join(t.TempDir(), name))
+	require.NoError(t, err)
+	_, err = db.Exec(testSchema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestURLRepository_ReadReplicaSplit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	primary := openTestDB(t, "primary.db")
+	replica := openTestDB(t, "replica.db")
+
+	repo := NewURLRepositoryWithReplica(primary, replica, logger, 0, false, false)
+	t.Cleanup(func() { _ = repo.Close() })
+	ctx := context.Background()
+
+	url, err := domain.NewURL("split", "https://example.com/split")
+	require.NoError(t, err)
+
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	// The write landed on the primary only
+	var primaryCount, replicaCount int
+	require.NoError(t, primary.Get(&primaryCount, "SELECT COUNT(*) FROM urls"))
+	require.NoError(t, replica.Get(&replicaCount, "SELECT COUNT(*) FROM urls"))
+	assert.Equal(t, 1, primaryCount)
+	assert.Equal(t, 0, replicaCount)
+
+	// Lookups are served by the replica, which has not caught up yet
+	_, err = repo.FindByShortCode(ctx, "split")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	exists, err := repo.Exists(ctx, domain.DefaultNamespace, "split")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Simulate replication and read again
+	_, err = replica.Exec(`INSERT INTO urls (short_code, original_url) VALUES ($1, $2)`, "split", "https://example.com/split")
+	require.NoError(t, err)
+
+	found, err := repo.FindByShortCode(ctx, "split")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/split", found.OriginalURL)
+
+	exists, err = repo.Exists(ctx, domain.DefaultNamespace, "split")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Click increments are mutations and must hit the primary
+	updated, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, "split", true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated.Clicks)
+
+	var replicaClicks int
+	require.NoError(t, replica.Get(&replicaClicks, "SELECT clicks FROM urls WHERE short_code = 'split'"))
+	assert.Equal(t, 0, replicaClicks)
+
+	require.NoError(t, repo.HealthCheck(ctx))
+}
+
+func TestURLRepository_WithoutReplicaSharesConnection(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	db := openTestDB(t, "single.db")
+
+	repo := NewURLRepository(db, logger, 0, false, false)
+	t.Cleanup(func() { _ = repo.Close() })
+	ctx := context.Background()
+
+	assert.Same(t, repo.writeDB, repo.readDB)
+
+	url, err := domain.NewURL("single", "https://example.com/single")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	found, err := repo.FindByShortCode(ctx, "single")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/single", found.OriginalURL)
+
+	require.NoError(t, repo.HealthCheck(ctx))
+}
+
+func TestURLRepository_CreatedByIP(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := NewURLRepository(openTestDB(t, "creator.db"), logger, 0, false, false)
+	t.Cleanup(func() { _ = repo.Close() })
+	ctx := context.Background()
+
+	for shortCode, ip := range map[string]string{"tracked": "203.0.113.7", "anonymous": ""} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		url.CreatedByIP = ip
+		created, err := repo.Create(ctx, url)
+		require.NoError(t, err)
+		assert.Equal(t, ip, created.CreatedByIP)
+	}
+
+	var createdBy sql.NullString
+	require.NoError(t, repo.writeDB.GetContext(ctx, &createdBy, `SELECT created_by_ip FROM urls WHERE short_code = 'anonymous'`))
+	assert.False(t, createdBy.Valid, "an unknown creator is stored as NULL")
+
+	urls, err := repo.ListByCreatorIP(ctx, "203.0.113.7", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "tracked", urls[0].ShortCode)
+	assert.Equal(t, "203.0.113.7", urls[0].CreatedByIP)
+}
+
+func TestURLRepository_AddClicks(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name           string
+		disablePrepare bool
+	}{
+		{name: "prepared statement", disablePrepare: false},
+		{name: "without prepare", disablePrepare: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewURLRepository(openTestDB(t, "clicks.db"), logger, 0, false, tt.disablePrepare)
+			t.Cleanup(func() { _ = repo.Close() })
+			ctx := context.Background()
+
+			for _, shortCode := range []string{"first", "second"} {
+				url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+				require.NoError(t, err)
+				_, err = repo.Create(ctx, url)
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, repo.AddClicks(ctx, []domain.ClickDelta{
+				{Namespace: domain.DefaultNamespace, ShortCode: "first", Clicks: 3, UniqueClicks: 2},
+				{Namespace: domain.DefaultNamespace, ShortCode: "second", Clicks: 1, UniqueClicks: 1},
+			}))
+
+			first, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "first")
+			require.NoError(t, err)
+			assert.Equal(t, 3, first.Clicks)
+			assert.Equal(t, 2, first.UniqueClicks)
+			second, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "second")
+			require.NoError(t, err)
+			assert.Equal(t, 1, second.Clicks)
+		})
+	}
+}
+
+func TestAuditRepository(t *testing.T) {
+	db := openTestDB(t, "audit.db")
+	_, err := db.Exec(`CREATE TABLE url_audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recorded_at DATETIME NOT NULL,
+		operation TEXT NOT NULL,
+		namespace TEXT NOT NULL,
+		short_code TEXT NOT NULL,
+		actor_ip TEXT NOT NULL,
+		request_id TEXT NOT NULL,
+		old_value TEXT,
+		new_value TEXT
+	)`)
+	require.NoError(t, err)
+	repo := NewAuditRepository(db)
+	ctx := context.Background()
+
+	recordedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []domain.AuditEntry{
+		{Timestamp: recordedAt, Operation: "create", NewValue: []byte(`{"originalUrl":"https://example.com"}`)},
+		{Timestamp: recordedAt.Add(time.Minute), Operation: "update", OldValue: []byte(`{"originalUrl":"https://example.com"}`), NewValue: []byte(`{"originalUrl":"https://example.org"}`)},
+		{Timestamp: recordedAt.Add(2 * time.Minute), Operation: "delete", OldValue: []byte(`{"originalUrl":"https://example.org"}`)},
+	}
+	for _, entry := range entries {
+		entry.Namespace, entry.ShortCode, entry.ActorIP, entry.RequestID = domain.DefaultNamespace, "audited", "192.0.2.1", "req-1"
+		require.NoError(t, repo.Record(ctx, entry))
+	}
+	require.NoError(t, repo.Record(ctx, domain.AuditEntry{Timestamp: recordedAt, Operation: "create", Namespace: "team", ShortCode: "audited"}))
+
+	listed, err := repo.ListByShortCode(ctx, domain.DefaultNamespace, "audited", 10)
+	require.NoError(t, err)
+	require.Len(t, listed, 3)
+	assert.Equal(t, "delete", listed[0].Operation)
+	assert.Nil(t, listed[0].NewValue, "NULL reads as a missing value")
+	assert.JSONEq(t, `{"originalUrl":"https://example.org"}`, string(listed[0].OldValue))
+	assert.Equal(t, "update", listed[1].Operation)
+	assert.Equal(t, "create", listed[2].Operation)
+	assert.Nil(t, listed[2].OldValue)
+	assert.Equal(t, "192.0.2.1", listed[2].ActorIP)
+	assert.True(t, recordedAt.Equal(listed[2].Timestamp))
+
+	listed, err = repo.ListByShortCode(ctx, domain.DefaultNamespace, "audited", 1)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "delete", listed[0].Operation)
+}
+
+func TestURLRepository_HealthCheckFailsWhenReplicaDown(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	primary := openTestDB(t, "primary.db")
+	replica := openTestDB(t, "replica.db")
+
+	repo := NewURLRepositoryWithReplica(primary, replica, logger, 0, false, false)
+	t.Cleanup(func() { _ = primary.Close() })
+
+	require.NoError(t, replica.Close())
+
+	err := repo.HealthCheck(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "replica")
+}
+
+// blockingConnector hands out connections whose every statement takes delay, unless the
+// context gives up first
+type blockingConnector struct {
+	delay time.Duration
+}
+
+func (c blockingConnector) Connect(context.Context) (driver.Conn, error) {
+	return blockingConn(c), nil
+}
+
+func (c blockingConnector) Driver() driver.Driver {
+	return nil
+}
+
+type blockingConn struct {
+	delay time.Duration
+}
+
+func (c blockingConn) wait(ctx context.Context) error {
+	select {
+	case <-time.After(c.delay):
+		return errors.New("query finished")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c blockingConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	return nil, c.wait(ctx)
+}
+
+func (c blockingConn) ExecContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Result, error) {
+	return nil, c.wait(ctx)
+}
+
+func (c blockingConn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	return nil, c.wait(ctx)
+}
+
+func (blockingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (blockingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("use BeginTx")
+}
+
+func (blockingConn) Close() error {
+	return nil
+}
+
+func TestURLRepository_QueryTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	db := sqlx.NewDb(sql.OpenDB(blockingConnector{delay: 200 * time.Millisecond}), "postgres")
+	repo := NewURLRepository(db, logger, 50*time.Millisecond, false, false)
+	t.Cleanup(func() { _ = repo.Close() })
+	ctx := context.Background()
+
+	url, err := domain.NewURL("slow", "https://example.com/slow")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		query func() error
+	}{
+		{"create", func() error { _, err := repo.Create(ctx, url); return err }},
+		{"find", func() error { _, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "slow"); return err }},
+		{"increment clicks", func() error { _, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, "slow", true); return err }},
+		{"list", func() error { _, err := repo.List(ctx, 0, 10); return err }},
+		{"record click", func() error {
+			return repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "slow", ClickedAt: time.Now()})
+		}},
+		{"stats", func() error { _, err := repo.Stats(ctx, time.Now(), time.Now().Add(-time.Hour)); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := tt.query()
+			assert.ErrorIs(t, err, domain.ErrQueryTimeout)
+			assert.Less(t, time.Since(start), 200*time.Millisecond)
+		})
+	}
+
+	t.Run("no timeout waits for the query", func(t *testing.T) {
+		repo := NewURLRepository(db, logger, 0, false, false)
+		_, err := repo.List(ctx, 0, 10)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, domain.ErrQueryTimeout)
+	})
+}
+
+func TestURLRepository_HandleQueryCanceled(t *testing.T) {
+	repo := NewURLRepository(nil, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Second, false, false)
+	queryCanceled := &pq.Error{Code: "57014", Message: "canceling statement due to user request"}
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("deadline passed", func(t *testing.T) {
+		err := repo.handlePostgreSQLError(expired, queryCanceled, "test")
+		assert.ErrorIs(t, err, domain.ErrQueryTimeout)
+	})
+
+	t.Run("caller went away", func(t *testing.T) {
+		err := repo.handlePostgreSQLError(canceled, queryCanceled, "test")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.NotErrorIs(t, err, domain.ErrQueryTimeout)
+	})
+
+	t.Run("canceled by the server", func(t *testing.T) {
+		err := repo.handlePostgreSQLError(context.Background(), queryCanceled, "test")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, domain.ErrQueryTimeout)
+		assert.NotErrorIs(t, err, context.Canceled)
+	})
+}
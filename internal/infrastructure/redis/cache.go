@@ -2,31 +2,81 @@ package redis
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/big"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
 	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/telemetry"
+)
+
+// GetOrSet takes a populate lock on a miss so that only one caller, across every instance,
+// fetches a URL at a time. The others poll for the entry it stores, and fetch it
+// themselves once the lock is released without one or populateWait has passed.
+const (
+	populateLockTTL  = 5 * time.Second
+	populateWait     = time.Second
+	populateInterval = 10 * time.Millisecond
 )
 
 type RedisCache struct {
-	client *redis.Client
-	logger *slog.Logger
+	client        *redis.Client
+	prefix        string
+	staleTTL      time.Duration
+	jitterPercent float64
+	logger        *slog.Logger
 }
 
-func NewRedisCache(client *redis.Client, logger *slog.Logger) *RedisCache {
+// NewRedisCache creates a cache whose keys all start with prefix. It also keeps a copy of
+// every URL for staleTTL, served by GetStale once the regular entry has expired. A zero
+// staleTTL keeps no copies. URL entries expire within jitterPercent of their TTL, either
+// way, so that URLs cached together do not all expire together; 0 keeps the TTL exact.
+func NewRedisCache(client *redis.Client, prefix string, staleTTL time.Duration, jitterPercent float64, logger *slog.Logger) *RedisCache {
 	return &RedisCache{
-		client: client,
-		logger: logger,
+		client:        client,
+		prefix:        prefix,
+		staleTTL:      staleTTL,
+		jitterPercent: jitterPercent,
+		logger:        logger,
+	}
+}
+
+// withJitter moves ttl by a uniformly random amount of up to jitterPct percent of it,
+// either way. The TTL is kept as is when the random source fails.
+func withJitter(ttl time.Duration, jitterPct float64) time.Duration {
+	spread := int64(float64(ttl) * jitterPct / 100)
+	if spread <= 0 {
+		return ttl
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(2*spread+1))
+	if err != nil {
+		return ttl
+	}
+	return ttl + time.Duration(n.Int64()-spread)
+}
+
+func (c *RedisCache) Get(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
+	return c.get(ctx, c.buildKey(namespace, shortCode))
+}
+
+func (c *RedisCache) GetStale(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
+	if c.staleTTL <= 0 {
+		return nil, nil
 	}
+	return c.get(ctx, c.buildStaleKey(namespace, shortCode))
 }
 
-func (c *RedisCache) Get(ctx context.Context, shortCode string) (*domain.URL, error) {
-	key := c.buildKey(shortCode)
+func (c *RedisCache) get(ctx context.Context, key string) (*domain.URL, error) {
+	ctx, span := telemetry.StartCacheSpan(ctx, "Get", "GET")
+	defer span.End()
 
 	val, err := c.client.Get(ctx, key).Result()
 	if err != nil {
@@ -38,7 +88,8 @@ func (c *RedisCache) Get(ctx context.Context, shortCode string) (*domain.URL, er
 		return nil, fmt.Errorf("cache get failed: %w", err)
 	}
 
-	var url domain.URL
+	// Entries cached before URLs could be disabled have no enabled field
+	url := domain.URL{Enabled: true}
 	if err := json.Unmarshal([]byte(val), &url); err != nil {
 		c.logger.Error("Failed to unmarshal cached value", "key", key, "error", err)
 		return nil, fmt.Errorf("failed to unmarshal cached value: %w", err)
@@ -47,8 +98,118 @@ func (c *RedisCache) Get(ctx context.Context, shortCode string) (*domain.URL, er
 	return &url, nil
 }
 
+// GetMulti reads every key in one pipeline. An entry that cannot be decoded counts as a miss.
+func (c *RedisCache) GetMulti(ctx context.Context, namespace string, shortCodes []string) (map[string]*domain.URL, error) {
+	urls := make(map[string]*domain.URL, len(shortCodes))
+	if len(shortCodes) == 0 {
+		return urls, nil
+	}
+
+	ctx, span := telemetry.StartCacheSpan(ctx, "GetMulti", "GET")
+	defer span.End()
+
+	cmds := make([]*redis.StringCmd, len(shortCodes))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, shortCode := range shortCodes {
+			cmds[i] = pipe.Get(ctx, c.buildKey(namespace, shortCode))
+		}
+		return nil
+	})
+	// Misses fail the pipeline with redis.Nil, they are told apart per command below
+	if err != nil && !errors.Is(err, redis.Nil) {
+		c.logger.Error("Failed to get from cache", "namespace", namespace, "count", len(shortCodes), "error", err)
+		return nil, fmt.Errorf("cache get failed: %w", err)
+	}
+
+	for i, cmd := range cmds {
+		val, err := cmd.Result()
+		if err != nil {
+			continue
+		}
+
+		// Entries cached before URLs could be disabled have no enabled field
+		url := domain.URL{Enabled: true}
+		if err := json.Unmarshal([]byte(val), &url); err != nil {
+			c.logger.Error("Failed to unmarshal cached value", "key", c.buildKey(namespace, shortCodes[i]), "error", err)
+			continue
+		}
+		urls[shortCodes[i]] = &url
+	}
+
+	return urls, nil
+}
+
+func (c *RedisCache) GetOrSet(ctx context.Context, namespace, shortCode string, ttl time.Duration, fetch func() (*domain.URL, error)) (*domain.URL, error) {
+	key := c.buildKey(namespace, shortCode)
+	url, err := c.get(ctx, key)
+	if err != nil {
+		// An unavailable cache must not fail the lookup
+		return fetch()
+	}
+	if url != nil {
+		return url, nil
+	}
+
+	lockKey := c.buildPopulateLockKey(namespace, shortCode)
+	locked, err := c.client.SetNX(ctx, lockKey, 1, populateLockTTL).Result()
+	if err != nil {
+		c.logger.Warn("Failed to take populate lock", "key", lockKey, "error", err)
+		return c.populate(ctx, ttl, fetch)
+	}
+	if !locked {
+		url, err := c.waitForPopulate(ctx, key, lockKey)
+		if err != nil || url != nil {
+			return url, err
+		}
+		return c.populate(ctx, ttl, fetch)
+	}
+
+	defer func() {
+		// A lock that fails to release expires after populateLockTTL anyway
+		_ = c.client.Del(context.WithoutCancel(ctx), lockKey).Err()
+	}()
+	return c.populate(ctx, ttl, fetch)
+}
+
+// populate stores the URL returned by fetch. A URL that cannot be cached is still returned.
+func (c *RedisCache) populate(ctx context.Context, ttl time.Duration, fetch func() (*domain.URL, error)) (*domain.URL, error) {
+	url, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	// Set logs its own failures
+	_ = c.Set(ctx, url, ttl)
+	return url, nil
+}
+
+// waitForPopulate polls key while another caller holds lockKey. It returns nil without an
+// error when the caller should fetch the URL itself.
+func (c *RedisCache) waitForPopulate(ctx context.Context, key, lockKey string) (*domain.URL, error) {
+	ticker := time.NewTicker(populateInterval)
+	defer ticker.Stop()
+	deadline := time.Now().Add(populateWait)
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		url, err := c.get(ctx, key)
+		if err != nil || url != nil {
+			return url, nil
+		}
+		// The holder is done but stored nothing, the URL may not exist or its fetch failed
+		if held, err := c.client.Exists(ctx, lockKey).Result(); err != nil || held == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
 func (c *RedisCache) Set(ctx context.Context, url *domain.URL, ttl time.Duration) error {
-	key := c.buildKey(url.ShortCode)
+	key := c.buildKey(url.Namespace, url.ShortCode)
 
 	data, err := json.Marshal(url)
 	if err != nil {
@@ -56,7 +217,17 @@ func (c *RedisCache) Set(ctx context.Context, url *domain.URL, ttl time.Duration
 		return fmt.Errorf("failed to marshal URL: %w", err)
 	}
 
-	if err := c.client.Set(ctx, key, data, ttl).Err(); err != nil {
+	ctx, span := telemetry.StartCacheSpan(ctx, "Set", "SET")
+	defer span.End()
+
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, withJitter(ttl, c.jitterPercent))
+		if c.staleTTL > 0 {
+			pipe.Set(ctx, c.buildStaleKey(url.Namespace, url.ShortCode), data, c.staleTTL)
+		}
+		return nil
+	})
+	if err != nil {
 		c.logger.Error("Failed to set cache", "key", key, "error", err)
 		return fmt.Errorf("cache set failed: %w", err)
 	}
@@ -64,10 +235,50 @@ func (c *RedisCache) Set(ctx context.Context, url *domain.URL, ttl time.Duration
 	return nil
 }
 
-func (c *RedisCache) Delete(ctx context.Context, shortCode string) error {
-	key := c.buildKey(shortCode)
+// SetMulti stores urls, and their stale copies, in one pipeline
+func (c *RedisCache) SetMulti(ctx context.Context, urls []*domain.URL, ttl time.Duration) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	entries := make([][]byte, len(urls))
+	for i, url := range urls {
+		data, err := json.Marshal(url)
+		if err != nil {
+			c.logger.Error("Failed to marshal URL for cache", "short_code", url.ShortCode, "error", err)
+			return fmt.Errorf("failed to marshal URL: %w", err)
+		}
+		entries[i] = data
+	}
+
+	ctx, span := telemetry.StartCacheSpan(ctx, "SetMulti", "SET")
+	defer span.End()
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, url := range urls {
+			pipe.Set(ctx, c.buildKey(url.Namespace, url.ShortCode), entries[i], withJitter(ttl, c.jitterPercent))
+			if c.staleTTL > 0 {
+				pipe.Set(ctx, c.buildStaleKey(url.Namespace, url.ShortCode), entries[i], c.staleTTL)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("Failed to set cache", "count", len(urls), "error", err)
+		return fmt.Errorf("cache set failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c *RedisCache) Delete(ctx context.Context, namespace, shortCode string) error {
+	key := c.buildKey(namespace, shortCode)
+
+	ctx, span := telemetry.StartCacheSpan(ctx, "Delete", "DEL")
+	defer span.End()
 
-	if err := c.client.Del(ctx, key).Err(); err != nil {
+	// The stale copy goes too, a changed or deleted URL must never be served from it
+	if err := c.client.Del(ctx, key, c.buildStaleKey(namespace, shortCode)).Err(); err != nil {
 		c.logger.Error("Failed to delete from cache", "key", key, "error", err)
 		return fmt.Errorf("cache delete failed: %w", err)
 	}
@@ -75,6 +286,101 @@ func (c *RedisCache) Delete(ctx context.Context, shortCode string) error {
 	return nil
 }
 
+// DeleteMulti removes the shortCodes of namespace, and their stale copies, in one pipeline
+func (c *RedisCache) DeleteMulti(ctx context.Context, namespace string, shortCodes []string) error {
+	if len(shortCodes) == 0 {
+		return nil
+	}
+
+	ctx, span := telemetry.StartCacheSpan(ctx, "DeleteMulti", "DEL")
+	defer span.End()
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, shortCode := range shortCodes {
+			pipe.Del(ctx, c.buildKey(namespace, shortCode), c.buildStaleKey(namespace, shortCode))
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("Failed to delete from cache", "namespace", namespace, "count", len(shortCodes), "error", err)
+		return fmt.Errorf("cache delete failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c *RedisCache) GetTopURLs(ctx context.Context, n int) ([]*domain.URL, error) {
+	key := c.buildTopURLsKey(n)
+
+	ctx, span := telemetry.StartCacheSpan(ctx, "GetTopURLs", "GET")
+	defer span.End()
+
+	val, err := c.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		c.logger.Error("Failed to get from cache", "key", key, "error", err)
+		return nil, fmt.Errorf("cache get failed: %w", err)
+	}
+
+	var urls []*domain.URL
+	if err := json.Unmarshal([]byte(val), &urls); err != nil {
+		c.logger.Error("Failed to unmarshal cached value", "key", key, "error", err)
+		return nil, fmt.Errorf("failed to unmarshal cached value: %w", err)
+	}
+
+	return urls, nil
+}
+
+func (c *RedisCache) SetTopURLs(ctx context.Context, n int, urls []*domain.URL, ttl time.Duration) error {
+	key := c.buildTopURLsKey(n)
+
+	data, err := json.Marshal(urls)
+	if err != nil {
+		c.logger.Error("Failed to marshal top URLs for cache", "n", n, "error", err)
+		return fmt.Errorf("failed to marshal top URLs: %w", err)
+	}
+
+	ctx, span := telemetry.StartCacheSpan(ctx, "SetTopURLs", "SET")
+	defer span.End()
+
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, ttl)
+		pipe.SAdd(ctx, c.buildTopURLsIndexKey(), n)
+		pipe.Expire(ctx, c.buildTopURLsIndexKey(), ttl)
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("Failed to set cache", "key", key, "error", err)
+		return fmt.Errorf("cache set failed: %w", err)
+	}
+
+	return nil
+}
+
+func (c *RedisCache) InvalidateTopURLs(ctx context.Context) error {
+	ctx, span := telemetry.StartCacheSpan(ctx, "InvalidateTopURLs", "SMEMBERS")
+	defer span.End()
+
+	sizes, err := c.client.SMembers(ctx, c.buildTopURLsIndexKey()).Result()
+	if err != nil {
+		c.logger.Error("Failed to read top URLs index", "error", err)
+		return fmt.Errorf("cache delete failed: %w", err)
+	}
+
+	keys := make([]string, 0, len(sizes)+1)
+	for _, n := range sizes {
+		keys = append(keys, c.prefix+":top_urls:"+n)
+	}
+	if err := c.client.Del(ctx, append(keys, c.buildTopURLsIndexKey())...).Err(); err != nil {
+		c.logger.Error("Failed to delete top URLs from cache", "error", err)
+		return fmt.Errorf("cache delete failed: %w", err)
+	}
+
+	return nil
+}
+
 func (c *RedisCache) Ping(ctx context.Context) error {
 	if err := c.client.Ping(ctx).Err(); err != nil {
 		c.logger.Error("Failed to ping Redis", "error", err)
@@ -83,6 +389,45 @@ func (c *RedisCache) Ping(ctx context.Context) error {
 	return nil
 }
 
-func (c *RedisCache) buildKey(shortCode string) string {
-	return fmt.Sprintf("url:%s", shortCode)
+// Keys scans with SCAN rather than KEYS, which would block Redis for as long as it takes to
+// walk the whole keyspace. count is only a hint of how many keys SCAN inspects.
+func (c *RedisCache) Keys(ctx context.Context, pattern string, cursor uint64, count int) ([]string, uint64, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	prefix := c.prefix + ":"
+	keys, next, err := c.client.Scan(ctx, cursor, globReplacer.Replace(prefix)+pattern, int64(count)).Result()
+	if err != nil {
+		c.logger.Error("Failed to scan cache keys", "pattern", pattern, "error", err)
+		return nil, 0, fmt.Errorf("cache scan failed: %w", err)
+	}
+
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, prefix)
+	}
+	return keys, next, nil
+}
+
+func (c *RedisCache) buildKey(namespace, shortCode string) string {
+	return fmt.Sprintf("%s:url:%s:%s", c.prefix, namespace, shortCode)
+}
+
+func (c *RedisCache) buildStaleKey(namespace, shortCode string) string {
+	return fmt.Sprintf("%s:stale:url:%s:%s", c.prefix, namespace, shortCode)
+}
+
+func (c *RedisCache) buildPopulateLockKey(namespace, shortCode string) string {
+	return fmt.Sprintf("%s:populate:url:%s:%s", c.prefix, namespace, shortCode)
+}
+
+func (c *RedisCache) buildTopURLsKey(n int) string {
+	return fmt.Sprintf("%s:top_urls:%d", c.prefix, n)
+}
+
+// buildTopURLsIndexKey names a set of the sizes of every ranking currently cached, so that
+// all rankings can be dropped together. Sizes rather than keys are kept so that the index
+// survives MigrateKeys.
+func (c *RedisCache) buildTopURLsIndexKey() string {
+	return c.prefix + ":top_urls:index"
 }
@@ -0,0 +1,420 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// testKeyPrefix starts every key written by the tests
+const testKeyPrefix = "dove"
+
+// recordingHook stands in for the Redis server: every command and pipeline succeeds
+// without a connection, and pipelines are recorded with their commands
+type recordingHook struct {
+	commands  []redis.Cmder
+	pipelines [][]redis.Cmder
+}
+
+func (h *recordingHook) DialHook(redis.DialHook) redis.DialHook {
+	return func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("unexpected dial")
+	}
+}
+
+func (h *recordingHook) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(_ context.Context, cmd redis.Cmder) error {
+		h.commands = append(h.commands, cmd)
+		return nil
+	}
+}
+
+func (h *recordingHook) ProcessPipelineHook(redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(_ context.Context, cmds []redis.Cmder) error {
+		h.pipelines = append(h.pipelines, cmds)
+		return nil
+	}
+}
+
+func newRecordingCache(t *testing.T, staleTTL time.Duration) (*RedisCache, *recordingHook) {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	t.Cleanup(func() { _ = client.Close() })
+	hook := &recordingHook{}
+	client.AddHook(hook)
+
+	return NewRedisCache(client, testKeyPrefix, staleTTL, 0, slog.New(slog.NewTextHandler(io.Discard, nil))), hook
+}
+
+func commandNames(cmds []redis.Cmder) []string {
+	names := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, fmt.Sprintf("%s %v", cmd.Name(), cmd.Args()[1]))
+	}
+	return names
+}
+
+func TestRedisCache_SetMulti(t *testing.T) {
+	urls := make([]*domain.URL, 0, 25)
+	for i := range 25 {
+		url, err := domain.NewURL(fmt.Sprintf("code%d", i), "https://example.com")
+		require.NoError(t, err)
+		urls = append(urls, url)
+	}
+
+	t.Run("one pipeline for every URL", func(t *testing.T) {
+		cache, hook := newRecordingCache(t, 0)
+
+		require.NoError(t, cache.SetMulti(context.Background(), urls, time.Minute))
+
+		assert.Empty(t, hook.commands)
+		require.Len(t, hook.pipelines, 1)
+		require.Len(t, hook.pipelines[0], len(urls))
+		assert.Equal(t, "set dove:url:default:code0", commandNames(hook.pipelines[0])[0])
+	})
+
+	t.Run("stale copies share the pipeline", func(t *testing.T) {
+		cache, hook := newRecordingCache(t, time.Hour)
+
+		require.NoError(t, cache.SetMulti(context.Background(), urls[:2], time.Minute))
+
+		require.Len(t, hook.pipelines, 1)
+		assert.Equal(t, []string{
+			"set dove:url:default:code0",
+			"set dove:stale:url:default:code0",
+			"set dove:url:default:code1",
+			"set dove:stale:url:default:code1",
+		}, commandNames(hook.pipelines[0]))
+	})
+
+	t.Run("nothing to store", func(t *testing.T) {
+		cache, hook := newRecordingCache(t, 0)
+
+		require.NoError(t, cache.SetMulti(context.Background(), nil, time.Minute))
+		assert.Empty(t, hook.pipelines)
+	})
+}
+
+// expiration returns the TTL a recorded set command was sent with
+func expiration(t *testing.T, cmd redis.Cmder) time.Duration {
+	t.Helper()
+
+	args := cmd.Args()
+	require.Len(t, args, 5, "set without expiration")
+	switch args[3] {
+	case "ex":
+		return time.Duration(args[4].(int64)) * time.Second
+	case "px":
+		return time.Duration(args[4].(int64)) * time.Millisecond
+	}
+	t.Fatalf("unexpected expiration argument %v", args[3])
+	return 0
+}
+
+func TestWithJitter(t *testing.T) {
+	const ttl = 10 * time.Minute
+	const calls = 1000
+	low, high := ttl-time.Minute, ttl+time.Minute
+
+	// Ten buckets of equal width over the range get about a tenth of the calls each
+	var buckets [10]int
+	for range calls {
+		jittered := withJitter(ttl, 10)
+		require.GreaterOrEqual(t, jittered, low)
+		require.LessOrEqual(t, jittered, high)
+		buckets[min(int((jittered-low)*10/(high-low)), 9)]++
+	}
+	for i, count := range buckets {
+		assert.InDelta(t, calls/10, count, 50, "bucket %d of %v", i, buckets)
+	}
+
+	assert.Equal(t, ttl, withJitter(ttl, 0))
+	assert.Equal(t, time.Duration(5), withJitter(5, 10), "too short to move")
+}
+
+func TestRedisCache_SetJitter(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	t.Cleanup(func() { _ = client.Close() })
+	hook := &recordingHook{}
+	client.AddHook(hook)
+	cache := NewRedisCache(client, testKeyPrefix, time.Hour, 10, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	url, err := domain.NewURL("jitter", "https://example.com")
+	require.NoError(t, err)
+	for range 20 {
+		require.NoError(t, cache.Set(context.Background(), url, time.Minute))
+	}
+	require.NoError(t, cache.SetMulti(context.Background(), []*domain.URL{url}, time.Minute))
+
+	seen := make(map[time.Duration]bool)
+	for _, pipeline := range hook.pipelines {
+		for _, cmd := range pipeline {
+			if cmd.Name() != "set" {
+				continue
+			}
+			ttl := expiration(t, cmd)
+			if cmd.Args()[1] == "dove:stale:url:default:jitter" {
+				assert.Equal(t, time.Hour, ttl, "stale copies keep their retention")
+				continue
+			}
+			assert.GreaterOrEqual(t, ttl, 54*time.Second)
+			assert.LessOrEqual(t, ttl, 66*time.Second)
+			seen[ttl] = true
+		}
+	}
+	assert.Greater(t, len(seen), 1, "entries expire at different times")
+}
+
+func TestRedisCache_DeleteMulti(t *testing.T) {
+	cache, hook := newRecordingCache(t, time.Hour)
+
+	require.NoError(t, cache.DeleteMulti(context.Background(), "team", []string{"a", "b", "c"}))
+
+	assert.Empty(t, hook.commands)
+	require.Len(t, hook.pipelines, 1)
+	require.Len(t, hook.pipelines[0], 3)
+	for i, code := range []string{"a", "b", "c"} {
+		assert.Equal(t, []any{"del", "dove:url:team:" + code, "dove:stale:url:team:" + code}, hook.pipelines[0][i].Args())
+	}
+}
+
+// storeHook stands in for the Redis server, keeping its keys in memory. Every get fails
+// with getErr when it is set.
+type storeHook struct {
+	mu     sync.Mutex
+	keys   map[string]string
+	getErr error
+}
+
+func (h *storeHook) DialHook(redis.DialHook) redis.DialHook {
+	return func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("unexpected dial")
+	}
+}
+
+func (h *storeHook) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(_ context.Context, cmd redis.Cmder) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.process(cmd)
+	}
+}
+
+func (h *storeHook) ProcessPipelineHook(redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(_ context.Context, cmds []redis.Cmder) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		// Like Redis, every command runs and the first error is returned
+		var firstErr error
+		for _, cmd := range cmds {
+			if err := h.process(cmd); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+}
+
+func (h *storeHook) process(cmd redis.Cmder) error {
+	args := cmd.Args()
+	switch cmd.Name() {
+	case "multi", "exec":
+	case "get":
+		val, ok := h.keys[args[1].(string)]
+		switch {
+		case h.getErr != nil:
+			cmd.SetErr(h.getErr)
+		case !ok:
+			cmd.SetErr(redis.Nil)
+		default:
+			cmd.(*redis.StringCmd).SetVal(val)
+		}
+		return cmd.Err()
+	case "set":
+		key := args[1].(string)
+		if args[len(args)-1] == "nx" {
+			_, taken := h.keys[key]
+			if !taken {
+				h.keys[key] = fmt.Sprint(args[2])
+			}
+			cmd.(*redis.BoolCmd).SetVal(!taken)
+			return nil
+		}
+		h.keys[key] = string(args[2].([]byte))
+	case "exists", "del":
+		var n int64
+		for _, key := range args[1:] {
+			if _, ok := h.keys[key.(string)]; ok {
+				n++
+				if cmd.Name() == "del" {
+					delete(h.keys, key.(string))
+				}
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(n)
+	default:
+		return errors.New("unexpected command " + cmd.Name())
+	}
+	return nil
+}
+
+func newStoreCache(t *testing.T) (*RedisCache, *storeHook) {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	t.Cleanup(func() { _ = client.Close() })
+	hook := &storeHook{keys: make(map[string]string)}
+	client.AddHook(hook)
+
+	return NewRedisCache(client, testKeyPrefix, 0, 0, slog.New(slog.NewTextHandler(io.Discard, nil))), hook
+}
+
+func TestRedisCache_GetMulti(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("hits and misses in one pipeline", func(t *testing.T) {
+		cache, hook := newStoreCache(t)
+		for _, shortCode := range []string{"first", "third"} {
+			url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+			require.NoError(t, err)
+			require.NoError(t, cache.Set(ctx, url, time.Minute))
+		}
+		hook.keys["dove:url:default:corrupt"] = "{"
+
+		urls, err := cache.GetMulti(ctx, domain.DefaultNamespace, []string{"first", "second", "corrupt", "third"})
+		require.NoError(t, err)
+		require.Len(t, urls, 2)
+		assert.Equal(t, "https://example.com/first", urls["first"].OriginalURL)
+		assert.Equal(t, "https://example.com/third", urls["third"].OriginalURL)
+		assert.True(t, urls["first"].Enabled)
+	})
+
+	t.Run("nothing to get", func(t *testing.T) {
+		cache, _ := newStoreCache(t)
+
+		urls, err := cache.GetMulti(ctx, domain.DefaultNamespace, nil)
+		require.NoError(t, err)
+		assert.Empty(t, urls)
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		cache, hook := newStoreCache(t)
+		hook.getErr = errors.New("connection refused")
+
+		_, err := cache.GetMulti(ctx, domain.DefaultNamespace, []string{"first"})
+		assert.Error(t, err)
+	})
+}
+
+// getOrSetConcurrently calls GetOrSet for the same URL from n goroutines released at once
+func getOrSetConcurrently(cache *RedisCache, n int, fetch func() (*domain.URL, error)) ([]*domain.URL, []error) {
+	results := make([]*domain.URL, n)
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = cache.GetOrSet(context.Background(), domain.DefaultNamespace, "hot", time.Minute, fetch)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return results, errs
+}
+
+func TestRedisCache_GetOrSet(t *testing.T) {
+	ctx := context.Background()
+	lockKey := "dove:populate:url:default:hot"
+
+	t.Run("concurrent misses fetch once", func(t *testing.T) {
+		cache, hook := newStoreCache(t)
+
+		var calls atomic.Int32
+		results, errs := getOrSetConcurrently(cache, 10, func() (*domain.URL, error) {
+			calls.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			return domain.NewURL("hot", "https://example.com/hot")
+		})
+
+		assert.Equal(t, int32(1), calls.Load())
+		for i := range results {
+			require.NoError(t, errs[i])
+			assert.Equal(t, "https://example.com/hot", results[i].OriginalURL)
+		}
+		assert.Contains(t, hook.keys, "dove:url:default:hot")
+		assert.NotContains(t, hook.keys, lockKey, "the lock is released")
+	})
+
+	t.Run("a hit does not fetch", func(t *testing.T) {
+		cache, _ := newStoreCache(t)
+		url, err := domain.NewURL("hot", "https://example.com/hot")
+		require.NoError(t, err)
+		require.NoError(t, cache.Set(ctx, url, time.Minute))
+
+		got, err := cache.GetOrSet(ctx, domain.DefaultNamespace, "hot", time.Minute, func() (*domain.URL, error) {
+			return nil, errors.New("unexpected fetch")
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/hot", got.OriginalURL)
+	})
+
+	t.Run("waiters fetch once the holder stored nothing", func(t *testing.T) {
+		cache, hook := newStoreCache(t)
+
+		_, errs := getOrSetConcurrently(cache, 10, func() (*domain.URL, error) {
+			time.Sleep(20 * time.Millisecond)
+			return nil, domain.ErrURLNotFound
+		})
+
+		for _, err := range errs {
+			assert.ErrorIs(t, err, domain.ErrURLNotFound)
+		}
+		assert.Empty(t, hook.keys)
+	})
+
+	t.Run("an unavailable cache falls back to fetch", func(t *testing.T) {
+		cache, hook := newStoreCache(t)
+		hook.getErr = errors.New("dial tcp: connection refused")
+
+		got, err := cache.GetOrSet(ctx, domain.DefaultNamespace, "hot", time.Minute, func() (*domain.URL, error) {
+			return domain.NewURL("hot", "https://example.com/hot")
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/hot", got.OriginalURL)
+	})
+}
+
+func TestRedisCache_Keys(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newKeyspaceCache(t,
+		"dove:url:default:abc",
+		"dove:top_urls:10",
+		"other:url:default:abc",
+	)
+
+	keys, next, err := cache.Keys(ctx, "url:*", 0, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"url:default:abc"}, keys)
+	assert.Zero(t, next)
+
+	keys, _, err = cache.Keys(ctx, "", 0, 100)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"url:default:abc", "top_urls:10"}, keys)
+}
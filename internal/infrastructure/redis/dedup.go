@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ClickDeduplicator keeps the visitors of each short URL in a Redis set. The set expires
+// window after its latest click, so a visitor stops counting as seen once the short URL
+// has gone unclicked for that long.
+type ClickDeduplicator struct {
+	client *redis.Client
+	prefix string
+	window time.Duration
+}
+
+// NewClickDeduplicator creates a deduplicator whose keys start with prefix
+func NewClickDeduplicator(client *redis.Client, prefix string, window time.Duration) *ClickDeduplicator {
+	return &ClickDeduplicator{
+		client: client,
+		prefix: prefix,
+		window: window,
+	}
+}
+
+func (d *ClickDeduplicator) FirstVisit(ctx context.Context, namespace, shortCode, visitor string) (bool, error) {
+	key := d.buildKey(namespace, shortCode)
+
+	var added *redis.IntCmd
+	_, err := d.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		added = pipe.SAdd(ctx, key, visitor)
+		pipe.Expire(ctx, key, d.window)
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("click deduplication failed: %w", err)
+	}
+
+	return added.Val() > 0, nil
+}
+
+func (d *ClickDeduplicator) Forget(ctx context.Context, namespace, shortCode string) error {
+	if err := d.client.Del(ctx, d.buildKey(namespace, shortCode)).Err(); err != nil {
+		return fmt.Errorf("click deduplication reset failed: %w", err)
+	}
+	return nil
+}
+
+func (d *ClickDeduplicator) buildKey(namespace, shortCode string) string {
+	return fmt.Sprintf("%s:clicks:dedup:%s:%s", d.prefix, namespace, shortCode)
+}
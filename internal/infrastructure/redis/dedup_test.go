@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setHook stands in for the Redis server, answering SADD from in-memory sets and
+// recording pipelines with their commands
+type setHook struct {
+	sets      map[string]map[string]bool
+	pipelines [][]redis.Cmder
+}
+
+func (h *setHook) DialHook(redis.DialHook) redis.DialHook {
+	return func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("unexpected dial")
+	}
+}
+
+func (h *setHook) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(context.Context, redis.Cmder) error {
+		return errors.New("unexpected command outside a pipeline")
+	}
+}
+
+func (h *setHook) ProcessPipelineHook(redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(_ context.Context, cmds []redis.Cmder) error {
+		h.pipelines = append(h.pipelines, cmds)
+		for _, cmd := range cmds {
+			if cmd.Name() != "sadd" {
+				continue
+			}
+			key, member := cmd.Args()[1].(string), cmd.Args()[2].(string)
+			if h.sets[key] == nil {
+				h.sets[key] = make(map[string]bool)
+			}
+			var added int64
+			if !h.sets[key][member] {
+				h.sets[key][member] = true
+				added = 1
+			}
+			cmd.(*redis.IntCmd).SetVal(added)
+		}
+		return nil
+	}
+}
+
+func TestClickDeduplicator_FirstVisit(t *testing.T) {
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	t.Cleanup(func() { _ = client.Close() })
+	hook := &setHook{sets: make(map[string]map[string]bool)}
+	client.AddHook(hook)
+
+	dedup := NewClickDeduplicator(client, testKeyPrefix, 30*time.Minute)
+
+	tests := []struct {
+		name      string
+		namespace string
+		shortCode string
+		visitor   string
+		first     bool
+	}{
+		{"first visit", "default", "abc123", "visitor-a", true},
+		{"repeat visit", "default", "abc123", "visitor-a", false},
+		{"another visitor", "default", "abc123", "visitor-b", true},
+		{"another short code", "default", "xyz789", "visitor-a", true},
+		{"another namespace", "acme", "abc123", "visitor-a", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := dedup.FirstVisit(ctx, tt.namespace, tt.shortCode, tt.visitor)
+			require.NoError(t, err)
+			assert.Equal(t, tt.first, first)
+		})
+	}
+
+	t.Run("each visit slides the window in the same pipeline", func(t *testing.T) {
+		require.Len(t, hook.pipelines, len(tests))
+		pipeline := hook.pipelines[0]
+		require.Len(t, pipeline, 2)
+		assert.Equal(t, []any{"sadd", "dove:clicks:dedup:default:abc123", "visitor-a"}, pipeline[0].Args())
+		assert.Equal(t, []any{"expire", "dove:clicks:dedup:default:abc123", int64(1800)}, pipeline[1].Args())
+	})
+}
+
+func TestClickDeduplicator_Forget(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	t.Cleanup(func() { _ = client.Close() })
+	hook := &recordingHook{}
+	client.AddHook(hook)
+
+	require.NoError(t, NewClickDeduplicator(client, testKeyPrefix, time.Minute).Forget(context.Background(), "default", "abc123"))
+
+	require.Len(t, hook.commands, 1)
+	assert.Equal(t, []any{"del", "dove:clicks:dedup:default:abc123"}, hook.commands[0].Args())
+}
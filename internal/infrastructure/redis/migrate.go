@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// migrateScanCount is the number of keys SCAN is asked to inspect per call
+const migrateScanCount = 500
+
+// globReplacer escapes the characters SCAN MATCH treats as a pattern
+var globReplacer = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// MigrateKeys renames every key under oldPrefix, including those of the click deduplicator,
+// to start with newPrefix instead, so that cache.key_prefix can change without losing the
+// cache. A key already present under newPrefix was written by the new deployment and wins,
+// the old one is deleted. Keys keep their TTL.
+func (c *RedisCache) MigrateKeys(ctx context.Context, oldPrefix, newPrefix string) (int, error) {
+	if oldPrefix == "" || newPrefix == "" {
+		return 0, fmt.Errorf("%w: prefixes must not be empty", domain.ErrInvalidKeyPrefix)
+	}
+	// SCAN would visit renamed keys again when they still match the old prefix
+	if strings.HasPrefix(newPrefix+":", oldPrefix+":") {
+		return 0, fmt.Errorf("%w: %q must not start with %q", domain.ErrInvalidKeyPrefix, newPrefix, oldPrefix+":")
+	}
+
+	renamed := 0
+	iter := c.client.Scan(ctx, 0, globReplacer.Replace(oldPrefix)+":*", migrateScanCount).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		target := newPrefix + strings.TrimPrefix(key, oldPrefix)
+
+		ok, err := c.client.RenameNX(ctx, key, target).Result()
+		switch {
+		case err != nil && strings.Contains(err.Error(), "no such key"):
+			// Expired since it was scanned
+			continue
+		case err != nil:
+			c.logger.Error("Failed to rename cache key", "key", key, "error", err)
+			return renamed, fmt.Errorf("cache key migration failed: %w", err)
+		case !ok:
+			if err := c.client.Del(ctx, key).Err(); err != nil {
+				return renamed, fmt.Errorf("cache key migration failed: %w", err)
+			}
+		default:
+			renamed++
+		}
+	}
+	if err := iter.Err(); err != nil {
+		c.logger.Error("Failed to scan cache keys", "prefix", oldPrefix, "error", err)
+		return renamed, fmt.Errorf("cache key migration failed: %w", err)
+	}
+
+	return renamed, nil
+}
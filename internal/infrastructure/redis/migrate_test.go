@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// keyspaceHook stands in for the Redis server with the keys it holds, answering SCAN in a
+// single batch, RENAMENX and DEL
+type keyspaceHook struct {
+	recordingHook
+	keys map[string]bool
+}
+
+func (h *keyspaceHook) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(_ context.Context, cmd redis.Cmder) error {
+		args := cmd.Args()
+		switch cmd.Name() {
+		case "scan":
+			var matched []string
+			for key := range h.keys {
+				// Redis globs escape like path.Match, and keys hold no slashes
+				if ok, _ := path.Match(args[3].(string), key); ok {
+					matched = append(matched, key)
+				}
+			}
+			cmd.(*redis.ScanCmd).SetVal(matched, 0)
+		case "renamenx":
+			from, to := args[1].(string), args[2].(string)
+			if h.keys[to] {
+				cmd.(*redis.BoolCmd).SetVal(false)
+				return nil
+			}
+			delete(h.keys, from)
+			h.keys[to] = true
+			cmd.(*redis.BoolCmd).SetVal(true)
+		case "del":
+			for _, key := range args[1:] {
+				delete(h.keys, key.(string))
+			}
+		}
+		return nil
+	}
+}
+
+func newKeyspaceCache(t *testing.T, keys ...string) (*RedisCache, *keyspaceHook) {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	t.Cleanup(func() { _ = client.Close() })
+	hook := &keyspaceHook{keys: make(map[string]bool)}
+	for _, key := range keys {
+		hook.keys[key] = true
+	}
+	client.AddHook(hook)
+
+	return NewRedisCache(client, testKeyPrefix, 0, 0, slog.New(slog.NewTextHandler(io.Discard, nil))), hook
+}
+
+func TestRedisCache_MigrateKeys(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("keys move to the new prefix", func(t *testing.T) {
+		cache, hook := newKeyspaceCache(t,
+			"dove:url:default:abc",
+			"dove:stale:url:default:abc",
+			"dove:top_urls:10",
+			"dove:clicks:dedup:default:abc",
+			"dove-staging:url:default:abc",
+			"other:url:default:abc",
+		)
+
+		renamed, err := cache.MigrateKeys(ctx, "dove", "prod")
+		require.NoError(t, err)
+		assert.Equal(t, 4, renamed)
+		assert.Equal(t, map[string]bool{
+			"prod:url:default:abc":          true,
+			"prod:stale:url:default:abc":    true,
+			"prod:top_urls:10":              true,
+			"prod:clicks:dedup:default:abc": true,
+			"dove-staging:url:default:abc":  true,
+			"other:url:default:abc":         true,
+		}, hook.keys)
+	})
+
+	t.Run("keys under the new prefix win", func(t *testing.T) {
+		cache, hook := newKeyspaceCache(t, "dove:url:default:abc", "prod:url:default:abc")
+
+		renamed, err := cache.MigrateKeys(ctx, "dove", "prod")
+		require.NoError(t, err)
+		assert.Zero(t, renamed)
+		assert.Equal(t, map[string]bool{"prod:url:default:abc": true}, hook.keys)
+	})
+
+	t.Run("prefixes are matched literally", func(t *testing.T) {
+		cache, hook := newKeyspaceCache(t, "dove:url:default:abc")
+
+		renamed, err := cache.MigrateKeys(ctx, "d*", "prod")
+		require.NoError(t, err)
+		assert.Zero(t, renamed)
+		assert.Equal(t, map[string]bool{"dove:url:default:abc": true}, hook.keys)
+	})
+
+	for _, tt := range []struct {
+		name      string
+		oldPrefix string
+		newPrefix string
+	}{
+		{"empty old prefix", "", "prod"},
+		{"empty new prefix", "dove", ""},
+		{"same prefix", "dove", "dove"},
+		{"new prefix nested in the old one", "dove", "dove:prod"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, _ := newKeyspaceCache(t)
+
+			_, err := cache.MigrateKeys(ctx, tt.oldPrefix, tt.newPrefix)
+			assert.ErrorIs(t, err, domain.ErrInvalidKeyPrefix)
+		})
+	}
+}
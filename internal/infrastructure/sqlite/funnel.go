@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// CreateFunnel inserts the funnel and its steps in one transaction
+func (r *URLRepository) CreateFunnel(ctx context.Context, funnel *domain.Funnel) (*domain.Funnel, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO funnels (name, created_at) VALUES ($1, $2)`, funnel.Name, funnel.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, step := range funnel.Steps {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO funnel_steps (funnel_id, step_order, namespace, short_code) VALUES ($1, $2, $3, $4)`, id, step.Order, step.Namespace, step.ShortCode); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &domain.Funnel{
+		ID:        id,
+		Name:      funnel.Name,
+		Steps:     append([]domain.FunnelStep(nil), funnel.Steps...),
+		CreatedAt: funnel.CreatedAt,
+	}, nil
+}
+
+func (r *URLRepository) FindFunnel(ctx context.Context, id int64) (*domain.Funnel, error) {
+	var funnel domain.Funnel
+	err := r.db.GetContext(ctx, &funnel, `SELECT id, name, created_at FROM funnels WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrFunnelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT step_order, namespace, short_code FROM funnel_steps WHERE funnel_id = $1 ORDER BY step_order`
+	if err := r.db.SelectContext(ctx, &funnel.Steps, query, id); err != nil {
+		return nil, err
+	}
+
+	return &funnel, nil
+}
+
+func (r *URLRepository) FindFunnelSteps(ctx context.Context, namespace, shortCode string) ([]domain.FunnelStepRef, error) {
+	var refs []domain.FunnelStepRef
+	query := `SELECT funnel_id, step_order FROM funnel_steps WHERE namespace = $1 AND short_code = $2 ORDER BY funnel_id, step_order`
+	if err := r.db.SelectContext(ctx, &refs, query, namespace, shortCode); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func (r *URLRepository) RecordFunnelClick(ctx context.Context, click *domain.FunnelClick) error {
+	query := `INSERT INTO funnel_clicks (funnel_id, step_order, session_id, clicked_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.ExecContext(ctx, query, click.FunnelID, click.StepOrder, click.SessionID, click.ClickedAt)
+	return err
+}
+
+func (r *URLRepository) FunnelSessionSteps(ctx context.Context, funnelID int64) ([]domain.FunnelSessionStep, error) {
+	var steps []domain.FunnelSessionStep
+	query := `SELECT DISTINCT session_id, step_order FROM funnel_clicks WHERE funnel_id = $1 ORDER BY session_id, step_order`
+	if err := r.db.SelectContext(ctx, &steps, query, funnelID); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
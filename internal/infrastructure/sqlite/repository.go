@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 
@@ -16,17 +19,77 @@ type URLRepository struct {
 	logger *slog.Logger
 }
 
-func NewURLRepository(db *sqlx.DB, logger *slog.Logger) *URLRepository {
-	return &URLRepository{db: db, logger: logger}
+// urlColumns lists the columns mapped onto domain.URL. A NULL description or creator
+// address reads as empty.
+const urlColumns = `id, short_code, original_url, clicks, created_at, updated_at, password_hash, health_status, last_checked_at, namespace, pool_enabled, pool, signed, geo_routes, device_routes, redirect_type, expires_at, tags, delay_seconds, unique_clicks, COALESCE(description, '') AS description, enabled, last_accessed_at, is_custom_alias, click_goal, goal_reached, COALESCE(created_by_ip, '') AS created_by_ip, COALESCE(title, '') AS title`
+
+// clickColumns lists the url_clicks columns mapped onto domain.Click
+const clickColumns = `id, namespace, short_code, clicked_at, referer, ua_browser, ua_os, ua_device_type, variant_id, target_url, redirect_duration_ms, ip_address, user_agent, country`
+
+// Pragmas are the connection settings applied by NewURLRepository. Empty values keep the
+// SQLite default.
+type Pragmas struct {
+	JournalMode   string // DELETE, TRUNCATE, PERSIST, MEMORY, WAL or OFF
+	Synchronous   string // OFF, NORMAL, FULL or EXTRA
+	BusyTimeoutMs int
+}
+
+var (
+	journalModes = map[string]bool{"DELETE": true, "TRUNCATE": true, "PERSIST": true, "MEMORY": true, "WAL": true, "OFF": true}
+	syncModes    = map[string]bool{"OFF": true, "NORMAL": true, "FULL": true, "EXTRA": true}
+)
+
+// NewURLRepository limits db to a single connection, since SQLite allows one writer at a
+// time and pooled writers only fail each other with SQLITE_BUSY, then applies pragmas to it.
+// The connection is never recycled, so the per-connection pragmas stay in effect.
+func NewURLRepository(db *sqlx.DB, pragmas Pragmas, logger *slog.Logger) (*URLRepository, error) {
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	statements := []string{fmt.Sprintf("PRAGMA busy_timeout = %d", pragmas.BusyTimeoutMs)}
+	if pragmas.JournalMode != "" {
+		mode := strings.ToUpper(pragmas.JournalMode)
+		if !journalModes[mode] {
+			return nil, fmt.Errorf("invalid SQLite journal mode %q", pragmas.JournalMode)
+		}
+		statements = append(statements, "PRAGMA journal_mode = "+mode)
+	}
+	if pragmas.Synchronous != "" {
+		mode := strings.ToUpper(pragmas.Synchronous)
+		if !syncModes[mode] {
+			return nil, fmt.Errorf("invalid SQLite synchronous mode %q", pragmas.Synchronous)
+		}
+		statements = append(statements, "PRAGMA synchronous = "+mode)
+	}
+
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return nil, fmt.Errorf("failed to apply %q: %w", statement, err)
+		}
+	}
+
+	return &URLRepository{db: db, logger: logger}, nil
 }
 
 func (r *URLRepository) Create(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Namespaces are registered on first use
+	if _, err := tx.ExecContext(ctx, `INSERT INTO namespaces (slug) VALUES ($1) ON CONFLICT (slug) DO NOTHING`, url.Namespace); err != nil {
+		return nil, err
+	}
+
 	query := `
-		INSERT INTO urls (short_code, original_url, clicks, created_at, updated_at)
-		VALUES (:short_code, :original_url, :clicks, :created_at, :updated_at)
+		INSERT INTO urls (namespace, short_code, original_url, clicks, created_at, updated_at, password_hash, health_status, pool_enabled, pool, signed, geo_routes, device_routes, redirect_type, expires_at, tags, delay_seconds, description, enabled, is_custom_alias, click_goal, created_by_ip, title)
+		VALUES (:namespace, :short_code, :original_url, :clicks, :created_at, :updated_at, :password_hash, :health_status, :pool_enabled, :pool, :signed, :geo_routes, :device_routes, :redirect_type, :expires_at, :tags, :delay_seconds, NULLIF(:description, ''), :enabled, :is_custom_alias, :click_goal, NULLIF(:created_by_ip, ''), NULLIF(:title, ''))
 	`
 
-	result, err := r.db.NamedExecContext(ctx, query, url)
+	result, err := tx.NamedExecContext(ctx, query, url)
 	if err != nil {
 		return nil, domain.ErrShortCodeExists
 	}
@@ -37,23 +100,63 @@ func (r *URLRepository) Create(ctx context.Context, url *domain.URL) (*domain.UR
 		return nil, err
 	}
 
+	var variants []domain.URLVariant
+	for _, variant := range url.Variants {
+		result, err := tx.ExecContext(ctx, `INSERT INTO url_variants (url_id, original_url, weight) VALUES ($1, $2, $3)`, id, variant.OriginalURL, variant.Weight)
+		if err != nil {
+			return nil, err
+		}
+		variantID, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, domain.URLVariant{ID: variantID, URLID: id, OriginalURL: variant.OriginalURL, Weight: variant.Weight})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	createdURL := &domain.URL{
-		ID:          id,
-		ShortCode:   url.ShortCode,
-		OriginalURL: url.OriginalURL,
-		Clicks:      url.Clicks,
-		CreatedAt:   url.CreatedAt,
-		UpdatedAt:   url.UpdatedAt,
+		ID:            id,
+		Namespace:     url.Namespace,
+		ShortCode:     url.ShortCode,
+		OriginalURL:   url.OriginalURL,
+		Clicks:        url.Clicks,
+		CreatedAt:     url.CreatedAt,
+		UpdatedAt:     url.UpdatedAt,
+		PasswordHash:  url.PasswordHash,
+		HealthStatus:  url.HealthStatus,
+		LastCheckedAt: url.LastCheckedAt,
+		Signed:        url.Signed,
+		PoolEnabled:   url.PoolEnabled,
+		Pool:          url.Pool,
+		GeoRoutes:     url.GeoRoutes,
+		DeviceRoutes:  url.DeviceRoutes,
+		RedirectType:  url.RedirectType,
+		DelaySeconds:  url.DelaySeconds,
+		ExpiresAt:     url.ExpiresAt,
+		Tags:          url.Tags,
+		Description:   url.Description,
+		Enabled:       url.Enabled,
+		ClickGoal:     url.ClickGoal,
+		CreatedByIP:   url.CreatedByIP,
+		Title:         url.Title,
+		Variants:      variants,
 	}
 
 	return createdURL, nil
 }
 
 func (r *URLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return r.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+}
+
+func (r *URLRepository) FindByNamespaceAndCode(ctx context.Context, namespace, shortCode string) (*domain.URL, error) {
 	var url domain.URL
-	query := `SELECT * FROM urls WHERE short_code = $1`
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE namespace = $1 AND short_code = $2`
 
-	err := r.db.GetContext(ctx, &url, query, shortCode)
+	err := r.db.GetContext(ctx, &url, query, namespace, shortCode)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrURLNotFound
@@ -61,13 +164,73 @@ func (r *URLRepository) FindByShortCode(ctx context.Context, shortCode string) (
 		return nil, err
 	}
 
+	if err := r.loadVariants(ctx, &url); err != nil {
+		return nil, err
+	}
+
 	return &url, nil
 }
 
-func (r *URLRepository) IncrementClicks(ctx context.Context, shortCode string) (*domain.URL, error) {
-	query := `UPDATE urls SET clicks = clicks + 1 WHERE short_code = $1`
+// loadVariants attaches the variants of url, if any, in creation order
+func (r *URLRepository) loadVariants(ctx context.Context, url *domain.URL) error {
+	query := `SELECT id, url_id, original_url, weight FROM url_variants WHERE url_id = $1 ORDER BY id ASC`
+
+	var variants []domain.URLVariant
+	if err := r.db.SelectContext(ctx, &variants, query, url.ID); err != nil {
+		return err
+	}
 
-	result, err := r.db.ExecContext(ctx, query, shortCode)
+	url.Variants = variants
+	return nil
+}
+
+func (r *URLRepository) FindByShortCodes(ctx context.Context, namespace string, shortCodes []string) ([]*domain.URL, error) {
+	urls := []*domain.URL{}
+	if len(shortCodes) == 0 {
+		return urls, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT `+urlColumns+` FROM urls WHERE namespace = ? AND short_code IN (?) ORDER BY id ASC`, namespace, shortCodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.SelectContext(ctx, &urls, query, args...); err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return urls, nil
+	}
+
+	byID := make(map[int64]*domain.URL, len(urls))
+	ids := make([]int64, 0, len(urls))
+	for _, url := range urls {
+		byID[url.ID] = url
+		ids = append(ids, url.ID)
+	}
+
+	query, args, err = sqlx.In(`SELECT id, url_id, original_url, weight FROM url_variants WHERE url_id IN (?) ORDER BY id ASC`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var variants []domain.URLVariant
+	if err := r.db.SelectContext(ctx, &variants, query, args...); err != nil {
+		return nil, err
+	}
+	for _, variant := range variants {
+		byID[variant.URLID].Variants = append(byID[variant.URLID].Variants, variant)
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) IncrementClicks(ctx context.Context, namespace, shortCode string, unique bool) (*domain.URL, error) {
+	query := `
+		UPDATE urls
+		SET clicks = clicks + 1, unique_clicks = unique_clicks + CASE WHEN $1 THEN 1 ELSE 0 END, last_accessed_at = $2
+		WHERE namespace = $3 AND short_code = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, unique, time.Now().UTC(), namespace, shortCode)
 	if err != nil {
 		return nil, err
 	}
@@ -82,14 +245,70 @@ func (r *URLRepository) IncrementClicks(ctx context.Context, shortCode string) (
 	}
 
 	// Fetch the updated record
-	return r.FindByShortCode(ctx, shortCode)
+	return r.FindByNamespaceAndCode(ctx, namespace, shortCode)
+}
+
+func (r *URLRepository) AddClicks(ctx context.Context, deltas []domain.ClickDelta) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PreparexContext(ctx, `
+		UPDATE urls
+		SET clicks = clicks + $1, unique_clicks = unique_clicks + $2, last_accessed_at = $3
+		WHERE namespace = $4 AND short_code = $5
+	`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	now := time.Now().UTC()
+	for _, delta := range deltas {
+		if _, err := stmt.ExecContext(ctx, delta.Clicks, delta.UniqueClicks, now, delta.Namespace, delta.ShortCode); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Update stores the editable fields of url. Clicks, health and variants are left alone,
+// as they have their own writers.
+func (r *URLRepository) Update(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	query := `
+		UPDATE urls
+		SET original_url = :original_url, password_hash = :password_hash, signed = :signed, pool_enabled = :pool_enabled,
+			pool = :pool, geo_routes = :geo_routes, device_routes = :device_routes, redirect_type = :redirect_type,
+			expires_at = :expires_at, tags = :tags, description = NULLIF(:description, ''),
+			title = NULLIF(:title, '')
+		WHERE namespace = :namespace AND short_code = :short_code
+	`
+
+	result, err := r.db.NamedExecContext(ctx, query, url)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, domain.ErrURLNotFound
+	}
+
+	return r.FindByNamespaceAndCode(ctx, url.Namespace, url.ShortCode)
 }
 
-func (r *URLRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
+func (r *URLRepository) Exists(ctx context.Context, namespace, shortCode string) (bool, error) {
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM urls WHERE short_code = $1)`
+	query := `SELECT EXISTS(SELECT 1 FROM urls WHERE namespace = $1 AND short_code = $2)`
 
-	err := r.db.GetContext(ctx, &exists, query, shortCode)
+	err := r.db.GetContext(ctx, &exists, query, namespace, shortCode)
 	if err != nil {
 		return false, err
 	}
@@ -97,6 +316,572 @@ func (r *URLRepository) Exists(ctx context.Context, shortCode string) (bool, err
 	return exists, nil
 }
 
+func (r *URLRepository) List(ctx context.Context, afterID int64, limit int) ([]*domain.URL, error) {
+	urls := []*domain.URL{}
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE id > $1 ORDER BY id ASC LIMIT $2`
+
+	if err := r.db.SelectContext(ctx, &urls, query, afterID, limit); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) ListByCreatorIP(ctx context.Context, ip string, afterID int64, limit int) ([]*domain.URL, error) {
+	urls := []*domain.URL{}
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE created_by_ip = $1 AND id > $2 ORDER BY id ASC LIMIT $3`
+
+	if err := r.db.SelectContext(ctx, &urls, query, ip, afterID, limit); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+// Search matches the query as a substring. LIKE ignores the case of ASCII letters only.
+func (r *URLRepository) Search(ctx context.Context, query string, limit int) ([]*domain.URL, error) {
+	urls := []*domain.URL{}
+	sqlQuery := `SELECT ` + urlColumns + ` FROM urls
+		WHERE original_url LIKE $1 ESCAPE '\' OR short_code LIKE $1 ESCAPE '\' OR description LIKE $1 ESCAPE '\'
+		ORDER BY id ASC LIMIT $2`
+
+	if err := r.db.SelectContext(ctx, &urls, sqlQuery, containsPattern(query), limit); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+// containsPattern is a LIKE pattern matching the values containing s, its wildcards
+// matched literally
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *URLRepository) ListForHealthCheck(ctx context.Context, limit int) ([]*domain.URL, error) {
+	var urls []*domain.URL
+	query := `SELECT ` + urlColumns + ` FROM urls ORDER BY last_checked_at ASC NULLS FIRST, id ASC LIMIT $1`
+
+	if err := r.db.SelectContext(ctx, &urls, query, limit); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) FindExpired(ctx context.Context, before time.Time, limit int) ([]*domain.URL, error) {
+	urls := []*domain.URL{}
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE expires_at IS NOT NULL AND julianday(expires_at) < julianday($1) ORDER BY id ASC LIMIT $2`
+
+	if err := r.db.SelectContext(ctx, &urls, query, before.UTC(), limit); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) FindNotAccessedSince(ctx context.Context, since time.Time, limit int) ([]*domain.URL, error) {
+	urls := []*domain.URL{}
+	query := `SELECT ` + urlColumns + ` FROM urls
+		WHERE enabled AND julianday(COALESCE(last_accessed_at, created_at)) < julianday($1)
+		ORDER BY julianday(COALESCE(last_accessed_at, created_at)) ASC, id ASC LIMIT $2`
+
+	if err := r.db.SelectContext(ctx, &urls, query, since.UTC(), limit); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) TopByClicks(ctx context.Context, n int) ([]*domain.URL, error) {
+	urls := []*domain.URL{}
+	query := `SELECT ` + urlColumns + ` FROM urls
+		WHERE enabled AND NOT signed AND password_hash = ''
+		ORDER BY clicks DESC, id ASC LIMIT $1`
+
+	if err := r.db.SelectContext(ctx, &urls, query, n); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) UpdateHealthStatus(ctx context.Context, namespace, shortCode, status string, checkedAt time.Time) error {
+	query := `UPDATE urls SET health_status = $1, last_checked_at = $2 WHERE namespace = $3 AND short_code = $4`
+
+	result, err := r.db.ExecContext(ctx, query, status, checkedAt.UTC(), namespace, shortCode)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (r *URLRepository) SetEnabled(ctx context.Context, namespace, shortCode string, enabled bool) error {
+	query := `UPDATE urls SET enabled = $1 WHERE namespace = $2 AND short_code = $3`
+
+	result, err := r.db.ExecContext(ctx, query, enabled, namespace, shortCode)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func (r *URLRepository) MarkGoalReached(ctx context.Context, namespace, shortCode string) (bool, error) {
+	query := `UPDATE urls SET goal_reached = 1 WHERE namespace = $1 AND short_code = $2 AND NOT goal_reached`
+
+	result, err := r.db.ExecContext(ctx, query, namespace, shortCode)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *URLRepository) RecordClick(ctx context.Context, click *domain.Click) error {
+	query := `
+		INSERT INTO url_clicks (namespace, short_code, clicked_at, referer, ua_browser, ua_os, ua_device_type, variant_id, target_url, redirect_duration_ms, ip_address, user_agent, country)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, click.Namespace, click.ShortCode, click.ClickedAt.UTC(), click.Referer, click.Browser, click.OS, click.DeviceType, click.VariantID, click.TargetURL, click.RedirectDurationMs, click.IPAddress, click.UserAgent, click.Country)
+	return err
+}
+
+// ListClicks compares times through julianday, as clicked_at is stored as text whose
+// format depends on how the click was written
+func (r *URLRepository) ListClicks(ctx context.Context, namespace, shortCode string, after domain.ClickCursor, limit int) ([]*domain.Click, error) {
+	query := `SELECT ` + clickColumns + ` FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2
+			AND (julianday(clicked_at) > julianday($3) OR (julianday(clicked_at) = julianday($3) AND id > $4))
+		ORDER BY julianday(clicked_at) ASC, id ASC LIMIT $5`
+
+	clicks := []*domain.Click{}
+	if err := r.db.SelectContext(ctx, &clicks, query, namespace, shortCode, after.ClickedAt.UTC(), after.ID, limit); err != nil {
+		return nil, err
+	}
+
+	return clicks, nil
+}
+
+func (r *URLRepository) ResetClicks(ctx context.Context, namespace, shortCode string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `UPDATE urls SET clicks = 0, unique_clicks = 0, updated_at = CURRENT_TIMESTAMP WHERE namespace = $1 AND short_code = $2`
+	result, err := tx.ExecContext(ctx, query, namespace, shortCode)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM url_clicks WHERE namespace = $1 AND short_code = $2`, namespace, shortCode); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a URL with its variants and clicks. Foreign keys are not enforced by
+// SQLite here, so the dependent rows are deleted explicitly.
+func (r *URLRepository) Delete(ctx context.Context, namespace, shortCode string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM url_variants WHERE url_id IN (SELECT id FROM urls WHERE namespace = $1 AND short_code = $2)`, namespace, shortCode); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM url_clicks WHERE namespace = $1 AND short_code = $2`, namespace, shortCode); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM urls WHERE namespace = $1 AND short_code = $2`, namespace, shortCode)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	return tx.Commit()
+}
+
+// DeleteMany removes the URLs of namespace with any of shortCodes, with their variants and
+// recorded clicks, in one transaction
+func (r *URLRepository) DeleteMany(ctx context.Context, namespace string, shortCodes []string) ([]*domain.URL, error) {
+	urls := []*domain.URL{}
+	if len(shortCodes) == 0 {
+		return urls, nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query, args, err := sqlx.In(`SELECT `+urlColumns+` FROM urls WHERE namespace = ? AND short_code IN (?) ORDER BY id ASC`, namespace, shortCodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.SelectContext(ctx, &urls, query, args...); err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return urls, nil
+	}
+
+	ids := make([]int64, 0, len(urls))
+	deleted := make([]string, 0, len(urls))
+	for _, url := range urls {
+		ids = append(ids, url.ID)
+		deleted = append(deleted, url.ShortCode)
+	}
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`DELETE FROM url_variants WHERE url_id IN (?)`, []any{ids}},
+		{`DELETE FROM url_clicks WHERE namespace = ? AND short_code IN (?)`, []any{namespace, deleted}},
+		{`DELETE FROM urls WHERE id IN (?)`, []any{ids}},
+	}
+	for _, statement := range statements {
+		query, args, err := sqlx.In(statement.query, statement.args...)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// bucketExpressions truncates clicked_at to the start of each bucket, formatted as RFC 3339
+var bucketExpressions = map[domain.Granularity]string{
+	domain.GranularityMinute: `strftime('%Y-%m-%dT%H:%M:00Z', clicked_at)`,
+	domain.GranularityHour:   `strftime('%Y-%m-%dT%H:00:00Z', clicked_at)`,
+	domain.GranularityDay:    `strftime('%Y-%m-%dT00:00:00Z', clicked_at)`,
+	domain.GranularityWeek:   `strftime('%Y-%m-%dT00:00:00Z', clicked_at, 'weekday 0', '-6 days')`,
+}
+
+func (r *URLRepository) ClickTimeSeries(ctx context.Context, namespace, shortCode string, granularity domain.Granularity, from, to time.Time) ([]domain.TimeBucket, error) {
+	expr, ok := bucketExpressions[granularity]
+	if !ok {
+		return nil, domain.ErrInvalidGranularity
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s AS period, COUNT(*) AS clicks
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2 AND clicked_at BETWEEN $3 AND $4
+		GROUP BY period
+		ORDER BY period
+	`, expr)
+
+	// clicked_at is stored as text, so the driver hands the period back as a string
+	var rows []struct {
+		Period string `db:"period"`
+		Clicks int    `db:"clicks"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, namespace, shortCode, from.UTC(), to.UTC()); err != nil {
+		return nil, err
+	}
+
+	buckets := make([]domain.TimeBucket, 0, len(rows))
+	for _, row := range rows {
+		period, err := time.Parse(time.RFC3339, row.Period)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bucket period %q: %w", row.Period, err)
+		}
+		buckets = append(buckets, domain.TimeBucket{Period: period, Clicks: row.Clicks})
+	}
+
+	return buckets, nil
+}
+
+// ClickHeatmap counts clicks per UTC minute and converts the minutes to loc in Go, SQLite
+// only knows UTC and the server's local time. Minutes keep zones with half and quarter hour
+// offsets exact.
+func (r *URLRepository) ClickHeatmap(ctx context.Context, namespace, shortCode string, loc *time.Location) (*domain.ClickHeatmap, error) {
+	query := fmt.Sprintf(`
+		SELECT %s AS period, COUNT(*) AS clicks
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2
+		GROUP BY period
+	`, bucketExpressions[domain.GranularityMinute])
+
+	var rows []struct {
+		Period string `db:"period"`
+		Clicks int    `db:"clicks"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, namespace, shortCode); err != nil {
+		return nil, err
+	}
+
+	heatmap := &domain.ClickHeatmap{}
+	for _, row := range rows {
+		period, err := time.Parse(time.RFC3339, row.Period)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bucket period %q: %w", row.Period, err)
+		}
+		heatmap.Add(period.In(loc), row.Clicks)
+	}
+
+	return heatmap, nil
+}
+
+func (r *URLRepository) TopReferrers(ctx context.Context, namespace, shortCode string, limit int) ([]domain.ReferrerCount, error) {
+	query := `
+		SELECT referer, COUNT(*) AS count
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2
+		GROUP BY referer
+		ORDER BY count DESC, referer ASC
+		LIMIT $3
+	`
+
+	referrers := []domain.ReferrerCount{}
+	if err := r.db.SelectContext(ctx, &referrers, query, namespace, shortCode, limit); err != nil {
+		return nil, err
+	}
+
+	return referrers, nil
+}
+
+func (r *URLRepository) TopIPs(ctx context.Context, limit int) ([]domain.IPCount, error) {
+	query := `
+		SELECT ip_address, COUNT(*) AS count
+		FROM url_clicks
+		WHERE ip_address != ''
+		GROUP BY ip_address
+		ORDER BY count DESC, ip_address ASC
+		LIMIT $1
+	`
+
+	ips := []domain.IPCount{}
+	if err := r.db.SelectContext(ctx, &ips, query, limit); err != nil {
+		return nil, err
+	}
+
+	return ips, nil
+}
+
+// deviceGroupColumns maps each device grouping to the url_clicks columns it aggregates by
+var deviceGroupColumns = map[domain.DeviceGrouping]string{
+	domain.DeviceGroupingDevice:  "ua_device_type, ua_browser, ua_os",
+	domain.DeviceGroupingBrowser: "ua_browser",
+	domain.DeviceGroupingOS:      "ua_os",
+}
+
+func (r *URLRepository) DeviceStats(ctx context.Context, namespace, shortCode string, grouping domain.DeviceGrouping) ([]domain.DeviceStat, error) {
+	columns, ok := deviceGroupColumns[grouping]
+	if !ok {
+		return nil, fmt.Errorf("unsupported device grouping %q", grouping)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %[1]s, COUNT(*) AS count
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2
+		GROUP BY %[1]s
+		ORDER BY count DESC, %[1]s
+	`, columns)
+
+	stats := []domain.DeviceStat{}
+	if err := r.db.SelectContext(ctx, &stats, query, namespace, shortCode); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (r *URLRepository) VariantStats(ctx context.Context, namespace, shortCode string) ([]domain.VariantStat, error) {
+	query := `
+		SELECT v.id AS variant_id, v.original_url, v.weight, COUNT(c.id) AS clicks
+		FROM url_variants v
+		JOIN urls u ON u.id = v.url_id
+		LEFT JOIN url_clicks c ON c.variant_id = v.id
+		WHERE u.namespace = $1 AND u.short_code = $2
+		GROUP BY v.id, v.original_url, v.weight
+		ORDER BY v.id ASC
+	`
+
+	stats := []domain.VariantStat{}
+	if err := r.db.SelectContext(ctx, &stats, query, namespace, shortCode); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// RedirectLatency loads the recorded redirect durations in order and computes their
+// percentiles in Go, SQLite has no percentile_cont
+func (r *URLRepository) RedirectLatency(ctx context.Context, namespace, shortCode string) (*domain.LatencyStats, error) {
+	query := `
+		SELECT redirect_duration_ms
+		FROM url_clicks
+		WHERE namespace = $1 AND short_code = $2 AND redirect_duration_ms IS NOT NULL
+		ORDER BY redirect_duration_ms ASC
+	`
+
+	var durations []float64
+	if err := r.db.SelectContext(ctx, &durations, query, namespace, shortCode); err != nil {
+		return nil, err
+	}
+
+	stats := domain.NewLatencyStats(durations)
+	return &stats, nil
+}
+
+func (r *URLRepository) Stats(ctx context.Context, now, since time.Time) (*domain.ServiceStats, error) {
+	// Timestamps are stored as text with whatever offset they were written in, so they
+	// are compared as julian days rather than as strings
+	query := `
+		SELECT
+			COUNT(*) AS total_urls,
+			COALESCE(SUM(clicks), 0) AS total_clicks,
+			COUNT(*) FILTER (WHERE expires_at IS NULL OR julianday(expires_at) > julianday($1)) AS active_urls,
+			COUNT(*) FILTER (WHERE julianday(created_at) > julianday($2)) AS recent_urls,
+			(SELECT COUNT(*) FROM url_clicks WHERE julianday(clicked_at) > julianday($2)) AS recent_clicks
+		FROM urls
+	`
+
+	var stats domain.ServiceStats
+	if err := r.db.GetContext(ctx, &stats, query, now.UTC(), since.UTC()); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+func (r *URLRepository) CountActive(ctx context.Context, now time.Time) (int64, error) {
+	query := `SELECT COUNT(*) FROM urls WHERE enabled AND (expires_at IS NULL OR julianday(expires_at) > julianday($1))`
+
+	var count int64
+	if err := r.db.GetContext(ctx, &count, query, now.UTC()); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CountByCreatedDate relies on date(), which converts the offset created_at was written
+// in to UTC
+func (r *URLRepository) CountByCreatedDate(ctx context.Context, date time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM urls WHERE date(created_at) = $1`
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, date.UTC().Format(time.DateOnly)); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *URLRepository) DailyActivity(ctx context.Context, from, to time.Time) ([]domain.DailyActivity, error) {
+	query := `
+		SELECT day, SUM(urls_created) AS urls_created, SUM(clicks) AS clicks
+		FROM (
+			SELECT date(created_at) AS day, 1 AS urls_created, 0 AS clicks
+			FROM urls
+			WHERE date(created_at) BETWEEN $1 AND $2
+			UNION ALL
+			SELECT date(clicked_at), 0, 1
+			FROM url_clicks
+			WHERE date(clicked_at) BETWEEN $1 AND $2
+		)
+		GROUP BY day
+		ORDER BY day
+	`
+
+	// date() returns text, so the driver hands the day back as a string
+	var rows []struct {
+		Day         string `db:"day"`
+		URLsCreated int64  `db:"urls_created"`
+		Clicks      int64  `db:"clicks"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly)); err != nil {
+		return nil, err
+	}
+
+	days := make([]domain.DailyActivity, 0, len(rows))
+	for _, row := range rows {
+		day, err := time.Parse(time.DateOnly, row.Day)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse day %q: %w", row.Day, err)
+		}
+		days = append(days, domain.DailyActivity{Day: day, URLsCreated: row.URLsCreated, Clicks: row.Clicks})
+	}
+
+	return days, nil
+}
+
+func (r *URLRepository) AliasLengths(ctx context.Context) ([]domain.AliasLengthCount, error) {
+	query := `
+		SELECT is_custom_alias AS custom, LENGTH(short_code) AS length, COUNT(*) AS urls
+		FROM urls
+		GROUP BY is_custom_alias, LENGTH(short_code)
+		ORDER BY is_custom_alias, length
+	`
+
+	counts := []domain.AliasLengthCount{}
+	if err := r.db.SelectContext(ctx, &counts, query); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// DB returns the connection pool backing the repository
+func (r *URLRepository) DB() *sql.DB {
+	return r.db.DB
+}
+
 func (r *URLRepository) Close() error {
 	if r.db != nil {
 		return r.db.Close()
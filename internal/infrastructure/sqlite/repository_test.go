@@ -0,0 +1,1283 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+func newTestRepository(t *testing.T) *URLRepository {
+	t.Helper()
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "dove.db"))
+	require.NoError(t, err)
+
+	driver, err := sqlite3.WithInstance(db.DB, &sqlite3.Config{})
+	require.NoError(t, err)
+	migrationsPath, err := filepath.Abs("../../../migrations/sqlite")
+	require.NoError(t, err)
+	m, err := migrate.NewWithDatabaseInstance(fmt.Sprintf("file://%s", migrationsPath), "sqlite3", driver)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+
+	repo, err := NewURLRepository(db, Pragmas{JournalMode: "WAL", Synchronous: "NORMAL", BusyTimeoutMs: 5000}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
+
+func TestURLRepository_ClickTimeSeries(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("series", "https://example.com/series")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	// Wednesday 2024-01-03 and the following Monday
+	clicks := []time.Time{
+		time.Date(2024, 1, 3, 10, 15, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 10, 45, 30, 500, time.UTC),
+		time.Date(2024, 1, 3, 11, 5, 0, 0, time.UTC),
+		time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), // outside the queried range
+	}
+	for _, clickedAt := range clicks {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "series", ClickedAt: clickedAt}))
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		granularity domain.Granularity
+		expected    []domain.TimeBucket
+	}{
+		{domain.GranularityMinute, []domain.TimeBucket{
+			{Period: time.Date(2024, 1, 3, 10, 15, 0, 0, time.UTC), Clicks: 1},
+			{Period: time.Date(2024, 1, 3, 10, 45, 0, 0, time.UTC), Clicks: 1},
+			{Period: time.Date(2024, 1, 3, 11, 5, 0, 0, time.UTC), Clicks: 1},
+			{Period: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC), Clicks: 1},
+		}},
+		{domain.GranularityHour, []domain.TimeBucket{
+			{Period: time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC), Clicks: 2},
+			{Period: time.Date(2024, 1, 3, 11, 0, 0, 0, time.UTC), Clicks: 1},
+			{Period: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC), Clicks: 1},
+		}},
+		{domain.GranularityDay, []domain.TimeBucket{
+			{Period: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Clicks: 3},
+			{Period: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), Clicks: 1},
+		}},
+		{domain.GranularityWeek, []domain.TimeBucket{
+			{Period: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Clicks: 3},
+			{Period: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), Clicks: 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.granularity), func(t *testing.T) {
+			buckets, err := repo.ClickTimeSeries(ctx, domain.DefaultNamespace, "series", tt.granularity, from, to)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buckets)
+
+			// The in-database truncation must agree with the domain rules used by other backends
+			for _, bucket := range buckets {
+				assert.Equal(t, tt.granularity.Truncate(bucket.Period), bucket.Period)
+			}
+		})
+	}
+
+	t.Run("no clicks in range", func(t *testing.T) {
+		buckets, err := repo.ClickTimeSeries(ctx, domain.DefaultNamespace, "series", domain.GranularityDay, to.AddDate(1, 0, 0), to.AddDate(1, 1, 0))
+		require.NoError(t, err)
+		assert.Empty(t, buckets)
+		assert.NotNil(t, buckets)
+	})
+}
+
+func TestURLRepository_ClickHeatmap(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("heatmap", "https://example.com/heatmap")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	// Wednesday 2024-01-03 twice, Sunday 2024-01-07 and Monday 2024-07-01, in daylight saving time in New York
+	for _, clickedAt := range []time.Time{
+		time.Date(2024, 1, 3, 10, 15, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 10, 45, 0, 0, time.UTC),
+		time.Date(2024, 1, 7, 2, 30, 0, 0, time.UTC),
+		time.Date(2024, 7, 1, 3, 0, 0, 0, time.UTC),
+	} {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "heatmap", ClickedAt: clickedAt}))
+	}
+
+	tests := []struct {
+		tz    string
+		hours map[int]int
+		days  map[time.Weekday]int
+	}{
+		{"UTC", map[int]int{10: 2, 2: 1, 3: 1}, map[time.Weekday]int{time.Wednesday: 2, time.Sunday: 1, time.Monday: 1}},
+		{"America/New_York", map[int]int{5: 2, 21: 1, 23: 1}, map[time.Weekday]int{time.Wednesday: 2, time.Saturday: 1, time.Sunday: 1}},
+		{"Asia/Kolkata", map[int]int{15: 1, 16: 1, 8: 2}, map[time.Weekday]int{time.Wednesday: 2, time.Sunday: 1, time.Monday: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tz, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.tz)
+			require.NoError(t, err)
+
+			var expected domain.ClickHeatmap
+			for hour, clicks := range tt.hours {
+				expected.HourOfDay[hour] = clicks
+			}
+			for day, clicks := range tt.days {
+				expected.DayOfWeek[day] = clicks
+			}
+
+			heatmap, err := repo.ClickHeatmap(ctx, domain.DefaultNamespace, "heatmap", loc)
+			require.NoError(t, err)
+			assert.Equal(t, &expected, heatmap)
+		})
+	}
+
+	t.Run("no clicks", func(t *testing.T) {
+		heatmap, err := repo.ClickHeatmap(ctx, domain.DefaultNamespace, "unknown", time.UTC)
+		require.NoError(t, err)
+		assert.Equal(t, &domain.ClickHeatmap{}, heatmap)
+	})
+}
+
+func TestURLRepository_AddClicks(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, shortCode := range []string{"first", "second"} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	_, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, "first", true)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.AddClicks(ctx, []domain.ClickDelta{
+		{Namespace: domain.DefaultNamespace, ShortCode: "first", Clicks: 5, UniqueClicks: 2},
+		{Namespace: domain.DefaultNamespace, ShortCode: "second", Clicks: 1},
+		{Namespace: domain.DefaultNamespace, ShortCode: "deleted", Clicks: 3},
+	}))
+
+	for shortCode, want := range map[string][2]int{"first": {6, 3}, "second": {1, 0}} {
+		url, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+		require.NoError(t, err)
+		assert.Equal(t, want[0], url.Clicks, shortCode)
+		assert.Equal(t, want[1], url.UniqueClicks, shortCode)
+		require.NotNil(t, url.LastAccessedAt, shortCode)
+		assert.WithinDuration(t, time.Now(), *url.LastAccessedAt, time.Minute, shortCode)
+	}
+}
+
+func TestURLRepository_ResetClicks(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, shortCode := range []string{"reset", "kept"} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	for _, shortCode := range []string{"reset", "kept"} {
+		_, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, shortCode, true)
+		require.NoError(t, err)
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: shortCode, ClickedAt: time.Now(), Referer: "example.org"}))
+	}
+
+	require.NoError(t, repo.ResetClicks(ctx, domain.DefaultNamespace, "reset"))
+
+	for shortCode, clicks := range map[string]int{"reset": 0, "kept": 1} {
+		url, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+		require.NoError(t, err)
+		assert.Equal(t, clicks, url.Clicks, shortCode)
+		assert.Equal(t, clicks, url.UniqueClicks, shortCode)
+
+		referrers, err := repo.TopReferrers(ctx, domain.DefaultNamespace, shortCode, 10)
+		require.NoError(t, err)
+		assert.Len(t, referrers, clicks, shortCode)
+	}
+
+	assert.ErrorIs(t, repo.ResetClicks(ctx, domain.DefaultNamespace, "missing"), domain.ErrURLNotFound)
+}
+
+func TestURLRepository_Delete(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, shortCode := range []string{"deleted", "kept"} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		url.Variants = []domain.URLVariant{{OriginalURL: "https://example.com/a", Weight: 100}}
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: shortCode, ClickedAt: time.Now(), Referer: "example.org"}))
+	}
+
+	require.NoError(t, repo.Delete(ctx, domain.DefaultNamespace, "deleted"))
+
+	_, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "deleted")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	for table, want := range map[string]int{"url_variants": 1, "url_clicks": 1} {
+		var count int
+		require.NoError(t, repo.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM `+table))
+		assert.Equal(t, want, count, table)
+	}
+
+	kept, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "kept")
+	require.NoError(t, err)
+	assert.Len(t, kept.Variants, 1)
+
+	assert.ErrorIs(t, repo.Delete(ctx, domain.DefaultNamespace, "deleted"), domain.ErrURLNotFound)
+}
+
+func TestURLRepository_FindExpired(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for shortCode, expiresIn := range map[string]time.Duration{"expired1": -time.Hour, "expired2": -time.Minute, "expired3": -time.Second, "active": time.Hour} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		expiresAt := now.Add(expiresIn)
+		url.ExpiresAt = &expiresAt
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	permanent, err := domain.NewURL("permanent", "https://example.com/permanent")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, permanent)
+	require.NoError(t, err)
+
+	expired, err := repo.FindExpired(ctx, now, 10)
+	require.NoError(t, err)
+	shortCodes := make([]string, len(expired))
+	for i, url := range expired {
+		shortCodes[i] = url.ShortCode
+	}
+	assert.ElementsMatch(t, []string{"expired1", "expired2", "expired3"}, shortCodes)
+
+	limited, err := repo.FindExpired(ctx, now, 2)
+	require.NoError(t, err)
+	assert.Len(t, limited, 2)
+	assert.Less(t, limited[0].ID, limited[1].ID)
+}
+
+func TestURLRepository_List(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		url, err := domain.NewURL(fmt.Sprintf("list%d", i), fmt.Sprintf("https://example.com/%d", i))
+		require.NoError(t, err)
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	var codes []string
+	var afterID int64
+	for {
+		page, err := repo.List(ctx, afterID, 2)
+		require.NoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		assert.LessOrEqual(t, len(page), 2)
+		for _, url := range page {
+			assert.Greater(t, url.ID, afterID)
+			codes = append(codes, url.ShortCode)
+		}
+		afterID = page[len(page)-1].ID
+	}
+
+	assert.Equal(t, []string{"list0", "list1", "list2", "list3", "list4"}, codes)
+}
+
+func TestURLRepository_ListByCreatorIP(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for i, ip := range []string{"192.168.1.1", "", "192.168.1.1", "2001:db8::1", "192.168.1.1"} {
+		url, err := domain.NewURL(fmt.Sprintf("ip%d", i), fmt.Sprintf("https://example.com/%d", i))
+		require.NoError(t, err)
+		url.CreatedByIP = ip
+		created, err := repo.Create(ctx, url)
+		require.NoError(t, err)
+		assert.Equal(t, ip, created.CreatedByIP)
+	}
+
+	found, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "ip3")
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", found.CreatedByIP)
+
+	var createdBy sql.NullString
+	require.NoError(t, repo.db.GetContext(ctx, &createdBy, `SELECT created_by_ip FROM urls WHERE short_code = 'ip1'`))
+	assert.False(t, createdBy.Valid, "an unknown creator is stored as NULL")
+
+	first, err := repo.ListByCreatorIP(ctx, "192.168.1.1", 0, 2)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, "ip0", first[0].ShortCode)
+	assert.Equal(t, "ip2", first[1].ShortCode)
+
+	rest, err := repo.ListByCreatorIP(ctx, "192.168.1.1", first[1].ID, 2)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Equal(t, "ip4", rest[0].ShortCode)
+
+	none, err := repo.ListByCreatorIP(ctx, "10.0.0.1", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func TestURLRepository_DeleteMany(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, shortCode := range []string{"first", "second", "kept"} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		url.Variants = []domain.URLVariant{{OriginalURL: "https://example.com/a", Weight: 100}}
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: shortCode, ClickedAt: time.Now(), Referer: "example.org"}))
+	}
+	other, err := domain.NewURL("first", "https://example.com/other")
+	require.NoError(t, err)
+	other.Namespace = "team"
+	_, err = repo.Create(ctx, other)
+	require.NoError(t, err)
+
+	deleted, err := repo.DeleteMany(ctx, domain.DefaultNamespace, []string{"second", "missing", "first"})
+	require.NoError(t, err)
+	codes := make([]string, 0, len(deleted))
+	for _, url := range deleted {
+		codes = append(codes, url.ShortCode)
+	}
+	assert.Equal(t, []string{"first", "second"}, codes)
+
+	for table, want := range map[string]int{"urls": 2, "url_variants": 1, "url_clicks": 1} {
+		var count int
+		require.NoError(t, repo.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM `+table))
+		assert.Equal(t, want, count, table)
+	}
+	_, err = repo.FindByNamespaceAndCode(ctx, "team", "first")
+	assert.NoError(t, err, "other namespaces are left alone")
+
+	deleted, err = repo.DeleteMany(ctx, domain.DefaultNamespace, []string{"first"})
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+}
+
+func TestURLRepository_FindByShortCodes(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for shortCode, variants := range map[string]int{"first": 2, "second": 0, "third": 1} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		for i := range variants {
+			url.Variants = append(url.Variants, domain.URLVariant{OriginalURL: fmt.Sprintf("https://example.com/%s/%d", shortCode, i), Weight: 50})
+		}
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	other, err := domain.NewURL("second", "https://example.com/other")
+	require.NoError(t, err)
+	other.Namespace = "team"
+	_, err = repo.Create(ctx, other)
+	require.NoError(t, err)
+
+	urls, err := repo.FindByShortCodes(ctx, domain.DefaultNamespace, []string{"second", "missing", "first"})
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	codes := map[string]*domain.URL{}
+	for _, url := range urls {
+		codes[url.ShortCode] = url
+	}
+	require.Contains(t, codes, "first")
+	require.Contains(t, codes, "second")
+	assert.Equal(t, "https://example.com/second", codes["second"].OriginalURL, "other namespaces are left alone")
+	assert.Empty(t, codes["second"].Variants)
+	require.Len(t, codes["first"].Variants, 2)
+	assert.Equal(t, "https://example.com/first/0", codes["first"].Variants[0].OriginalURL)
+	assert.Equal(t, "https://example.com/first/1", codes["first"].Variants[1].OriginalURL)
+
+	urls, err = repo.FindByShortCodes(ctx, domain.DefaultNamespace, []string{"missing"})
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func TestURLRepository_Search(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, seed := range []struct{ shortCode, originalURL, description string }{
+		{"golang", "https://go.dev", ""},
+		{"docs", "https://example.com/GoLang/docs", ""},
+		{"rust", "https://rust-lang.org", "Systems language homepage"},
+		{"discount", "https://example.com/sale?off=100%25", ""},
+	} {
+		url, err := domain.NewURL(seed.shortCode, seed.originalURL)
+		require.NoError(t, err)
+		url.Description = seed.description
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		limit    int
+		expected []string
+	}{
+		{name: "short code", query: "rust", limit: 10, expected: []string{"rust"}},
+		{name: "original URL ignoring case", query: "golang", limit: 10, expected: []string{"golang", "docs"}},
+		{name: "limit", query: "golang", limit: 1, expected: []string{"golang"}},
+		{name: "description ignoring case", query: "SYSTEMS", limit: 10, expected: []string{"rust"}},
+		{name: "wildcards match literally", query: "%25", limit: 10, expected: []string{"discount"}},
+		{name: "underscore matches literally", query: "go_dev", limit: 10, expected: []string{}},
+		{name: "no match", query: "python", limit: 10, expected: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := repo.Search(ctx, tt.query, tt.limit)
+			require.NoError(t, err)
+
+			codes := make([]string, 0, len(urls))
+			for _, url := range urls {
+				codes = append(codes, url.ShortCode)
+			}
+			assert.Equal(t, tt.expected, codes)
+		})
+	}
+}
+
+func TestURLRepository_ListClicks(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("listed", "https://example.com")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	// Two clicks share every second, so pages have to break ties by ID
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 7 {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{
+			Namespace: domain.DefaultNamespace, ShortCode: "listed", ClickedAt: base.Add(time.Duration(i/2) * time.Second),
+			Referer: "(direct)", Browser: "Chrome", OS: "macOS", DeviceType: "desktop",
+			IPAddress: fmt.Sprintf("198.51.100.%d", i), UserAgent: "Mozilla/5.0", Country: "FR",
+		}))
+	}
+
+	var ips []string
+	var after domain.ClickCursor
+	for {
+		page, err := repo.ListClicks(ctx, domain.DefaultNamespace, "listed", after, 3)
+		require.NoError(t, err)
+		for _, click := range page {
+			ips = append(ips, click.IPAddress)
+			assert.Equal(t, "FR", click.Country)
+			assert.Equal(t, "Mozilla/5.0", click.UserAgent)
+		}
+		if len(page) < 3 {
+			break
+		}
+		after = page[len(page)-1].Cursor()
+	}
+	assert.Equal(t, []string{"198.51.100.0", "198.51.100.1", "198.51.100.2", "198.51.100.3", "198.51.100.4", "198.51.100.5", "198.51.100.6"}, ips)
+
+	clicks, err := repo.ListClicks(ctx, "other", "listed", domain.ClickCursor{}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, clicks)
+}
+
+func TestURLRepository_TopReferrers(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("refs", "https://example.com/refs")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	for referer, count := range map[string]int{"a.example.com": 1, "b.example.com/post": 3, domain.DirectReferer: 2} {
+		for i := 0; i < count; i++ {
+			require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "refs", ClickedAt: time.Now(), Referer: referer}))
+		}
+	}
+
+	referrers, err := repo.TopReferrers(ctx, domain.DefaultNamespace, "refs", 20)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ReferrerCount{
+		{Referer: "b.example.com/post", Count: 3},
+		{Referer: domain.DirectReferer, Count: 2},
+		{Referer: "a.example.com", Count: 1},
+	}, referrers)
+
+	referrers, err = repo.TopReferrers(ctx, domain.DefaultNamespace, "refs", 2)
+	require.NoError(t, err)
+	assert.Len(t, referrers, 2)
+
+	referrers, err = repo.TopReferrers(ctx, domain.DefaultNamespace, "unknown", 20)
+	require.NoError(t, err)
+	assert.Empty(t, referrers)
+}
+
+func TestURLRepository_TopIPs(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, shortCode := range []string{"ips-a", "ips-b"} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	clicks := []struct{ shortCode, ip string }{
+		{"ips-a", "203.0.113.1"}, {"ips-b", "203.0.113.1"}, {"ips-a", "2001:db8::1"},
+		{"ips-b", "2001:db8::1"}, {"ips-b", "203.0.113.1"}, {"ips-a", "198.51.100.7"},
+		{"ips-a", ""}, {"ips-b", ""}, {"ips-b", ""}, {"ips-b", ""},
+	}
+	for _, click := range clicks {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: click.shortCode, ClickedAt: time.Now(), IPAddress: click.ip}))
+	}
+
+	ips, err := repo.TopIPs(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.IPCount{
+		{IPAddress: "203.0.113.1", Count: 3},
+		{IPAddress: "2001:db8::1", Count: 2},
+		{IPAddress: "198.51.100.7", Count: 1},
+	}, ips, "clicks without an address are left out")
+
+	ips, err = repo.TopIPs(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, ips, 1)
+}
+
+func TestURLRepository_UniqueClicks(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("unique", "https://example.com/unique")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	var updated *domain.URL
+	for _, unique := range []bool{true, false, false, true} {
+		updated, err = repo.IncrementClicks(ctx, domain.DefaultNamespace, "unique", unique)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 4, updated.Clicks)
+	assert.Equal(t, 2, updated.UniqueClicks)
+}
+
+func TestURLRepository_RedirectLatency(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("latency", "https://example.com/latency")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	// Recorded out of order, plus a click from before durations were measured
+	for i := 100; i >= 1; i-- {
+		ms := float64(i)
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "latency", ClickedAt: time.Now(), RedirectDurationMs: &ms}))
+	}
+	require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "latency", ClickedAt: time.Now()}))
+
+	stats, err := repo.RedirectLatency(ctx, domain.DefaultNamespace, "latency")
+	require.NoError(t, err)
+	assert.Equal(t, 100, stats.Count)
+	assert.InDelta(t, 50.5, stats.P50, 1e-9)
+	assert.InDelta(t, 95.05, stats.P95, 1e-9)
+	assert.InDelta(t, 99.01, stats.P99, 1e-9)
+	assert.InDelta(t, 50.5, stats.Avg, 1e-9)
+
+	stats, err = repo.RedirectLatency(ctx, domain.DefaultNamespace, "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.LatencyStats{}, stats)
+}
+
+func TestURLRepository_DeviceStats(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("devices", "https://example.com/devices")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	clicks := []domain.Click{
+		{DeviceType: "mobile", Browser: "Chrome", OS: "Android"},
+		{DeviceType: "mobile", Browser: "Chrome", OS: "Android"},
+		{DeviceType: "desktop", Browser: "Chrome", OS: "Windows"},
+		{DeviceType: "mobile", Browser: "Safari", OS: "iOS"},
+	}
+	for _, click := range clicks {
+		click.Namespace = domain.DefaultNamespace
+		click.ShortCode = "devices"
+		click.ClickedAt = time.Now()
+		click.Referer = domain.DirectReferer
+		require.NoError(t, repo.RecordClick(ctx, &click))
+	}
+
+	stats, err := repo.DeviceStats(ctx, domain.DefaultNamespace, "devices", domain.DeviceGroupingDevice)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.DeviceStat{
+		{DeviceType: "mobile", Browser: "Chrome", OS: "Android", Count: 2},
+		{DeviceType: "desktop", Browser: "Chrome", OS: "Windows", Count: 1},
+		{DeviceType: "mobile", Browser: "Safari", OS: "iOS", Count: 1},
+	}, stats)
+
+	stats, err = repo.DeviceStats(ctx, domain.DefaultNamespace, "devices", domain.DeviceGroupingBrowser)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.DeviceStat{{Browser: "Chrome", Count: 3}, {Browser: "Safari", Count: 1}}, stats)
+
+	stats, err = repo.DeviceStats(ctx, domain.DefaultNamespace, "devices", domain.DeviceGroupingOS)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.DeviceStat{{OS: "Android", Count: 2}, {OS: "Windows", Count: 1}, {OS: "iOS", Count: 1}}, stats)
+
+	_, err = repo.DeviceStats(ctx, domain.DefaultNamespace, "devices", domain.DeviceGrouping("country"))
+	assert.Error(t, err)
+}
+
+func TestURLRepository_NamespacesIsolateShortCodes(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	for _, namespace := range []string{domain.DefaultNamespace, "acme"} {
+		url, err := domain.NewURL("shared", "https://example.com/"+namespace)
+		require.NoError(t, err)
+		url.Namespace = namespace
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	duplicate, err := domain.NewURL("shared", "https://example.com/again")
+	require.NoError(t, err)
+	duplicate.Namespace = "acme"
+	_, err = repo.Create(ctx, duplicate)
+	assert.ErrorIs(t, err, domain.ErrShortCodeExists)
+
+	_, err = repo.IncrementClicks(ctx, "acme", "shared", true)
+	require.NoError(t, err)
+	require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: "acme", ShortCode: "shared", ClickedAt: time.Now(), Referer: domain.DirectReferer}))
+
+	acme, err := repo.FindByNamespaceAndCode(ctx, "acme", "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/acme", acme.OriginalURL)
+	assert.Equal(t, 1, acme.Clicks)
+
+	fallback, err := repo.FindByShortCode(ctx, "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/default", fallback.OriginalURL)
+	assert.Equal(t, 0, fallback.Clicks)
+
+	referrers, err := repo.TopReferrers(ctx, domain.DefaultNamespace, "shared", 20)
+	require.NoError(t, err)
+	assert.Empty(t, referrers)
+
+	exists, err := repo.Exists(ctx, "other", "shared")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = repo.FindByNamespaceAndCode(ctx, "other", "shared")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestURLRepository_Variants(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("split", "https://example.com/landing")
+	require.NoError(t, err)
+	url.Variants = []domain.URLVariant{
+		{OriginalURL: "https://example.com/landing-a", Weight: 70},
+		{OriginalURL: "https://example.com/landing-b", Weight: 30},
+	}
+	created, err := repo.Create(ctx, url)
+	require.NoError(t, err)
+	require.Len(t, created.Variants, 2)
+
+	found, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "split")
+	require.NoError(t, err)
+	assert.Equal(t, created.Variants, found.Variants)
+
+	variantB := found.Variants[1].ID
+	for _, variantID := range []*int64{&variantB, &variantB, nil} {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{
+			Namespace: domain.DefaultNamespace,
+			ShortCode: "split",
+			ClickedAt: time.Now(),
+			Referer:   domain.DirectReferer,
+			VariantID: variantID,
+		}))
+	}
+
+	stats, err := repo.VariantStats(ctx, domain.DefaultNamespace, "split")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.VariantStat{
+		{VariantID: found.Variants[0].ID, OriginalURL: "https://example.com/landing-a", Weight: 70, Clicks: 0},
+		{VariantID: variantB, OriginalURL: "https://example.com/landing-b", Weight: 30, Clicks: 2},
+	}, stats)
+
+	// Plain URLs carry no variants
+	plain, err := domain.NewURL("plain", "https://example.com/plain")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, plain)
+	require.NoError(t, err)
+
+	found, err = repo.FindByShortCode(ctx, "plain")
+	require.NoError(t, err)
+	assert.Empty(t, found.Variants)
+
+	stats, err = repo.VariantStats(ctx, domain.DefaultNamespace, "plain")
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+}
+
+func TestURLRepository_Pool(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("pool", "https://eu.example.com")
+	require.NoError(t, err)
+	url.PoolEnabled = true
+	url.Pool = &domain.URLPool{Targets: []domain.PoolTarget{
+		{URL: "https://eu.example.com", Weight: 3},
+		{URL: "https://us.example.com", Weight: 1},
+	}}
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	found, err := repo.FindByShortCode(ctx, "pool")
+	require.NoError(t, err)
+	assert.True(t, found.PoolEnabled)
+	assert.Equal(t, url.Pool, found.Pool)
+
+	require.NoError(t, repo.RecordClick(ctx, &domain.Click{
+		Namespace: domain.DefaultNamespace,
+		ShortCode: "pool",
+		ClickedAt: time.Now(),
+		Referer:   domain.DirectReferer,
+		TargetURL: "https://us.example.com",
+	}))
+
+	plain, err := domain.NewURL("plain", "https://example.com")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, plain)
+	require.NoError(t, err)
+
+	found, err = repo.FindByShortCode(ctx, "plain")
+	require.NoError(t, err)
+	assert.False(t, found.PoolEnabled)
+	assert.Nil(t, found.Pool)
+}
+
+func TestURLRepository_GeoRoutes(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("docs", "https://example.com/docs")
+	require.NoError(t, err)
+	url.GeoRoutes = domain.GeoRoutes{
+		{CountryCode: "US", DestinationURL: "https://us.example.com/docs"},
+		{CountryCode: "DE", DestinationURL: "https://eu.example.com/docs"},
+	}
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	found, err := repo.FindByShortCode(ctx, "docs")
+	require.NoError(t, err)
+	assert.Equal(t, url.GeoRoutes, found.GeoRoutes)
+
+	plain, err := domain.NewURL("plain", "https://example.com")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, plain)
+	require.NoError(t, err)
+
+	found, err = repo.FindByShortCode(ctx, "plain")
+	require.NoError(t, err)
+	assert.Empty(t, found.GeoRoutes)
+}
+
+func TestURLRepository_DeviceRoutes(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("app", "https://example.com/app")
+	require.NoError(t, err)
+	url.DeviceRoutes = domain.DeviceRoutes{
+		{DeviceType: "mobile", DestinationURL: "https://m.example.com/app"},
+	}
+	_, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+
+	found, err := repo.FindByShortCode(ctx, "app")
+	require.NoError(t, err)
+	assert.Equal(t, url.DeviceRoutes, found.DeviceRoutes)
+}
+
+func TestURLRepository_Update(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("edit", "https://example.com/before")
+	require.NoError(t, err)
+	created, err := repo.Create(ctx, url)
+	require.NoError(t, err)
+	_, err = repo.IncrementClicks(ctx, domain.DefaultNamespace, "edit", true)
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	created.OriginalURL = "https://example.com/after"
+	created.RedirectType = 302
+	created.ExpiresAt = &expiresAt
+	created.Tags = domain.Tags{"docs"}
+	created.Description = "Before and after"
+	created.Title = "Destination page"
+
+	updated, err := repo.Update(ctx, created)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/after", updated.OriginalURL)
+	assert.Equal(t, 302, updated.RedirectType)
+	require.NotNil(t, updated.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*updated.ExpiresAt))
+	assert.Equal(t, domain.Tags{"docs"}, updated.Tags)
+	assert.Equal(t, "Before and after", updated.Description)
+	assert.Equal(t, "Destination page", updated.Title)
+	// Clicks have their own writer and are not overwritten
+	assert.Equal(t, 1, updated.Clicks)
+
+	updated.ExpiresAt = nil
+	updated.Tags = nil
+	updated.Description = ""
+	updated, err = repo.Update(ctx, updated)
+	require.NoError(t, err)
+	assert.Nil(t, updated.ExpiresAt)
+	assert.Empty(t, updated.Tags)
+	assert.Empty(t, updated.Description)
+
+	var description sql.NullString
+	require.NoError(t, repo.db.GetContext(ctx, &description, `SELECT description FROM urls WHERE short_code = 'edit'`))
+	assert.False(t, description.Valid, "an empty description is stored as NULL")
+
+	missing, err := domain.NewURL("missing", "https://example.com")
+	require.NoError(t, err)
+	_, err = repo.Update(ctx, missing)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestURLRepository_SetEnabled(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	url, err := domain.NewURL("toggle", "https://example.com/toggle")
+	require.NoError(t, err)
+	created, err := repo.Create(ctx, url)
+	require.NoError(t, err)
+	assert.True(t, created.Enabled)
+
+	require.NoError(t, repo.SetEnabled(ctx, domain.DefaultNamespace, "toggle", false))
+	found, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "toggle")
+	require.NoError(t, err)
+	assert.False(t, found.Enabled)
+
+	// Editing a URL leaves its state alone
+	found.OriginalURL = "https://example.com/edited"
+	found.Enabled = true
+	updated, err := repo.Update(ctx, found)
+	require.NoError(t, err)
+	assert.False(t, updated.Enabled)
+
+	require.NoError(t, repo.SetEnabled(ctx, domain.DefaultNamespace, "toggle", true))
+	found, err = repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "toggle")
+	require.NoError(t, err)
+	assert.True(t, found.Enabled)
+
+	assert.ErrorIs(t, repo.SetEnabled(ctx, "team", "toggle", false), domain.ErrURLNotFound)
+}
+
+func TestURLRepository_MarkGoalReached(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	goal := 3
+	url, err := domain.NewURL("campaign", "https://example.com")
+	require.NoError(t, err)
+	url.ClickGoal = &goal
+	created, err := repo.Create(ctx, url)
+	require.NoError(t, err)
+	require.NotNil(t, created.ClickGoal)
+	assert.Equal(t, 3, *created.ClickGoal)
+	assert.False(t, created.GoalReached)
+
+	reached, err := repo.MarkGoalReached(ctx, domain.DefaultNamespace, "campaign")
+	require.NoError(t, err)
+	assert.True(t, reached)
+	reached, err = repo.MarkGoalReached(ctx, domain.DefaultNamespace, "campaign")
+	require.NoError(t, err)
+	assert.False(t, reached, "the goal is only marked once")
+
+	found, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "campaign")
+	require.NoError(t, err)
+	assert.True(t, found.GoalReached)
+
+	reached, err = repo.MarkGoalReached(ctx, domain.DefaultNamespace, "missing")
+	require.NoError(t, err)
+	assert.False(t, reached)
+}
+
+func TestURLRepository_FindNotAccessedSince(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+
+	create := func(shortCode string, createdAt time.Time, lastAccessedAt *time.Time) {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		url.CreatedAt = createdAt
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+		if lastAccessedAt != nil {
+			_, err = repo.db.Exec("UPDATE urls SET last_accessed_at = $1 WHERE short_code = $2", lastAccessedAt.UTC(), shortCode)
+			require.NoError(t, err)
+		}
+	}
+
+	staleClick, recentClick := daysAgo(45), daysAgo(2)
+	create("never", daysAgo(40), nil)
+	create("stale", daysAgo(90), &staleClick)
+	create("recent", daysAgo(90), &recentClick)
+	create("fresh", daysAgo(5), nil)
+	create("suspended", daysAgo(90), nil)
+	require.NoError(t, repo.SetEnabled(ctx, domain.DefaultNamespace, "suspended", false))
+
+	shortCodes := func(urls []*domain.URL) []string {
+		codes := make([]string, 0, len(urls))
+		for _, url := range urls {
+			codes = append(codes, url.ShortCode)
+		}
+		return codes
+	}
+
+	cold, err := repo.FindNotAccessedSince(ctx, daysAgo(30), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stale", "never"}, shortCodes(cold))
+	require.NotNil(t, cold[0].LastAccessedAt)
+	assert.WithinDuration(t, staleClick, *cold[0].LastAccessedAt, time.Second)
+	assert.Nil(t, cold[1].LastAccessedAt)
+
+	cold, err = repo.FindNotAccessedSince(ctx, daysAgo(30), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stale"}, shortCodes(cold))
+
+	// A click makes a URL warm again
+	clicked, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, "never", false)
+	require.NoError(t, err)
+	require.NotNil(t, clicked.LastAccessedAt)
+	assert.WithinDuration(t, time.Now(), *clicked.LastAccessedAt, time.Minute)
+
+	cold, err = repo.FindNotAccessedSince(ctx, daysAgo(30), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stale"}, shortCodes(cold))
+}
+
+func TestURLRepository_AliasLengths(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	counts, err := repo.AliasLengths(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+
+	for shortCode, custom := range map[string]bool{"aB3dE6": false, "x9Y8z7": false, "Qw3rTy7": false, "promo": true, "sale": true, "mega": true} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		url.IsCustomAlias = custom
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	found, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "promo")
+	require.NoError(t, err)
+	assert.True(t, found.IsCustomAlias)
+
+	counts, err = repo.AliasLengths(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.AliasLengthCount{
+		{Custom: false, Length: 6, URLs: 2},
+		{Custom: false, Length: 7, URLs: 1},
+		{Custom: true, Length: 4, URLs: 2},
+		{Custom: true, Length: 5, URLs: 1},
+	}, counts)
+}
+
+func TestURLRepository_CountActive(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	for shortCode, expiresAt := range map[string]*time.Time{"permanent": nil, "later": &future, "expired": &past, "paused": nil} {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		url.ExpiresAt = expiresAt
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.SetEnabled(ctx, domain.DefaultNamespace, "paused", false))
+
+	count, err := repo.CountActive(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestURLRepository_DailyActivity(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	day := func(date string, hour int) time.Time {
+		parsed, err := time.Parse(time.DateOnly, date)
+		require.NoError(t, err)
+		return parsed.Add(time.Duration(hour) * time.Hour)
+	}
+
+	// Written with their offset, 23:30 on January 1st in New York is January 2nd in UTC
+	newYork := time.FixedZone("EST", -5*60*60)
+	created := map[string]time.Time{
+		"first":  day("2024-01-01", 0),
+		"second": day("2024-01-01", 23),
+		"third":  time.Date(2024, 1, 1, 23, 30, 0, 0, newYork),
+		"fourth": day("2024-01-05", 12),
+	}
+	for shortCode, at := range created {
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+		_, err = repo.db.Exec("UPDATE urls SET created_at = $1 WHERE short_code = $2", at, shortCode)
+		require.NoError(t, err)
+	}
+	for _, click := range []struct {
+		shortCode string
+		at        time.Time
+	}{
+		{"first", day("2024-01-01", 10)},
+		{"first", day("2024-01-02", 10)},
+		{"second", time.Date(2024, 1, 2, 20, 0, 0, 0, newYork)},
+		{"fourth", day("2024-01-08", 0)},
+	} {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: click.shortCode, ClickedAt: click.at, Referer: domain.DirectReferer}))
+	}
+
+	for date, expected := range map[string]int{"2024-01-01": 2, "2024-01-02": 1, "2024-01-03": 0, "2024-01-05": 1} {
+		count, err := repo.CountByCreatedDate(ctx, day(date, 0))
+		require.NoError(t, err)
+		assert.Equal(t, expected, count, date)
+	}
+
+	activity, err := repo.DailyActivity(ctx, day("2024-01-01", 0), day("2024-01-07", 0))
+	require.NoError(t, err)
+	assert.Equal(t, []domain.DailyActivity{
+		{Day: day("2024-01-01", 0), URLsCreated: 2, Clicks: 1},
+		{Day: day("2024-01-02", 0), URLsCreated: 1, Clicks: 1},
+		{Day: day("2024-01-03", 0), URLsCreated: 0, Clicks: 1},
+		{Day: day("2024-01-05", 0), URLsCreated: 1, Clicks: 0},
+	}, activity)
+}
+
+func TestURLRepository_Pragmas(t *testing.T) {
+	repo := newTestRepository(t)
+
+	var journalMode, synchronous string
+	var busyTimeout int
+	require.NoError(t, repo.db.Get(&journalMode, "PRAGMA journal_mode"))
+	require.NoError(t, repo.db.Get(&busyTimeout, "PRAGMA busy_timeout"))
+	require.NoError(t, repo.db.Get(&synchronous, "PRAGMA synchronous"))
+	assert.Equal(t, "wal", journalMode)
+	assert.Equal(t, 5000, busyTimeout)
+	assert.Equal(t, "1", synchronous) // NORMAL
+
+	t.Run("invalid values are rejected", func(t *testing.T) {
+		db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "invalid.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+
+		_, err = NewURLRepository(db, Pragmas{JournalMode: "WAL; DROP TABLE urls"}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+		assert.ErrorContains(t, err, "invalid SQLite journal mode")
+	})
+}
+
+func TestURLRepository_ConcurrentWrites(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url, err := domain.NewURL(fmt.Sprintf("busy%d", i), "https://example.com")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if _, err := repo.Create(ctx, url); err != nil {
+				errs <- err
+				return
+			}
+			// Mix in updates so writers contend for the lock
+			if _, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, url.ShortCode, true); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	var count int
+	require.NoError(t, repo.db.Get(&count, "SELECT COUNT(*) FROM urls"))
+	assert.Equal(t, 50, count)
+}
+
+func TestURLRepository_Stats(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+	// Timestamps written with different offsets still compare as instants
+	plusFive := time.FixedZone("UTC+5", 5*60*60)
+
+	fresh, err := domain.NewURL("fresh", "https://example.com/fresh")
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, fresh)
+	require.NoError(t, err)
+
+	old, err := domain.NewURL("old", "https://example.com/old")
+	require.NoError(t, err)
+	old.CreatedAt = now.Add(-48 * time.Hour)
+	expired := now.Add(-time.Hour)
+	old.ExpiresAt = &expired
+	_, err = repo.Create(ctx, old)
+	require.NoError(t, err)
+
+	expiring, err := domain.NewURL("expiring", "https://example.com/expiring")
+	require.NoError(t, err)
+	expiring.CreatedAt = now.Add(-2 * time.Hour).In(plusFive)
+	expiresAt := now.Add(time.Hour).In(plusFive)
+	expiring.ExpiresAt = &expiresAt
+	_, err = repo.Create(ctx, expiring)
+	require.NoError(t, err)
+
+	for range 2 {
+		_, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, "fresh", true)
+		require.NoError(t, err)
+	}
+	_, err = repo.IncrementClicks(ctx, domain.DefaultNamespace, "old", true)
+	require.NoError(t, err)
+	for _, clickedAt := range []time.Time{now.Add(-time.Minute), now.Add(-23 * time.Hour), now.Add(-25 * time.Hour)} {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "fresh", ClickedAt: clickedAt}))
+	}
+
+	stats, err := repo.Stats(ctx, now, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, &domain.ServiceStats{
+		TotalURLs:    3,
+		TotalClicks:  3,
+		ActiveURLs:   2,
+		RecentURLs:   2,
+		RecentClicks: 2,
+	}, stats)
+
+	t.Run("empty repository", func(t *testing.T) {
+		stats, err := newTestRepository(t).Stats(ctx, now, now.Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, &domain.ServiceStats{}, stats)
+	})
+}
+
+func TestURLRepository_Funnels(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	steps := []domain.FunnelStep{
+		{Order: 1, Namespace: domain.DefaultNamespace, ShortCode: "landing"},
+		{Order: 2, Namespace: "team", ShortCode: "signup"},
+	}
+	created, err := repo.CreateFunnel(ctx, &domain.Funnel{Name: "Signup", Steps: steps, CreatedAt: time.Now().UTC()})
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+
+	found, err := repo.FindFunnel(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Signup", found.Name)
+	assert.Equal(t, steps, found.Steps)
+
+	_, err = repo.FindFunnel(ctx, created.ID+1)
+	assert.ErrorIs(t, err, domain.ErrFunnelNotFound)
+
+	refs, err := repo.FindFunnelSteps(ctx, "team", "signup")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.FunnelStepRef{{FunnelID: created.ID, StepOrder: 2}}, refs)
+	refs, err = repo.FindFunnelSteps(ctx, domain.DefaultNamespace, "signup")
+	require.NoError(t, err)
+	assert.Empty(t, refs, "steps are matched by namespace too")
+
+	for _, click := range []domain.FunnelClick{
+		{FunnelID: created.ID, StepOrder: 1, SessionID: "b"},
+		{FunnelID: created.ID, StepOrder: 1, SessionID: "a"},
+		{FunnelID: created.ID, StepOrder: 1, SessionID: "a"},
+		{FunnelID: created.ID, StepOrder: 2, SessionID: "a"},
+	} {
+		click.ClickedAt = time.Now().UTC()
+		require.NoError(t, repo.RecordFunnelClick(ctx, &click))
+	}
+
+	sessionSteps, err := repo.FunnelSessionSteps(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.FunnelSessionStep{
+		{SessionID: "a", StepOrder: 1},
+		{SessionID: "a", StepOrder: 2},
+		{SessionID: "b", StepOrder: 1},
+	}, sessionSteps)
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+// Operation names recorded in audit entries
+const (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationReset   = "reset_analytics"
+	OperationDisable = "disable"
+	OperationEnable  = "enable"
+)
+
+// Entry is a single audit record, written as one JSON line
+type Entry struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Operation   string    `json:"operation"`
+	Namespace   string    `json:"namespace"`
+	ShortCode   string    `json:"shortCode"`
+	OriginalURL string    `json:"originalUrl"`
+	ActorIP     string    `json:"actorIp"`
+	RequestID   string    `json:"requestId"`
+}
+
+// AuditLogger appends audit entries as JSON lines to a dedicated writer,
+// kept separate from the application log so it can be retained and shipped independently
+type AuditLogger struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewAuditLogger creates an audit logger writing to w. The caller keeps ownership of w.
+func NewAuditLogger(w io.Writer) *AuditLogger {
+	return &AuditLogger{enc: json.NewEncoder(w)}
+}
+
+// NewFileAuditLogger opens path in append-only mode and returns an audit logger writing to it
+func NewFileAuditLogger(path string) (*AuditLogger, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &AuditLogger{enc: json.NewEncoder(f), closer: f}, nil
+}
+
+// Log writes entry to the audit trail. Missing timestamp, actor IP and request ID
+// are filled in from the current time and the request context.
+func (l *AuditLogger) Log(ctx context.Context, entry Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	if entry.ActorIP == "" {
+		entry.ActorIP = logging.ClientIPFromContext(ctx)
+	}
+	if entry.RequestID == "" {
+		entry.RequestID = logging.RequestIDFromContext(ctx)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.enc.Encode(entry); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Close releases the audit log file, if the logger owns one
+func (l *AuditLogger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
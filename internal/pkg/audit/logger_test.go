@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+)
+
+func TestAuditLogger_FileContainsAllFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	auditLogger, err := NewFileAuditLogger(path)
+	require.NoError(t, err)
+
+	ctx := logging.WithRequestID(context.Background(), "req-123")
+	ctx = logging.WithClientIP(ctx, "203.0.113.7")
+
+	require.NoError(t, auditLogger.Log(ctx, Entry{
+		Operation:   OperationCreate,
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com",
+	}))
+	require.NoError(t, auditLogger.Log(ctx, Entry{
+		Operation:   OperationDelete,
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com",
+	}))
+	require.NoError(t, auditLogger.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, lines, 2)
+
+	for _, field := range []string{"timestamp", "operation", "shortCode", "originalUrl", "actorIp", "requestId"} {
+		assert.Contains(t, lines[0], field)
+	}
+	assert.Equal(t, OperationCreate, lines[0]["operation"])
+	assert.Equal(t, OperationDelete, lines[1]["operation"])
+	assert.Equal(t, "abc123", lines[0]["shortCode"])
+	assert.Equal(t, "https://example.com", lines[0]["originalUrl"])
+	assert.Equal(t, "203.0.113.7", lines[0]["actorIp"])
+	assert.Equal(t, "req-123", lines[0]["requestId"])
+
+	_, err = time.Parse(time.RFC3339Nano, lines[0]["timestamp"].(string))
+	assert.NoError(t, err)
+}
+
+func TestAuditLogger_AppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for i := 0; i < 2; i++ {
+		auditLogger, err := NewFileAuditLogger(path)
+		require.NoError(t, err)
+		require.NoError(t, auditLogger.Log(context.Background(), Entry{Operation: OperationCreate, ShortCode: "abc"}))
+		require.NoError(t, auditLogger.Close())
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(data, []byte("\n")))
+}
+
+func TestAuditLogger_ExplicitFieldsAreKept(t *testing.T) {
+	var buf bytes.Buffer
+	auditLogger := NewAuditLogger(&buf)
+
+	ts := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	ctx := logging.WithClientIP(context.Background(), "203.0.113.7")
+
+	require.NoError(t, auditLogger.Log(ctx, Entry{
+		Timestamp: ts,
+		Operation: OperationUpdate,
+		ShortCode: "abc",
+		ActorIP:   "198.51.100.1",
+	}))
+
+	var entry Entry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.True(t, ts.Equal(entry.Timestamp))
+	assert.Equal(t, "198.51.100.1", entry.ActorIP)
+	assert.Empty(t, entry.RequestID)
+}
@@ -0,0 +1,54 @@
+// Package geoip determines which country a request comes from.
+package geoip
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Locator resolves the country of a request to an upper case ISO 3166-1 alpha-2 code,
+// or "" when it cannot be determined
+type Locator interface {
+	Country(r *http.Request) string
+}
+
+// HeaderLocator reads the country from a header set by an edge proxy or CDN, such as
+// Cloudflare's CF-IPCountry. The header must be set by a trusted proxy, as clients can
+// send it themselves.
+type HeaderLocator string
+
+func (h HeaderLocator) Country(r *http.Request) string {
+	country := strings.ToUpper(strings.TrimSpace(r.Header.Get(string(h))))
+	// Cloudflare reports XX for unknown and T1 for Tor exit nodes
+	if len(country) != 2 || country == "XX" || country == "T1" {
+		return ""
+	}
+	return country
+}
+
+type contextKey struct{}
+
+// WithCountry returns a copy of ctx carrying the request country
+func WithCountry(ctx context.Context, country string) context.Context {
+	return context.WithValue(ctx, contextKey{}, country)
+}
+
+// CountryFromContext returns the country stored by Middleware, or "" when unknown
+func CountryFromContext(ctx context.Context) string {
+	country, _ := ctx.Value(contextKey{}).(string)
+	return country
+}
+
+// Middleware resolves the country of every request with locator and stores it in the
+// request context
+func Middleware(locator Locator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if country := locator.Country(r); country != "" {
+				r = r.WithContext(WithCountry(r.Context(), country))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package geoip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderLocator(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "upper case", header: "US", expected: "US"},
+		{name: "lower case", header: "de", expected: "DE"},
+		{name: "missing", header: "", expected: ""},
+		{name: "unknown", header: "XX", expected: ""},
+		{name: "tor", header: "T1", expected: ""},
+		{name: "not a country code", header: "USA", expected: ""},
+	}
+
+	locator := HeaderLocator("CF-IPCountry")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("CF-IPCountry", tt.header)
+			}
+			assert.Equal(t, tt.expected, locator.Country(r))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var country string
+	handler := Middleware(HeaderLocator("CF-IPCountry"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		country = CountryFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("CF-IPCountry", "fr")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "FR", country)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, country)
+}
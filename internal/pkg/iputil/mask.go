@@ -0,0 +1,22 @@
+package iputil
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// MaskIP hides the last part of an address so that it can be shown to anyone: the last
+// octet of an IPv4 address, or the last group of an IPv6 one, becomes xxx. It returns ""
+// when addr is not an address.
+func MaskIP(addr string) string {
+	normalized := NormalizeIP(addr)
+	if normalized == "" {
+		return ""
+	}
+
+	separator := ":"
+	if netip.MustParseAddr(normalized).Is4() {
+		separator = "."
+	}
+	return normalized[:strings.LastIndex(normalized, separator)+1] + "xxx"
+}
@@ -0,0 +1,29 @@
+package iputil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		expected string
+	}{
+		{name: "ipv4", addr: "192.168.1.1", expected: "192.168.1.xxx"},
+		{name: "ipv4-mapped", addr: "::ffff:203.0.113.7", expected: "203.0.113.xxx"},
+		{name: "ipv6", addr: "2001:db8:85a3::8a2e:370:7334", expected: "2001:db8:85a3::8a2e:370:xxx"},
+		{name: "ipv6 compressed tail", addr: "2001:db8::", expected: "2001:db8::xxx"},
+		{name: "ipv6 loopback", addr: "::1", expected: "::xxx"},
+		{name: "empty", addr: "", expected: ""},
+		{name: "not an address", addr: "localhost", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MaskIP(tt.addr))
+		})
+	}
+}
@@ -0,0 +1,20 @@
+// Package iputil normalises client IP addresses so that one visitor is always stored the
+// same way.
+package iputil
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// NormalizeIP returns the canonical form of an IPv4 or IPv6 address, or "" when addr is
+// not one. IPv4-mapped IPv6 addresses such as ::ffff:192.168.1.1 become plain IPv4, IPv6
+// addresses are compressed and lower cased, and zones are dropped.
+func NormalizeIP(addr string) string {
+	addr = strings.Trim(strings.TrimSpace(addr), "[]")
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return ""
+	}
+	return ip.Unmap().WithZone("").String()
+}
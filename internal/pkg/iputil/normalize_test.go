@@ -0,0 +1,34 @@
+package iputil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		expected string
+	}{
+		{name: "ipv4", addr: "192.168.1.1", expected: "192.168.1.1"},
+		{name: "ipv4 with spaces", addr: " 203.0.113.7 ", expected: "203.0.113.7"},
+		{name: "ipv6", addr: "2001:db8::1", expected: "2001:db8::1"},
+		{name: "ipv6 expanded upper case", addr: "2001:0DB8:0000:0000:0000:0000:0000:0001", expected: "2001:db8::1"},
+		{name: "ipv6 loopback", addr: "::1", expected: "::1"},
+		{name: "ipv6 in brackets", addr: "[2001:db8::1]", expected: "2001:db8::1"},
+		{name: "ipv6 with zone", addr: "fe80::1%eth0", expected: "fe80::1"},
+		{name: "ipv4-mapped", addr: "::ffff:192.168.1.1", expected: "192.168.1.1"},
+		{name: "ipv4-mapped hex", addr: "::ffff:c0a8:101", expected: "192.168.1.1"},
+		{name: "empty", addr: "", expected: ""},
+		{name: "hostname", addr: "localhost", expected: ""},
+		{name: "with port", addr: "192.168.1.1:8080", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NormalizeIP(tt.addr))
+		})
+	}
+}
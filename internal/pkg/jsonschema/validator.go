@@ -0,0 +1,117 @@
+// Package jsonschema checks request bodies against JSON Schemas embedded in the binary, for
+// rules on raw JSON values that struct validation cannot express.
+package jsonschema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	schemalib "github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+//go:embed schemas/*.json
+var schemas embed.FS
+
+// CreateURLRequest checks the body of a short URL creation
+var CreateURLRequest = mustCompile("schemas/create_url_request.json")
+
+// Error is one rule a document broke
+type Error struct {
+	Field   string `json:"field" example:"url"` // dotted path of the value, empty for the whole document
+	Message string `json:"message" example:"url must not point to localhost or a private network"`
+}
+
+// Validator checks documents against one compiled schema
+type Validator struct {
+	schema *schemalib.Schema
+	// document is the decoded schema, read for the description of a failed rule
+	document any
+}
+
+// mustCompile compiles an embedded schema; they are part of the binary, so a schema that
+// does not compile is a programming error
+func mustCompile(path string) *Validator {
+	data, err := schemas.ReadFile(path)
+	if err != nil {
+		panic(fmt.Sprintf("jsonschema: %v", err))
+	}
+
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		panic(fmt.Sprintf("jsonschema: %s: %v", path, err))
+	}
+
+	compiler := schemalib.NewCompiler()
+	if err := compiler.AddResource(path, bytes.NewReader(data)); err != nil {
+		panic(fmt.Sprintf("jsonschema: %s: %v", path, err))
+	}
+	schema, err := compiler.Compile(path)
+	if err != nil {
+		panic(fmt.Sprintf("jsonschema: %s: %v", path, err))
+	}
+
+	return &Validator{schema: schema, document: document}
+}
+
+// Validate returns the rules body breaks, none when it is valid. An error is only returned
+// when body is not JSON.
+func (v *Validator) Validate(body []byte) ([]Error, error) {
+	var instance any
+	if err := json.Unmarshal(body, &instance); err != nil {
+		return nil, err
+	}
+
+	err := v.schema.Validate(instance)
+	var validationErr *schemalib.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	var violations []Error
+	v.collect(validationErr, &violations)
+	return violations, nil
+}
+
+// collect appends the innermost errors of err, which name the rules actually broken
+func (v *Validator) collect(err *schemalib.ValidationError, violations *[]Error) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			v.collect(cause, violations)
+		}
+		return
+	}
+
+	message := err.Message
+	if description, ok := v.description(err.KeywordLocation); ok {
+		message = description
+	}
+	*violations = append(*violations, Error{
+		Field:   strings.ReplaceAll(strings.TrimPrefix(err.InstanceLocation, "/"), "/", "."),
+		Message: message,
+	})
+}
+
+// description returns the description of the subschema at the JSON pointer location, which
+// the schemas use to explain their rules
+func (v *Validator) description(location string) (string, bool) {
+	node := v.document
+	for _, token := range strings.Split(strings.TrimPrefix(location, "/"), "/") {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		node = object[token]
+	}
+
+	object, ok := node.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	description, ok := object["description"].(string)
+	return description, ok
+}
@@ -0,0 +1,71 @@
+// Package lock provides short-lived locks shared by every instance of the service.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Locker grants exclusive use of a key across instances
+type Locker interface {
+	// TryLock takes key for at most ttl without waiting for it; ok is false when another
+	// holder has it. unlock releases the lock early and does nothing once it expired.
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// releaseScript deletes the lock only while it still holds the token of its owner, so that
+// a holder whose lock expired cannot release the lock of the next one
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// releaseTimeout bounds the release of a lock, which outlives the context it was taken with
+const releaseTimeout = time.Second
+
+// RedisLock implements Locker with SET NX PX, holding each lock under its own Redis key
+type RedisLock struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLock creates a locker whose keys start with prefix
+func NewRedisLock(client *redis.Client, prefix string) *RedisLock {
+	return &RedisLock{client: client, prefix: prefix}
+}
+
+func (l *RedisLock) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, false, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+	lockKey := l.buildKey(key)
+
+	ok, err := l.client.SetNX(ctx, lockKey, token, ttl).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lock %s: %w", key, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+		defer cancel()
+		// A lock that fails to release expires after ttl anyway
+		_ = releaseScript.Run(releaseCtx, l.client, []string{lockKey}, token).Err()
+	}
+	return unlock, true, nil
+}
+
+func (l *RedisLock) buildKey(key string) string {
+	return fmt.Sprintf("%s:lock:%s", l.prefix, key)
+}
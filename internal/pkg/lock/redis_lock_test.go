@@ -0,0 +1,163 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockHook stands in for the Redis server, answering SET NX and the release script from
+// an in-memory map of keys to tokens
+type lockHook struct {
+	mu   sync.Mutex
+	keys map[string]string
+	sets []redis.Cmder
+}
+
+func newLockHook() *lockHook {
+	return &lockHook{keys: make(map[string]string)}
+}
+
+// expire drops key, as Redis does once the lock TTL passes
+func (h *lockHook) expire(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.keys, key)
+}
+
+func (h *lockHook) DialHook(redis.DialHook) redis.DialHook {
+	return func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("unexpected dial")
+	}
+}
+
+func (h *lockHook) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(_ context.Context, cmd redis.Cmder) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		args := cmd.Args()
+		switch cmd.Name() {
+		case "set":
+			h.sets = append(h.sets, cmd)
+			key, token := args[1].(string), args[2].(string)
+			_, taken := h.keys[key]
+			if !taken {
+				h.keys[key] = token
+			}
+			cmd.(*redis.BoolCmd).SetVal(!taken)
+		case "evalsha":
+			key, token := args[3].(string), args[4].(string)
+			var deleted int64
+			if h.keys[key] == token {
+				delete(h.keys, key)
+				deleted = 1
+			}
+			cmd.(*redis.Cmd).SetVal(deleted)
+		default:
+			return errors.New("unexpected command " + cmd.Name())
+		}
+		return nil
+	}
+}
+
+func (h *lockHook) ProcessPipelineHook(redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(context.Context, []redis.Cmder) error {
+		return errors.New("unexpected pipeline")
+	}
+}
+
+func newTestLock(t *testing.T) (*RedisLock, *lockHook) {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	t.Cleanup(func() { _ = client.Close() })
+	hook := newLockHook()
+	client.AddHook(hook)
+	return NewRedisLock(client, "dove"), hook
+}
+
+func TestRedisLock_TryLock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sets the key with NX and a millisecond TTL", func(t *testing.T) {
+		locker, hook := newTestLock(t)
+
+		unlock, ok, err := locker.TryLock(ctx, "alias:default:promo", 1500*time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, ok)
+		defer unlock()
+
+		require.Len(t, hook.sets, 1)
+		args := hook.sets[0].Args()
+		assert.Equal(t, "dove:lock:alias:default:promo", args[1])
+		assert.Equal(t, []any{"px", int64(1500), "nx"}, args[3:])
+	})
+
+	t.Run("refuses a held lock until it is released", func(t *testing.T) {
+		locker, _ := newTestLock(t)
+
+		unlock, ok, err := locker.TryLock(ctx, "alias:default:promo", time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		_, ok, err = locker.TryLock(ctx, "alias:default:promo", time.Second)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		_, ok, err = locker.TryLock(ctx, "alias:default:other", time.Second)
+		require.NoError(t, err)
+		assert.True(t, ok, "other keys are not affected")
+
+		unlock()
+		_, ok, err = locker.TryLock(ctx, "alias:default:promo", time.Second)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("an expired holder does not release the next one", func(t *testing.T) {
+		locker, hook := newTestLock(t)
+
+		staleUnlock, ok, err := locker.TryLock(ctx, "alias:default:promo", time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+		hook.expire("dove:lock:alias:default:promo")
+
+		_, ok, err = locker.TryLock(ctx, "alias:default:promo", time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		staleUnlock()
+		_, ok, err = locker.TryLock(ctx, "alias:default:promo", time.Second)
+		require.NoError(t, err)
+		assert.False(t, ok, "the lock still belongs to its second holder")
+	})
+
+	t.Run("one of many concurrent callers gets the lock", func(t *testing.T) {
+		locker, _ := newTestLock(t)
+
+		var acquired atomic.Int32
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, ok, err := locker.TryLock(ctx, "alias:default:promo", time.Second)
+				assert.NoError(t, err)
+				if ok {
+					acquired.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), acquired.Load())
+	})
+}
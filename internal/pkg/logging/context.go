@@ -13,6 +13,7 @@ const (
 	loggerKey    contextKey = "logger"
 	traceIDKey   contextKey = "trace_id"
 	requestIDKey contextKey = "request_id"
+	clientIPKey  contextKey = "client_ip"
 )
 
 // WithLogger adds a logger to the context
@@ -54,6 +55,19 @@ func RequestIDFromContext(ctx context.Context) string {
 	return ""
 }
 
+// WithClientIP adds the client IP address to the context
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIPFromContext extracts the client IP address from context
+func ClientIPFromContext(ctx context.Context) string {
+	if ip, ok := ctx.Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return ""
+}
+
 // GenerateTraceID generates a new trace ID
 func GenerateTraceID() string {
 	bytes := make([]byte, 16)
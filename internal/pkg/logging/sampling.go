@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"log/slog"
+	"math"
+)
+
+// SamplingHandler keeps every Warn and Error record and a random share of the records
+// below Warn, so that busy deployments are not flooded with per-request logs
+type SamplingHandler struct {
+	next slog.Handler
+	rate float64
+}
+
+// NewSamplingHandler wraps next, passing on rate (between 0 and 1) of the Info and Debug records
+func NewSamplingHandler(next slog.Handler, rate float64) *SamplingHandler {
+	return &SamplingHandler{next: next, rate: rate}
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level < slog.LevelWarn && !sampled(h.rate) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{next: h.next.WithAttrs(attrs), rate: h.rate}
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{next: h.next.WithGroup(name), rate: h.rate}
+}
+
+// sampled reports true with probability rate
+func sampled(rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// Rather log too much than lose records silently
+		return true
+	}
+	return float64(binary.BigEndian.Uint64(b[:])) < rate*math.MaxUint64
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingHandler counts the records it receives by level
+type countingHandler struct {
+	counts map[slog.Level]int
+}
+
+func (h *countingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *countingHandler) Handle(_ context.Context, record slog.Record) error {
+	h.counts[record.Level]++
+	return nil
+}
+
+func (h *countingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *countingHandler) WithGroup(string) slog.Handler { return h }
+
+func TestSamplingHandler(t *testing.T) {
+	const calls = 10000
+
+	for _, rate := range []float64{0, 0.1, 0.5, 0.9, 1} {
+		t.Run(fmt.Sprintf("rate %.1f", rate), func(t *testing.T) {
+			counter := &countingHandler{counts: map[slog.Level]int{}}
+			logger := slog.New(NewSamplingHandler(counter, rate)).With("component", "test")
+
+			for range calls {
+				logger.Info("Request completed")
+				logger.Warn("Slow request")
+				logger.Error("Request failed")
+			}
+
+			share := float64(counter.counts[slog.LevelInfo]) / calls
+			assert.LessOrEqual(t, math.Abs(share-rate), 0.05, "info share %.3f for rate %.1f", share, rate)
+			assert.Equal(t, calls, counter.counts[slog.LevelWarn])
+			assert.Equal(t, calls, counter.counts[slog.LevelError])
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ValidateBuckets checks histogram bucket bounds from the config. nil leaves the default
+// buckets, otherwise there must be at least one bound and each must be above the previous.
+func ValidateBuckets(buckets []float64) error {
+	if buckets == nil {
+		return nil
+	}
+	if len(buckets) == 0 {
+		return errors.New("at least one bucket is required")
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return fmt.Errorf("buckets must be in ascending order, got %v after %v", buckets[i], buckets[i-1])
+		}
+	}
+	return nil
+}
+
+// bucketsOrDefault returns buckets, or prometheus.DefBuckets when they are not set
+func bucketsOrDefault(buckets []float64) []float64 {
+	if len(buckets) == 0 {
+		return prometheus.DefBuckets
+	}
+	return buckets
+}
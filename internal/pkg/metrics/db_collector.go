@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// CollectDBStats exposes the connection pool statistics of db, read on every scrape and
+// labelled with driverName
+func (p *PrometheusRegistry) CollectDBStats(db *sql.DB, driverName string) error {
+	return p.registerer.Register(newDBStatsCollector(p.config.Namespace, p.config.Subsystem, db.Stats, driverName))
+}
+
+// CollectPgxPoolStats exposes the statistics of a pgx connection pool under the same
+// names as CollectDBStats
+func (p *PrometheusRegistry) CollectPgxPoolStats(pool *pgxpool.Pool, driverName string) error {
+	return p.registerer.Register(newDBStatsCollector(p.config.Namespace, p.config.Subsystem, func() sql.DBStats {
+		stat := pool.Stat()
+		return sql.DBStats{
+			MaxOpenConnections: int(stat.MaxConns()),
+			OpenConnections:    int(stat.TotalConns()),
+			InUse:              int(stat.AcquiredConns()),
+			Idle:               int(stat.IdleConns()),
+			WaitCount:          stat.EmptyAcquireCount(),
+			// Acquiring an idle connection takes next to no time, so the total acquire
+			// time is close to the time spent waiting
+			WaitDuration:      stat.AcquireDuration(),
+			MaxIdleClosed:     stat.MaxIdleDestroyCount(),
+			MaxLifetimeClosed: stat.MaxLifetimeDestroyCount(),
+		}
+	}, driverName))
+}
+
+// CollectRedisPoolStats exposes the connection pool statistics of client, read on every scrape
+func (p *PrometheusRegistry) CollectRedisPoolStats(client *redis.Client) error {
+	return p.registerer.Register(newRedisPoolCollector(p.config.Namespace, p.config.Subsystem, client))
+}
+
+// dbStatsCollector reports the sql.DBStats returned by stats without keeping any state of its own
+type dbStatsCollector struct {
+	stats func() sql.DBStats
+
+	maxOpenConnections *prometheus.Desc
+	openConnections    *prometheus.Desc
+	inUse              *prometheus.Desc
+	idle               *prometheus.Desc
+	waitCount          *prometheus.Desc
+	waitDuration       *prometheus.Desc
+	maxIdleClosed      *prometheus.Desc
+	maxLifetimeClosed  *prometheus.Desc
+}
+
+func newDBStatsCollector(namespace, subsystem string, stats func() sql.DBStats, driverName string) *dbStatsCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "db_"+name),
+			help,
+			nil,
+			prometheus.Labels{LabelDatabaseType: driverName},
+		)
+	}
+
+	return &dbStatsCollector{
+		stats:              stats,
+		maxOpenConnections: desc("max_open_connections", "Maximum number of open connections to the database"),
+		openConnections:    desc("open_connections", "Number of established connections, both in use and idle"),
+		inUse:              desc("in_use_connections", "Number of connections currently in use"),
+		idle:               desc("idle_connections", "Number of idle connections"),
+		waitCount:          desc("wait_count_total", "Total number of connections waited for"),
+		waitDuration:       desc("wait_duration_seconds_total", "Total time blocked waiting for a new connection"),
+		maxIdleClosed:      desc("max_idle_closed_total", "Total number of connections closed due to the idle connection limit"),
+		maxLifetimeClosed:  desc("max_lifetime_closed_total", "Total number of connections closed due to the maximum connection lifetime"),
+	}
+}
+
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpenConnections
+	ch <- c.openConnections
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxLifetimeClosed
+}
+
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpenConnections, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.openConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
+
+// redisPoolCollector reports redis.PoolStats without keeping any state of its own
+type redisPoolCollector struct {
+	client *redis.Client
+
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+}
+
+func newRedisPoolCollector(namespace, subsystem string, client *redis.Client) *redisPoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "redis_pool_"+name), help, nil, nil)
+	}
+
+	return &redisPoolCollector{
+		client:     client,
+		hits:       desc("hits_total", "Total number of times a free connection was found in the pool"),
+		misses:     desc("misses_total", "Total number of times a free connection was not found in the pool"),
+		timeouts:   desc("timeouts_total", "Total number of times a wait for a connection timed out"),
+		totalConns: desc("total_connections", "Number of connections in the pool"),
+		idleConns:  desc("idle_connections", "Number of idle connections in the pool"),
+		staleConns: desc("stale_connections_total", "Total number of stale connections removed from the pool"),
+	}
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.staleConns
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns))
+}
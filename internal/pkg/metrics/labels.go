@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"container/list"
+	"sync"
+)
+
+// OtherLabelValue replaces label values that are not tracked
+const OtherLabelValue = "other"
+
+// labelTracker bounds the values of a high cardinality label, such as a short code, to the
+// capacity most recently seen ones. A value is counted as OtherLabelValue until it is seen
+// again while still tracked, so one-off values never get a series of their own.
+type labelTracker struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // most recently seen at the front
+	items    map[string]*list.Element
+}
+
+func newLabelTracker(capacity int) *labelTracker {
+	return &labelTracker{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// observe returns the label value to record value under, and the value that stopped being
+// tracked to make room for it, or "" when none did. The series of an evicted value should be
+// deleted to keep the label bounded.
+func (t *labelTracker) observe(value string) (label, evicted string) {
+	if t.capacity <= 0 {
+		return OtherLabelValue, ""
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if element, ok := t.items[value]; ok {
+		t.order.MoveToFront(element)
+		return value, ""
+	}
+
+	if t.order.Len() >= t.capacity {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		evicted = oldest.Value.(string)
+		delete(t.items, evicted)
+	}
+	t.items[value] = t.order.PushFront(value)
+	return OtherLabelValue, evicted
+}
@@ -3,6 +3,8 @@ package metrics
 import (
 	"net/http"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
 const (
@@ -39,6 +41,11 @@ func (rw *responseWriter) Write(data []byte) (int, error) {
 	return rw.ResponseWriter.Write(data)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // PrometheusMiddleware creates HTTP middleware that records Prometheus metrics
 func PrometheusMiddleware(registry Registry) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -72,5 +79,18 @@ func PrometheusMiddleware(registry Registry) func(http.Handler) http.Handler {
 	}
 }
 
+// RedirectMiddleware counts every response of a redirect route by the status it was
+// served with and its shortCode URL parameter
+func RedirectMiddleware(registry Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := newResponseWriter(w)
+			next.ServeHTTP(ww, r)
+
+			registry.RecordRedirect(chi.URLParam(r, "shortCode"), FormatStatusCode(ww.statusCode))
+		})
+	}
+}
+
 // MetricsMiddleware is an alias for PrometheusMiddleware for backward compatibility
 var MetricsMiddleware = PrometheusMiddleware
@@ -0,0 +1,226 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/sp3dr4/dove/config"
+)
+
+// otelScope is the instrumentation scope the registry's meter reports under
+const otelScope = "github.com/sp3dr4/dove/internal/pkg/metrics"
+
+// OTelRegistry implements the Registry interface on the OpenTelemetry metrics API. Metric
+// names and labels are the same as PrometheusRegistry's so dashboards work with either
+// backend. Metrics are pushed by the MeterProvider, so there is no scrape handler.
+type OTelRegistry struct {
+	// HTTP Metrics
+	httpRequestsTotal    metric.Int64Counter
+	httpRequestDuration  metric.Float64Histogram
+	httpRequestsInFlight metric.Int64UpDownCounter
+
+	// Business Metrics
+	urlsCreatedTotal    metric.Int64Counter
+	urlsRedirectedTotal metric.Int64Counter
+	urlsExpiredTotal    metric.Int64Counter
+	urlsDisabledTotal   metric.Int64Counter
+	goalsReachedTotal   metric.Int64Counter
+	createDuration      metric.Float64Histogram
+	getDuration         metric.Float64Histogram
+	// activeURLs is reported by an observable gauge, as OpenTelemetry gauges cannot be set
+	activeURLs atomic.Int64
+
+	// Bound the namespace and short_code attributes
+	namespaces *labelTracker
+	shortCodes *labelTracker
+}
+
+// NewOTelRegistry creates a registry whose instruments come from provider
+func NewOTelRegistry(cfg config.MetricsConfig, provider metric.MeterProvider) (Registry, error) {
+	meter := provider.Meter(otelScope)
+	name := func(name string) string {
+		return prometheus.BuildFQName(cfg.Namespace, cfg.Subsystem, name)
+	}
+
+	httpRequestsTotal, err := meter.Int64Counter(name("http_requests_total"),
+		metric.WithDescription("Total number of HTTP requests"))
+	if err != nil {
+		return nil, err
+	}
+
+	httpRequestDuration, err := meter.Float64Histogram(name("http_request_duration_seconds"),
+		metric.WithDescription("HTTP request duration in seconds"),
+		metric.WithExplicitBucketBoundaries(bucketsOrDefault(cfg.HTTPBuckets)...))
+	if err != nil {
+		return nil, err
+	}
+
+	httpRequestsInFlight, err := meter.Int64UpDownCounter(name("http_requests_in_flight"),
+		metric.WithDescription("Number of HTTP requests currently being processed"))
+	if err != nil {
+		return nil, err
+	}
+
+	urlsCreatedTotal, err := meter.Int64Counter(name("urls_created_total"),
+		metric.WithDescription("Total number of URLs created"))
+	if err != nil {
+		return nil, err
+	}
+
+	urlsRedirectedTotal, err := meter.Int64Counter(name("urls_redirected_total"),
+		metric.WithDescription("Total number of URL redirects"))
+	if err != nil {
+		return nil, err
+	}
+
+	urlsExpiredTotal, err := meter.Int64Counter(name("urls_expired_total"),
+		metric.WithDescription("Total number of expired URLs deleted by the cleanup job"))
+	if err != nil {
+		return nil, err
+	}
+
+	urlsDisabledTotal, err := meter.Int64Counter(name("urls_disabled_total"),
+		metric.WithDescription("Total number of URLs disabled through the admin API"))
+	if err != nil {
+		return nil, err
+	}
+
+	goalsReachedTotal, err := meter.Int64Counter(name("url_goals_reached_total"),
+		metric.WithDescription("Total number of URLs that reached their click goal"))
+	if err != nil {
+		return nil, err
+	}
+
+	createDuration, err := meter.Float64Histogram(name("url_create_duration_seconds"),
+		metric.WithDescription("Time taken to create a URL in seconds"),
+		metric.WithExplicitBucketBoundaries(bucketsOrDefault(cfg.DBBuckets)...))
+	if err != nil {
+		return nil, err
+	}
+
+	getDuration, err := meter.Float64Histogram(name("url_get_duration_seconds"),
+		metric.WithDescription("Time taken to look up a URL, from the cache or the database, in seconds"),
+		metric.WithExplicitBucketBoundaries(bucketsOrDefault(cfg.DBBuckets)...))
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &OTelRegistry{
+		httpRequestsTotal:    httpRequestsTotal,
+		httpRequestDuration:  httpRequestDuration,
+		httpRequestsInFlight: httpRequestsInFlight,
+		urlsCreatedTotal:     urlsCreatedTotal,
+		urlsRedirectedTotal:  urlsRedirectedTotal,
+		urlsExpiredTotal:     urlsExpiredTotal,
+		urlsDisabledTotal:    urlsDisabledTotal,
+		goalsReachedTotal:    goalsReachedTotal,
+		createDuration:       createDuration,
+		getDuration:          getDuration,
+		namespaces:           newLabelTracker(cfg.TrackTopNCodes),
+		shortCodes:           newLabelTracker(cfg.TrackTopNCodes),
+	}
+
+	_, err = meter.Int64ObservableGauge(name("urls_active"),
+		metric.WithDescription("Number of enabled URLs without an expiry or not expired yet"),
+		metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+			observer.Observe(registry.activeURLs.Load())
+			return nil
+		}))
+	if err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+// RecordHTTPRequest records an HTTP request with method, path, status code, and duration
+func (o *OTelRegistry) RecordHTTPRequest(method, path, statusCode string, duration float64) {
+	labels := metric.WithAttributeSet(attribute.NewSet(
+		attribute.String(LabelMethod, method),
+		attribute.String(LabelPath, path),
+		attribute.String(LabelStatusCode, statusCode),
+	))
+	o.httpRequestsTotal.Add(context.Background(), 1, labels)
+	o.httpRequestDuration.Record(context.Background(), duration, labels)
+}
+
+// IncHTTPRequestsInFlight increments the in-flight HTTP requests counter
+func (o *OTelRegistry) IncHTTPRequestsInFlight() {
+	o.httpRequestsInFlight.Add(context.Background(), 1)
+}
+
+// DecHTTPRequestsInFlight decrements the in-flight HTTP requests counter
+func (o *OTelRegistry) DecHTTPRequestsInFlight() {
+	o.httpRequestsInFlight.Add(context.Background(), -1)
+}
+
+// RecordURLCreated counts a URL created in namespace. Instruments cannot forget attribute
+// sets, so a namespace that stops being tracked keeps its last value.
+func (o *OTelRegistry) RecordURLCreated(namespace string) {
+	label, _ := o.namespaces.observe(namespace)
+	o.urlsCreatedTotal.Add(context.Background(), 1, metric.WithAttributes(attribute.String(LabelNamespace, label)))
+}
+
+// RecordRedirect counts a response of the redirect endpoint for shortCode
+func (o *OTelRegistry) RecordRedirect(shortCode, statusCode string) {
+	label, _ := o.shortCodes.observe(shortCode)
+	o.urlsRedirectedTotal.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String(LabelStatusCode, statusCode),
+		attribute.String(LabelShortCode, label),
+	))
+}
+
+// AddURLsExpired adds n to the expired URLs counter
+func (o *OTelRegistry) AddURLsExpired(n int) {
+	o.urlsExpiredTotal.Add(context.Background(), int64(n))
+}
+
+// IncURLsDisabled counts a URL being disabled
+func (o *OTelRegistry) IncURLsDisabled() {
+	o.urlsDisabledTotal.Add(context.Background(), 1)
+}
+
+// IncGoalsReached counts a URL reaching its click goal
+func (o *OTelRegistry) IncGoalsReached() {
+	o.goalsReachedTotal.Add(context.Background(), 1)
+}
+
+// IncActiveURLs counts a URL becoming active
+func (o *OTelRegistry) IncActiveURLs() {
+	o.activeURLs.Add(1)
+}
+
+// DecActiveURLs counts a URL no longer being active
+func (o *OTelRegistry) DecActiveURLs() {
+	o.activeURLs.Add(-1)
+}
+
+// SetActiveURLs sets the active URLs gauge to n
+func (o *OTelRegistry) SetActiveURLs(n int64) {
+	o.activeURLs.Store(n)
+}
+
+// RecordCreateDuration records how long creating a URL took
+func (o *OTelRegistry) RecordCreateDuration(duration float64, success bool) {
+	o.createDuration.Record(context.Background(), duration, metric.WithAttributes(attribute.String(LabelStatus, statusLabel(success))))
+}
+
+// RecordGetDuration records how long looking up a URL took
+func (o *OTelRegistry) RecordGetDuration(duration float64, success bool) {
+	o.getDuration.Record(context.Background(), duration, metric.WithAttributes(attribute.String(LabelStatus, statusLabel(success))))
+}
+
+// GetRegistry returns nil, metrics are not kept in a Prometheus registry
+func (o *OTelRegistry) GetRegistry() *prometheus.Registry {
+	return nil
+}
+
+// GetHandler returns nil, metrics are pushed to the collector instead of scraped
+func (o *OTelRegistry) GetHandler() http.Handler {
+	return nil
+}
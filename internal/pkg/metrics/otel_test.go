@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/sp3dr4/dove/config"
+)
+
+// namingMeterProvider records the name of every instrument created through it
+type namingMeterProvider struct {
+	noop.MeterProvider
+	meter *namingMeter
+}
+
+func (p *namingMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return p.meter
+}
+
+type namingMeter struct {
+	noop.Meter
+	names []string
+}
+
+func (m *namingMeter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	m.names = append(m.names, name)
+	return m.Meter.Int64Counter(name, options...)
+}
+
+func (m *namingMeter) Int64UpDownCounter(name string, options ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	m.names = append(m.names, name)
+	return m.Meter.Int64UpDownCounter(name, options...)
+}
+
+func (m *namingMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	m.names = append(m.names, name)
+	return m.Meter.Float64Histogram(name, options...)
+}
+
+func (m *namingMeter) Int64ObservableGauge(name string, options ...metric.Int64ObservableGaugeOption) (metric.Int64ObservableGauge, error) {
+	m.names = append(m.names, name)
+	return m.Meter.Int64ObservableGauge(name, options...)
+}
+
+func TestNewOTelRegistry(t *testing.T) {
+	cfg := config.MetricsConfig{
+		Enabled:   true,
+		Namespace: "dove",
+		Subsystem: "urlshortener",
+		Backend:   "otel",
+	}
+
+	registry, err := NewOTelRegistry(cfg, noop.NewMeterProvider())
+	require.NoError(t, err)
+
+	assert.NotPanics(t, func() {
+		registry.RecordHTTPRequest("GET", "/test", "200", 0.1)
+		registry.IncHTTPRequestsInFlight()
+		registry.DecHTTPRequestsInFlight()
+		registry.RecordURLCreated("default")
+		registry.RecordRedirect("abc123", "301")
+		registry.AddURLsExpired(2)
+		registry.IncURLsDisabled()
+		registry.SetActiveURLs(10)
+		registry.IncActiveURLs()
+		registry.DecActiveURLs()
+	})
+
+	// Metrics are pushed, there is nothing to scrape
+	assert.Nil(t, registry.GetRegistry())
+	assert.Nil(t, registry.GetHandler())
+}
+
+func TestOTelRegistry_MatchesPrometheusNames(t *testing.T) {
+	cfg := config.MetricsConfig{
+		Enabled:   true,
+		Namespace: "test",
+		Subsystem: "test",
+	}
+
+	promRegistry, err := NewPrometheusRegistry(cfg)
+	require.NoError(t, err)
+	// Vector metrics are only gathered once they have a series
+	promRegistry.RecordHTTPRequest("GET", "/test", "200", 0.1)
+	promRegistry.RecordURLCreated("default")
+	promRegistry.RecordRedirect("abc123", "301")
+	promRegistry.RecordCreateDuration(0.01, true)
+	promRegistry.RecordGetDuration(0.001, false)
+
+	families, err := promRegistry.GetRegistry().Gather()
+	require.NoError(t, err)
+	promNames := make([]string, 0, len(families))
+	for _, family := range families {
+		promNames = append(promNames, family.GetName())
+	}
+
+	provider := &namingMeterProvider{meter: &namingMeter{}}
+	_, err = NewOTelRegistry(cfg, provider)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, promNames, provider.meter.names)
+}
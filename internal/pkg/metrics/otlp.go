@@ -0,0 +1,333 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/embedded"
+	"go.opentelemetry.io/otel/metric/noop"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// OTLPMeterProvider is a metric.MeterProvider that aggregates measurements in memory and
+// pushes them to an OTLP gRPC collector with cumulative temporality. It implements the
+// synchronous instruments used by OTelRegistry (Int64Counter, Int64UpDownCounter and
+// Float64Histogram); every other instrument is a no-op.
+type OTLPMeterProvider struct {
+	embedded.MeterProvider
+
+	conn        *grpc.ClientConn
+	client      collectorpb.MetricsServiceClient
+	serviceName string
+	startTime   time.Time
+
+	mu     sync.Mutex
+	meters []*otlpMeter
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// NewOTLPMeterProvider connects to the collector at endpoint (host:port, plaintext) and
+// exports every interval until Shutdown is called
+func NewOTLPMeterProvider(endpoint, serviceName string, interval time.Duration) (*OTLPMeterProvider, error) {
+	if endpoint == "" {
+		return nil, errors.New("OTLP endpoint is required")
+	}
+	if interval <= 0 {
+		return nil, errors.New("OTLP export interval must be positive")
+	}
+
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &OTLPMeterProvider{
+		conn:        conn,
+		client:      collectorpb.NewMetricsServiceClient(conn),
+		serviceName: serviceName,
+		startTime:   time.Now(),
+		stop:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	go p.run(interval)
+
+	return p, nil
+}
+
+// Meter returns a meter whose instruments are exported under the given scope name
+func (p *OTLPMeterProvider) Meter(name string, opts ...metric.MeterOption) metric.Meter {
+	cfg := metric.NewMeterConfig(opts...)
+	meter := &otlpMeter{scope: &commonpb.InstrumentationScope{Name: name, Version: cfg.InstrumentationVersion()}}
+
+	p.mu.Lock()
+	p.meters = append(p.meters, meter)
+	p.mu.Unlock()
+
+	return meter
+}
+
+// ForceFlush exports the current value of every instrument right away
+func (p *OTLPMeterProvider) ForceFlush(ctx context.Context) error {
+	_, err := p.client.Export(ctx, p.collect(time.Now()))
+	return err
+}
+
+// Shutdown stops the periodic export, sends a final one and closes the connection
+func (p *OTLPMeterProvider) Shutdown(ctx context.Context) error {
+	var err error
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		<-p.stopped
+		err = errors.Join(p.ForceFlush(ctx), p.conn.Close())
+	})
+	return err
+}
+
+func (p *OTLPMeterProvider) run(interval time.Duration) {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			// A failed export is retried with fresh cumulative values on the next tick
+			_ = p.ForceFlush(ctx)
+			cancel()
+		}
+	}
+}
+
+func (p *OTLPMeterProvider) collect(now time.Time) *collectorpb.ExportMetricsServiceRequest {
+	p.mu.Lock()
+	meters := append([]*otlpMeter(nil), p.meters...)
+	p.mu.Unlock()
+
+	start := uint64(p.startTime.UnixNano()) //nolint:gosec // wall clock times are positive
+	end := uint64(now.UnixNano())           //nolint:gosec // wall clock times are positive
+
+	scopes := make([]*metricspb.ScopeMetrics, 0, len(meters))
+	for _, meter := range meters {
+		scopes = append(scopes, &metricspb.ScopeMetrics{Scope: meter.scope, Metrics: meter.collect(start, end)})
+	}
+
+	return &collectorpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{stringKeyValue("service.name", p.serviceName)},
+			},
+			ScopeMetrics: scopes,
+		}},
+	}
+}
+
+type otlpMeter struct {
+	noop.Meter
+
+	scope *commonpb.InstrumentationScope
+
+	mu          sync.Mutex
+	instruments []otlpInstrument
+}
+
+type otlpInstrument interface {
+	collect(start, end uint64) *metricspb.Metric
+}
+
+func (m *otlpMeter) register(instrument otlpInstrument) {
+	m.mu.Lock()
+	m.instruments = append(m.instruments, instrument)
+	m.mu.Unlock()
+}
+
+func (m *otlpMeter) collect(start, end uint64) []*metricspb.Metric {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	collected := make([]*metricspb.Metric, 0, len(m.instruments))
+	for _, instrument := range m.instruments {
+		collected = append(collected, instrument.collect(start, end))
+	}
+	return collected
+}
+
+func (m *otlpMeter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	cfg := metric.NewInt64CounterConfig(options...)
+	counter := &otlpSum{name: name, description: cfg.Description(), unit: cfg.Unit(), monotonic: true, points: make(map[attribute.Distinct]*otlpSumPoint)}
+	m.register(counter)
+	return counter, nil
+}
+
+func (m *otlpMeter) Int64UpDownCounter(name string, options ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	cfg := metric.NewInt64UpDownCounterConfig(options...)
+	counter := &otlpSum{name: name, description: cfg.Description(), unit: cfg.Unit(), points: make(map[attribute.Distinct]*otlpSumPoint)}
+	m.register(counter)
+	return counter, nil
+}
+
+func (m *otlpMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	cfg := metric.NewFloat64HistogramConfig(options...)
+	bounds := append([]float64(nil), cfg.ExplicitBucketBoundaries()...)
+	sort.Float64s(bounds)
+
+	histogram := &otlpHistogram{name: name, description: cfg.Description(), unit: cfg.Unit(), bounds: bounds, points: make(map[attribute.Distinct]*otlpHistogramPoint)}
+	m.register(histogram)
+	return histogram, nil
+}
+
+// otlpSum backs both counters and up-down counters, which differ only in monotonicity
+type otlpSum struct {
+	embedded.Int64Counter
+	embedded.Int64UpDownCounter
+
+	name        string
+	description string
+	unit        string
+	monotonic   bool
+
+	mu     sync.Mutex
+	points map[attribute.Distinct]*otlpSumPoint
+}
+
+type otlpSumPoint struct {
+	attributes attribute.Set
+	value      int64
+}
+
+func (s *otlpSum) Enabled(context.Context) bool { return true }
+
+func (s *otlpSum) Add(_ context.Context, incr int64, options ...metric.AddOption) {
+	attributes := metric.NewAddConfig(options).Attributes()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	point, ok := s.points[attributes.Equivalent()]
+	if !ok {
+		point = &otlpSumPoint{attributes: attributes}
+		s.points[attributes.Equivalent()] = point
+	}
+	point.value += incr
+}
+
+func (s *otlpSum) collect(start, end uint64) *metricspb.Metric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dataPoints := make([]*metricspb.NumberDataPoint, 0, len(s.points))
+	for _, point := range s.points {
+		dataPoints = append(dataPoints, &metricspb.NumberDataPoint{
+			Attributes:        keyValues(point.attributes),
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Value:             &metricspb.NumberDataPoint_AsInt{AsInt: point.value},
+		})
+	}
+
+	return &metricspb.Metric{
+		Name:        s.name,
+		Description: s.description,
+		Unit:        s.unit,
+		Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             dataPoints,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            s.monotonic,
+		}},
+	}
+}
+
+type otlpHistogram struct {
+	embedded.Float64Histogram
+
+	name        string
+	description string
+	unit        string
+	bounds      []float64
+
+	mu     sync.Mutex
+	points map[attribute.Distinct]*otlpHistogramPoint
+}
+
+type otlpHistogramPoint struct {
+	attributes attribute.Set
+	count      uint64
+	sum        float64
+	buckets    []uint64
+}
+
+func (h *otlpHistogram) Enabled(context.Context) bool { return true }
+
+func (h *otlpHistogram) Record(_ context.Context, value float64, options ...metric.RecordOption) {
+	attributes := metric.NewRecordConfig(options).Attributes()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	point, ok := h.points[attributes.Equivalent()]
+	if !ok {
+		point = &otlpHistogramPoint{attributes: attributes, buckets: make([]uint64, len(h.bounds)+1)}
+		h.points[attributes.Equivalent()] = point
+	}
+	point.count++
+	point.sum += value
+	// Buckets are upper inclusive, like Prometheus le buckets
+	point.buckets[sort.SearchFloat64s(h.bounds, value)]++
+}
+
+func (h *otlpHistogram) collect(start, end uint64) *metricspb.Metric {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	dataPoints := make([]*metricspb.HistogramDataPoint, 0, len(h.points))
+	for _, point := range h.points {
+		sum := point.sum
+		dataPoints = append(dataPoints, &metricspb.HistogramDataPoint{
+			Attributes:        keyValues(point.attributes),
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Count:             point.count,
+			Sum:               &sum,
+			BucketCounts:      append([]uint64(nil), point.buckets...),
+			ExplicitBounds:    h.bounds,
+		})
+	}
+
+	return &metricspb.Metric{
+		Name:        h.name,
+		Description: h.description,
+		Unit:        h.unit,
+		Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints:             dataPoints,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}},
+	}
+}
+
+func keyValues(set attribute.Set) []*commonpb.KeyValue {
+	kvs := make([]*commonpb.KeyValue, 0, set.Len())
+	for _, kv := range set.ToSlice() {
+		kvs = append(kvs, stringKeyValue(string(kv.Key), kv.Value.Emit()))
+	}
+	return kvs
+}
+
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
@@ -13,7 +13,9 @@ import (
 // PrometheusRegistry implements the Registry interface using Prometheus metrics
 type PrometheusRegistry struct {
 	registry *prometheus.Registry
-	config   config.MetricsConfig
+	// registerer adds the configured constant labels to every metric registered through it
+	registerer prometheus.Registerer
+	config     config.MetricsConfig
 
 	// HTTP Metrics
 	httpRequestsTotal    *prometheus.CounterVec
@@ -21,13 +23,24 @@ type PrometheusRegistry struct {
 	httpRequestsInFlight prometheus.Gauge
 
 	// Business Metrics
-	urlsCreatedTotal    prometheus.Counter
-	urlsRedirectedTotal prometheus.Counter
+	urlsCreatedTotal    *prometheus.CounterVec
+	urlsRedirectedTotal *prometheus.CounterVec
+	urlsExpiredTotal    prometheus.Counter
+	urlsDisabledTotal   prometheus.Counter
+	goalsReachedTotal   prometheus.Counter
+	urlsActive          prometheus.Gauge
+	createDuration      *prometheus.HistogramVec
+	getDuration         *prometheus.HistogramVec
+
+	// Bound the namespace and short_code labels
+	namespaces *labelTracker
+	shortCodes *labelTracker
 }
 
 // NewPrometheusRegistry creates a new Prometheus metrics registry
 func NewPrometheusRegistry(cfg config.MetricsConfig) (Registry, error) {
 	registry := prometheus.NewRegistry()
+	registerer := prometheus.WrapRegistererWith(cfg.ConstLabels, registry)
 
 	// Create HTTP metrics
 	httpRequestsTotal := prometheus.NewCounterVec(
@@ -46,7 +59,7 @@ func NewPrometheusRegistry(cfg config.MetricsConfig) (Registry, error) {
 			Subsystem: cfg.Subsystem,
 			Name:      "http_request_duration_seconds",
 			Help:      "HTTP request duration in seconds",
-			Buckets:   prometheus.DefBuckets,
+			Buckets:   bucketsOrDefault(cfg.HTTPBuckets),
 		},
 		[]string{LabelMethod, LabelPath, LabelStatusCode},
 	)
@@ -61,22 +74,82 @@ func NewPrometheusRegistry(cfg config.MetricsConfig) (Registry, error) {
 	)
 
 	// Create business metrics
-	urlsCreatedTotal := prometheus.NewCounter(
+	urlsCreatedTotal := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: cfg.Namespace,
 			Subsystem: cfg.Subsystem,
 			Name:      "urls_created_total",
 			Help:      "Total number of URLs created",
 		},
+		[]string{LabelNamespace},
 	)
 
-	urlsRedirectedTotal := prometheus.NewCounter(
+	urlsRedirectedTotal := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: cfg.Namespace,
 			Subsystem: cfg.Subsystem,
 			Name:      "urls_redirected_total",
 			Help:      "Total number of URL redirects",
 		},
+		[]string{LabelStatusCode, LabelShortCode},
+	)
+
+	urlsExpiredTotal := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: cfg.Subsystem,
+			Name:      "urls_expired_total",
+			Help:      "Total number of expired URLs deleted by the cleanup job",
+		},
+	)
+
+	urlsDisabledTotal := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: cfg.Subsystem,
+			Name:      "urls_disabled_total",
+			Help:      "Total number of URLs disabled through the admin API",
+		},
+	)
+
+	goalsReachedTotal := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: cfg.Subsystem,
+			Name:      "url_goals_reached_total",
+			Help:      "Total number of URLs that reached their click goal",
+		},
+	)
+
+	urlsActive := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: cfg.Subsystem,
+			Name:      "urls_active",
+			Help:      "Number of enabled URLs without an expiry or not expired yet",
+		},
+	)
+
+	createDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: cfg.Subsystem,
+			Name:      "url_create_duration_seconds",
+			Help:      "Time taken to create a URL in seconds",
+			Buckets:   bucketsOrDefault(cfg.DBBuckets),
+		},
+		[]string{LabelStatus},
+	)
+
+	getDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: cfg.Subsystem,
+			Name:      "url_get_duration_seconds",
+			Help:      "Time taken to look up a URL, from the cache or the database, in seconds",
+			Buckets:   bucketsOrDefault(cfg.DBBuckets),
+		},
+		[]string{LabelStatus},
 	)
 
 	// Register all metrics
@@ -86,28 +159,43 @@ func NewPrometheusRegistry(cfg config.MetricsConfig) (Registry, error) {
 		httpRequestsInFlight,
 		urlsCreatedTotal,
 		urlsRedirectedTotal,
+		urlsExpiredTotal,
+		urlsDisabledTotal,
+		goalsReachedTotal,
+		urlsActive,
+		createDuration,
+		getDuration,
 	}
 
 	for _, collector := range metricsCollectors {
-		if err := registry.Register(collector); err != nil {
+		if err := registerer.Register(collector); err != nil {
 			return nil, err
 		}
 	}
 
 	// Register Go runtime metrics if enabled
 	if cfg.CollectRuntime {
-		registry.MustRegister(collectors.NewGoCollector())
-		registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		registerer.MustRegister(collectors.NewGoCollector())
+		registerer.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 	}
 
 	return &PrometheusRegistry{
 		registry:             registry,
+		registerer:           registerer,
 		config:               cfg,
 		httpRequestsTotal:    httpRequestsTotal,
 		httpRequestDuration:  httpRequestDuration,
 		httpRequestsInFlight: httpRequestsInFlight,
 		urlsCreatedTotal:     urlsCreatedTotal,
 		urlsRedirectedTotal:  urlsRedirectedTotal,
+		urlsExpiredTotal:     urlsExpiredTotal,
+		urlsDisabledTotal:    urlsDisabledTotal,
+		goalsReachedTotal:    goalsReachedTotal,
+		urlsActive:           urlsActive,
+		createDuration:       createDuration,
+		getDuration:          getDuration,
+		namespaces:           newLabelTracker(cfg.TrackTopNCodes),
+		shortCodes:           newLabelTracker(cfg.TrackTopNCodes),
 	}, nil
 }
 
@@ -132,14 +220,62 @@ func (p *PrometheusRegistry) DecHTTPRequestsInFlight() {
 	p.httpRequestsInFlight.Dec()
 }
 
-// IncURLsCreated increments the URLs created counter
-func (p *PrometheusRegistry) IncURLsCreated() {
-	p.urlsCreatedTotal.Inc()
+// RecordURLCreated counts a URL created in namespace
+func (p *PrometheusRegistry) RecordURLCreated(namespace string) {
+	label, evicted := p.namespaces.observe(namespace)
+	if evicted != "" {
+		p.urlsCreatedTotal.DeletePartialMatch(prometheus.Labels{LabelNamespace: evicted})
+	}
+	p.urlsCreatedTotal.With(prometheus.Labels{LabelNamespace: label}).Inc()
+}
+
+// RecordRedirect counts a response of the redirect endpoint for shortCode
+func (p *PrometheusRegistry) RecordRedirect(shortCode, statusCode string) {
+	label, evicted := p.shortCodes.observe(shortCode)
+	if evicted != "" {
+		p.urlsRedirectedTotal.DeletePartialMatch(prometheus.Labels{LabelShortCode: evicted})
+	}
+	p.urlsRedirectedTotal.With(prometheus.Labels{LabelStatusCode: statusCode, LabelShortCode: label}).Inc()
+}
+
+// AddURLsExpired adds n to the expired URLs counter
+func (p *PrometheusRegistry) AddURLsExpired(n int) {
+	p.urlsExpiredTotal.Add(float64(n))
+}
+
+// IncURLsDisabled counts a URL being disabled
+func (p *PrometheusRegistry) IncURLsDisabled() {
+	p.urlsDisabledTotal.Inc()
+}
+
+// IncGoalsReached counts a URL reaching its click goal
+func (p *PrometheusRegistry) IncGoalsReached() {
+	p.goalsReachedTotal.Inc()
+}
+
+// IncActiveURLs counts a URL becoming active
+func (p *PrometheusRegistry) IncActiveURLs() {
+	p.urlsActive.Inc()
+}
+
+// DecActiveURLs counts a URL no longer being active
+func (p *PrometheusRegistry) DecActiveURLs() {
+	p.urlsActive.Dec()
+}
+
+// SetActiveURLs sets the active URLs gauge to n
+func (p *PrometheusRegistry) SetActiveURLs(n int64) {
+	p.urlsActive.Set(float64(n))
+}
+
+// RecordCreateDuration records how long creating a URL took
+func (p *PrometheusRegistry) RecordCreateDuration(duration float64, success bool) {
+	p.createDuration.With(prometheus.Labels{LabelStatus: statusLabel(success)}).Observe(duration)
 }
 
-// IncURLsRedirected increments the URLs redirected counter
-func (p *PrometheusRegistry) IncURLsRedirected() {
-	p.urlsRedirectedTotal.Inc()
+// RecordGetDuration records how long looking up a URL took
+func (p *PrometheusRegistry) RecordGetDuration(duration float64, success bool) {
+	p.getDuration.With(prometheus.Labels{LabelStatus: statusLabel(success)}).Observe(duration)
 }
 
 // GetRegistry returns the underlying Prometheus registry
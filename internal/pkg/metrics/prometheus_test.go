@@ -1,6 +1,9 @@
 package metrics
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -103,9 +106,170 @@ func TestPrometheusRegistry_BusinessMetrics(t *testing.T) {
 
 	// Test business metrics
 	assert.NotPanics(t, func() {
-		registry.IncURLsCreated()
-		registry.IncURLsCreated()
-		registry.IncURLsRedirected()
+		registry.RecordURLCreated("default")
+		registry.RecordURLCreated("team")
+		registry.RecordRedirect("abc123", "301")
+		registry.AddURLsExpired(3)
+		registry.IncURLsDisabled()
+	})
+}
+
+func TestPrometheusRegistry_ConstLabels(t *testing.T) {
+	registry, err := NewPrometheusRegistry(config.MetricsConfig{
+		Enabled:        true,
+		Namespace:      "dove",
+		Subsystem:      "urlshortener",
+		CollectRuntime: true,
+		ConstLabels:    map[string]string{"env": "prod", "region": "eu"},
+	})
+	require.NoError(t, err)
+	registry.RecordHTTPRequest("GET", "/abc123", "301", 0.02)
+	registry.RecordURLCreated("default")
+	registry.RecordRedirect("abc123", "301")
+
+	rec := httptest.NewRecorder()
+	registry.GetHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	samples := 0
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		samples++
+		assert.Contains(t, line, `env="prod"`, line)
+		assert.Contains(t, line, `region="eu"`, line)
+	}
+	assert.NotZero(t, samples)
+
+	t.Run("labels clashing with a metric label are refused", func(t *testing.T) {
+		_, err := NewPrometheusRegistry(config.MetricsConfig{Enabled: true, Namespace: "dove", ConstLabels: map[string]string{"namespace": "prod"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestPrometheusRegistry_Buckets(t *testing.T) {
+	scrape := func(t *testing.T, cfg config.MetricsConfig) string {
+		t.Helper()
+
+		registry, err := NewPrometheusRegistry(cfg)
+		require.NoError(t, err)
+		registry.RecordHTTPRequest("GET", "/abc123", "301", 0.02)
+		registry.RecordGetDuration(0.003, true)
+
+		rec := httptest.NewRecorder()
+		registry.GetHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+
+	t.Run("custom buckets", func(t *testing.T) {
+		body := scrape(t, config.MetricsConfig{
+			Namespace:   "dove",
+			Subsystem:   "urlshortener",
+			HTTPBuckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.5, 1},
+			DBBuckets:   []float64{0.002, 0.02},
+		})
+
+		assert.Contains(t, body, `dove_urlshortener_http_request_duration_seconds_bucket{method="GET",path="/abc123",status_code="301",le="0.001"} 0`)
+		assert.Contains(t, body, `dove_urlshortener_http_request_duration_seconds_bucket{method="GET",path="/abc123",status_code="301",le="0.025"} 1`)
+		assert.NotContains(t, body, `http_request_duration_seconds_bucket{method="GET",path="/abc123",status_code="301",le="10"}`)
+		assert.Contains(t, body, `dove_urlshortener_url_get_duration_seconds_bucket{status="success",le="0.002"} 0`)
+		assert.Contains(t, body, `dove_urlshortener_url_get_duration_seconds_bucket{status="success",le="0.02"} 1`)
+	})
+
+	t.Run("default buckets", func(t *testing.T) {
+		body := scrape(t, config.MetricsConfig{Namespace: "dove", Subsystem: "urlshortener"})
+
+		assert.Contains(t, body, `dove_urlshortener_http_request_duration_seconds_bucket{method="GET",path="/abc123",status_code="301",le="10"} 1`)
+		assert.Contains(t, body, `dove_urlshortener_url_get_duration_seconds_bucket{status="success",le="0.005"} 1`)
+	})
+}
+
+func TestValidateBuckets(t *testing.T) {
+	assert.NoError(t, ValidateBuckets(nil), "unset buckets use the default")
+	assert.NoError(t, ValidateBuckets([]float64{0.5}))
+	assert.NoError(t, ValidateBuckets([]float64{0.001, 0.01, 1}))
+
+	assert.ErrorContains(t, ValidateBuckets([]float64{}), "at least one bucket")
+	assert.ErrorContains(t, ValidateBuckets([]float64{0.1, 0.05, 1}), "ascending order")
+	assert.ErrorContains(t, ValidateBuckets([]float64{0.1, 0.1}), "ascending order")
+}
+
+func TestPrometheusRegistry_ActiveURLs(t *testing.T) {
+	registry, err := NewPrometheusRegistry(config.MetricsConfig{Enabled: true, Namespace: "dove", Subsystem: "urlshortener"})
+	require.NoError(t, err)
+
+	scrape := func() string {
+		rec := httptest.NewRecorder()
+		registry.GetHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+
+	registry.SetActiveURLs(41)
+	registry.IncActiveURLs()
+	registry.IncActiveURLs()
+	registry.DecActiveURLs()
+	assert.Contains(t, scrape(), "dove_urlshortener_urls_active 42")
+
+	registry.SetActiveURLs(7)
+	assert.Contains(t, scrape(), "dove_urlshortener_urls_active 7")
+}
+
+func TestPrometheusRegistry_LabelCardinality(t *testing.T) {
+	registry, err := NewPrometheusRegistry(config.MetricsConfig{
+		Enabled:        true,
+		Namespace:      "dove",
+		Subsystem:      "urlshortener",
+		TrackTopNCodes: 2,
+	})
+	require.NoError(t, err)
+
+	scrape := func() string {
+		rec := httptest.NewRecorder()
+		registry.GetHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+
+	t.Run("unknown short codes count as other", func(t *testing.T) {
+		for _, shortCode := range []string{"a1", "a2", "a3", "a4"} {
+			registry.RecordRedirect(shortCode, "301")
+		}
+		body := scrape()
+		assert.Contains(t, body, `dove_urlshortener_urls_redirected_total{short_code="other",status_code="301"} 4`)
+		assert.NotContains(t, body, `short_code="a`)
+	})
+
+	t.Run("tracked short codes get their own series", func(t *testing.T) {
+		// a3 and a4 are the two most recently seen
+		registry.RecordRedirect("a4", "301")
+		registry.RecordRedirect("a4", "404")
+		registry.RecordRedirect("a1", "301")
+		body := scrape()
+		assert.Contains(t, body, `dove_urlshortener_urls_redirected_total{short_code="a4",status_code="301"} 1`)
+		assert.Contains(t, body, `dove_urlshortener_urls_redirected_total{short_code="a4",status_code="404"} 1`)
+		assert.Contains(t, body, `dove_urlshortener_urls_redirected_total{short_code="other",status_code="301"} 5`)
+	})
+
+	t.Run("codes pushed out lose their series", func(t *testing.T) {
+		// a1 took the place of a3; a5 now takes the place of a4
+		registry.RecordRedirect("a1", "301")
+		registry.RecordRedirect("a5", "301")
+		body := scrape()
+		assert.Contains(t, body, `dove_urlshortener_urls_redirected_total{short_code="a1",status_code="301"} 1`)
+		assert.NotContains(t, body, `short_code="a4"`)
+		assert.Contains(t, body, `dove_urlshortener_urls_redirected_total{short_code="other",status_code="301"} 6`)
+	})
+
+	t.Run("namespaces are bounded the same way", func(t *testing.T) {
+		for _, namespace := range []string{"default", "default", "team", "default"} {
+			registry.RecordURLCreated(namespace)
+		}
+		body := scrape()
+		assert.Contains(t, body, `dove_urlshortener_urls_created_total{namespace="default"} 2`)
+		assert.Contains(t, body, `dove_urlshortener_urls_created_total{namespace="other"} 2`)
 	})
 }
 
@@ -117,8 +281,10 @@ func TestNoOpRegistry(t *testing.T) {
 		registry.RecordHTTPRequest("GET", "/test", "200", 0.1)
 		registry.IncHTTPRequestsInFlight()
 		registry.DecHTTPRequestsInFlight()
-		registry.IncURLsCreated()
-		registry.IncURLsRedirected()
+		registry.RecordURLCreated("default")
+		registry.RecordRedirect("abc123", "301")
+		registry.AddURLsExpired(1)
+		registry.IncURLsDisabled()
 
 		// These should return nil for NoOp
 		assert.Nil(t, registry.GetRegistry())
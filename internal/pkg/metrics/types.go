@@ -14,8 +14,22 @@ type Registry interface {
 	DecHTTPRequestsInFlight()
 
 	// Business Metrics
-	IncURLsCreated()
-	IncURLsRedirected()
+	// RecordURLCreated and RecordRedirect label their counts with the namespace and short
+	// code, keeping only the most recently seen ones and counting the rest as OtherLabelValue
+	RecordURLCreated(namespace string)
+	RecordRedirect(shortCode, statusCode string)
+	AddURLsExpired(n int)
+	IncURLsDisabled()
+	IncGoalsReached()
+	// The active URLs gauge counts enabled URLs without an expiry or not expired yet. It is
+	// set from the repository and then kept up to date as URLs are created and disabled.
+	IncActiveURLs()
+	DecActiveURLs()
+	SetActiveURLs(n int64)
+	// RecordCreateDuration and RecordGetDuration time the creation and the lookup of a URL
+	// by the service, in seconds, labeled with whether they succeeded
+	RecordCreateDuration(duration float64, success bool)
+	RecordGetDuration(duration float64, success bool)
 
 	// Prometheus-specific methods
 	GetRegistry() *prometheus.Registry
@@ -32,8 +46,16 @@ func NewNoOpRegistry() Registry {
 func (n *NoOpRegistry) RecordHTTPRequest(method, path, statusCode string, duration float64) {}
 func (n *NoOpRegistry) IncHTTPRequestsInFlight()                                            {}
 func (n *NoOpRegistry) DecHTTPRequestsInFlight()                                            {}
-func (n *NoOpRegistry) IncURLsCreated()                                                     {}
-func (n *NoOpRegistry) IncURLsRedirected()                                                  {}
+func (n *NoOpRegistry) RecordURLCreated(string)                                             {}
+func (n *NoOpRegistry) RecordRedirect(string, string)                                       {}
+func (n *NoOpRegistry) AddURLsExpired(int)                                                  {}
+func (n *NoOpRegistry) IncURLsDisabled()                                                    {}
+func (n *NoOpRegistry) IncGoalsReached()                                                    {}
+func (n *NoOpRegistry) IncActiveURLs()                                                      {}
+func (n *NoOpRegistry) DecActiveURLs()                                                      {}
+func (n *NoOpRegistry) SetActiveURLs(int64)                                                 {}
+func (n *NoOpRegistry) RecordCreateDuration(float64, bool)                                  {}
+func (n *NoOpRegistry) RecordGetDuration(float64, bool)                                     {}
 func (n *NoOpRegistry) GetRegistry() *prometheus.Registry                                   { return nil }
 func (n *NoOpRegistry) GetHandler() http.Handler                                            { return nil }
 
@@ -56,5 +78,21 @@ const (
 	LabelOperation    = "operation"
 	LabelStatus       = "status"
 	LabelCacheStatus  = "cache_status"
+	LabelNamespace    = "namespace"
+	LabelShortCode    = "short_code"
 	LabelDatabaseType = "database_type"
 )
+
+// Values of the status label of the service durations
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"
+)
+
+// statusLabel is the status label value of an operation that succeeded or not
+func statusLabel(success bool) string {
+	if success {
+		return StatusSuccess
+	}
+	return StatusError
+}
@@ -0,0 +1,793 @@
+// Package openapi generates the OpenAPI 3.1 description of the API from the handler code.
+// Operations come from the annotations on the doc comment of each handler, the same ones
+// swag reads, and schemas from the Go types the annotations name, so that changing a
+// handler or a type it returns changes the spec.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the OpenAPI version of generated specs. Schemas keep to the features 3.1
+// shares with 3.0, which kin-openapi validates: nullable fields carry the x-nullable
+// extension rather than a null type.
+const Version = "3.1.0"
+
+// Packages lists the packages the spec of this service is generated from: the server
+// command carries the general annotations and the internal packages the handlers and the
+// types they name
+var Packages = []string{"./cmd/server", "./internal/..."}
+
+// Header starts every generated file
+const Header = "# Code generated by go run ./cmd/openapi. DO NOT EDIT.\n"
+
+// loadMode loads the listed packages from source, so that struct fields keep their comments,
+// and their dependencies from export data
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedImports | packages.NeedDeps
+
+// Generate loads patterns, resolved from dir, and builds the spec they describe. The spec is
+// validated before it is returned.
+func Generate(ctx context.Context, dir string, patterns ...string) (*openapi3.T, error) {
+	fset := token.NewFileSet()
+	pkgs, err := packages.Load(&packages.Config{Context: ctx, Mode: loadMode, Dir: dir, Fset: fset}, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+	var loadErrs []error
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			loadErrs = append(loadErrs, err)
+		}
+	})
+	if err := errors.Join(loadErrs...); err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+	// Packages are visited in a stable order so that the spec is too
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].PkgPath < pkgs[j].PkgPath })
+
+	g := &generator{
+		doc: &openapi3.T{
+			OpenAPI:    Version,
+			Info:       &openapi3.Info{},
+			Paths:      openapi3.NewPaths(),
+			Components: &openapi3.Components{Schemas: openapi3.Schemas{}, SecuritySchemes: openapi3.SecuritySchemes{}},
+		},
+		schemas:         newSchemaBuilder(),
+		operationIDs:    make(map[string]string),
+		securitySchemes: make(map[string]bool),
+	}
+	for _, pkg := range pkgs {
+		g.schemas.addComments(pkg)
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if err := g.addFile(pkg, file); err != nil {
+				return nil, fmt.Errorf("%s: %w", fset.Position(file.Package).Filename, err)
+			}
+		}
+	}
+	if !g.foundGeneral {
+		return nil, errors.New("no package doc comment carries the general @title annotation")
+	}
+	g.doc.Components.Schemas = g.schemas.components
+	g.addTags()
+
+	if err := g.doc.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+	return g.doc, nil
+}
+
+// Marshal encodes doc as YAML, preceded by Header, with the top-level sections in the order
+// of the OpenAPI specification rather than alphabetically
+func Marshal(doc *openapi3.T) ([]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, and decoding it into a node keeps the key order of the encoder
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+	if len(node.Content) == 1 {
+		orderKeys(node.Content[0], "openapi", "info", "servers", "tags", "security", "paths", "components")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(Header)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle drops the JSON flow style and quotes of a decoded node and its children, so
+// that the encoder picks the usual YAML style
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// orderKeys moves the listed keys of a mapping node first, in the given order
+func orderKeys(mapping *yaml.Node, keys ...string) {
+	if mapping.Kind != yaml.MappingNode {
+		return
+	}
+	rank := func(key string) int {
+		if i := slices.Index(keys, key); i >= 0 {
+			return i
+		}
+		return len(keys)
+	}
+	pairs := make([][2]*yaml.Node, 0, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{mapping.Content[i], mapping.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return rank(pairs[i][0].Value) < rank(pairs[j][0].Value) })
+	mapping.Content = mapping.Content[:0]
+	for _, pair := range pairs {
+		mapping.Content = append(mapping.Content, pair[0], pair[1])
+	}
+}
+
+type generator struct {
+	doc     *openapi3.T
+	schemas *schemaBuilder
+	// operationIDs maps each operation ID to the route that has it, as IDs must be unique
+	operationIDs map[string]string
+	// securitySchemes holds the names of the schemes declared by the general annotations
+	securitySchemes map[string]bool
+	foundGeneral    bool
+}
+
+// scope resolves the type names of the annotations of one file: names qualified with an
+// import name belong to that package, and others to the package of the file
+type scope struct {
+	pkg     *types.Package
+	imports map[string]*types.Package
+}
+
+func newScope(pkg *packages.Package, file *ast.File) scope {
+	byPath := make(map[string]*types.Package)
+	for _, imported := range pkg.Types.Imports() {
+		byPath[imported.Path()] = imported
+	}
+
+	s := scope{pkg: pkg.Types, imports: make(map[string]*types.Package)}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imported, ok := byPath[path]
+		if !ok {
+			continue
+		}
+		name := imported.Name()
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		s.imports[name] = imported
+	}
+	return s
+}
+
+// lookup finds the named type of an annotation, such as ProblemDetail or domain.URL
+func (s scope) lookup(name string) (types.Type, error) {
+	pkg := s.pkg
+	if qualifier, typeName, ok := strings.Cut(name, "."); ok {
+		imported, found := s.imports[qualifier]
+		if !found {
+			return nil, fmt.Errorf("type %s: package %s is not imported", name, qualifier)
+		}
+		pkg, name = imported, typeName
+	}
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in package %s", name, pkg.Path())
+	}
+	return obj.Type(), nil
+}
+
+func (g *generator) addFile(pkg *packages.Package, file *ast.File) error {
+	if file.Doc != nil && g.isGeneral(file.Doc) {
+		if g.foundGeneral {
+			return errors.New("general annotations are declared twice")
+		}
+		g.foundGeneral = true
+		if err := g.addGeneral(file.Doc); err != nil {
+			return err
+		}
+	}
+
+	s := newScope(pkg, file)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Doc == nil {
+			continue
+		}
+		if err := g.addOperations(s, fn); err != nil {
+			return fmt.Errorf("%s: %w", fn.Name.Name, err)
+		}
+	}
+	return nil
+}
+
+// annotation is one @name line of a doc comment
+type annotation struct {
+	name string
+	args string
+}
+
+// annotations returns the @name lines of a doc comment, in order
+func annotations(doc *ast.CommentGroup) []annotation {
+	var result []annotation
+	for _, comment := range doc.List {
+		line := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if !strings.HasPrefix(line, "@") {
+			continue
+		}
+		name, args := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			name, args = line[:i], line[i+1:]
+		}
+		result = append(result, annotation{name: strings.ToLower(name), args: strings.TrimSpace(args)})
+	}
+	return result
+}
+
+func (g *generator) isGeneral(doc *ast.CommentGroup) bool {
+	for _, a := range annotations(doc) {
+		if a.name == "@title" {
+			return true
+		}
+	}
+	return false
+}
+
+// addGeneral reads the API information, servers and security schemes. An annotation after
+// a @securityDefinitions line describes that scheme.
+func (g *generator) addGeneral(doc *ast.CommentGroup) error {
+	var host, basePath string
+	var schemes []string
+	var scheme *openapi3.SecurityScheme
+
+	for _, a := range annotations(doc) {
+		switch {
+		case a.name == "@title":
+			g.doc.Info.Title = a.args
+		case a.name == "@version":
+			g.doc.Info.Version = a.args
+		case a.name == "@description" && scheme == nil:
+			g.doc.Info.Description = a.args
+		case a.name == "@termsofservice":
+			g.doc.Info.TermsOfService = a.args
+		case a.name == "@host":
+			host = a.args
+		case a.name == "@basepath":
+			basePath = a.args
+		case a.name == "@schemes":
+			schemes = strings.Fields(a.args)
+		case strings.HasPrefix(a.name, "@securitydefinitions."):
+			name := a.args
+			switch kind := strings.TrimPrefix(a.name, "@securitydefinitions."); kind {
+			case "apikey":
+				scheme = openapi3.NewSecurityScheme()
+				scheme.Type = "apiKey"
+			case "basic":
+				scheme = openapi3.NewSecurityScheme()
+				scheme.Type, scheme.Scheme = "http", "basic"
+			default:
+				return fmt.Errorf("unsupported security definition %s", kind)
+			}
+			g.doc.Components.SecuritySchemes[name] = &openapi3.SecuritySchemeRef{Value: scheme}
+			g.securitySchemes[name] = true
+		case scheme != nil && a.name == "@in":
+			scheme.In = a.args
+		case scheme != nil && a.name == "@name":
+			scheme.Name = a.args
+		case scheme != nil && a.name == "@description":
+			scheme.Description = a.args
+		default:
+			return fmt.Errorf("unsupported general annotation %s", a.name)
+		}
+	}
+
+	if basePath == "/" {
+		basePath = ""
+	}
+	if host != "" {
+		if len(schemes) == 0 {
+			schemes = []string{"http"}
+		}
+		for _, s := range schemes {
+			g.doc.Servers = append(g.doc.Servers, &openapi3.Server{URL: s + "://" + host + basePath})
+		}
+	}
+	return nil
+}
+
+// operation collects the annotations of one operation until its @Router lines
+type operation struct {
+	op       *openapi3.Operation
+	accept   []string
+	produce  []string
+	body     *openapi3.RequestBody
+	form     *openapi3.Schema
+	routed   bool
+	function string
+}
+
+func (g *generator) newOperation(function string) *operation {
+	return &operation{op: &openapi3.Operation{Responses: openapi3.NewResponsesWithCapacity(0)}, function: function}
+}
+
+// addOperations adds the operations described by the doc comment of fn. A comment may
+// describe several operations, each ending with its @Router lines; consecutive @Router
+// lines share their operation.
+func (g *generator) addOperations(s scope, fn *ast.FuncDecl) error {
+	all := annotations(fn.Doc)
+	if !slices.ContainsFunc(all, func(a annotation) bool { return a.name == "@router" }) {
+		return nil
+	}
+
+	var current *operation
+	for _, a := range all {
+		if current == nil || (current.routed && a.name != "@router") {
+			current = g.newOperation(fn.Name.Name)
+		}
+		if err := g.apply(s, current, a); err != nil {
+			return fmt.Errorf("%s %s: %w", a.name, a.args, err)
+		}
+	}
+	if current != nil && !current.routed {
+		return errors.New("annotations after the last @Router")
+	}
+	return nil
+}
+
+// mimeTypes maps the short names of @Accept and @Produce to their media type
+var mimeTypes = map[string]string{
+	"json":                  "application/json",
+	"xml":                   "application/xml",
+	"plain":                 "text/plain",
+	"html":                  "text/html",
+	"mpfd":                  "multipart/form-data",
+	"x-www-form-urlencoded": "application/x-www-form-urlencoded",
+	"octet-stream":          "application/octet-stream",
+}
+
+func parseMimeTypes(args string) []string {
+	var result []string
+	for _, name := range strings.Split(args, ",") {
+		name = strings.TrimSpace(name)
+		if mime, ok := mimeTypes[name]; ok {
+			name = mime
+		}
+		result = append(result, name)
+	}
+	return result
+}
+
+func (g *generator) apply(s scope, o *operation, a annotation) error {
+	switch a.name {
+	case "@summary":
+		o.op.Summary = a.args
+	case "@description":
+		o.op.Description = strings.TrimPrefix(o.op.Description+"\n"+a.args, "\n")
+	case "@id":
+		o.op.OperationID = a.args
+	case "@tags":
+		for _, tag := range strings.Split(a.args, ",") {
+			o.op.Tags = append(o.op.Tags, strings.TrimSpace(tag))
+		}
+	case "@accept":
+		o.accept = append(o.accept, parseMimeTypes(a.args)...)
+	case "@produce":
+		o.produce = append(o.produce, parseMimeTypes(a.args)...)
+	case "@security":
+		if !g.securitySchemes[a.args] {
+			return errors.New("undeclared security scheme")
+		}
+		o.op.Security = &openapi3.SecurityRequirements{openapi3.NewSecurityRequirement().Authenticate(a.args)}
+	case "@deprecated":
+		o.op.Deprecated = true
+	case "@param":
+		return g.addParam(s, o, a.args)
+	case "@success", "@failure":
+		return g.addResponse(s, o, a.args)
+	case "@header":
+		return g.addHeader(o, a.args)
+	case "@router":
+		return g.addRoute(o, a.args)
+	default:
+		return errors.New("unsupported annotation")
+	}
+	return nil
+}
+
+// tokenize splits annotation arguments on blanks, keeping quoted strings and parenthesised
+// attributes such as Enums(a, b) whole. Quotes are removed.
+func tokenize(args string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuote, depth, started := false, 0, false
+	flush := func() {
+		if started {
+			tokens = append(tokens, current.String())
+		}
+		current.Reset()
+		started = false
+	}
+
+	for _, r := range args {
+		switch {
+		case r == '"' && depth == 0:
+			inQuote = !inQuote
+			started = true
+		case inQuote:
+			current.WriteRune(r)
+		case r == '(':
+			depth++
+			current.WriteRune(r)
+			started = true
+		case r == ')':
+			depth--
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && depth == 0:
+			flush()
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if inQuote || depth != 0 {
+		return nil, errors.New("unbalanced quotes or parentheses")
+	}
+	flush()
+	return tokens, nil
+}
+
+// addParam reads "name in type required description [attributes]"
+func (g *generator) addParam(s scope, o *operation, args string) error {
+	tokens, err := tokenize(args)
+	if err != nil {
+		return err
+	}
+	if len(tokens) < 5 {
+		return errors.New("expected name, location, type, required and description")
+	}
+	name, in, typeName, description := tokens[0], tokens[1], tokens[2], tokens[4]
+	required, err := strconv.ParseBool(tokens[3])
+	if err != nil {
+		return fmt.Errorf("required: %w", err)
+	}
+
+	switch in {
+	case "body":
+		schema, err := g.typeSchema(s, typeName)
+		if err != nil {
+			return err
+		}
+		if o.body != nil {
+			return errors.New("an operation has one body")
+		}
+		o.body = &openapi3.RequestBody{Description: description, Required: required, Content: openapi3.NewContentWithSchemaRef(schema, bodyMimeTypes(o.accept))}
+		o.op.RequestBody = &openapi3.RequestBodyRef{Value: o.body}
+		return nil
+	case "formData":
+		schema, err := primitiveSchema(typeName)
+		if err != nil {
+			return err
+		}
+		schema.Description = description
+		if err := applyAttributes(schema, tokens[5:]); err != nil {
+			return err
+		}
+		if o.form == nil {
+			o.form = openapi3.NewObjectSchema()
+		}
+		o.form.WithPropertyRef(name, openapi3.NewSchemaRef("", schema))
+		if required {
+			o.form.Required = append(o.form.Required, name)
+		}
+		return nil
+	case "path", "query", "header", "cookie":
+	default:
+		return fmt.Errorf("unsupported location %s", in)
+	}
+
+	schema, err := primitiveSchema(typeName)
+	if err != nil {
+		return err
+	}
+	if err := applyAttributes(schema, tokens[5:]); err != nil {
+		return err
+	}
+	o.op.AddParameter(&openapi3.Parameter{
+		Name:        name,
+		In:          in,
+		Description: description,
+		Required:    required || in == "path",
+		Schema:      openapi3.NewSchemaRef("", schema),
+	})
+	return nil
+}
+
+// bodyMimeTypes lists the media types of a request body, leaving out form types which
+// formData parameters describe
+func bodyMimeTypes(accept []string) []string {
+	var result []string
+	for _, mime := range accept {
+		if mime != "multipart/form-data" && mime != "application/x-www-form-urlencoded" {
+			result = append(result, mime)
+		}
+	}
+	if len(result) == 0 {
+		return []string{"application/json"}
+	}
+	return result
+}
+
+// primitiveSchema returns the schema of a parameter type
+func primitiveSchema(typeName string) (*openapi3.Schema, error) {
+	switch typeName {
+	case "string":
+		return openapi3.NewStringSchema(), nil
+	case "int", "integer":
+		return openapi3.NewIntegerSchema(), nil
+	case "number":
+		return openapi3.NewFloat64Schema(), nil
+	case "bool", "boolean":
+		return openapi3.NewBoolSchema(), nil
+	case "file":
+		return openapi3.NewStringSchema().WithFormat("binary"), nil
+	case "object":
+		return openapi3.NewObjectSchema(), nil
+	}
+	return nil, fmt.Errorf("unsupported parameter type %s", typeName)
+}
+
+// applyAttributes reads the default(...), minimum(...), maximum(...), Enums(...) and
+// format(...) attributes of a parameter
+func applyAttributes(schema *openapi3.Schema, attributes []string) error {
+	for _, attribute := range attributes {
+		name, value, ok := strings.Cut(attribute, "(")
+		if !ok || !strings.HasSuffix(value, ")") {
+			return fmt.Errorf("malformed attribute %s", attribute)
+		}
+		value = strings.TrimSuffix(value, ")")
+
+		switch strings.ToLower(name) {
+		case "default":
+			parsed, err := parseValue(schema, value)
+			if err != nil {
+				return err
+			}
+			schema.Default = parsed
+		case "minimum", "maximum":
+			bound, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if strings.EqualFold(name, "minimum") {
+				schema.Min = &bound
+			} else {
+				schema.Max = &bound
+			}
+		case "enums":
+			for _, item := range strings.Split(value, ",") {
+				parsed, err := parseValue(schema, strings.TrimSpace(item))
+				if err != nil {
+					return err
+				}
+				schema.Enum = append(schema.Enum, parsed)
+			}
+		case "format":
+			schema.Format = value
+		default:
+			return fmt.Errorf("unsupported attribute %s", name)
+		}
+	}
+	return nil
+}
+
+// parseValue converts a default, enum value or example to the type of schema. Integers are
+// float64 like every JSON number once decoded, which is what examples are validated as.
+func parseValue(schema *openapi3.Schema, value string) (any, error) {
+	switch {
+	case schema.Type.Is(openapi3.TypeInteger):
+		n, err := strconv.ParseInt(value, 10, 64)
+		return float64(n), err
+	case schema.Type.Is(openapi3.TypeNumber):
+		return strconv.ParseFloat(value, 64)
+	case schema.Type.Is(openapi3.TypeBoolean):
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
+
+// typeSchema resolves the type of a body or response, such as []domain.URL
+func (g *generator) typeSchema(s scope, typeName string) (*openapi3.SchemaRef, error) {
+	if elem, ok := strings.CutPrefix(typeName, "[]"); ok {
+		items, err := g.typeSchema(s, elem)
+		if err != nil {
+			return nil, err
+		}
+		array := openapi3.NewArraySchema()
+		array.Items = items
+		return openapi3.NewSchemaRef("", array), nil
+	}
+	if schema, err := primitiveSchema(typeName); err == nil {
+		return openapi3.NewSchemaRef("", schema), nil
+	}
+	t, err := s.lookup(typeName)
+	if err != nil {
+		return nil, err
+	}
+	return g.schemas.schemaOf(t)
+}
+
+// addResponse reads "code [{kind} type] description"
+func (g *generator) addResponse(s scope, o *operation, args string) error {
+	tokens, err := tokenize(args)
+	if err != nil {
+		return err
+	}
+	if len(tokens) < 2 {
+		return errors.New("expected a status code and a description")
+	}
+	code, err := strconv.Atoi(tokens[0])
+	if err != nil {
+		return fmt.Errorf("status code: %w", err)
+	}
+
+	response := openapi3.NewResponse().WithDescription(tokens[len(tokens)-1])
+	if len(tokens) > 2 {
+		if len(tokens) != 4 || !strings.HasPrefix(tokens[1], "{") || !strings.HasSuffix(tokens[1], "}") {
+			return errors.New("expected code {kind} type description")
+		}
+		kind, typeName := strings.Trim(tokens[1], "{}"), tokens[2]
+		schema, err := g.typeSchema(s, typeName)
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case "array":
+			array := openapi3.NewArraySchema()
+			array.Items = schema
+			schema = openapi3.NewSchemaRef("", array)
+		case "object", "string", "integer", "number", "boolean":
+		default:
+			return fmt.Errorf("unsupported kind %s", kind)
+		}
+		response.Content = openapi3.NewContentWithSchemaRef(schema, produceMimeTypes(o.produce))
+	}
+
+	if existing := o.op.Responses.Status(code); existing != nil {
+		return fmt.Errorf("status %d is described twice", code)
+	}
+	o.op.Responses.Set(strconv.Itoa(code), &openapi3.ResponseRef{Value: response})
+	return nil
+}
+
+func produceMimeTypes(produce []string) []string {
+	if len(produce) == 0 {
+		return []string{"application/json"}
+	}
+	return produce
+}
+
+// addHeader reads "code {type} name description", for a response described earlier
+func (g *generator) addHeader(o *operation, args string) error {
+	tokens, err := tokenize(args)
+	if err != nil {
+		return err
+	}
+	if len(tokens) != 4 {
+		return errors.New("expected code {type} name description")
+	}
+	response := o.op.Responses.Value(tokens[0])
+	if response == nil {
+		return fmt.Errorf("status %s is not described", tokens[0])
+	}
+	schema, err := primitiveSchema(strings.Trim(tokens[1], "{}"))
+	if err != nil {
+		return err
+	}
+	if response.Value.Headers == nil {
+		response.Value.Headers = openapi3.Headers{}
+	}
+	response.Value.Headers[tokens[2]] = &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+		Description: tokens[3],
+		Schema:      openapi3.NewSchemaRef("", schema),
+	}}}
+	return nil
+}
+
+// addRoute reads "path [method]" and adds the operation at that route
+func (g *generator) addRoute(o *operation, args string) error {
+	path, method, ok := strings.Cut(args, " ")
+	method = strings.Trim(strings.TrimSpace(method), "[]")
+	if !ok || method == "" {
+		return errors.New("expected path [method]")
+	}
+	method = strings.ToUpper(method)
+
+	// formData parameters make up one more body content, the form
+	if o.form != nil && !o.routed {
+		if o.body == nil {
+			o.body = &openapi3.RequestBody{Content: openapi3.Content{}}
+			o.op.RequestBody = &openapi3.RequestBodyRef{Value: o.body}
+		}
+		mime := "multipart/form-data"
+		if slices.Contains(o.accept, "application/x-www-form-urlencoded") {
+			mime = "application/x-www-form-urlencoded"
+		}
+		o.body.Content[mime] = openapi3.NewMediaType().WithSchema(o.form)
+	}
+
+	if item := g.doc.Paths.Value(path); item != nil && item.GetOperation(method) != nil {
+		return fmt.Errorf("%s %s is described twice", method, path)
+	}
+	op := o.op
+	if o.routed {
+		// Another route of the same operation gets a copy, so that its ID can differ
+		copied := *o.op
+		copied.OperationID = ""
+		op = &copied
+	}
+	if op.OperationID == "" {
+		// Such as getRedirect and headRedirect for HandleRedirect
+		op.OperationID = strings.ToLower(method) + strings.TrimPrefix(o.function, "Handle")
+	}
+	if route, taken := g.operationIDs[op.OperationID]; taken {
+		return fmt.Errorf("operation ID %s is already used by %s", op.OperationID, route)
+	}
+	g.operationIDs[op.OperationID] = method + " " + path
+
+	g.doc.AddOperation(path, method, op)
+	o.routed = true
+	return nil
+}
+
+// addTags lists every tag used by an operation, sorted
+func (g *generator) addTags() {
+	seen := make(map[string]bool)
+	for _, item := range g.doc.Paths.Map() {
+		for _, op := range item.Operations() {
+			for _, tag := range op.Tags {
+				seen[tag] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.doc.Tags = append(g.doc.Tags, &openapi3.Tag{Name: name})
+	}
+}
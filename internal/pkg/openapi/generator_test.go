@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// moduleRoot is the directory the spec of the service is generated from
+const moduleRoot = "../../.."
+
+func TestOpenAPISpecIsUpToDate(t *testing.T) {
+	ctx := context.Background()
+
+	doc, err := Generate(ctx, moduleRoot, Packages...)
+	require.NoError(t, err)
+	generated, err := Marshal(doc)
+	require.NoError(t, err)
+
+	committed, err := os.ReadFile(filepath.Join(moduleRoot, "docs", "openapi.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, string(committed), string(generated), "docs/openapi.yaml is stale, run go generate ./cmd/openapi")
+
+	loaded, err := openapi3.NewLoader().LoadFromData(committed)
+	require.NoError(t, err)
+	require.NoError(t, loaded.Validate(ctx))
+	assert.Equal(t, Version, loaded.OpenAPI)
+
+	t.Run("describes operations from their annotations", func(t *testing.T) {
+		shorten := loaded.Paths.Find("/shorten").Post
+		require.NotNil(t, shorten)
+		assert.Equal(t, "postShorten", shorten.OperationID)
+		assert.Equal(t, "#/components/schemas/application.CreateURLRequest", shorten.RequestBody.Value.Content.Get("application/json").Schema.Ref)
+		assert.Equal(t, "#/components/schemas/application.URLResponse", shorten.Responses.Status(201).Value.Content.Get("application/json").Schema.Ref)
+
+		head := loaded.Paths.Find("/{shortCode}").Head
+		require.NotNil(t, head, "a doc comment describes several operations")
+		assert.Equal(t, "headRedirect", head.OperationID)
+		assert.Contains(t, head.Responses.Status(200).Value.Headers, "X-Clicks")
+
+		cold := loaded.Paths.Find("/admin/urls/cold").Get
+		require.NotNil(t, cold)
+		assert.Equal(t, openapi3.SecurityRequirements{{"AdminAPIKey": []string{}}}, *cold.Security)
+		days := cold.Parameters.GetByInAndName("query", "days")
+		require.NotNil(t, days)
+		assert.InDelta(t, 30, days.Schema.Value.Default, 0)
+		assert.InDelta(t, 3650, *days.Schema.Value.Max, 0)
+	})
+
+	t.Run("describes schemas from the Go types", func(t *testing.T) {
+		request := loaded.Components.Schemas["application.CreateURLRequest"].Value
+		require.NotNil(t, request)
+		clickGoal := request.Properties["clickGoal"].Value
+		assert.True(t, clickGoal.Type.Is(openapi3.TypeInteger))
+		assert.InDelta(t, 1, *clickGoal.Min, 0, "validate rules become constraints")
+		assert.Contains(t, clickGoal.Description, "ClickGoal is the number of clicks", "field comments become descriptions")
+
+		funnel := loaded.Components.Schemas["application.CreateFunnelRequest"].Value
+		require.NotNil(t, funnel)
+		assert.Equal(t, []string{"name", "steps"}, funnel.Required)
+
+		url := loaded.Components.Schemas["application.URLInfoResponse"].Value
+		require.NotNil(t, url)
+		assert.Equal(t, "date-time", url.Properties["createdAt"].Value.Format)
+	})
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		expected []string
+	}{
+		{name: "blanks", args: "limit\tquery  int", expected: []string{"limit", "query", "int"}},
+		{name: "quoted", args: `200 {object} ProblemDetail "Short URL not found"`, expected: []string{"200", "{object}", "ProblemDetail", "Short URL not found"}},
+		{name: "empty quoted", args: `a ""`, expected: []string{"a", ""}},
+		{name: "attributes", args: `format query string false "Export format" Enums(csv, json) default(csv)`, expected: []string{"format", "query", "string", "false", "Export format", "Enums(csv, json)", "default(csv)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenize(tt.args)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, tokens)
+		})
+	}
+
+	_, err := tokenize(`"unterminated`)
+	assert.Error(t, err)
+	_, err = tokenize("Enums(a, b")
+	assert.Error(t, err)
+}
+
+func TestApplyValidation(t *testing.T) {
+	maxLength := uint64(32)
+	maxItems := uint64(20)
+
+	tests := []struct {
+		name     string
+		schema   *openapi3.Schema
+		rules    string
+		required bool
+		expected *openapi3.Schema
+	}{
+		{
+			name:     "required string with length",
+			schema:   openapi3.NewStringSchema(),
+			rules:    "required,min=3,max=32",
+			required: true,
+			expected: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, MinLength: 3, MaxLength: &maxLength},
+		},
+		{
+			name:     "integer bounds",
+			schema:   openapi3.NewIntegerSchema(),
+			rules:    "omitempty,min=1",
+			expected: openapi3.NewIntegerSchema().WithMin(1),
+		},
+		{
+			name:     "one of",
+			schema:   openapi3.NewIntegerSchema(),
+			rules:    "omitnil,oneof=301 302",
+			expected: openapi3.NewIntegerSchema().WithEnum(float64(301), float64(302)),
+		},
+		{
+			name:   "rules after dive apply to items",
+			schema: openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema()),
+			rules:  "omitempty,max=20,unique,dive,required,max=32",
+			expected: &openapi3.Schema{
+				Type:        &openapi3.Types{openapi3.TypeArray},
+				MaxItems:    &maxItems,
+				UniqueItems: true,
+				Items:       openapi3.NewSchemaRef("", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, MaxLength: &maxLength}),
+			},
+		},
+		{
+			name:     "url",
+			schema:   openapi3.NewStringSchema(),
+			rules:    "required,url",
+			required: true,
+			expected: openapi3.NewStringSchema().WithFormat("uri"),
+		},
+		{
+			name:     "safe url",
+			schema:   openapi3.NewStringSchema(),
+			rules:    "omitempty,safeurl",
+			expected: openapi3.NewStringSchema().WithFormat("uri"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			required, err := applyValidation(tt.schema, tt.rules)
+			require.NoError(t, err)
+			assert.Equal(t, tt.required, required)
+			assert.Equal(t, tt.expected, tt.schema)
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"golang.org/x/tools/go/packages"
+)
+
+// schemaBuilder turns Go types into schemas. Named struct types become components named
+// after their package and type, such as domain.URL, and other types are inlined.
+type schemaBuilder struct {
+	components openapi3.Schemas
+	// owners maps each component name to the type it describes, to detect two packages
+	// of the same name declaring the same type name
+	owners map[string]*types.Named
+	// comments holds the doc or line comment of each struct field, by position
+	comments map[token.Pos]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		components: openapi3.Schemas{},
+		owners:     make(map[string]*types.Named),
+		comments:   make(map[token.Pos]string),
+	}
+}
+
+// addComments remembers the comments of the struct fields declared in pkg
+func (b *schemaBuilder) addComments(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(node ast.Node) bool {
+			st, ok := node.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				comment := field.Doc
+				if comment == nil {
+					comment = field.Comment
+				}
+				if comment == nil {
+					continue
+				}
+				text := strings.TrimSpace(comment.Text())
+				if len(field.Names) == 0 {
+					b.comments[field.Type.Pos()] = text
+				}
+				for _, name := range field.Names {
+					b.comments[name.Pos()] = text
+				}
+			}
+			return true
+		})
+	}
+}
+
+// schemaOf returns the schema of t, a reference for named structs
+func (b *schemaBuilder) schemaOf(t types.Type) (*openapi3.SchemaRef, error) {
+	t = types.Unalias(t)
+	switch t := t.(type) {
+	case *types.Named:
+		return b.namedSchema(t)
+	case *types.Pointer:
+		return b.schemaOf(t.Elem())
+	case *types.Basic:
+		schema, err := basicSchema(t)
+		if err != nil {
+			return nil, err
+		}
+		return openapi3.NewSchemaRef("", schema), nil
+	case *types.Slice:
+		return b.arraySchema(t.Elem())
+	case *types.Array:
+		return b.arraySchema(t.Elem())
+	case *types.Map:
+		values, err := b.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema := openapi3.NewObjectSchema()
+		schema.AdditionalProperties = openapi3.AdditionalProperties{Schema: values}
+		return openapi3.NewSchemaRef("", schema), nil
+	case *types.Struct:
+		schema, err := b.structSchema(t)
+		if err != nil {
+			return nil, err
+		}
+		return openapi3.NewSchemaRef("", schema), nil
+	case *types.Interface:
+		// Any value
+		return openapi3.NewSchemaRef("", &openapi3.Schema{}), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+func (b *schemaBuilder) arraySchema(elem types.Type) (*openapi3.SchemaRef, error) {
+	if basic, ok := elem.Underlying().(*types.Basic); ok && basic.Kind() == types.Byte {
+		// encoding/json encodes byte slices in base64
+		return openapi3.NewSchemaRef("", openapi3.NewBytesSchema()), nil
+	}
+	items, err := b.schemaOf(elem)
+	if err != nil {
+		return nil, err
+	}
+	schema := openapi3.NewArraySchema()
+	schema.Items = items
+	return openapi3.NewSchemaRef("", schema), nil
+}
+
+func basicSchema(t *types.Basic) (*openapi3.Schema, error) {
+	switch kind := t.Kind(); {
+	case kind == types.Bool:
+		return openapi3.NewBoolSchema(), nil
+	case kind == types.String:
+		return openapi3.NewStringSchema(), nil
+	case kind == types.Int64 || kind == types.Uint64:
+		return openapi3.NewInt64Schema(), nil
+	case kind == types.Int32 || kind == types.Uint32:
+		return openapi3.NewInt32Schema(), nil
+	case t.Info()&types.IsInteger != 0:
+		return openapi3.NewIntegerSchema(), nil
+	case kind == types.Float32:
+		return openapi3.NewFloat64Schema().WithFormat("float"), nil
+	case kind == types.Float64:
+		return openapi3.NewFloat64Schema().WithFormat("double"), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// namedSchema handles the types encoded other than their structure suggests, and makes
+// named structs components
+func (b *schemaBuilder) namedSchema(t *types.Named) (*openapi3.SchemaRef, error) {
+	obj := t.Obj()
+	if obj.Pkg() == nil {
+		// error, the only predeclared named type
+		return openapi3.NewSchemaRef("", openapi3.NewStringSchema()), nil
+	}
+	switch obj.Pkg().Path() + "." + obj.Name() {
+	case "time.Time":
+		return openapi3.NewSchemaRef("", openapi3.NewDateTimeSchema()), nil
+	case "time.Duration":
+		return openapi3.NewSchemaRef("", openapi3.NewInt64Schema()), nil
+	case "encoding/json.RawMessage":
+		return openapi3.NewSchemaRef("", &openapi3.Schema{}), nil
+	}
+
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		ref, err := b.schemaOf(t.Underlying())
+		if err != nil {
+			return nil, err
+		}
+		if enum := enumValues(t); len(enum) > 0 && ref.Ref == "" {
+			ref.Value.Enum = enum
+		}
+		return ref, nil
+	}
+
+	name := obj.Pkg().Name() + "." + obj.Name()
+	if owner, ok := b.owners[name]; ok {
+		if owner != t {
+			return nil, fmt.Errorf("types %s and %s are both named %s", owner, t, name)
+		}
+		return openapi3.NewSchemaRef(componentRef(name), b.components[name].Value), nil
+	}
+	// Registered before its fields, which may refer back to it, and filled in after
+	component := &openapi3.Schema{}
+	b.owners[name] = t
+	b.components[name] = openapi3.NewSchemaRef("", component)
+	schema, err := b.structSchema(st)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	*component = *schema
+	return openapi3.NewSchemaRef(componentRef(name), component), nil
+}
+
+func componentRef(name string) string {
+	return "#/components/schemas/" + name
+}
+
+// enumValues lists the constants of a named basic type declared in its package, in
+// declaration order
+func enumValues(t *types.Named) []any {
+	scope := t.Obj().Pkg().Scope()
+	var consts []*types.Const
+	for _, name := range scope.Names() {
+		if c, ok := scope.Lookup(name).(*types.Const); ok && c.Exported() && types.Identical(c.Type(), t) {
+			consts = append(consts, c)
+		}
+	}
+	sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+
+	values := make([]any, 0, len(consts))
+	for _, c := range consts {
+		switch c.Val().Kind() {
+		case constant.String:
+			values = append(values, constant.StringVal(c.Val()))
+		case constant.Int:
+			value, _ := constant.Float64Val(c.Val())
+			values = append(values, value)
+		default:
+			return nil
+		}
+	}
+	return values
+}
+
+// structSchema describes the JSON object encoding/json makes of st
+func (b *schemaBuilder) structSchema(st *types.Struct) (*openapi3.Schema, error) {
+	schema := openapi3.NewObjectSchema()
+	for i := range st.NumFields() {
+		field := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		name, options, _ := strings.Cut(tag.Get("json"), ",")
+		if name == "-" && options == "" {
+			continue
+		}
+
+		if field.Embedded() && name == "" {
+			// The fields of embedded structs are promoted
+			embedded := field.Type()
+			if pointer, ok := embedded.(*types.Pointer); ok {
+				embedded = pointer.Elem()
+			}
+			if inner, ok := embedded.Underlying().(*types.Struct); ok {
+				promoted, err := b.structSchema(inner)
+				if err != nil {
+					return nil, err
+				}
+				for key, property := range promoted.Properties {
+					schema.Properties[key] = property
+				}
+				schema.Required = append(schema.Required, promoted.Required...)
+				continue
+			}
+		}
+		if !field.Exported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name()
+		}
+
+		property, required, err := b.fieldSchema(field, tag, options)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name(), err)
+		}
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	sort.Strings(schema.Required)
+	return schema, nil
+}
+
+// fieldSchema applies the comment and tags of a struct field to the schema of its type.
+// The swag tags swaggertype, format, enums, example and extensions are honoured, as are
+// the validate rules that translate to a schema.
+func (b *schemaBuilder) fieldSchema(field *types.Var, tag reflect.StructTag, options string) (*openapi3.SchemaRef, bool, error) {
+	var ref *openapi3.SchemaRef
+	if override := tag.Get("swaggertype"); override != "" {
+		schema, err := primitiveSchema(override)
+		if err != nil {
+			return nil, false, err
+		}
+		ref = openapi3.NewSchemaRef("", schema)
+	} else {
+		schemaRef, err := b.schemaOf(field.Type())
+		if err != nil {
+			return nil, false, err
+		}
+		ref = schemaRef
+	}
+	if strings.Contains(options, "string") {
+		// The ,string option encodes numbers and booleans as JSON strings
+		ref = openapi3.NewSchemaRef("", openapi3.NewStringSchema())
+	}
+
+	// A reference cannot carry anything else, so the field wraps it
+	schema := ref.Value
+	if ref.Ref != "" {
+		schema = &openapi3.Schema{AllOf: openapi3.SchemaRefs{ref}}
+	} else {
+		copied := *schema
+		schema = &copied
+	}
+	schema.Description = b.comments[field.Pos()]
+	if format := tag.Get("format"); format != "" {
+		schema.Format = format
+	}
+	if enums := tag.Get("enums"); enums != "" {
+		for _, value := range strings.Split(enums, ",") {
+			parsed, err := parseValue(schema, value)
+			if err != nil {
+				return nil, false, err
+			}
+			schema.Enum = append(schema.Enum, parsed)
+		}
+	}
+	if example, ok := tag.Lookup("example"); ok {
+		parsed, err := parseExample(schema, example)
+		if err != nil {
+			return nil, false, err
+		}
+		schema.Example = parsed
+	}
+	for _, extension := range strings.Split(tag.Get("extensions"), ",") {
+		if extension == "" {
+			continue
+		}
+		name, value, found := strings.Cut(extension, "=")
+		if schema.Extensions == nil {
+			schema.Extensions = make(map[string]any)
+		}
+		if found {
+			schema.Extensions[name] = value
+		} else {
+			schema.Extensions[name] = true
+		}
+	}
+
+	required, err := applyValidation(schema, tag.Get("validate"))
+	if err != nil {
+		return nil, false, err
+	}
+	if ref.Ref != "" && schema.Description == "" && len(schema.Extensions) == 0 && schema.Example == nil {
+		return ref, required, nil
+	}
+	return openapi3.NewSchemaRef("", schema), required, nil
+}
+
+// parseExample converts an example tag to the type of schema; list examples of arrays
+// are comma separated
+func parseExample(schema *openapi3.Schema, example string) (any, error) {
+	if !schema.Type.Is(openapi3.TypeArray) {
+		return parseValue(schema, example)
+	}
+	items := openapi3.NewStringSchema()
+	if schema.Items != nil && schema.Items.Value != nil {
+		items = schema.Items.Value
+	}
+	var values []any
+	for _, value := range strings.Split(example, ",") {
+		parsed, err := parseValue(items, value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, parsed)
+	}
+	return values, nil
+}
+
+// applyValidation translates validate rules to schema constraints and reports whether the
+// field is required. The rules after dive apply to the items of an array.
+func applyValidation(schema *openapi3.Schema, rules string) (bool, error) {
+	if rules == "" {
+		return false, nil
+	}
+	required := false
+	target := schema
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = required || target == schema
+		case "dive":
+			if schema.Items == nil || schema.Items.Value == nil || schema.Items.Ref != "" {
+				// Item rules of referenced schemas belong to the component
+				return required, nil
+			}
+			copied := *schema.Items.Value
+			schema.Items = openapi3.NewSchemaRef("", &copied)
+			target = &copied
+		case "min", "max", "len", "gte", "lte":
+			bound, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return false, fmt.Errorf("rule %s: %w", rule, err)
+			}
+			applyBound(target, name, bound)
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				parsed, err := parseValue(target, value)
+				if err != nil {
+					return false, err
+				}
+				target.Enum = append(target.Enum, parsed)
+			}
+		case "unique":
+			target.UniqueItems = true
+		case "url", "http_url", "safeurl":
+			target.Format = "uri"
+		case "email":
+			target.Format = "email"
+		}
+	}
+	return required, nil
+}
+
+// applyBound sets the minimum or maximum a rule puts on a value, a length or a number of
+// items, depending on the type of schema
+func applyBound(schema *openapi3.Schema, rule string, bound float64) {
+	lower := rule == "min" || rule == "gte" || rule == "len"
+	upper := rule == "max" || rule == "lte" || rule == "len"
+	count := uint64(bound)
+
+	switch {
+	case schema.Type.Is(openapi3.TypeString):
+		if lower {
+			schema.MinLength = count
+		}
+		if upper {
+			schema.MaxLength = &count
+		}
+	case schema.Type.Is(openapi3.TypeArray):
+		if lower {
+			schema.MinItems = count
+		}
+		if upper {
+			schema.MaxItems = &count
+		}
+	case schema.Type.Is(openapi3.TypeInteger), schema.Type.Is(openapi3.TypeNumber):
+		if lower {
+			schema.Min = &bound
+		}
+		if upper {
+			schema.Max = &bound
+		}
+	}
+}
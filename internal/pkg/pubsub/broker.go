@@ -0,0 +1,142 @@
+package pubsub
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+// ErrTooManySubscribers is returned when the broker is at its subscriber cap
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+// subscriberBuffer is the number of events queued per subscriber before new events are dropped
+const subscriberBuffer = 16
+
+// topic identifies the short URL a subscription follows
+type topic struct {
+	namespace string
+	shortCode string
+}
+
+// Broker is an in-process publish/subscribe hub for click events, keyed by short URL, and
+// for goal events, which go to every goal subscriber
+type Broker struct {
+	mu             sync.RWMutex
+	subscribers    map[topic][]chan domain.ClickEvent
+	count          int
+	maxSubscribers int
+	// goalSubscribers are consumers within the service, not counted against maxSubscribers
+	goalSubscribers []chan domain.GoalReachedEvent
+}
+
+// NewBroker creates a broker accepting at most maxSubscribers concurrent subscriptions.
+// A non-positive maxSubscribers means unlimited.
+func NewBroker(maxSubscribers int) *Broker {
+	return &Broker{
+		subscribers:    make(map[topic][]chan domain.ClickEvent),
+		maxSubscribers: maxSubscribers,
+	}
+}
+
+// Subscribe registers for click events on shortCode within namespace. The returned function
+// must be called to deregister; it closes the channel and is safe to call more than once.
+func (b *Broker) Subscribe(namespace, shortCode string) (<-chan domain.ClickEvent, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxSubscribers > 0 && b.count >= b.maxSubscribers {
+		return nil, nil, ErrTooManySubscribers
+	}
+
+	key := topic{namespace: namespace, shortCode: shortCode}
+	ch := make(chan domain.ClickEvent, subscriberBuffer)
+	b.subscribers[key] = append(b.subscribers[key], ch)
+	b.count++
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() { b.unsubscribe(key, ch) })
+	}
+
+	return ch, unsubscribe, nil
+}
+
+func (b *Broker) unsubscribe(key topic, ch chan domain.ClickEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := b.subscribers[key]
+	for i, sub := range subs {
+		if sub == ch {
+			subs = append(subs[:i], subs[i+1:]...)
+			b.count--
+			close(ch)
+			break
+		}
+	}
+
+	if len(subs) == 0 {
+		delete(b.subscribers, key)
+	} else {
+		b.subscribers[key] = subs
+	}
+}
+
+// Publish delivers event to every subscriber of its short URL without blocking.
+// Subscribers that are not keeping up miss the event.
+func (b *Broker) Publish(event domain.ClickEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, ch := range b.subscribers[topic{namespace: event.Namespace, shortCode: event.ShortCode}] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// SubscribeGoals registers for the goal events of every short URL. The returned function
+// must be called to deregister; it closes the channel and is safe to call more than once.
+func (b *Broker) SubscribeGoals() (<-chan domain.GoalReachedEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan domain.GoalReachedEvent, subscriberBuffer)
+	b.goalSubscribers = append(b.goalSubscribers, ch)
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.goalSubscribers = slices.DeleteFunc(b.goalSubscribers, func(sub chan domain.GoalReachedEvent) bool { return sub == ch })
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// PublishGoalReached delivers event to every goal subscriber without blocking. Subscribers
+// that are not keeping up miss the event.
+func (b *Broker) PublishGoalReached(event domain.GoalReachedEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, ch := range b.goalSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// SubscriberCount returns the number of active subscriptions
+func (b *Broker) SubscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.count
+}
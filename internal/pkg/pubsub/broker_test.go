@@ -0,0 +1,106 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+func TestBroker_PublishDeliversToMatchingSubscribers(t *testing.T) {
+	broker := NewBroker(0)
+
+	first, unsubFirst, err := broker.Subscribe(domain.DefaultNamespace, "abc")
+	require.NoError(t, err)
+	defer unsubFirst()
+	second, unsubSecond, err := broker.Subscribe(domain.DefaultNamespace, "abc")
+	require.NoError(t, err)
+	defer unsubSecond()
+	other, unsubOther, err := broker.Subscribe(domain.DefaultNamespace, "xyz")
+	require.NoError(t, err)
+	defer unsubOther()
+	otherNamespace, unsubOtherNamespace, err := broker.Subscribe("acme", "abc")
+	require.NoError(t, err)
+	defer unsubOtherNamespace()
+
+	broker.Publish(domain.ClickEvent{Namespace: domain.DefaultNamespace, ShortCode: "abc", Clicks: 1})
+
+	assert.Equal(t, 1, (<-first).Clicks)
+	assert.Equal(t, 1, (<-second).Clicks)
+	assert.Empty(t, other)
+	assert.Empty(t, otherNamespace)
+}
+
+func TestBroker_SubscriberLimit(t *testing.T) {
+	broker := NewBroker(1)
+
+	_, unsubscribe, err := broker.Subscribe(domain.DefaultNamespace, "abc")
+	require.NoError(t, err)
+
+	_, _, err = broker.Subscribe(domain.DefaultNamespace, "xyz")
+	assert.ErrorIs(t, err, ErrTooManySubscribers)
+
+	unsubscribe()
+	assert.Equal(t, 0, broker.SubscriberCount())
+
+	_, unsubscribe, err = broker.Subscribe(domain.DefaultNamespace, "xyz")
+	require.NoError(t, err)
+	unsubscribe()
+}
+
+func TestBroker_UnsubscribeClosesChannel(t *testing.T) {
+	broker := NewBroker(0)
+
+	events, unsubscribe, err := broker.Subscribe(domain.DefaultNamespace, "abc")
+	require.NoError(t, err)
+
+	unsubscribe()
+	unsubscribe()
+
+	_, ok := <-events
+	assert.False(t, ok)
+
+	// Publishing after the last subscriber left must not panic
+	broker.Publish(domain.ClickEvent{Namespace: domain.DefaultNamespace, ShortCode: "abc", Clicks: 1})
+}
+
+func TestBroker_PublishDoesNotBlockSlowSubscribers(t *testing.T) {
+	broker := NewBroker(0)
+
+	events, unsubscribe, err := broker.Subscribe(domain.DefaultNamespace, "abc")
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer*2; i++ {
+		broker.Publish(domain.ClickEvent{Namespace: domain.DefaultNamespace, ShortCode: "abc", Clicks: i + 1})
+	}
+
+	assert.Len(t, events, subscriberBuffer)
+}
+
+func TestBroker_GoalSubscribers(t *testing.T) {
+	broker := NewBroker(1)
+
+	first, unsubFirst := broker.SubscribeGoals()
+	second, unsubSecond := broker.SubscribeGoals()
+	defer unsubSecond()
+
+	// Goal subscribers do not take the place of click subscribers
+	_, unsubscribe, err := broker.Subscribe(domain.DefaultNamespace, "abc")
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	broker.PublishGoalReached(domain.GoalReachedEvent{Namespace: domain.DefaultNamespace, ShortCode: "abc", ClickGoal: 3, Clicks: 3})
+	assert.Equal(t, "abc", (<-first).ShortCode)
+	assert.Equal(t, 3, (<-second).ClickGoal)
+
+	unsubFirst()
+	unsubFirst()
+	_, open := <-first
+	assert.False(t, open)
+
+	broker.PublishGoalReached(domain.GoalReachedEvent{ShortCode: "xyz"})
+	assert.Equal(t, "xyz", (<-second).ShortCode)
+}
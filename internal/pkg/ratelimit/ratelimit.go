@@ -0,0 +1,67 @@
+// Package ratelimit bounds how many requests each client, told apart by a key such as its
+// IP address, may make. Clients are tracked in a map of bounded size, forgetting the least
+// recently seen one when full.
+package ratelimit
+
+import (
+	"container/list"
+	"net/http"
+)
+
+// Limiter decides whether the client with the given key may make one more request now
+type Limiter interface {
+	Allow(key string) bool
+}
+
+// Middleware answers the requests limiter refuses with reject rather than passing them on.
+// Requests get their client key from key; those with an empty key are never limited.
+func Middleware(limiter Limiter, key func(*http.Request) string, reject http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if k := key(r); k != "" && !limiter.Allow(k) {
+				reject.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clients holds the state of up to capacity clients, evicting the least recently seen
+// one to make room. It is not safe for concurrent use.
+type clients[T any] struct {
+	capacity int
+	recent   *list.List // of *client[T], most recently seen first
+	byKey    map[string]*list.Element
+}
+
+type client[T any] struct {
+	key   string
+	state T
+}
+
+func newClients[T any](capacity int) *clients[T] {
+	return &clients[T]{capacity: max(capacity, 1), recent: list.New(), byKey: make(map[string]*list.Element)}
+}
+
+// get returns the state of key, creating it with create when the client is new
+func (c *clients[T]) get(key string, create func() T) T {
+	if element, ok := c.byKey[key]; ok {
+		c.recent.MoveToFront(element)
+		return element.Value.(*client[T]).state
+	}
+
+	if c.recent.Len() >= c.capacity {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.byKey, oldest.Value.(*client[T]).key)
+	}
+	state := create()
+	c.byKey[key] = c.recent.PushFront(&client[T]{key: key, state: state})
+	return state
+}
+
+// len returns how many clients are tracked
+func (c *clients[T]) len() int {
+	return c.recent.Len()
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	limiter := NewTokenBucketLimiter(1, 1, 100)
+	key := func(r *http.Request) string { return r.Header.Get("X-Client") }
+	reject := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusTooManyRequests) })
+	handler := Middleware(limiter, key, reject)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(client string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Client", client)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("a"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("a"))
+	assert.Equal(t, http.StatusOK, serve(""))
+	assert.Equal(t, http.StatusOK, serve(""), "requests without a key are never limited")
+}
+
+func TestClients_EvictsLeastRecentlySeen(t *testing.T) {
+	c := newClients[int](2)
+	created := 0
+	create := func() int { created++; return created }
+
+	assert.Equal(t, 1, c.get("a", create))
+	assert.Equal(t, 2, c.get("b", create))
+	assert.Equal(t, 1, c.get("a", create), "seen again")
+	assert.Equal(t, 3, c.get("c", create), "evicts b")
+	assert.Equal(t, 2, c.len())
+	assert.Equal(t, 1, c.get("a", create))
+	assert.Equal(t, 4, c.get("b", create), "b was forgotten")
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingWindowLimiter allows each client limit requests over any span of one window. The
+// requests of the previous fixed window count in proportion to how much of it the sliding
+// window still covers, so only two counters are kept per client.
+type SlidingWindowLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows *clients[*slidingWindow]
+}
+
+type slidingWindow struct {
+	start    time.Time // of the current fixed window
+	current  int
+	previous int
+}
+
+// NewSlidingWindowLimiter creates a limiter of limit requests per window, tracking up to
+// maxIPs clients
+func NewSlidingWindowLimiter(limit int, window time.Duration, maxIPs int) *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{limit: limit, window: window, now: time.Now, windows: newClients[*slidingWindow](maxIPs)}
+}
+
+// Allow counts a request of key, reporting whether it is within the limit. Refused
+// requests are not counted.
+func (l *SlidingWindowLimiter) Allow(key string) bool {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.windows.get(key, func() *slidingWindow { return &slidingWindow{start: now.Truncate(l.window)} })
+	if start := now.Truncate(l.window); start != w.start {
+		if start.Sub(w.start) == l.window {
+			w.previous = w.current
+		} else {
+			w.previous = 0
+		}
+		w.start, w.current = start, 0
+	}
+
+	covered := 1 - float64(now.Sub(w.start))/float64(l.window)
+	if float64(w.previous)*covered+float64(w.current) >= float64(l.limit) {
+		return false
+	}
+	w.current++
+	return true
+}
@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingWindowLimiter_Allow(t *testing.T) {
+	limiter := NewSlidingWindowLimiter(4, time.Minute, 100)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	for i := range 4 {
+		assert.True(t, limiter.Allow("192.0.2.1"), "request %d of the window", i+1)
+	}
+	assert.False(t, limiter.Allow("192.0.2.1"), "request past the limit")
+	assert.True(t, limiter.Allow("192.0.2.2"), "other clients have their own window")
+
+	// Halfway through the next window, half of the previous 4 requests still count
+	now = now.Add(90 * time.Second)
+	assert.True(t, limiter.Allow("192.0.2.1"))
+	assert.True(t, limiter.Allow("192.0.2.1"))
+	assert.False(t, limiter.Allow("192.0.2.1"))
+
+	// Windows further back are forgotten
+	now = now.Add(2 * time.Minute)
+	for i := range 4 {
+		assert.True(t, limiter.Allow("192.0.2.1"), "request %d after a quiet window", i+1)
+	}
+}
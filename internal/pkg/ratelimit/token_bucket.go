@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// TokenBucketLimiter gives each client a bucket of b tokens refilled at r tokens per
+// second, each request taking one. Clients may burst up to b requests, then make r per
+// second.
+type TokenBucketLimiter struct {
+	limit rate.Limit
+	burst int
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets *clients[*rate.Limiter]
+}
+
+// NewTokenBucketLimiter creates a limiter of r requests per second with bursts of b,
+// tracking up to maxIPs clients
+func NewTokenBucketLimiter(r rate.Limit, b int, maxIPs int) *TokenBucketLimiter {
+	return &TokenBucketLimiter{limit: r, burst: b, now: time.Now, buckets: newClients[*rate.Limiter](maxIPs)}
+}
+
+// Allow takes a token from the bucket of key, reporting whether there was one
+func (l *TokenBucketLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	bucket := l.buckets.get(key, func() *rate.Limiter { return rate.NewLimiter(l.limit, l.burst) })
+	l.mu.Unlock()
+
+	return bucket.AllowN(l.now(), 1)
+}
@@ -0,0 +1,25 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucketLimiter_BurstThenThrottle(t *testing.T) {
+	limiter := NewTokenBucketLimiter(10, 5, 100)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	for i := range 5 {
+		assert.True(t, limiter.Allow("192.0.2.1"), "request %d of the burst", i+1)
+	}
+	assert.False(t, limiter.Allow("192.0.2.1"), "request past the burst")
+	assert.True(t, limiter.Allow("192.0.2.2"), "other clients have their own bucket")
+
+	// 10 requests per second refill a token every 100ms
+	now = now.Add(100 * time.Millisecond)
+	assert.True(t, limiter.Allow("192.0.2.1"))
+	assert.False(t, limiter.Allow("192.0.2.1"))
+}
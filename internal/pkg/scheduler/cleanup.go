@@ -0,0 +1,205 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+)
+
+// cleanupBatchSize is how many expired URLs are loaded and deleted at a time
+const cleanupBatchSize = 100
+
+// URLService is the part of the URL service the scheduler changes URLs through, so that
+// every change is audited as if made through the API
+type URLService interface {
+	// DeleteExpiredURL deletes url and its cache entry, failing with domain.ErrURLNotFound
+	// when it is already gone
+	DeleteExpiredURL(ctx context.Context, url *domain.URL) error
+	// SetURLEnabled disables or enables a URL and drops its cache entry, reporting whether
+	// that changed anything
+	SetURLEnabled(ctx context.Context, namespace, shortCode string, enabled bool) (bool, error)
+}
+
+// CleanupScheduler periodically deletes URLs whose expiry has passed, together with
+// their cache entries, and optionally disables URLs nobody clicked for a while
+type CleanupScheduler struct {
+	repo      domain.URLRepository
+	urls      URLService
+	metrics   metrics.Registry
+	interval  time.Duration
+	coldAfter time.Duration // URLs are never disabled when 0
+	logger    *slog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewCleanupScheduler creates a scheduler finding URLs in repo and deleting or disabling
+// them through urls
+func NewCleanupScheduler(repo domain.URLRepository, urls URLService, metricsRegistry metrics.Registry, interval, coldAfter time.Duration, logger *slog.Logger) *CleanupScheduler {
+	return &CleanupScheduler{
+		repo:      repo,
+		urls:      urls,
+		metrics:   metricsRegistry,
+		interval:  interval,
+		coldAfter: coldAfter,
+		logger:    logger,
+	}
+}
+
+// Start launches the background loop. The first cleanup runs immediately.
+func (s *CleanupScheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("Expired URL cleanup failed", "error", err)
+			}
+			if s.coldAfter > 0 {
+				if _, err := s.DisableColdOnce(ctx); err != nil && ctx.Err() == nil {
+					s.logger.Error("Cold URL disabling failed", "error", err)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels a running cleanup and waits for the loop to exit or ctx to expire
+func (s *CleanupScheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunOnce deletes every URL that had expired when the run started, one batch at a time,
+// and returns how many were deleted. A URL that fails to delete is logged and left for
+// the next run.
+func (s *CleanupScheduler) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	total := 0
+
+	for {
+		urls, err := s.repo.FindExpired(ctx, now, cleanupBatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		deleted := 0
+		for _, url := range urls {
+			if ctx.Err() != nil {
+				break
+			}
+			if s.delete(ctx, url) {
+				deleted++
+			}
+		}
+		total += deleted
+		s.metrics.AddURLsExpired(deleted)
+
+		// Stop on a short batch, or when nothing could be deleted so that the same
+		// failing URLs are not fetched over and over
+		if len(urls) < cleanupBatchSize || deleted == 0 || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if total > 0 {
+		s.logger.Info("Expired URLs deleted", "count", total)
+		s.recountActive(ctx, now)
+	}
+	return total, ctx.Err()
+}
+
+// recountActive sets the active URLs gauge from the repository. URLs leave the gauge when
+// they expire rather than when they are deleted, which only a count can tell.
+func (s *CleanupScheduler) recountActive(ctx context.Context, now time.Time) {
+	count, err := s.repo.CountActive(ctx, now)
+	if err != nil {
+		s.logger.Warn("Failed to count active URLs", "error", err)
+		return
+	}
+	s.metrics.SetActiveURLs(count)
+}
+
+// delete reports whether this run deleted url; one already gone is skipped, not a failure
+func (s *CleanupScheduler) delete(ctx context.Context, url *domain.URL) bool {
+	err := s.urls.DeleteExpiredURL(ctx, url)
+	if err != nil && !errors.Is(err, domain.ErrURLNotFound) {
+		s.logger.Warn("Failed to delete expired URL", "namespace", url.Namespace, "short_code", url.ShortCode, "error", err)
+	}
+	return err == nil
+}
+
+// DisableColdOnce disables every enabled URL neither clicked nor created within coldAfter
+// of the run starting, one batch at a time, and returns how many were disabled. It does
+// nothing when coldAfter is 0.
+func (s *CleanupScheduler) DisableColdOnce(ctx context.Context) (int, error) {
+	if s.coldAfter <= 0 {
+		return 0, nil
+	}
+
+	since := time.Now().UTC().Add(-s.coldAfter)
+	total := 0
+
+	for {
+		urls, err := s.repo.FindNotAccessedSince(ctx, since, cleanupBatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		disabled := 0
+		for _, url := range urls {
+			if ctx.Err() != nil {
+				break
+			}
+			if s.disable(ctx, url) {
+				disabled++
+			}
+		}
+		total += disabled
+
+		if len(urls) < cleanupBatchSize || disabled == 0 || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if total > 0 {
+		s.logger.Info("Cold URLs disabled", "count", total, "cold_after", s.coldAfter)
+	}
+	return total, ctx.Err()
+}
+
+// disable reports whether this run disabled url; one deleted or disabled meanwhile is skipped
+func (s *CleanupScheduler) disable(ctx context.Context, url *domain.URL) bool {
+	disabled, err := s.urls.SetURLEnabled(ctx, url.Namespace, url.ShortCode, false)
+	if err != nil && !errors.Is(err, domain.ErrURLNotFound) {
+		s.logger.Warn("Failed to disable cold URL", "namespace", url.Namespace, "short_code", url.ShortCode, "error", err)
+	}
+	return disabled
+}
@@ -0,0 +1,236 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/infrastructure/cache"
+	"github.com/sp3dr4/dove/internal/infrastructure/memory"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+)
+
+// recordingCache remembers which short codes were invalidated
+type recordingCache struct {
+	*cache.NoOpCache
+	deleted []string
+}
+
+func (c *recordingCache) Delete(_ context.Context, _, shortCode string) error {
+	c.deleted = append(c.deleted, shortCode)
+	return nil
+}
+
+// countingRegistry counts the expired, disabled and active URLs reported to it
+type countingRegistry struct {
+	metrics.NoOpRegistry
+	expired  int
+	disabled int
+	active   int64
+}
+
+func (r *countingRegistry) AddURLsExpired(n int) {
+	r.expired += n
+}
+
+func (r *countingRegistry) IncURLsDisabled() {
+	r.disabled++
+}
+
+func (r *countingRegistry) DecActiveURLs() {
+	r.active--
+}
+
+func (r *countingRegistry) SetActiveURLs(n int64) {
+	r.active = n
+}
+
+// failingDeleteRepository refuses to delete one short code
+type failingDeleteRepository struct {
+	domain.URLRepository
+	shortCode string
+}
+
+func (r *failingDeleteRepository) Delete(ctx context.Context, namespace, shortCode string) error {
+	if shortCode == r.shortCode {
+		return errors.New("database unavailable")
+	}
+	return r.URLRepository.Delete(ctx, namespace, shortCode)
+}
+
+// newService creates the URL service the scheduler changes URLs through
+func newService(repo domain.URLRepository, urlCache domain.Cache, auditLog domain.AuditRepository, registry metrics.Registry, logger *slog.Logger) *application.URLService {
+	return application.NewURLService(repo, urlCache, time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{AuditLog: auditLog, Metrics: registry}, logger)
+}
+
+func createURL(t *testing.T, repo domain.URLRepository, shortCode string, expiresAt *time.Time) {
+	t.Helper()
+
+	url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+	require.NoError(t, err)
+	url.ExpiresAt = expiresAt
+	_, err = repo.Create(context.Background(), url)
+	require.NoError(t, err)
+}
+
+func TestCleanupScheduler_RunOnce(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	t.Run("deletes every expired URL across batches", func(t *testing.T) {
+		repo := memory.NewURLRepository(logger, 0)
+		// More than two batches
+		for i := range 2*cleanupBatchSize + 5 {
+			createURL(t, repo, fmt.Sprintf("old%d", i), &past)
+		}
+		createURL(t, repo, "active", &future)
+		createURL(t, repo, "permanent", nil)
+		urlCache := &recordingCache{NoOpCache: cache.NewNoOpCache()}
+		auditLog := memory.NewAuditRepository()
+		registry := &countingRegistry{}
+		service := newService(repo, urlCache, auditLog, registry, logger)
+
+		deleted, err := NewCleanupScheduler(repo, service, registry, time.Minute, 0, logger).RunOnce(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2*cleanupBatchSize+5, deleted)
+		assert.Equal(t, deleted, registry.expired)
+		assert.Equal(t, int64(2), registry.active, "the active URLs are counted again")
+		assert.Len(t, urlCache.deleted, deleted)
+		for _, shortCode := range []string{"active", "permanent"} {
+			exists, err := repo.Exists(ctx, domain.DefaultNamespace, shortCode)
+			require.NoError(t, err)
+			assert.True(t, exists, shortCode)
+		}
+		remaining, err := repo.FindExpired(ctx, time.Now(), 10)
+		require.NoError(t, err)
+		assert.Empty(t, remaining)
+
+		entries, err := auditLog.ListByShortCode(ctx, domain.DefaultNamespace, "old0", 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, audit.OperationDelete, entries[0].Operation)
+		assert.Nil(t, entries[0].NewValue)
+	})
+
+	t.Run("leaves URLs that fail to delete for the next run", func(t *testing.T) {
+		repo := memory.NewURLRepository(logger, 0)
+		createURL(t, repo, "stuck", &past)
+		createURL(t, repo, "old", &past)
+		registry := &countingRegistry{}
+		failing := &failingDeleteRepository{URLRepository: repo, shortCode: "stuck"}
+		service := newService(failing, cache.NewNoOpCache(), nil, registry, logger)
+		scheduler := NewCleanupScheduler(failing, service, registry, time.Minute, 0, logger)
+
+		deleted, err := scheduler.RunOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		assert.Equal(t, 1, registry.expired)
+
+		remaining, err := repo.FindExpired(ctx, time.Now(), 10)
+		require.NoError(t, err)
+		require.Len(t, remaining, 1)
+		assert.Equal(t, "stuck", remaining[0].ShortCode)
+	})
+}
+
+func TestCleanupScheduler_DisableColdOnce(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ctx := context.Background()
+	coldAfter := 30 * 24 * time.Hour
+
+	// createAged creates a URL as if it had been created age ago
+	createAged := func(t *testing.T, repo domain.URLRepository, shortCode string, age time.Duration) {
+		t.Helper()
+		url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+		url.CreatedAt = time.Now().Add(-age)
+		_, err = repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	t.Run("disables URLs without recent activity", func(t *testing.T) {
+		repo := memory.NewURLRepository(logger, 0)
+		for i := range cleanupBatchSize + 5 {
+			createAged(t, repo, fmt.Sprintf("cold%d", i), 40*24*time.Hour)
+		}
+		createAged(t, repo, "clicked", 40*24*time.Hour)
+		_, err := repo.IncrementClicks(ctx, domain.DefaultNamespace, "clicked", false)
+		require.NoError(t, err)
+		createAged(t, repo, "recent", 24*time.Hour)
+		createAged(t, repo, "suspended", 40*24*time.Hour)
+		require.NoError(t, repo.SetEnabled(ctx, domain.DefaultNamespace, "suspended", false))
+		urlCache := &recordingCache{NoOpCache: cache.NewNoOpCache()}
+		auditLog := memory.NewAuditRepository()
+		registry := &countingRegistry{}
+		service := newService(repo, urlCache, auditLog, registry, logger)
+
+		disabled, err := NewCleanupScheduler(repo, service, registry, time.Minute, coldAfter, logger).DisableColdOnce(ctx)
+		require.NoError(t, err)
+
+		assert.Equal(t, cleanupBatchSize+5, disabled)
+		assert.Equal(t, disabled, registry.disabled)
+		assert.Equal(t, int64(-disabled), registry.active, "disabled URLs leave the active URLs")
+		assert.Len(t, urlCache.deleted, disabled)
+		for _, shortCode := range []string{"clicked", "recent"} {
+			url, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+			require.NoError(t, err)
+			assert.True(t, url.Enabled, shortCode)
+		}
+		cold, err := repo.FindNotAccessedSince(ctx, time.Now().Add(-coldAfter), 10)
+		require.NoError(t, err)
+		assert.Empty(t, cold)
+
+		entries, err := auditLog.ListByShortCode(ctx, domain.DefaultNamespace, "cold0", 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, audit.OperationDisable, entries[0].Operation)
+	})
+
+	t.Run("does nothing when turned off", func(t *testing.T) {
+		repo := memory.NewURLRepository(logger, 0)
+		createAged(t, repo, "cold", 40*24*time.Hour)
+
+		disabled, err := NewCleanupScheduler(repo, newService(repo, cache.NewNoOpCache(), nil, metrics.NewNoOpRegistry(), logger), metrics.NewNoOpRegistry(), time.Minute, 0, logger).DisableColdOnce(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, disabled)
+
+		url, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "cold")
+		require.NoError(t, err)
+		assert.True(t, url.Enabled)
+	})
+}
+
+func TestCleanupScheduler_StartStop(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	repo := memory.NewURLRepository(logger, 0)
+	past := time.Now().Add(-time.Hour)
+	createURL(t, repo, "old", &past)
+
+	scheduler := NewCleanupScheduler(repo, newService(repo, cache.NewNoOpCache(), nil, metrics.NewNoOpRegistry(), logger), metrics.NewNoOpRegistry(), time.Hour, 0, logger)
+	scheduler.Start()
+
+	// The first run starts right away
+	assert.Eventually(t, func() bool {
+		exists, err := repo.Exists(context.Background(), domain.DefaultNamespace, "old")
+		return err == nil && !exists
+	}, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, scheduler.Stop(ctx))
+}
@@ -0,0 +1,80 @@
+package shortcode
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Character sets of generated short codes
+const (
+	CharsetAlphanumeric = "alphanumeric"
+	CharsetSafe         = "safe"   // alphanumeric without the look-alikes 0, O, I and l
+	CharsetHex          = "hex"    // lowercase hexadecimal digits
+	CharsetCustom       = "custom" // the characters given to NewShortCodeGenerator
+)
+
+// MinCustomCharsetLength is the smallest custom alphabet accepted, keeping six character
+// codes at over 16 million combinations
+const MinCustomCharsetLength = 16
+
+var charsets = map[string]string{
+	CharsetAlphanumeric: "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	CharsetSafe:         "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ123456789",
+	CharsetHex:          "0123456789abcdef",
+}
+
+// ErrInvalidCharset is returned for an unknown charset or an unusable custom alphabet
+var ErrInvalidCharset = errors.New("invalid short code charset")
+
+// ShortCodeGenerator draws random short codes from a fixed alphabet
+type ShortCodeGenerator struct {
+	alphabet string
+}
+
+// NewShortCodeGenerator creates a generator for one of the named charsets. custom is only
+// used by CharsetCustom and must hold at least MinCustomCharsetLength distinct ASCII
+// letters or digits, the characters allowed in aliases.
+func NewShortCodeGenerator(charset, custom string) (*ShortCodeGenerator, error) {
+	if charset != CharsetCustom {
+		alphabet, ok := charsets[charset]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown charset %q", ErrInvalidCharset, charset)
+		}
+		return &ShortCodeGenerator{alphabet: alphabet}, nil
+	}
+
+	if len(custom) < MinCustomCharsetLength {
+		return nil, fmt.Errorf("%w: custom charset needs at least %d characters, got %d", ErrInvalidCharset, MinCustomCharsetLength, len(custom))
+	}
+	seen := make(map[rune]bool, len(custom))
+	for _, r := range custom {
+		if !isAlphanumeric(r) {
+			return nil, fmt.Errorf("%w: custom charset may only hold ASCII letters and digits, got %q", ErrInvalidCharset, r)
+		}
+		if seen[r] {
+			return nil, fmt.Errorf("%w: custom charset repeats %q", ErrInvalidCharset, r)
+		}
+		seen[r] = true
+	}
+	return &ShortCodeGenerator{alphabet: custom}, nil
+}
+
+// Generate returns a code of length characters, each drawn uniformly from the alphabet
+func (g *ShortCodeGenerator) Generate(length int) (string, error) {
+	size := big.NewInt(int64(len(g.alphabet)))
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate short code: %w", err)
+		}
+		code[i] = g.alphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func isAlphanumeric(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
@@ -0,0 +1,68 @@
+package shortcode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortCodeGenerator_Charsets(t *testing.T) {
+	tests := []struct {
+		charset   string
+		custom    string
+		allowed   string
+		forbidden string
+	}{
+		{CharsetAlphanumeric, "", charsets[CharsetAlphanumeric], "-_"},
+		{CharsetSafe, "", charsets[CharsetSafe], "0OIl"},
+		{CharsetHex, "", "0123456789abcdef", "ABCDEFghijxyz"},
+		{CharsetCustom, "ABCDEFGHJKMNPQRS", "ABCDEFGHJKMNPQRS", "abcxyz0123456789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.charset, func(t *testing.T) {
+			generator, err := NewShortCodeGenerator(tt.charset, tt.custom)
+			require.NoError(t, err)
+
+			seen := make(map[rune]bool)
+			for range 500 {
+				code, err := generator.Generate(8)
+				require.NoError(t, err)
+				require.Len(t, code, 8)
+				for _, r := range code {
+					seen[r] = true
+					assert.True(t, strings.ContainsRune(tt.allowed, r), "%q is not in the %s charset", r, tt.charset)
+					assert.False(t, strings.ContainsRune(tt.forbidden, r), "%q must not appear in the %s charset", r, tt.charset)
+				}
+			}
+			// 4000 draws reach every character of these alphabets
+			assert.Len(t, seen, len(tt.allowed))
+		})
+	}
+}
+
+func TestNewShortCodeGenerator_RejectsInvalidCharsets(t *testing.T) {
+	tests := []struct {
+		name    string
+		charset string
+		custom  string
+		message string
+	}{
+		{"unknown charset", "base64", "", `unknown charset "base64"`},
+		{"empty custom charset", CharsetCustom, "", "at least 16 characters, got 0"},
+		{"too short custom charset", CharsetCustom, "abcdef", "at least 16 characters, got 6"},
+		{"repeated character", CharsetCustom, "abcdefghijklmnoa", `repeats 'a'`},
+		{"non alphanumeric character", CharsetCustom, "abcdefghijklmno-", `got '-'`},
+		{"non ASCII character", CharsetCustom, "abcdefghijklmnoé", `got 'é'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewShortCodeGenerator(tt.charset, tt.custom)
+			require.ErrorIs(t, err, ErrInvalidCharset)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package shortcode
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ReservedWords is the set of words that cannot be claimed as short codes, such as the
+// paths the service routes itself. Words are compared ignoring case and may be added while
+// the set is in use.
+type ReservedWords struct {
+	mu    sync.RWMutex
+	words map[string]bool
+}
+
+// NewReservedWords creates a set holding words
+func NewReservedWords(words ...string) *ReservedWords {
+	reserved := &ReservedWords{words: make(map[string]bool, len(words))}
+	reserved.Add(words...)
+	return reserved
+}
+
+// Add reserves words, ignoring empty ones
+func (r *ReservedWords) Add(words ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, word := range words {
+		if word != "" {
+			r.words[strings.ToLower(word)] = true
+		}
+	}
+}
+
+// Contains reports whether code is reserved, ignoring case. A nil set reserves nothing.
+func (r *ReservedWords) Contains(code string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.words[strings.ToLower(code)]
+}
+
+// Words returns the reserved words in lowercase, sorted
+func (r *ReservedWords) Words() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	words := make([]string, 0, len(r.words))
+	for word := range r.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
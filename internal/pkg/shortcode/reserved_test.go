@@ -0,0 +1,35 @@
+package shortcode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservedWords_Contains(t *testing.T) {
+	reserved := NewReservedWords("admin", "Shorten", "")
+	reserved.Add("redoc")
+
+	tests := []struct {
+		code     string
+		expected bool
+	}{
+		{"admin", true},
+		{"ADMIN", true},
+		{"shorten", true},
+		{"redoc", true},
+		{"validcode", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.expected, reserved.Contains(tt.code))
+		})
+	}
+
+	assert.Equal(t, []string{"admin", "redoc", "shorten"}, reserved.Words())
+
+	var none *ReservedWords
+	assert.False(t, none.Contains("admin"), "a nil set reserves nothing")
+}
@@ -0,0 +1,70 @@
+// Package shortcode builds and checks self-contained short codes.
+package shortcode
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignedPrefix marks signed codes, which can never collide with aliases as those may not start with it
+const SignedPrefix = "s_"
+
+// macSize is the number of HMAC-SHA256 bytes kept in a token, 128 bits
+const macSize = 16
+
+// GenerateSigned returns a token for shortCode that stops verifying at expiresAt. The token
+// is SignedPrefix followed by base64url("{shortCode}:{expiry_unix}:{hmac}"), so the expiry
+// travels with the code and needs no storage.
+func GenerateSigned(secret []byte, shortCode string, expiresAt time.Time) string {
+	payload := shortCode + ":" + strconv.FormatInt(expiresAt.Unix(), 10)
+	return SignedPrefix + base64.RawURLEncoding.EncodeToString([]byte(payload+":"+sign(secret, payload)))
+}
+
+// IsSigned reports whether code has the shape of a signed token
+func IsSigned(code string) bool {
+	return strings.HasPrefix(code, SignedPrefix)
+}
+
+// VerifySigned checks the signature and expiry of token and returns the short code it wraps
+func VerifySigned(secret []byte, token string) (shortCode string, ok bool) {
+	return verifySigned(secret, token, time.Now())
+}
+
+func verifySigned(secret []byte, token string, now time.Time) (string, bool) {
+	encoded, found := strings.CutPrefix(token, SignedPrefix)
+	if !found {
+		return "", false
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", false
+	}
+
+	payload := parts[0] + ":" + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(secret, payload))) {
+		return "", false
+	}
+
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || !now.Before(time.Unix(expiry, 0)) {
+		return "", false
+	}
+
+	return parts[0], true
+}
+
+func sign(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:macSize])
+}
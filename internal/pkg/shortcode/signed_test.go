@@ -0,0 +1,66 @@
+package shortcode
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigned_RoundTrip(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Now()
+	token := GenerateSigned(secret, "launch", now.Add(2*time.Hour))
+
+	assert.True(t, IsSigned(token))
+	assert.False(t, IsSigned("launch"))
+
+	shortCode, ok := VerifySigned(secret, token)
+	assert.True(t, ok)
+	assert.Equal(t, "launch", shortCode)
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, SignedPrefix))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(raw), "launch:"))
+
+	_, ok = verifySigned(secret, token, now.Add(2*time.Hour+time.Second))
+	assert.False(t, ok, "expired")
+}
+
+func TestVerifySigned_RejectsTampering(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	expiresAt := time.Now().Add(time.Hour)
+	token := GenerateSigned(secret, "launch", expiresAt)
+
+	// Reuse the valid signature with a different payload
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, SignedPrefix))
+	require.NoError(t, err)
+	mac := string(raw)[strings.LastIndex(string(raw), ":"):]
+	forged := func(payload string) string {
+		return SignedPrefix + base64.RawURLEncoding.EncodeToString([]byte(payload+mac))
+	}
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong secret", GenerateSigned([]byte("another secret"), "launch", expiresAt)},
+		{"other short code", forged("other:" + expiry)},
+		{"extended expiry", forged("launch:99999999999")},
+		{"missing prefix", strings.TrimPrefix(token, SignedPrefix)},
+		{"not base64", SignedPrefix + "!!!"},
+		{"wrong shape", SignedPrefix + base64.RawURLEncoding.EncodeToString([]byte("launch"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := VerifySigned(secret, tt.token)
+			assert.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Package singleflight coalesces concurrent calls for the same key into a single execution.
+package singleflight
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Group runs at most one fn per key at a time. Callers arriving while a call for their key
+// is in flight wait for it and receive its result instead of starting their own.
+// The zero value is ready to use.
+type Group[T any] struct {
+	group singleflight.Group
+}
+
+// Do executes fn for key, or joins the execution already in flight. A caller whose ctx is
+// done stops waiting with ctx's error while fn carries on for the others. shared reports
+// whether the result was handed to more than one caller, in which case it must be treated
+// as read only.
+func (g *Group[T]) Do(ctx context.Context, key string, fn func() (T, error)) (value T, shared bool, err error) {
+	results := g.group.DoChan(key, func() (any, error) {
+		return fn()
+	})
+
+	select {
+	case <-ctx.Done():
+		return value, false, ctx.Err()
+	case result := <-results:
+		value, _ = result.Val.(T)
+		return value, result.Shared, result.Err
+	}
+}
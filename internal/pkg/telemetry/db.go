@@ -0,0 +1,41 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerScope is the instrumentation scope the spans report under
+const tracerScope = "github.com/sp3dr4/dove/internal/pkg/telemetry"
+
+// Database systems, as named by the OpenTelemetry semantic conventions
+const (
+	DBSystemPostgreSQL = "postgresql"
+	DBSystemRedis      = "redis"
+)
+
+// StartDBSpan starts a client span named db.<operation> for a PostgreSQL query, as a child
+// of the span in ctx if any. The caller ends the span. Spans are dropped unless a tracer
+// provider is registered with otel.SetTracerProvider.
+func StartDBSpan(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	return startSpan(ctx, "db."+operation, DBSystemPostgreSQL, query)
+}
+
+// StartCacheSpan starts a client span named cache.<operation> for a Redis command, the same
+// way StartDBSpan does for queries
+func StartCacheSpan(ctx context.Context, operation, command string) (context.Context, trace.Span) {
+	return startSpan(ctx, "cache."+operation, DBSystemRedis, command)
+}
+
+func startSpan(ctx context.Context, name, system, statement string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerScope).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", system),
+			attribute.String("db.statement", statement),
+		),
+	)
+}
@@ -0,0 +1,112 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// startedSpan is what recordingTracer saw of a span when it started
+type startedSpan struct {
+	name   string
+	parent trace.SpanContext
+	config trace.SpanConfig
+}
+
+// recordingTracerProvider records every span started through it, and starts noop spans
+type recordingTracerProvider struct {
+	noop.TracerProvider
+	tracer *recordingTracer
+}
+
+func (p *recordingTracerProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return p.tracer
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	spans []startedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.spans = append(t.spans, startedSpan{
+		name:   name,
+		parent: trace.SpanContextFromContext(ctx),
+		config: trace.NewSpanStartConfig(options...),
+	})
+	return t.Tracer.Start(ctx, name, options...)
+}
+
+func useRecordingTracer(t *testing.T) *recordingTracer {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	tracer := &recordingTracer{}
+	otel.SetTracerProvider(&recordingTracerProvider{tracer: tracer})
+	return tracer
+}
+
+func TestStartDBSpan(t *testing.T) {
+	t.Run("without a tracer provider", func(t *testing.T) {
+		previous := otel.GetTracerProvider()
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+		otel.SetTracerProvider(noop.NewTracerProvider())
+
+		ctx, span := StartDBSpan(context.Background(), "Create", "INSERT INTO urls")
+		defer span.End()
+
+		assert.False(t, span.IsRecording())
+		assert.Equal(t, span, trace.SpanFromContext(ctx))
+	})
+
+	t.Run("is a child of the request span", func(t *testing.T) {
+		tracer := useRecordingTracer(t)
+
+		request := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{2},
+			TraceFlags: trace.FlagsSampled,
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), request)
+
+		ctx, span := StartDBSpan(ctx, "FindByShortCode", "SELECT * FROM urls")
+		span.End()
+		_, span = StartCacheSpan(ctx, "Set", "SET")
+		span.End()
+
+		require.Len(t, tracer.spans, 2)
+
+		db := tracer.spans[0]
+		assert.Equal(t, "db.FindByShortCode", db.name)
+		assert.Equal(t, request, db.parent)
+		assert.Equal(t, trace.SpanKindClient, db.config.SpanKind())
+		assert.ElementsMatch(t, []attribute.KeyValue{
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", "SELECT * FROM urls"),
+		}, db.config.Attributes())
+
+		cache := tracer.spans[1]
+		assert.Equal(t, "cache.Set", cache.name)
+		assert.Equal(t, request.TraceID(), cache.parent.TraceID())
+		assert.ElementsMatch(t, []attribute.KeyValue{
+			attribute.String("db.system", "redis"),
+			attribute.String("db.statement", "SET"),
+		}, cache.config.Attributes())
+	})
+
+	t.Run("starts a trace without a request span", func(t *testing.T) {
+		tracer := useRecordingTracer(t)
+
+		_, span := StartDBSpan(context.Background(), "Create", "INSERT INTO urls")
+		span.End()
+
+		require.Len(t, tracer.spans, 1)
+		assert.False(t, tracer.spans[0].parent.IsValid())
+	})
+}
@@ -0,0 +1,79 @@
+package urlfetch
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxRedirects is how many redirects a client from NewClient follows
+const MaxRedirects = 5
+
+var (
+	ErrPrivateAddress = errors.New("destination is not a public address")
+	ErrTooManyHops    = fmt.Errorf("stopped after %d redirects", MaxRedirects)
+)
+
+// NewClient returns a client for fetching pages chosen by users. It only connects to public
+// addresses, checked once host names are resolved so that neither a name nor a redirect
+// reaches the internal network, and follows at most MaxRedirects redirects. Proxy
+// environment variables are ignored, since only the proxy address would be checked.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   refuseNonPublic,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Transport: transport, CheckRedirect: checkRedirect}
+}
+
+// refuseNonPublic is the dialer Control, called with the resolved address of every connection
+func refuseNonPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublic(addr) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addr)
+	}
+	return nil
+}
+
+// checkRedirect refuses redirects to other schemes or to hosts that are obviously not
+// public, ahead of the dialer checking the address they resolve to
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= MaxRedirects {
+		return ErrTooManyHops
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(req.URL.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublic(addr) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addr)
+	}
+	return nil
+}
+
+// isPublic rejects private, loopback, link-local, multicast and unspecified addresses
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
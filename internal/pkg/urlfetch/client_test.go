@@ -0,0 +1,90 @@
+package urlfetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	neturl "net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient_RefusesNonPublicAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<title>Internal</title>`))
+	}))
+	t.Cleanup(server.Close)
+
+	// The host name resolves to the loopback address, which is checked once resolved
+	parsed, err := neturl.Parse(server.URL)
+	require.NoError(t, err)
+	for _, url := range []string{server.URL, "http://localhost:" + parsed.Port()} {
+		_, err := NewClient().Get(url)
+		assert.ErrorIs(t, err, ErrPrivateAddress, url)
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	redirect := func(target string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, target, nil)
+		require.NoError(t, err)
+		return req
+	}
+	via := []*http.Request{redirect("https://example.com/start")}
+
+	tests := []struct {
+		target string
+		want   error
+	}{
+		{target: "https://example.org/next"},
+		{target: "http://169.254.169.254/latest/meta-data/", want: ErrPrivateAddress},
+		{target: "http://[::ffff:127.0.0.1]/", want: ErrPrivateAddress},
+		{target: "http://10.0.0.1/", want: ErrPrivateAddress},
+		{target: "http://internal.localhost/", want: ErrPrivateAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			err := checkRedirect(redirect(tt.target), via)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		assert.Error(t, checkRedirect(redirect("ftp://example.org/file"), via))
+	})
+
+	t.Run("too many redirects", func(t *testing.T) {
+		hops := make([]*http.Request, MaxRedirects)
+		for i := range hops {
+			hops[i] = redirect("https://example.com/hop")
+		}
+		assert.ErrorIs(t, checkRedirect(redirect("https://example.org/next"), hops), ErrTooManyHops)
+	})
+}
+
+func TestIsPublic(t *testing.T) {
+	for address, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"::ffff:8.8.8.8":   true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"0.0.0.0":          false,
+		"224.0.0.1":        false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		assert.Equal(t, want, isPublic(netip.MustParseAddr(address)), address)
+	}
+}
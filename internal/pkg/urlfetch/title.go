@@ -0,0 +1,156 @@
+// Package urlfetch retrieves metadata from destination pages.
+package urlfetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// Timeout bounds the whole fetch of a page title, HEAD and GET together
+	Timeout = 3 * time.Second
+	// MaxBodySize is how much of a page is read looking for its title
+	MaxBodySize = 512 << 10
+	// MaxSlugLength is the longest slug produced from a title
+	MaxSlugLength = 15
+)
+
+var (
+	ErrNotHTML   = errors.New("destination is not an HTML page")
+	ErrNoTitle   = errors.New("page has no title")
+	ErrBadStatus = errors.New("destination returned an error status")
+)
+
+// FetchTitle returns the og:title of the page at rawURL, or the contents of its <title>
+// element when it has none. A HEAD
+// request first checks that the page is HTML, so large downloads are not started for
+// anything else; servers that do not support HEAD are tolerated.
+func FetchTitle(ctx context.Context, client *http.Client, rawURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	head, err := request(ctx, client, http.MethodHead, rawURL)
+	if err != nil {
+		return "", err
+	}
+	_ = head.Body.Close()
+	if head.StatusCode < http.StatusBadRequest && !isHTML(head.Header.Get("Content-Type")) {
+		return "", ErrNotHTML
+	}
+
+	resp, err := request(ctx, client, http.MethodGet, rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("%w: %d", ErrBadStatus, resp.StatusCode)
+	}
+	if !isHTML(resp.Header.Get("Content-Type")) {
+		return "", ErrNotHTML
+	}
+
+	return extractTitle(io.LimitReader(resp.Body, MaxBodySize))
+}
+
+func request(ctx context.Context, client *http.Client, method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "dove-title-fetcher/1.0")
+
+	return client.Do(req)
+}
+
+// isHTML accepts a missing Content-Type, which browsers would sniff
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// extractTitle returns the og:title of the page, or the text of its first <title> element
+// without one, stopping at the end of <head>
+func extractTitle(r io.Reader) (string, error) {
+	var title string
+	found := func() (string, error) {
+		if title == "" {
+			return "", ErrNoTitle
+		}
+		return title, nil
+	}
+
+	tokenizer := html.NewTokenizer(r)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				return found()
+			}
+			return "", tokenizer.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch string(name) {
+			case "meta":
+				if ogTitle := openGraphTitle(tokenizer, hasAttr); ogTitle != "" {
+					return ogTitle, nil
+				}
+			case "title":
+				if title != "" || tokenizer.Next() != html.TextToken {
+					continue
+				}
+				title = strings.TrimSpace(string(tokenizer.Text()))
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "head" {
+				return found()
+			}
+		}
+	}
+}
+
+// openGraphTitle returns the content of the current <meta> tag when it is an og:title
+func openGraphTitle(tokenizer *html.Tokenizer, hasAttr bool) string {
+	var property, content string
+	for hasAttr {
+		var key, value []byte
+		key, value, hasAttr = tokenizer.TagAttr()
+		switch string(key) {
+		case "property":
+			property = string(value)
+		case "content":
+			content = string(value)
+		}
+	}
+	if !strings.EqualFold(property, "og:title") {
+		return ""
+	}
+	return strings.TrimSpace(content)
+}
+
+// Slugify reduces a title to lowercase ASCII letters and digits, truncated to MaxSlugLength
+func Slugify(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			if b.Len() == MaxSlugLength {
+				break
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package urlfetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchTitle(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<!DOCTYPE html><html><head><meta charset="utf-8"><title> The Go Blog &amp; More </title></head><body></body></html>`))
+	})
+	mux.HandleFunc("/open-graph", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Plain title</title><meta property="og:title" content=" Shared title "/></head></html>`))
+	})
+	mux.HandleFunc("/untitled", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head></head><body><title>not in head</title></body></html>`))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><!--" + strings.Repeat("x", MaxBodySize) + "--><title>too late</title></html>"))
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		_, _ = w.Write([]byte(`<title>GET only</title>`))
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		path        string
+		expected    string
+		expectedErr error
+	}{
+		{path: "/page", expected: "The Go Blog & More"},
+		{path: "/no-head", expected: "GET only"},
+		{path: "/open-graph", expected: "Shared title"},
+		{path: "/untitled", expectedErr: ErrNoTitle},
+		{path: "/image", expectedErr: ErrNotHTML},
+		{path: "/huge", expectedErr: ErrNoTitle},
+		{path: "/missing", expectedErr: ErrBadStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			title, err := FetchTitle(context.Background(), server.Client(), server.URL+tt.path)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, title)
+		})
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected string
+	}{
+		{"The Go Blog", "thegoblog"},
+		{"Go 1.24 Release Notes - The Go Programming Language", "go124releasenot"},
+		{"Café & Crème", "cafcrme"},
+		{"   ", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Slugify(tt.title), tt.title)
+	}
+}
@@ -0,0 +1,130 @@
+// Package useragent classifies User-Agent headers into browser, operating system
+// and device type for click analytics. It recognises the common browsers and
+// platforms by their well-known tokens and is not meant to be exhaustive.
+package useragent
+
+import "strings"
+
+// Device types
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// Unknown is reported for a browser or operating system that could not be identified
+const Unknown = "unknown"
+
+// UserAgent is the classification of a User-Agent header
+type UserAgent struct {
+	Browser    string
+	OS         string
+	DeviceType string
+}
+
+type token struct {
+	needle string
+	name   string
+}
+
+// Automated clients, matched before anything else. Each entry's name is reported as the browser.
+var botTokens = []token{
+	{"curl/", "curl"},
+	{"wget/", "Wget"},
+	{"python-requests", "python-requests"},
+	{"go-http-client", "Go-http-client"},
+	{"postmanruntime", "Postman"},
+	{"googlebot", "Googlebot"},
+	{"bingbot", "Bingbot"},
+	{"slackbot", "Slackbot"},
+	{"twitterbot", "Twitterbot"},
+	{"facebookexternalhit", "Facebook"},
+	{"bot", "Bot"},
+	{"crawler", "Crawler"},
+	{"spider", "Spider"},
+}
+
+// Browsers, in match order: most embed "Chrome" or "Safari" in their header,
+// so the specific ones must come first
+var browserTokens = []token{
+	{"edg/", "Edge"},
+	{"edga/", "Edge"},
+	{"edgios/", "Edge"},
+	{"edge/", "Edge"},
+	{"opr/", "Opera"},
+	{"opera", "Opera"},
+	{"samsungbrowser/", "Samsung Internet"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"crios/", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"msie ", "Internet Explorer"},
+	{"trident/", "Internet Explorer"},
+	{"safari/", "Safari"},
+}
+
+// Operating systems, in match order: iOS headers contain "like Mac OS X"
+// and Android headers contain "Linux"
+var osTokens = []token{
+	{"windows", "Windows"},
+	{"iphone", "iOS"},
+	{"ipad", "iOS"},
+	{"ipod", "iOS"},
+	{"android", "Android"},
+	{"cros", "ChromeOS"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"linux", "Linux"},
+}
+
+// Parse classifies a User-Agent header
+func Parse(header string) UserAgent {
+	ua := strings.ToLower(strings.TrimSpace(header))
+	if ua == "" {
+		return UserAgent{Browser: Unknown, OS: Unknown, DeviceType: DeviceUnknown}
+	}
+
+	if name, ok := match(ua, botTokens); ok {
+		return UserAgent{Browser: name, OS: matchOr(ua, osTokens), DeviceType: DeviceBot}
+	}
+
+	return UserAgent{
+		Browser:    matchOr(ua, browserTokens),
+		OS:         matchOr(ua, osTokens),
+		DeviceType: deviceType(ua),
+	}
+}
+
+func deviceType(ua string) string {
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"):
+		return DeviceTablet
+	case strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		// Android tablets omit the "Mobile" token
+		return DeviceTablet
+	case strings.Contains(ua, "mobile"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"), strings.Contains(ua, "android"):
+		return DeviceMobile
+	case strings.Contains(ua, "windows"), strings.Contains(ua, "macintosh"), strings.Contains(ua, "x11"), strings.Contains(ua, "cros"):
+		return DeviceDesktop
+	default:
+		return DeviceUnknown
+	}
+}
+
+func match(ua string, tokens []token) (string, bool) {
+	for _, t := range tokens {
+		if strings.Contains(ua, t.needle) {
+			return t.name, true
+		}
+	}
+	return "", false
+}
+
+func matchOr(ua string, tokens []token) string {
+	if name, ok := match(ua, tokens); ok {
+		return name
+	}
+	return Unknown
+}
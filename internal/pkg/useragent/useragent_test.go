@@ -0,0 +1,87 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected UserAgent
+	}{
+		{
+			name:     "chrome on android",
+			header:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Mobile Safari/537.36",
+			expected: UserAgent{Browser: "Chrome", OS: "Android", DeviceType: DeviceMobile},
+		},
+		{
+			name:     "safari on ios",
+			header:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			expected: UserAgent{Browser: "Safari", OS: "iOS", DeviceType: DeviceMobile},
+		},
+		{
+			name:     "safari on ipad",
+			header:   "Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			expected: UserAgent{Browser: "Safari", OS: "iOS", DeviceType: DeviceTablet},
+		},
+		{
+			name:     "chrome on ios",
+			header:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/124.0.6367.88 Mobile/15E148 Safari/604.1",
+			expected: UserAgent{Browser: "Chrome", OS: "iOS", DeviceType: DeviceMobile},
+		},
+		{
+			name:     "android tablet",
+			header:   "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			expected: UserAgent{Browser: "Chrome", OS: "Android", DeviceType: DeviceTablet},
+		},
+		{
+			name:     "chrome on windows",
+			header:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			expected: UserAgent{Browser: "Chrome", OS: "Windows", DeviceType: DeviceDesktop},
+		},
+		{
+			name:     "edge on windows",
+			header:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.67",
+			expected: UserAgent{Browser: "Edge", OS: "Windows", DeviceType: DeviceDesktop},
+		},
+		{
+			name:     "firefox on linux",
+			header:   "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+			expected: UserAgent{Browser: "Firefox", OS: "Linux", DeviceType: DeviceDesktop},
+		},
+		{
+			name:     "safari on macos",
+			header:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4_1) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15",
+			expected: UserAgent{Browser: "Safari", OS: "macOS", DeviceType: DeviceDesktop},
+		},
+		{
+			name:     "curl",
+			header:   "curl/8.5.0",
+			expected: UserAgent{Browser: "curl", OS: Unknown, DeviceType: DeviceBot},
+		},
+		{
+			name:     "googlebot",
+			header:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			expected: UserAgent{Browser: "Googlebot", OS: Unknown, DeviceType: DeviceBot},
+		},
+		{
+			name:     "empty",
+			header:   "",
+			expected: UserAgent{Browser: Unknown, OS: Unknown, DeviceType: DeviceUnknown},
+		},
+		{
+			name:     "unrecognised",
+			header:   "SomethingElse/1.0",
+			expected: UserAgent{Browser: Unknown, OS: Unknown, DeviceType: DeviceUnknown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Parse(tt.header))
+		})
+	}
+}
@@ -0,0 +1,4 @@
+-- Optional bcrypt passphrase for protected short URLs
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN urls.password_hash IS 'Bcrypt hash of the link passphrase, empty for public links';
@@ -0,0 +1,13 @@
+-- Individual click records backing the time-series analytics
+CREATE TABLE IF NOT EXISTS url_clicks (
+    id BIGSERIAL PRIMARY KEY,
+    short_code VARCHAR(20) NOT NULL REFERENCES urls(short_code) ON DELETE CASCADE,
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Time-series queries always filter by short code and a clicked_at range
+CREATE INDEX IF NOT EXISTS idx_url_clicks_short_code_clicked_at
+ON url_clicks(short_code, clicked_at);
+
+COMMENT ON TABLE url_clicks IS 'One row per redirect, used for click analytics';
+COMMENT ON COLUMN url_clicks.clicked_at IS 'When the short URL was followed';
@@ -0,0 +1,10 @@
+-- Destination health maintained by the background health checker
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS health_status VARCHAR(16) NOT NULL DEFAULT 'unknown';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP WITH TIME ZONE;
+
+-- The checker always picks the least recently checked URLs first
+CREATE INDEX IF NOT EXISTS idx_urls_last_checked_at
+ON urls(last_checked_at ASC NULLS FIRST);
+
+COMMENT ON COLUMN urls.health_status IS 'Destination health: unknown, healthy or dead';
+COMMENT ON COLUMN urls.last_checked_at IS 'When the destination was last probed, NULL if never';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_url_clicks_short_code_referer;
+
+ALTER TABLE url_clicks DROP COLUMN IF EXISTS referer;
@@ -0,0 +1,7 @@
+-- Normalised Referer (host and path) of each click; earlier clicks count as direct traffic
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS referer TEXT NOT NULL DEFAULT '(direct)';
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_short_code_referer
+ON url_clicks(short_code, referer);
+
+COMMENT ON COLUMN url_clicks.referer IS 'Referring host and path, or (direct) when the request had none';
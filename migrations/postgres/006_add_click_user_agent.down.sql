@@ -0,0 +1,3 @@
+ALTER TABLE url_clicks DROP COLUMN IF EXISTS ua_device_type;
+ALTER TABLE url_clicks DROP COLUMN IF EXISTS ua_os;
+ALTER TABLE url_clicks DROP COLUMN IF EXISTS ua_browser;
@@ -0,0 +1,6 @@
+-- Browser, operating system and device type parsed from the User-Agent of each click
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS ua_browser TEXT NOT NULL DEFAULT 'unknown';
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS ua_os TEXT NOT NULL DEFAULT 'unknown';
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS ua_device_type TEXT NOT NULL DEFAULT 'unknown';
+
+COMMENT ON COLUMN url_clicks.ua_device_type IS 'desktop, mobile, tablet, bot or unknown';
@@ -0,0 +1,19 @@
+-- Fails if the same short code is in use in several namespaces
+DROP INDEX IF EXISTS idx_url_clicks_namespace_short_code_referer;
+DROP INDEX IF EXISTS idx_url_clicks_namespace_short_code_clicked_at;
+
+ALTER TABLE url_clicks DROP CONSTRAINT IF EXISTS url_clicks_namespace_short_code_fkey;
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_namespace_short_code_key;
+
+ALTER TABLE urls ADD CONSTRAINT urls_short_code_key UNIQUE (short_code);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_short_code ON urls(short_code);
+ALTER TABLE url_clicks ADD CONSTRAINT url_clicks_short_code_fkey
+    FOREIGN KEY (short_code) REFERENCES urls(short_code) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_short_code_clicked_at ON url_clicks(short_code, clicked_at);
+CREATE INDEX IF NOT EXISTS idx_url_clicks_short_code_referer ON url_clicks(short_code, referer);
+
+ALTER TABLE url_clicks DROP COLUMN IF EXISTS namespace;
+ALTER TABLE urls DROP COLUMN IF EXISTS namespace;
+
+DROP TABLE IF EXISTS namespaces;
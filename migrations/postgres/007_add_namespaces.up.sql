@@ -0,0 +1,36 @@
+-- Isolated short code spaces for multi-tenant deployments
+CREATE TABLE IF NOT EXISTS namespaces (
+    id BIGSERIAL PRIMARY KEY,
+    slug VARCHAR(32) UNIQUE NOT NULL,
+    owner_id VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO namespaces (slug) VALUES ('default') ON CONFLICT (slug) DO NOTHING;
+
+-- Existing URLs and clicks move to the default namespace
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS namespace VARCHAR(32) NOT NULL DEFAULT 'default'
+    REFERENCES namespaces(slug);
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS namespace VARCHAR(32) NOT NULL DEFAULT 'default';
+
+-- Short codes become unique per namespace; clicks follow the composite key
+ALTER TABLE url_clicks DROP CONSTRAINT IF EXISTS url_clicks_short_code_fkey;
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_short_code_key;
+DROP INDEX IF EXISTS idx_urls_short_code;
+
+ALTER TABLE urls ADD CONSTRAINT urls_namespace_short_code_key UNIQUE (namespace, short_code);
+ALTER TABLE url_clicks ADD CONSTRAINT url_clicks_namespace_short_code_fkey
+    FOREIGN KEY (namespace, short_code) REFERENCES urls(namespace, short_code) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_url_clicks_short_code_clicked_at;
+DROP INDEX IF EXISTS idx_url_clicks_short_code_referer;
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_namespace_short_code_clicked_at
+ON url_clicks(namespace, short_code, clicked_at);
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_namespace_short_code_referer
+ON url_clicks(namespace, short_code, referer);
+
+COMMENT ON TABLE namespaces IS 'Tenant short code spaces, registered when first used';
+COMMENT ON COLUMN namespaces.owner_id IS 'Identifier of the owning tenant, empty when unassigned';
+COMMENT ON COLUMN urls.namespace IS 'Namespace the short code is unique within';
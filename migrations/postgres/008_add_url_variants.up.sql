@@ -0,0 +1,20 @@
+-- Alternative destinations of a short URL for A/B testing, chosen at random in proportion to weight
+CREATE TABLE IF NOT EXISTS url_variants (
+    id BIGSERIAL PRIMARY KEY,
+    url_id BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    original_url TEXT NOT NULL,
+    weight INTEGER NOT NULL CHECK (weight > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_variants_url_id ON url_variants(url_id);
+
+-- Variant each click was sent to; NULL for URLs without variants
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS variant_id BIGINT
+    REFERENCES url_variants(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_variant_id ON url_clicks(variant_id);
+
+COMMENT ON TABLE url_variants IS 'Weighted alternative destinations of a short URL';
+COMMENT ON COLUMN url_variants.weight IS 'Relative share of the traffic sent to this destination';
+COMMENT ON COLUMN url_clicks.variant_id IS 'Variant the click was redirected to, if the URL has variants';
@@ -0,0 +1,4 @@
+ALTER TABLE url_clicks DROP COLUMN IF EXISTS target_url;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS pool;
+ALTER TABLE urls DROP COLUMN IF EXISTS pool_enabled;
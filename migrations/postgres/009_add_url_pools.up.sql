@@ -0,0 +1,10 @@
+-- Load balanced short URLs redirect to the targets of their pool in weighted round-robin order
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS pool_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS pool JSONB;
+
+-- Pool target each click was sent to, empty for URLs without a pool
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS target_url TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN urls.pool_enabled IS 'Whether redirects rotate through the pool targets';
+COMMENT ON COLUMN urls.pool IS 'Weighted redirect targets as {"targets": [{"url", "weight"}]}';
+COMMENT ON COLUMN url_clicks.target_url IS 'Pool target the click was redirected to';
@@ -0,0 +1,4 @@
+-- Signed URLs carry their expiry in an HMAC-signed token instead of the database
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS signed BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN urls.signed IS 'Whether the URL is only reachable through a signed, expiring token';
@@ -0,0 +1,4 @@
+-- Country specific destinations that take precedence over the other redirect targets
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS geo_routes JSONB;
+
+COMMENT ON COLUMN urls.geo_routes IS 'Country routes as [{"countryCode", "destinationUrl"}], NULL when the URL has none';
@@ -0,0 +1,4 @@
+-- Device specific destinations, consulted after the geo routes
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS device_routes JSONB;
+
+COMMENT ON COLUMN urls.device_routes IS 'Device routes as [{"deviceType", "destinationUrl"}], NULL when the URL has none';
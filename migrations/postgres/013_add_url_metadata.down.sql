@@ -0,0 +1,3 @@
+ALTER TABLE urls DROP COLUMN IF EXISTS tags;
+ALTER TABLE urls DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS redirect_type;
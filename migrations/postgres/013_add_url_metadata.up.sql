@@ -0,0 +1,8 @@
+-- Per URL redirect status, expiry and tags, all editable through PATCH /shorten/{shortCode}
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_type INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS tags JSONB;
+
+COMMENT ON COLUMN urls.redirect_type IS 'HTTP status of plain redirects, 0 for the default 301';
+COMMENT ON COLUMN urls.expires_at IS 'When the URL stops redirecting, NULL for never';
+COMMENT ON COLUMN urls.tags IS 'Labels as a JSON array of strings';
@@ -0,0 +1,4 @@
+-- Seconds an interstitial countdown page is shown before redirecting
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS delay_seconds INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN urls.delay_seconds IS 'Seconds of countdown page before redirecting, 0 to redirect immediately';
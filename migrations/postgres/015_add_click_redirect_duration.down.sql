@@ -0,0 +1 @@
+ALTER TABLE url_clicks DROP COLUMN IF EXISTS redirect_duration_ms;
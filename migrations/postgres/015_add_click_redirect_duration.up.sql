@@ -0,0 +1,4 @@
+-- Time the redirect handler took before recording the click, NULL for clicks recorded earlier
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS redirect_duration_ms DOUBLE PRECISION;
+
+COMMENT ON COLUMN url_clicks.redirect_duration_ms IS 'Milliseconds from request receipt to recording the click, NULL when unknown';
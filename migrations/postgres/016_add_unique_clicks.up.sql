@@ -0,0 +1,4 @@
+-- Clicks from visitors not already seen within the deduplication window
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS unique_clicks BIGINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN urls.unique_clicks IS 'Clicks left after removing repeat visits within the deduplication window';
@@ -0,0 +1,4 @@
+-- Free text label shown to operators, NULL when the URL has none
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS description TEXT;
+
+COMMENT ON COLUMN urls.description IS 'Human readable label of the URL, at most 500 characters';
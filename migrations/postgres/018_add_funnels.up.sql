@@ -0,0 +1,34 @@
+-- Ordered sequences of short URLs, such as the pages of a campaign, whose visitors are
+-- followed from step to step
+CREATE TABLE IF NOT EXISTS funnels (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Steps refer to URLs by code rather than by key, so that a deleted URL leaves a gap in
+-- the funnel instead of renumbering it
+CREATE TABLE IF NOT EXISTS funnel_steps (
+    funnel_id BIGINT NOT NULL REFERENCES funnels(id) ON DELETE CASCADE,
+    step_order INTEGER NOT NULL CHECK (step_order > 0),
+    namespace VARCHAR(32) NOT NULL,
+    short_code VARCHAR(20) NOT NULL,
+    PRIMARY KEY (funnel_id, step_order)
+);
+
+-- Redirects look up the funnels of the URL they serve
+CREATE INDEX IF NOT EXISTS idx_funnel_steps_url ON funnel_steps(namespace, short_code);
+
+CREATE TABLE IF NOT EXISTS funnel_clicks (
+    id BIGSERIAL PRIMARY KEY,
+    funnel_id BIGINT NOT NULL,
+    step_order INTEGER NOT NULL,
+    session_id VARCHAR(64) NOT NULL,
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (funnel_id, step_order) REFERENCES funnel_steps(funnel_id, step_order) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_funnel_clicks_funnel_session ON funnel_clicks(funnel_id, session_id);
+
+COMMENT ON TABLE funnels IS 'Ordered sequences of short URLs followed by visitor session';
+COMMENT ON COLUMN funnel_clicks.session_id IS 'Value of the dove_sid cookie of the visitor';
@@ -0,0 +1,9 @@
+-- Disabled URLs are kept but answer redirects with 410 Gone until enabled again
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Partial index, as few URLs are ever disabled
+CREATE INDEX IF NOT EXISTS idx_urls_disabled
+ON urls(namespace, short_code)
+WHERE NOT enabled;
+
+COMMENT ON COLUMN urls.enabled IS 'Whether the URL redirects, false while an operator suspended it';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_urls_last_activity;
+ALTER TABLE urls DROP COLUMN IF EXISTS last_accessed_at;
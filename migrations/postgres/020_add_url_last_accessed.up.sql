@@ -0,0 +1,9 @@
+-- Time of the latest click, NULL until the URL is first clicked
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS last_accessed_at TIMESTAMP WITH TIME ZONE;
+
+-- Cold URL lookups order enabled URLs by their latest activity
+CREATE INDEX IF NOT EXISTS idx_urls_last_activity
+ON urls((COALESCE(last_accessed_at, created_at)))
+WHERE enabled;
+
+COMMENT ON COLUMN urls.last_accessed_at IS 'When the URL was last clicked, NULL if never';
@@ -0,0 +1,5 @@
+-- Whether the short code was chosen by the creator rather than generated. URLs created
+-- before this column existed count as generated, as nothing recorded the difference.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS is_custom_alias BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN urls.is_custom_alias IS 'Whether the short code is a custom alias rather than a generated code';
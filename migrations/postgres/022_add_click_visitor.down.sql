@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_url_clicks_namespace_short_code_clicked_at_id;
+
+ALTER TABLE url_clicks DROP COLUMN IF EXISTS country;
+ALTER TABLE url_clicks DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE url_clicks DROP COLUMN IF EXISTS ip_address;
@@ -0,0 +1,12 @@
+-- Visitor of each click, kept for raw click exports. Clicks recorded earlier have none.
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS ip_address TEXT NOT NULL DEFAULT '';
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE url_clicks ADD COLUMN IF NOT EXISTS country VARCHAR(2) NOT NULL DEFAULT '';
+
+-- Exports page through the clicks of a URL by clicked_at and then id
+CREATE INDEX IF NOT EXISTS idx_url_clicks_namespace_short_code_clicked_at_id
+ON url_clicks(namespace, short_code, clicked_at, id);
+
+COMMENT ON COLUMN url_clicks.ip_address IS 'Client IP address of the visitor, empty when unknown';
+COMMENT ON COLUMN url_clicks.user_agent IS 'Raw User-Agent header of the visitor';
+COMMENT ON COLUMN url_clicks.country IS 'ISO 3166-1 alpha-2 country of the visitor, empty when unknown';
@@ -0,0 +1,2 @@
+ALTER TABLE urls DROP COLUMN IF EXISTS goal_reached;
+ALTER TABLE urls DROP COLUMN IF EXISTS click_goal;
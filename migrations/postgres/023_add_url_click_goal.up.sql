@@ -0,0 +1,7 @@
+-- Number of clicks after which a URL has reached its goal, NULL for URLs without one.
+-- goal_reached is set once, by the click that reaches the goal.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS click_goal INTEGER CHECK (click_goal > 0);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS goal_reached BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN urls.click_goal IS 'Clicks the URL aims for, NULL when it has no goal';
+COMMENT ON COLUMN urls.goal_reached IS 'Whether the clicks have reached click_goal';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_url_clicks_ip_address;
+
+ALTER TABLE url_clicks ALTER COLUMN ip_address TYPE TEXT USING COALESCE(host(ip_address), '');
+ALTER TABLE url_clicks ALTER COLUMN ip_address SET DEFAULT '';
+ALTER TABLE url_clicks ALTER COLUMN ip_address SET NOT NULL;
+
+COMMENT ON COLUMN url_clicks.ip_address IS 'Client IP address of the visitor, empty when unknown';
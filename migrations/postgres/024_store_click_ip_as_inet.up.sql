@@ -0,0 +1,11 @@
+-- Client IP addresses become inet, NULL when unknown. IPv4-mapped IPv6 addresses recorded
+-- so far are stored as plain IPv4, as new clicks are.
+ALTER TABLE url_clicks ALTER COLUMN ip_address DROP DEFAULT;
+ALTER TABLE url_clicks ALTER COLUMN ip_address DROP NOT NULL;
+ALTER TABLE url_clicks ALTER COLUMN ip_address TYPE INET
+USING NULLIF(regexp_replace(ip_address, '^::ffff:(\d+\.\d+\.\d+\.\d+)$', '\1', 'i'), '')::inet;
+
+-- Top IPs group every click by address
+CREATE INDEX IF NOT EXISTS idx_url_clicks_ip_address ON url_clicks(ip_address);
+
+COMMENT ON COLUMN url_clicks.ip_address IS 'Client IP address of the visitor, NULL when unknown';
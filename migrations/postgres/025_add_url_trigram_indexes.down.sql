@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS urls_description_trgm_idx;
+DROP INDEX IF EXISTS urls_short_code_trgm_idx;
+DROP INDEX IF EXISTS urls_original_url_trgm_idx;
+
+-- pg_trgm is left installed, other objects of the database may depend on it
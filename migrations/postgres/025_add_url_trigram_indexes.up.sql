@@ -0,0 +1,23 @@
+-- Trigram indexes serve URL searches, both substring matches with ILIKE and similarity
+-- matches with %. Creating pg_trgm takes a role allowed to create extensions, which
+-- hosted databases may not grant: the indexes are then skipped, searches scan the table
+-- and database.postgres.trgm_enabled must stay off.
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN insufficient_privilege THEN
+    RAISE NOTICE 'pg_trgm could not be created, URL searches will not use trigram indexes';
+END
+$$;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX IF NOT EXISTS urls_original_url_trgm_idx ON urls USING gin (original_url gin_trgm_ops);
+        -- Searches also match short codes and descriptions, which need their own index
+        -- for the planner to combine them rather than scan
+        CREATE INDEX IF NOT EXISTS urls_short_code_trgm_idx ON urls USING gin (short_code gin_trgm_ops);
+        CREATE INDEX IF NOT EXISTS urls_description_trgm_idx ON urls USING gin (description gin_trgm_ops);
+    END IF;
+END
+$$;
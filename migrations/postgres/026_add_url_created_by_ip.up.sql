@@ -0,0 +1,7 @@
+-- Address of the client that created the URL, NULL when unknown, for abuse investigations
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS created_by_ip INET;
+
+-- Admins list the URLs created from one address
+CREATE INDEX IF NOT EXISTS idx_urls_created_by_ip ON urls(created_by_ip);
+
+COMMENT ON COLUMN urls.created_by_ip IS 'Client IP address of the creator, NULL when unknown';
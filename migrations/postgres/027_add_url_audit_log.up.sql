@@ -0,0 +1,20 @@
+-- Change history of short URLs, read back by the admin audit log endpoint. Entries outlive
+-- the URLs they describe, so deletions stay on record.
+CREATE TABLE IF NOT EXISTS url_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    operation VARCHAR(32) NOT NULL,
+    namespace VARCHAR(32) NOT NULL,
+    short_code VARCHAR(20) NOT NULL,
+    actor_ip TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    old_value JSONB,
+    new_value JSONB
+);
+
+-- The history of a URL is read newest first
+CREATE INDEX IF NOT EXISTS idx_url_audit_log_url ON url_audit_log(namespace, short_code, recorded_at DESC, id DESC);
+
+COMMENT ON TABLE url_audit_log IS 'Change history of short URLs';
+COMMENT ON COLUMN url_audit_log.old_value IS 'URL before the change, NULL when it was created';
+COMMENT ON COLUMN url_audit_log.new_value IS 'URL after the change, NULL when it was deleted';
@@ -0,0 +1,4 @@
+-- Title of the destination page, NULL until fetched or when the page has none
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS title TEXT;
+
+COMMENT ON COLUMN urls.title IS 'og:title or <title> of the destination page, fetched after creation';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_urls_created_date;
@@ -0,0 +1,5 @@
+-- Daily stats count the URLs created on each UTC day. created_at has no NOT NULL
+-- constraint, and URLs without it are never counted.
+CREATE INDEX IF NOT EXISTS idx_urls_created_date
+ON urls(((created_at AT TIME ZONE 'UTC')::date))
+WHERE created_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Optional bcrypt passphrase for protected short URLs
+ALTER TABLE urls ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_url_clicks_short_code_clicked_at;
+
+DROP TABLE IF EXISTS url_clicks;
@@ -0,0 +1,8 @@
+-- Individual click records backing the time-series analytics
+CREATE TABLE IF NOT EXISTS url_clicks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_code TEXT NOT NULL REFERENCES urls(short_code) ON DELETE CASCADE,
+    clicked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_short_code_clicked_at ON url_clicks(short_code, clicked_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_urls_last_checked_at;
+
+ALTER TABLE urls DROP COLUMN last_checked_at;
+ALTER TABLE urls DROP COLUMN health_status;
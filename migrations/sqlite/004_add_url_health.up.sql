@@ -0,0 +1,5 @@
+-- Destination health maintained by the background health checker
+ALTER TABLE urls ADD COLUMN health_status TEXT NOT NULL DEFAULT 'unknown';
+ALTER TABLE urls ADD COLUMN last_checked_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_urls_last_checked_at ON urls(last_checked_at ASC);
@@ -0,0 +1,4 @@
+-- Normalised Referer (host and path) of each click; earlier clicks count as direct traffic
+ALTER TABLE url_clicks ADD COLUMN referer TEXT NOT NULL DEFAULT '(direct)';
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_short_code_referer ON url_clicks(short_code, referer);
@@ -0,0 +1,4 @@
+-- Browser, operating system and device type parsed from the User-Agent of each click
+ALTER TABLE url_clicks ADD COLUMN ua_browser TEXT NOT NULL DEFAULT 'unknown';
+ALTER TABLE url_clicks ADD COLUMN ua_os TEXT NOT NULL DEFAULT 'unknown';
+ALTER TABLE url_clicks ADD COLUMN ua_device_type TEXT NOT NULL DEFAULT 'unknown';
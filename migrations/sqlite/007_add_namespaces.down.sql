@@ -0,0 +1,58 @@
+-- Restores globally unique short codes; fails if a short code is in use in several namespaces
+ALTER TABLE url_clicks RENAME TO url_clicks_old;
+ALTER TABLE urls RENAME TO urls_old;
+DROP TRIGGER IF EXISTS update_urls_updated_at;
+
+CREATE TABLE urls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_code TEXT UNIQUE NOT NULL,
+    original_url TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    clicks INTEGER DEFAULT 0,
+    password_hash TEXT NOT NULL DEFAULT '',
+    health_status TEXT NOT NULL DEFAULT 'unknown',
+    last_checked_at DATETIME,
+
+    CHECK (length(short_code) >= 3),
+    CHECK (length(original_url) > 0),
+    CHECK (clicks >= 0)
+);
+
+CREATE TABLE url_clicks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    short_code TEXT NOT NULL REFERENCES urls(short_code) ON DELETE CASCADE,
+    clicked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    referer TEXT NOT NULL DEFAULT '(direct)',
+    ua_browser TEXT NOT NULL DEFAULT 'unknown',
+    ua_os TEXT NOT NULL DEFAULT 'unknown',
+    ua_device_type TEXT NOT NULL DEFAULT 'unknown'
+);
+
+INSERT INTO urls (id, short_code, original_url, created_at, updated_at, clicks, password_hash, health_status, last_checked_at)
+SELECT id, short_code, original_url, created_at, updated_at, clicks, password_hash, health_status, last_checked_at
+FROM urls_old;
+
+INSERT INTO url_clicks (id, short_code, clicked_at, referer, ua_browser, ua_os, ua_device_type)
+SELECT id, short_code, clicked_at, referer, ua_browser, ua_os, ua_device_type
+FROM url_clicks_old;
+
+DROP TABLE url_clicks_old;
+DROP TABLE urls_old;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_short_code ON urls(short_code);
+CREATE INDEX IF NOT EXISTS idx_urls_created_at ON urls(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_urls_popular ON urls(clicks DESC, created_at DESC) WHERE clicks > 0;
+CREATE INDEX IF NOT EXISTS idx_urls_last_checked_at ON urls(last_checked_at ASC);
+CREATE INDEX IF NOT EXISTS idx_url_clicks_short_code_clicked_at ON url_clicks(short_code, clicked_at);
+CREATE INDEX IF NOT EXISTS idx_url_clicks_short_code_referer ON url_clicks(short_code, referer);
+
+CREATE TRIGGER IF NOT EXISTS update_urls_updated_at
+    AFTER UPDATE ON urls
+    FOR EACH ROW
+    WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE urls SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+DROP TABLE IF EXISTS namespaces;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_url_clicks_variant_id;
+
+ALTER TABLE url_clicks DROP COLUMN variant_id;
+
+DROP TABLE IF EXISTS url_variants;
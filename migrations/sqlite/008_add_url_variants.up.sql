@@ -0,0 +1,16 @@
+-- Alternative destinations of a short URL for A/B testing, chosen at random in proportion to weight
+CREATE TABLE IF NOT EXISTS url_variants (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    url_id INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    original_url TEXT NOT NULL,
+    weight INTEGER NOT NULL CHECK (weight > 0),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_variants_url_id ON url_variants(url_id);
+
+-- Variant each click was sent to; NULL for URLs without variants. SQLite cannot drop a
+-- column that takes part in a foreign key, so this one is left unconstrained.
+ALTER TABLE url_clicks ADD COLUMN variant_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks_variant_id ON url_clicks(variant_id);
@@ -0,0 +1,4 @@
+ALTER TABLE url_clicks DROP COLUMN target_url;
+
+ALTER TABLE urls DROP COLUMN pool;
+ALTER TABLE urls DROP COLUMN pool_enabled;
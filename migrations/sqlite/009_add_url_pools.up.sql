@@ -0,0 +1,6 @@
+-- Load balanced short URLs redirect to the targets of their pool in weighted round-robin order
+ALTER TABLE urls ADD COLUMN pool_enabled BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN pool TEXT;
+
+-- Pool target each click was sent to, empty for URLs without a pool
+ALTER TABLE url_clicks ADD COLUMN target_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Signed URLs carry their expiry in an HMAC-signed token instead of the database
+ALTER TABLE urls ADD COLUMN signed BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Country specific destinations that take precedence over the other redirect targets
+ALTER TABLE urls ADD COLUMN geo_routes TEXT;
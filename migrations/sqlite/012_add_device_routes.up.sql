@@ -0,0 +1,2 @@
+-- Device specific destinations, consulted after the geo routes
+ALTER TABLE urls ADD COLUMN device_routes TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE urls DROP COLUMN tags;
+ALTER TABLE urls DROP COLUMN expires_at;
+ALTER TABLE urls DROP COLUMN redirect_type;
@@ -0,0 +1,4 @@
+-- Per URL redirect status, expiry and tags, all editable through PATCH /shorten/{shortCode}
+ALTER TABLE urls ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN expires_at DATETIME;
+ALTER TABLE urls ADD COLUMN tags TEXT;
@@ -0,0 +1,2 @@
+-- Seconds an interstitial countdown page is shown before redirecting
+ALTER TABLE urls ADD COLUMN delay_seconds INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE url_clicks DROP COLUMN redirect_duration_ms;
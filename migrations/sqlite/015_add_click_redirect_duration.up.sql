@@ -0,0 +1,2 @@
+-- Time the redirect handler took before recording the click, NULL for clicks recorded earlier
+ALTER TABLE url_clicks ADD COLUMN redirect_duration_ms REAL;
@@ -0,0 +1,2 @@
+-- Clicks from visitors not already seen within the deduplication window
+ALTER TABLE urls ADD COLUMN unique_clicks INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Free text label shown to operators, NULL when the URL has none
+ALTER TABLE urls ADD COLUMN description TEXT;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS funnel_clicks;
+DROP TABLE IF EXISTS funnel_steps;
+DROP TABLE IF EXISTS funnels;
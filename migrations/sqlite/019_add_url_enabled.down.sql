@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_urls_disabled;
+ALTER TABLE urls DROP COLUMN enabled;
@@ -0,0 +1,5 @@
+-- Disabled URLs are kept but answer redirects with 410 Gone until enabled again
+ALTER TABLE urls ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT 1;
+
+-- Partial index, as few URLs are ever disabled
+CREATE INDEX IF NOT EXISTS idx_urls_disabled ON urls(namespace, short_code) WHERE enabled = 0;
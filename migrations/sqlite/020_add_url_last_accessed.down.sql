@@ -0,0 +1 @@
+ALTER TABLE urls DROP COLUMN last_accessed_at;
@@ -0,0 +1,2 @@
+-- Time of the latest click, NULL until the URL is first clicked
+ALTER TABLE urls ADD COLUMN last_accessed_at DATETIME;
@@ -0,0 +1 @@
+ALTER TABLE urls DROP COLUMN is_custom_alias;
@@ -0,0 +1,3 @@
+-- Whether the short code was chosen by the creator rather than generated. URLs created
+-- before this column existed count as generated, as nothing recorded the difference.
+ALTER TABLE urls ADD COLUMN is_custom_alias BOOLEAN NOT NULL DEFAULT 0;
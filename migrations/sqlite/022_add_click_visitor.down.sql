@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_url_clicks_namespace_short_code_clicked_at_id;
+
+ALTER TABLE url_clicks DROP COLUMN country;
+ALTER TABLE url_clicks DROP COLUMN user_agent;
+ALTER TABLE url_clicks DROP COLUMN ip_address;
@@ -0,0 +1,8 @@
+-- Visitor of each click, kept for raw click exports. Clicks recorded earlier have none.
+ALTER TABLE url_clicks ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+ALTER TABLE url_clicks ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE url_clicks ADD COLUMN country TEXT NOT NULL DEFAULT '';
+
+-- Exports page through the clicks of a URL by clicked_at and then id
+CREATE INDEX IF NOT EXISTS idx_url_clicks_namespace_short_code_clicked_at_id
+ON url_clicks(namespace, short_code, clicked_at, id);
@@ -0,0 +1,2 @@
+ALTER TABLE urls DROP COLUMN goal_reached;
+ALTER TABLE urls DROP COLUMN click_goal;
@@ -0,0 +1,4 @@
+-- Number of clicks after which a URL has reached its goal, NULL for URLs without one.
+-- goal_reached is set once, by the click that reaches the goal.
+ALTER TABLE urls ADD COLUMN click_goal INTEGER CHECK (click_goal > 0);
+ALTER TABLE urls ADD COLUMN goal_reached BOOLEAN NOT NULL DEFAULT 0;
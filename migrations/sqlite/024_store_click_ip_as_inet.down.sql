@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_url_clicks_ip_address;
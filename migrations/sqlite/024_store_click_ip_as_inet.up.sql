@@ -0,0 +1,7 @@
+-- SQLite has no inet type, so addresses stay text. IPv4-mapped IPv6 addresses recorded so
+-- far are stored as plain IPv4, as new clicks are.
+UPDATE url_clicks SET ip_address = substr(ip_address, 8)
+WHERE lower(ip_address) LIKE '::ffff:%.%.%.%';
+
+-- Top IPs group every click by address
+CREATE INDEX IF NOT EXISTS idx_url_clicks_ip_address ON url_clicks(ip_address);
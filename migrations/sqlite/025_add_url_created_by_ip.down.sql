@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_urls_created_by_ip;
+
+ALTER TABLE urls DROP COLUMN created_by_ip;
@@ -0,0 +1,6 @@
+-- Address of the client that created the URL, NULL when unknown, for abuse investigations.
+-- SQLite has no inet type, so addresses are text.
+ALTER TABLE urls ADD COLUMN created_by_ip TEXT;
+
+-- Admins list the URLs created from one address
+CREATE INDEX IF NOT EXISTS idx_urls_created_by_ip ON urls(created_by_ip);
@@ -0,0 +1,2 @@
+-- Title of the destination page, NULL until fetched or when the page has none
+ALTER TABLE urls ADD COLUMN title TEXT;
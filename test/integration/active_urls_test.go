@@ -0,0 +1,33 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+func TestCountActive_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, alias := range []string{"permanent", "later", "expired", "paused"} {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias}, testBaseURL)
+		require.NoError(t, err)
+	}
+	_, err := env.DB.Exec("UPDATE urls SET expires_at = $1 WHERE short_code = 'later'", now.Add(time.Hour))
+	require.NoError(t, err)
+	_, err = env.DB.Exec("UPDATE urls SET expires_at = $1 WHERE short_code = 'expired'", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, env.Repo.SetEnabled(ctx, domain.DefaultNamespace, "paused", false))
+
+	count, err := env.Repo.CountActive(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
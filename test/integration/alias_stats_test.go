@@ -0,0 +1,46 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/application"
+)
+
+func TestAliasStats_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	for i := range 4 {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: fmt.Sprintf("https://example.com/generated/%d", i)}, testBaseURL)
+		require.NoError(t, err)
+	}
+	for _, alias := range []string{"abc", "promo", "summersale", "team"} {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias}, testBaseURL)
+		require.NoError(t, err)
+	}
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/team", CustomAlias: "team", Namespace: "marketing"}, testBaseURL)
+	require.NoError(t, err)
+
+	// URLs created before the column existed count as generated whatever their length
+	for _, shortCode := range []string{"legacy1", "legacy22"} {
+		_, err := env.DB.Exec("INSERT INTO urls (short_code, original_url) VALUES ($1, $2)", shortCode, "https://example.com/"+shortCode)
+		require.NoError(t, err)
+	}
+
+	stats, err := env.Service.GetAliasStats(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(6), stats.Generated.Count)
+	assert.Equal(t, map[int]int64{6: 4, 7: 1, 8: 1}, stats.Generated.Distribution)
+	assert.InDelta(t, 39.0/6, stats.Generated.AvgLength, 0.001)
+
+	assert.Equal(t, int64(5), stats.Custom.Count)
+	assert.Equal(t, 3, stats.Custom.MinLength)
+	assert.Equal(t, 10, stats.Custom.MaxLength)
+	assert.InDelta(t, 26.0/5, stats.Custom.AvgLength, 0.001)
+}
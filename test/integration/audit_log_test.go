@@ -0,0 +1,87 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	pgxRepo "github.com/sp3dr4/dove/internal/infrastructure/pgx"
+	postgresRepo "github.com/sp3dr4/dove/internal/infrastructure/postgres"
+	redisCache "github.com/sp3dr4/dove/internal/infrastructure/redis"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/logging"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+)
+
+func TestURLService_AuditLog_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cache := redisCache.NewRedisCache(env.RedisClient, testKeyPrefix, time.Hour, 0, logger)
+
+	pgxPool, err := pgxpool.New(context.Background(), env.ConnStr)
+	require.NoError(t, err)
+	pgxURLs := pgxRepo.NewURLRepository(pgxPool, logger, false)
+	t.Cleanup(func() { _ = pgxURLs.Close() })
+
+	tests := []struct {
+		name  string
+		repo  domain.URLRepository
+		audit *postgresRepo.AuditRepository
+	}{
+		{name: "pq", repo: env.Repo, audit: postgresRepo.NewAuditRepository(env.DB)},
+		{name: "pgx", repo: pgxURLs, audit: postgresRepo.NewAuditRepository(sqlx.NewDb(pgxURLs.DB(), "postgres"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetupTestEnvironment(t)
+			service := application.NewURLService(tt.repo, cache, time.Hour, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{AuditLog: tt.audit}, logger)
+
+			ctx := logging.WithRequestID(context.Background(), "req-"+tt.name)
+			ctx = logging.WithClientIP(ctx, "198.51.100.4")
+
+			_, err := service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/v1", CustomAlias: "audited"}, "http://localhost:8080")
+			require.NoError(t, err)
+			updated := "https://example.com/v2"
+			_, err = service.PatchURL(ctx, domain.DefaultNamespace, "audited", application.PatchURLRequest{OriginalURL: &updated}, "http://localhost:8080")
+			require.NoError(t, err)
+			_, err = service.BulkDeleteURLs(ctx, domain.DefaultNamespace, application.BulkDeleteRequest{ShortCodes: []string{"audited"}})
+			require.NoError(t, err)
+
+			entries, err := service.GetAuditLog(ctx, domain.DefaultNamespace, "audited", application.DefaultAuditLogLimit)
+			require.NoError(t, err)
+			require.Len(t, entries, 3)
+
+			originalURL := func(value json.RawMessage) string {
+				var url domain.URL
+				require.NoError(t, json.Unmarshal(value, &url))
+				return url.OriginalURL
+			}
+			assert.Equal(t, audit.OperationDelete, entries[0].Operation)
+			assert.Equal(t, "https://example.com/v2", originalURL(entries[0].OldValue))
+			assert.Nil(t, entries[0].NewValue)
+			assert.Equal(t, audit.OperationUpdate, entries[1].Operation)
+			assert.Equal(t, "https://example.com/v1", originalURL(entries[1].OldValue))
+			assert.Equal(t, "https://example.com/v2", originalURL(entries[1].NewValue))
+			assert.Equal(t, audit.OperationCreate, entries[2].Operation)
+			assert.Nil(t, entries[2].OldValue)
+			for _, entry := range entries {
+				assert.Equal(t, "198.51.100.4", entry.ActorIP)
+				assert.Equal(t, "req-"+tt.name, entry.RequestID)
+			}
+
+			limited, err := service.GetAuditLog(ctx, domain.DefaultNamespace, "audited", 2)
+			require.NoError(t, err)
+			assert.Len(t, limited, 2)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	pgxRepo "github.com/sp3dr4/dove/internal/infrastructure/pgx"
+	redisCache "github.com/sp3dr4/dove/internal/infrastructure/redis"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+)
+
+func TestURLService_BulkDeleteURLs_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cache := redisCache.NewRedisCache(env.RedisClient, testKeyPrefix, time.Hour, 0, logger)
+
+	pgxPool, err := pgxpool.New(ctx, env.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(pgxPool.Close)
+	repos := map[string]domain.URLRepository{
+		"pq":  env.Repo,
+		"pgx": pgxRepo.NewURLRepository(pgxPool, logger, false),
+	}
+
+	for driver, repo := range repos {
+		t.Run(driver, func(t *testing.T) {
+			service := application.NewURLService(repo, cache, time.Hour, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+
+			existing := make([]string, 5)
+			for i := range existing {
+				existing[i] = fmt.Sprintf("%sbulk%d", driver, i)
+				_, err := service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/" + existing[i], CustomAlias: existing[i]}, "http://localhost:8080")
+				require.NoError(t, err)
+				require.NoError(t, repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: existing[i], ClickedAt: time.Now(), Referer: domain.DirectReferer}))
+			}
+			kept := driver + "kept"
+			_, err := service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/kept", CustomAlias: kept}, "http://localhost:8080")
+			require.NoError(t, err)
+
+			missing := []string{driver + "missing0", driver + "missing1"}
+			result, err := service.BulkDeleteURLs(ctx, domain.DefaultNamespace, application.BulkDeleteRequest{
+				ShortCodes: []string{existing[0], missing[0], existing[1], existing[2], missing[1], existing[3], existing[4]},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, len(existing), result.Deleted)
+			assert.Equal(t, missing, result.NotFound)
+
+			for _, shortCode := range existing {
+				_, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+				assert.ErrorIs(t, err, domain.ErrURLNotFound, shortCode)
+
+				cached, err := cache.Get(ctx, domain.DefaultNamespace, shortCode)
+				require.NoError(t, err)
+				assert.Nil(t, cached, shortCode)
+			}
+
+			var clicks int
+			require.NoError(t, env.DB.GetContext(ctx, &clicks, `SELECT COUNT(*) FROM url_clicks WHERE short_code LIKE $1`, driver+"bulk%"))
+			assert.Zero(t, clicks, "recorded clicks are deleted with their URL")
+
+			exists, err := repo.Exists(ctx, domain.DefaultNamespace, kept)
+			require.NoError(t, err)
+			assert.True(t, exists)
+		})
+	}
+}
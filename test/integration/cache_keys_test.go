@@ -0,0 +1,47 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/application"
+	redisCache "github.com/sp3dr4/dove/internal/infrastructure/redis"
+)
+
+func TestRedisCache_Keys_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	cache := redisCache.NewRedisCache(env.RedisClient, testKeyPrefix, 0, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for i := range 30 {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: fmt.Sprintf("https://example.com/%d", i), CustomAlias: fmt.Sprintf("scan%d", i)}, testBaseURL)
+		require.NoError(t, err)
+	}
+	created, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/keys", CustomAlias: "scanned"}, testBaseURL)
+	require.NoError(t, err)
+
+	// Small pages make SCAN take several calls
+	var keys []string
+	var cursor uint64
+	for {
+		page, next, err := cache.Keys(ctx, "url:default:*", cursor, 5)
+		require.NoError(t, err)
+		keys = append(keys, page...)
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	assert.Contains(t, keys, "url:default:"+created.ShortCode)
+	assert.Contains(t, keys, "url:default:scan0")
+	for _, key := range keys {
+		assert.Regexp(t, `^url:default:`, key)
+	}
+}
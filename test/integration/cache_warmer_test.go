@@ -0,0 +1,46 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx/fxtest"
+
+	"github.com/sp3dr4/dove/config"
+	"github.com/sp3dr4/dove/internal/application"
+	fxProviders "github.com/sp3dr4/dove/internal/fx"
+)
+
+func TestCacheWarmer_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	for i, alias := range []string{"cold", "warm", "hot"} {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias}, testBaseURL)
+		require.NoError(t, err)
+		_, err = env.DB.Exec("UPDATE urls SET clicks = $1 WHERE short_code = $2", i*100, alias)
+		require.NoError(t, err)
+	}
+	// Creating a URL caches it, start from the empty cache of a restart
+	cleanRedisCache(t, env.RedisClient)
+
+	lc := fxtest.NewLifecycle(t)
+	fxProviders.RegisterCacheWarmerHooks(lc, fxProviders.CacheWarmerParams{
+		Service: env.Service,
+		Config:  &config.Config{Cache: config.CacheConfig{Enabled: true, WarmEnabled: true, WarmCount: 2}},
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	lc.RequireStart()
+	t.Cleanup(lc.RequireStop)
+
+	for alias, cached := range map[string]bool{"hot": true, "warm": true, "cold": false} {
+		exists, err := env.RedisClient.Exists(ctx, fmt.Sprintf("%s:url:default:%s", testKeyPrefix, alias)).Result()
+		require.NoError(t, err)
+		assert.Equal(t, cached, exists == 1, alias)
+	}
+}
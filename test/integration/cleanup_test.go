@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/config"
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	redisCache "github.com/sp3dr4/dove/internal/infrastructure/redis"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+	"github.com/sp3dr4/dove/internal/pkg/scheduler"
+)
+
+func TestCleanupScheduler_DeletesExpiredURLs_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cache := redisCache.NewRedisCache(env.RedisClient, testKeyPrefix, time.Hour, 0, logger)
+
+	past := time.Now().Add(-time.Hour)
+	expired := make([]string, 10)
+	for i := range expired {
+		expired[i] = fmt.Sprintf("expired%d", i)
+		url, err := domain.NewURL(expired[i], "https://example.com/"+expired[i])
+		require.NoError(t, err)
+		url.ExpiresAt = &past
+		created, err := env.Repo.Create(ctx, url)
+		require.NoError(t, err)
+		require.NoError(t, cache.Set(ctx, created, time.Hour))
+		require.NoError(t, env.Repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: expired[i], ClickedAt: past, Referer: domain.DirectReferer}))
+	}
+	active, err := domain.NewURL("active", "https://example.com/active")
+	require.NoError(t, err)
+	_, err = env.Repo.Create(ctx, active)
+	require.NoError(t, err)
+
+	registry, err := metrics.NewPrometheusRegistry(config.MetricsConfig{Enabled: true, Namespace: "dove"})
+	require.NoError(t, err)
+	service := application.NewURLService(env.Repo, cache, time.Hour, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{Metrics: registry}, logger)
+
+	deleted, err := scheduler.NewCleanupScheduler(env.Repo, service, registry, time.Minute, 0, logger).RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(expired), deleted)
+
+	for _, shortCode := range expired {
+		_, err := env.Repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+		assert.ErrorIs(t, err, domain.ErrURLNotFound, shortCode)
+
+		cached, err := cache.Get(ctx, domain.DefaultNamespace, shortCode)
+		require.NoError(t, err)
+		assert.Nil(t, cached, shortCode)
+	}
+
+	var clicks int
+	require.NoError(t, env.DB.GetContext(ctx, &clicks, `SELECT COUNT(*) FROM url_clicks`))
+	assert.Zero(t, clicks)
+
+	exists, err := env.Repo.Exists(ctx, domain.DefaultNamespace, "active")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	families, err := registry.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == "dove_urls_expired_total" {
+			assert.Equal(t, float64(len(expired)), family.GetMetric()[0].GetCounter().GetValue())
+			return
+		}
+	}
+	t.Fatal("dove_urls_expired_total not gathered")
+}
@@ -0,0 +1,59 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	redisCache "github.com/sp3dr4/dove/internal/infrastructure/redis"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
+)
+
+func TestClickGoal_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	broker := pubsub.NewBroker(0)
+	service := application.NewURLService(env.Repo, redisCache.NewRedisCache(env.RedisClient, testKeyPrefix, 0, 0, logger), 10*time.Minute, audit.NewAuditLogger(io.Discard), broker, application.URLServiceDeps{}, logger)
+	goals, unsubscribe := broker.SubscribeGoals()
+	defer unsubscribe()
+
+	goal := 3
+	_, err := service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/campaign", CustomAlias: "campaign", ClickGoal: &goal}, testBaseURL)
+	require.NoError(t, err)
+
+	for click := 1; click <= 4; click++ {
+		_, err := service.IncrementClicks(ctx, domain.DefaultNamespace, "campaign", application.ClickDetails{})
+		require.NoError(t, err)
+
+		if click == goal {
+			require.Len(t, goals, 1, "the goal is announced on click %d", click)
+			event := <-goals
+			assert.Equal(t, "campaign", event.ShortCode)
+			assert.Equal(t, 3, event.Clicks)
+		} else {
+			assert.Empty(t, goals, "click %d", click)
+		}
+	}
+
+	var stored struct {
+		ClickGoal   int  `db:"click_goal"`
+		GoalReached bool `db:"goal_reached"`
+	}
+	require.NoError(t, env.DB.Get(&stored, "SELECT click_goal, goal_reached FROM urls WHERE short_code = 'campaign'"))
+	assert.Equal(t, 3, stored.ClickGoal)
+	assert.True(t, stored.GoalReached)
+
+	url, err := service.GetURL(ctx, domain.DefaultNamespace, "campaign")
+	require.NoError(t, err)
+	assert.True(t, url.GoalReached, "the cached copy was refreshed")
+}
@@ -0,0 +1,64 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/infrastructure/clickbuffer"
+	redisCache "github.com/sp3dr4/dove/internal/infrastructure/redis"
+)
+
+func TestClickBuffer_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cache := redisCache.NewRedisCache(env.RedisClient, testKeyPrefix, time.Hour, 0, logger)
+	buffer := clickbuffer.NewBuffer(env.Repo, cache, time.Minute, logger)
+
+	for _, req := range []application.CreateURLRequest{
+		{URL: "https://example.com/buffered", CustomAlias: "buffered"},
+		{URL: "https://example.com/team", CustomAlias: "buffered", Namespace: "team"},
+	} {
+		_, err := env.Service.CreateShortURL(ctx, req, testBaseURL)
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				buffer.Add(domain.DefaultNamespace, "buffered", i == 0)
+			}
+		}()
+	}
+	wg.Wait()
+	buffer.Add("team", "buffered", true)
+	buffer.Add(domain.DefaultNamespace, "deleted", true)
+
+	flushed, err := buffer.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1002), flushed)
+
+	for namespace, want := range map[string][2]int{domain.DefaultNamespace: {1000, 50}, "team": {1, 1}} {
+		url, err := env.Repo.FindByNamespaceAndCode(ctx, namespace, "buffered")
+		require.NoError(t, err)
+		assert.Equal(t, want[0], url.Clicks, namespace)
+		assert.Equal(t, want[1], url.UniqueClicks, namespace)
+		assert.NotNil(t, url.LastAccessedAt, namespace)
+
+		cached, err := cache.Get(ctx, namespace, "buffered")
+		require.NoError(t, err)
+		assert.Nil(t, cached, "flushed URLs are read afresh")
+	}
+}
@@ -0,0 +1,147 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/config"
+	httpAdapter "github.com/sp3dr4/dove/internal/adapters/http"
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	redisCache "github.com/sp3dr4/dove/internal/infrastructure/redis"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+	"github.com/sp3dr4/dove/internal/pkg/scheduler"
+)
+
+// seedActivity creates a short URL and backdates its creation and latest click, leaving
+// last_accessed_at NULL when lastAccessed is nil
+func seedActivity(t *testing.T, env *TestEnvironment, shortCode string, created time.Time, lastAccessed *time.Time) {
+	t.Helper()
+
+	_, err := env.Service.CreateShortURL(context.Background(), application.CreateURLRequest{URL: "https://example.com/" + shortCode, CustomAlias: shortCode}, testBaseURL)
+	require.NoError(t, err)
+	_, err = env.DB.Exec("UPDATE urls SET created_at = $1, last_accessed_at = $2 WHERE short_code = $3", created, lastAccessed, shortCode)
+	require.NoError(t, err)
+}
+
+func TestFindNotAccessedSince_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	now := time.Now()
+	daysAgo := func(days int) *time.Time {
+		at := now.AddDate(0, 0, -days)
+		return &at
+	}
+
+	seedActivity(t, env, "never", *daysAgo(60), nil)
+	seedActivity(t, env, "stale", *daysAgo(200), daysAgo(90))
+	seedActivity(t, env, "lapsed", *daysAgo(200), daysAgo(31))
+	seedActivity(t, env, "recent", *daysAgo(200), daysAgo(29))
+	seedActivity(t, env, "fresh", *daysAgo(10), nil)
+	seedActivity(t, env, "paused", *daysAgo(200), daysAgo(90))
+	_, err := env.Service.SetURLEnabled(ctx, domain.DefaultNamespace, "paused", false)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		since *time.Time
+		limit int
+		want  []string
+	}{
+		{name: "30 days", since: daysAgo(30), limit: 10, want: []string{"stale", "never", "lapsed"}},
+		{name: "limited", since: daysAgo(30), limit: 2, want: []string{"stale", "never"}},
+		{name: "100 days", since: daysAgo(100), limit: 10, want: []string{}},
+		{name: "a week", since: daysAgo(7), limit: 10, want: []string{"stale", "never", "lapsed", "recent", "fresh"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := env.Repo.FindNotAccessedSince(ctx, *tt.since, tt.limit)
+			require.NoError(t, err)
+
+			shortCodes := []string{}
+			for _, url := range urls {
+				shortCodes = append(shortCodes, url.ShortCode)
+			}
+			assert.Equal(t, tt.want, shortCodes)
+		})
+	}
+
+	t.Run("a click makes a URL warm again", func(t *testing.T) {
+		clicked, err := env.Repo.IncrementClicks(ctx, domain.DefaultNamespace, "stale", false)
+		require.NoError(t, err)
+		require.NotNil(t, clicked.LastAccessedAt)
+		assert.WithinDuration(t, time.Now(), *clicked.LastAccessedAt, time.Minute)
+
+		urls, err := env.Repo.FindNotAccessedSince(ctx, *daysAgo(30), 10)
+		require.NoError(t, err)
+		for _, url := range urls {
+			assert.NotEqual(t, "stale", url.ShortCode)
+		}
+	})
+}
+
+func TestColdURLs_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Now()
+	lastClick := now.AddDate(0, 0, -45)
+
+	seedActivity(t, env, "cold", now.AddDate(0, 0, -90), &lastClick)
+	seedActivity(t, env, "warm", now.AddDate(0, 0, -90), &now)
+
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+	server := httptest.NewServer(httpAdapter.NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/admin/urls/cold?days=30&limit=100", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var urls []application.URLInfoResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&urls))
+	require.Len(t, urls, 1)
+	assert.Equal(t, "cold", urls[0].ShortCode)
+	require.NotNil(t, urls[0].LastAccessedAt)
+	assert.WithinDuration(t, lastClick, *urls[0].LastAccessedAt, time.Second)
+
+	t.Run("the cleanup disables cold URLs", func(t *testing.T) {
+		cache := redisCache.NewRedisCache(env.RedisClient, testKeyPrefix, time.Hour, 0, logger)
+		cold, err := env.Repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "cold")
+		require.NoError(t, err)
+		require.NoError(t, cache.Set(ctx, cold, time.Hour))
+
+		cleanup := scheduler.NewCleanupScheduler(env.Repo, env.Service, metrics.NewNoOpRegistry(), time.Minute, 30*24*time.Hour, logger)
+		disabled, err := cleanup.DisableColdOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, disabled)
+
+		for shortCode, enabled := range map[string]bool{"cold": false, "warm": true} {
+			url, err := env.Repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, shortCode)
+			require.NoError(t, err)
+			assert.Equal(t, enabled, url.Enabled, shortCode)
+		}
+		cached, err := cache.Get(ctx, domain.DefaultNamespace, "cold")
+		require.NoError(t, err)
+		assert.Nil(t, cached)
+
+		disabled, err = cleanup.DisableColdOnce(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, disabled, "disabled URLs are no longer cold")
+	})
+}
@@ -0,0 +1,64 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	pgxRepo "github.com/sp3dr4/dove/internal/infrastructure/pgx"
+)
+
+func TestURLRepository_CreatedByIP_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	pgxPool, err := pgxpool.New(ctx, env.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(pgxPool.Close)
+	repos := map[string]domain.URLRepository{
+		"pq":  env.Repo,
+		"pgx": pgxRepo.NewURLRepository(pgxPool, logger, false),
+	}
+
+	for driver, repo := range repos {
+		t.Run(driver, func(t *testing.T) {
+			creators := map[string]string{driver + "v4": "192.168.1.1", driver + "v6": "2001:db8::1", driver + "anon": ""}
+			for shortCode, ip := range creators {
+				url, err := domain.NewURL(shortCode, "https://example.com/"+shortCode)
+				require.NoError(t, err)
+				url.CreatedByIP = ip
+				created, err := repo.Create(ctx, url)
+				require.NoError(t, err)
+				assert.Equal(t, ip, created.CreatedByIP, "inet columns read back without a netmask")
+
+				found, err := repo.FindByShortCode(ctx, shortCode)
+				require.NoError(t, err)
+				assert.Equal(t, ip, found.CreatedByIP)
+			}
+
+			var stored *string
+			require.NoError(t, env.DB.GetContext(ctx, &stored, `SELECT host(created_by_ip) FROM urls WHERE short_code = $1`, driver+"anon"))
+			assert.Nil(t, stored, "an unknown creator is stored as NULL")
+
+			for shortCode, ip := range creators {
+				if ip == "" {
+					continue
+				}
+				urls, err := repo.ListByCreatorIP(ctx, ip, 0, 10)
+				require.NoError(t, err)
+				var shortCodes []string
+				for _, url := range urls {
+					shortCodes = append(shortCodes, url.ShortCode)
+				}
+				assert.Contains(t, shortCodes, shortCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+)
+
+func TestDailyStats_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	day := func(date string, hour int) time.Time {
+		parsed, err := time.Parse(time.DateOnly, date)
+		require.NoError(t, err)
+		return parsed.Add(time.Duration(hour) * time.Hour)
+	}
+
+	// 23:30 in New York on January 1st is already January 2nd in UTC
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	created := map[string]time.Time{
+		"first":  day("2024-01-01", 0),
+		"second": day("2024-01-01", 23),
+		"third":  time.Date(2024, 1, 1, 23, 30, 0, 0, newYork),
+		"fourth": day("2024-01-05", 12),
+		"before": day("2023-12-31", 23),
+	}
+	for alias, at := range created {
+		seedActivity(t, env, alias, at, nil)
+	}
+
+	clicks := []struct {
+		shortCode string
+		at        time.Time
+	}{
+		{"first", day("2024-01-01", 10)},
+		{"first", day("2024-01-02", 10)},
+		{"second", day("2024-01-02", 23)},
+		{"fourth", day("2024-01-06", 1)},
+		{"fourth", day("2024-01-08", 0)},
+	}
+	for _, click := range clicks {
+		require.NoError(t, env.Repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: click.shortCode, ClickedAt: click.at, Referer: domain.DirectReferer}))
+	}
+
+	t.Run("count by created date", func(t *testing.T) {
+		for date, expected := range map[string]int{"2024-01-01": 2, "2024-01-02": 1, "2024-01-05": 1, "2024-01-03": 0, "2023-12-31": 1} {
+			count, err := env.Repo.CountByCreatedDate(ctx, day(date, 0))
+			require.NoError(t, err)
+			assert.Equal(t, expected, count, date)
+		}
+	})
+
+	t.Run("daily activity", func(t *testing.T) {
+		activity, err := env.Repo.DailyActivity(ctx, day("2024-01-01", 0), day("2024-01-07", 0))
+		require.NoError(t, err)
+		require.Len(t, activity, 4)
+		expected := []domain.DailyActivity{
+			{Day: day("2024-01-01", 0), URLsCreated: 2, Clicks: 1},
+			{Day: day("2024-01-02", 0), URLsCreated: 1, Clicks: 2},
+			{Day: day("2024-01-05", 0), URLsCreated: 1, Clicks: 0},
+			{Day: day("2024-01-06", 0), URLsCreated: 0, Clicks: 1},
+		}
+		for i := range expected {
+			assert.True(t, expected[i].Day.Equal(activity[i].Day), "day %d is %s", i, activity[i].Day)
+			assert.Equal(t, expected[i].URLsCreated, activity[i].URLsCreated, expected[i].Day)
+			assert.Equal(t, expected[i].Clicks, activity[i].Clicks, expected[i].Day)
+		}
+	})
+
+	t.Run("daily stats", func(t *testing.T) {
+		counts, err := env.Service.GetDailyStats(ctx, day("2024-01-01", 0), day("2024-01-03", 0))
+		require.NoError(t, err)
+		assert.Equal(t, []application.DailyCount{
+			{Date: "2024-01-01", URLsCreated: 2, Clicks: 1},
+			{Date: "2024-01-02", URLsCreated: 1, Clicks: 2},
+			{Date: "2024-01-03", URLsCreated: 0, Clicks: 0},
+		}, counts)
+	})
+}
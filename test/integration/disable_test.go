@@ -0,0 +1,83 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/config"
+	httpAdapter "github.com/sp3dr4/dove/internal/adapters/http"
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+)
+
+func TestDisableURL_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/suspended", CustomAlias: "suspended"}, testBaseURL)
+	require.NoError(t, err)
+
+	registry, err := metrics.NewPrometheusRegistry(config.MetricsConfig{Enabled: true, Namespace: "dove"})
+	require.NoError(t, err)
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+	server := httptest.NewServer(httpAdapter.NewRouter(handlers, nil, logger, cfg, registry, nil, nil))
+	defer server.Close()
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	do := func(method, path string, admin bool) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		require.NoError(t, err)
+		if admin {
+			req.Header.Set("Authorization", "Bearer s3cret")
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	// The first redirect caches the URL, which disabling has to invalidate
+	require.Equal(t, http.StatusMovedPermanently, do(http.MethodGet, "/suspended", false).StatusCode)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodPatch, "/admin/urls/suspended/disable", true).StatusCode)
+	resp := do(http.MethodGet, "/suspended", false)
+	require.Equal(t, http.StatusGone, resp.StatusCode)
+	var problem httpAdapter.ProblemDetail
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, httpAdapter.ProblemTypeDisabled, problem.Type)
+	assert.Equal(t, http.StatusGone, do(http.MethodHead, "/suspended", false).StatusCode)
+
+	// Disabling again changes nothing and is not counted
+	require.Equal(t, http.StatusNoContent, do(http.MethodPatch, "/admin/urls/suspended/disable", true).StatusCode)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodPatch, "/admin/urls/suspended/enable", true).StatusCode)
+	resp = do(http.MethodGet, "/suspended", false)
+	require.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://example.com/suspended", resp.Header.Get("Location"))
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPatch, "/admin/urls/missing/disable", true).StatusCode)
+
+	families, err := registry.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == "dove_urls_disabled_total" {
+			assert.Equal(t, float64(1), family.GetMetric()[0].GetCounter().GetValue())
+			return
+		}
+	}
+	t.Fatal("dove_urls_disabled_total not gathered")
+}
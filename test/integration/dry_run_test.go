@@ -0,0 +1,34 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/application"
+)
+
+func TestDryRunCreate_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	response, err := env.Service.DryRunCreate(ctx, application.CreateURLRequest{URL: "https://example.com/preview", CustomAlias: "preview"}, testBaseURL)
+	require.NoError(t, err)
+	assert.True(t, response.DryRun)
+	assert.Equal(t, testBaseURL+"/preview", response.ShortURL)
+
+	var rows int
+	require.NoError(t, env.DB.GetContext(ctx, &rows, "SELECT COUNT(*) FROM urls"))
+	assert.Zero(t, rows, "dry runs insert no row")
+
+	keys, err := env.RedisClient.Keys(ctx, testKeyPrefix+":*").Result()
+	require.NoError(t, err)
+	assert.Empty(t, keys, "dry runs cache nothing")
+
+	// The alias is still free to be created for real
+	created, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/preview", CustomAlias: "preview"}, testBaseURL)
+	require.NoError(t, err)
+	assert.False(t, created.DryRun)
+}
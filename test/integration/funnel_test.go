@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/config"
+	httpAdapter "github.com/sp3dr4/dove/internal/adapters/http"
+	"github.com/sp3dr4/dove/internal/application"
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+)
+
+func TestFunnel_TracksSessionsAcrossRequests_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, alias := range []string{"step1", "step2", "step3"} {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias}, testBaseURL)
+		require.NoError(t, err)
+	}
+
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	cfg := &config.Config{Admin: config.AdminConfig{APIKey: "s3cret"}}
+	server := httptest.NewServer(httpAdapter.NewRouter(handlers, nil, logger, cfg, metrics.NewNoOpRegistry(), nil, nil))
+	defer server.Close()
+
+	admin := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	resp := admin(http.MethodPost, "/admin/funnels", `{"name":"Signup","steps":[{"shortCode":"step1"},{"shortCode":"step2"},{"shortCode":"step3"}]}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var funnel domain.Funnel
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&funnel))
+	require.Len(t, funnel.Steps, 3)
+
+	// Each visitor is a browser keeping its own cookies, so the dove_sid cookie set by the
+	// first step identifies it on the next ones
+	visitor := func(t *testing.T, shortCodes ...string) {
+		t.Helper()
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		client := &http.Client{
+			Jar: jar,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		for _, shortCode := range shortCodes {
+			resp, err := client.Get(server.URL + "/" + shortCode)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			require.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+		}
+	}
+	visitor(t, "step1", "step2", "step3")
+	visitor(t, "step1", "step2", "step1", "step3", "step2")
+	visitor(t, "step1", "step2")
+	visitor(t, "step1")
+	visitor(t, "step2", "step3")
+
+	var sessions int
+	require.NoError(t, env.DB.GetContext(ctx, &sessions, `SELECT COUNT(DISTINCT session_id) FROM funnel_clicks WHERE funnel_id = $1`, funnel.ID))
+	assert.Equal(t, 5, sessions, "one session per visitor")
+
+	resp = admin(http.MethodGet, fmt.Sprintf("/admin/funnels/%d/analytics", funnel.ID), "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var analytics application.FunnelAnalyticsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&analytics))
+	assert.Equal(t, "Signup", analytics.Name)
+	assert.Equal(t, []application.FunnelStepAnalytics{
+		{Order: 1, Namespace: domain.DefaultNamespace, ShortCode: "step1", Sessions: 4, CompletionRate: 1},
+		{Order: 2, Namespace: domain.DefaultNamespace, ShortCode: "step2", Sessions: 3, CompletionRate: 0.75},
+		{Order: 3, Namespace: domain.DefaultNamespace, ShortCode: "step3", Sessions: 2, CompletionRate: 0.5},
+	}, analytics.Steps)
+}
@@ -0,0 +1,160 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+
+	"github.com/sp3dr4/dove/config"
+	"github.com/sp3dr4/dove/internal/pkg/metrics"
+)
+
+// mockCollector is an OTLP gRPC metrics service that keeps every request it receives
+type mockCollector struct {
+	collectorpb.UnimplementedMetricsServiceServer
+
+	mu       sync.Mutex
+	requests []*collectorpb.ExportMetricsServiceRequest
+}
+
+func (c *mockCollector) Export(_ context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	return &collectorpb.ExportMetricsServiceResponse{}, nil
+}
+
+// lastMetrics returns the metrics of the most recent export by name
+func (c *mockCollector) lastMetrics(t *testing.T) map[string]*metricspb.Metric {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	require.NotEmpty(t, c.requests)
+
+	byName := make(map[string]*metricspb.Metric)
+	for _, resourceMetrics := range c.requests[len(c.requests)-1].GetResourceMetrics() {
+		for _, scopeMetrics := range resourceMetrics.GetScopeMetrics() {
+			for _, m := range scopeMetrics.GetMetrics() {
+				byName[m.GetName()] = m
+			}
+		}
+	}
+	return byName
+}
+
+func startMockCollector(t *testing.T) (*mockCollector, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	collector := &mockCollector{}
+	server := grpc.NewServer()
+	collectorpb.RegisterMetricsServiceServer(server, collector)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return collector, listener.Addr().String()
+}
+
+func TestOTelRegistry_ExportsToCollector_Integration(t *testing.T) {
+	collector, endpoint := startMockCollector(t)
+
+	provider, err := metrics.NewOTLPMeterProvider(endpoint, "dove", time.Hour)
+	require.NoError(t, err)
+
+	registry, err := metrics.NewOTelRegistry(config.MetricsConfig{
+		Enabled:   true,
+		Namespace: "dove",
+		Subsystem: "urlshortener",
+		Backend:   "otel",
+	}, provider)
+	require.NoError(t, err)
+
+	registry.RecordHTTPRequest("GET", "/{shortCode}", "301", 0.02)
+	registry.RecordHTTPRequest("GET", "/{shortCode}", "301", 0.2)
+	registry.RecordHTTPRequest("POST", "/shorten", "201", 3)
+	registry.IncHTTPRequestsInFlight()
+	registry.IncHTTPRequestsInFlight()
+	registry.DecHTTPRequestsInFlight()
+	registry.RecordURLCreated("default")
+	registry.RecordRedirect("abc123", "301")
+	registry.RecordRedirect("abc123", "301")
+
+	// Shutdown sends a final export
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, provider.Shutdown(ctx))
+
+	exported := collector.lastMetrics(t)
+	assert.Len(t, exported, 5)
+
+	t.Run("request counter keeps the Prometheus labels", func(t *testing.T) {
+		requests := exported["dove_urlshortener_http_requests_total"]
+		require.NotNil(t, requests)
+		sum := requests.GetSum()
+		require.NotNil(t, sum)
+		assert.True(t, sum.GetIsMonotonic())
+		assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, sum.GetAggregationTemporality())
+
+		byPath := make(map[string]int64)
+		for _, point := range sum.GetDataPoints() {
+			labels := make(map[string]string)
+			for _, kv := range point.GetAttributes() {
+				labels[kv.GetKey()] = kv.GetValue().GetStringValue()
+			}
+			assert.ElementsMatch(t, []string{metrics.LabelMethod, metrics.LabelPath, metrics.LabelStatusCode}, keys(labels))
+			byPath[labels[metrics.LabelPath]] = point.GetAsInt()
+		}
+		assert.Equal(t, map[string]int64{"/{shortCode}": 2, "/shorten": 1}, byPath)
+	})
+
+	t.Run("duration histogram uses Prometheus default buckets", func(t *testing.T) {
+		duration := exported["dove_urlshortener_http_request_duration_seconds"]
+		require.NotNil(t, duration)
+		histogram := duration.GetHistogram()
+		require.NotNil(t, histogram)
+		require.Len(t, histogram.GetDataPoints(), 2)
+
+		for _, point := range histogram.GetDataPoints() {
+			assert.Len(t, point.GetExplicitBounds(), 11)
+			assert.Len(t, point.GetBucketCounts(), 12)
+			if point.GetCount() == 2 {
+				assert.InDelta(t, 0.22, point.GetSum(), 1e-9)
+			}
+		}
+	})
+
+	t.Run("in flight gauge and business counters", func(t *testing.T) {
+		inFlight := exported["dove_urlshortener_http_requests_in_flight"].GetSum()
+		require.NotNil(t, inFlight)
+		assert.False(t, inFlight.GetIsMonotonic())
+		require.Len(t, inFlight.GetDataPoints(), 1)
+		assert.Equal(t, int64(1), inFlight.GetDataPoints()[0].GetAsInt())
+
+		created := exported["dove_urlshortener_urls_created_total"].GetSum().GetDataPoints()
+		require.Len(t, created, 1)
+		assert.Equal(t, int64(1), created[0].GetAsInt())
+
+		redirected := exported["dove_urlshortener_urls_redirected_total"].GetSum().GetDataPoints()
+		require.Len(t, redirected, 1)
+		assert.Equal(t, int64(2), redirected[0].GetAsInt())
+	})
+}
+
+func keys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
@@ -0,0 +1,167 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	pgxRepo "github.com/sp3dr4/dove/internal/infrastructure/pgx"
+	postgresRepo "github.com/sp3dr4/dove/internal/infrastructure/postgres"
+)
+
+// driverSnapshot is everything observed by runRepositoryScenario, compared across drivers
+type driverSnapshot struct {
+	Created      *domain.URL
+	Duplicate    error
+	Found        *domain.URL
+	Incremented  *domain.URL
+	Updated      *domain.URL
+	Exists       bool
+	Missing      error
+	MissingClick error
+	Listed       []*domain.URL
+	Top          []*domain.URL
+	Referrers    []domain.ReferrerCount
+	Devices      []domain.DeviceStat
+	Browsers     []domain.DeviceStat
+	Variants     []domain.VariantStat
+	Series       []domain.TimeBucket
+	Heatmap      *domain.ClickHeatmap
+	Latency      *domain.LatencyStats
+	Stats        *domain.ServiceStats
+}
+
+func runRepositoryScenario(t *testing.T, repo domain.URLRepository) driverSnapshot {
+	t.Helper()
+	ctx := context.Background()
+	createdAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	var snapshot driverSnapshot
+	var err error
+
+	url := &domain.URL{
+		Namespace:    domain.DefaultNamespace,
+		ShortCode:    "pgx",
+		OriginalURL:  "https://example.com/pgx",
+		CreatedAt:    createdAt,
+		HealthStatus: domain.HealthStatusUnknown,
+		Tags:         domain.Tags{"go", "postgres"},
+		Description:  "Driver parity check",
+		Title:        "Driver parity page",
+		GeoRoutes:    domain.GeoRoutes{{CountryCode: "DE", DestinationURL: "https://example.de"}},
+		Variants:     []domain.URLVariant{{OriginalURL: "https://example.com/a", Weight: 70}, {OriginalURL: "https://example.com/b", Weight: 30}},
+	}
+	snapshot.Created, err = repo.Create(ctx, url)
+	require.NoError(t, err)
+	_, snapshot.Duplicate = repo.Create(ctx, url)
+
+	pooled := &domain.URL{
+		Namespace:    "team",
+		ShortCode:    "pooled",
+		OriginalURL:  "https://example.com/1",
+		CreatedAt:    createdAt,
+		HealthStatus: domain.HealthStatusUnknown,
+		PoolEnabled:  true,
+		Pool:         &domain.URLPool{Targets: []domain.PoolTarget{{URL: "https://example.com/1", Weight: 1}, {URL: "https://example.com/2", Weight: 2}}},
+	}
+	_, err = repo.Create(ctx, pooled)
+	require.NoError(t, err)
+
+	snapshot.Found, err = repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "pgx")
+	require.NoError(t, err)
+	_, snapshot.Missing = repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "missing")
+	_, snapshot.MissingClick = repo.IncrementClicks(ctx, domain.DefaultNamespace, "missing", true)
+
+	_, err = repo.IncrementClicks(ctx, domain.DefaultNamespace, "pgx", true)
+	require.NoError(t, err)
+	snapshot.Incremented, err = repo.IncrementClicks(ctx, domain.DefaultNamespace, "pgx", false)
+	require.NoError(t, err)
+
+	for i, duration := range []float64{1, 2, 3, 4} {
+		require.NoError(t, repo.RecordClick(ctx, &domain.Click{
+			Namespace:          domain.DefaultNamespace,
+			ShortCode:          "pgx",
+			ClickedAt:          createdAt.Add(time.Duration(i) * time.Hour),
+			Referer:            []string{"a.example", "b.example"}[i%2],
+			Browser:            "Firefox",
+			OS:                 "Linux",
+			DeviceType:         "desktop",
+			VariantID:          &snapshot.Created.Variants[0].ID,
+			RedirectDurationMs: &duration,
+		}))
+	}
+
+	update := *snapshot.Found
+	update.OriginalURL = "https://example.com/updated"
+	update.Tags = nil
+	snapshot.Updated, err = repo.Update(ctx, &update)
+	require.NoError(t, err)
+
+	snapshot.Exists, err = repo.Exists(ctx, "team", "pooled")
+	require.NoError(t, err)
+	snapshot.Listed, err = repo.List(ctx, 0, 10)
+	require.NoError(t, err)
+	snapshot.Top, err = repo.TopByClicks(ctx, 1)
+	require.NoError(t, err)
+	snapshot.Referrers, err = repo.TopReferrers(ctx, domain.DefaultNamespace, "pgx", 5)
+	require.NoError(t, err)
+	snapshot.Devices, err = repo.DeviceStats(ctx, domain.DefaultNamespace, "pgx", domain.DeviceGroupingDevice)
+	require.NoError(t, err)
+	snapshot.Browsers, err = repo.DeviceStats(ctx, domain.DefaultNamespace, "pgx", domain.DeviceGroupingBrowser)
+	require.NoError(t, err)
+	snapshot.Variants, err = repo.VariantStats(ctx, domain.DefaultNamespace, "pgx")
+	require.NoError(t, err)
+	snapshot.Series, err = repo.ClickTimeSeries(ctx, domain.DefaultNamespace, "pgx", domain.GranularityHour, createdAt, createdAt.Add(24*time.Hour))
+	require.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	snapshot.Heatmap, err = repo.ClickHeatmap(ctx, domain.DefaultNamespace, "pgx", newYork)
+	require.NoError(t, err)
+	snapshot.Latency, err = repo.RedirectLatency(ctx, domain.DefaultNamespace, "pgx")
+	require.NoError(t, err)
+	snapshot.Stats, err = repo.Stats(ctx, createdAt, createdAt.Add(-time.Hour))
+	require.NoError(t, err)
+
+	// Set by the database as the scenario runs
+	for _, url := range append([]*domain.URL{snapshot.Created, snapshot.Found, snapshot.Incremented, snapshot.Updated}, append(snapshot.Listed, snapshot.Top...)...) {
+		url.UpdatedAt = time.Time{}
+		url.CreatedAt = url.CreatedAt.UTC()
+	}
+	for i := range snapshot.Series {
+		snapshot.Series[i].Period = snapshot.Series[i].Period.UTC()
+	}
+	return snapshot
+}
+
+func TestPgxRepository_MatchesLibPQ_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	pq := runRepositoryScenario(t, postgresRepo.NewURLRepository(env.DB, logger, 0, false, false))
+
+	// Identities restart, so both runs see the same IDs
+	env = SetupTestEnvironment(t)
+	pool, err := pgxpool.New(context.Background(), env.ConnStr)
+	require.NoError(t, err)
+	repo := pgxRepo.NewURLRepository(pool, logger, false)
+	t.Cleanup(func() { _ = repo.Close() })
+	require.NoError(t, repo.HealthCheck(context.Background()))
+
+	pgx := runRepositoryScenario(t, repo)
+
+	assert.ErrorIs(t, pgx.Duplicate, domain.ErrShortCodeExists)
+	assert.ErrorIs(t, pgx.Missing, domain.ErrURLNotFound)
+	assert.ErrorIs(t, pgx.MissingClick, domain.ErrURLNotFound)
+	assert.Equal(t, 2, pgx.Incremented.Clicks)
+	assert.Equal(t, 1, pgx.Incremented.UniqueClicks)
+	// Clicks start at 12:00 UTC on 2025-03-01, a Saturday, which is 07:00 in New York
+	assert.Equal(t, [24]int{7: 1, 8: 1, 9: 1, 10: 1}, pgx.Heatmap.HourOfDay)
+	assert.Equal(t, [7]int{time.Saturday: 4}, pgx.Heatmap.DayOfWeek)
+	assert.Equal(t, pq, pgx)
+}
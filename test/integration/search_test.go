@@ -0,0 +1,110 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	pgxRepo "github.com/sp3dr4/dove/internal/infrastructure/pgx"
+	postgresRepo "github.com/sp3dr4/dove/internal/infrastructure/postgres"
+)
+
+// searchURLs is how many URLs the search is measured against
+const searchURLs = 1000
+
+func TestURLRepository_Search_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	_, err := env.DB.Exec(`INSERT INTO urls (short_code, original_url, description)
+		SELECT 'bulk' || i, 'https://example.com/articles/' || i, 'Article ' || i FROM generate_series(1, $1) AS i`, searchURLs-3)
+	require.NoError(t, err)
+	for _, url := range []*domain.URL{
+		{Namespace: domain.DefaultNamespace, ShortCode: "effgo", OriginalURL: "https://go.dev/doc/effective_go", HealthStatus: domain.HealthStatusUnknown},
+		{Namespace: domain.DefaultNamespace, ShortCode: "discount", OriginalURL: "https://shop.example.net/sale?off=100%25", HealthStatus: domain.HealthStatusUnknown},
+		{Namespace: domain.DefaultNamespace, ShortCode: "rust", OriginalURL: "https://rust-lang.org", Description: "Systems language homepage", HealthStatus: domain.HealthStatusUnknown},
+	} {
+		_, err := env.Repo.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	_, err = env.DB.Exec("ANALYZE urls")
+	require.NoError(t, err)
+
+	pgxPool, err := pgxpool.New(ctx, env.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(pgxPool.Close)
+	repos := map[string]struct {
+		exact domain.URLRepository
+		fuzzy domain.URLRepository
+	}{
+		"pq":  {exact: env.Repo, fuzzy: postgresRepo.NewURLRepository(env.DB, logger, 0, true, false)},
+		"pgx": {exact: pgxRepo.NewURLRepository(pgxPool, logger, false), fuzzy: pgxRepo.NewURLRepository(pgxPool, logger, true)},
+	}
+
+	codesOf := func(urls []*domain.URL) []string {
+		codes := make([]string, 0, len(urls))
+		for _, url := range urls {
+			codes = append(codes, url.ShortCode)
+		}
+		return codes
+	}
+
+	for driver, repo := range repos {
+		t.Run(driver, func(t *testing.T) {
+			tests := []struct {
+				name     string
+				query    string
+				expected []string
+				fuzzy    []string // when different with trigram search
+			}{
+				{name: "original URL ignoring case", query: "EFFECTIVE_GO", expected: []string{"effgo"}},
+				{name: "short code", query: "bulk99", expected: []string{"bulk99", "bulk990", "bulk991", "bulk992", "bulk993", "bulk994", "bulk995", "bulk996", "bulk997"}},
+				{name: "description", query: "systems", expected: []string{"rust"}},
+				{name: "wildcards match literally", query: "100%25", expected: []string{"discount"}},
+				{name: "similar original URL", query: "https://go.dev/doc/efective-go", expected: []string{}, fuzzy: []string{"effgo"}},
+				{name: "no match", query: "python", expected: []string{}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					urls, err := repo.exact.Search(ctx, tt.query, 20)
+					require.NoError(t, err)
+					assert.Equal(t, tt.expected, codesOf(urls))
+
+					fuzzy := tt.fuzzy
+					if fuzzy == nil {
+						fuzzy = tt.expected
+					}
+					urls, err = repo.fuzzy.Search(ctx, tt.query, 20)
+					require.NoError(t, err)
+					assert.Equal(t, fuzzy, codesOf(urls))
+				})
+			}
+
+			t.Run("limit", func(t *testing.T) {
+				urls, err := repo.fuzzy.Search(ctx, "articles", 5)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"bulk1", "bulk2", "bulk3", "bulk4", "bulk5"}, codesOf(urls))
+			})
+
+			t.Run("within 100ms", func(t *testing.T) {
+				result := testing.Benchmark(func(b *testing.B) {
+					for b.Loop() {
+						if _, err := repo.fuzzy.Search(ctx, "go.dev/doc/effective", 20); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+				t.Logf("search over %d URLs: %s", searchURLs, result)
+				assert.Less(t, time.Duration(result.NsPerOp()), 100*time.Millisecond)
+			})
+		})
+	}
+}
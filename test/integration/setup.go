@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -25,21 +26,30 @@ import (
 	"github.com/sp3dr4/dove/internal/application"
 	postgresRepo "github.com/sp3dr4/dove/internal/infrastructure/postgres"
 	redisCache "github.com/sp3dr4/dove/internal/infrastructure/redis"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/lock"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
 )
 
 var (
 	sharedPgContainer    *postgresContainer.PostgresContainer
 	sharedRedisContainer *redisContainer.RedisContainer
 	sharedDB             *sqlx.DB
+	sharedConnStr        string
 	sharedRedisClient    *redis.Client
 	containerOnce        sync.Once
 	cleanupOnce          sync.Once
 )
 
+// testKeyPrefix starts the Redis keys of the test environment, as in the default configuration
+const testKeyPrefix = "dove"
+
 // TestEnvironment holds the test setup
 type TestEnvironment struct {
 	DB          *sqlx.DB
+	ConnStr     string // PostgreSQL connection string of DB
 	RedisClient *redis.Client
+	Repo        *postgresRepo.URLRepository
 	Service     *application.URLService
 }
 
@@ -73,6 +83,7 @@ func SetupTestEnvironment(t *testing.T) *TestEnvironment {
 			t.Fatalf("failed to connect to database: %v", err)
 		}
 		sharedDB = db
+		sharedConnStr = connStr
 
 		if migErr := runMigrations(db.DB); migErr != nil {
 			t.Fatalf("failed to run migrations: %v", migErr)
@@ -110,13 +121,15 @@ func SetupTestEnvironment(t *testing.T) *TestEnvironment {
 	cleanRedisCache(t, sharedRedisClient)
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	repo := postgresRepo.NewURLRepository(sharedDB, logger)
-	cache := redisCache.NewRedisCache(sharedRedisClient, logger)
-	service := application.NewURLService(repo, cache, 10*time.Minute, logger)
+	repo := postgresRepo.NewURLRepository(sharedDB, logger, 0, false, false)
+	cache := redisCache.NewRedisCache(sharedRedisClient, testKeyPrefix, time.Hour, 0, logger)
+	service := application.NewURLService(repo, cache, 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{Locker: lock.NewRedisLock(sharedRedisClient, testKeyPrefix)}, logger)
 
 	return &TestEnvironment{
 		DB:          sharedDB,
+		ConnStr:     sharedConnStr,
 		RedisClient: sharedRedisClient,
+		Repo:        repo,
 		Service:     service,
 	}
 }
@@ -142,7 +155,7 @@ func CleanupSharedResources() {
 
 // cleanDatabase truncates all tables to ensure test isolation
 func cleanDatabase(t *testing.T, db *sqlx.DB) {
-	_, err := db.Exec("TRUNCATE TABLE urls RESTART IDENTITY CASCADE")
+	_, err := db.Exec("TRUNCATE TABLE urls, funnels, url_audit_log RESTART IDENTITY CASCADE")
 	if err != nil {
 		t.Fatalf("failed to clean database: %v", err)
 	}
@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	pgxRepo "github.com/sp3dr4/dove/internal/infrastructure/pgx"
+	postgresRepo "github.com/sp3dr4/dove/internal/infrastructure/postgres"
+)
+
+// PgBouncer in transaction mode cannot keep prepared statements, so with disable_prepare
+// every repository operation must behave as it does with them
+func TestRepositories_WithoutPreparedStatements_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	prepared := runRepositoryScenario(t, postgresRepo.NewURLRepository(env.DB, logger, 0, false, false))
+
+	addClicks := func(t *testing.T, repo domain.URLRepository) {
+		t.Helper()
+		require.NoError(t, repo.AddClicks(ctx, []domain.ClickDelta{
+			{Namespace: domain.DefaultNamespace, ShortCode: "pgx", Clicks: 5, UniqueClicks: 2},
+			{Namespace: "team", ShortCode: "pooled", Clicks: 1, UniqueClicks: 1},
+		}))
+		url, err := repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "pgx")
+		require.NoError(t, err)
+		assert.Equal(t, prepared.Incremented.Clicks+5, url.Clicks)
+		assert.Equal(t, prepared.Incremented.UniqueClicks+2, url.UniqueClicks)
+	}
+
+	t.Run("lib/pq", func(t *testing.T) {
+		// Identities restart, so every run sees the same IDs
+		env := SetupTestEnvironment(t)
+		repo := postgresRepo.NewURLRepository(env.DB, logger, 0, false, true)
+
+		assert.Equal(t, prepared, runRepositoryScenario(t, repo))
+		addClicks(t, repo)
+	})
+
+	t.Run("pgx", func(t *testing.T) {
+		env := SetupTestEnvironment(t)
+		pool, err := pgxRepo.NewPool(ctx, env.ConnStr, true)
+		require.NoError(t, err)
+		repo := pgxRepo.NewURLRepository(pool, logger, false)
+		t.Cleanup(func() { _ = repo.Close() })
+		require.NoError(t, repo.HealthCheck(ctx))
+
+		assert.Equal(t, prepared, runRepositoryScenario(t, repo))
+		addClicks(t, repo)
+
+		// The database/sql wrapper used by the migrations shares the pool settings
+		var one int
+		require.NoError(t, repo.DB().QueryRowContext(ctx, "SELECT $1::int", 1).Scan(&one))
+		assert.Equal(t, 1, one)
+	})
+}
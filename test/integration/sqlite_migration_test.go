@@ -0,0 +1,92 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/infrastructure/migration"
+	"github.com/sp3dr4/dove/internal/infrastructure/migrations"
+	sqliteRepo "github.com/sp3dr4/dove/internal/infrastructure/sqlite"
+)
+
+func TestMigrateSQLiteToPostgres_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	srcDB, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "dove.db"))
+	require.NoError(t, err)
+	defer func() { _ = srcDB.Close() }()
+	migrationsPath, err := filepath.Abs("../../migrations/sqlite")
+	require.NoError(t, err)
+	require.NoError(t, migrations.NewRepository(srcDB.DB, "sqlite3", migrationsPath).Up(ctx))
+	src, err := sqliteRepo.NewURLRepository(srcDB, sqliteRepo.Pragmas{}, logger)
+	require.NoError(t, err)
+
+	clickedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 500 {
+		url, err := domain.NewURL(fmt.Sprintf("lite%d", i), fmt.Sprintf("https://example.com/%d", i))
+		require.NoError(t, err)
+		if i%50 == 0 {
+			url.Variants = []domain.URLVariant{
+				{OriginalURL: "https://a.example.com", Weight: 1},
+				{OriginalURL: "https://b.example.com", Weight: 3},
+			}
+		}
+		created, err := src.Create(ctx, url)
+		require.NoError(t, err)
+
+		click := &domain.Click{Namespace: created.Namespace, ShortCode: created.ShortCode, ClickedAt: clickedAt, Referer: "(direct)", IPAddress: "203.0.113.7"}
+		if len(created.Variants) > 0 {
+			click.VariantID = &created.Variants[1].ID
+		}
+		require.NoError(t, src.RecordClick(ctx, click))
+	}
+	// Unparseable in an inet column, the click is skipped rather than failing the migration
+	require.NoError(t, src.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "lite1", ClickedAt: clickedAt, IPAddress: "not-an-ip"}))
+
+	var progressed []string
+	report, err := migration.MigrateSQLiteToPostgres(ctx, src, env.Repo, srcDB, env.DB, func(table string, rows int) {
+		progressed = append(progressed, fmt.Sprintf("%s:%d", table, rows))
+	}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, migration.DatabaseReport{
+		URLs:     domain.StoreMigrationReport{Migrated: 500},
+		Variants: migration.RowsReport{Copied: 20},
+		Clicks:   migration.RowsReport{Copied: 500, Failed: 1},
+	}, report)
+	assert.Equal(t, []string{"urls:500", "url_variants:20", "url_clicks:500"}, progressed)
+
+	for _, table := range []string{"urls", "url_variants"} {
+		var srcCount, dstCount int
+		require.NoError(t, srcDB.GetContext(ctx, &srcCount, `SELECT COUNT(*) FROM `+table))
+		require.NoError(t, env.DB.GetContext(ctx, &dstCount, `SELECT COUNT(*) FROM `+table))
+		assert.Equal(t, srcCount, dstCount, table)
+	}
+	var clicks int
+	require.NoError(t, env.DB.GetContext(ctx, &clicks, `SELECT COUNT(*) FROM url_clicks`))
+	assert.Equal(t, 500, clicks)
+
+	// Clicks point at the copies of their variants
+	var variantURL string
+	require.NoError(t, env.DB.GetContext(ctx, &variantURL, `
+		SELECT v.original_url FROM url_clicks c JOIN url_variants v ON v.id = c.variant_id
+		WHERE c.short_code = 'lite50'`))
+	assert.Equal(t, "https://b.example.com", variantURL)
+
+	// Running it again copies nothing twice
+	report, err = migration.MigrateSQLiteToPostgres(ctx, src, env.Repo, srcDB, env.DB, nil, logger)
+	require.NoError(t, err)
+	assert.Equal(t, migration.DatabaseReport{URLs: domain.StoreMigrationReport{Skipped: 500}}, report)
+}
@@ -0,0 +1,73 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/infrastructure/memory"
+	"github.com/sp3dr4/dove/internal/infrastructure/migration"
+)
+
+func TestStoreMigrator_MemoryToPostgres_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	src := memory.NewURLRepository(logger, 0)
+	for i := range 100 {
+		url, err := domain.NewURL(fmt.Sprintf("mem%d", i), fmt.Sprintf("https://example.com/%d", i))
+		require.NoError(t, err)
+		url.Clicks = i
+		if i%10 == 0 {
+			url.Namespace = "team"
+		}
+		_, err = src.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	taken, err := domain.NewURL("mem1", "https://example.com/taken")
+	require.NoError(t, err)
+	_, err = env.Repo.Create(ctx, taken)
+	require.NoError(t, err)
+
+	migrator := migration.NewStoreMigrator(src, "memory", func(storeType string) (domain.URLRepository, error) {
+		require.Equal(t, "postgres", storeType)
+		// The environment closes its repository itself
+		return nopCloseRepository{env.Repo}, nil
+	}, logger)
+
+	report, err := migrator.MigrateStore(ctx, "memory", "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.StoreMigrationReport{Migrated: 99, Skipped: 1}, report)
+
+	var count int
+	require.NoError(t, env.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM urls`))
+	assert.Equal(t, 100, count)
+
+	migrated, err := env.Repo.FindByNamespaceAndCode(ctx, "team", "mem50")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/50", migrated.OriginalURL)
+	assert.Equal(t, 50, migrated.Clicks)
+
+	kept, err := env.Repo.FindByNamespaceAndCode(ctx, domain.DefaultNamespace, "mem1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/taken", kept.OriginalURL)
+
+	// Running it again finds every URL already migrated
+	report, err = migrator.MigrateStore(ctx, "memory", "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.StoreMigrationReport{Skipped: 100}, report)
+}
+
+// nopCloseRepository keeps the migrator from closing a repository it did not open
+type nopCloseRepository struct {
+	domain.URLRepository
+}
+
+func (nopCloseRepository) Close() error { return nil }
@@ -1,17 +1,33 @@
 package integration
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	httpAdapter "github.com/sp3dr4/dove/internal/adapters/http"
 	"github.com/sp3dr4/dove/internal/application"
 	"github.com/sp3dr4/dove/internal/domain"
+	"github.com/sp3dr4/dove/internal/infrastructure/migrations"
+	postgresRepo "github.com/sp3dr4/dove/internal/infrastructure/postgres"
+	redisCache "github.com/sp3dr4/dove/internal/infrastructure/redis"
+	"github.com/sp3dr4/dove/internal/pkg/audit"
+	"github.com/sp3dr4/dove/internal/pkg/pubsub"
 )
 
 const testBaseURL = "http://localhost:8080"
@@ -60,7 +76,7 @@ func TestURLService_CreateShortURL_IntegrationFlow(t *testing.T) {
 			tt.checkResult(t, resp, tt.request)
 
 			// Verify URL can be retrieved
-			retrievedURL, err := service.GetURL(ctx, resp.ShortCode)
+			retrievedURL, err := service.GetURL(ctx, domain.DefaultNamespace, resp.ShortCode)
 			require.NoError(t, err)
 			assert.Equal(t, tt.request.URL, retrievedURL.OriginalURL)
 		})
@@ -150,6 +166,43 @@ func TestURLService_DuplicateAlias_Integration(t *testing.T) {
 	assert.Equal(t, domain.ErrShortCodeExists, err)
 }
 
+func TestURLService_ConcurrentAlias_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+
+	ctx := context.Background()
+	service := env.Service
+
+	const numGoroutines = 10
+	errChan := make(chan error, numGoroutines)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			req := application.CreateURLRequest{
+				URL:         "https://example.com",
+				CustomAlias: "contested",
+			}
+			_, createErr := service.CreateShortURL(ctx, req, testBaseURL)
+			errChan <- createErr
+		}()
+	}
+
+	// Exactly one creation claims the alias, whether the others lose the lock or find it taken
+	created := 0
+	for i := 0; i < numGoroutines; i++ {
+		chanErr := <-errChan
+		if chanErr == nil {
+			created++
+			continue
+		}
+		assert.ErrorIs(t, chanErr, domain.ErrShortCodeExists)
+	}
+	assert.Equal(t, 1, created)
+
+	keys, err := env.RedisClient.Keys(ctx, testKeyPrefix+":lock:*").Result()
+	require.NoError(t, err)
+	assert.Empty(t, keys, "every lock is released")
+}
+
 func TestURLService_ClickTracking_Integration(t *testing.T) {
 	env := SetupTestEnvironment(t)
 
@@ -164,17 +217,17 @@ func TestURLService_ClickTracking_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify initial click count is 0
-	url, err := service.GetURL(ctx, "clicktest")
+	url, err := service.GetURL(ctx, domain.DefaultNamespace, "clicktest")
 	require.NoError(t, err)
 	assert.Equal(t, 0, url.Clicks)
 
 	// Increment clicks multiple times
 	for i := 1; i <= 3; i++ {
-		_, err = service.IncrementClicks(ctx, "clicktest")
+		_, err = service.IncrementClicks(ctx, domain.DefaultNamespace, "clicktest", application.ClickDetails{})
 		require.NoError(t, err)
 
 		// Verify click count
-		url, err = service.GetURL(ctx, "clicktest")
+		url, err = service.GetURL(ctx, domain.DefaultNamespace, "clicktest")
 		require.NoError(t, err)
 		assert.Equal(t, i, url.Clicks)
 	}
@@ -187,11 +240,11 @@ func TestURLService_NonExistentURL_Integration(t *testing.T) {
 	service := env.Service
 
 	// Try to get non-existent URL
-	_, err := service.GetURL(ctx, "notfound")
+	_, err := service.GetURL(ctx, domain.DefaultNamespace, "notfound")
 	assert.Equal(t, domain.ErrURLNotFound, err)
 
 	// Try to increment clicks for non-existent URL
-	_, err = service.IncrementClicks(ctx, "notfound")
+	_, err = service.IncrementClicks(ctx, domain.DefaultNamespace, "notfound", application.ClickDetails{})
 	assert.Equal(t, domain.ErrURLNotFound, err)
 }
 
@@ -217,7 +270,7 @@ func TestURLService_ConcurrentAccess_Integration(t *testing.T) {
 	for i := 0; i < numGoroutines; i++ {
 		go func() {
 			for j := 0; j < clicksPerGoroutine; j++ {
-				_, incrementErr := service.IncrementClicks(ctx, "concurrent")
+				_, incrementErr := service.IncrementClicks(ctx, domain.DefaultNamespace, "concurrent", application.ClickDetails{})
 				if incrementErr != nil {
 					errChan <- incrementErr
 					return
@@ -234,7 +287,7 @@ func TestURLService_ConcurrentAccess_Integration(t *testing.T) {
 	}
 
 	// Verify final click count
-	url, err := service.GetURL(ctx, "concurrent")
+	url, err := service.GetURL(ctx, domain.DefaultNamespace, "concurrent")
 	require.NoError(t, err)
 
 	expectedClicks := numGoroutines * clicksPerGoroutine
@@ -255,12 +308,12 @@ func TestURLService_CacheBehavior_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// First get - this should cache the URL
-	url1, err := service.GetURL(ctx, "cachetest")
+	url1, err := service.GetURL(ctx, domain.DefaultNamespace, "cachetest")
 	require.NoError(t, err)
 	assert.Equal(t, created.OriginalURL, url1.OriginalURL)
 
 	// Verify it's in cache by checking Redis directly
-	cachedData, err := env.RedisClient.Get(ctx, "url:cachetest").Result()
+	cachedData, err := env.RedisClient.Get(ctx, "dove:url:default:cachetest").Result()
 	require.NoError(t, err)
 	assert.NotEmpty(t, cachedData)
 
@@ -269,21 +322,21 @@ func TestURLService_CacheBehavior_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Second get - should return cached version (still 0 clicks)
-	url2, err := service.GetURL(ctx, "cachetest")
+	url2, err := service.GetURL(ctx, domain.DefaultNamespace, "cachetest")
 	require.NoError(t, err)
 	assert.Equal(t, 0, url2.Clicks)
 
 	// Clear cache manually
-	err = env.RedisClient.Del(ctx, "url:cachetest").Err()
+	err = env.RedisClient.Del(ctx, "dove:url:default:cachetest").Err()
 	require.NoError(t, err)
 
 	// Third get - should fetch from DB and see updated clicks
-	url3, err := service.GetURL(ctx, "cachetest")
+	url3, err := service.GetURL(ctx, domain.DefaultNamespace, "cachetest")
 	require.NoError(t, err)
 	assert.Equal(t, 10, url3.Clicks)
 
 	// Verify it's cached again
-	cachedData2, err := env.RedisClient.Get(ctx, "url:cachetest").Result()
+	cachedData2, err := env.RedisClient.Get(ctx, "dove:url:default:cachetest").Result()
 	require.NoError(t, err)
 	assert.NotEmpty(t, cachedData2)
 }
@@ -302,26 +355,26 @@ func TestURLService_CacheInvalidation_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Get URL to populate cache
-	url1, err := service.GetURL(ctx, "invalidtest")
+	url1, err := service.GetURL(ctx, domain.DefaultNamespace, "invalidtest")
 	require.NoError(t, err)
 	assert.Equal(t, 0, url1.Clicks)
 
 	// Verify it's cached
-	cachedData, err := env.RedisClient.Get(ctx, "url:invalidtest").Result()
+	cachedData, err := env.RedisClient.Get(ctx, "dove:url:default:invalidtest").Result()
 	require.NoError(t, err)
 	assert.NotEmpty(t, cachedData)
 
 	// Increment clicks (should update cache)
-	_, err = service.IncrementClicks(ctx, "invalidtest")
+	_, err = service.IncrementClicks(ctx, domain.DefaultNamespace, "invalidtest", application.ClickDetails{})
 	require.NoError(t, err)
 
 	// Get URL again - should see updated clicks from cache
-	url2, err := service.GetURL(ctx, "invalidtest")
+	url2, err := service.GetURL(ctx, domain.DefaultNamespace, "invalidtest")
 	require.NoError(t, err)
 	assert.Equal(t, 1, url2.Clicks)
 
 	// Verify cache was updated by checking raw data
-	cachedData2, err := env.RedisClient.Get(ctx, "url:invalidtest").Result()
+	cachedData2, err := env.RedisClient.Get(ctx, "dove:url:default:invalidtest").Result()
 	require.NoError(t, err)
 
 	// Parse JSON to verify clicks were updated
@@ -345,17 +398,942 @@ func TestURLService_RedisCacheMiss_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify it's not in cache
-	err = env.RedisClient.Get(ctx, "url:directdb").Err()
+	err = env.RedisClient.Get(ctx, "dove:url:default:directdb").Err()
 	assert.Equal(t, redis.Nil, err)
 
 	// Get URL through service - should fetch from DB and cache it
-	url, err := service.GetURL(ctx, "directdb")
+	url, err := service.GetURL(ctx, domain.DefaultNamespace, "directdb")
 	require.NoError(t, err)
 	assert.Equal(t, "https://example.com/direct", url.OriginalURL)
 	assert.Equal(t, 5, url.Clicks)
 
 	// Verify it's now cached
-	cachedData, err := env.RedisClient.Get(ctx, "url:directdb").Result()
+	cachedData, err := env.RedisClient.Get(ctx, "dove:url:default:directdb").Result()
 	require.NoError(t, err)
 	assert.NotEmpty(t, cachedData)
 }
+
+func TestURLService_PasswordProtection_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+
+	ctx := context.Background()
+	service := env.Service
+
+	req := application.CreateURLRequest{
+		URL:         "https://example.com/secret",
+		CustomAlias: "secret",
+		Password:    "opensesame",
+	}
+	resp, err := service.CreateShortURL(ctx, req, testBaseURL)
+	require.NoError(t, err)
+	assert.True(t, resp.Protected)
+
+	// The stored hash must never be the plain password
+	var storedHash string
+	err = env.DB.Get(&storedHash, "SELECT password_hash FROM urls WHERE short_code = $1", "secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, req.Password, storedHash)
+
+	handlers := httpAdapter.NewHandlers(service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	tests := []struct {
+		name           string
+		target         string
+		header         string
+		expectedStatus int
+	}{
+		{"no password", "/secret", "", http.StatusUnauthorized},
+		{"wrong password in query", "/secret?p=wrong", "", http.StatusUnauthorized},
+		{"wrong password in header", "/secret", "wrong", http.StatusUnauthorized},
+		{"correct password in query", "/secret?p=opensesame", "", http.StatusMovedPermanently},
+		{"correct password in header", "/secret", "opensesame", http.StatusMovedPermanently},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set("X-URL-Password", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusMovedPermanently {
+				assert.Equal(t, req.URL, w.Header().Get("Location"))
+			} else {
+				assert.Empty(t, w.Header().Get("Location"))
+			}
+		})
+	}
+
+	// Only the two successful redirects should have been counted
+	url, err := service.GetURL(ctx, domain.DefaultNamespace, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, 2, url.Clicks)
+}
+
+func TestURLRepository_ClickTimeSeries_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/series",
+		CustomAlias: "series",
+	}, testBaseURL)
+	require.NoError(t, err)
+
+	// Wednesday 2024-01-03 and the following Monday
+	clicks := []time.Time{
+		time.Date(2024, 1, 3, 10, 15, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 10, 45, 30, 0, time.UTC),
+		time.Date(2024, 1, 3, 11, 5, 0, 0, time.UTC),
+		time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), // outside the queried range
+	}
+	for _, clickedAt := range clicks {
+		require.NoError(t, env.Repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "series", ClickedAt: clickedAt}))
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		granularity domain.Granularity
+		expected    []domain.TimeBucket
+	}{
+		{domain.GranularityMinute, []domain.TimeBucket{
+			{Period: time.Date(2024, 1, 3, 10, 15, 0, 0, time.UTC), Clicks: 1},
+			{Period: time.Date(2024, 1, 3, 10, 45, 0, 0, time.UTC), Clicks: 1},
+			{Period: time.Date(2024, 1, 3, 11, 5, 0, 0, time.UTC), Clicks: 1},
+			{Period: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC), Clicks: 1},
+		}},
+		{domain.GranularityHour, []domain.TimeBucket{
+			{Period: time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC), Clicks: 2},
+			{Period: time.Date(2024, 1, 3, 11, 0, 0, 0, time.UTC), Clicks: 1},
+			{Period: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC), Clicks: 1},
+		}},
+		{domain.GranularityDay, []domain.TimeBucket{
+			{Period: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Clicks: 3},
+			{Period: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), Clicks: 1},
+		}},
+		{domain.GranularityWeek, []domain.TimeBucket{
+			{Period: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Clicks: 3},
+			{Period: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), Clicks: 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.granularity), func(t *testing.T) {
+			buckets, err := env.Repo.ClickTimeSeries(ctx, domain.DefaultNamespace, "series", tt.granularity, from, to)
+			require.NoError(t, err)
+			require.Len(t, buckets, len(tt.expected))
+			for i, bucket := range buckets {
+				assert.True(t, tt.expected[i].Period.Equal(bucket.Period), "bucket %d: expected %s, got %s", i, tt.expected[i].Period, bucket.Period)
+				assert.Equal(t, tt.expected[i].Clicks, bucket.Clicks)
+			}
+		})
+	}
+}
+
+func TestURLService_ClickTimeSeries_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/stats",
+		CustomAlias: "stats",
+	}, testBaseURL)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := env.Service.IncrementClicks(ctx, domain.DefaultNamespace, "stats", application.ClickDetails{})
+		require.NoError(t, err)
+	}
+
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/analytics/timeseries", handlers.HandleClickTimeSeries)
+
+	req := httptest.NewRequest(http.MethodGet, "/shorten/stats/analytics/timeseries?granularity=hour", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var buckets []domain.TimeBucket
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &buckets))
+	total := 0
+	for _, bucket := range buckets {
+		total += bucket.Clicks
+	}
+	assert.Equal(t, 3, total)
+
+	req = httptest.NewRequest(http.MethodGet, "/shorten/stats/analytics/timeseries?from=2024-01-01&to=2024-06-01", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestURLRepository_HealthCheckOrdering_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	for _, alias := range []string{"stale", "fresh", "never"} {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+			URL:         "https://example.com/" + alias,
+			CustomAlias: alias,
+		}, testBaseURL)
+		require.NoError(t, err)
+	}
+
+	now := time.Now().UTC()
+	require.NoError(t, env.Repo.UpdateHealthStatus(ctx, domain.DefaultNamespace, "stale", domain.HealthStatusHealthy, now.Add(-time.Hour)))
+	require.NoError(t, env.Repo.UpdateHealthStatus(ctx, domain.DefaultNamespace, "fresh", domain.HealthStatusDead, now))
+
+	urls, err := env.Repo.ListForHealthCheck(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, urls, 3)
+	assert.Equal(t, "never", urls[0].ShortCode)
+	assert.Equal(t, domain.HealthStatusUnknown, urls[0].HealthStatus)
+	assert.Nil(t, urls[0].LastCheckedAt)
+	assert.Equal(t, "stale", urls[1].ShortCode)
+	assert.Equal(t, "fresh", urls[2].ShortCode)
+	assert.Equal(t, domain.HealthStatusDead, urls[2].HealthStatus)
+
+	urls, err = env.Repo.ListForHealthCheck(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "never", urls[0].ShortCode)
+
+	err = env.Repo.UpdateHealthStatus(ctx, domain.DefaultNamespace, "missing", domain.HealthStatusDead, now)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestURLRepository_List_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+			URL:         "https://example.com/list",
+			CustomAlias: "list" + string(rune('a'+i)),
+		}, testBaseURL)
+		require.NoError(t, err)
+	}
+
+	first, err := env.Repo.List(ctx, 0, 3)
+	require.NoError(t, err)
+	require.Len(t, first, 3)
+
+	rest, err := env.Repo.List(ctx, first[2].ID, 3)
+	require.NoError(t, err)
+	require.Len(t, rest, 2)
+	assert.Equal(t, "listd", rest[0].ShortCode)
+	assert.Equal(t, "liste", rest[1].ShortCode)
+}
+
+func TestURLService_BulkImport_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	router := chi.NewRouter()
+	router.Post("/urls/import", handlers.HandleImport)
+
+	upload := func() application.ImportReport {
+		file, err := os.Open("testdata/import.ndjson")
+		require.NoError(t, err)
+		defer func() { _ = file.Close() }()
+
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("file", "import.ndjson")
+		require.NoError(t, err)
+		_, err = io.Copy(part, file)
+		require.NoError(t, err)
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/urls/import", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var report application.ImportReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return report
+	}
+
+	// The fixture has 100 entries: 5 reuse the previous line's alias and 3 have invalid URLs
+	report := upload()
+	assert.Equal(t, 92, report.Succeeded)
+	assert.Equal(t, 8, report.Failed)
+
+	failedLines := make([]int, 0, len(report.Errors))
+	for _, importErr := range report.Errors {
+		failedLines = append(failedLines, importErr.Line)
+	}
+	assert.Equal(t, []int{10, 20, 30, 50, 60, 70, 90, 95}, failedLines)
+	assert.Equal(t, "short code already exists", report.Errors[0].Message)
+	assert.Contains(t, report.Errors[1].Message, "url")
+
+	var count int
+	require.NoError(t, env.DB.Get(&count, "SELECT COUNT(*) FROM urls"))
+	assert.Equal(t, 92, count)
+
+	// Every imported URL was cached in the same batch
+	cached, err := env.RedisClient.Keys(context.Background(), "dove:url:default:import*").Result()
+	require.NoError(t, err)
+	assert.Len(t, cached, 92)
+
+	url, err := env.Service.GetURL(context.Background(), domain.DefaultNamespace, "import009")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/import/9", url.OriginalURL)
+
+	// Importing the same file again creates nothing new
+	report = upload()
+	assert.Equal(t, 0, report.Succeeded)
+	assert.Equal(t, 100, report.Failed)
+}
+
+func TestURLService_TopReferrers_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/refs",
+		CustomAlias: "refs",
+	}, testBaseURL)
+	require.NoError(t, err)
+
+	for _, referer := range []string{
+		"",
+		"",
+		"",
+		"https://blog.example.com/post?utm_source=feed",
+		"https://blog.example.com/post",
+	} {
+		_, err := env.Service.IncrementClicks(ctx, domain.DefaultNamespace, "refs", application.ClickDetails{Referer: referer})
+		require.NoError(t, err)
+	}
+
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/analytics/referrers", handlers.HandleTopReferrers)
+
+	req := httptest.NewRequest(http.MethodGet, "/shorten/refs/analytics/referrers?limit=20", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var referrers []domain.ReferrerCount
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &referrers))
+	assert.Equal(t, []domain.ReferrerCount{
+		{Referer: domain.DirectReferer, Count: 3},
+		{Referer: "blog.example.com/post", Count: 2},
+	}, referrers)
+
+	// Clicks recorded before the referer column existed default to direct traffic
+	_, err = env.DB.Exec(`INSERT INTO url_clicks (short_code, clicked_at) VALUES ('refs', NOW())`)
+	require.NoError(t, err)
+
+	referrers, err = env.Repo.TopReferrers(ctx, domain.DefaultNamespace, "refs", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ReferrerCount{{Referer: domain.DirectReferer, Count: 4}}, referrers)
+}
+
+func TestURLService_DeviceStats_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/devices",
+		CustomAlias: "devices",
+	}, testBaseURL)
+	require.NoError(t, err)
+
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+	router.Get("/shorten/{shortCode}/analytics/devices", handlers.HandleDeviceStats)
+	router.Get("/shorten/{shortCode}/analytics/browsers", handlers.HandleBrowserStats)
+
+	for _, ua := range []string{
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Mobile Safari/537.36",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Mobile Safari/537.36",
+		"curl/8.5.0",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/devices", nil)
+		req.Header.Set("User-Agent", ua)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMovedPermanently, w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/shorten/devices/analytics/devices", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats []domain.DeviceStat
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, []domain.DeviceStat{
+		{DeviceType: "mobile", Browser: "Chrome", OS: "Android", Count: 2},
+		{DeviceType: "bot", Browser: "curl", OS: "unknown", Count: 1},
+	}, stats)
+
+	req = httptest.NewRequest(http.MethodGet, "/shorten/devices/analytics/browsers", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, []domain.DeviceStat{{Browser: "Chrome", Count: 2}, {Browser: "curl", Count: 1}}, stats)
+}
+
+func TestURLService_TopURLsCache_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	for _, alias := range []string{"topa", "topb"} {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+			URL:         "https://example.com/" + alias,
+			CustomAlias: alias,
+		}, testBaseURL)
+		require.NoError(t, err)
+	}
+
+	for _, shortCode := range []string{"topa", "topa", "topa", "topb"} {
+		_, err := env.Service.IncrementClicks(ctx, domain.DefaultNamespace, shortCode, application.ClickDetails{})
+		require.NoError(t, err)
+	}
+
+	top, err := env.Service.GetTopURLs(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, top, 2)
+	assert.Equal(t, "topa", top[0].ShortCode)
+	assert.Equal(t, "topb", top[1].ShortCode)
+
+	// The ranking is now cached with a short TTL
+	ttl, err := env.RedisClient.TTL(ctx, "dove:top_urls:2").Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, 30*time.Second)
+
+	// Any click drops the cached ranking
+	for range 3 {
+		_, err = env.Service.IncrementClicks(ctx, domain.DefaultNamespace, "topb", application.ClickDetails{})
+		require.NoError(t, err)
+	}
+
+	exists, err := env.RedisClient.Exists(ctx, "dove:top_urls:2").Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists)
+
+	top, err = env.Service.GetTopURLs(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, top, 2)
+	assert.Equal(t, "topb", top[0].ShortCode)
+	assert.Equal(t, 4, top[0].Clicks)
+}
+
+func TestURLService_Namespaces_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	for _, namespace := range []string{"", "acme"} {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+			URL:         "https://example.com/" + namespace,
+			CustomAlias: "shared",
+			Namespace:   namespace,
+		}, testBaseURL)
+		require.NoError(t, err)
+	}
+
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/again",
+		CustomAlias: "shared",
+		Namespace:   "acme",
+	}, testBaseURL)
+	assert.ErrorIs(t, err, domain.ErrShortCodeExists)
+
+	_, err = env.Service.IncrementClicks(ctx, "acme", "shared", application.ClickDetails{})
+	require.NoError(t, err)
+
+	defaultURL, err := env.Service.GetURL(ctx, domain.DefaultNamespace, "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/", defaultURL.OriginalURL)
+	assert.Equal(t, 0, defaultURL.Clicks)
+
+	acmeURL, err := env.Service.GetURL(ctx, "acme", "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/acme", acmeURL.OriginalURL)
+	assert.Equal(t, 1, acmeURL.Clicks)
+
+	// Each namespace is cached under its own key
+	for _, key := range []string{"dove:url:default:shared", "dove:url:acme:shared"} {
+		exists, err := env.RedisClient.Exists(ctx, key).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), exists, key)
+	}
+
+	_, err = env.Service.GetURL(ctx, "globex", "shared")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestURLService_VariantDistribution_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	weights := map[string]int{
+		"https://example.com/landing-a": 50,
+		"https://example.com/landing-b": 30,
+		"https://example.com/landing-c": 20,
+	}
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/landing",
+		CustomAlias: "abtest",
+		Variants: []application.Variant{
+			{URL: "https://example.com/landing-a", Weight: 50},
+			{URL: "https://example.com/landing-b", Weight: 30},
+			{URL: "https://example.com/landing-c", Weight: 20},
+		},
+	}, testBaseURL)
+	require.NoError(t, err)
+
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/analytics/variants", handlers.HandleVariantStats)
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	const redirects = 1000
+	for range redirects {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/abtest", nil))
+		require.Equal(t, http.StatusFound, w.Code)
+		require.Contains(t, weights, w.Header().Get("Location"))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shorten/abtest/analytics/variants", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats []domain.VariantStat
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	require.Len(t, stats, 3)
+
+	total := 0
+	for _, stat := range stats {
+		total += stat.Clicks
+		share := float64(stat.Clicks) / redirects
+		expected := float64(weights[stat.OriginalURL]) / 100
+		assert.InDelta(t, expected, share, 0.05, stat.OriginalURL)
+	}
+	assert.Equal(t, redirects, total)
+
+	// The cached URL keeps its variants across clicks
+	url, err := env.Service.GetURL(ctx, domain.DefaultNamespace, "abtest")
+	require.NoError(t, err)
+	assert.Len(t, url.Variants, 3)
+	assert.Equal(t, redirects, url.Clicks)
+}
+
+func TestURLService_PoolRoundRobin_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+		CustomAlias: "pooled",
+		Pool: &application.Pool{Targets: []application.PoolTarget{
+			{URL: "https://eu.example.com", Weight: 3},
+			{URL: "https://us.example.com", Weight: 1},
+		}},
+	}, testBaseURL)
+	require.NoError(t, err)
+
+	// The pool survives the JSONB round trip
+	stored, err := env.Repo.FindByShortCode(ctx, "pooled")
+	require.NoError(t, err)
+	assert.True(t, stored.PoolEnabled)
+	require.NotNil(t, stored.Pool)
+	assert.Len(t, stored.Pool.Targets, 2)
+
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+
+	for range 40 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pooled", nil))
+		require.Equal(t, http.StatusFound, w.Code)
+	}
+
+	var counts []struct {
+		TargetURL string `db:"target_url"`
+		Clicks    int    `db:"clicks"`
+	}
+	err = env.DB.Select(&counts, `SELECT target_url, COUNT(*) AS clicks FROM url_clicks WHERE short_code = 'pooled' GROUP BY target_url ORDER BY target_url`)
+	require.NoError(t, err)
+	require.Len(t, counts, 2)
+	assert.Equal(t, "https://eu.example.com", counts[0].TargetURL)
+	assert.Equal(t, 30, counts[0].Clicks)
+	assert.Equal(t, "https://us.example.com", counts[1].TargetURL)
+	assert.Equal(t, 10, counts[1].Clicks)
+}
+
+func TestURLService_GeoRoutes_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/docs",
+		CustomAlias: "geodocs",
+		GeoRoutes: []application.GeoRoute{
+			{CountryCode: "US", DestinationURL: "https://us.example.com/docs"},
+			{CountryCode: "DE", DestinationURL: "https://eu.example.com/docs"},
+		},
+	}, testBaseURL)
+	require.NoError(t, err)
+
+	// The routes survive the JSONB round trip
+	stored, err := env.Repo.FindByShortCode(ctx, "geodocs")
+	require.NoError(t, err)
+	assert.Len(t, stored.GeoRoutes, 2)
+
+	// The first lookup caches the URL; the cached copy keeps its routes
+	_, err = env.Service.GetURL(ctx, domain.DefaultNamespace, "geodocs")
+	require.NoError(t, err)
+	cachedData, err := env.RedisClient.Get(ctx, "dove:url:default:geodocs").Result()
+	require.NoError(t, err)
+	var cached domain.URL
+	require.NoError(t, json.Unmarshal([]byte(cachedData), &cached))
+	assert.Equal(t, stored.GeoRoutes, cached.GeoRoutes)
+
+	destination, ok := cached.GeoRoutes.Destination("DE")
+	assert.True(t, ok)
+	assert.Equal(t, "https://eu.example.com/docs", destination)
+}
+
+func TestURLService_StaleCacheOnDatabaseOutage_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/stale", CustomAlias: "staletest"}, testBaseURL)
+	require.NoError(t, err)
+
+	// The regular entry expires while the stale copy is kept
+	require.NoError(t, env.RedisClient.Del(ctx, "dove:url:default:staletest").Err())
+	staleTTL, err := env.RedisClient.TTL(ctx, "dove:stale:url:default:staletest").Result()
+	require.NoError(t, err)
+	assert.Greater(t, staleTTL, 10*time.Minute)
+
+	// Simulate the outage with a repository whose connection is gone
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	brokenDB, err := sqlx.Open("postgres", "postgres://nobody@127.0.0.1:1/none?sslmode=disable&connect_timeout=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = brokenDB.Close() })
+	service := application.NewURLService(postgresRepo.NewURLRepository(brokenDB, logger, 0, false, false), redisCache.NewRedisCache(env.RedisClient, testKeyPrefix, time.Hour, 0, logger), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{}, logger)
+
+	url, err := service.GetURL(ctx, domain.DefaultNamespace, "staletest")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/stale", url.OriginalURL)
+	assert.True(t, url.Stale)
+
+	// Deleting a cache entry drops its stale copy as well
+	require.NoError(t, redisCache.NewRedisCache(env.RedisClient, testKeyPrefix, time.Hour, 0, logger).Delete(ctx, domain.DefaultNamespace, "staletest"))
+	_, err = service.GetURL(ctx, domain.DefaultNamespace, "staletest")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestURLService_AdminStats_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, alias := range []string{"statsa", "statsb"} {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+			URL:         "https://example.com/" + alias,
+			CustomAlias: alias,
+		}, testBaseURL)
+		require.NoError(t, err)
+	}
+
+	// Created two days ago and expired since
+	old, err := domain.NewURL("statsold", "https://example.com/old")
+	require.NoError(t, err)
+	old.CreatedAt = now.Add(-48 * time.Hour)
+	expired := now.Add(-time.Hour)
+	old.ExpiresAt = &expired
+	_, err = env.Repo.Create(ctx, old)
+	require.NoError(t, err)
+
+	for _, shortCode := range []string{"statsa", "statsa", "statsb"} {
+		_, err := env.Service.IncrementClicks(ctx, domain.DefaultNamespace, shortCode, application.ClickDetails{})
+		require.NoError(t, err)
+	}
+	require.NoError(t, env.Repo.RecordClick(ctx, &domain.Click{Namespace: domain.DefaultNamespace, ShortCode: "statsold", ClickedAt: now.Add(-30 * time.Hour)}))
+
+	// statsa was cached on creation, statsold never was
+	_, err = env.Service.GetURL(ctx, domain.DefaultNamespace, "statsa")
+	require.NoError(t, err)
+	_, err = env.Service.GetURL(ctx, domain.DefaultNamespace, "statsold")
+	require.NoError(t, err)
+
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	router := chi.NewRouter()
+	router.With(httpAdapter.AdminAuthMiddleware("admin-key")).Get("/admin/stats", handlers.HandleStats)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+	req.Header.Set("Authorization", "Bearer admin-key")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats application.StatsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+
+	// The counters agree with the tables
+	var expected struct {
+		TotalURLs    int64 `db:"total_urls"`
+		TotalClicks  int64 `db:"total_clicks"`
+		RecordedRows int64 `db:"recorded_rows"`
+	}
+	require.NoError(t, env.DB.GetContext(ctx, &expected, `
+		SELECT COUNT(*) AS total_urls, SUM(clicks) AS total_clicks, (SELECT COUNT(*) FROM url_clicks) AS recorded_rows
+		FROM urls
+	`))
+	assert.Equal(t, expected.TotalURLs, stats.TotalURLs)
+	assert.Equal(t, expected.TotalClicks, stats.TotalClicks)
+	assert.Equal(t, expected.RecordedRows-1, stats.ClicksToday)
+
+	assert.Equal(t, application.StatsResponse{
+		TotalURLs:        3,
+		TotalClicks:      3,
+		ActiveURLs:       2,
+		URLsCreatedToday: 2,
+		ClicksToday:      3,
+		CacheHitRate:     0.5,
+	}, stats)
+}
+
+func TestURLService_RedirectLatency_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/latency",
+		CustomAlias: "latency",
+	}, testBaseURL)
+	require.NoError(t, err)
+
+	for i := 1; i <= 100; i++ {
+		_, err := env.Service.IncrementClicks(ctx, domain.DefaultNamespace, "latency", application.ClickDetails{
+			RedirectDuration: time.Duration(i) * time.Millisecond,
+		})
+		require.NoError(t, err)
+	}
+
+	// Clicks recorded before durations were measured are left out
+	_, err = env.DB.Exec(`INSERT INTO url_clicks (short_code, clicked_at) VALUES ('latency', NOW())`)
+	require.NoError(t, err)
+
+	handlers := httpAdapter.NewHandlers(env.Service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	router := chi.NewRouter()
+	router.Get("/shorten/{shortCode}/analytics/latency", handlers.HandleRedirectLatency)
+
+	req := httptest.NewRequest(http.MethodGet, "/shorten/latency/analytics/latency", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats domain.LatencyStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, 100, stats.Count)
+	assert.Positive(t, stats.P95)
+	assert.InDelta(t, 50.5, stats.P50, 1e-6)
+	assert.InDelta(t, 95.05, stats.P95, 1e-6)
+	assert.InDelta(t, 99.01, stats.P99, 1e-6)
+	assert.InDelta(t, 50.5, stats.Avg, 1e-6)
+}
+
+func TestURLService_ClickDeduplication_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dedup := redisCache.NewClickDeduplicator(env.RedisClient, testKeyPrefix, time.Minute)
+	service := application.NewURLService(env.Repo, redisCache.NewRedisCache(env.RedisClient, testKeyPrefix, 0, 0, logger), 10*time.Minute, audit.NewAuditLogger(io.Discard), pubsub.NewBroker(0), application.URLServiceDeps{Dedup: dedup}, logger)
+
+	_, err := service.CreateShortURL(ctx, application.CreateURLRequest{
+		URL:         "https://example.com/dedup",
+		CustomAlias: "dedup",
+	}, testBaseURL)
+	require.NoError(t, err)
+
+	handlers := httpAdapter.NewHandlers(service, testBaseURL, env.Repo, httpAdapter.HandlersDeps{})
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", handlers.HandleRedirect)
+	router.Get("/shorten/{shortCode}", handlers.HandleURLInfo)
+
+	for range 5 {
+		req := httptest.NewRequest(http.MethodGet, "/dedup", nil)
+		req.RemoteAddr = "203.0.113.7:51234"
+		req.Header.Set("User-Agent", "curl/8.5.0")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMovedPermanently, w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/shorten/dedup", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var info application.URLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, 5, info.Clicks)
+	assert.Equal(t, 1, info.UniqueClicks)
+
+	members, err := env.RedisClient.SMembers(ctx, "dove:clicks:dedup:default:dedup").Result()
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.NotContains(t, members[0], "203.0.113.7")
+
+	ttl, err := env.RedisClient.TTL(ctx, "dove:clicks:dedup:default:dedup").Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, time.Minute)
+}
+
+func TestMigrationRepository_List_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+
+	repo := migrations.NewRepository(env.DB.DB, "postgres", "../../migrations/postgres")
+	statuses, err := repo.List(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+
+	for _, status := range statuses {
+		assert.True(t, status.Applied, "migration %d %s", status.Version, status.Name)
+		assert.False(t, status.Dirty, "migration %d %s", status.Version, status.Name)
+	}
+	assert.Equal(t, "create_urls_table", statuses[0].Name)
+
+	// The shared database is still at the latest version
+	assert.NoError(t, repo.Up(context.Background()))
+}
+
+func TestRedisCache_KeyPrefixes_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Two environments sharing one Redis, with the same short code pointing elsewhere
+	staging := redisCache.NewRedisCache(env.RedisClient, "staging", 0, 0, logger)
+	prod := redisCache.NewRedisCache(env.RedisClient, "prod", 0, 0, logger)
+	require.NoError(t, staging.Set(ctx, &domain.URL{Namespace: domain.DefaultNamespace, ShortCode: "shared", OriginalURL: "https://staging.example.com"}, time.Minute))
+	require.NoError(t, prod.Set(ctx, &domain.URL{Namespace: domain.DefaultNamespace, ShortCode: "shared", OriginalURL: "https://example.com"}, time.Minute))
+	require.NoError(t, staging.SetTopURLs(ctx, 5, []*domain.URL{{ShortCode: "shared"}}, time.Minute))
+
+	got, err := staging.Get(ctx, domain.DefaultNamespace, "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "https://staging.example.com", got.OriginalURL)
+	got, err = prod.Get(ctx, domain.DefaultNamespace, "shared")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", got.OriginalURL)
+
+	top, err := prod.GetTopURLs(ctx, 5)
+	require.NoError(t, err)
+	assert.Nil(t, top)
+
+	stagingVisits := redisCache.NewClickDeduplicator(env.RedisClient, "staging", time.Minute)
+	prodVisits := redisCache.NewClickDeduplicator(env.RedisClient, "prod", time.Minute)
+	first, err := stagingVisits.FirstVisit(ctx, domain.DefaultNamespace, "shared", "visitor")
+	require.NoError(t, err)
+	assert.True(t, first)
+	first, err = prodVisits.FirstVisit(ctx, domain.DefaultNamespace, "shared", "visitor")
+	require.NoError(t, err)
+	assert.True(t, first)
+
+	t.Run("deleting in one environment leaves the other", func(t *testing.T) {
+		require.NoError(t, prod.InvalidateTopURLs(ctx))
+		top, err := staging.GetTopURLs(ctx, 5)
+		require.NoError(t, err)
+		assert.Len(t, top, 1)
+	})
+
+	t.Run("keys migrate to a new prefix", func(t *testing.T) {
+		renamed, err := staging.MigrateKeys(ctx, "staging", "qa")
+		require.NoError(t, err)
+		// URL, ranking, ranking index and visitors
+		assert.Equal(t, 4, renamed)
+
+		qa := redisCache.NewRedisCache(env.RedisClient, "qa", 0, 0, logger)
+		got, err := qa.Get(ctx, domain.DefaultNamespace, "shared")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "https://staging.example.com", got.OriginalURL)
+
+		require.NoError(t, qa.InvalidateTopURLs(ctx))
+		remaining, err := env.RedisClient.Keys(ctx, "qa:top_urls:*").Result()
+		require.NoError(t, err)
+		assert.Empty(t, remaining)
+
+		left, err := env.RedisClient.Keys(ctx, "staging:*").Result()
+		require.NoError(t, err)
+		assert.Empty(t, left)
+		got, err = prod.Get(ctx, domain.DefaultNamespace, "shared")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", got.OriginalURL)
+	})
+}
+
+func TestURLService_ResetAnalytics_Integration(t *testing.T) {
+	env := SetupTestEnvironment(t)
+	ctx := context.Background()
+
+	for _, alias := range []string{"qareset", "kept"} {
+		_, err := env.Service.CreateShortURL(ctx, application.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias}, testBaseURL)
+		require.NoError(t, err)
+		for range 2 {
+			_, err := env.Service.IncrementClicks(ctx, domain.DefaultNamespace, alias, application.ClickDetails{Referer: "https://example.org/post"})
+			require.NoError(t, err)
+		}
+	}
+	// Cached with its clicks, the reset must not leave them behind
+	cached, err := env.Service.GetURL(ctx, domain.DefaultNamespace, "qareset")
+	require.NoError(t, err)
+	require.Equal(t, 2, cached.Clicks)
+
+	require.NoError(t, env.Service.ResetAnalytics(ctx, domain.DefaultNamespace, "qareset"))
+
+	url, err := env.Service.GetURL(ctx, domain.DefaultNamespace, "qareset")
+	require.NoError(t, err)
+	assert.Zero(t, url.Clicks)
+	assert.Zero(t, url.UniqueClicks)
+
+	var events int
+	require.NoError(t, env.DB.GetContext(ctx, &events, `SELECT COUNT(*) FROM url_clicks WHERE namespace = $1 AND short_code = $2`, domain.DefaultNamespace, "qareset"))
+	assert.Zero(t, events)
+
+	t.Run("other URLs keep their analytics", func(t *testing.T) {
+		url, err := env.Service.GetURL(ctx, domain.DefaultNamespace, "kept")
+		require.NoError(t, err)
+		assert.Equal(t, 2, url.Clicks)
+
+		referrers, err := env.Service.GetTopReferrers(ctx, domain.DefaultNamespace, "kept", 10)
+		require.NoError(t, err)
+		require.Len(t, referrers, 1)
+		assert.Equal(t, 2, referrers[0].Count)
+	})
+
+	assert.ErrorIs(t, env.Service.ResetAnalytics(ctx, domain.DefaultNamespace, "missing"), domain.ErrURLNotFound)
+}